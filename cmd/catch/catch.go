@@ -49,6 +49,10 @@ var (
 	// TODO: This should be randomly assigned at stored in the JSON DB.
 	registryInternalAddr = flag.String("registry-internal-addr", "127.0.0.1:0", "address for registry to listen on internally")
 	containerdSocket     = flag.String("containerd-socket", "/run/containerd/containerd.sock", "path to containerd socket (required for registry cache)")
+
+	// The mirror address is written to the Docker daemon config, so it has to
+	// stay the same across restarts.
+	registryMirrorAddr = flag.String("registry-mirror-addr", "127.0.0.1:41549", "address for the pull-through registry mirror to listen on once it is enabled")
 )
 
 var (
@@ -65,6 +69,7 @@ var (
 	}
 	exitProcess                             = os.Exit
 	setupDockerFn                           = setupDocker
+	setupPodmanFn                           = setupPodman
 	setupVMHostFn                           = setupVMHost
 	doInstallFn                             = doInstall
 	validateCatchRuntimeFn                  = validateCatchRuntime
@@ -315,6 +320,10 @@ func runCatchProcess(args []string, out io.Writer) error {
 	curUser := must.Get(user.Current())
 	scfg := newCatchConfig(paths, curUser.Username, *registryInternalAddr, *containerdSocket, startup.servicesRoot)
 	applyInstallMeta(scfg, dataDir)
+	rt, err := hostContainerRuntime(scfg)
+	if err != nil {
+		return err
+	}
 
 	if handled, err := handleLocalCommand(args, scfg, dataDir, out); err != nil {
 		return err
@@ -322,7 +331,7 @@ func runCatchProcess(args []string, out io.Writer) error {
 		return nil
 	}
 
-	if err := validateCatchRuntimeFn(*containerdSocket); err != nil {
+	if err := validateCatchRuntimeFn(*containerdSocket, rt); err != nil {
 		return err
 	}
 	runServer(dataDir, scfg)
@@ -376,7 +385,7 @@ func handleVMRunCommand(args []string) error {
 	if err != nil {
 		return err
 	}
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	ctx, stop := catch.VMRunContext(context.Background(), *serviceRoot, *apiSock)
 	defer stop()
 	err = runVMConsoleProxy(ctx, catch.VMConsoleProxyConfig{
 		Service:              *service,
@@ -737,13 +746,23 @@ func handleVMNetworkEnsureCommand(args []string, scfg *catch.Config) (bool, erro
 }
 
 func handleInstallCommand(scfg *catch.Config, dataDir string) error {
-	if err := setupDockerFn(); err != nil {
-		return fmt.Errorf("failed to set up docker: %w", err)
+	rt, err := selectInstallContainerRuntime(scfg, os.Getenv)
+	if err != nil {
+		return err
 	}
-	if err := ensureContainerdSnapshotterForInstallFn(defaultDockerConfigPath); err != nil {
-		return fmt.Errorf("failed to configure docker: %w", err)
+	if rt == svc.ContainerRuntimePodman {
+		if err := setupPodmanFn(); err != nil {
+			return fmt.Errorf("failed to set up podman: %w", err)
+		}
+	} else {
+		if err := setupDockerFn(); err != nil {
+			return fmt.Errorf("failed to set up docker: %w", err)
+		}
+		if err := ensureContainerdSnapshotterForInstallFn(defaultDockerConfigPath); err != nil {
+			return fmt.Errorf("failed to configure docker: %w", err)
+		}
 	}
-	if err := validateCatchRuntimeFn(*containerdSocket); err != nil {
+	if err := validateCatchRuntimeFn(*containerdSocket, rt); err != nil {
 		return fmt.Errorf("failed to validate catch runtime prerequisites: %w", err)
 	}
 	if err := doInstallFn(scfg, dataDir); err != nil {
@@ -775,6 +794,7 @@ func runServer(dataDir string, scfg *catch.Config) {
 	rpcln := must.Get(ts.Listen("tcp", fmt.Sprintf(":%d", defaultRPCPort)))
 	internalRegLn := must.Get(net.Listen("tcp", *registryInternalAddr))
 	scfg.InternalRegistryAddr = internalRegLn.Addr().String()
+	scfg.RegistryMirrorAddr = *registryMirrorAddr
 	regln := must.Get(ts.ListenTLS("tcp", ":443"))
 	dockerPluginSock := dockerPluginSocket()
 	runtime := must.Get(startCatchServerWithDockerPlugin(scfg, dockerPluginSock, catch.NewServer))
@@ -786,6 +806,9 @@ func runServer(dataDir string, scfg *catch.Config) {
 			log.Fatalf("internal registry server error: %v", err)
 		}
 	}()
+	if err := server.StartRegistryMirror(); err != nil {
+		log.Printf("registry mirror disabled: %v", err)
+	}
 	go func() {
 		mux := http.NewServeMux()
 		mux.Handle("/v2/", server.RegistryHandler())
@@ -825,7 +848,12 @@ func acquireCatchServerLock(dataDir string) (*os.File, error) {
 	return f, nil
 }
 
-func validateCatchRuntime(socket string) error {
+func validateCatchRuntime(socket string, rt svc.ContainerRuntime) error {
+	if rt.IsPodman() {
+		// Podman hosts keep the internal registry on disk, not in containerd.
+		_, err := svc.ContainerRuntimePodman.LookPath()
+		return err
+	}
 	if err := validateContainerdSocket(socket); err != nil {
 		return err
 	}
@@ -998,7 +1026,7 @@ func setupDocker() error {
 
 func defaultDockerSetupDeps() dockerSetupDeps {
 	return dockerSetupDeps{
-		dockerCmd:  svc.DockerCmd,
+		dockerCmd:  svc.ContainerRuntimeDocker.LookPath,
 		confirm:    cmdutil.Confirm,
 		stdin:      os.Stdin,
 		stderr:     os.Stderr,
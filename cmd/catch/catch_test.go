@@ -24,6 +24,7 @@ import (
 
 	"github.com/yeetrun/yeet/pkg/catch"
 	cdb "github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/svc"
 	"tailscale.com/ipn/ipnstate"
 	"tailscale.com/types/views"
 )
@@ -448,7 +449,7 @@ func TestRunCatchProcessDNSRunsWithoutRuntimeValidation(t *testing.T) {
 		validateCatchRuntimeFn = oldValidateRuntime
 	})
 
-	validateCatchRuntimeFn = func(string) error {
+	validateCatchRuntimeFn = func(string, svc.ContainerRuntime) error {
 		t.Fatal("dns command should not validate containerd runtime")
 		return nil
 	}
@@ -481,7 +482,7 @@ func TestISODNSLocalCommandRoutesExactlyAndRejectsArguments(t *testing.T) {
 		validateCatchRuntimeFn = oldValidateRuntime
 	})
 
-	validateCatchRuntimeFn = func(string) error {
+	validateCatchRuntimeFn = func(string, svc.ContainerRuntime) error {
 		t.Fatal("iso-dns command should not validate containerd runtime")
 		return nil
 	}
@@ -541,7 +542,7 @@ func TestRunCatchProcessReturnsRuntimeValidationError(t *testing.T) {
 	oldValidateRuntime := validateCatchRuntimeFn
 	*legacyDataDir = t.TempDir()
 	wantErr := errors.New("runtime missing")
-	validateCatchRuntimeFn = func(string) error { return wantErr }
+	validateCatchRuntimeFn = func(string, svc.ContainerRuntime) error { return wantErr }
 	t.Cleanup(func() {
 		*legacyDataDir = oldDataDir
 		validateCatchRuntimeFn = oldValidateRuntime
@@ -576,7 +577,7 @@ func TestHandleLocalCommandInstallPreparesRuntimeBeforeInstallingService(t *test
 		order = append(order, "snapshotter")
 		return nil
 	}
-	validateCatchRuntimeFn = func(string) error {
+	validateCatchRuntimeFn = func(string, svc.ContainerRuntime) error {
 		order = append(order, "runtime")
 		return nil
 	}
@@ -602,6 +603,63 @@ func TestHandleLocalCommandInstallPreparesRuntimeBeforeInstallingService(t *test
 	}
 }
 
+func TestHandleLocalCommandInstallSetsUpPodmanInsteadOfDocker(t *testing.T) {
+	oldSetupDocker := setupDockerFn
+	oldSetupPodman := setupPodmanFn
+	oldEnsureSnapshotter := ensureContainerdSnapshotterForInstallFn
+	oldValidateRuntime := validateCatchRuntimeFn
+	oldDoInstall := doInstallFn
+	oldSetupVMHost := setupVMHostFn
+	t.Cleanup(func() {
+		setupDockerFn = oldSetupDocker
+		setupPodmanFn = oldSetupPodman
+		ensureContainerdSnapshotterForInstallFn = oldEnsureSnapshotter
+		validateCatchRuntimeFn = oldValidateRuntime
+		doInstallFn = oldDoInstall
+		setupVMHostFn = oldSetupVMHost
+	})
+	t.Setenv(catchContainerRuntimeEnv, "podman")
+
+	var order []string
+	setupDockerFn = func() error {
+		order = append(order, "docker")
+		return nil
+	}
+	setupPodmanFn = func() error {
+		order = append(order, "podman")
+		return nil
+	}
+	ensureContainerdSnapshotterForInstallFn = func(string) error {
+		order = append(order, "snapshotter")
+		return nil
+	}
+	var validated svc.ContainerRuntime
+	validateCatchRuntimeFn = func(_ string, rt svc.ContainerRuntime) error {
+		validated = rt
+		order = append(order, "runtime")
+		return nil
+	}
+	doInstallFn = func(*catch.Config, string) error {
+		order = append(order, "install")
+		return nil
+	}
+	setupVMHostFn = func(string) error {
+		order = append(order, "vm")
+		return nil
+	}
+
+	if _, err := handleLocalCommand([]string{"install"}, &catch.Config{}, t.TempDir(), io.Discard); err != nil {
+		t.Fatalf("handleLocalCommand install returned error: %v", err)
+	}
+	want := []string{"podman", "runtime", "install", "vm"}
+	if !reflect.DeepEqual(order, want) {
+		t.Fatalf("install order = %#v, want %#v", order, want)
+	}
+	if validated != svc.ContainerRuntimePodman {
+		t.Fatalf("install validated runtime %q, want podman", validated)
+	}
+}
+
 func TestHandleSpecialCommandVMRun(t *testing.T) {
 	oldRun := runVMConsoleProxy
 	defer func() { runVMConsoleProxy = oldRun }()
@@ -0,0 +1,65 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/yeetrun/yeet/pkg/catch"
+	"github.com/yeetrun/yeet/pkg/cmdutil"
+	"github.com/yeetrun/yeet/pkg/svc"
+)
+
+// catchContainerRuntimeEnv carries `yeet init --container-runtime` to
+// `catch install`.
+const catchContainerRuntimeEnv = "CATCH_CONTAINER_RUNTIME"
+
+// hostContainerRuntime returns the container runtime stored in the host
+// settings.
+func hostContainerRuntime(scfg *catch.Config) (svc.ContainerRuntime, error) {
+	if scfg.DB == nil {
+		return svc.ContainerRuntimeDocker, nil
+	}
+	dv, err := scfg.DB.Get()
+	if err != nil {
+		return "", fmt.Errorf("failed to read host settings: %w", err)
+	}
+	return svc.ContainerRuntimeFromData(dv)
+}
+
+// selectInstallContainerRuntime returns the runtime catch install sets up:
+// the one named by CATCH_CONTAINER_RUNTIME, recorded in the host settings,
+// or else the one already recorded.
+func selectInstallContainerRuntime(scfg *catch.Config, getenv func(string) string) (svc.ContainerRuntime, error) {
+	name := strings.TrimSpace(getenv(catchContainerRuntimeEnv))
+	if name == "" {
+		return hostContainerRuntime(scfg)
+	}
+	rt, err := svc.ParseContainerRuntime(name)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s: %w", catchContainerRuntimeEnv, err)
+	}
+	if scfg.DB == nil {
+		return rt, nil
+	}
+	if _, err := catch.StoreContainerRuntime(scfg.DB, rt); err != nil {
+		return "", fmt.Errorf("failed to select container runtime: %w", err)
+	}
+	return rt, nil
+}
+
+// setupPodman checks podman can run compose projects. `podman compose` hands
+// off to docker-compose, which talks to the podman API socket.
+func setupPodman() error {
+	if _, err := svc.ContainerRuntimePodman.LookPath(); err != nil {
+		return fmt.Errorf("podman is required; install it with the host package manager")
+	}
+	if err := cmdutil.NewStdCmd("systemctl", "enable", "--now", "podman.socket").Run(); err != nil {
+		return fmt.Errorf("failed to enable podman.socket: %w", err)
+	}
+	return svc.ContainerRuntimePodman.CheckCompose(context.Background())
+}
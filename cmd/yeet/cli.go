@@ -81,18 +81,23 @@ func buildGroupHandlers() map[string]yargs.Group {
 				"pull":     handleDockerGroup,
 				"update":   handleDockerGroup,
 				"outdated": handleDockerGroup,
+				"images":   handleDockerGroup,
 				"push":     yeet.HandlePush,
 			},
 		},
 		"vm": {
 			Description: "Manage VM-specific commands",
 			Commands: map[string]yargs.SubcommandHandler{
-				"console": handleVMGroup,
-				"set":     handleVMGroup,
-				"memory":  handleVMGroup,
-				"images":  handleVMGroup,
-				"kernel":  handleVMGroup,
-				"runtime": handleVMGroup,
+				"console":   handleVMGroup,
+				"set":       handleVMGroup,
+				"hibernate": handleVMGroup,
+				"resume":    handleVMGroup,
+				"memory":    handleVMGroup,
+				"images":    handleVMGroup,
+				"kernel":    handleVMGroup,
+				"disk":      handleVMGroup,
+				"migrate":   handleVMGroup,
+				"runtime":   handleVMGroup,
 			},
 		},
 		"env": {
@@ -118,6 +123,9 @@ func buildGroupHandlers() map[string]yargs.Group {
 				"rollback":    handleServiceGroup,
 				"generations": handleServiceGroup,
 				"sync":        handleServiceGroup,
+				"export":      handleServiceGroup,
+				"import":      handleServiceGroup,
+				"move":        handleServiceGroup,
 			},
 		},
 		"snapshots": {
@@ -152,7 +160,7 @@ func buildHelpConfig() yargs.HelpConfig {
 	subcommands["init"] = yargs.SubCommandInfo{
 		Name:        "init",
 		Description: "Install catch on a remote host (prompts for Tailscale OAuth setup when needed)",
-		Usage:       "[--from-github] [--nightly] [--install-docker] [--install-vm-tools] [--workspace=PATH] [--no-workspace] [--data-dir=PATH_OR_DATASET] [--services-root=PATH_OR_DATASET] [--zfs] [--ts-client-secret=<secret>] [--ts-auth-key=<key>] [ROOT@MACHINE-HOST]",
+		Usage:       "[--from-github] [--nightly] [--install-docker] [--container-runtime=docker|podman] [--install-vm-tools] [--workspace=PATH] [--no-workspace] [--data-dir=PATH_OR_DATASET] [--services-root=PATH_OR_DATASET] [--zfs] [--ts-client-secret=<secret>] [--ts-auth-key=<key>] [ROOT@MACHINE-HOST]",
 		Examples: []string{
 			"yeet init root@<machine-host>",
 			"yeet init --workspace ~/yeet-services root@<machine-host>",
@@ -161,6 +169,7 @@ func buildHelpConfig() yargs.HelpConfig {
 			"yeet init --zfs --data-dir=flash/yeet/data --services-root=flash/yeet/services root@<machine-host>",
 			"yeet init --ts-client-secret=<secret> root@<machine-host>",
 			"yeet init --install-docker --install-vm-tools --ts-client-secret=<secret> root@<machine-host>",
+			"yeet init --container-runtime=podman root@<machine-host>",
 			"yeet init --ts-auth-key=<key> root@<machine-host>",
 			"yeet init",
 		},
@@ -186,12 +195,13 @@ func buildHelpConfig() yargs.HelpConfig {
 	subcommands["ssh"] = yargs.SubCommandInfo{
 		Name:        "ssh",
 		Description: "Open a catch host shell, a service shell, or a VM guest shell",
-		Usage:       "[--force-proxy] [<svc>] [-- <remote-cmd...>]",
+		Usage:       "[--force-proxy|--agent] [<svc>] [-- <remote-cmd...>]",
 		Examples: []string{
 			"yeet ssh",
 			"yeet --host=<host> ssh",
 			"yeet ssh <svc>",
 			"yeet ssh --force-proxy <vm>",
+			"yeet ssh --agent <vm> -- systemctl status",
 			"yeet ssh -- uname -a",
 			"yeet ssh <svc> -- ls -la",
 		},
@@ -242,8 +252,8 @@ func buildHelpConfig() yargs.HelpConfig {
 		docker.Commands["push"] = yargs.SubCommandInfo{
 			Name:        "push",
 			Description: "Push a container image to the remote host (optionally run it)",
-			Usage:       "docker push SVC IMAGE [--run] [--all-local]",
-			Examples:    []string{"yeet docker push <svc> <local-image>:<tag> --run"},
+			Usage:       "docker push SVC IMAGE [--run] [--all-local] [--platform=os/arch]",
+			Examples:    []string{"yeet docker push <svc> <local-image>:<tag> --run", "yeet docker push <svc> <multi-arch-image>:<tag> --platform=linux/arm64"},
 		}
 		groups["docker"] = docker
 	}
@@ -22,7 +22,8 @@ var serviceBridgeSkippedGroupCommands = map[string]map[string]struct{}{
 		"images": {},
 	},
 	"service": {
-		"sync": {},
+		"sync":   {},
+		"import": {},
 	},
 	"snapshots": {
 		"list":      {},
@@ -185,9 +186,15 @@ func bridgeGroupArgs(args []string, groupSpecs map[string]map[string]map[string]
 	if isServiceBridgeHostLevelGroupCommand(args[0], args[1]) {
 		return "", "", append([]string{}, args...), true
 	}
+	if args[0] == "docker" && args[1] == "images" {
+		return bridgeDockerImagesArgs(args, flags)
+	}
 	if args[0] == "vm" && args[1] == "kernel" {
 		return bridgeVMKernelArgs(args, flags)
 	}
+	if args[0] == "vm" && args[1] == "disk" {
+		return bridgeVMDiskArgs(args, flags)
+	}
 	if args[0] == "vm" && args[1] == "runtime" {
 		return bridgeVMRuntimeArgs(args, flags, "")
 	}
@@ -286,6 +293,16 @@ func positionalArgIndices(args []string, start int, flags map[string]cli.FlagSpe
 	return result
 }
 
+// bridgeDockerImagesArgs scopes `docker images <svc>` to the service; the
+// unscoped listing and prune stay host-level.
+func bridgeDockerImagesArgs(args []string, flags map[string]cli.FlagSpec) (service string, host string, bridged []string, ok bool) {
+	positionals := positionalArgIndices(args, 2, flags)
+	if len(positionals) == 0 || args[positionals[0]] == cli.DockerImagesActionPrune {
+		return "", "", nil, false
+	}
+	return bridgeCommandArgs(args, 2, flags)
+}
+
 func bridgeVMKernelArgs(args []string, flags map[string]cli.FlagSpec) (service string, host string, bridged []string, ok bool) {
 	if len(args) < 3 || args[2] != "sync" {
 		return "", "", nil, false
@@ -293,6 +310,13 @@ func bridgeVMKernelArgs(args []string, flags map[string]cli.FlagSpec) (service s
 	return bridgeCommandArgs(args, 3, flags)
 }
 
+func bridgeVMDiskArgs(args []string, flags map[string]cli.FlagSpec) (service string, host string, bridged []string, ok bool) {
+	if len(args) < 3 || strings.HasPrefix(args[2], "-") {
+		return "", "", nil, false
+	}
+	return bridgeCommandArgs(args, 3, flags)
+}
+
 func isVariadicServiceGroupCommand(group string, command string) bool {
 	reg := cli.RemoteCommandRegistry()
 	groupSpec, ok := reg.Groups[group]
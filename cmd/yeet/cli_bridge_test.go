@@ -46,6 +46,8 @@ func TestBridgeServiceArgsDoesNotBridgeLocalOrEmptyCommands(t *testing.T) {
 		{"service", "sync", "svc-a"},
 		{"service", "sync", "--all"},
 		{"service", "sync", "--config", "./yeet.toml", "svc-a"},
+		{"service", "import", "svc-a.yeet.tar.zst"},
+		{"service", "import", "svc-a.yeet.tar.zst", "--as", "svc-b"},
 		{"docker"},
 		{"unknown", "svc-a"},
 		{"env", "bogus", "svc-a"},
@@ -565,6 +567,19 @@ func TestBridgeServiceArgsVMSet(t *testing.T) {
 			wantService: "devbox",
 			wantBridged: "vm set --net lan --macvlan-parent=vmbr0",
 		},
+		{
+			name:        "vm hibernate policy flag",
+			args:        []string{"vm", "hibernate", "devbox@host-a", "--on-host-shutdown=on"},
+			wantService: "devbox",
+			wantHost:    "host-a",
+			wantBridged: "vm hibernate --on-host-shutdown=on",
+		},
+		{
+			name:        "vm resume",
+			args:        []string{"vm", "resume", "devbox"},
+			wantService: "devbox",
+			wantBridged: "vm resume",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -830,6 +845,30 @@ func TestBridgeServiceArgsDockerOutdatedNoServiceDoesNotBridge(t *testing.T) {
 	}
 }
 
+func TestBridgeServiceArgsDockerImages(t *testing.T) {
+	remoteSpecs := cli.RemoteFlagSpecs()
+	groupSpecs := cli.RemoteGroupFlagSpecs()
+	for _, args := range [][]string{
+		{"docker", "images"},
+		{"docker", "images", "--format=json"},
+		{"docker", "images", "prune", "--dry-run"},
+	} {
+		service, host, bridged, ok := bridgeServiceArgs(args, remoteSpecs, groupSpecs, "")
+		if ok {
+			t.Fatalf("%q: expected host-level command, got service=%q host=%q bridged=%v", args, service, host, bridged)
+		}
+	}
+
+	args := []string{"docker", "images", "--format", "json", "svc-a@host-a"}
+	service, host, bridged, ok := bridgeServiceArgs(args, remoteSpecs, groupSpecs, "")
+	if !ok || service != "svc-a" || host != "host-a" {
+		t.Fatalf("bridge = %q@%q ok=%v, want svc-a@host-a", service, host, ok)
+	}
+	if got := strings.Join(bridged, " "); got != "docker images --format json" {
+		t.Fatalf("bridged = %q, want docker images --format json", got)
+	}
+}
+
 func TestBridgeServiceArgsDockerPushDoesNotBridge(t *testing.T) {
 	remoteSpecs := cli.RemoteFlagSpecs()
 	groupSpecs := cli.RemoteGroupFlagSpecs()
@@ -898,6 +937,15 @@ func TestBridgeServiceArgsVMConsoleGroup(t *testing.T) {
 	if got := strings.Join(bridged, " "); got != "vm console" {
 		t.Fatalf("unexpected bridged args: %s", got)
 	}
+
+	args = []string{"vm", "console", "devbox", "--attach", "--lines", "50"}
+	service, _, bridged, ok = bridgeServiceArgs(args, remoteSpecs, groupSpecs, "")
+	if !ok || service != "devbox" {
+		t.Fatalf("bridge with flags = %q, %v", service, ok)
+	}
+	if got := strings.Join(bridged, " "); got != "vm console --attach --lines 50" {
+		t.Fatalf("unexpected bridged args with flags: %s", got)
+	}
 }
 
 func TestBridgeServiceArgsSkipsVMImages(t *testing.T) {
@@ -958,6 +1006,22 @@ func TestBridgeServiceArgsVMKernelSync(t *testing.T) {
 	}
 }
 
+func TestBridgeServiceArgsVMDisk(t *testing.T) {
+	remoteSpecs := cli.RemoteFlagSpecs()
+	groupSpecs := cli.RemoteGroupFlagSpecs()
+	args := []string{"vm", "disk", "resize", "devbox@host-a", "data", "--size=300G"}
+	service, host, bridged, ok := bridgeServiceArgs(args, remoteSpecs, groupSpecs, "")
+	if !ok {
+		t.Fatalf("expected to recognize vm disk group command")
+	}
+	if service != "devbox" || host != "host-a" {
+		t.Fatalf("service, host = %q, %q, want devbox, host-a", service, host)
+	}
+	if got := strings.Join(bridged, " "); got != "vm disk resize data --size=300G" {
+		t.Fatalf("bridged args = %q, want vm disk resize data --size=300G", got)
+	}
+}
+
 func TestBridgeVMRuntimeCommands(t *testing.T) {
 	remoteSpecs := cli.RemoteFlagSpecs()
 	groupSpecs := cli.RemoteGroupFlagSpecs()
@@ -371,11 +371,12 @@ func TestCopyHelpMentionsVMGuestCopy(t *testing.T) {
 	stdout := string(rawStdout)
 	for _, want := range []string{
 		"Copy files between local paths and service data or VM guests",
-		"[--force-proxy] [-avz] <src>... <dst>",
+		"[--force-proxy|--agent] [-avz] <src>... <dst>",
 		"yeet copy ./config.yml svc:data/config.yml",
 		"yeet copy ./configs/*.yml devbox:~/configs/",
 		`yeet copy devbox:"/var/log/*.log" ./logs/`,
 		"yeet copy --force-proxy ./configs/ devbox:~/configs/",
+		"yeet copy --agent ./motd devbox:/etc/motd",
 	} {
 		if !strings.Contains(stdout, want) {
 			t.Fatalf("copy help missing %q\n%s", want, stdout)
@@ -507,7 +508,7 @@ func TestSSHHelpMentionsCatchRPCShellBehavior(t *testing.T) {
 	if !strings.Contains(stdout, "Open a catch host shell, a service shell, or a VM guest shell") {
 		t.Fatalf("stdout missing ssh shell description:\n%s", stdout)
 	}
-	if !strings.Contains(stdout, "yeet ssh [OPTIONS] [--force-proxy] [<svc>] [-- <remote-cmd...>]") {
+	if !strings.Contains(stdout, "yeet ssh [OPTIONS] [--force-proxy|--agent] [<svc>] [-- <remote-cmd...>]") {
 		t.Fatalf("stdout missing ssh usage:\n%s", stdout)
 	}
 	if strings.Contains(stdout, "ssh-opts") {
@@ -1300,6 +1301,36 @@ func TestPrepareCommandRoute(t *testing.T) {
 			wantArgs:    []string{"service", "generations", "--format=json"},
 			wantBridged: []string{"service", "generations", "--format=json"},
 		},
+		{
+			name:        "service export data host target",
+			args:        []string{"service@catch-a", "export", "svc-a", "--data"},
+			wantHost:    "catch-a",
+			wantService: "svc-a",
+			wantArgs:    []string{"service", "export", "--data"},
+			wantBridged: []string{"service", "export", "--data"},
+		},
+		{
+			name:        "service move qualified source",
+			args:        []string{"service", "move", "svc-a@catch-a", "--to", "catch-b"},
+			wantHost:    "catch-a",
+			wantService: "svc-a",
+			wantArgs:    []string{"service", "move", "--to", "catch-b"},
+			wantBridged: []string{"service", "move", "--to", "catch-b"},
+		},
+		{
+			name:        "vm migrate qualified source",
+			args:        []string{"vm", "migrate", "devbox@catch-a", "--to=catch-b"},
+			wantHost:    "catch-a",
+			wantService: "devbox",
+			wantArgs:    []string{"vm", "migrate", "--to=catch-b"},
+			wantBridged: []string{"vm", "migrate", "--to=catch-b"},
+		},
+		{
+			name:     "service import keeps archive positional",
+			args:     []string{"service@catch-b", "import", "svc-a.yeet.tar.zst", "--as=svc-b"},
+			wantHost: "catch-b",
+			wantArgs: []string{"service", "import", "svc-a.yeet.tar.zst", "--as=svc-b"},
+		},
 		{
 			name:     "snapshots defaults is unscoped remote group",
 			args:     []string{"snapshots@catch-a", "defaults", "show"},
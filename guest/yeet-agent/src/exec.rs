@@ -0,0 +1,371 @@
+//! Streaming command execution for protocol v2 `exec` requests.
+
+use crate::frame::{FRAME_EOF, FRAME_EXIT, FRAME_RESIZE, FRAME_STDERR, FRAME_STDIN, FRAME_STDOUT};
+use crate::{AgentRequest, read_frame, response_error, response_ok, write_frame, write_response};
+use std::fs::File;
+use std::io::{self, BufRead, Read, Write};
+use std::os::fd::{AsRawFd, FromRawFd, RawFd};
+use std::os::unix::process::{CommandExt, ExitStatusExt};
+use std::path::Path;
+use std::process::{Child, Command, ExitStatus, Stdio};
+use std::sync::Mutex;
+use std::sync::atomic::{AtomicBool, Ordering};
+use std::thread;
+
+const DEFAULT_ROWS: u16 = 24;
+const DEFAULT_COLS: u16 = 80;
+const OUTPUT_CHUNK: usize = 32 * 1024;
+
+struct Session {
+    child: Child,
+    input: Option<Box<dyn Write + Send>>,
+    outputs: Vec<(u8, Box<dyn Read + Send>)>,
+    pty: Option<File>,
+}
+
+pub fn handle<R: BufRead + Send, W: Write + Send>(
+    req: &AgentRequest,
+    reader: R,
+    mut writer: W,
+) -> io::Result<()> {
+    let session = match spawn(req) {
+        Ok(session) => session,
+        Err(err) => {
+            return write_response(
+                &mut writer,
+                &response_error(req, "exec_failed", err.to_string()),
+            );
+        }
+    };
+    write_response(&mut writer, &response_ok(req))?;
+    run(session, reader, writer)
+}
+
+fn spawn(req: &AgentRequest) -> io::Result<Session> {
+    let argv = if req.argv.is_empty() {
+        default_shell()
+    } else {
+        req.argv.clone()
+    };
+    let mut cmd = Command::new(&argv[0]);
+    cmd.args(&argv[1..]);
+    let home = std::env::var("HOME").unwrap_or_else(|_| "/root".to_string());
+    if Path::new(&home).is_dir() {
+        cmd.current_dir(&home);
+    } else {
+        cmd.current_dir("/");
+    }
+    cmd.env("HOME", &home);
+    if req.tty {
+        if !req.term.is_empty() {
+            cmd.env("TERM", &req.term);
+        }
+        spawn_pty(
+            cmd,
+            non_zero(req.rows, DEFAULT_ROWS),
+            non_zero(req.cols, DEFAULT_COLS),
+        )
+    } else {
+        spawn_pipes(cmd)
+    }
+}
+
+fn default_shell() -> Vec<String> {
+    let shell = if Path::new("/bin/bash").exists() {
+        "/bin/bash"
+    } else {
+        "/bin/sh"
+    };
+    vec![shell.to_string(), "-l".to_string()]
+}
+
+fn non_zero(value: u16, fallback: u16) -> u16 {
+    if value == 0 { fallback } else { value }
+}
+
+fn spawn_pipes(mut cmd: Command) -> io::Result<Session> {
+    cmd.stdin(Stdio::piped())
+        .stdout(Stdio::piped())
+        .stderr(Stdio::piped());
+    let mut child = cmd.spawn()?;
+    let input = child
+        .stdin
+        .take()
+        .map(|stdin| Box::new(stdin) as Box<dyn Write + Send>);
+    let mut outputs: Vec<(u8, Box<dyn Read + Send>)> = Vec::new();
+    if let Some(stdout) = child.stdout.take() {
+        outputs.push((FRAME_STDOUT, Box::new(stdout)));
+    }
+    if let Some(stderr) = child.stderr.take() {
+        outputs.push((FRAME_STDERR, Box::new(stderr)));
+    }
+    Ok(Session {
+        child,
+        input,
+        outputs,
+        pty: None,
+    })
+}
+
+fn spawn_pty(mut cmd: Command, rows: u16, cols: u16) -> io::Result<Session> {
+    let (master, slave) = open_pty(rows, cols)?;
+    cmd.stdin(Stdio::from(slave.try_clone()?))
+        .stdout(Stdio::from(slave.try_clone()?))
+        .stderr(Stdio::from(slave));
+    unsafe {
+        cmd.pre_exec(|| {
+            if libc::setsid() < 0 {
+                return Err(io::Error::last_os_error());
+            }
+            if libc::ioctl(0, libc::TIOCSCTTY as _, 0) < 0 {
+                return Err(io::Error::last_os_error());
+            }
+            Ok(())
+        });
+    }
+    let child = cmd.spawn()?;
+    // The Command still holds the slave side; drop it so reads from the
+    // master see EOF once the child and its descendants exit.
+    drop(cmd);
+    Ok(Session {
+        child,
+        input: Some(Box::new(master.try_clone()?)),
+        outputs: vec![(FRAME_STDOUT, Box::new(master.try_clone()?))],
+        pty: Some(master),
+    })
+}
+
+fn open_pty(rows: u16, cols: u16) -> io::Result<(File, File)> {
+    let mut master: libc::c_int = -1;
+    let mut slave: libc::c_int = -1;
+    let size = window_size(rows, cols);
+    let rc = unsafe {
+        libc::openpty(
+            &mut master,
+            &mut slave,
+            std::ptr::null_mut(),
+            std::ptr::null(),
+            &size,
+        )
+    };
+    if rc < 0 {
+        return Err(io::Error::last_os_error());
+    }
+    let master = unsafe { File::from_raw_fd(master) };
+    let slave = unsafe { File::from_raw_fd(slave) };
+    set_cloexec(master.as_raw_fd())?;
+    set_cloexec(slave.as_raw_fd())?;
+    Ok((master, slave))
+}
+
+fn set_cloexec(fd: RawFd) -> io::Result<()> {
+    if unsafe { libc::fcntl(fd, libc::F_SETFD, libc::FD_CLOEXEC) } < 0 {
+        return Err(io::Error::last_os_error());
+    }
+    Ok(())
+}
+
+fn window_size(rows: u16, cols: u16) -> libc::winsize {
+    libc::winsize {
+        ws_row: rows,
+        ws_col: cols,
+        ws_xpixel: 0,
+        ws_ypixel: 0,
+    }
+}
+
+fn resize_pty(pty: &File, rows: u16, cols: u16) {
+    let size = window_size(rows, cols);
+    unsafe {
+        libc::ioctl(pty.as_raw_fd(), libc::TIOCSWINSZ as _, &size);
+    }
+}
+
+fn run<R: Read + Send, W: Write + Send>(
+    mut session: Session,
+    reader: R,
+    writer: W,
+) -> io::Result<()> {
+    let writer = Mutex::new(writer);
+    let exited = AtomicBool::new(false);
+    let pid = session.child.id() as libc::pid_t;
+    let input = session.input.take();
+    let pty = session.pty.take();
+    let outputs = std::mem::take(&mut session.outputs);
+    thread::scope(|scope| {
+        let pumps: Vec<_> = outputs
+            .into_iter()
+            .map(|(kind, out)| {
+                let writer = &writer;
+                scope.spawn(move || copy_output(kind, out, writer))
+            })
+            .collect();
+        let exited = &exited;
+        scope.spawn(move || {
+            copy_input(reader, input, pty.as_ref());
+            // The host went away while the command was still running.
+            if !exited.load(Ordering::SeqCst) {
+                unsafe {
+                    libc::kill(pid, libc::SIGHUP);
+                }
+            }
+        });
+
+        let status = session.child.wait();
+        exited.store(true, Ordering::SeqCst);
+        for pump in pumps {
+            let _ = pump.join();
+        }
+        let code = exit_code(status?);
+        let mut w = writer.lock().unwrap_or_else(|err| err.into_inner());
+        write_frame(&mut *w, FRAME_EXIT, &code.to_be_bytes())
+    })
+}
+
+fn exit_code(status: ExitStatus) -> i32 {
+    status
+        .code()
+        .unwrap_or_else(|| 128 + status.signal().unwrap_or(0))
+}
+
+fn copy_output<W: Write>(kind: u8, mut out: Box<dyn Read + Send>, writer: &Mutex<W>) {
+    let mut buf = vec![0u8; OUTPUT_CHUNK];
+    loop {
+        let n = match out.read(&mut buf) {
+            Ok(0) => return,
+            Ok(n) => n,
+            Err(err) if err.kind() == io::ErrorKind::Interrupted => continue,
+            // A PTY master reports EIO once every slave handle is closed.
+            Err(_) => return,
+        };
+        let mut w = writer.lock().unwrap_or_else(|err| err.into_inner());
+        if write_frame(&mut *w, kind, &buf[..n]).is_err() {
+            return;
+        }
+    }
+}
+
+fn copy_input<R: Read>(
+    mut reader: R,
+    mut input: Option<Box<dyn Write + Send>>,
+    pty: Option<&File>,
+) {
+    while let Ok(Some((kind, payload))) = read_frame(&mut reader) {
+        match kind {
+            FRAME_STDIN => {
+                if let Some(w) = input.as_mut()
+                    && w.write_all(&payload).and_then(|()| w.flush()).is_err()
+                {
+                    input = None;
+                }
+            }
+            FRAME_EOF => input = None,
+            FRAME_RESIZE if payload.len() == 4 => {
+                if let Some(pty) = pty {
+                    let rows = u16::from_be_bytes([payload[0], payload[1]]);
+                    let cols = u16::from_be_bytes([payload[2], payload[3]]);
+                    resize_pty(pty, rows, cols);
+                }
+            }
+            _ => {}
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::Value;
+    use std::io::BufReader;
+    use std::os::unix::net::UnixStream;
+
+    struct ExecOutput {
+        response: Value,
+        stdout: Vec<u8>,
+        stderr: Vec<u8>,
+        code: Option<i32>,
+    }
+
+    fn run_exec(req: AgentRequest, input: &[(u8, &[u8])]) -> ExecOutput {
+        let (agent, mut host) = UnixStream::pair().expect("socket pair");
+        let server = thread::spawn(move || {
+            let reader = BufReader::new(agent.try_clone().expect("clone"));
+            handle(&req, reader, agent)
+        });
+        for (kind, payload) in input {
+            write_frame(&mut host, *kind, payload).expect("write frame");
+        }
+
+        let mut reader = BufReader::new(host.try_clone().expect("clone"));
+        let mut line = String::new();
+        reader.read_line(&mut line).expect("response line");
+        let mut out = ExecOutput {
+            response: serde_json::from_str(&line).expect("response json"),
+            stdout: Vec::new(),
+            stderr: Vec::new(),
+            code: None,
+        };
+        if out.response.get("error").is_none() {
+            while let Some((kind, payload)) = read_frame(&mut reader).expect("read frame") {
+                match kind {
+                    FRAME_STDOUT => out.stdout.extend_from_slice(&payload),
+                    FRAME_STDERR => out.stderr.extend_from_slice(&payload),
+                    FRAME_EXIT => {
+                        out.code = Some(i32::from_be_bytes(payload.try_into().expect("exit code")));
+                        break;
+                    }
+                    other => panic!("unexpected frame {other}"),
+                }
+            }
+        }
+        drop(reader);
+        drop(host);
+        server.join().expect("join").expect("handle exec");
+        out
+    }
+
+    fn exec_request(argv: &[&str], tty: bool) -> AgentRequest {
+        AgentRequest {
+            protocol: 2,
+            request_type: "exec".to_string(),
+            request_id: "e1".to_string(),
+            argv: argv.iter().map(|arg| arg.to_string()).collect(),
+            tty,
+            rows: 30,
+            cols: 100,
+            ..AgentRequest::default()
+        }
+    }
+
+    #[test]
+    fn streams_stdin_stdout_stderr_and_exit_code() {
+        let got = run_exec(
+            exec_request(&["sh", "-c", "cat; echo oops >&2; exit 3"], false),
+            &[(FRAME_STDIN, b"hello\n"), (FRAME_EOF, b"")],
+        );
+
+        assert_eq!(got.response["type"], "exec");
+        assert_eq!(got.stdout, b"hello\n");
+        assert_eq!(got.stderr, b"oops\n");
+        assert_eq!(got.code, Some(3));
+    }
+
+    #[test]
+    fn runs_commands_on_a_pty_with_the_requested_size() {
+        let got = run_exec(
+            exec_request(&["sh", "-c", "test -t 0 && stty size"], true),
+            &[],
+        );
+
+        assert_eq!(got.code, Some(0));
+        assert_eq!(String::from_utf8_lossy(&got.stdout).trim(), "30 100");
+    }
+
+    #[test]
+    fn reports_spawn_failures_as_errors() {
+        let got = run_exec(exec_request(&["/nonexistent/yeet-agent-test"], false), &[]);
+
+        assert_eq!(got.response["error"]["code"], "exec_failed");
+        assert_eq!(got.code, None);
+    }
+}
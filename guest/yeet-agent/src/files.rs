@@ -0,0 +1,309 @@
+//! File push and pull for protocol v2 `file_push` and `file_pull` requests.
+
+use crate::frame::{FRAME_EOF, FRAME_STDERR, FRAME_STDIN, FRAME_STDOUT};
+use crate::{
+    AgentRequest, AgentResponse, read_frame, response_error, response_ok, write_frame,
+    write_response,
+};
+use std::fs::{self, File, OpenOptions};
+use std::io::{self, Read, Write};
+use std::os::unix::fs::{OpenOptionsExt, PermissionsExt};
+use std::path::{Path, PathBuf};
+use std::sync::atomic::{AtomicU64, Ordering};
+
+const DEFAULT_MODE: u32 = 0o644;
+const PULL_CHUNK: usize = 64 * 1024;
+
+static TEMP_COUNTER: AtomicU64 = AtomicU64::new(0);
+
+/// Receives FRAME_STDIN data up to FRAME_EOF into `req.path`, then replies with
+/// a single response line. The file is staged next to the target and renamed
+/// into place, so a failed push never leaves a partial file behind.
+pub fn handle_push<R: Read, W: Write>(
+    req: &AgentRequest,
+    reader: &mut R,
+    writer: &mut W,
+) -> io::Result<()> {
+    let resp = match push(req, reader) {
+        Ok(size) => AgentResponse {
+            size: Some(size),
+            ..response_ok(req)
+        },
+        Err(err) => response_error(req, "file_push_failed", err.to_string()),
+    };
+    write_response(writer, &resp)
+}
+
+/// Replies with the file size and mode, then streams the contents as
+/// FRAME_STDOUT chunks terminated by FRAME_EOF. A read error mid-stream is sent
+/// as FRAME_STDERR instead of FRAME_EOF.
+pub fn handle_pull<W: Write>(req: &AgentRequest, writer: &mut W) -> io::Result<()> {
+    let (mut file, meta) = match open_pull(&req.path) {
+        Ok(opened) => opened,
+        Err(err) => {
+            return write_response(
+                writer,
+                &response_error(req, "file_pull_failed", err.to_string()),
+            );
+        }
+    };
+    let resp = AgentResponse {
+        size: Some(meta.len()),
+        mode: Some(meta.permissions().mode() & 0o7777),
+        ..response_ok(req)
+    };
+    write_response(writer, &resp)?;
+    let mut buf = vec![0u8; PULL_CHUNK];
+    loop {
+        match file.read(&mut buf) {
+            Ok(0) => return write_frame(writer, FRAME_EOF, &[]),
+            Ok(n) => write_frame(writer, FRAME_STDOUT, &buf[..n])?,
+            Err(err) if err.kind() == io::ErrorKind::Interrupted => {}
+            Err(err) => return write_frame(writer, FRAME_STDERR, err.to_string().as_bytes()),
+        }
+    }
+}
+
+fn push<R: Read>(req: &AgentRequest, reader: &mut R) -> io::Result<u64> {
+    let mut staged = match Staged::create(&req.path, req.mode) {
+        Ok(staged) => staged,
+        Err(err) => {
+            drain_push(reader)?;
+            return Err(err);
+        }
+    };
+    let mut written = 0u64;
+    let mut write_err = None;
+    loop {
+        match read_frame(reader)? {
+            Some((FRAME_STDIN, data)) if write_err.is_none() => {
+                match staged.file.write_all(&data) {
+                    Ok(()) => written += data.len() as u64,
+                    Err(err) => write_err = Some(err),
+                }
+            }
+            Some((FRAME_EOF, _)) => break,
+            Some(_) => {}
+            None => {
+                return Err(io::Error::new(
+                    io::ErrorKind::UnexpectedEof,
+                    "push stream ended before the end of the file",
+                ));
+            }
+        }
+    }
+    if let Some(err) = write_err {
+        return Err(err);
+    }
+    staged.commit()?;
+    Ok(written)
+}
+
+// Consumes the rest of a push so the host, which sends the whole file before
+// reading the response, is not left blocked on a full socket.
+fn drain_push<R: Read>(reader: &mut R) -> io::Result<()> {
+    while let Some((kind, _)) = read_frame(reader)? {
+        if kind == FRAME_EOF {
+            break;
+        }
+    }
+    Ok(())
+}
+
+fn absolute_path(raw: &str) -> io::Result<&Path> {
+    let path = Path::new(raw);
+    if raw.is_empty() || !path.is_absolute() {
+        return Err(io::Error::new(
+            io::ErrorKind::InvalidInput,
+            format!("path {raw:?} must be absolute"),
+        ));
+    }
+    Ok(path)
+}
+
+fn open_pull(raw: &str) -> io::Result<(File, fs::Metadata)> {
+    let path = absolute_path(raw)?;
+    let file = File::open(path)?;
+    let meta = file.metadata()?;
+    if meta.is_dir() {
+        return Err(io::Error::new(
+            io::ErrorKind::InvalidInput,
+            format!("{raw} is a directory"),
+        ));
+    }
+    if !meta.is_file() {
+        return Err(io::Error::new(
+            io::ErrorKind::InvalidInput,
+            format!("{raw} is not a regular file"),
+        ));
+    }
+    Ok((file, meta))
+}
+
+struct Staged {
+    file: File,
+    tmp: PathBuf,
+    dst: PathBuf,
+    mode: u32,
+    committed: bool,
+}
+
+impl Staged {
+    fn create(raw: &str, mode: u32) -> io::Result<Self> {
+        let dst = absolute_path(raw)?.to_path_buf();
+        let existing = fs::metadata(&dst).ok();
+        if existing.as_ref().is_some_and(|meta| meta.is_dir()) {
+            return Err(io::Error::new(
+                io::ErrorKind::InvalidInput,
+                format!("{raw} is a directory"),
+            ));
+        }
+        let mode = match (mode, existing) {
+            (0, Some(meta)) => meta.permissions().mode() & 0o7777,
+            (0, None) => DEFAULT_MODE,
+            (mode, _) => mode & 0o7777,
+        };
+        let name = dst
+            .file_name()
+            .and_then(|name| name.to_str())
+            .unwrap_or("file");
+        let tmp = dst.with_file_name(format!(
+            ".{name}.yeet-agent-{}-{}",
+            std::process::id(),
+            TEMP_COUNTER.fetch_add(1, Ordering::Relaxed)
+        ));
+        let file = OpenOptions::new()
+            .write(true)
+            .create_new(true)
+            .mode(mode)
+            .open(&tmp)?;
+        Ok(Staged {
+            file,
+            tmp,
+            dst,
+            mode,
+            committed: false,
+        })
+    }
+
+    fn commit(&mut self) -> io::Result<()> {
+        self.file
+            .set_permissions(fs::Permissions::from_mode(self.mode))?;
+        self.file.sync_all()?;
+        fs::rename(&self.tmp, &self.dst)?;
+        self.committed = true;
+        Ok(())
+    }
+}
+
+impl Drop for Staged {
+    fn drop(&mut self) {
+        if !self.committed {
+            let _ = fs::remove_file(&self.tmp);
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::Value;
+    use std::io::{BufRead, BufReader};
+
+    fn temp_dir(name: &str) -> PathBuf {
+        let dir = std::env::temp_dir().join(format!("yeet-agent-{name}-{}", std::process::id()));
+        let _ = fs::remove_dir_all(&dir);
+        fs::create_dir_all(&dir).expect("temp dir");
+        dir
+    }
+
+    fn file_request(request_type: &str, path: &Path, mode: u32) -> AgentRequest {
+        AgentRequest {
+            protocol: 2,
+            request_type: request_type.to_string(),
+            request_id: "f1".to_string(),
+            path: path.to_string_lossy().into_owned(),
+            mode,
+            ..AgentRequest::default()
+        }
+    }
+
+    #[test]
+    fn pushes_and_pulls_a_file() {
+        let dir = temp_dir("push-pull");
+        let path = dir.join("hello.bin");
+
+        let mut input = Vec::new();
+        write_frame(&mut input, FRAME_STDIN, b"hello ").expect("frame");
+        write_frame(&mut input, FRAME_STDIN, &[0, 1, 2]).expect("frame");
+        write_frame(&mut input, FRAME_EOF, &[]).expect("frame");
+        let mut out = Vec::new();
+        handle_push(
+            &file_request("file_push", &path, 0o600),
+            &mut input.as_slice(),
+            &mut out,
+        )
+        .expect("push");
+        let resp: Value = serde_json::from_slice(&out).expect("push response");
+        assert_eq!(resp["size"], 9);
+        assert!(resp.get("error").is_none());
+        assert_eq!(fs::read(&path).expect("read"), b"hello \x00\x01\x02");
+        assert_eq!(
+            fs::metadata(&path).expect("stat").permissions().mode() & 0o777,
+            0o600
+        );
+
+        let mut out = Vec::new();
+        handle_pull(&file_request("file_pull", &path, 0), &mut out).expect("pull");
+        let mut reader = BufReader::new(out.as_slice());
+        let mut line = String::new();
+        reader.read_line(&mut line).expect("line");
+        let resp: Value = serde_json::from_str(&line).expect("pull response");
+        assert_eq!(resp["size"], 9);
+        assert_eq!(resp["mode"], 0o600);
+        assert_eq!(
+            read_frame(&mut reader).expect("frame"),
+            Some((FRAME_STDOUT, b"hello \x00\x01\x02".to_vec()))
+        );
+        assert_eq!(
+            read_frame(&mut reader).expect("frame"),
+            Some((FRAME_EOF, Vec::new()))
+        );
+
+        let _ = fs::remove_dir_all(dir);
+    }
+
+    #[test]
+    fn truncated_push_leaves_no_file_behind() {
+        let dir = temp_dir("truncated");
+        let path = dir.join("partial");
+
+        let mut input = Vec::new();
+        write_frame(&mut input, FRAME_STDIN, b"partial").expect("frame");
+        let mut out = Vec::new();
+        handle_push(
+            &file_request("file_push", &path, 0),
+            &mut input.as_slice(),
+            &mut out,
+        )
+        .expect("push");
+
+        let resp: Value = serde_json::from_slice(&out).expect("response");
+        assert_eq!(resp["error"]["code"], "file_push_failed");
+        assert_eq!(fs::read_dir(&dir).expect("read dir").count(), 0);
+
+        let _ = fs::remove_dir_all(dir);
+    }
+
+    #[test]
+    fn rejects_relative_paths_and_directories() {
+        let dir = temp_dir("reject");
+        for path in [Path::new("relative/file"), dir.as_path()] {
+            let mut out = Vec::new();
+            handle_pull(&file_request("file_pull", path, 0), &mut out).expect("pull");
+            let resp: Value = serde_json::from_slice(&out).expect("response");
+            assert_eq!(resp["error"]["code"], "file_pull_failed");
+        }
+        let _ = fs::remove_dir_all(dir);
+    }
+}
@@ -0,0 +1,101 @@
+//! Binary framing for protocol v2 stream requests.
+//!
+//! After the JSON request line (and, for exec and file_pull, the JSON response
+//! line) both peers exchange frames of the form `[kind u8][len u32 BE][payload]`.
+//! Frames keep stdout, stderr and control messages apart without base64 or
+//! escaping, so file transfers stay binary-safe.
+
+use std::io::{self, Read, Write};
+
+/// Host to guest: bytes for the process stdin or the pushed file.
+pub const FRAME_STDIN: u8 = 1;
+/// Guest to host: process stdout, PTY output or pulled file bytes.
+pub const FRAME_STDOUT: u8 = 2;
+/// Guest to host: process stderr, or the reason a pull was cut short.
+pub const FRAME_STDERR: u8 = 3;
+/// Guest to host: the process exit code as an i32 BE payload.
+pub const FRAME_EXIT: u8 = 4;
+/// Host to guest: PTY window size as rows and cols, each u16 BE.
+pub const FRAME_RESIZE: u8 = 5;
+/// Either direction: no more FRAME_STDIN or FRAME_STDOUT data follows.
+pub const FRAME_EOF: u8 = 6;
+
+pub const MAX_FRAME_LEN: usize = 1 << 20;
+
+pub fn write_frame<W: Write>(writer: &mut W, kind: u8, payload: &[u8]) -> io::Result<()> {
+    if payload.len() > MAX_FRAME_LEN {
+        return Err(io::Error::new(
+            io::ErrorKind::InvalidInput,
+            "frame payload too large",
+        ));
+    }
+    let mut header = [0u8; 5];
+    header[0] = kind;
+    header[1..].copy_from_slice(&(payload.len() as u32).to_be_bytes());
+    writer.write_all(&header)?;
+    writer.write_all(payload)?;
+    writer.flush()
+}
+
+/// Reads the next frame. A clean end of stream before a frame header returns
+/// `Ok(None)`; a stream that ends inside a frame is an error.
+pub fn read_frame<R: Read>(reader: &mut R) -> io::Result<Option<(u8, Vec<u8>)>> {
+    let mut header = [0u8; 5];
+    let mut filled = 0;
+    while filled < header.len() {
+        match reader.read(&mut header[filled..]) {
+            Ok(0) if filled == 0 => return Ok(None),
+            Ok(0) => {
+                return Err(io::Error::new(
+                    io::ErrorKind::UnexpectedEof,
+                    "stream ended inside a frame header",
+                ));
+            }
+            Ok(n) => filled += n,
+            Err(err) if err.kind() == io::ErrorKind::Interrupted => {}
+            Err(err) => return Err(err),
+        }
+    }
+    let len = u32::from_be_bytes([header[1], header[2], header[3], header[4]]) as usize;
+    if len > MAX_FRAME_LEN {
+        return Err(io::Error::new(
+            io::ErrorKind::InvalidData,
+            "frame payload too large",
+        ));
+    }
+    let mut payload = vec![0u8; len];
+    reader.read_exact(&mut payload)?;
+    Ok(Some((header[0], payload)))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn round_trips_frames() {
+        let mut buf = Vec::new();
+        write_frame(&mut buf, FRAME_STDOUT, b"hello").expect("write");
+        write_frame(&mut buf, FRAME_EOF, &[]).expect("write");
+
+        let mut r = buf.as_slice();
+        assert_eq!(
+            read_frame(&mut r).expect("read"),
+            Some((FRAME_STDOUT, b"hello".to_vec()))
+        );
+        assert_eq!(
+            read_frame(&mut r).expect("read"),
+            Some((FRAME_EOF, Vec::new()))
+        );
+        assert_eq!(read_frame(&mut r).expect("read"), None);
+    }
+
+    #[test]
+    fn rejects_truncated_and_oversized_frames() {
+        let mut truncated = [FRAME_STDOUT, 0, 0].as_slice();
+        assert!(read_frame(&mut truncated).is_err());
+
+        let mut oversized = [FRAME_STDOUT, 0xff, 0xff, 0xff, 0xff].as_slice();
+        assert!(read_frame(&mut oversized).is_err());
+    }
+}
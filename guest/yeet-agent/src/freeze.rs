@@ -0,0 +1,160 @@
+//! Guest filesystem freeze and thaw for protocol v2 `fs_freeze` and `fs_thaw`.
+//!
+//! catch freezes block-backed filesystems before it snapshots a running VM's
+//! disk so the snapshot is filesystem-consistent. If catch never sends the
+//! thaw, the agent thaws on its own after FREEZE_TIMEOUT instead of leaving the
+//! guest wedged.
+
+use std::fs::{self, File};
+use std::io;
+use std::os::fd::AsRawFd;
+use std::sync::Mutex;
+use std::sync::atomic::{AtomicU64, Ordering};
+use std::thread;
+use std::time::Duration;
+
+// _IOWR('X', 119, int) and _IOWR('X', 120, int) from linux/fs.h.
+const FIFREEZE: libc::c_ulong = 0xC004_5877;
+const FITHAW: libc::c_ulong = 0xC004_5878;
+
+const FREEZE_TIMEOUT: Duration = Duration::from_secs(60);
+
+static FROZEN: Mutex<Vec<String>> = Mutex::new(Vec::new());
+static GENERATION: AtomicU64 = AtomicU64::new(0);
+
+pub fn freeze() -> io::Result<usize> {
+    let mounts = freezable_mounts(&fs::read_to_string("/proc/self/mounts")?);
+    let mut frozen = FROZEN.lock().unwrap_or_else(|err| err.into_inner());
+    if !frozen.is_empty() {
+        return Err(io::Error::new(
+            io::ErrorKind::AlreadyExists,
+            "filesystems are already frozen",
+        ));
+    }
+    // Freeze nested mounts before their parents, like fsfreeze callers do.
+    for mount in mounts.iter().rev() {
+        match fs_ioctl(mount, FIFREEZE) {
+            Ok(()) => frozen.push(mount.clone()),
+            Err(err) if unsupported(&err) => {}
+            Err(err) => {
+                thaw_all(&mut frozen);
+                return Err(io::Error::new(err.kind(), format!("freeze {mount}: {err}")));
+            }
+        }
+    }
+    let count = frozen.len();
+    let generation = GENERATION.fetch_add(1, Ordering::SeqCst) + 1;
+    thread::spawn(move || {
+        thread::sleep(FREEZE_TIMEOUT);
+        if GENERATION.load(Ordering::SeqCst) == generation {
+            eprintln!(
+                "yeet-agent: thawing filesystems after {FREEZE_TIMEOUT:?} without a thaw request"
+            );
+            let _ = thaw();
+        }
+    });
+    Ok(count)
+}
+
+pub fn thaw() -> io::Result<usize> {
+    GENERATION.fetch_add(1, Ordering::SeqCst);
+    let mut frozen = FROZEN.lock().unwrap_or_else(|err| err.into_inner());
+    Ok(thaw_all(&mut frozen))
+}
+
+fn thaw_all(frozen: &mut Vec<String>) -> usize {
+    let mut count = 0;
+    while let Some(mount) = frozen.pop() {
+        match fs_ioctl(&mount, FITHAW) {
+            Ok(()) => count += 1,
+            Err(err) => eprintln!("yeet-agent: thaw {mount}: {err}"),
+        }
+    }
+    count
+}
+
+fn fs_ioctl(mount: &str, request: libc::c_ulong) -> io::Result<()> {
+    let dir = File::open(mount)?;
+    if unsafe { libc::ioctl(dir.as_raw_fd(), request as _, 0) } < 0 {
+        return Err(io::Error::last_os_error());
+    }
+    Ok(())
+}
+
+fn unsupported(err: &io::Error) -> bool {
+    matches!(
+        err.raw_os_error(),
+        Some(libc::EOPNOTSUPP) | Some(libc::ENOTTY) | Some(libc::EINVAL)
+    )
+}
+
+/// Returns the mount points of device-backed filesystems in mount order, once
+/// each. Pseudo filesystems have no block device to make consistent.
+pub fn freezable_mounts(raw: &str) -> Vec<String> {
+    let mut out: Vec<String> = Vec::new();
+    for line in raw.lines() {
+        let mut fields = line.split_whitespace();
+        let (Some(source), Some(target)) = (fields.next(), fields.next()) else {
+            continue;
+        };
+        if !source.starts_with("/dev/") {
+            continue;
+        }
+        let target = unescape_mount_field(target);
+        if !out.contains(&target) {
+            out.push(target);
+        }
+    }
+    out
+}
+
+// /proc/self/mounts escapes space, tab, newline and backslash as \ooo.
+fn unescape_mount_field(raw: &str) -> String {
+    let bytes = raw.as_bytes();
+    let mut out = Vec::with_capacity(bytes.len());
+    let mut i = 0;
+    while i < bytes.len() {
+        if bytes[i] == b'\\'
+            && i + 3 < bytes.len()
+            && bytes[i + 1..i + 4]
+                .iter()
+                .all(|b| (b'0'..=b'7').contains(b))
+        {
+            let value =
+                (bytes[i + 1] - b'0') * 64 + (bytes[i + 2] - b'0') * 8 + (bytes[i + 3] - b'0');
+            out.push(value);
+            i += 4;
+        } else {
+            out.push(bytes[i]);
+            i += 1;
+        }
+    }
+    String::from_utf8_lossy(&out).into_owned()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn lists_device_backed_mounts_once_in_mount_order() {
+        let raw = "\
+/dev/vda / ext4 rw,relatime 0 0
+proc /proc proc rw 0 0
+tmpfs /run tmpfs rw 0 0
+/dev/vdb /var/lib/data xfs rw 0 0
+/dev/vda / ext4 rw,relatime 0 0
+/dev/vdc /mnt/with\\040space ext4 rw 0 0
+";
+        assert_eq!(
+            freezable_mounts(raw),
+            vec!["/", "/var/lib/data", "/mnt/with space"]
+        );
+    }
+
+    #[test]
+    fn leaves_incomplete_escapes_alone() {
+        assert_eq!(unescape_mount_field("/mnt/a\\04"), "/mnt/a\\04");
+        assert_eq!(unescape_mount_field("/mnt/a\\134b"), "/mnt/a\\b");
+    }
+}
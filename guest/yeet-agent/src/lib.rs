@@ -6,6 +6,16 @@ use std::path::Path;
 use std::process::Command;
 use std::time::Duration;
 
+#[cfg(target_os = "linux")]
+mod exec;
+#[cfg(target_os = "linux")]
+mod files;
+mod frame;
+#[cfg(target_os = "linux")]
+mod freeze;
+mod metrics;
+mod power;
+
 #[cfg(target_os = "linux")]
 use std::fs::File;
 #[cfg(target_os = "linux")]
@@ -15,18 +25,46 @@ use std::mem;
 #[cfg(target_os = "linux")]
 use std::os::fd::{FromRawFd, RawFd};
 
-pub const PROTOCOL_VERSION: u32 = 1;
+pub use frame::{
+    FRAME_EOF, FRAME_EXIT, FRAME_RESIZE, FRAME_STDERR, FRAME_STDIN, FRAME_STDOUT, read_frame,
+    write_frame,
+};
+pub use metrics::GuestMetrics;
+
+pub const PROTOCOL_VERSION: u32 = 2;
+pub const MIN_PROTOCOL_VERSION: u32 = 1;
 pub const AGENT_PORT: u32 = 7788;
 
-#[derive(Debug, Deserialize)]
+// Protocol v2 request types. Each one takes over the connection after the
+// JSON request line; see the frame module for the wire format.
+const STREAM_REQUEST_TYPES: [&str; 5] = ["exec", "file_push", "file_pull", "fs_freeze", "fs_thaw"];
+
+// Written by the first-boot user-data script catch injects into the rootfs.
+const USER_DATA_STATUS_PATH: &str = "/var/lib/yeet-vm/user-data.status";
+
+#[derive(Debug, Default, Deserialize)]
 pub struct AgentRequest {
     pub protocol: u32,
     #[serde(rename = "type")]
     pub request_type: String,
     pub request_id: String,
+    #[serde(default)]
+    pub argv: Vec<String>,
+    #[serde(default)]
+    pub tty: bool,
+    #[serde(default)]
+    pub rows: u16,
+    #[serde(default)]
+    pub cols: u16,
+    #[serde(default)]
+    pub term: String,
+    #[serde(default)]
+    pub path: String,
+    #[serde(default)]
+    pub mode: u32,
 }
 
-#[derive(Debug, Serialize)]
+#[derive(Debug, Default, Serialize)]
 pub struct AgentResponse {
     pub protocol: u32,
     #[serde(rename = "type")]
@@ -37,10 +75,20 @@ pub struct AgentResponse {
     #[serde(skip_serializing_if = "Option::is_none")]
     pub ssh_ready: Option<bool>,
     #[serde(skip_serializing_if = "Option::is_none")]
+    pub user_data: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub size: Option<u64>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub mode: Option<u32>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub frozen: Option<usize>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub metrics: Option<GuestMetrics>,
+    #[serde(skip_serializing_if = "Option::is_none")]
     pub error: Option<AgentError>,
 }
 
-#[derive(Debug, Serialize)]
+#[derive(Debug, Default, Serialize)]
 pub struct AgentError {
     pub code: String,
     pub message: String,
@@ -115,7 +163,7 @@ fn usable_ipv4(raw: &str) -> Option<String> {
     Some(ip.to_string())
 }
 
-pub fn handle_one_request<R: BufRead, W: Write, F, S>(
+pub fn handle_one_request<R: BufRead + Send, W: Write + Send, F, S>(
     mut reader: R,
     mut writer: W,
     mut network_state: F,
@@ -135,67 +183,136 @@ where
     let req: AgentRequest = serde_json::from_str(&line)
         .map_err(|err| io::Error::new(io::ErrorKind::InvalidData, err))?;
 
-    let resp = if req.protocol != PROTOCOL_VERSION {
-        response_error(
+    let is_stream = STREAM_REQUEST_TYPES.contains(&req.request_type.as_str());
+    let resp = if !(MIN_PROTOCOL_VERSION..=PROTOCOL_VERSION).contains(&req.protocol) {
+        protocol_mismatch(
             &req,
-            "protocol_mismatch",
             format!(
-                "unsupported protocol version {}; expected {}",
-                req.protocol, PROTOCOL_VERSION
+                "unsupported protocol version {}; expected {}..{}",
+                req.protocol, MIN_PROTOCOL_VERSION, PROTOCOL_VERSION
             ),
         )
+    } else if is_stream && req.protocol < 2 {
+        protocol_mismatch(
+            &req,
+            format!("{} requires protocol version 2", req.request_type),
+        )
+    } else if is_stream {
+        return handle_stream_request(&req, reader, writer);
     } else {
         match req.request_type.as_str() {
             "network_state" => match network_state() {
                 Ok(interfaces) => AgentResponse {
-                    protocol: PROTOCOL_VERSION,
-                    response_type: req.request_type.clone(),
-                    request_id: req.request_id.clone(),
                     interfaces: usable_interfaces(&interfaces),
-                    ssh_ready: None,
-                    error: None,
+                    ..response_ok(&req)
                 },
                 Err(err) => response_error(&req, "network_state_failed", err.to_string()),
             },
             "guest_ready" => match network_state() {
                 Ok(interfaces) => AgentResponse {
-                    protocol: PROTOCOL_VERSION,
-                    response_type: req.request_type.clone(),
-                    request_id: req.request_id.clone(),
                     interfaces: usable_interfaces(&interfaces),
                     ssh_ready: Some(ssh_ready()),
-                    error: None,
+                    user_data: user_data_status(Path::new(USER_DATA_STATUS_PATH)),
+                    ..response_ok(&req)
                 },
                 Err(err) => response_error(&req, "network_state_failed", err.to_string()),
             },
-            "ping" | "hello" => AgentResponse {
-                protocol: PROTOCOL_VERSION,
-                response_type: req.request_type.clone(),
-                request_id: req.request_id.clone(),
-                interfaces: Vec::new(),
-                ssh_ready: None,
-                error: None,
+            "guest_metrics" => match metrics::collect() {
+                Ok(metrics) => AgentResponse {
+                    metrics: Some(metrics),
+                    ..response_ok(&req)
+                },
+                Err(err) => response_error(&req, "guest_metrics_failed", err.to_string()),
+            },
+            "shutdown" => match power::request_shutdown() {
+                Ok(()) => response_ok(&req),
+                Err(err) => response_error(&req, "shutdown_failed", err.to_string()),
             },
+            "ping" | "hello" => response_ok(&req),
             _ => response_error(&req, "unknown_request", "unknown request type".to_string()),
         }
     };
 
-    serde_json::to_writer(&mut writer, &resp).map_err(json_error_to_io)?;
+    write_response(&mut writer, &resp)
+}
+
+#[cfg(target_os = "linux")]
+fn handle_stream_request<R: BufRead + Send, W: Write + Send>(
+    req: &AgentRequest,
+    mut reader: R,
+    mut writer: W,
+) -> io::Result<()> {
+    match req.request_type.as_str() {
+        "exec" => exec::handle(req, reader, writer),
+        "file_push" => files::handle_push(req, &mut reader, &mut writer),
+        "file_pull" => files::handle_pull(req, &mut writer),
+        "fs_freeze" => {
+            let resp = match freeze::freeze() {
+                Ok(count) => AgentResponse {
+                    frozen: Some(count),
+                    ..response_ok(req)
+                },
+                Err(err) => response_error(req, "fs_freeze_failed", err.to_string()),
+            };
+            write_response(&mut writer, &resp)
+        }
+        "fs_thaw" => {
+            let resp = match freeze::thaw() {
+                Ok(count) => AgentResponse {
+                    frozen: Some(count),
+                    ..response_ok(req)
+                },
+                Err(err) => response_error(req, "fs_thaw_failed", err.to_string()),
+            };
+            write_response(&mut writer, &resp)
+        }
+        _ => unreachable!("not a stream request type"),
+    }
+}
+
+#[cfg(not(target_os = "linux"))]
+fn handle_stream_request<R: BufRead + Send, W: Write + Send>(
+    req: &AgentRequest,
+    _reader: R,
+    mut writer: W,
+) -> io::Result<()> {
+    let resp = response_error(
+        req,
+        "unsupported",
+        format!("{} requires Linux", req.request_type),
+    );
+    write_response(&mut writer, &resp)
+}
+
+pub(crate) fn write_response<W: Write>(writer: &mut W, resp: &AgentResponse) -> io::Result<()> {
+    serde_json::to_writer(&mut *writer, resp).map_err(json_error_to_io)?;
     writer.write_all(b"\n")?;
     writer.flush()
 }
 
-fn response_error(req: &AgentRequest, code: &str, message: String) -> AgentResponse {
+pub(crate) fn response_ok(req: &AgentRequest) -> AgentResponse {
     AgentResponse {
-        protocol: PROTOCOL_VERSION,
+        protocol: req.protocol,
         response_type: req.request_type.clone(),
         request_id: req.request_id.clone(),
-        interfaces: Vec::new(),
-        ssh_ready: None,
+        ..AgentResponse::default()
+    }
+}
+
+pub(crate) fn response_error(req: &AgentRequest, code: &str, message: String) -> AgentResponse {
+    AgentResponse {
         error: Some(AgentError {
             code: code.to_string(),
             message,
         }),
+        ..response_ok(req)
+    }
+}
+
+fn protocol_mismatch(req: &AgentRequest, message: String) -> AgentResponse {
+    AgentResponse {
+        protocol: PROTOCOL_VERSION,
+        ..response_error(req, "protocol_mismatch", message)
     }
 }
 
@@ -232,6 +349,17 @@ pub fn collect_network_state() -> io::Result<Vec<AgentInterface>> {
     Err(last_err.unwrap_or_else(|| io::Error::new(io::ErrorKind::NotFound, "no ip command found")))
 }
 
+/// Returns the first-boot user-data status, or None when the guest has no
+/// user-data or the script has not started.
+pub fn user_data_status(path: &Path) -> Option<String> {
+    let raw = fs::read_to_string(path).ok()?;
+    let status = raw.trim();
+    if status.is_empty() {
+        return None;
+    }
+    Some(status.to_string())
+}
+
 pub fn ssh_ready() -> bool {
     let addr = SocketAddrV4::new(Ipv4Addr::LOCALHOST, 22);
     TcpStream::connect_timeout(&addr.into(), Duration::from_millis(200)).is_ok()
@@ -304,9 +432,13 @@ pub fn serve_vsock_forever(_port: u32) -> io::Result<()> {
     loop {
         match accept_vsock(fd) {
             Ok(conn_fd) => {
-                if let Err(err) = handle_stream_fd(conn_fd) {
-                    eprintln!("yeet-agent: connection error: {err}");
-                }
+                // Exec sessions are long-lived, so each connection gets its own
+                // thread to keep network_state queries responsive.
+                std::thread::spawn(move || {
+                    if let Err(err) = handle_stream_fd(conn_fd) {
+                        eprintln!("yeet-agent: connection error: {err}");
+                    }
+                });
             }
             Err(err) if err.kind() == io::ErrorKind::Interrupted => {}
             Err(err) => return Err(err),
@@ -436,7 +568,7 @@ mod tests {
         .expect("handle request");
 
         let resp: Value = serde_json::from_slice(&out).expect("response json");
-        assert_eq!(resp["protocol"], PROTOCOL_VERSION);
+        assert_eq!(resp["protocol"], 1);
         assert_eq!(resp["type"], "network_state");
         assert_eq!(resp["request_id"], "r1");
         assert_eq!(resp["interfaces"][0]["name"], "eth0");
@@ -465,7 +597,7 @@ mod tests {
         .expect("handle request");
 
         let resp: Value = serde_json::from_slice(&out).expect("response json");
-        assert_eq!(resp["protocol"], PROTOCOL_VERSION);
+        assert_eq!(resp["protocol"], 1);
         assert_eq!(resp["type"], "guest_ready");
         assert_eq!(resp["request_id"], "r-ready");
         assert_eq!(resp["interfaces"][0]["name"], "eth0");
@@ -474,6 +606,21 @@ mod tests {
         assert!(resp.get("error").is_none());
     }
 
+    #[test]
+    fn reads_user_data_status() {
+        let dir = std::env::temp_dir().join(format!("yeet-agent-user-data-{}", std::process::id()));
+        fs::create_dir_all(&dir).expect("create temp dir");
+        let path = dir.join("user-data.status");
+
+        assert_eq!(user_data_status(&path), None);
+        fs::write(&path, "failed: runcmd 2\n").expect("write status");
+        assert_eq!(user_data_status(&path).as_deref(), Some("failed: runcmd 2"));
+        fs::write(&path, "\n").expect("write empty status");
+        assert_eq!(user_data_status(&path), None);
+
+        fs::remove_dir_all(&dir).expect("remove temp dir");
+    }
+
     #[test]
     fn handles_ping_and_hello_requests() {
         for request_type in ["ping", "hello"] {
@@ -486,7 +633,7 @@ mod tests {
                 .expect("handle request");
 
             let resp: Value = serde_json::from_slice(&out).expect("response json");
-            assert_eq!(resp["protocol"], PROTOCOL_VERSION);
+            assert_eq!(resp["protocol"], 1);
             assert_eq!(resp["type"], request_type);
             assert_eq!(resp["request_id"], "r2");
             assert!(resp.get("interfaces").is_none());
@@ -499,7 +646,7 @@ mod tests {
         let called = Cell::new(false);
         let mut out = Vec::new();
         handle_one_request(
-            br#"{"protocol":2,"type":"network_state","request_id":"r3"}
+            br#"{"protocol":3,"type":"network_state","request_id":"r3"}
 "#
             .as_slice(),
             &mut out,
@@ -523,7 +670,7 @@ mod tests {
     fn rejects_unknown_request_type() {
         let mut out = Vec::new();
         handle_one_request(
-            br#"{"protocol":1,"type":"exec","request_id":"r4"}
+            br#"{"protocol":1,"type":"reboot","request_id":"r4"}
 "#
             .as_slice(),
             &mut out,
@@ -533,11 +680,29 @@ mod tests {
         .expect("handle request");
 
         let resp: Value = serde_json::from_slice(&out).expect("response json");
-        assert_eq!(resp["type"], "exec");
+        assert_eq!(resp["type"], "reboot");
         assert_eq!(resp["request_id"], "r4");
         assert_eq!(resp["error"]["code"], "unknown_request");
     }
 
+    #[test]
+    fn rejects_stream_requests_on_protocol_v1() {
+        let mut out = Vec::new();
+        handle_one_request(
+            br#"{"protocol":1,"type":"exec","request_id":"r5","argv":["true"]}
+"#
+            .as_slice(),
+            &mut out,
+            || Ok(Vec::new()),
+            || false,
+        )
+        .expect("handle request");
+
+        let resp: Value = serde_json::from_slice(&out).expect("response json");
+        assert_eq!(resp["protocol"], PROTOCOL_VERSION);
+        assert_eq!(resp["error"]["code"], "protocol_mismatch");
+    }
+
     #[test]
     fn parses_ip_json_output() {
         let raw = br#"[
@@ -0,0 +1,139 @@
+//! Guest resource usage for the `guest_metrics` request.
+//!
+//! catch reads CPU and network counters from the host side; only the guest
+//! knows how much of its memory is really in use, how full its root
+//! filesystem is, and whether anyone is logged in over SSH.
+
+use serde::Serialize;
+use std::io;
+
+#[derive(Debug, Default, Clone, Eq, PartialEq, Serialize)]
+pub struct GuestMetrics {
+    pub memory_total_bytes: u64,
+    pub memory_available_bytes: u64,
+    pub root_total_bytes: u64,
+    pub root_available_bytes: u64,
+    pub ssh_sessions: u32,
+}
+
+const SSH_PORT: u16 = 22;
+const TCP_ESTABLISHED: &str = "01";
+
+pub fn collect() -> io::Result<GuestMetrics> {
+    let (memory_total_bytes, memory_available_bytes) =
+        parse_meminfo(&std::fs::read_to_string("/proc/meminfo")?)?;
+    let (root_total_bytes, root_available_bytes) = root_usage()?;
+    Ok(GuestMetrics {
+        memory_total_bytes,
+        memory_available_bytes,
+        root_total_bytes,
+        root_available_bytes,
+        ssh_sessions: ssh_sessions(),
+    })
+}
+
+/// Counts established connections to the SSH port. A guest without IPv6 has
+/// no /proc/net/tcp6, so unreadable tables count as empty.
+fn ssh_sessions() -> u32 {
+    ["/proc/net/tcp", "/proc/net/tcp6"]
+        .iter()
+        .filter_map(|path| std::fs::read_to_string(path).ok())
+        .map(|raw| count_established(&raw, SSH_PORT))
+        .sum()
+}
+
+/// Counts /proc/net/tcp rows in the ESTABLISHED state whose local port is
+/// `port`.
+pub fn count_established(raw: &str, port: u16) -> u32 {
+    let mut count = 0;
+    for line in raw.lines().skip(1) {
+        let mut fields = line.split_whitespace().skip(1);
+        let (Some(local), Some(_remote), Some(state)) =
+            (fields.next(), fields.next(), fields.next())
+        else {
+            continue;
+        };
+        let local_port = local
+            .rsplit_once(':')
+            .and_then(|(_, hex)| u16::from_str_radix(hex, 16).ok());
+        if state == TCP_ESTABLISHED && local_port == Some(port) {
+            count += 1;
+        }
+    }
+    count
+}
+
+/// Returns MemTotal and MemAvailable from /proc/meminfo in bytes.
+pub fn parse_meminfo(raw: &str) -> io::Result<(u64, u64)> {
+    let mut total = None;
+    let mut available = None;
+    for line in raw.lines() {
+        let Some((key, value)) = line.split_once(':') else {
+            continue;
+        };
+        let slot = match key.trim() {
+            "MemTotal" => &mut total,
+            "MemAvailable" => &mut available,
+            _ => continue,
+        };
+        let kib = value
+            .trim()
+            .trim_end_matches("kB")
+            .trim()
+            .parse::<u64>()
+            .map_err(|err| io::Error::new(io::ErrorKind::InvalidData, format!("{key}: {err}")))?;
+        *slot = Some(kib * 1024);
+    }
+    match (total, available) {
+        (Some(total), Some(available)) => Ok((total, available)),
+        _ => Err(io::Error::new(
+            io::ErrorKind::InvalidData,
+            "/proc/meminfo is missing MemTotal or MemAvailable",
+        )),
+    }
+}
+
+#[cfg(target_os = "linux")]
+fn root_usage() -> io::Result<(u64, u64)> {
+    let mut stat: libc::statvfs = unsafe { std::mem::zeroed() };
+    // SAFETY: the path is a NUL-terminated literal and stat is a valid out
+    // pointer for the duration of the call.
+    if unsafe { libc::statvfs(c"/".as_ptr(), &mut stat) } != 0 {
+        return Err(io::Error::last_os_error());
+    }
+    let block = stat.f_frsize as u64;
+    Ok((stat.f_blocks as u64 * block, stat.f_bavail as u64 * block))
+}
+
+#[cfg(not(target_os = "linux"))]
+fn root_usage() -> io::Result<(u64, u64)> {
+    Err(io::Error::new(
+        io::ErrorKind::Unsupported,
+        "root filesystem usage requires Linux",
+    ))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn parses_meminfo() {
+        let raw = "MemTotal:        2014256 kB\nMemFree:          112340 kB\nMemAvailable:    1500000 kB\n";
+        assert_eq!(
+            parse_meminfo(raw).unwrap(),
+            (2014256 * 1024, 1500000 * 1024)
+        );
+    }
+
+    #[test]
+    fn counts_established_ssh_connections() {
+        let raw = "  sl  local_address rem_address   st tx_queue rx_queue\n   0: 00000000:0016 00000000:0000 0A 00000000:00000000\n   1: 0C64A8C0:0016 0164A8C0:D431 01 00000000:00000000\n   2: 0C64A8C0:1F90 0164A8C0:D432 01 00000000:00000000\n";
+        assert_eq!(count_established(raw, 22), 1);
+    }
+
+    #[test]
+    fn rejects_meminfo_without_available() {
+        assert!(parse_meminfo("MemTotal: 1024 kB\n").is_err());
+    }
+}
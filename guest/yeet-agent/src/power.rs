@@ -0,0 +1,34 @@
+//! Clean guest shutdown for the `shutdown` request.
+//!
+//! Firecracker has no ACPI power button, but the guest boots with `reboot=k`
+//! so a reboot resets the VM and Firecracker exits. catch only sends this
+//! request while it is stopping the VM unit, so systemd does not restart it.
+
+use std::io;
+use std::path::Path;
+use std::process::{Command, Stdio};
+
+const REBOOT_COMMANDS: [(&str, &[&str]); 4] = [
+    ("/usr/bin/systemctl", &["reboot"]),
+    ("/run/current-system/sw/bin/systemctl", &["reboot"]),
+    ("/bin/systemctl", &["reboot"]),
+    ("/sbin/reboot", &[]),
+];
+
+/// Starts an orderly reboot and returns without waiting for it, so the agent
+/// can still answer the request.
+pub fn request_shutdown() -> io::Result<()> {
+    let (path, args) = REBOOT_COMMANDS
+        .iter()
+        .find(|(path, _)| Path::new(path).exists())
+        .ok_or_else(|| io::Error::new(io::ErrorKind::NotFound, "no reboot command found"))?;
+    let mut child = Command::new(path)
+        .args(*args)
+        .stdin(Stdio::null())
+        .stdout(Stdio::null())
+        .stderr(Stdio::null())
+        .spawn()
+        .map_err(|err| io::Error::new(err.kind(), format!("{path}: {err}")))?;
+    std::thread::spawn(move || child.wait());
+    Ok(())
+}
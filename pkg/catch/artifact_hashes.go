@@ -5,6 +5,7 @@
 package catch
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
@@ -13,8 +14,13 @@ import (
 
 	"github.com/yeetrun/yeet/pkg/catchrpc"
 	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/svc"
 )
 
+var dockerImageStatesFn = func(ctx context.Context, service *svc.DockerComposeService) ([]svc.DockerImageState, error) {
+	return service.ImageStates(ctx)
+}
+
 func (s *Server) artifactHashes(service string) (catchrpc.ArtifactHashesResponse, error) {
 	sv, err := s.serviceView(service)
 	if err != nil {
@@ -43,9 +49,54 @@ func (s *Server) artifactHashes(service string) (catchrpc.ArtifactHashesResponse
 			resp.Env = &catchrpc.ArtifactHash{Kind: "env file", SHA256: hash}
 		}
 	}
+	if sv.ServiceType() == db.ServiceTypeDockerCompose {
+		project, err := svc.ComposeProjectFromArtifacts(sv.AsStruct().Artifacts, sv.LatestGeneration())
+		if err != nil {
+			return catchrpc.ArtifactHashesResponse{}, err
+		}
+		hash, err := project.Hash()
+		if err != nil {
+			return catchrpc.ArtifactHashesResponse{}, err
+		}
+		if hash != "" {
+			resp.ComposeProject = &catchrpc.ArtifactHash{Kind: "compose project", SHA256: hash}
+		}
+	}
 	return resp, nil
 }
 
+// artifactImages reports the images of a docker compose service's running
+// containers so yeet run can tell whether an image or Dockerfile payload
+// would change anything. Other service types have no images.
+func (s *Server) artifactImages(ctx context.Context, service string) ([]catchrpc.ArtifactImage, error) {
+	sv, err := s.serviceView(service)
+	if err != nil {
+		return nil, err
+	}
+	if sv.ServiceType() != db.ServiceTypeDockerCompose {
+		return nil, nil
+	}
+	dc, err := s.dockerComposeService(service)
+	if err != nil {
+		return nil, err
+	}
+	states, err := dockerImageStatesFn(ctx, dc)
+	if err != nil {
+		return nil, err
+	}
+	images := make([]catchrpc.ArtifactImage, 0, len(states))
+	for _, state := range states {
+		images = append(images, catchrpc.ArtifactImage{
+			Container:     state.ContainerName,
+			Image:         state.Image,
+			RunningDigest: state.RunningDigest,
+			LatestDigest:  state.LatestDigest,
+			BuildHash:     state.Labels[catchrpc.ImageLabelBuildHash],
+		})
+	}
+	return images, nil
+}
+
 func payloadArtifactPath(sv db.ServiceView) (string, string) {
 	if !sv.Valid() {
 		return "", ""
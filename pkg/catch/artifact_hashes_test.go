@@ -5,14 +5,17 @@
 package catch
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/yeetrun/yeet/pkg/catchrpc"
 	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/svc"
 )
 
 func TestPayloadArtifactPath(t *testing.T) {
@@ -126,6 +129,42 @@ func TestArtifactHashesMissingService(t *testing.T) {
 	}
 }
 
+func TestArtifactImages(t *testing.T) {
+	server := newTestServer(t)
+	web := serviceWithArtifacts(db.ServiceTypeDockerCompose, nil)
+	web.Name = "web"
+	api := serviceWithArtifacts(db.ServiceTypeSystemd, nil)
+	api.Name = "api"
+	if err := server.cfg.DB.Set(&db.Data{Services: map[string]*db.Service{"web": web, "api": api}}); err != nil {
+		t.Fatal(err)
+	}
+	old := dockerImageStatesFn
+	t.Cleanup(func() { dockerImageStatesFn = old })
+	dockerImageStatesFn = func(_ context.Context, service *svc.DockerComposeService) ([]svc.DockerImageState, error) {
+		if service.Name != "web" {
+			t.Fatalf("image states for %q, want web", service.Name)
+		}
+		return []svc.DockerImageState{{
+			ContainerName: "web",
+			Image:         "catchit.dev/web:latest",
+			RunningDigest: "sha256:run",
+			Labels:        map[string]string{catchrpc.ImageLabelBuildHash: "abc", "other": "x"},
+		}}, nil
+	}
+
+	got, err := server.artifactImages(context.Background(), "web")
+	if err != nil {
+		t.Fatalf("artifactImages: %v", err)
+	}
+	want := []catchrpc.ArtifactImage{{Container: "web", Image: "catchit.dev/web:latest", RunningDigest: "sha256:run", BuildHash: "abc"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("artifactImages = %#v, want %#v", got, want)
+	}
+	if got, err := server.artifactImages(context.Background(), "api"); err != nil || got != nil {
+		t.Fatalf("systemd artifactImages = %#v, %v; want none", got, err)
+	}
+}
+
 func TestHashFileSHA256(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "payload")
@@ -174,11 +174,13 @@ func (s *Server) callerPermissions(ctx context.Context, remoteAddr string) (perm
 
 func rpcMethodPermissions(method string) (permissionSet, error) {
 	switch method {
-	case "catch.Info", "catch.ServiceInfo", "catch.ArtifactHashes", "catch.ZFSServiceRootCandidates", catchrpc.RPCMethodServiceRootDefaults, "catch.VMDefaults", "catch.ServicesList":
+	case "catch.Info", "catch.ServiceInfo", "catch.ArtifactHashes", catchrpc.RPCMethodBuildContextMissing, "catch.ZFSServiceRootCandidates", catchrpc.RPCMethodServiceRootDefaults, "catch.VMDefaults", "catch.ServicesList":
 		return newPermissionSet(permissionRead), nil
 	case catchrpc.RPCMethodHostStoragePlan, catchrpc.RPCMethodHostStorageApply,
 		catchrpc.RPCMethodHostStorageFinalize, catchrpc.RPCMethodHostStorageCleanup,
-		catchrpc.RPCMethodISOPoolPlan, catchrpc.RPCMethodISOPoolApply:
+		catchrpc.RPCMethodISOPoolPlan, catchrpc.RPCMethodISOPoolApply,
+		catchrpc.RPCMethodRegistryMirrorSet, catchrpc.RPCMethodContainerRuntimeSet,
+		catchrpc.RPCMethodImagePolicySet:
 		return newPermissionSet(permissionManage), nil
 	case "catch.TailscaleSetup":
 		return newPermissionSet(permissionRead, permissionManage, permissionSSH), nil
@@ -0,0 +1,363 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+	"github.com/yeetrun/yeet/pkg/cli"
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/svc"
+)
+
+// Scheduled image updates check a compose service's upstream digests once per
+// window. In apply mode the update runs like `docker update`, under the
+// docker-update snapshot event, and the previous images are put back when the
+// service does not come up healthy.
+const (
+	autoUpdateDefaultWindow = "03:00"
+	// autoUpdateWindowSlack is how late a check may still start, so a catch
+	// that was down during the window does not update at an odd hour.
+	autoUpdateWindowSlack  = time.Hour
+	autoUpdateHistoryLimit = 10
+
+	autoUpdateResultAvailable  = "available"
+	autoUpdateResultUpdated    = "updated"
+	autoUpdateResultRolledBack = "rolled-back"
+	autoUpdateResultFailed     = "failed"
+)
+
+// autoUpdateCompose is the part of svc.DockerComposeService an update uses.
+type autoUpdateCompose interface {
+	imagePolicyUpdater
+	Update() error
+	Health(ctx context.Context) (bool, string, error)
+}
+
+var (
+	autoUpdateCheckInterval = time.Minute
+	autoUpdateHealthTimeout = 2 * time.Minute
+	// autoUpdateHealthSettle is how long the service must stay healthy
+	// before an update counts as done, so crash loops are caught.
+	autoUpdateHealthSettle = 15 * time.Second
+	autoUpdateHealthPoll   = 5 * time.Second
+	autoUpdateOutdatedFn   = func(ctx context.Context, s *Server, name string) ([]svc.DockerOutdatedRow, error) {
+		return s.DockerComposeOutdated(ctx, name, svc.DockerOutdatedOptions{})
+	}
+	autoUpdateComposeFn = func(s *Server, name string) (autoUpdateCompose, error) {
+		return s.dockerComposeService(name)
+	}
+)
+
+// errAutoUpdateRolledBack marks an update whose previous images were
+// restored.
+var errAutoUpdateRolledBack = errors.New("previous images restored")
+
+// setServiceAutoUpdate applies `service set --auto-update` and
+// --auto-update-window. Turning updates off drops the policy and its history.
+func (s *Server) setServiceAutoUpdate(name, mode, window string) error {
+	_, _, err := s.cfg.DB.MutateService(name, func(_ *db.Data, service *db.Service) error {
+		if service.ServiceType != db.ServiceTypeDockerCompose {
+			return fmt.Errorf("--auto-update applies only to docker compose services")
+		}
+		if mode == cli.AutoUpdateOff {
+			service.AutoUpdate = nil
+			return nil
+		}
+		cfg := db.AutoUpdateConfig{}
+		if service.AutoUpdate != nil {
+			cfg = *service.AutoUpdate
+		}
+		if mode != "" {
+			cfg.Mode = mode
+		}
+		if window != "" {
+			cfg.Window = window
+		}
+		if cfg.Mode == "" {
+			return fmt.Errorf("--auto-update-window requires --auto-update=notify or apply")
+		}
+		service.AutoUpdate = &cfg
+		return nil
+	})
+	return err
+}
+
+func serviceAutoUpdateInfo(sv db.ServiceView) *catchrpc.ServiceAutoUpdate {
+	cfg := sv.AutoUpdate()
+	if !cfg.Valid() {
+		return nil
+	}
+	info := &catchrpc.ServiceAutoUpdate{
+		Mode:      cfg.Mode(),
+		Window:    autoUpdateWindowValue(cfg.Window()),
+		LastCheck: cfg.LastCheck(),
+	}
+	for _, result := range cfg.History().All() {
+		info.History = append(info.History, autoUpdateResultInfo(result))
+	}
+	return info
+}
+
+func autoUpdateResultInfo(result db.AutoUpdateResult) catchrpc.AutoUpdateResult {
+	return catchrpc.AutoUpdateResult{
+		Time:   result.Time,
+		Result: result.Result,
+		Images: result.Images,
+		Error:  result.Error,
+	}
+}
+
+func autoUpdateWindowValue(window string) string {
+	if window == "" {
+		return autoUpdateDefaultWindow
+	}
+	return window
+}
+
+// autoUpdateWindowStart returns the most recent start of w at or before now.
+func autoUpdateWindowStart(w cli.AutoUpdateWindow, now time.Time) time.Time {
+	start := time.Date(now.Year(), now.Month(), now.Day(), w.Hour, w.Minute, 0, 0, now.Location())
+	if start.After(now) {
+		start = start.AddDate(0, 0, -1)
+	}
+	for !w.Daily && start.Weekday() != w.Weekday {
+		start = start.AddDate(0, 0, -1)
+	}
+	return start
+}
+
+// autoUpdateDue reports whether a check should start now: the window opened
+// less than autoUpdateWindowSlack ago and no check ran since it opened.
+func autoUpdateDue(cfg db.AutoUpdateConfigView, now time.Time) (bool, error) {
+	w, err := cli.ParseAutoUpdateWindow(autoUpdateWindowValue(cfg.Window()))
+	if err != nil {
+		return false, fmt.Errorf("invalid auto-update window %q: %w", cfg.Window(), err)
+	}
+	start := autoUpdateWindowStart(w, now)
+	if now.Sub(start) >= autoUpdateWindowSlack {
+		return false, nil
+	}
+	if last, err := time.Parse(time.RFC3339, cfg.LastCheck()); err == nil && !last.Before(start) {
+		return false, nil
+	}
+	return true, nil
+}
+
+func (s *Server) runAutoUpdater(ctx context.Context) {
+	ticker := time.NewTicker(autoUpdateCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.checkAutoUpdates(ctx, time.Now()); err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf("auto-update check failed: %v", err)
+			}
+		}
+	}
+}
+
+// checkAutoUpdates runs the checks whose window is open, one service at a
+// time so updates do not all pull at once.
+func (s *Server) checkAutoUpdates(ctx context.Context, now time.Time) error {
+	dv, err := s.getDB()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for name, sv := range dv.Services().All() {
+		cfg := sv.AutoUpdate()
+		if sv.ServiceType() != db.ServiceTypeDockerCompose || !cfg.Valid() {
+			continue
+		}
+		due, err := autoUpdateDue(cfg, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		if !due {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		errs = append(errs, s.runAutoUpdate(ctx, name, cfg.Mode(), now))
+	}
+	return errors.Join(errs...)
+}
+
+// runAutoUpdate checks one service and records the outcome when there was
+// something to report. LastCheck is stored first so a crash mid-update does
+// not retry within the same window.
+func (s *Server) runAutoUpdate(ctx context.Context, name, mode string, now time.Time) error {
+	if err := s.mutateAutoUpdate(name, func(cfg *db.AutoUpdateConfig) {
+		cfg.LastCheck = now.UTC().Format(time.RFC3339)
+	}); err != nil {
+		return err
+	}
+	result, ok := s.autoUpdateService(ctx, name, mode)
+	if !ok {
+		return nil
+	}
+	result.Time = time.Now().UTC().Format(time.RFC3339)
+	return s.recordAutoUpdateResult(name, result)
+}
+
+func (s *Server) autoUpdateService(ctx context.Context, name, mode string) (db.AutoUpdateResult, bool) {
+	rows, err := autoUpdateOutdatedFn(ctx, s, name)
+	if err != nil {
+		return db.AutoUpdateResult{Result: autoUpdateResultFailed, Error: err.Error()}, true
+	}
+	var images, problems []string
+	for _, row := range rows {
+		switch row.Status {
+		case svc.DockerOutdatedUpdateAvailable:
+			images = append(images, row.ContainerName+" "+row.Image)
+		case svc.DockerOutdatedError:
+			problems = append(problems, row.ContainerName+": "+row.Reason)
+		}
+	}
+	if len(images) == 0 {
+		if len(problems) == 0 {
+			return db.AutoUpdateResult{}, false
+		}
+		return db.AutoUpdateResult{Result: autoUpdateResultFailed, Error: strings.Join(problems, "; ")}, true
+	}
+	result := db.AutoUpdateResult{Result: autoUpdateResultAvailable, Images: strings.Join(images, ", ")}
+	if mode != cli.AutoUpdateApply {
+		return result, true
+	}
+	err = s.applyAutoUpdate(ctx, name)
+	switch {
+	case err == nil:
+		result.Result = autoUpdateResultUpdated
+	case errors.Is(err, errAutoUpdateRolledBack):
+		result.Result, result.Error = autoUpdateResultRolledBack, err.Error()
+	default:
+		result.Result, result.Error = autoUpdateResultFailed, err.Error()
+	}
+	return result, true
+}
+
+// applyAutoUpdate pulls and recreates the service, then waits for it to come
+// up. If it does not, the images it ran before are tagged and started again;
+// the recovery snapshot, when policy takes one, is left for a manual restore.
+func (s *Server) applyAutoUpdate(ctx context.Context, name string) error {
+	release := s.serviceOperationLocks.Lock(name)
+	defer release()
+	if err := s.checkServiceIdentityMutationAllowed(name); err != nil {
+		return err
+	}
+	sv, err := s.serviceView(name)
+	if err != nil {
+		return err
+	}
+	compose, err := autoUpdateComposeFn(s, name)
+	if err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	err = s.withServiceSnapshot(ctx, snapshotOperation{
+		Service: sv.AsStruct(),
+		Event:   snapshotEventDockerUpdate,
+		Writer:  &out,
+		Operation: func() error {
+			previous, err := compose.ImageIDs(ctx)
+			if err != nil {
+				return err
+			}
+			if err := s.updateCompose(ctx, name, compose, compose.Update); err != nil {
+				return rollbackAutoUpdate(ctx, compose, previous, err)
+			}
+			if err := waitAutoUpdateHealthy(ctx, compose); err != nil {
+				return rollbackAutoUpdate(ctx, compose, previous, err)
+			}
+			return nil
+		},
+	})
+	if msg := strings.TrimSpace(out.String()); msg != "" {
+		log.Printf("auto-update %s: %s", name, msg)
+	}
+	return err
+}
+
+func rollbackAutoUpdate(ctx context.Context, compose autoUpdateCompose, previous map[string]string, cause error) error {
+	if len(previous) == 0 {
+		return cause
+	}
+	if err := compose.RestoreImages(ctx, previous); err != nil {
+		return fmt.Errorf("%w; rollback failed: %v", cause, err)
+	}
+	return fmt.Errorf("%w; %w", cause, errAutoUpdateRolledBack)
+}
+
+// waitAutoUpdateHealthy waits until every container has been running, and
+// healthy where it has a health check, for autoUpdateHealthSettle.
+func waitAutoUpdateHealthy(ctx context.Context, compose autoUpdateCompose) error {
+	deadline := time.Now().Add(autoUpdateHealthTimeout)
+	var healthySince time.Time
+	detail := "containers did not settle"
+	for {
+		ok, d, err := compose.Health(ctx)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		switch {
+		case !ok:
+			healthySince, detail = time.Time{}, d
+		case healthySince.IsZero():
+			healthySince = now
+		}
+		if !healthySince.IsZero() && now.Sub(healthySince) >= autoUpdateHealthSettle {
+			return nil
+		}
+		if now.After(deadline) {
+			return fmt.Errorf("service did not come back up: %s", detail)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(autoUpdateHealthPoll):
+		}
+	}
+}
+
+func (s *Server) recordAutoUpdateResult(name string, result db.AutoUpdateResult) error {
+	err := s.mutateAutoUpdate(name, func(cfg *db.AutoUpdateConfig) {
+		cfg.History = append(cfg.History, result)
+		if extra := len(cfg.History) - autoUpdateHistoryLimit; extra > 0 {
+			cfg.History = append([]db.AutoUpdateResult(nil), cfg.History[extra:]...)
+		}
+	})
+	msg := fmt.Sprintf("auto-update %s: %s %s", name, result.Result, result.Images)
+	if result.Error != "" {
+		msg += ": " + result.Error
+	}
+	log.Print(msg)
+	s.PublishEvent(Event{Type: EventTypeServiceAutoUpdate, ServiceName: name, Data: EventData{autoUpdateResultInfo(result)}})
+	return err
+}
+
+// mutateAutoUpdate edits the stored policy. It does nothing when the policy
+// was removed meanwhile.
+func (s *Server) mutateAutoUpdate(name string, fn func(*db.AutoUpdateConfig)) error {
+	_, _, err := s.cfg.DB.MutateService(name, func(_ *db.Data, service *db.Service) error {
+		if service.AutoUpdate != nil {
+			fn(service.AutoUpdate)
+		}
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,229 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yeetrun/yeet/pkg/cli"
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/svc"
+)
+
+type fakeAutoUpdateCompose struct {
+	healthy   bool
+	updateErr error
+	calls     []string
+	images    []string
+	digests   map[string]string
+}
+
+func (f *fakeAutoUpdateCompose) ImageIDs(context.Context) (map[string]string, error) {
+	f.calls = append(f.calls, "ids")
+	return map[string]string{"nginx:latest": "sha256:old"}, nil
+}
+
+func (f *fakeAutoUpdateCompose) Update() error {
+	f.calls = append(f.calls, "update")
+	return f.updateErr
+}
+
+func (f *fakeAutoUpdateCompose) PullContext(context.Context) error {
+	f.calls = append(f.calls, "pull")
+	return nil
+}
+
+func (f *fakeAutoUpdateCompose) DeclaredImages(context.Context) ([]string, error) {
+	return f.images, nil
+}
+
+func (f *fakeAutoUpdateCompose) LocalImageDigest(_ context.Context, image string) (string, error) {
+	return f.digests[image], nil
+}
+
+func (f *fakeAutoUpdateCompose) UpDetached(context.Context, bool) error {
+	f.calls = append(f.calls, "up")
+	return nil
+}
+
+func (f *fakeAutoUpdateCompose) Health(context.Context) (bool, string, error) {
+	f.calls = append(f.calls, "health")
+	return f.healthy, "web is restarting", nil
+}
+
+func (f *fakeAutoUpdateCompose) RestoreImages(_ context.Context, ids map[string]string) error {
+	f.calls = append(f.calls, "restore "+ids["nginx:latest"])
+	return nil
+}
+
+func addTestComposeService(t *testing.T, server *Server, name string, cfg *db.AutoUpdateConfig) {
+	t.Helper()
+	_, _, err := server.cfg.DB.MutateService(name, func(_ *db.Data, service *db.Service) error {
+		service.ServiceType = db.ServiceTypeDockerCompose
+		service.AutoUpdate = cfg
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func withFakeAutoUpdate(t *testing.T, rows []svc.DockerOutdatedRow, compose *fakeAutoUpdateCompose) {
+	t.Helper()
+	oldOutdated, oldCompose := autoUpdateOutdatedFn, autoUpdateComposeFn
+	oldTimeout, oldSettle, oldPoll := autoUpdateHealthTimeout, autoUpdateHealthSettle, autoUpdateHealthPoll
+	t.Cleanup(func() {
+		autoUpdateOutdatedFn, autoUpdateComposeFn = oldOutdated, oldCompose
+		autoUpdateHealthTimeout, autoUpdateHealthSettle, autoUpdateHealthPoll = oldTimeout, oldSettle, oldPoll
+	})
+	autoUpdateOutdatedFn = func(context.Context, *Server, string) ([]svc.DockerOutdatedRow, error) { return rows, nil }
+	autoUpdateComposeFn = func(*Server, string) (autoUpdateCompose, error) { return compose, nil }
+	autoUpdateHealthTimeout, autoUpdateHealthSettle, autoUpdateHealthPoll = 0, 0, 0
+}
+
+func TestServiceSetAutoUpdate(t *testing.T) {
+	server := newTestServer(t)
+	addTestComposeService(t, server, "web", nil)
+	execer := &ttyExecer{s: server, sn: "web", rw: &bytes.Buffer{}, isPty: false}
+
+	if err := execer.serviceSetCmdFunc(cli.ServiceSetFlags{AutoUpdateWindow: "Sun 03:00"}); err == nil || !strings.Contains(err.Error(), "requires --auto-update") {
+		t.Fatalf("window without policy error = %v", err)
+	}
+	if err := execer.serviceSetCmdFunc(cli.ServiceSetFlags{AutoUpdate: "apply", AutoUpdateWindow: "Sun 03:00"}); err != nil {
+		t.Fatalf("serviceSetCmdFunc: %v", err)
+	}
+	if err := execer.serviceSetCmdFunc(cli.ServiceSetFlags{AutoUpdate: "notify"}); err != nil {
+		t.Fatalf("serviceSetCmdFunc: %v", err)
+	}
+	sv, _ := server.serviceView("web")
+	if got := sv.AutoUpdate().AsStruct(); got == nil || got.Mode != "notify" || got.Window != "Sun 03:00" {
+		t.Fatalf("AutoUpdate = %#v, want notify on Sun 03:00", got)
+	}
+	if err := execer.serviceSetCmdFunc(cli.ServiceSetFlags{AutoUpdate: "off"}); err != nil {
+		t.Fatalf("serviceSetCmdFunc: %v", err)
+	}
+	if sv, _ := server.serviceView("web"); sv.AutoUpdate().Valid() {
+		t.Fatal("AutoUpdate still set after off")
+	}
+
+	addTestVMService(t, server, "devbox", nil)
+	execer.sn = "devbox"
+	if err := execer.serviceSetCmdFunc(cli.ServiceSetFlags{AutoUpdate: "apply"}); err == nil || !strings.Contains(err.Error(), "docker compose") {
+		t.Fatalf("VM auto-update error = %v, want compose-only error", err)
+	}
+}
+
+func TestAutoUpdateDue(t *testing.T) {
+	// 2026-10-18 is a Sunday.
+	at := func(day, hour, minute int) time.Time { return time.Date(2026, 10, day, hour, minute, 0, 0, time.Local) }
+	tests := []struct {
+		name   string
+		window string
+		last   time.Time
+		now    time.Time
+		want   bool
+	}{
+		{name: "weekly open", window: "Sun 03:00", now: at(18, 3, 0), want: true},
+		{name: "weekly wrong day", window: "Sat 03:00", now: at(18, 3, 0)},
+		{name: "after slack", window: "Sun 03:00", now: at(18, 4, 0)},
+		{name: "already checked", window: "Sun 03:00", last: at(18, 3, 0), now: at(18, 3, 30)},
+		{name: "checked last week", window: "Sun 03:00", last: at(11, 3, 0), now: at(18, 3, 30), want: true},
+		{name: "default daily", now: at(16, 3, 10), want: true},
+		{name: "before window", window: "23:30", now: at(16, 23, 29)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := db.AutoUpdateConfig{Mode: "notify", Window: tc.window}
+			if !tc.last.IsZero() {
+				cfg.LastCheck = tc.last.UTC().Format(time.RFC3339)
+			}
+			got, err := autoUpdateDue(cfg.View(), tc.now)
+			if err != nil || got != tc.want {
+				t.Fatalf("autoUpdateDue = %v, %v; want %v", got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestCheckAutoUpdatesAppliesAndRollsBack(t *testing.T) {
+	rows := []svc.DockerOutdatedRow{
+		{ServiceName: "web", ContainerName: "web", Image: "nginx:latest", Status: svc.DockerOutdatedUpdateAvailable},
+		{ServiceName: "web", ContainerName: "cache", Image: "redis:7", Status: svc.DockerOutdatedCurrent},
+	}
+	now := time.Date(2026, 10, 18, 3, 5, 0, 0, time.Local)
+	tests := []struct {
+		name      string
+		mode      string
+		compose   fakeAutoUpdateCompose
+		result    string
+		wantCalls []string
+	}{
+		{name: "notify", mode: "notify", result: autoUpdateResultAvailable},
+		{name: "updated", mode: "apply", compose: fakeAutoUpdateCompose{healthy: true}, result: autoUpdateResultUpdated, wantCalls: []string{"ids", "update", "health"}},
+		{name: "unhealthy", mode: "apply", result: autoUpdateResultRolledBack, wantCalls: []string{"ids", "update", "health", "restore sha256:old"}},
+		{name: "update failed", mode: "apply", compose: fakeAutoUpdateCompose{updateErr: errors.New("pull failed")}, result: autoUpdateResultRolledBack, wantCalls: []string{"ids", "update", "restore sha256:old"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t)
+			addTestComposeService(t, server, "web", &db.AutoUpdateConfig{Mode: tc.mode})
+			withFakeAutoUpdate(t, rows, &tc.compose)
+			events := make(chan Event, 1)
+			h := server.AddEventListener(events, func(e Event) bool { return e.Type == EventTypeServiceAutoUpdate })
+			defer server.RemoveEventListener(h)
+
+			if err := server.checkAutoUpdates(context.Background(), now); err != nil {
+				t.Fatalf("checkAutoUpdates: %v", err)
+			}
+			if !reflect.DeepEqual(tc.compose.calls, tc.wantCalls) {
+				t.Fatalf("compose calls = %q, want %q", tc.compose.calls, tc.wantCalls)
+			}
+			sv, _ := server.serviceView("web")
+			cfg := sv.AutoUpdate().AsStruct()
+			if cfg.LastCheck != now.UTC().Format(time.RFC3339) || len(cfg.History) != 1 {
+				t.Fatalf("AutoUpdate = %#v, want one result checked at %v", cfg, now)
+			}
+			if got := cfg.History[0]; got.Result != tc.result || got.Images != "web nginx:latest" {
+				t.Fatalf("result = %#v, want %s for web nginx:latest", got, tc.result)
+			}
+			select {
+			case e := <-events:
+				if e.ServiceName != "web" {
+					t.Fatalf("event = %#v", e)
+				}
+			default:
+				t.Fatal("no auto-update event published")
+			}
+
+			if err := server.checkAutoUpdates(context.Background(), now.Add(time.Minute)); err != nil {
+				t.Fatalf("second checkAutoUpdates: %v", err)
+			}
+			if sv, _ := server.serviceView("web"); sv.AutoUpdate().History().Len() != 1 {
+				t.Fatal("checked twice in one window")
+			}
+		})
+	}
+}
+
+func TestRecordAutoUpdateResultKeepsRecentHistory(t *testing.T) {
+	server := newTestServer(t)
+	addTestComposeService(t, server, "web", &db.AutoUpdateConfig{Mode: "notify"})
+	for i := range autoUpdateHistoryLimit + 2 {
+		if err := server.recordAutoUpdateResult("web", db.AutoUpdateResult{Result: autoUpdateResultAvailable, Images: strings.Repeat("x", i+1)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sv, _ := server.serviceView("web")
+	history := sv.AutoUpdate().AsStruct().History
+	if len(history) != autoUpdateHistoryLimit || history[0].Images != "xxx" {
+		t.Fatalf("history = %d entries starting %q, want %d starting xxx", len(history), history[0].Images, autoUpdateHistoryLimit)
+	}
+}
@@ -90,6 +90,7 @@ type Server struct {
 	newDockerComposeService            func(sv db.ServiceView) (dockerNetNSReconciler, error)
 	serviceRootDirFunc                 func(string) (string, error)
 	zfsRunner                          zfsCommandRunner
+	xfsQuotaRunner                     xfsQuotaRunner
 	serviceOperationLocks              serviceOperationLocks
 	serviceIdentityRecoveryMu          sync.RWMutex
 	serviceIdentityMutationBlocks      map[string]error
@@ -107,6 +108,9 @@ type Server struct {
 	vmRuntimeTrialDeps                 *vmRuntimeTrialConsumerDeps
 	vmRuntimeRestartDeps               *vmRuntimeRestartDeps
 	vmRuntimeRestartLocks              sync.Map
+	vmConsoleWriters                   sync.Map
+	remoteBuildLocks                   sync.Map
+	registryMirror                     registryMirrorState
 }
 
 type vmRuntimeRecoveryBarrier struct {
@@ -129,6 +133,7 @@ const (
 	EventTypeServiceCreated       EventType = "ServiceCreated"
 	EventTypeServiceConfigChanged EventType = "ServiceConfigChanged"
 	EventTypeServiceConfigStaged  EventType = "ServiceConfigStaged"
+	EventTypeServiceAutoUpdate    EventType = "ServiceAutoUpdate"
 )
 
 type EventData struct {
@@ -191,6 +196,7 @@ type Config struct {
 	MountsRoot           string
 	InternalRegistryAddr string
 	ExternalRegistryAddr string
+	RegistryMirrorAddr   string
 	RegistryRoot         string
 	ContainerdSocket     string
 	RegistryStorage      registry.Storage
@@ -218,14 +224,7 @@ func NewUnstartedServer(config *Config) *Server {
 	}
 	s.registry = s.newRegistry()
 	s.newDockerComposeService = func(sv db.ServiceView) (dockerNetNSReconciler, error) {
-		root := s.serviceRootFromView(sv)
-		return svc.NewDockerComposeService(
-			s.cfg.DB,
-			sv,
-			serviceDataDirForRoot(root),
-			serviceRunDirForRoot(root),
-			svc.WithTailscaleGuardRunner(s.catchRunnerPath()),
-		)
+		return s.newDockerComposeServiceAt(sv, s.serviceRootFromView(sv))
 	}
 	s.recoverVMRuntimeState = s.recoverVMRuntimeAdoptions
 	s.acquireCatchInstallLock = func(ctx context.Context, dataRoot string) (io.Closer, error) {
@@ -296,6 +295,14 @@ func (s *Server) Start() {
 		}
 		s.runVMBalloonController(s.ctx)
 	})
+	s.waitGroup.Go(func() {
+		if !runtimeRecovery.Wait(s.ctx) {
+			return
+		}
+		s.runVMIdleStopper(s.ctx)
+	})
+	s.waitGroup.Go(func() { s.runAutoUpdater(s.ctx) })
+	s.waitGroup.Go(func() { s.runRegistryGC(s.ctx) })
 	if err := s.checkTailscaleResolverMutationAllowed(); err != nil {
 		log.Printf("network runtime startup reconciliation blocked: %v", err)
 	} else if err := s.prepareNetworkRuntime(s.ctx); err != nil {
@@ -375,7 +382,7 @@ func (s *Server) isoRecordKinds() (hasISO bool, requiresDocker bool, err error)
 }
 
 func waitDockerReadyForISO(ctx context.Context) error {
-	docker, err := svc.DockerCmd()
+	docker, err := svc.ContainerRuntimeDocker.LookPath()
 	if err != nil {
 		return err
 	}
@@ -454,8 +461,12 @@ func (s *Server) reconcileRuntimeState() {
 	logRuntimeReconcileError("tailscale DNS config reconciliation failed", s.reconcileTailscaleDNSConfigs(s.ctx))
 	logRuntimeReconcileError("netns reconciliation failed", s.reconcileNetNSBackedDockerServices(s.ctx))
 	logRuntimeReconcileError("tailscale sidecar verification failed", s.reconcileTailscaleResolverMounts(s.ctx))
-	logRuntimeReconcileError("docker netns NAT reconciliation failed", reconcileDockerNetNSPortForwards(s.cfg.DB))
+	if !s.containerRuntime().IsPodman() {
+		// Port forwards come from the yeet Docker network plugin.
+		logRuntimeReconcileError("docker netns NAT reconciliation failed", reconcileDockerNetNSPortForwards(s.cfg.DB))
+	}
 	logRuntimeReconcileError("VM network reconciliation failed", s.reconcileVMNetworks(s.ctx))
+	logRuntimeReconcileError("VM power unit reconciliation failed", s.syncVMPowerUnits())
 }
 
 func logRuntimeReconcileError(message string, err error) {
@@ -520,7 +531,16 @@ func (s *Server) dockerComposeService(sn string) (*svc.DockerComposeService, err
 	if !ok {
 		return nil, errServiceNotFound
 	}
-	root := s.serviceRootFromView(sv)
+	service, err := s.newDockerComposeServiceAt(sv, s.serviceRootFromView(sv))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load service: %v", err)
+	}
+	return service, nil
+}
+
+// newDockerComposeServiceAt loads the compose project of sv rooted at root,
+// with its restart gate when the project waits for mounts.
+func (s *Server) newDockerComposeServiceAt(sv db.ServiceView, root string) (*svc.DockerComposeService, error) {
 	service, err := svc.NewDockerComposeService(
 		s.cfg.DB,
 		sv,
@@ -529,7 +549,10 @@ func (s *Server) dockerComposeService(sn string) (*svc.DockerComposeService, err
 		svc.WithTailscaleGuardRunner(s.catchRunnerPath()),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load service: %v", err)
+		return nil, err
+	}
+	if service.RestartGate, err = writeComposeRestartGate(sv, serviceRunDirForRoot(root)); err != nil {
+		return nil, err
 	}
 	return service, nil
 }
@@ -1488,6 +1511,11 @@ func (s *Server) cleanupVMNetworkForRemoval(report *RemoveReport, name string) {
 	if service.ServiceType != db.ServiceTypeVM || service.VM == nil {
 		return
 	}
+	if len(service.Publish) != 0 {
+		if err := s.syncVMPublishedPorts(name); err != nil {
+			report.addWarning(err)
+		}
+	}
 	plan := vmNetworkPlanFromDB(name, service.VM.Networks)
 	if len(plan.Interfaces) == 0 {
 		return
@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/yeetrun/yeet/pkg/netns"
+	"github.com/yeetrun/yeet/pkg/svc"
 	"gopkg.in/yaml.v3"
 )
 
@@ -19,13 +20,14 @@ type composeDNSService struct {
 }
 
 type composeDNSOverlayService struct {
-	DNS       []string `yaml:"dns,omitempty"`
-	DNSSearch []string `yaml:"dns_search,omitempty"`
+	NetworkMode string   `yaml:"network_mode,omitempty"`
+	DNS         []string `yaml:"dns,omitempty"`
+	DNSSearch   []string `yaml:"dns_search,omitempty"`
 }
 
 type composeNetworkOverlay struct {
 	Services map[string]composeDNSOverlayService `yaml:"services,omitempty"`
-	Networks map[string]composeOverlayNetwork    `yaml:"networks"`
+	Networks map[string]composeOverlayNetwork    `yaml:"networks,omitempty"`
 }
 
 type composeOverlayNetwork struct {
@@ -70,7 +72,30 @@ func composeDNSServices(raw []byte) ([]composeDNSService, error) {
 	return out, nil
 }
 
-func renderDockerComposeNetwork(env netns.Service, services []composeDNSService) (string, error) {
+// composeServicesWithPorts returns the services in a compose file that set
+// ports:. Files without services, such as partial overrides, have none.
+func composeServicesWithPorts(raw []byte) ([]string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("parse compose yaml: %w", err)
+	}
+	services := yamlMappingValue(yamlDocumentRoot(&doc), "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	var names []string
+	for idx := 0; idx+1 < len(services.Content); idx += 2 {
+		if ports := yamlMappingValue(services.Content[idx+1], "ports"); ports != nil && len(ports.Content) > 0 {
+			names = append(names, services.Content[idx].Value)
+		}
+	}
+	return names, nil
+}
+
+func renderDockerComposeNetwork(rt svc.ContainerRuntime, env netns.Service, services []composeDNSService) (string, error) {
+	if rt.IsPodman() {
+		return renderPodmanComposeNetwork(env, services)
+	}
 	overlay := composeNetworkOverlay{
 		Networks: map[string]composeOverlayNetwork{
 			"default": {
@@ -102,6 +127,30 @@ func renderDockerComposeNetwork(env netns.Service, services []composeDNSService)
 	return string(raw), nil
 }
 
+// renderPodmanComposeNetwork joins every compose service to the service
+// network namespace directly, since podman cannot use the yeet Docker network
+// plugin. The services share one network stack, as they would in a pod.
+func renderPodmanComposeNetwork(env netns.Service, services []composeDNSService) (string, error) {
+	if len(services) == 0 {
+		return "", fmt.Errorf("compose file has no services to place in the service network namespace")
+	}
+	mode := "ns:" + filepath.Join("/var/run/netns", env.NetNS())
+	overlay := composeNetworkOverlay{Services: make(map[string]composeDNSOverlayService, len(services))}
+	for _, service := range services {
+		entry := composeDNSOverlayService{NetworkMode: mode}
+		if env.ServiceIP.IsValid() && !service.CustomResolver {
+			entry.DNS = []string{yeetDNSHostIP}
+			entry.DNSSearch = []string{strings.TrimSuffix(yeetDNSDomain, ".")}
+		}
+		overlay.Services[service.Name] = entry
+	}
+	raw, err := yaml.Marshal(overlay)
+	if err != nil {
+		return "", fmt.Errorf("marshal compose network overlay: %w", err)
+	}
+	return string(raw), nil
+}
+
 func composeServiceHasResolver(node *yaml.Node, seen map[*yaml.Node]bool) bool {
 	if node == nil || node.Kind != yaml.MappingNode {
 		return false
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/yeetrun/yeet/pkg/netns"
+	"github.com/yeetrun/yeet/pkg/svc"
 	"gopkg.in/yaml.v3"
 )
 
@@ -103,7 +104,7 @@ func TestComposeDNSServicesRejectsMalformedCompose(t *testing.T) {
 }
 
 func TestRenderDockerComposeNetworkAddsDNSOnlyForSvcServicesWithoutCustomResolvers(t *testing.T) {
-	overlay, err := renderDockerComposeNetwork(netns.Service{
+	overlay, err := renderDockerComposeNetwork(svc.ContainerRuntimeDocker, netns.Service{
 		ServiceName: "client",
 		ServiceIP:   netipPrefixForTest(t, "192.168.100.3/32"),
 	}, []composeDNSService{
@@ -136,7 +137,7 @@ func TestRenderDockerComposeNetworkAddsDNSOnlyForSvcServicesWithoutCustomResolve
 }
 
 func TestRenderDockerComposeNetworkOmitsDNSWithoutSvc(t *testing.T) {
-	overlay, err := renderDockerComposeNetwork(netns.Service{ServiceName: "client"}, []composeDNSService{{Name: "api"}})
+	overlay, err := renderDockerComposeNetwork(svc.ContainerRuntimeDocker, netns.Service{ServiceName: "client"}, []composeDNSService{{Name: "api"}})
 	if err != nil {
 		t.Fatalf("renderDockerComposeNetwork: %v", err)
 	}
@@ -145,6 +146,40 @@ func TestRenderDockerComposeNetworkOmitsDNSWithoutSvc(t *testing.T) {
 	}
 }
 
+func TestRenderDockerComposeNetworkJoinsNetNSOnPodman(t *testing.T) {
+	overlay, err := renderDockerComposeNetwork(svc.ContainerRuntimePodman, netns.Service{
+		ServiceName: "client",
+		ServiceIP:   netipPrefixForTest(t, "192.168.100.3/32"),
+	}, []composeDNSService{
+		{Name: "api"},
+		{Name: "db", CustomResolver: true},
+	})
+	if err != nil {
+		t.Fatalf("renderDockerComposeNetwork: %v", err)
+	}
+	var doc struct {
+		Services map[string]composeDNSOverlayService `yaml:"services"`
+		Networks map[string]any                      `yaml:"networks"`
+	}
+	if err := yaml.Unmarshal([]byte(overlay), &doc); err != nil {
+		t.Fatalf("unmarshal overlay: %v\n%s", err, overlay)
+	}
+	if len(doc.Networks) != 0 {
+		t.Fatalf("podman overlay declares networks:\n%s", overlay)
+	}
+	for _, name := range []string{"api", "db"} {
+		if got := doc.Services[name].NetworkMode; got != "ns:/var/run/netns/yeet-client-ns" {
+			t.Fatalf("%s network_mode = %q", name, got)
+		}
+	}
+	if len(doc.Services["api"].DNS) != 1 || len(doc.Services["db"].DNS) != 0 {
+		t.Fatalf("dns = %#v, want it only for services without a resolver", doc.Services)
+	}
+	if _, err := renderDockerComposeNetwork(svc.ContainerRuntimePodman, netns.Service{ServiceName: "client"}, nil); err == nil {
+		t.Fatal("podman overlay without services succeeded")
+	}
+}
+
 func netipPrefixForTest(t *testing.T, value string) netip.Prefix {
 	t.Helper()
 	prefix, err := netip.ParsePrefix(value)
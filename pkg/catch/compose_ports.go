@@ -91,6 +91,43 @@ func readComposePorts(path, serviceName string) ([]string, error) {
 	return normalizePublish(ports), nil
 }
 
+// readComposeFilePorts returns the ports every service in a compose file
+// declares, in short syntax. Long-syntax entries are converted.
+func readComposeFilePorts(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Services map[string]struct {
+			Ports []any `yaml:"ports"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	var ports []string
+	for _, service := range doc.Services {
+		for _, entry := range service.Ports {
+			switch entry := entry.(type) {
+			case string:
+				ports = append(ports, entry)
+			case map[string]any:
+				published := fmt.Sprint(entry["published"])
+				if entry["published"] == nil || published == "" {
+					continue
+				}
+				port := published + ":" + fmt.Sprint(entry["target"])
+				if proto, ok := entry["protocol"].(string); ok {
+					port += "/" + proto
+				}
+				ports = append(ports, port)
+			}
+		}
+	}
+	return normalizePublish(ports), nil
+}
+
 func composeServiceMap(doc map[string]any, serviceName string) (map[string]any, error) {
 	servicesRaw, ok := doc["services"]
 	if !ok {
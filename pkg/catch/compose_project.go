@@ -0,0 +1,144 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/svc"
+	"tailscale.com/util/mak"
+)
+
+const (
+	// composeProjectFlagPrefix marks --compose-file and --compose-env-file
+	// values the client has already read and encoded; catch never reads them
+	// from its own disk.
+	composeProjectFlagPrefix = "base64:"
+	composeProjectMaxBytes   = 256 << 10
+
+	composeProjectOnlyMessage = "--compose-file, --compose-env-file, and --profile are only valid for docker compose payloads"
+	composeProjectISOMessage  = "ISO networking does not support --compose-file, --compose-env-file, or --profile; flatten the compose files first"
+)
+
+// composeProfilePattern matches the profile names docker compose accepts.
+var composeProfilePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// hasComposeProject reports whether the run supplied any compose project
+// input.
+func (c FileInstallerCfg) hasComposeProject() bool {
+	return len(c.ComposeFiles) != 0 || len(c.ComposeEnvFiles) != 0 || len(c.Profiles) != 0
+}
+
+func decodeComposeProjectFlag(flag, value string) ([]byte, error) {
+	encoded, ok := strings.CutPrefix(strings.TrimSpace(value), composeProjectFlagPrefix)
+	if !ok {
+		return nil, fmt.Errorf("%s must be sent by the yeet client; pass a local file path", flag)
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", flag, err)
+	}
+	if len(raw) > composeProjectMaxBytes {
+		return nil, fmt.Errorf("%s is %d bytes; the limit is %d", flag, len(raw), composeProjectMaxBytes)
+	}
+	return raw, nil
+}
+
+func validateComposeProfiles(profiles []string) error {
+	for _, profile := range profiles {
+		if !composeProfilePattern.MatchString(profile) {
+			return fmt.Errorf("invalid compose profile %q", profile)
+		}
+	}
+	return nil
+}
+
+// stageComposeProject writes the run's override files, interpolation env
+// files, and profiles next to the compose payload and records them as
+// artifacts of the new generation.
+func (i *FileInstaller) stageComposeProject() error {
+	if err := validateComposeProfiles(i.cfg.Profiles); err != nil {
+		return err
+	}
+	binDir := i.serviceBinDir()
+	for n, value := range i.cfg.ComposeFiles {
+		path := filepath.Join(binDir, fmt.Sprintf("docker-compose.override-%d.%s.yml", n, i.version()))
+		if err := writeComposeProjectFile(path, "--compose-file", value, 0o644); err != nil {
+			return err
+		}
+		mak.Set(&i.artifacts, db.ArtifactDockerComposeOverride(n), path)
+	}
+	for n, value := range i.cfg.ComposeEnvFiles {
+		path := filepath.Join(binDir, fmt.Sprintf("compose-%d.%s.env", n, i.version()))
+		if err := writeComposeProjectFile(path, "--compose-env-file", value, 0o600); err != nil {
+			return err
+		}
+		mak.Set(&i.artifacts, db.ArtifactDockerComposeEnv(n), path)
+	}
+	if len(i.cfg.Profiles) != 0 {
+		path := filepath.Join(binDir, fmt.Sprintf("compose-profiles.%s", i.version()))
+		if err := os.WriteFile(path, svc.FormatComposeProfiles(i.cfg.Profiles), 0o644); err != nil {
+			return fmt.Errorf("write compose profiles: %w", err)
+		}
+		mak.Set(&i.artifacts, db.ArtifactDockerComposeProfiles, path)
+	}
+	return nil
+}
+
+func writeComposeProjectFile(path, flag, value string, mode os.FileMode) error {
+	raw, err := decodeComposeProjectFlag(flag, value)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, raw, mode); err != nil {
+		return fmt.Errorf("write %s: %w", flag, err)
+	}
+	return nil
+}
+
+// isComposeProjectArtifact reports whether name is part of a compose project
+// rather than the base compose file.
+func isComposeProjectArtifact(name db.ArtifactName) bool {
+	s := string(name)
+	return name == db.ArtifactDockerComposeProfiles ||
+		strings.HasPrefix(s, "compose.override.") ||
+		strings.HasPrefix(s, "compose.env.")
+}
+
+// unstageComposeProject drops staged compose project artifacts that the new
+// generation does not carry. Staged refs otherwise stick across generations,
+// so a removed override would come back on the next commit.
+func unstageComposeProject(s *db.Service, staged map[db.ArtifactName]string) {
+	for name, artifact := range s.Artifacts {
+		if artifact == nil || !isComposeProjectArtifact(name) {
+			continue
+		}
+		if _, ok := staged[name]; ok {
+			continue
+		}
+		delete(artifact.Refs, db.ArtifactRef("staged"))
+	}
+}
+
+// composeProjectISOError rejects ISO networking for a generation that has a
+// compose project. ISO admission resolves only the base compose file, so
+// overrides would bypass it.
+func composeProjectISOError(artifacts db.ArtifactStore, gen int) error {
+	project, err := svc.ComposeProjectFromArtifacts(artifacts, gen)
+	if err != nil {
+		return err
+	}
+	if !project.IsZero() {
+		return errors.New(composeProjectISOMessage)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"encoding/base64"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/svc"
+)
+
+func encodedComposeProjectFile(content string) string {
+	return composeProjectFlagPrefix + base64.StdEncoding.EncodeToString([]byte(content))
+}
+
+func stageTestComposeProject(t *testing.T, server *Server, cfg FileInstallerCfg, payload string) error {
+	t.Helper()
+	cfg.InstallerCfg = InstallerCfg{ServiceName: "web"}
+	cfg.StageOnly = true
+	installer, err := NewFileInstaller(server, cfg)
+	if err != nil {
+		t.Fatalf("NewFileInstaller: %v", err)
+	}
+	if _, err := installer.Write([]byte(payload)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	return installer.Close()
+}
+
+func TestInstallerStagesComposeProject(t *testing.T) {
+	server := newTestServer(t)
+	compose := "services:\n  web:\n    image: nginx:latest\n"
+	err := stageTestComposeProject(t, server, FileInstallerCfg{
+		PayloadName:     "compose.yml",
+		ComposeFiles:    []string{encodedComposeProjectFile("services: {base: {}}\n"), encodedComposeProjectFile("services: {prod: {}}\n")},
+		ComposeEnvFiles: []string{encodedComposeProjectFile("TAG=1\n")},
+		Profiles:        []string{"worker", "debug"},
+	}, compose)
+	if err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	service := testService(t, server, "web")
+	for name, want := range map[db.ArtifactName]string{
+		db.ArtifactDockerComposeOverride(0): "services: {base: {}}\n",
+		db.ArtifactDockerComposeOverride(1): "services: {prod: {}}\n",
+		db.ArtifactDockerComposeEnv(0):      "TAG=1\n",
+	} {
+		raw, err := os.ReadFile(stagedArtifactPath(t, service, name))
+		if err != nil || string(raw) != want {
+			t.Fatalf("%s = %q, %v; want %q", name, raw, err, want)
+		}
+	}
+	raw, err := os.ReadFile(stagedArtifactPath(t, service, db.ArtifactDockerComposeProfiles))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := svc.ParseComposeProfiles(raw); !reflect.DeepEqual(got, []string{"worker", "debug"}) {
+		t.Fatalf("profiles = %q, want worker, debug", got)
+	}
+
+	// A redeploy carries the whole project, so files it drops are unstaged.
+	err = stageTestComposeProject(t, server, FileInstallerCfg{
+		PayloadName:  "compose.yml",
+		ComposeFiles: []string{encodedComposeProjectFile("services: {prod: {}}\n")},
+	}, compose)
+	if err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+	service = testService(t, server, "web")
+	if _, ok := service.Artifacts.Staged(db.ArtifactDockerComposeOverride(0)); !ok {
+		t.Fatal("override 0 not staged after redeploy")
+	}
+	for _, name := range []db.ArtifactName{db.ArtifactDockerComposeOverride(1), db.ArtifactDockerComposeEnv(0), db.ArtifactDockerComposeProfiles} {
+		if _, ok := service.Artifacts.Staged(name); ok {
+			t.Fatalf("%s still staged after redeploy without it", name)
+		}
+	}
+}
+
+func TestInstallerRejectsInvalidComposeProject(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     FileInstallerCfg
+		payload string
+		want    string
+	}{
+		{
+			name:    "script payload",
+			cfg:     FileInstallerCfg{PayloadName: "job.sh", Profiles: []string{"worker"}},
+			payload: "#!/bin/sh\necho hi\n",
+			want:    "only valid for docker compose payloads",
+		},
+		{
+			name:    "unencoded file",
+			cfg:     FileInstallerCfg{PayloadName: "compose.yml", ComposeFiles: []string{"prod.override.yml"}},
+			payload: "services:\n  web:\n    image: nginx:latest\n",
+			want:    "must be sent by the yeet client",
+		},
+		{
+			name:    "bad profile",
+			cfg:     FileInstallerCfg{PayloadName: "compose.yml", Profiles: []string{"-x"}},
+			payload: "services:\n  web:\n    image: nginx:latest\n",
+			want:    "invalid compose profile",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := stageTestComposeProject(t, newTestServer(t), tc.cfg, tc.payload)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("Close error = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestComposeProjectISOError(t *testing.T) {
+	artifacts := db.ArtifactStore{
+		db.ArtifactDockerComposeOverride(0): {Refs: map[db.ArtifactRef]string{db.Gen(2): "/srv/override.yml"}},
+	}
+	if err := composeProjectISOError(artifacts, 1); err != nil {
+		t.Fatalf("generation without a project: %v", err)
+	}
+	if err := composeProjectISOError(artifacts, 2); err == nil || !strings.Contains(err.Error(), "ISO networking") {
+		t.Fatalf("generation with overrides error = %v, want ISO rejection", err)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/svc"
+)
+
+// checkContainerRuntimeFn verifies a runtime can run compose projects before
+// the host switches to it.
+var checkContainerRuntimeFn = func(ctx context.Context, rt svc.ContainerRuntime) error {
+	return rt.CheckCompose(ctx)
+}
+
+// containerRuntime returns the engine compose services run on, as recorded
+// in the host settings. An unreadable setting falls back to docker.
+func (s *Server) containerRuntime() svc.ContainerRuntime {
+	if s.cfg.DB == nil {
+		return svc.ContainerRuntimeDocker
+	}
+	dv, err := s.cfg.DB.Get()
+	if err != nil {
+		log.Printf("failed to read container runtime: %v", err)
+		return svc.ContainerRuntimeDocker
+	}
+	rt, err := svc.ContainerRuntimeFromData(dv)
+	if err != nil {
+		log.Printf("failed to read container runtime: %v", err)
+		return svc.ContainerRuntimeDocker
+	}
+	return rt
+}
+
+// SetContainerRuntime switches the engine compose services run on.
+func (s *Server) SetContainerRuntime(ctx context.Context, req catchrpc.ContainerRuntimeSetRequest) (catchrpc.ContainerRuntimeSetResult, error) {
+	rt, err := svc.ParseContainerRuntime(req.Runtime)
+	if err != nil {
+		return catchrpc.ContainerRuntimeSetResult{}, err
+	}
+	result := catchrpc.ContainerRuntimeSetResult{Runtime: string(rt)}
+	dv, err := s.getDB()
+	if err != nil {
+		return catchrpc.ContainerRuntimeSetResult{}, err
+	}
+	if current, err := svc.ContainerRuntimeFromData(*dv); err == nil && current == rt {
+		return result, nil
+	}
+	if err := checkContainerRuntimeFn(ctx, rt); err != nil {
+		return catchrpc.ContainerRuntimeSetResult{}, err
+	}
+	result.Changed, err = StoreContainerRuntime(s.cfg.DB, rt)
+	if err != nil {
+		return catchrpc.ContainerRuntimeSetResult{}, err
+	}
+	return result, nil
+}
+
+// StoreContainerRuntime records rt in the host settings. Compose projects do not move between engines, so switching
+// is refused while any compose service is installed.
+func StoreContainerRuntime(store *db.Store, rt svc.ContainerRuntime) (changed bool, _ error) {
+	_, err := store.MutateData(func(d *db.Data) error {
+		current, _ := svc.ParseContainerRuntime(d.ContainerRuntime)
+		if current == rt {
+			return nil
+		}
+		var compose []string
+		for name, service := range d.Services {
+			if service != nil && service.ServiceType == db.ServiceTypeDockerCompose {
+				compose = append(compose, name)
+			}
+		}
+		if len(compose) > 0 {
+			sort.Strings(compose)
+			return fmt.Errorf("cannot switch from %s to %s while compose services are installed (%s); remove them first and redeploy after the switch", current, rt, strings.Join(compose, ", "))
+		}
+		if rt == svc.ContainerRuntimePodman && d.RegistryMirror != nil {
+			return fmt.Errorf("cannot switch to podman while the registry mirror is enabled; it configures the Docker daemon, disable it with host set --registry-mirror=off first")
+		}
+		d.ContainerRuntime = string(rt)
+		if rt == svc.ContainerRuntimeDocker {
+			d.ContainerRuntime = ""
+		}
+		changed = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return changed, nil
+}
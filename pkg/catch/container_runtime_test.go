@@ -0,0 +1,86 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/svc"
+)
+
+func TestSetContainerRuntimeRefusesSwitchWithComposeServices(t *testing.T) {
+	server := newTestServer(t)
+	oldCheck := checkContainerRuntimeFn
+	t.Cleanup(func() {
+		checkContainerRuntimeFn = oldCheck
+	})
+	var checked []svc.ContainerRuntime
+	checkContainerRuntimeFn = func(_ context.Context, rt svc.ContainerRuntime) error {
+		checked = append(checked, rt)
+		return nil
+	}
+	if _, err := server.cfg.DB.MutateData(func(d *db.Data) error {
+		d.Services = map[string]*db.Service{"web": {Name: "web", ServiceType: db.ServiceTypeDockerCompose}}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	_, err := server.SetContainerRuntime(ctx, catchrpc.ContainerRuntimeSetRequest{Runtime: "podman"})
+	if err == nil || !strings.Contains(err.Error(), "compose services are installed (web)") {
+		t.Fatalf("SetContainerRuntime error = %v, want compose services refusal", err)
+	}
+	if server.containerRuntime().IsPodman() {
+		t.Fatal("refused switch still selected podman")
+	}
+
+	if _, err := server.cfg.DB.MutateData(func(d *db.Data) error {
+		delete(d.Services, "web")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := server.SetContainerRuntime(ctx, catchrpc.ContainerRuntimeSetRequest{Runtime: "podman"})
+	if err != nil || got != (catchrpc.ContainerRuntimeSetResult{Runtime: "podman", Changed: true}) {
+		t.Fatalf("SetContainerRuntime = %#v, %v; want podman changed", got, err)
+	}
+	dv, err := server.getDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dv.ContainerRuntime() != "podman" || !server.containerRuntime().IsPodman() {
+		t.Fatalf("db runtime = %q podman=%v, want podman selected", dv.ContainerRuntime(), server.containerRuntime().IsPodman())
+	}
+
+	got, err = server.SetContainerRuntime(ctx, catchrpc.ContainerRuntimeSetRequest{Runtime: "podman"})
+	if err != nil || got.Changed {
+		t.Fatalf("repeat = %#v, %v; want unchanged", got, err)
+	}
+	got, err = server.SetContainerRuntime(ctx, catchrpc.ContainerRuntimeSetRequest{Runtime: "docker"})
+	if err != nil || !got.Changed {
+		t.Fatalf("back to docker = %#v, %v; want changed", got, err)
+	}
+	if dv, _ := server.getDB(); dv.ContainerRuntime() != "" {
+		t.Fatalf("db runtime = %q, want empty for docker", dv.ContainerRuntime())
+	}
+
+	if _, err := server.cfg.DB.MutateData(func(d *db.Data) error {
+		d.RegistryMirror = &db.RegistryMirrorConfig{Upstream: "docker.io"}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.SetContainerRuntime(ctx, catchrpc.ContainerRuntimeSetRequest{Runtime: "podman"}); err == nil || !strings.Contains(err.Error(), "registry mirror is enabled") {
+		t.Fatalf("SetContainerRuntime with mirror = %v, want registry mirror refusal", err)
+	}
+	if len(checked) != 4 {
+		t.Fatalf("checked runtimes = %v, want one check per switch attempt", checked)
+	}
+}
@@ -7,6 +7,7 @@ package catch
 import (
 	"log"
 
+	"github.com/yeetrun/yeet/pkg/catchrpc"
 	"github.com/yeetrun/yeet/pkg/db"
 	"github.com/yeetrun/yeet/pkg/svc"
 )
@@ -37,6 +38,10 @@ type ServiceStatusData struct {
 	ServiceName     string                `json:"serviceName"`
 	ServiceType     ServiceDataType       `json:"serviceType"`
 	ComponentStatus []ComponentStatusData `json:"components"`
+	// Storage is omitted when usage cannot be read without walking the root.
+	Storage *catchrpc.ServiceStorage `json:"storage,omitempty"`
+	// VMMetrics is only sampled for running VMs with status --wide.
+	VMMetrics *catchrpc.ServiceVMMetrics `json:"vmMetrics,omitempty"`
 }
 
 type ComponentStatusData struct {
@@ -13,7 +13,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/yeetrun/yeet/pkg/svc"
 	"tailscale.com/logtail/backoff"
 )
 
@@ -31,6 +30,19 @@ var dockerComposeServiceStatus = map[string]ComponentStatus{
 	"exec_start":  "-",
 	"exec_die":    "-",
 	"exec_create": "-",
+
+	// podman lifecycle events with no docker counterpart
+	"init":    "-",
+	"cleanup": "-",
+	"mount":   "-",
+	"unmount": "-",
+}
+
+// podmanEventActions maps podman event statuses to the docker actions they
+// stand for.
+var podmanEventActions = map[string]string{
+	"died":   "die",
+	"remove": "destroy",
 }
 
 type dockerMonitorActor struct {
@@ -44,6 +56,22 @@ type dockerMonitorEvent struct {
 	Type   string             `json:"Type"`
 	Action string             `json:"Action"`
 	Actor  dockerMonitorActor `json:"Actor"`
+
+	// Attributes is where podman puts the container labels.
+	Attributes map[string]string `json:"Attributes"`
+}
+
+// normalize rewrites a podman event into the docker event shape.
+func (e *dockerMonitorEvent) normalize() {
+	if e.Action == "" {
+		e.Action = e.Status
+		if action, ok := podmanEventActions[e.Status]; ok {
+			e.Action = action
+		}
+	}
+	if e.Actor.Attributes == nil {
+		e.Actor.Attributes = e.Attributes
+	}
 }
 
 func (s *Server) monitorDocker() {
@@ -58,8 +86,8 @@ execLoop:
 		default:
 		}
 
-		// Get the Docker command
-		docker, err := svc.DockerCmd()
+		// Get the container runtime command
+		docker, err := s.containerRuntime().LookPath()
 		if err != nil {
 			log.Printf("failed to get docker command: %v", err)
 			bo.BackOff(ctx, err)
@@ -92,6 +120,7 @@ execLoop:
 				continue
 			}
 
+			entry.normalize()
 			s.handleDockerMonitorEvent(entry)
 		}
 	}
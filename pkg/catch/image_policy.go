@@ -0,0 +1,307 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+	"github.com/yeetrun/yeet/pkg/cli"
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/registry"
+	"github.com/yeetrun/yeet/pkg/svc"
+)
+
+// SetImagePolicy changes the host image policy. Turning it off keeps the
+// trust keys, which services that require signed images still use.
+func (s *Server) SetImagePolicy(req catchrpc.ImagePolicySetRequest) (catchrpc.ImagePolicySetResult, error) {
+	switch req.Mode {
+	case "", cli.ImagePolicyRequireSigned, cli.ImagePolicyOff:
+	default:
+		return catchrpc.ImagePolicySetResult{}, fmt.Errorf("image policy must be %s or %s", cli.ImagePolicyRequireSigned, cli.ImagePolicyOff)
+	}
+	var trustKeys []string
+	for i, data := range req.TrustKeys {
+		key, err := registry.ParsePublicKey([]byte(data))
+		if err != nil {
+			return catchrpc.ImagePolicySetResult{}, fmt.Errorf("trust key %d: %w", i+1, err)
+		}
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			return catchrpc.ImagePolicySetResult{}, fmt.Errorf("trust key %d: %w", i+1, err)
+		}
+		trustKeys = append(trustKeys, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+	}
+	var result catchrpc.ImagePolicySetResult
+	_, err := s.cfg.DB.MutateData(func(d *db.Data) error {
+		policy := db.ImagePolicyConfig{}
+		if d.ImagePolicy != nil {
+			policy = *d.ImagePolicy.Clone()
+		}
+		switch req.Mode {
+		case cli.ImagePolicyRequireSigned:
+			policy.Mode = cli.ImagePolicyRequireSigned
+		case cli.ImagePolicyOff:
+			policy.Mode = ""
+		}
+		if len(trustKeys) > 0 {
+			policy.TrustKeys = trustKeys
+		}
+		if policy.Mode == cli.ImagePolicyRequireSigned && len(policy.TrustKeys) == 0 {
+			return fmt.Errorf("--image-policy=%s needs at least one --trust-key", cli.ImagePolicyRequireSigned)
+		}
+		var next *db.ImagePolicyConfig
+		if policy.Mode != "" || len(policy.TrustKeys) > 0 {
+			next = &policy
+		}
+		result.Changed = !reflect.DeepEqual(d.ImagePolicy, next)
+		d.ImagePolicy = next
+		result.Mode = cli.ImagePolicyOff
+		if policy.Mode != "" {
+			result.Mode = policy.Mode
+		}
+		keys, err := parseTrustKeys(policy.TrustKeys)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			result.TrustKeys = append(result.TrustKeys, registry.KeyFingerprint(key))
+		}
+		return nil
+	})
+	if err != nil {
+		return catchrpc.ImagePolicySetResult{}, err
+	}
+	return result, nil
+}
+
+func (s *Server) setServiceImagePolicy(name, policy string) error {
+	_, _, err := s.cfg.DB.MutateService(name, func(_ *db.Data, service *db.Service) error {
+		if service.ServiceType != db.ServiceTypeDockerCompose {
+			return fmt.Errorf("--image-policy applies only to docker compose services")
+		}
+		if policy == cli.ImagePolicyInherit {
+			policy = ""
+		}
+		service.ImagePolicy = policy
+		return nil
+	})
+	return err
+}
+
+// imagePolicyKeys returns the keys the compose images of service must be
+// signed with, or nil when they are not checked. The service setting
+// overrides the host one.
+func imagePolicyKeys(dv db.DataView, service string) ([]crypto.PublicKey, error) {
+	policy := dv.ImagePolicy()
+	required := policy.Valid() && policy.Mode() == cli.ImagePolicyRequireSigned
+	if sv, ok := dv.Services().GetOk(service); ok {
+		switch sv.ImagePolicy() {
+		case cli.ImagePolicyRequireSigned:
+			required = true
+		case cli.ImagePolicyOff:
+			required = false
+		}
+	}
+	if !required {
+		return nil, nil
+	}
+	if !policy.Valid() || policy.TrustKeys().Len() == 0 {
+		return nil, fmt.Errorf("service %q requires signed images, but no trust keys are set; add them with yeet host set --trust-key", service)
+	}
+	return parseTrustKeys(policy.TrustKeys().AsSlice())
+}
+
+func parseTrustKeys(pems []string) ([]crypto.PublicKey, error) {
+	keys := make([]crypto.PublicKey, 0, len(pems))
+	for i, data := range pems {
+		key, err := registry.ParsePublicKey([]byte(data))
+		if err != nil {
+			return nil, fmt.Errorf("stored trust key %d: %w", i+1, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// imagePolicyCompose is the part of a compose service the image policy
+// needs.
+type imagePolicyCompose interface {
+	PullContext(ctx context.Context) error
+	DeclaredImages(ctx context.Context) ([]string, error)
+	LocalImageDigest(ctx context.Context, image string) (string, error)
+}
+
+// verifyComposeImages checks every image of a compose service against the
+// image policy before containers start from them, pulling the images first
+// when pull is set. It reports whether the policy applies; callers then start
+// containers without pulling again, so the images checked are the ones that
+// run.
+func (s *Server) verifyComposeImages(ctx context.Context, service string, compose imagePolicyCompose, pull bool) (bool, error) {
+	dv, err := s.getDB()
+	if err != nil {
+		return false, err
+	}
+	keys, err := imagePolicyKeys(*dv, service)
+	if err != nil {
+		return true, err
+	}
+	if keys == nil {
+		return false, nil
+	}
+	if pull {
+		if err := compose.PullContext(ctx); err != nil {
+			return true, err
+		}
+	}
+	images, err := compose.DeclaredImages(ctx)
+	if err != nil {
+		return true, err
+	}
+	for _, image := range images {
+		if err := s.verifyImage(ctx, *dv, service, compose, image, keys); err != nil {
+			return true, fmt.Errorf("image policy requires signed images: %w", err)
+		}
+	}
+	return true, nil
+}
+
+func (s *Server) verifyImage(ctx context.Context, dv db.DataView, service string, compose imagePolicyCompose, image string, keys []crypto.PublicKey) error {
+	if repo, ref, ok := internalImageRef(image); ok {
+		digest := ref
+		if !isDigest(ref) {
+			ir, ok := dv.Images().GetOk(db.ImageRepoName(repo))
+			var mf db.ImageManifest
+			if ok {
+				mf, ok = ir.Refs().GetOk(db.ImageRef(ref))
+			}
+			if !ok {
+				return fmt.Errorf("%s is not in the internal registry", image)
+			}
+			digest = mf.BlobHash
+		}
+		if s.registry == nil {
+			return fmt.Errorf("%s: internal registry is not running", image)
+		}
+		return imageSignatureError(image, digest, registry.VerifyCosignSignature(ctx, s.registry.storage, repo, digest, keys))
+	}
+	digest, err := compose.LocalImageDigest(ctx, image)
+	if err != nil {
+		return err
+	}
+	name, _, _ := strings.Cut(image, "@")
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	domain, repo := registry.ParseRepositoryName(name)
+	sources, err := s.imageSignatureSources(dv, service, domain, repo)
+	if err != nil {
+		return fmt.Errorf("%s: %w", image, err)
+	}
+	for _, src := range sources {
+		err := registry.VerifyCosignSignature(ctx, src.store, src.repo, digest, keys)
+		if !errors.Is(err, registry.ErrNoSignature) {
+			return imageSignatureError(image, digest, err)
+		}
+	}
+	return imageSignatureError(image, digest, fmt.Errorf("signature not available offline; copy %s:%s into %s/%s",
+		name, registry.SignatureTag(digest), svc.InternalRegistryHost, service))
+}
+
+// imageSignatureSource is a local store that may hold the signatures of an
+// external image under repo.
+type imageSignatureSource struct {
+	store registry.Storage
+	repo  string
+}
+
+// imageSignatureSources returns where the signatures of an external image
+// are read, without contacting its registry: the service's repo in the
+// internal registry, where they are pushed next to the service's images, and
+// the registry mirror cache when the mirror serves domain.
+func (s *Server) imageSignatureSources(dv db.DataView, service, domain, repo string) ([]imageSignatureSource, error) {
+	var sources []imageSignatureSource
+	if s.registry != nil {
+		sources = append(sources, imageSignatureSource{store: s.registry.storage, repo: service})
+	}
+	if cfg := dv.RegistryMirror(); cfg.Valid() && cfg.Upstream() == domain {
+		cache, err := registry.NewFilesystemStorage(s.registryMirrorCacheDir(domain))
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, imageSignatureSource{store: cache, repo: repo})
+	}
+	return sources, nil
+}
+
+func imageSignatureError(image, digest string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s (%s): %w", image, digest, err)
+}
+
+// internalImageRef splits a compose image served by the internal registry
+// into its repository and tag or digest.
+func internalImageRef(image string) (repo, ref string, ok bool) {
+	rest, ok := strings.CutPrefix(image, svc.InternalRegistryHost+"/")
+	if !ok {
+		return "", "", false
+	}
+	if repo, digest, ok := strings.Cut(rest, "@"); ok {
+		return repo, digest, true
+	}
+	if i := strings.LastIndex(rest, ":"); i > strings.LastIndex(rest, "/") {
+		return rest[:i], rest[i+1:], true
+	}
+	return rest, "latest", true
+}
+
+// imagePolicyUpdater is a compose service `docker update` can run under the
+// image policy.
+type imagePolicyUpdater interface {
+	imagePolicyCompose
+	ImageIDs(ctx context.Context) (map[string]string, error)
+	RestoreImages(ctx context.Context, ids map[string]string) error
+	UpDetached(ctx context.Context, pull bool) error
+}
+
+// updateCompose runs update, or, when the image policy applies to service,
+// pulls, checks the new images and recreates the containers without pulling
+// again. Images that fail the check are replaced by the ones that ran before.
+func (s *Server) updateCompose(ctx context.Context, service string, compose imagePolicyUpdater, update func() error) error {
+	dv, err := s.getDB()
+	if err != nil {
+		return err
+	}
+	keys, err := imagePolicyKeys(*dv, service)
+	if err != nil {
+		return err
+	}
+	if keys == nil {
+		return update()
+	}
+	previous, err := compose.ImageIDs(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := s.verifyComposeImages(ctx, service, compose, true); err != nil {
+		if len(previous) == 0 {
+			return err
+		}
+		if rerr := compose.RestoreImages(ctx, previous); rerr != nil {
+			return fmt.Errorf("%w; restoring previous images failed: %v", err, rerr)
+		}
+		return err
+	}
+	return compose.UpDetached(ctx, false)
+}
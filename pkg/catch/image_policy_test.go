@@ -0,0 +1,310 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+	"github.com/yeetrun/yeet/pkg/cli"
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/registry"
+)
+
+const (
+	testSignedDigest   = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	testUnsignedDigest = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+)
+
+func newTestTrustKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func putTestRegistryBlob(t *testing.T, store registry.Storage, data []byte) string {
+	t.Helper()
+	ctx := context.Background()
+	upload, err := store.NewUpload(ctx)
+	if err != nil {
+		t.Fatalf("NewUpload: %v", err)
+	}
+	if _, err := store.CopyChunk(ctx, upload.UUID, bytes.NewReader(data)); err != nil {
+		t.Fatalf("CopyChunk: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	if _, err := store.CompleteUpload(ctx, upload.UUID, digest); err != nil {
+		t.Fatalf("CompleteUpload: %v", err)
+	}
+	return digest
+}
+
+// pushTestSignature pushes a cosign signature of digest, made with key, to
+// repo of store.
+func pushTestSignature(t *testing.T, store registry.Storage, repo, digest string, key *ecdsa.PrivateKey) {
+	t.Helper()
+	payload := fmt.Appendf(nil, `{"critical":{"identity":{"docker-reference":%q},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, repo, digest)
+	sum := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	payloadDigest := putTestRegistryBlob(t, store, payload)
+	configDigest := putTestRegistryBlob(t, store, []byte(`{"architecture":"","os":"","rootfs":{"type":"layers","diff_ids":[]}}`))
+	manifest := fmt.Appendf(nil, `{"schemaVersion":2,"mediaType":%q,"config":{"mediaType":%q,"digest":%q,"size":1},"layers":[{"mediaType":"application/vnd.dev.cosign.simplesigning.v1+json","digest":%q,"size":%d,"annotations":{"dev.cosignproject.cosign/signature":%q}}]}`,
+		ocispec.MediaTypeImageManifest, ocispec.MediaTypeImageConfig, configDigest, payloadDigest, len(payload), base64.StdEncoding.EncodeToString(sig))
+	if _, err := store.PutManifest(context.Background(), repo, registry.SignatureTag(digest), manifest, ocispec.MediaTypeImageManifest); err != nil {
+		t.Fatalf("PutManifest signature: %v", err)
+	}
+}
+
+func setTestImageRef(t *testing.T, server *Server, repo, ref, digest string) {
+	t.Helper()
+	_, err := server.cfg.DB.MutateData(func(d *db.Data) error {
+		ir, ok := d.Images[db.ImageRepoName(repo)]
+		if !ok {
+			ir = &db.ImageRepo{Refs: map[db.ImageRef]db.ImageManifest{}}
+			if d.Images == nil {
+				d.Images = map[db.ImageRepoName]*db.ImageRepo{}
+			}
+			d.Images[db.ImageRepoName(repo)] = ir
+		}
+		ir.Refs[db.ImageRef(ref)] = db.ImageManifest{BlobHash: digest}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetImagePolicy(t *testing.T) {
+	server := newTestServer(t)
+	_, trustKey := newTestTrustKey(t)
+
+	if _, err := server.SetImagePolicy(catchrpc.ImagePolicySetRequest{Mode: cli.ImagePolicyRequireSigned}); err == nil || !strings.Contains(err.Error(), "needs at least one --trust-key") {
+		t.Fatalf("require-signed without keys error = %v", err)
+	}
+	if _, err := server.SetImagePolicy(catchrpc.ImagePolicySetRequest{TrustKeys: []string{"not a key"}}); err == nil || !strings.Contains(err.Error(), "trust key 1") {
+		t.Fatalf("invalid key error = %v", err)
+	}
+	req := catchrpc.ImagePolicySetRequest{Mode: cli.ImagePolicyRequireSigned, TrustKeys: []string{trustKey}}
+	result, err := server.SetImagePolicy(req)
+	if err != nil {
+		t.Fatalf("SetImagePolicy: %v", err)
+	}
+	if result.Mode != cli.ImagePolicyRequireSigned || !result.Changed || len(result.TrustKeys) != 1 || !strings.HasPrefix(result.TrustKeys[0], "SHA256:") {
+		t.Fatalf("result = %#v", result)
+	}
+	if result, err := server.SetImagePolicy(req); err != nil || result.Changed {
+		t.Fatalf("repeat result = %#v, %v; want unchanged", result, err)
+	}
+
+	// Turning the policy off keeps the keys for per-service overrides.
+	result, err = server.SetImagePolicy(catchrpc.ImagePolicySetRequest{Mode: cli.ImagePolicyOff})
+	if err != nil {
+		t.Fatalf("SetImagePolicy off: %v", err)
+	}
+	if result.Mode != cli.ImagePolicyOff || !result.Changed || len(result.TrustKeys) != 1 {
+		t.Fatalf("off result = %#v", result)
+	}
+	dv, err := server.getDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := dv.ImagePolicy().AsStruct(); got == nil || got.Mode != "" || len(got.TrustKeys) != 1 {
+		t.Fatalf("stored policy = %#v, want keys without a mode", got)
+	}
+}
+
+func TestServiceSetImagePolicy(t *testing.T) {
+	server := newTestServer(t)
+	_, trustKey := newTestTrustKey(t)
+	addTestComposeService(t, server, "web", nil)
+	execer := &ttyExecer{s: server, sn: "web", rw: &bytes.Buffer{}, isPty: false}
+
+	if err := execer.serviceSetCmdFunc(cli.ServiceSetFlags{ImagePolicy: cli.ImagePolicyRequireSigned}); err != nil {
+		t.Fatalf("serviceSetCmdFunc: %v", err)
+	}
+	dv, _ := server.getDB()
+	if _, err := imagePolicyKeys(*dv, "web"); err == nil || !strings.Contains(err.Error(), "no trust keys") {
+		t.Fatalf("override without keys error = %v", err)
+	}
+	if _, err := server.SetImagePolicy(catchrpc.ImagePolicySetRequest{TrustKeys: []string{trustKey}}); err != nil {
+		t.Fatal(err)
+	}
+	dv, _ = server.getDB()
+	if keys, err := imagePolicyKeys(*dv, "web"); err != nil || len(keys) != 1 {
+		t.Fatalf("service override keys = %d, %v; want the host key", len(keys), err)
+	}
+	if keys, err := imagePolicyKeys(*dv, "other"); err != nil || keys != nil {
+		t.Fatalf("host policy off keys = %v, %v; want none", keys, err)
+	}
+
+	if err := execer.serviceSetCmdFunc(cli.ServiceSetFlags{ImagePolicy: cli.ImagePolicyInherit}); err != nil {
+		t.Fatalf("serviceSetCmdFunc inherit: %v", err)
+	}
+	if sv, _ := server.serviceView("web"); sv.ImagePolicy() != "" {
+		t.Fatalf("ImagePolicy = %q after inherit", sv.ImagePolicy())
+	}
+
+	addTestVMService(t, server, "devbox", nil)
+	execer.sn = "devbox"
+	if err := execer.serviceSetCmdFunc(cli.ServiceSetFlags{ImagePolicy: cli.ImagePolicyOff}); err == nil || !strings.Contains(err.Error(), "docker compose") {
+		t.Fatalf("VM image policy error = %v, want compose-only error", err)
+	}
+}
+
+func TestVerifyComposeImages(t *testing.T) {
+	server := newTestServer(t)
+	key, trustKey := newTestTrustKey(t)
+	storage := server.registry.storage
+	addTestComposeService(t, server, "web", nil)
+	ctx := context.Background()
+
+	compose := &fakeAutoUpdateCompose{images: []string{"catchit.dev/web", "ghcr.io/acme/app:2"}, digests: map[string]string{"ghcr.io/acme/app:2": testSignedDigest}}
+	if checked, err := server.verifyComposeImages(ctx, "web", compose, true); checked || err != nil {
+		t.Fatalf("verifyComposeImages without a policy = %v, %v", checked, err)
+	}
+	if len(compose.calls) != 0 {
+		t.Fatalf("calls = %q, want none without a policy", compose.calls)
+	}
+	if _, err := server.SetImagePolicy(catchrpc.ImagePolicySetRequest{Mode: cli.ImagePolicyRequireSigned, TrustKeys: []string{trustKey}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Signatures of images from other registries are only read locally.
+	setTestImageRef(t, server, "web", "latest", testSignedDigest)
+	pushTestSignature(t, storage, "web", testSignedDigest, key)
+	external := &fakeAutoUpdateCompose{images: []string{"ghcr.io/acme/app:2"}, digests: map[string]string{"ghcr.io/acme/app:2": testUnsignedDigest}}
+	_, err := server.verifyComposeImages(ctx, "web", external, false)
+	if err == nil || !strings.Contains(err.Error(), "signature not available offline; copy ghcr.io/acme/app:"+registry.SignatureTag(testUnsignedDigest)+" into catchit.dev/web") {
+		t.Fatalf("external image without a local signature error = %v", err)
+	}
+
+	// Signatures pushed next to the service's images cover external images.
+	checked, err := server.verifyComposeImages(ctx, "web", compose, true)
+	if !checked || err != nil {
+		t.Fatalf("verifyComposeImages signed = %v, %v", checked, err)
+	}
+	if !reflect.DeepEqual(compose.calls, []string{"pull"}) {
+		t.Fatalf("calls = %q", compose.calls)
+	}
+
+	// The registry mirror cache holds signatures of the registry it mirrors.
+	if _, err := server.cfg.DB.MutateData(func(d *db.Data) error {
+		d.RegistryMirror = &db.RegistryMirrorConfig{Upstream: cli.RegistryMirrorDockerHub}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	cache, err := registry.NewFilesystemStorage(server.registryMirrorCacheDir(cli.RegistryMirrorDockerHub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pushTestSignature(t, cache, "library/nginx", testUnsignedDigest, key)
+	mirrored := &fakeAutoUpdateCompose{images: []string{"nginx:1"}, digests: map[string]string{"nginx:1": testUnsignedDigest}}
+	if _, err := server.verifyComposeImages(ctx, "web", mirrored, false); err != nil {
+		t.Fatalf("verifyComposeImages from the mirror cache: %v", err)
+	}
+
+	setTestImageRef(t, server, "web", "latest", testUnsignedDigest)
+	_, err = server.verifyComposeImages(ctx, "web", compose, false)
+	if err == nil || !strings.Contains(err.Error(), "image policy requires signed images: catchit.dev/web ("+testUnsignedDigest+"): no signature found") {
+		t.Fatalf("unsigned internal image error = %v", err)
+	}
+
+	if err := server.setServiceImagePolicy("web", cli.ImagePolicyOff); err != nil {
+		t.Fatal(err)
+	}
+	if checked, err := server.verifyComposeImages(ctx, "web", compose, false); checked || err != nil {
+		t.Fatalf("verifyComposeImages with service override off = %v, %v", checked, err)
+	}
+}
+
+func TestUpdateComposeRestoresImagesThatFailThePolicy(t *testing.T) {
+	server := newTestServer(t)
+	key, trustKey := newTestTrustKey(t)
+	addTestComposeService(t, server, "web", nil)
+	if _, err := server.SetImagePolicy(catchrpc.ImagePolicySetRequest{Mode: cli.ImagePolicyRequireSigned, TrustKeys: []string{trustKey}}); err != nil {
+		t.Fatal(err)
+	}
+	pushTestSignature(t, server.registry.storage, "web", testSignedDigest, key)
+	ctx := context.Background()
+	update := func() error { t.Fatal("update ran under the image policy"); return nil }
+
+	setTestImageRef(t, server, "web", "latest", testUnsignedDigest)
+	compose := &fakeAutoUpdateCompose{images: []string{"catchit.dev/web"}}
+	if err := server.updateCompose(ctx, "web", compose, update); err == nil || !strings.Contains(err.Error(), "no signature found") {
+		t.Fatalf("updateCompose unsigned error = %v", err)
+	}
+	if want := []string{"ids", "pull", "restore sha256:old"}; !reflect.DeepEqual(compose.calls, want) {
+		t.Fatalf("calls = %q, want %q", compose.calls, want)
+	}
+
+	setTestImageRef(t, server, "web", "latest", testSignedDigest)
+	compose = &fakeAutoUpdateCompose{images: []string{"catchit.dev/web"}}
+	if err := server.updateCompose(ctx, "web", compose, update); err != nil {
+		t.Fatalf("updateCompose signed: %v", err)
+	}
+	if want := []string{"ids", "pull", "up"}; !reflect.DeepEqual(compose.calls, want) {
+		t.Fatalf("calls = %q, want %q", compose.calls, want)
+	}
+}
+
+func TestRegistrySignaturePushRetriesRunInstall(t *testing.T) {
+	server := newTestServer(t)
+	key, trustKey := newTestTrustKey(t)
+	storage := server.registry.storage
+	var installs int
+	storage.newInstaller = func(*Server, FileInstallerCfg) (registryInstaller, error) {
+		installs++
+		return &stubInstaller{}, nil
+	}
+	addTestComposeService(t, server, "web", nil)
+	setTestImageRef(t, server, "web", "run", testSignedDigest)
+
+	// Without a policy a signature is only stored.
+	pushTestSignature(t, storage, "web", testSignedDigest, key)
+	if installs != 0 {
+		t.Fatalf("installs = %d without a policy, want 0", installs)
+	}
+	if _, ok, err := storage.lookupManifest("web", registry.SignatureTag(testSignedDigest)); err != nil || !ok {
+		t.Fatalf("signature ref recorded = %v, %v", ok, err)
+	}
+
+	if _, err := server.SetImagePolicy(catchrpc.ImagePolicySetRequest{Mode: cli.ImagePolicyRequireSigned, TrustKeys: []string{trustKey}}); err != nil {
+		t.Fatal(err)
+	}
+	pushTestSignature(t, storage, "web", testUnsignedDigest, key)
+	if installs != 0 {
+		t.Fatalf("installs = %d for a signature of another image, want 0", installs)
+	}
+	pushTestSignature(t, storage, "web", testSignedDigest, key)
+	if installs != 1 {
+		t.Fatalf("installs = %d after signing the run image, want 1", installs)
+	}
+}
@@ -59,6 +59,11 @@ type FileInstallerCfg struct {
 	SnapshotPolicyChange bool
 	SnapshotPolicy       *db.SnapshotPolicy
 	snapshotPolicyFlags  *cli.ServiceSetFlags
+	// ComposeFiles and ComposeEnvFiles hold client-encoded file contents;
+	// see composeProjectFlagPrefix.
+	ComposeFiles    []string
+	ComposeEnvFiles []string
+	Profiles        []string
 	// PayloadName preserves the original filename for type detection.
 	PayloadName string
 
@@ -1151,7 +1156,7 @@ func (i *FileInstaller) writeDockerComposeNetwork(env netns.Service) error {
 	if err != nil {
 		return err
 	}
-	dockerNet, err := renderDockerComposeNetwork(env, services)
+	dockerNet, err := renderDockerComposeNetwork(i.s.containerRuntime(), env, services)
 	if err != nil {
 		return err
 	}
@@ -1165,7 +1170,9 @@ func (i *FileInstaller) writeDockerComposeNetwork(env netns.Service) error {
 
 func (i *FileInstaller) composeDNSOverlayServices(env netns.Service) ([]composeDNSService, error) {
 	composePath, ok := i.artifacts[db.ArtifactDockerComposeFile]
-	if !ok || !env.ServiceIP.IsValid() {
+	// Podman places every compose service in the namespace itself, so it
+	// needs the services even without a service IP.
+	if !ok || (!env.ServiceIP.IsValid() && !i.s.containerRuntime().IsPodman()) {
 		return nil, nil
 	}
 	raw, err := os.ReadFile(composePath)
@@ -1176,14 +1183,48 @@ func (i *FileInstaller) composeDNSOverlayServices(env netns.Service) ([]composeD
 	if err != nil {
 		return nil, fmt.Errorf("compose DNS overlay: %w", err)
 	}
+	if i.s.containerRuntime().IsPodman() {
+		if err := i.rejectPodmanComposePorts(raw); err != nil {
+			return nil, err
+		}
+	}
 	for _, service := range services {
-		if service.CustomResolver {
+		if service.CustomResolver && env.ServiceIP.IsValid() {
 			i.printf("warning: compose service %q defines dns or dns_search; leaving resolver configuration unchanged\n", service.Name)
 		}
 	}
 	return services, nil
 }
 
+// rejectPodmanComposePorts refuses compose files that publish ports on a
+// podman host. Podman runs every compose service in the service network
+// namespace, and port forwards into it come from the yeet Docker network
+// plugin, so published ports would never be reachable.
+func (i *FileInstaller) rejectPodmanComposePorts(base []byte) error {
+	files := [][]byte{base}
+	for n := 0; ; n++ {
+		path, ok := i.artifacts[db.ArtifactDockerComposeOverride(n)]
+		if !ok {
+			break
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read compose override file: %w", err)
+		}
+		files = append(files, raw)
+	}
+	for _, raw := range files {
+		names, err := composeServicesWithPorts(raw)
+		if err != nil {
+			return err
+		}
+		if len(names) > 0 {
+			return fmt.Errorf("compose service %q publishes ports; this host runs podman, which cannot publish ports from the service network namespace, remove ports: (and --publish) or use the docker container runtime", names[0])
+		}
+	}
+	return nil
+}
+
 func (i *FileInstaller) setArtifacts(files map[db.ArtifactName]string) {
 	for k, v := range files {
 		mak.Set(&i.artifacts, k, v)
@@ -1638,6 +1679,9 @@ type fileInstallPlan struct {
 	allowServiceTypeUpgrade bool
 	publish                 []string
 	publishSet              bool
+	// composeProject replaces the staged compose project with the one this
+	// install carries, which may be empty.
+	composeProject bool
 }
 
 func (i *FileInstaller) installOnClose() error {
@@ -2064,6 +2108,13 @@ func (i *FileInstaller) configureAndStageNativeISOInstall(plan fileInstallPlan)
 }
 
 func (i *FileInstaller) configureAndStageComposeISOInstall(plan fileInstallPlan) error {
+	if i.s.containerRuntime().IsPodman() {
+		// ISO projects attach through the yeet Docker network plugin.
+		return fmt.Errorf("--net=iso needs the docker container runtime; this host runs podman")
+	}
+	if i.cfg.hasComposeProject() {
+		return errors.New(composeProjectISOMessage)
+	}
 	if _, err := i.prepareISOCompose(context.Background(), nil); err != nil {
 		return err
 	}
@@ -2245,6 +2296,9 @@ func (t *fileInstallerISOTransition) StartReplacement(ctx context.Context, _ str
 }
 
 func (i *FileInstaller) prepareInstallPlan(tmppath string) (fileInstallPlan, error) {
+	if i.cfg.hasComposeProject() && (i.cfg.EnvFile || i.cfg.NoBinary) {
+		return fileInstallPlan{}, errors.New(composeProjectOnlyMessage)
+	}
 	switch {
 	case i.cfg.EnvFile:
 		return i.prepareEnvFileInstall(), nil
@@ -2458,11 +2512,17 @@ func (i *FileInstaller) composePayloadPublishesPorts(bin string, binFT ftdetect.
 
 func (i *FileInstaller) preparePayloadByType(bin string, binFT ftdetect.FileType) (fileInstallPlan, error) {
 	if systemdPayloadType(binFT) {
+		if i.cfg.hasComposeProject() {
+			return fileInstallPlan{}, errors.New(composeProjectOnlyMessage)
+		}
 		return i.prepareSystemdPayload(binFT)
 	}
 	if binFT == ftdetect.DockerCompose {
 		return i.prepareDockerComposePayload(bin)
 	}
+	if i.cfg.hasComposeProject() {
+		return fileInstallPlan{}, errors.New(composeProjectOnlyMessage)
+	}
 	if cfg, ok := generatedPayloadTypes[binFT]; ok {
 		return i.prepareGeneratedComposePayload(cfg.message, cfg.payloadName, cfg.artifactName, cfg.kind, cfg.render)
 	}
@@ -2801,6 +2861,9 @@ func (i *FileInstaller) prepareDockerComposePayload(bin string) (fileInstallPlan
 		publish = nil
 	}
 	publishSet := err == nil || publishChanged
+	if err := i.stageComposeProject(); err != nil {
+		return fileInstallPlan{}, err
+	}
 	dst := filepath.Join(i.serviceBinDir(), fmt.Sprintf("docker-compose.%s.yml", i.version()))
 	mak.Set(&i.artifacts, db.ArtifactDockerComposeFile, dst)
 	return fileInstallPlan{
@@ -2808,6 +2871,7 @@ func (i *FileInstaller) prepareDockerComposePayload(bin string) (fileInstallPlan
 		detectedServiceType: db.ServiceTypeDockerCompose,
 		publish:             publish,
 		publishSet:          publishSet,
+		composeProject:      true,
 	}, nil
 }
 
@@ -2830,6 +2894,7 @@ func (i *FileInstaller) prepareGeneratedComposePayload(message, payloadName stri
 		allowServiceTypeUpgrade: true,
 		publish:                 normalizePublish(i.cfg.Publish),
 		publishSet:              true,
+		composeProject:          true,
 	}, nil
 }
 
@@ -2966,6 +3031,9 @@ func (i *FileInstaller) applyInstallPlanToService(s *db.Service, plan fileInstal
 	}
 	applyInstallNetworks(s, i.macvlan, i.svcNet, i.tsNet)
 	applyInstallPublish(s, plan)
+	if plan.composeProject {
+		unstageComposeProject(s, i.artifacts)
+	}
 	stageArtifacts(s, i.artifacts)
 	if err := i.stageResolvedSandboxPolicy(s, plan); err != nil {
 		return err
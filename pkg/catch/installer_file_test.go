@@ -3927,6 +3927,43 @@ func TestISOComposeOverlayBypassesLegacyDNSOverlay(t *testing.T) {
 	}
 }
 
+func TestWriteDockerComposeNetworkRejectsPortsOnPodman(t *testing.T) {
+	server := newTestServer(t)
+	if _, err := server.cfg.DB.MutateData(func(d *db.Data) error {
+		d.ContainerRuntime = string(svc.ContainerRuntimePodman)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.ensureDirs("web", ""); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	composePath := filepath.Join(dir, "compose.yml")
+	overridePath := filepath.Join(dir, "override.yml")
+	if err := os.WriteFile(composePath, []byte("services:\n  app:\n    image: nginx\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(overridePath, []byte("services:\n  app:\n    ports: [\"8080:80\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	installer := &FileInstaller{
+		s:   server,
+		cfg: FileInstallerCfg{InstallerCfg: InstallerCfg{ServiceName: "web"}},
+		artifacts: map[db.ArtifactName]string{
+			db.ArtifactDockerComposeFile: composePath,
+		},
+	}
+	env := netns.Service{ServiceName: "web"}
+	if err := installer.writeDockerComposeNetwork(env); err != nil {
+		t.Fatalf("writeDockerComposeNetwork without ports: %v", err)
+	}
+	installer.artifacts[db.ArtifactDockerComposeOverride(0)] = overridePath
+	if err := installer.writeDockerComposeNetwork(env); err == nil || !strings.Contains(err.Error(), `compose service "app" publishes ports`) {
+		t.Fatalf("writeDockerComposeNetwork with override ports = %v, want podman ports refusal", err)
+	}
+}
+
 func TestInstallerCloseStagesComposeLANOverlayWithoutYeetDNS(t *testing.T) {
 	oldHostDefaultRouteInterfaceFn := hostDefaultRouteInterfaceFn
 	hostDefaultRouteInterfaceFn = func() (string, error) { return "vmbr0", nil }
@@ -940,18 +940,27 @@ func installDockerComposeService(si *Installer, s *db.Service) error {
 		}
 		return si.installISOComposeService(s)
 	}
-	// Check that docker is installed before trying to install.
-	if _, err := svc.DockerCmd(); err != nil {
-		return err // svc.ErrDockerNotFound
-	}
 	service, err := si.newDockerComposeService(s)
 	if err != nil {
 		return fmt.Errorf("failed to create service: %v", err)
 	}
-	if err := service.InstallWithPull(si.icfg.Pull); err != nil {
+	// Check that the container runtime is installed before trying to install.
+	if _, err := service.DockerCmd(); err != nil {
+		return err // svc.ErrDockerNotFound
+	}
+	// Check images before the running containers are stopped.
+	pull := si.icfg.Pull
+	verified, err := si.s.verifyComposeImages(context.Background(), s.Name, service, pull)
+	if err != nil {
+		return err
+	}
+	if verified {
+		pull = false
+	}
+	if err := service.InstallWithPull(pull); err != nil {
 		return fmt.Errorf("failed to install service: %v", err)
 	}
-	if err := service.UpWithPull(si.icfg.Pull); err != nil {
+	if err := service.UpWithPull(pull); err != nil {
 		return fmt.Errorf("failed to up service: %v", err)
 	}
 	return nil
@@ -977,7 +986,7 @@ type isoComposeLifecycle struct {
 }
 
 func (si *Installer) installISOComposeService(record *db.Service) error {
-	if _, err := svc.DockerCmd(); err != nil {
+	if _, err := svc.ContainerRuntimeDocker.LookPath(); err != nil {
 		return err
 	}
 	compose, err := si.newDockerComposeService(record)
@@ -1212,13 +1221,20 @@ func (l *isoComposeLifecycle) InstallTailscale(ctx context.Context, _ *FileInsta
 }
 
 func (l *isoComposeLifecycle) Pull(ctx context.Context) error {
-	if !l.si.icfg.Pull {
-		return nil
+	if l.si.icfg.Pull {
+		if l.pullCompose != nil {
+			if err := l.pullCompose(ctx); err != nil {
+				return err
+			}
+		} else if err := l.compose.PullContext(ctx); err != nil {
+			return err
+		}
 	}
-	if l.pullCompose != nil {
-		return l.pullCompose(ctx)
+	if l.compose == nil {
+		return nil
 	}
-	return l.compose.PullContext(ctx)
+	_, err := l.si.s.verifyComposeImages(ctx, l.record.Name, l.compose, false)
+	return err
 }
 func (l *isoComposeLifecycle) Build(context.Context) error { return nil }
 
@@ -1412,13 +1428,13 @@ func installDockerComposeServiceDefinition(si *Installer, s *db.Service) error {
 	if s.ISO != nil {
 		return si.installISOComposeServiceDefinition(s)
 	}
-	if _, err := svc.DockerCmd(); err != nil {
-		return err
-	}
 	service, err := si.newDockerComposeService(s)
 	if err != nil {
 		return fmt.Errorf("failed to create service: %v", err)
 	}
+	if _, err := service.DockerCmd(); err != nil {
+		return err
+	}
 	if err := service.InstallWithPull(si.icfg.Pull); err != nil {
 		return fmt.Errorf("failed to install service: %v", err)
 	}
@@ -1426,7 +1442,7 @@ func installDockerComposeServiceDefinition(si *Installer, s *db.Service) error {
 }
 
 func (si *Installer) installISOComposeServiceDefinition(record *db.Service) error {
-	if _, err := svc.DockerCmd(); err != nil {
+	if _, err := svc.ContainerRuntimeDocker.LookPath(); err != nil {
 		return err
 	}
 	compose, err := si.newDockerComposeService(record)
@@ -1476,14 +1492,7 @@ func (si *Installer) suspendUI() {
 }
 
 func (si *Installer) newDockerComposeService(s *db.Service) (*svc.DockerComposeService, error) {
-	serviceRoot := si.s.serviceRootFromView(s.View())
-	service, err := svc.NewDockerComposeService(
-		si.s.cfg.DB,
-		s.View(),
-		serviceDataDirForRoot(serviceRoot),
-		serviceRunDirForRoot(serviceRoot),
-		svc.WithTailscaleGuardRunner(si.s.catchRunnerPath()),
-	)
+	service, err := si.s.newDockerComposeServiceAt(s.View(), si.s.serviceRootFromView(s.View()))
 	if err != nil {
 		return nil, err
 	}
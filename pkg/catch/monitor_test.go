@@ -6,6 +6,7 @@ package catch
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
@@ -143,6 +144,22 @@ func TestDockerMonitorEventHandlesColonActionAndDestroy(t *testing.T) {
 	}
 }
 
+func TestDockerMonitorEventNormalizesPodmanEvents(t *testing.T) {
+	server := newTestServer(t)
+	addTestService(t, server, "web", db.ServiceTypeDockerCompose)
+
+	var entry dockerMonitorEvent
+	raw := `{"ID":"abc","Name":"catch-web-app-1","Status":"died","Type":"container","Attributes":{"com.docker.compose.project":"catch-web","com.docker.compose.service":"app"}}`
+	if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+		t.Fatal(err)
+	}
+	entry.normalize()
+	if !server.handleDockerMonitorEvent(entry) {
+		t.Fatalf("expected podman died event to publish: %#v", entry)
+	}
+	assertStoredComponentStatus(t, server, "web", "app", ComponentStatusStopped)
+}
+
 func TestDockerMonitorEventFiltersUntrackedEvents(t *testing.T) {
 	server := newTestServer(t)
 	addTestService(t, server, "web", db.ServiceTypeDockerCompose)
@@ -142,7 +142,7 @@ type systemdMountFileSet struct {
 
 func systemdMountFiles(root string, vol db.Volume) (systemdMountFileSet, error) {
 	unitName := translateMountPathToUnitName(vol.Path)
-	mountContent, err := renderSystemdTemplate(systemdMountTemplate, vol)
+	mountContent, err := renderSystemdTemplate(systemdMountTemplate, systemdMountVolume(vol))
 	if err != nil {
 		return systemdMountFileSet{}, err
 	}
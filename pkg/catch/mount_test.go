@@ -13,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/svc"
 )
 
 func TestTranslateMountPathToUnitName(t *testing.T) {
@@ -238,7 +239,12 @@ func stubSystemdMountHooks(t *testing.T, systemdRoot string, commands *[][]strin
 	oldSystemdDir := systemdSystemDir
 	oldRunSystemdCommand := runSystemdCommand
 	oldSystemdQuietStatus := systemdQuietStatus
+	oldDisableComposeRestart := disableComposeRestart
 	systemdSystemDir = systemdRoot
+	disableComposeRestart = func(_ svc.ContainerRuntime, service string) error {
+		*commands = append(*commands, []string{"restart-gate", service})
+		return nil
+	}
 	runSystemdCommand = func(args ...string) error {
 		*commands = append(*commands, append([]string(nil), args...))
 		return nil
@@ -251,5 +257,6 @@ func stubSystemdMountHooks(t *testing.T, systemdRoot string, commands *[][]strin
 		systemdSystemDir = oldSystemdDir
 		runSystemdCommand = oldRunSystemdCommand
 		systemdQuietStatus = oldSystemdQuietStatus
+		disableComposeRestart = oldDisableComposeRestart
 	})
 }
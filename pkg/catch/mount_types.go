@@ -0,0 +1,518 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/svc"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	mountTypeNFS   = "nfs"
+	mountTypeCIFS  = "cifs"
+	mountTypeSSHFS = "sshfs"
+	mountTypeBind  = "bind"
+
+	// maxMountCredentialsBytes bounds the credentials payload; CIFS
+	// credential files and SSH keys are a few KiB at most.
+	maxMountCredentialsBytes = 64 << 10
+
+	mountHealthTimeout = 2 * time.Second
+)
+
+var (
+	// mountHealthCheck reports the state of a mount target for listings.
+	mountHealthCheck = checkMountHealth
+	// installMountRequirements and removeMountRequirements manage the
+	// systemd drop-ins that order services after their mounts.
+	installMountRequirements = writeMountRequirementDropIns
+	removeMountRequirements  = removeMountRequirementDropIns
+	// disableComposeRestart turns off the restart policy of a gated
+	// project's existing containers until its next deploy applies the gate.
+	disableComposeRestart = disableComposeProjectRestart
+)
+
+// normalizeMountType maps accepted aliases onto the type stored in the
+// database.
+func normalizeMountType(mountType string) string {
+	switch mountType {
+	case "":
+		return mountTypeNFS
+	case "smb", "smb3":
+		return mountTypeCIFS
+	}
+	return mountType
+}
+
+// defaultCheckMountCommand verifies the host can perform mounts of the
+// given type. Bind mounts are handled by the kernel and need no helper.
+func defaultCheckMountCommand(mountType string) error {
+	switch mountType {
+	case mountTypeBind:
+		return nil
+	case mountTypeSSHFS:
+		if _, err := exec.LookPath("sshfs"); err != nil {
+			return fmt.Errorf("sshfs not found; install the sshfs package on the host")
+		}
+		return nil
+	}
+	mountCmd := fmt.Sprintf("/sbin/mount.%s", mountType)
+	if _, err := os.Stat(mountCmd); err != nil {
+		return fmt.Errorf("mount command %q not found", mountCmd)
+	}
+	return nil
+}
+
+// mountSourcePath validates source for mountType and returns the path part
+// used to derive the default mount name.
+func mountSourcePath(mountType, source string) (string, error) {
+	switch mountType {
+	case mountTypeCIFS:
+		share := strings.TrimPrefix(source, "//")
+		server, path, ok := strings.Cut(share, "/")
+		if !strings.HasPrefix(source, "//") || !ok || server == "" || strings.Trim(path, "/") == "" {
+			return "", fmt.Errorf("source %q must be in the format //server/share", source)
+		}
+		return path, nil
+	case mountTypeBind:
+		if !filepath.IsAbs(source) {
+			return "", fmt.Errorf("source %q must be an absolute host path", source)
+		}
+		return source, nil
+	}
+	_, path, ok := strings.Cut(source, ":")
+	if !ok {
+		return "", fmt.Errorf("source %q must be in the format host:path", source)
+	}
+	return path, nil
+}
+
+// systemdMountVolume translates a stored volume into the What/Type/Options
+// systemd expects for its mount type.
+func systemdMountVolume(vol db.Volume) db.Volume {
+	switch vol.Type {
+	case mountTypeBind:
+		vol.Type = "none"
+		vol.Opts = joinMountOptions("bind", userMountOptions(vol.Opts))
+	case mountTypeSSHFS:
+		vol.Type = "fuse.sshfs"
+		opts := joinMountOptions("_netdev", "allow_other", "reconnect", "ServerAliveInterval=15", "ServerAliveCountMax=3", userMountOptions(vol.Opts))
+		if vol.Credentials != "" {
+			opts = joinMountOptions(opts, "IdentityFile="+vol.Credentials)
+		}
+		// accept-new records the first key seen instead of waiting on a
+		// prompt nobody answers, and rejects a key that later changes or
+		// differs from the one pinned with --host-key.
+		if vol.KnownHosts != "" {
+			opts = joinMountOptions(opts, "UserKnownHostsFile="+vol.KnownHosts, "StrictHostKeyChecking=accept-new")
+		}
+		vol.Opts = opts
+	case mountTypeCIFS:
+		if vol.Credentials != "" {
+			vol.Opts = joinMountOptions(userMountOptions(vol.Opts), "credentials="+vol.Credentials)
+		}
+	}
+	if vol.Opts == "" {
+		vol.Opts = "defaults"
+	}
+	return vol
+}
+
+func userMountOptions(opts string) string {
+	if opts == "defaults" {
+		return ""
+	}
+	return opts
+}
+
+func joinMountOptions(opts ...string) string {
+	var parts []string
+	for _, opt := range opts {
+		if opt != "" {
+			parts = append(parts, opt)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// mountCredentialsPath is where catch keeps the secret for the named mount,
+// outside the mounts and services roots so it never ends up in a service
+// export or snapshot.
+func mountCredentialsPath(rootDir, name string) string {
+	return filepath.Join(rootDir, "secrets", "mounts", name)
+}
+
+// mountKnownHostsPath is the known_hosts file of the named sshfs mount.
+func mountKnownHostsPath(rootDir, name string) string {
+	return mountCredentialsPath(rootDir, name) + ".known_hosts"
+}
+
+// mountKnownHosts renders the known_hosts file of an sshfs mount of source
+// that pins hostKey, or an empty one that the first connection fills in.
+func mountKnownHosts(source, opts, hostKey string) ([]byte, error) {
+	if hostKey == "" {
+		return nil, nil
+	}
+	fields := strings.Fields(hostKey)
+	if len(fields) < 2 || !isSSHHostKeyType(fields[0]) {
+		return nil, fmt.Errorf("--host-key must be an SSH public key such as \"ssh-ed25519 AAAA...\"")
+	}
+	if _, err := base64.StdEncoding.DecodeString(fields[1]); err != nil {
+		return nil, fmt.Errorf("--host-key has an invalid key: %w", err)
+	}
+	host, _, _ := strings.Cut(source, ":")
+	if _, after, ok := strings.Cut(host, "@"); ok {
+		host = after
+	}
+	for _, opt := range strings.Split(opts, ",") {
+		if port, ok := strings.CutPrefix(opt, "port="); ok && port != "22" {
+			host = "[" + host + "]:" + port
+		}
+	}
+	return []byte(host + " " + fields[0] + " " + fields[1] + "\n"), nil
+}
+
+func isSSHHostKeyType(keyType string) bool {
+	for _, prefix := range []string{"ssh-", "ecdsa-sha2-", "sk-"} {
+		if strings.HasPrefix(keyType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// readMountCredentials reads and validates the credentials payload streamed
+// by the client for mountType.
+func readMountCredentials(r io.Reader, mountType string) ([]byte, error) {
+	if mountType != mountTypeCIFS && mountType != mountTypeSSHFS {
+		return nil, fmt.Errorf("--credentials only applies to cifs and sshfs mounts")
+	}
+	raw, err := io.ReadAll(io.LimitReader(r, maxMountCredentialsBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+	if len(raw) > maxMountCredentialsBytes {
+		return nil, fmt.Errorf("credentials exceed %d bytes", maxMountCredentialsBytes)
+	}
+	if mountType == mountTypeCIFS && !bytes.Contains(raw, []byte("username=")) {
+		return nil, fmt.Errorf("cifs credentials must contain a username= line")
+	}
+	if mountType == mountTypeSSHFS && !bytes.Contains(raw, []byte("PRIVATE KEY-----")) {
+		return nil, fmt.Errorf("sshfs credentials must be an SSH private key")
+	}
+	return raw, nil
+}
+
+func writeMountCredentials(path string, raw []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+	if err := writeTextFileAtomically(path, raw, 0o600); err != nil {
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	return nil
+}
+
+// mountRequirementUnit is a systemd unit that must wait for a mount.
+// Content, when set, is the unit file itself; catch owns such units and
+// writes them next to their drop-ins.
+type mountRequirementUnit struct {
+	Dir     string
+	Unit    string
+	Content string
+	// Compose is the service whose compose project the unit gates, and
+	// Runtime the engine that runs it.
+	Compose string
+	Runtime svc.ContainerRuntime
+}
+
+// mountRequirementUnitForService returns the unit that starts sv. Compose
+// containers have no unit of their own, so they get a catch-managed one that
+// starts the project once its mounts are up and stops it when they go away;
+// dockerd and every other project on the host stay independent of the share.
+// The project then runs with its restart policies turned off (see
+// writeComposeRestartGate), or dockerd would start it at boot before the
+// mounts exist.
+func mountRequirementUnitForService(sv db.ServiceView, rt svc.ContainerRuntime) mountRequirementUnit {
+	switch sv.ServiceType() {
+	case db.ServiceTypeVM:
+		return mountRequirementUnit{Dir: vmSystemdSystemDir, Unit: vmSystemdUnitName(sv.Name())}
+	case db.ServiceTypeDockerCompose:
+		return mountRequirementUnit{
+			Dir:     systemdSystemDir,
+			Unit:    composeMountUnitName(sv.Name()),
+			Content: composeMountUnitContent(sv.Name(), rt),
+			Compose: sv.Name(),
+			Runtime: rt,
+		}
+	default:
+		return mountRequirementUnit{Dir: systemdSystemDir, Unit: sv.Name() + ".service"}
+	}
+}
+
+// composeMountUnitName is the unit that gates a compose project on its
+// mounts.
+func composeMountUnitName(service string) string {
+	return "yeet-" + service + "-compose.service"
+}
+
+// composeMountUnitContent starts and stops an existing compose project by
+// name; the project's compose files stay managed by catch.
+func composeMountUnitContent(service string, runtime svc.ContainerRuntime) string {
+	rt := runtime.Command()
+	project := svc.ComposeProjectName(service)
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	fmt.Fprintf(&b, "Description=yeet compose project %s\n", service)
+	if !runtime.IsPodman() {
+		fmt.Fprintf(&b, "Wants=%s\nAfter=%s\n", dockerServiceUnit, dockerServiceUnit)
+	}
+	b.WriteString("\n[Service]\n")
+	b.WriteString("Type=oneshot\n")
+	b.WriteString("RemainAfterExit=yes\n")
+	fmt.Fprintf(&b, "ExecStart=%s compose --project-name %s start\n", rt, project)
+	fmt.Fprintf(&b, "ExecStop=%s compose --project-name %s stop\n", rt, project)
+	b.WriteString("\n[Install]\n")
+	b.WriteString("WantedBy=multi-user.target\n")
+	return b.String()
+}
+
+func mountRequirementDropInName(mountName string) string {
+	return "yeet-mount-" + mountName + ".conf"
+}
+
+func writeMountRequirementDropIns(vol db.Volume, units []mountRequirementUnit) error {
+	if len(units) == 0 {
+		return nil
+	}
+	content := "[Unit]\nRequiresMountsFor=" + vol.Path + "\n"
+	var owned []mountRequirementUnit
+	for _, u := range units {
+		if u.Content != "" {
+			if _, err := writeTextFileIfChanged(filepath.Join(u.Dir, u.Unit), u.Content, 0o644); err != nil {
+				return err
+			}
+			owned = append(owned, u)
+		}
+		path := filepath.Join(u.Dir, u.Unit+".d", mountRequirementDropInName(vol.Name))
+		if _, err := writeTextFileIfChanged(path, content, 0o644); err != nil {
+			return err
+		}
+	}
+	if err := runSystemdCommand("daemon-reload"); err != nil {
+		return fmt.Errorf("failed to reload systemd: %v", err)
+	}
+	for _, u := range owned {
+		if err := runSystemdCommand("enable", u.Unit); err != nil {
+			return fmt.Errorf("failed to enable %s: %v", u.Unit, err)
+		}
+		if u.Compose == "" {
+			continue
+		}
+		if err := disableComposeRestart(u.Runtime, u.Compose); err != nil {
+			return fmt.Errorf("failed to turn off restart policies of %s: %v", u.Compose, err)
+		}
+	}
+	return nil
+}
+
+// composeRestartGateName is the compose file, in the service run directory,
+// that turns off the restart policies of a gated project.
+const composeRestartGateName = "compose.restart-gate.yml"
+
+// writeComposeRestartGate writes the compose file that sets restart: "no" on
+// every service of sv's current generation when a mount gate unit owns the
+// project's startup, and returns its path. Without a gate it removes a stale
+// file and returns "".
+func writeComposeRestartGate(sv db.ServiceView, runDir string) (string, error) {
+	path := filepath.Join(runDir, composeRestartGateName)
+	if _, err := os.Stat(filepath.Join(systemdSystemDir, composeMountUnitName(sv.Name()))); err != nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		return "", nil
+	}
+	service := sv.AsStruct()
+	var names []string
+	for _, file := range service.Artifacts.ComposeFiles(db.Gen(service.Generation)) {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("read compose file for restart gate: %w", err)
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal(raw, &doc); err != nil {
+			return "", fmt.Errorf("parse compose file for restart gate: %w", err)
+		}
+		services := yamlMappingValue(yamlDocumentRoot(&doc), "services")
+		if services == nil || services.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(services.Content); i += 2 {
+			if name := services.Content[i].Value; !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return "", nil
+	}
+	var b strings.Builder
+	b.WriteString("services:\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %s:\n    restart: \"no\"\n", strconv.Quote(name))
+	}
+	if err := os.MkdirAll(runDir, 0o755); err != nil {
+		return "", err
+	}
+	if _, err := writeTextFileIfChanged(path, b.String(), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func disableComposeProjectRestart(runtime svc.ContainerRuntime, service string) error {
+	rt := runtime.Command()
+	out, err := exec.Command(rt, "ps", "-aq", "--filter", "label=com.docker.compose.project="+svc.ComposeProjectName(service)).Output()
+	if err != nil {
+		return err
+	}
+	ids := strings.Fields(string(out))
+	if len(ids) == 0 {
+		return nil
+	}
+	if out, err := exec.Command(rt, append([]string{"update", "--restart=no"}, ids...)...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// removeMountRequirementDropIns removes the mount's drop-ins from every unit,
+// so services removed since the mount was created do not leave them behind.
+// Compose gate units left without any mount requirement are removed too.
+func removeMountRequirementDropIns(vol db.Volume) error {
+	dirs := []string{systemdSystemDir}
+	if vmSystemdSystemDir != systemdSystemDir {
+		dirs = append(dirs, vmSystemdSystemDir)
+	}
+	var paths []string
+	for _, dir := range dirs {
+		matches, err := filepath.Glob(filepath.Join(dir, "*.d", mountRequirementDropInName(vol.Name)))
+		if err != nil {
+			return err
+		}
+		paths = append(paths, matches...)
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	for _, path := range paths {
+		if err := removeSystemdUnit(path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+	gates, err := filepath.Glob(filepath.Join(systemdSystemDir, composeMountUnitName("*")))
+	if err != nil {
+		return err
+	}
+	for _, gate := range gates {
+		left, err := filepath.Glob(filepath.Join(gate+".d", mountRequirementDropInName("*")))
+		if err != nil {
+			return err
+		}
+		if len(left) != 0 {
+			continue
+		}
+		if err := removeComposeMountUnitFile(filepath.Base(gate)); err != nil {
+			return err
+		}
+	}
+	if err := runSystemdCommand("daemon-reload"); err != nil {
+		return fmt.Errorf("failed to reload systemd: %v", err)
+	}
+	return nil
+}
+
+// removeComposeMountUnitFile disables and deletes a compose gate unit. It is
+// disabled rather than stopped: stopping would stop the project's containers.
+// The containers keep restart: "no" until the project's next deploy.
+func removeComposeMountUnitFile(unit string) error {
+	path := filepath.Join(systemdSystemDir, unit)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if err := runSystemdCommand("disable", unit); err != nil {
+		return fmt.Errorf("failed to disable %s: %v", unit, err)
+	}
+	if err := removeSystemdUnit(path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	if err := os.RemoveAll(path + ".d"); err != nil {
+		return fmt.Errorf("failed to remove %s.d: %w", path, err)
+	}
+	return nil
+}
+
+// removeComposeMountUnit drops the mount gate of a removed compose service.
+func removeComposeMountUnit(service string) error {
+	path := filepath.Join(systemdSystemDir, composeMountUnitName(service))
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if err := removeComposeMountUnitFile(composeMountUnitName(service)); err != nil {
+		return err
+	}
+	if err := runSystemdCommand("daemon-reload"); err != nil {
+		return fmt.Errorf("failed to reload systemd: %v", err)
+	}
+	return nil
+}
+
+// checkMountHealth reports "ok", "stale", "unmounted" or "missing" for a
+// mount target. A hung network mount blocks stat indefinitely, so the stat
+// runs in the background and a timeout counts as stale; the goroutine is
+// left to finish whenever the kernel gives up.
+func checkMountHealth(path string) string {
+	done := make(chan string, 1)
+	go func() { done <- statMountHealth(path) }()
+	select {
+	case status := <-done:
+		return status
+	case <-time.After(mountHealthTimeout):
+		return "stale"
+	}
+}
+
+func statMountHealth(path string) string {
+	dev, err := pathDevice(path)
+	if err != nil {
+		if errors.Is(err, syscall.ESTALE) || errors.Is(err, syscall.ENOTCONN) || errors.Is(err, syscall.EIO) {
+			return "stale"
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			return "missing"
+		}
+		return "error"
+	}
+	parentDev, err := pathDevice(filepath.Dir(path))
+	if err != nil || parentDev == dev {
+		return "unmounted"
+	}
+	return "ok"
+}
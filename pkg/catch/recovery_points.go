@@ -187,7 +187,7 @@ func (s *Server) setRecoveryPointProtected(ctx context.Context, serviceName, sel
 }
 
 func (s *Server) removeRecoveryPoint(ctx context.Context, serviceName, selector string, yes bool, rw io.ReadWriter) error {
-	point, _, err := s.resolveRecoveryPoint(ctx, serviceName, selector)
+	point, service, err := s.resolveRecoveryPoint(ctx, serviceName, selector)
 	if err != nil {
 		return err
 	}
@@ -207,6 +207,11 @@ func (s *Server) removeRecoveryPoint(ctx context.Context, serviceName, selector
 	if err := destroySnapshot(ctx, s.zfsRunner, point.Name); err != nil {
 		return err
 	}
+	if service.VM != nil {
+		if err := s.destroyVMDataDiskSnapshots(ctx, vmDataDiskSnapshotDatasets(service.VM.DataDisks), []string{point.Name}); err != nil {
+			return err
+		}
+	}
 	writef(rw, "Removed recovery point: %s\n", point.Name)
 	return nil
 }
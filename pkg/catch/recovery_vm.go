@@ -64,6 +64,9 @@ func (s *Server) cloneVMRecoveryPoint(ctx context.Context, service *db.Service,
 	}
 	writef(w, "Created VM service: %s (stopped).\n", newServiceName)
 	writef(w, "Cloned VM disk: %s\n", targetDataset)
+	if len(service.VM.DataDisks) > 0 {
+		writef(w, "Data disks were not cloned; add new ones with yeet vm disk add %s.\n", newServiceName)
+	}
 	return nil
 }
 
@@ -121,6 +124,9 @@ func (s *Server) restoreDiskVMRecoveryPoint(ctx context.Context, service *db.Ser
 		return err
 	}
 	writef(rw, "Restored VM disk: %s\n", point.Name)
+	if err := s.restoreVMDataDisksFromRecoveryPoint(ctx, service, point, rw); err != nil {
+		return err
+	}
 	if err := startVMAfterRestore(service.Name, flags.Start, rw); err != nil {
 		return err
 	}
@@ -194,7 +200,7 @@ func (s *Server) createPreRestoreVMSnapshot(ctx context.Context, service *db.Ser
 	if err != nil {
 		return "", err
 	}
-	result, err := s.createPausedVMSnapshot(ctx, service, dataset, flags)
+	result, err := s.createPausedVMSnapshot(ctx, service, dataset, vmDataDiskSnapshotDatasets(vm.DataDisks), flags)
 	return result.Name, err
 }
 
@@ -208,21 +214,56 @@ func (s *Server) serviceRootFromService(service *db.Service) string {
 	return s.defaultServiceRootDir(service.Name)
 }
 
+// restoreVMDataDisksFromRecoveryPoint restores each zvol data disk that has a
+// snapshot taken with the recovery point. Disks attached after the point was
+// taken are left as they are.
+func (s *Server) restoreVMDataDisksFromRecoveryPoint(ctx context.Context, service *db.Service, point recoveryPoint, w io.Writer) error {
+	runner := s.zfsRunner
+	if runner == nil {
+		runner = runZFSCommand
+	}
+	for _, disk := range service.VM.DataDisks {
+		if disk.Backend != vmDiskBackendZVOL {
+			writef(w, "Data disk %s is a raw file; left unchanged.\n", disk.Name)
+			continue
+		}
+		dataset := vmDataDiskDataset(disk)
+		snapshot := dataset + "@" + point.ShortName
+		exists, err := zfsDatasetExists(ctx, runner, snapshot)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			writef(w, "Data disk %s has no snapshot in %s; left unchanged.\n", disk.Name, point.ShortName)
+			continue
+		}
+		if err := s.restoreVMZVOLDatasetFromSnapshot(ctx, snapshot, dataset); err != nil {
+			return fmt.Errorf("restore data disk %s: %w", disk.Name, err)
+		}
+		writef(w, "Restored data disk %s: %s\n", disk.Name, snapshot)
+	}
+	return nil
+}
+
 func (s *Server) restoreVMZVOLFromSnapshot(ctx context.Context, point recoveryPoint) error {
-	tempDataset, err := vmRestoreTempDataset(point.Dataset)
+	return s.restoreVMZVOLDatasetFromSnapshot(ctx, point.Name, point.Dataset)
+}
+
+func (s *Server) restoreVMZVOLDatasetFromSnapshot(ctx context.Context, snapshot, dataset string) error {
+	tempDataset, err := vmRestoreTempDataset(dataset)
 	if err != nil {
 		return err
 	}
-	if err := zfsCloneSnapshot(ctx, s.zfsRunner, point.Name, tempDataset); err != nil {
+	if err := zfsCloneSnapshot(ctx, s.zfsRunner, snapshot, tempDataset); err != nil {
 		return err
 	}
 
-	if err := s.validateVMRestoreZVOLSizes(ctx, tempDataset, point.Dataset); err != nil {
+	if err := s.validateVMRestoreZVOLSizes(ctx, tempDataset, dataset); err != nil {
 		return vmRestoreTempCleanupError(tempDataset, err, zfsDestroyDataset(ctx, s.zfsRunner, tempDataset))
 	}
 
 	sourceDevice := zvolDevicePath(tempDataset)
-	targetDevice := zvolDevicePath(point.Dataset)
+	targetDevice := zvolDevicePath(dataset)
 	if err := currentVMRestoreZVOLDeviceWaiter()(ctx, sourceDevice, targetDevice); err != nil {
 		return vmRestoreTempCleanupError(tempDataset, err, zfsDestroyDataset(ctx, s.zfsRunner, tempDataset))
 	}
@@ -617,6 +658,7 @@ func cloneVMRecoveryService(source *db.Service, newServiceName string, targetDat
 	cloned.VM.Sockets.APISocketPath = replaceOrClearServiceSegment(cloned.VM.Sockets.APISocketPath, source.Name, newServiceName)
 	cloned.VM.PIDFile = replaceOrClearServiceSegment(cloned.VM.PIDFile, source.Name, newServiceName)
 	cloned.VM.Networks = cloneVMRecoveryNetworks(newServiceName, source.VM.Networks)
+	cloned.VM.DataDisks = nil
 	return cloned
 }
 
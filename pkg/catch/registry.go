@@ -6,14 +6,20 @@ package catch
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/netip"
 	"os"
+	"os/exec"
+	"runtime"
 	"strings"
+	"sync"
+	"time"
 
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/yeetrun/yeet/pkg/db"
 	"github.com/yeetrun/yeet/pkg/registry"
 	"github.com/yeetrun/yeet/pkg/svc"
@@ -29,6 +35,15 @@ type newRegistryInstaller func(*Server, FileInstallerCfg) (registryInstaller, er
 
 func (s *Server) newRegistry() *containerRegistry {
 	base := s.cfg.RegistryStorage
+	if base == nil && s.containerRuntime().IsPodman() {
+		// Podman does not read images from containerd. Pushes land on disk
+		// and are copied into podman's store, see loadPodmanImageFn.
+		var err error
+		base, err = registry.NewFilesystemStorage(s.cfg.RegistryRoot)
+		if err != nil {
+			log.Fatalf("NewFilesystemStorage: %v", err)
+		}
+	}
 	if base == nil {
 		if s.cfg.ContainerdSocket == "" {
 			log.Fatalf("containerd socket not configured; set --containerd-socket (default /run/containerd/containerd.sock)")
@@ -87,11 +102,42 @@ func (cr *containerRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	cr.handler.ServeHTTP(w, r)
 }
 
+// loadPodmanImageFn copies a pushed image into podman's store under the name
+// compose files use. Docker sees pushes directly through containerd; podman
+// pulls them back from the loopback side of the internal registry, which
+// serves reads only.
+var loadPodmanImageFn = func(ctx context.Context, registryAddr, repo, reference, digest string) error {
+	podman, err := svc.ContainerRuntimePodman.LookPath()
+	if err != nil {
+		return err
+	}
+	if registryAddr == "" {
+		return fmt.Errorf("internal registry address is not configured")
+	}
+	src := fmt.Sprintf("%s/%s@%s", registryAddr, repo, digest)
+	if out, err := exec.CommandContext(ctx, podman, "pull", "--quiet", "--tls-verify=false", src).CombinedOutput(); err != nil {
+		return fmt.Errorf("podman pull %s: %w: %s", src, err, strings.TrimSpace(string(out)))
+	}
+	dst := fmt.Sprintf("%s/%s:%s", svc.InternalRegistryHost, repo, reference)
+	if out, err := exec.CommandContext(ctx, podman, "tag", src, dst).CombinedOutput(); err != nil {
+		return fmt.Errorf("podman tag %s: %w: %s", dst, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// registryHostPlatform is the platform tagged pushes must provide; tests
+// override it.
+var registryHostPlatform = ocispec.Platform{OS: runtime.GOOS, Architecture: runtime.GOARCH}
+
 type internalRegistryStorage struct {
 	s            *Server
 	base         registry.Storage
 	repoPrefix   string
 	newInstaller newRegistryInstaller
+
+	// gcMu keeps garbage collection from running between a push writing
+	// content and recording the refs that make it reachable.
+	gcMu sync.RWMutex
 }
 
 func (s *internalRegistryStorage) storageRepo(repo string) string {
@@ -132,6 +178,8 @@ func (s *internalRegistryStorage) GetManifest(ctx context.Context, repo, referen
 }
 
 func (s *internalRegistryStorage) PutManifest(ctx context.Context, repo, reference string, data []byte, mediaType string) (string, error) {
+	s.gcMu.RLock()
+	defer s.gcMu.RUnlock()
 	if isDigest(reference) {
 		return s.base.PutManifest(ctx, s.storageRepo(repo), reference, data, mediaType)
 	}
@@ -139,15 +187,30 @@ func (s *internalRegistryStorage) PutManifest(ctx context.Context, repo, referen
 	if err != nil {
 		return "", err
 	}
+	if signed, ok := registry.SignedDigest(reference); ok {
+		return s.putSignature(ctx, svcName, repo, reference, signed, data, mediaType)
+	}
 	refs, stageOnly, err := refsForTag(reference)
 	if err != nil {
 		return "", err
 	}
+	// Children of a multi-platform index arrive by digest and are not
+	// checked; the tagged index only needs one entry this host can run.
+	if err := registry.CheckManifestPlatform(ctx, s.base, data, mediaType, registryHostPlatform); err != nil {
+		return "", err
+	}
 	digest, err := s.base.PutManifest(ctx, s.storageRepo(repo), reference, data, mediaType)
 	if err != nil {
 		log.Printf("registry PutManifest failed for %q:%q: %v", repo, reference, err)
 		return "", err
 	}
+	if s.s.containerRuntime().IsPodman() {
+		if err := loadPodmanImageFn(ctx, s.s.cfg.InternalRegistryAddr, repo, reference, digest); err != nil {
+			log.Printf("registry podman load failed for %q:%q: %v", repo, reference, err)
+			return "", err
+		}
+	}
+	pushedAt := time.Now().UTC().Format(time.RFC3339)
 	d, err := s.s.cfg.DB.MutateData(func(d *db.Data) error {
 		ir, ok := d.Images[db.ImageRepoName(repo)]
 		if !ok {
@@ -160,6 +223,7 @@ func (s *internalRegistryStorage) PutManifest(ctx context.Context, repo, referen
 			ir.Refs[db.ImageRef(ref)] = db.ImageManifest{
 				ContentType: mediaType,
 				BlobHash:    digest,
+				PushedAt:    pushedAt,
 			}
 		}
 		return nil
@@ -173,6 +237,126 @@ func (s *internalRegistryStorage) PutManifest(ctx context.Context, repo, referen
 	return digest, nil
 }
 
+// putSignature stores a cosign signature pushed for the image with digest
+// signed. When that image is the last one pushed as :run and the service
+// requires signed images, its install, refused until now, is retried.
+func (s *internalRegistryStorage) putSignature(ctx context.Context, svcName, repo, tag, signed string, data []byte, mediaType string) (string, error) {
+	digest, err := s.base.PutManifest(ctx, s.storageRepo(repo), tag, data, mediaType)
+	if err != nil {
+		log.Printf("registry PutManifest failed for %q:%q: %v", repo, tag, err)
+		return "", err
+	}
+	var retry bool
+	d, err := s.s.cfg.DB.MutateData(func(d *db.Data) error {
+		ir, ok := d.Images[db.ImageRepoName(repo)]
+		if !ok {
+			ir = &db.ImageRepo{Refs: make(map[db.ImageRef]db.ImageManifest, 1)}
+			mak.Set(&d.Images, db.ImageRepoName(repo), ir)
+		}
+		ir.Refs[db.ImageRef(tag)] = db.ImageManifest{
+			ContentType: mediaType,
+			BlobHash:    digest,
+			PushedAt:    time.Now().UTC().Format(time.RFC3339),
+		}
+		run, ok := ir.Refs["run"]
+		retry = ok && run.BlobHash == signed
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if !retry {
+		return digest, nil
+	}
+	if keys, err := imagePolicyKeys(d.View(), svcName); err != nil || keys == nil {
+		return digest, nil
+	}
+	if err := s.stageCompose(d, svcName, repo, false); err != nil {
+		log.Printf("registry install failed: %v", err)
+	}
+	return digest, nil
+}
+
+// putBuiltImage records an image catch built itself under tag, like a push
+// does, so the image policy and garbage collection see it. The manifest tree
+// rooted at root must already have its blobs in storage. Earlier build tags
+// of the repo are dropped unless a compose generation still names them.
+func (s *internalRegistryStorage) putBuiltImage(ctx context.Context, repo, tag string, root ocispec.Descriptor) (string, error) {
+	s.gcMu.RLock()
+	defer s.gcMu.RUnlock()
+	svcName, err := parseRepo(repo)
+	if err != nil {
+		return "", err
+	}
+	if err := s.putManifestTree(ctx, repo, tag, root); err != nil {
+		return "", err
+	}
+	digest := root.Digest.String()
+	_, err = s.s.cfg.DB.MutateData(func(d *db.Data) error {
+		ir, ok := d.Images[db.ImageRepoName(repo)]
+		if !ok {
+			ir = &db.ImageRepo{Refs: make(map[db.ImageRef]db.ImageManifest, 1)}
+			mak.Set(&d.Images, db.ImageRepoName(repo), ir)
+		}
+		named, err := composeImageTags(d.Services[svcName], repo)
+		if err != nil {
+			return err
+		}
+		for ref := range ir.Refs {
+			if strings.HasPrefix(string(ref), remoteBuildTagPrefix) && !named[string(ref)] {
+				delete(ir.Refs, ref)
+			}
+		}
+		ir.Refs[db.ImageRef(tag)] = db.ImageManifest{
+			ContentType: root.MediaType,
+			BlobHash:    digest,
+			PushedAt:    time.Now().UTC().Format(time.RFC3339),
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return digest, nil
+}
+
+// putManifestTree stores the manifest desc and, for an index, its children
+// by digest, reading them from the blob store.
+func (s *internalRegistryStorage) putManifestTree(ctx context.Context, repo, reference string, desc ocispec.Descriptor) error {
+	rc, err := s.base.GetBlob(ctx, desc.Digest.String())
+	if err != nil {
+		return fmt.Errorf("read manifest %s: %w", desc.Digest, err)
+	}
+	data, err := io.ReadAll(io.LimitReader(rc, remoteBuildImageManifestMaxBytes+1))
+	_ = rc.Close()
+	if err != nil {
+		return fmt.Errorf("read manifest %s: %w", desc.Digest, err)
+	}
+	if len(data) > remoteBuildImageManifestMaxBytes {
+		return fmt.Errorf("manifest %s is larger than %d bytes", desc.Digest, remoteBuildImageManifestMaxBytes)
+	}
+	switch desc.MediaType {
+	case ocispec.MediaTypeImageIndex, "application/vnd.docker.distribution.manifest.list.v2+json":
+		var index ocispec.Index
+		if err := json.Unmarshal(data, &index); err != nil {
+			return fmt.Errorf("decode index %s: %w", desc.Digest, err)
+		}
+		for _, child := range index.Manifests {
+			if err := s.putManifestTree(ctx, repo, child.Digest.String(), child); err != nil {
+				return err
+			}
+		}
+	}
+	digest, err := s.base.PutManifest(ctx, s.storageRepo(repo), reference, data, desc.MediaType)
+	if err != nil {
+		return err
+	}
+	if digest != desc.Digest.String() {
+		return fmt.Errorf("manifest %s stored with digest %s", desc.Digest, digest)
+	}
+	return nil
+}
+
 func (s *internalRegistryStorage) ManifestExists(ctx context.Context, repo, reference string) bool {
 	if isDigest(reference) {
 		return s.base.ManifestExists(ctx, s.storageRepo(repo), reference)
@@ -228,6 +412,8 @@ func (s *internalRegistryStorage) CopyChunk(ctx context.Context, uuid string, r
 }
 
 func (s *internalRegistryStorage) CompleteUpload(ctx context.Context, uuid, expectedDigest string) (string, error) {
+	s.gcMu.RLock()
+	defer s.gcMu.RUnlock()
 	return s.base.CompleteUpload(ctx, uuid, expectedDigest)
 }
 
@@ -235,6 +421,38 @@ func (s *internalRegistryStorage) AbortUpload(ctx context.Context, uuid string)
 	return s.base.AbortUpload(ctx, uuid)
 }
 
+// Repositories lists the image repos that have refs in the database.
+func (s *internalRegistryStorage) Repositories(ctx context.Context) ([]string, error) {
+	dv, err := s.s.getDB()
+	if err != nil {
+		return nil, err
+	}
+	var repos []string
+	for repo, ir := range dv.Images().All() {
+		if ir.Refs().Len() > 0 {
+			repos = append(repos, string(repo))
+		}
+	}
+	return repos, nil
+}
+
+// Tags lists the database refs of repo.
+func (s *internalRegistryStorage) Tags(ctx context.Context, repo string) ([]string, error) {
+	dv, err := s.s.getDB()
+	if err != nil {
+		return nil, err
+	}
+	ir, ok := dv.Images().GetOk(db.ImageRepoName(repo))
+	if !ok || ir.Refs().Len() == 0 {
+		return nil, registry.ErrRepositoryNotFound
+	}
+	var tags []string
+	for ref := range ir.Refs().All() {
+		tags = append(tags, string(ref))
+	}
+	return tags, nil
+}
+
 func (s *internalRegistryStorage) lookupManifest(repo, reference string) (db.ImageManifest, bool, error) {
 	dv, err := s.s.getDB()
 	if err != nil {
@@ -0,0 +1,396 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yeetrun/yeet/pkg/cli"
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/registry"
+	"github.com/yeetrun/yeet/pkg/svc"
+)
+
+const (
+	// registryGCGracePeriod protects blobs uploaded by a push whose manifest
+	// has not arrived yet.
+	registryGCGracePeriod   = time.Hour
+	registryGCCheckInterval = 15 * time.Minute
+)
+
+// internalImageRefRE matches internal registry image references in compose
+// files, capturing the repository, tag and digest.
+var internalImageRefRE = regexp.MustCompile(regexp.QuoteMeta(svc.InternalRegistryHost) +
+	`/([a-z0-9][a-z0-9._/-]*)(?::([A-Za-z0-9_][A-Za-z0-9_.-]*))?(?:@(sha256:[0-9a-f]{64}))?`)
+
+type registryGCRow struct {
+	Kind   string `json:"kind"`
+	Ref    string `json:"ref"`
+	Digest string `json:"digest"`
+	Size   int64  `json:"size,omitempty"`
+	State  string `json:"state"`
+}
+
+func (e *ttyExecer) dockerImagesPruneCmdFunc(flags cli.DockerImagesFlags) error {
+	if e.sn != SystemService {
+		return fmt.Errorf("docker images prune is host-level; run it without a service")
+	}
+	if flags.Every != "" {
+		return e.s.setRegistryGCInterval(e.rw, flags.Every)
+	}
+	result, err := e.s.collectRegistryGarbage(e.ctx, flags.DryRun)
+	if renderErr := renderRegistryGCResult(e.rw, flags.Format, result, flags.DryRun); renderErr != nil {
+		return errors.Join(err, renderErr)
+	}
+	return err
+}
+
+func (s *Server) setRegistryGCInterval(w io.Writer, every string) error {
+	interval := every
+	if every == "off" {
+		interval = ""
+	}
+	_, err := s.cfg.DB.MutateData(func(d *db.Data) error {
+		if interval == "" {
+			d.RegistryGC = nil
+			return nil
+		}
+		if d.RegistryGC == nil {
+			d.RegistryGC = &db.RegistryGCConfig{}
+		}
+		d.RegistryGC.Interval = interval
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if interval == "" {
+		_, err = fmt.Fprintln(w, "Periodic registry garbage collection disabled.")
+		return err
+	}
+	_, err = fmt.Fprintf(w, "Registry garbage collection runs every %s.\n", interval)
+	return err
+}
+
+// collectRegistryGarbage removes internal registry content that no image ref
+// or service generation reaches, and evicts old images from the registry
+// mirror caches. Signature refs survive only while the manifest they sign
+// does. Pushes wait for it to finish so a manifest cannot
+// land between marking and sweeping.
+func (s *Server) collectRegistryGarbage(ctx context.Context, dryRun bool) (registry.GCResult, error) {
+	if s.registry == nil {
+		return registry.GCResult{}, fmt.Errorf("internal registry is not configured")
+	}
+	storage := s.registry.storage
+	storage.gcMu.Lock()
+	defer storage.gcMu.Unlock()
+
+	dv, err := s.getDB()
+	if err != nil {
+		return registry.GCResult{}, err
+	}
+	d := dv.AsStruct()
+	roots, err := registryGCRoots(d, storage.storageRepo)
+	if err != nil {
+		return registry.GCResult{}, err
+	}
+	opts := registry.GCOptions{
+		Roots:      roots,
+		RepoPrefix: storage.repoPrefix,
+		Before:     time.Now().Add(-registryGCGracePeriod),
+		DryRun:     true,
+	}
+	unreached, err := registry.CollectGarbage(ctx, storage.base, opts)
+	if err != nil {
+		return registry.GCResult{}, err
+	}
+	sigs := registrySignatureRefs(d)
+	stale := staleRegistrySignatures(sigs, storage.storageRepo, unreached)
+	for _, sig := range sigs {
+		if !stale[sig] {
+			opts.Roots = append(opts.Roots, registry.GCRoot{Repo: storage.storageRepo(sig.repo), Reference: sig.digest})
+		}
+	}
+	if !dryRun && len(stale) > 0 {
+		if _, err := s.cfg.DB.MutateData(func(d *db.Data) error {
+			for sig := range stale {
+				if ir, ok := d.Images[db.ImageRepoName(sig.repo)]; ok {
+					delete(ir.Refs, db.ImageRef(sig.tag))
+				}
+			}
+			return nil
+		}); err != nil {
+			return registry.GCResult{}, err
+		}
+	}
+	opts.DryRun = dryRun
+	result, gcErr := registry.CollectGarbage(ctx, storage.base, opts)
+	mirrored, mirrorErr := s.evictRegistryMirrorCaches(ctx, dryRun)
+	result.Tags = append(result.Tags, mirrored.Tags...)
+	result.Content = append(result.Content, mirrored.Content...)
+	result.Kept += mirrored.Kept
+	return result, errors.Join(gcErr, mirrorErr)
+}
+
+// registrySignatureRef is a cosign signature tag recorded in the database.
+type registrySignatureRef struct {
+	repo   string
+	tag    string
+	digest string // of the signature manifest
+	signed string // digest of the image it signs
+}
+
+func registrySignatureRefs(d *db.Data) []registrySignatureRef {
+	var sigs []registrySignatureRef
+	for repo, ir := range d.Images {
+		for ref, mf := range ir.Refs {
+			if signed, ok := registry.SignedDigest(string(ref)); ok {
+				sigs = append(sigs, registrySignatureRef{repo: string(repo), tag: string(ref), digest: mf.BlobHash, signed: signed})
+			}
+		}
+	}
+	return sigs
+}
+
+// staleRegistrySignatures returns the signature refs whose image garbage
+// collection is about to remove. Signatures of images that were never in the
+// registry, such as external images, are not stale.
+func staleRegistrySignatures(sigs []registrySignatureRef, storageRepo func(string) string, unreached registry.GCResult) map[registrySignatureRef]bool {
+	removed := make(map[string]bool, len(unreached.Content))
+	for _, c := range unreached.Content {
+		removed[c.Repo+"@"+c.Digest] = true
+	}
+	stale := map[registrySignatureRef]bool{}
+	for _, sig := range sigs {
+		if removed[storageRepo(sig.repo)+"@"+sig.signed] || removed["@"+sig.signed] {
+			stale[sig] = true
+		}
+	}
+	return stale
+}
+
+// registryGCRoots returns the manifests the registry must keep regardless of
+// signatures: all image refs except signature tags, plus the internal images
+// named by any generation's compose file.
+func registryGCRoots(d *db.Data, storageRepo func(string) string) ([]registry.GCRoot, error) {
+	var roots []registry.GCRoot
+	for repo, ir := range d.Images {
+		for ref, mf := range ir.Refs {
+			if _, ok := registry.SignedDigest(string(ref)); ok {
+				continue
+			}
+			roots = append(roots, registry.GCRoot{Repo: storageRepo(string(repo)), Reference: mf.BlobHash})
+		}
+	}
+	seen := map[string]bool{}
+	for name, sv := range d.Services {
+		err := forEachComposeFile(sv, func(_ db.ArtifactRef, path string, raw []byte) error {
+			if !seen[path] {
+				seen[path] = true
+				roots = append(roots, composeRegistryGCRoots(string(raw), storageRepo)...)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("read compose file for %s: %w", name, err)
+		}
+	}
+	return roots, nil
+}
+
+// forEachComposeFile calls fn with every compose file of sv, base and
+// overrides, under each ref the base compose file has. Files that no longer
+// exist are skipped.
+func forEachComposeFile(sv *db.Service, fn func(ref db.ArtifactRef, path string, raw []byte) error) error {
+	if sv == nil {
+		return nil
+	}
+	a, ok := sv.Artifacts[db.ArtifactDockerComposeFile]
+	if !ok {
+		return nil
+	}
+	for ref := range a.Refs {
+		for _, path := range sv.Artifacts.ComposeFiles(ref) {
+			raw, err := os.ReadFile(path)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := fn(ref, path, raw); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func composeRegistryGCRoots(compose string, storageRepo func(string) string) []registry.GCRoot {
+	var roots []registry.GCRoot
+	for _, m := range internalImageRefRE.FindAllStringSubmatch(compose, -1) {
+		repo := storageRepo(strings.TrimSuffix(m[1], "/"))
+		if m[3] != "" {
+			roots = append(roots, registry.GCRoot{Repo: repo, Reference: m[3]})
+		}
+		tag := m[2]
+		if tag == "" && m[3] == "" {
+			tag = "latest"
+		}
+		if tag != "" {
+			roots = append(roots, registry.GCRoot{Repo: repo, Reference: tag})
+		}
+	}
+	return roots
+}
+
+// composeImageTags returns the tags of the internal repo that the compose
+// files of sv, across all generations, name.
+func composeImageTags(sv *db.Service, repo string) (map[string]bool, error) {
+	tags := map[string]bool{}
+	err := forEachComposeFile(sv, func(_ db.ArtifactRef, _ string, raw []byte) error {
+		for _, m := range internalImageRefRE.FindAllStringSubmatch(string(raw), -1) {
+			if strings.TrimSuffix(m[1], "/") == repo && m[2] != "" {
+				tags[m[2]] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read compose file for %s: %w", sv.Name, err)
+	}
+	return tags, nil
+}
+
+func (s *Server) runRegistryGC(ctx context.Context) {
+	ticker := time.NewTicker(registryGCCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.checkRegistryGC(ctx, time.Now()); err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf("registry garbage collection failed: %v", err)
+			}
+		}
+	}
+}
+
+// checkRegistryGC runs garbage collection when the configured interval has
+// passed since the last run.
+func (s *Server) checkRegistryGC(ctx context.Context, now time.Time) error {
+	dv, err := s.getDB()
+	if err != nil {
+		return err
+	}
+	cfg := dv.RegistryGC()
+	if !cfg.Valid() || cfg.Interval() == "" {
+		return nil
+	}
+	interval, err := time.ParseDuration(cfg.Interval())
+	if err != nil {
+		return fmt.Errorf("invalid registry gc interval %q", cfg.Interval())
+	}
+	if last, err := time.Parse(time.RFC3339, cfg.LastRun()); err == nil && now.Sub(last) < interval {
+		return nil
+	}
+	result, gcErr := s.collectRegistryGarbage(ctx, false)
+	if len(result.Content) > 0 || len(result.Tags) > 0 {
+		log.Printf("registry garbage collection removed %d tags and %d items, freeing %s",
+			len(result.Tags), len(result.Content), formatVMProvisionBytes(result.Bytes()))
+	}
+	_, err = s.cfg.DB.MutateData(func(d *db.Data) error {
+		if d.RegistryGC != nil {
+			d.RegistryGC.LastRun = now.UTC().Format(time.RFC3339)
+		}
+		return nil
+	})
+	return errors.Join(gcErr, err)
+}
+
+func registryGCRows(result registry.GCResult, dryRun bool) []registryGCRow {
+	state := "removed"
+	if dryRun {
+		state = "would-remove"
+	}
+	rows := make([]registryGCRow, 0, len(result.Tags)+len(result.Content))
+	for _, tag := range result.Tags {
+		rows = append(rows, registryGCRow{Kind: "tag", Ref: tag.Repo + ":" + tag.Tag, Digest: tag.Digest, State: state})
+	}
+	for _, c := range result.Content {
+		kind := "blob"
+		if c.Repo != "" {
+			kind = "manifest"
+		}
+		rows = append(rows, registryGCRow{Kind: kind, Ref: c.Repo, Digest: c.Digest, Size: c.Size, State: state})
+	}
+	return rows
+}
+
+// compactRegistryDigest trims table digests to the 12 hex characters
+// Docker shows; JSON output keeps them whole.
+func compactRegistryDigest(digest string) string {
+	algo, hex, ok := strings.Cut(digest, ":")
+	if !ok || len(hex) <= 12 {
+		return digest
+	}
+	return algo + ":" + hex[:12]
+}
+
+func renderRegistryGCResult(w io.Writer, formatOut string, result registry.GCResult, dryRun bool) error {
+	rows := registryGCRows(result, dryRun)
+	switch strings.TrimSpace(formatOut) {
+	case "json":
+		return json.NewEncoder(w).Encode(rows)
+	case "json-pretty":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rows)
+	case "", "table":
+		return renderRegistryGCTable(w, rows, result, dryRun)
+	default:
+		return fmt.Errorf("unsupported docker images format %q", formatOut)
+	}
+}
+
+func renderRegistryGCTable(w io.Writer, rows []registryGCRow, result registry.GCResult, dryRun bool) error {
+	if len(rows) == 0 {
+		_, err := fmt.Fprintln(w, "No registry images to prune.")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	if _, err := fmt.Fprintln(tw, "KIND\tREF\tDIGEST\tSIZE\tSTATE"); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		size := "-"
+		if row.Kind != "tag" {
+			size = formatVMProvisionBytes(row.Size)
+		}
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", row.Kind, dash(row.Ref), compactRegistryDigest(row.Digest), size, row.State); err != nil {
+			return err
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	verb := "Freed"
+	if dryRun {
+		verb = "Would free"
+	}
+	_, err := fmt.Fprintf(w, "%s %s; kept %d reachable or recent items.\n", verb, formatVMProvisionBytes(result.Bytes()), result.Kept)
+	return err
+}
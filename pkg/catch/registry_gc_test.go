@@ -0,0 +1,280 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/registry"
+)
+
+func TestCollectRegistryGarbageKeepsReferencedImages(t *testing.T) {
+	server := newTestServer(t)
+	storage := server.registry.storage
+	storage.newInstaller = func(*Server, FileInstallerCfg) (registryInstaller, error) {
+		return &stubInstaller{}, nil
+	}
+	ctx := context.Background()
+	const mediaType = "application/vnd.oci.image.manifest.v1+json"
+	old, err := storage.PutManifest(ctx, "svc/app", "run", []byte(`{"schemaVersion":2,"layers":[]}`), mediaType)
+	if err != nil {
+		t.Fatalf("PutManifest old: %v", err)
+	}
+	live, err := storage.PutManifest(ctx, "svc/app", "run", []byte(`{"schemaVersion":2,"layers":[{"digest":"sha256:x"}]}`), mediaType)
+	if err != nil {
+		t.Fatalf("PutManifest live: %v", err)
+	}
+	past := time.Now().Add(-2 * registryGCGracePeriod)
+	err = filepath.WalkDir(server.cfg.RegistryRoot, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		return os.Chtimes(path, past, past)
+	})
+	if err != nil {
+		t.Fatalf("age registry: %v", err)
+	}
+
+	dry, err := server.collectRegistryGarbage(ctx, true)
+	if err != nil {
+		t.Fatalf("collectRegistryGarbage dry run: %v", err)
+	}
+	if len(dry.Content) != 1 || dry.Content[0].Digest != old {
+		t.Fatalf("dry run content = %+v, want only %s", dry.Content, old)
+	}
+	if !storage.base.ManifestExists(ctx, "catchit.dev/svc/app", old) {
+		t.Fatal("dry run removed the old manifest")
+	}
+
+	if _, err := server.collectRegistryGarbage(ctx, false); err != nil {
+		t.Fatalf("collectRegistryGarbage: %v", err)
+	}
+	if storage.base.ManifestExists(ctx, "catchit.dev/svc/app", old) {
+		t.Fatal("unreferenced manifest survived garbage collection")
+	}
+	if !storage.ManifestExists(ctx, "svc/app", "run") || !storage.base.ManifestExists(ctx, "catchit.dev/svc/app", live) {
+		t.Fatal("referenced manifest was collected")
+	}
+}
+
+func TestCollectRegistryGarbageEvictsOldMirrorImages(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+	dir := server.registryMirrorCacheDir("docker.io")
+	cache, err := registry.NewFilesystemStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const mediaType = "application/vnd.oci.image.manifest.v1+json"
+	old, err := cache.PutManifest(ctx, "library/nginx", "1.26", []byte(`{"schemaVersion":2,"layers":[]}`), mediaType)
+	if err != nil {
+		t.Fatalf("PutManifest old: %v", err)
+	}
+	past := time.Now().Add(-2 * registry.DefaultMirrorCacheMaxAge)
+	err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		return os.Chtimes(path, past, past)
+	})
+	if err != nil {
+		t.Fatalf("age mirror cache: %v", err)
+	}
+	if _, err := cache.PutManifest(ctx, "library/nginx", "1.27", []byte(`{"schemaVersion":2,"layers":[{"digest":"sha256:y"}]}`), mediaType); err != nil {
+		t.Fatalf("PutManifest recent: %v", err)
+	}
+
+	result, err := server.collectRegistryGarbage(ctx, false)
+	if err != nil {
+		t.Fatalf("collectRegistryGarbage: %v", err)
+	}
+	want := []registry.TagInfo{{Repo: "docker.io/library/nginx", Tag: "1.26", Digest: old}}
+	if !reflect.DeepEqual(result.Tags, want) {
+		t.Fatalf("tags = %+v, want %+v", result.Tags, want)
+	}
+	if cache.ManifestExists(ctx, "library/nginx", old) || cache.ManifestExists(ctx, "library/nginx", "1.26") {
+		t.Fatal("old mirrored image survived garbage collection")
+	}
+	if !cache.ManifestExists(ctx, "library/nginx", "1.27") {
+		t.Fatal("recent mirrored image was evicted")
+	}
+}
+
+func TestCollectRegistryGarbageDropsSignaturesOfCollectedImages(t *testing.T) {
+	server := newTestServer(t)
+	storage := server.registry.storage
+	storage.newInstaller = func(*Server, FileInstallerCfg) (registryInstaller, error) {
+		return &stubInstaller{}, nil
+	}
+	key, _ := newTestTrustKey(t)
+	ctx := context.Background()
+	const mediaType = "application/vnd.oci.image.manifest.v1+json"
+	old, err := storage.PutManifest(ctx, "web", "run", []byte(`{"schemaVersion":2,"layers":[]}`), mediaType)
+	if err != nil {
+		t.Fatalf("PutManifest old: %v", err)
+	}
+	live, err := storage.PutManifest(ctx, "web", "run", []byte(`{"schemaVersion":2,"layers":[{"digest":"sha256:x"}]}`), mediaType)
+	if err != nil {
+		t.Fatalf("PutManifest live: %v", err)
+	}
+	sigManifests := map[string]string{}
+	for _, digest := range []string{old, live, testSignedDigest} {
+		pushTestSignature(t, storage, "web", digest, key)
+		mf, _, err := storage.lookupManifest("web", registry.SignatureTag(digest))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sigManifests[digest] = mf.BlobHash
+	}
+	past := time.Now().Add(-2 * registryGCGracePeriod)
+	err = filepath.WalkDir(server.cfg.RegistryRoot, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		return os.Chtimes(path, past, past)
+	})
+	if err != nil {
+		t.Fatalf("age registry: %v", err)
+	}
+
+	if _, err := server.collectRegistryGarbage(ctx, false); err != nil {
+		t.Fatalf("collectRegistryGarbage: %v", err)
+	}
+	if storage.base.ManifestExists(ctx, "catchit.dev/web", old) {
+		t.Fatal("manifest signed by a stale signature survived garbage collection")
+	}
+	for digest, want := range map[string]bool{old: false, live: true, testSignedDigest: true} {
+		tag := registry.SignatureTag(digest)
+		if _, ok, err := storage.lookupManifest("web", tag); err != nil || ok != want {
+			t.Fatalf("signature ref %s recorded = %v, %v; want %v", tag, ok, err, want)
+		}
+		if got := storage.base.ManifestExists(ctx, "catchit.dev/web", sigManifests[digest]); got != want {
+			t.Fatalf("signature manifest %s exists = %v, want %v", tag, got, want)
+		}
+	}
+}
+
+func TestCollectRegistryGarbageKeepsImagesNamedOnlyByComposeOverrides(t *testing.T) {
+	server := newTestServer(t)
+	storage := server.registry.storage
+	storage.newInstaller = func(*Server, FileInstallerCfg) (registryInstaller, error) {
+		return &stubInstaller{}, nil
+	}
+	ctx := context.Background()
+	const mediaType = "application/vnd.oci.image.manifest.v1+json"
+	old, err := storage.PutManifest(ctx, "svc/app", "run", []byte(`{"schemaVersion":2,"layers":[]}`), mediaType)
+	if err != nil {
+		t.Fatalf("PutManifest old: %v", err)
+	}
+	if _, err := storage.PutManifest(ctx, "svc/app", "run", []byte(`{"schemaVersion":2,"layers":[{"digest":"sha256:x"}]}`), mediaType); err != nil {
+		t.Fatalf("PutManifest live: %v", err)
+	}
+	dir := t.TempDir()
+	base := filepath.Join(dir, "compose.yml")
+	override := filepath.Join(dir, "compose.override.0")
+	if err := os.WriteFile(base, []byte("services:\n  db:\n    image: postgres:16\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(override, []byte("services:\n  app:\n    image: catchit.dev/svc/app@"+old+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = server.cfg.DB.MutateService("svc", func(_ *db.Data, service *db.Service) error {
+		service.ServiceType = db.ServiceTypeDockerCompose
+		service.Artifacts = db.ArtifactStore{
+			db.ArtifactDockerComposeFile:        {Refs: map[db.ArtifactRef]string{db.Gen(1): base}},
+			db.ArtifactDockerComposeOverride(0): {Refs: map[db.ArtifactRef]string{db.Gen(1): override}},
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-2 * registryGCGracePeriod)
+	err = filepath.WalkDir(server.cfg.RegistryRoot, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		return os.Chtimes(path, past, past)
+	})
+	if err != nil {
+		t.Fatalf("age registry: %v", err)
+	}
+
+	if _, err := server.collectRegistryGarbage(ctx, false); err != nil {
+		t.Fatalf("collectRegistryGarbage: %v", err)
+	}
+	if !storage.base.ManifestExists(ctx, "catchit.dev/svc/app", old) {
+		t.Fatal("manifest named only by a compose override was collected")
+	}
+}
+
+func TestRegistryGCRootsIncludeComposeImages(t *testing.T) {
+	dir := t.TempDir()
+	gen1 := filepath.Join(dir, "compose.yml-1")
+	pinned := "sha256:" + strings.Repeat("a", 64)
+	compose := "services:\n  app:\n    image: catchit.dev/svc/app@" + pinned + "\n  worker:\n    image: catchit.dev/svc/worker\n  db:\n    image: postgres:16\n"
+	if err := os.WriteFile(gen1, []byte(compose), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	d := &db.Data{
+		Images: map[db.ImageRepoName]*db.ImageRepo{
+			"svc/app": {Refs: map[db.ImageRef]db.ImageManifest{"run": {BlobHash: "sha256:run"}}},
+		},
+		Services: map[string]*db.Service{
+			"svc": {Artifacts: db.ArtifactStore{
+				db.ArtifactDockerComposeFile: {Refs: map[db.ArtifactRef]string{
+					db.Gen(1): gen1,
+					"latest":  gen1,
+					db.Gen(2): filepath.Join(dir, "missing.yml"),
+				}},
+			}},
+		},
+	}
+	roots, err := registryGCRoots(d, func(repo string) string { return "catchit.dev/" + repo })
+	if err != nil {
+		t.Fatalf("registryGCRoots: %v", err)
+	}
+	want := []registry.GCRoot{
+		{Repo: "catchit.dev/svc/app", Reference: "sha256:run"},
+		{Repo: "catchit.dev/svc/app", Reference: pinned},
+		{Repo: "catchit.dev/svc/worker", Reference: "latest"},
+	}
+	if !reflect.DeepEqual(roots, want) {
+		t.Fatalf("roots = %+v, want %+v", roots, want)
+	}
+}
+
+func TestRenderRegistryGCResultTable(t *testing.T) {
+	result := registry.GCResult{
+		Tags:    []registry.TagInfo{{Repo: "catchit.dev/svc/app", Tag: "old", Digest: "sha256:m"}},
+		Content: []registry.ContentInfo{{Repo: "catchit.dev/svc/app", Digest: "sha256:m", Size: 512}, {Digest: "sha256:l", Size: 2048}},
+		Kept:    3,
+	}
+	var out bytes.Buffer
+	if err := renderRegistryGCResult(&out, "table", result, true); err != nil {
+		t.Fatalf("renderRegistryGCResult: %v", err)
+	}
+	for _, want := range []string{"KIND", "tag", "catchit.dev/svc/app:old", "manifest", "blob", "2.0 KB", "would-remove", "Would free 2.5 KB; kept 3"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := renderRegistryGCResult(&out, "table", registry.GCResult{}, false); err != nil {
+		t.Fatalf("renderRegistryGCResult empty: %v", err)
+	}
+	if got := strings.TrimSpace(out.String()); got != "No registry images to prune." {
+		t.Fatalf("empty output = %q", got)
+	}
+}
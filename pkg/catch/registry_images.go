@@ -0,0 +1,239 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yeetrun/yeet/pkg/cli"
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/registry"
+)
+
+type dockerImageRow struct {
+	Repo     string           `json:"repo"`
+	Tag      string           `json:"tag"`
+	Digest   string           `json:"digest"`
+	Size     int64            `json:"size,omitempty"`
+	PushedAt string           `json:"pushedAt,omitempty"`
+	UsedBy   []dockerImageUse `json:"usedBy,omitempty"`
+}
+
+// dockerImageUse lists the generations of a service whose compose file names
+// an image.
+type dockerImageUse struct {
+	Service     string `json:"service"`
+	Generations []int  `json:"generations,omitempty"`
+	Staged      bool   `json:"staged,omitempty"`
+}
+
+func (e *ttyExecer) dockerImagesListCmdFunc(flags cli.DockerImagesFlags) error {
+	service := ""
+	if e.sn != SystemService {
+		service = e.sn
+	}
+	rows, err := e.s.listDockerImages(e.ctx, service)
+	if err != nil {
+		return err
+	}
+	return renderDockerImageRows(e.rw, flags.Format, rows)
+}
+
+// listDockerImages lists every ref pushed to the internal registry, or only
+// the repos of service when it is set.
+func (s *Server) listDockerImages(ctx context.Context, service string) ([]dockerImageRow, error) {
+	if s.registry == nil {
+		return nil, fmt.Errorf("internal registry is not configured")
+	}
+	storage := s.registry.storage
+	dv, err := s.getDB()
+	if err != nil {
+		return nil, err
+	}
+	d := dv.AsStruct()
+	if service != "" {
+		if _, ok := d.Services[service]; !ok {
+			return nil, fmt.Errorf("service %q not found", service)
+		}
+	}
+	uses, err := composeImageUses(d)
+	if err != nil {
+		return nil, err
+	}
+	var rows []dockerImageRow
+	for repo, ir := range d.Images {
+		svcName, err := parseRepo(string(repo))
+		if err != nil || (service != "" && svcName != service) {
+			continue
+		}
+		for ref, mf := range ir.Refs {
+			row := dockerImageRow{
+				Repo:     string(repo),
+				Tag:      string(ref),
+				Digest:   mf.BlobHash,
+				PushedAt: mf.PushedAt,
+				UsedBy:   uses.forImage(string(repo), mf.BlobHash),
+			}
+			size, err := registry.ImageSize(ctx, storage.base, storage.storageRepo(string(repo)), mf.BlobHash)
+			if err != nil && !errors.Is(err, registry.ErrManifestNotFound) {
+				return nil, fmt.Errorf("size of %s:%s: %w", repo, ref, err)
+			}
+			row.Size = size
+			rows = append(rows, row)
+		}
+	}
+	slices.SortFunc(rows, func(a, b dockerImageRow) int {
+		if c := strings.Compare(a.Repo, b.Repo); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Tag, b.Tag)
+	})
+	return rows, nil
+}
+
+// composeImageRef is an internal image named in one compose artifact.
+type composeImageRef struct {
+	service string
+	// gen is the generation of the artifact, or -1 for the staged one.
+	gen    int
+	repo   string
+	digest string
+}
+
+type composeImageRefs []composeImageRef
+
+// composeImageUses collects the internal images named by every service's
+// generation and staged compose files.
+func composeImageUses(d *db.Data) (composeImageRefs, error) {
+	var refs composeImageRefs
+	for name, sv := range d.Services {
+		err := forEachComposeFile(sv, func(ref db.ArtifactRef, _ string, raw []byte) error {
+			gen, ok := parseGenRef(ref)
+			if !ok {
+				if ref != "staged" {
+					return nil
+				}
+				gen = -1
+			}
+			for _, m := range internalImageRefRE.FindAllStringSubmatch(string(raw), -1) {
+				refs = append(refs, composeImageRef{
+					service: name,
+					gen:     gen,
+					repo:    strings.TrimSuffix(m[1], "/"),
+					digest:  m[3],
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("read compose file for %s: %w", name, err)
+		}
+	}
+	return refs, nil
+}
+
+// forImage returns the services and generations that use repo. A compose
+// file that pins a digest only uses that digest.
+func (refs composeImageRefs) forImage(repo, digest string) []dockerImageUse {
+	byService := map[string]*dockerImageUse{}
+	for _, ref := range refs {
+		if ref.repo != repo || (ref.digest != "" && ref.digest != digest) {
+			continue
+		}
+		use, ok := byService[ref.service]
+		if !ok {
+			use = &dockerImageUse{Service: ref.service}
+			byService[ref.service] = use
+		}
+		if ref.gen < 0 {
+			use.Staged = true
+		} else if !slices.Contains(use.Generations, ref.gen) {
+			use.Generations = append(use.Generations, ref.gen)
+		}
+	}
+	var uses []dockerImageUse
+	for _, use := range byService {
+		slices.Sort(use.Generations)
+		uses = append(uses, *use)
+	}
+	slices.SortFunc(uses, func(a, b dockerImageUse) int { return strings.Compare(a.Service, b.Service) })
+	return uses
+}
+
+func renderDockerImageRows(w io.Writer, formatOut string, rows []dockerImageRow) error {
+	switch strings.TrimSpace(formatOut) {
+	case "json":
+		return json.NewEncoder(w).Encode(rows)
+	case "json-pretty":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rows)
+	case "", "table":
+		return renderDockerImageRowsTable(w, rows)
+	default:
+		return fmt.Errorf("unsupported docker images format %q", formatOut)
+	}
+}
+
+func renderDockerImageRowsTable(w io.Writer, rows []dockerImageRow) error {
+	if len(rows) == 0 {
+		_, err := fmt.Fprintln(w, "No images in the internal registry.")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	if _, err := fmt.Fprintln(tw, "REPO\tTAG\tDIGEST\tSIZE\tPUSHED\tUSED BY"); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			row.Repo,
+			row.Tag,
+			compactRegistryDigest(row.Digest),
+			formatVMProvisionBytes(row.Size),
+			dash(formatDockerImagePushedAt(row.PushedAt)),
+			dash(formatDockerImageUses(row.UsedBy)),
+		); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+func formatDockerImagePushedAt(raw string) string {
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return raw
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// formatDockerImageUses renders uses like "api gen 3,4 +staged, web gen 7".
+func formatDockerImageUses(uses []dockerImageUse) string {
+	parts := make([]string, 0, len(uses))
+	for _, use := range uses {
+		part := use.Service
+		if len(use.Generations) > 0 {
+			gens := make([]string, 0, len(use.Generations))
+			for _, gen := range use.Generations {
+				gens = append(gens, strconv.Itoa(gen))
+			}
+			part += " gen " + strings.Join(gens, ",")
+		}
+		if use.Staged {
+			part += " +staged"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
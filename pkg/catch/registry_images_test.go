@@ -0,0 +1,151 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yeetrun/yeet/pkg/db"
+)
+
+func TestListDockerImagesReportsRefsAndUses(t *testing.T) {
+	server := newTestServer(t)
+	storage := server.registry.storage
+	storage.newInstaller = func(*Server, FileInstallerCfg) (registryInstaller, error) {
+		return &stubInstaller{}, nil
+	}
+	ctx := context.Background()
+	manifest := []byte(`{"schemaVersion":2,"config":{"digest":"sha256:c","size":100},"layers":[{"digest":"sha256:l","size":900}]}`)
+	digest, err := storage.PutManifest(ctx, "svc/app", "run", manifest, "application/vnd.oci.image.manifest.v1+json")
+	if err != nil {
+		t.Fatalf("PutManifest: %v", err)
+	}
+	if _, err := storage.PutManifest(ctx, "other", "latest", []byte(`{"schemaVersion":2}`), "application/vnd.oci.image.manifest.v1+json"); err != nil {
+		t.Fatalf("PutManifest other: %v", err)
+	}
+
+	dir := t.TempDir()
+	compose := filepath.Join(dir, "compose.yml")
+	if err := os.WriteFile(compose, []byte("services:\n  app:\n    image: catchit.dev/svc/app\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	_, err = server.cfg.DB.MutateData(func(d *db.Data) error {
+		d.Services = map[string]*db.Service{
+			"svc": {Name: "svc", Artifacts: db.ArtifactStore{
+				db.ArtifactDockerComposeFile: {Refs: map[db.ArtifactRef]string{
+					db.Gen(3): compose,
+					db.Gen(2): compose,
+					"staged":  compose,
+					"latest":  compose,
+				}},
+			}},
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("MutateData: %v", err)
+	}
+
+	rows, err := server.listDockerImages(ctx, "svc")
+	if err != nil {
+		t.Fatalf("listDockerImages: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Tag != "run" || rows[1].Tag != "staged" {
+		t.Fatalf("rows = %+v, want svc/app run and staged", rows)
+	}
+	row := rows[0]
+	if row.Repo != "svc/app" || row.Digest != digest || row.Size != int64(len(manifest))+1000 || row.PushedAt == "" {
+		t.Fatalf("row = %+v", row)
+	}
+	if want := []dockerImageUse{{Service: "svc", Generations: []int{2, 3}, Staged: true}}; !reflect.DeepEqual(row.UsedBy, want) {
+		t.Fatalf("UsedBy = %+v, want %+v", row.UsedBy, want)
+	}
+
+	all, err := server.listDockerImages(ctx, "")
+	if err != nil {
+		t.Fatalf("listDockerImages all: %v", err)
+	}
+	if len(all) != 3 || all[0].Repo != "other" {
+		t.Fatalf("all rows = %+v, want other plus svc/app", all)
+	}
+	if _, err := server.listDockerImages(ctx, "missing"); err == nil {
+		t.Fatal("listDockerImages for a missing service succeeded")
+	}
+}
+
+func TestInternalRegistryCatalogListsDatabaseRefs(t *testing.T) {
+	server := newTestServer(t)
+	storage := server.registry.storage
+	storage.newInstaller = func(*Server, FileInstallerCfg) (registryInstaller, error) {
+		return &stubInstaller{}, nil
+	}
+	if _, err := storage.PutManifest(context.Background(), "svc/app", "run", []byte(`{"schemaVersion":2}`), "application/vnd.oci.image.manifest.v1+json"); err != nil {
+		t.Fatalf("PutManifest: %v", err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "http://example"+path, nil)
+		req.RemoteAddr = "127.0.0.1:1234"
+		rr := httptest.NewRecorder()
+		server.registry.ServeHTTP(rr, req)
+		return rr
+	}
+	var catalog struct {
+		Repositories []string `json:"repositories"`
+	}
+	if err := json.Unmarshal(get("/v2/_catalog").Body.Bytes(), &catalog); err != nil {
+		t.Fatalf("decode catalog: %v", err)
+	}
+	if !reflect.DeepEqual(catalog.Repositories, []string{"svc/app"}) {
+		t.Fatalf("catalog = %q, want [svc/app]", catalog.Repositories)
+	}
+	var tags struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.Unmarshal(get("/v2/svc/app/tags/list").Body.Bytes(), &tags); err != nil {
+		t.Fatalf("decode tags: %v", err)
+	}
+	if !reflect.DeepEqual(tags.Tags, []string{"run", "staged"}) {
+		t.Fatalf("tags = %q, want [run staged]", tags.Tags)
+	}
+	if rr := get("/v2/svc/missing/tags/list"); rr.Code != http.StatusNotFound {
+		t.Fatalf("missing repo status = %d, want 404", rr.Code)
+	}
+}
+
+func TestRenderDockerImageRowsTable(t *testing.T) {
+	rows := []dockerImageRow{{
+		Repo:     "svc/app",
+		Tag:      "run",
+		Digest:   "sha256:" + strings.Repeat("b", 64),
+		Size:     3 << 20,
+		PushedAt: "2026-01-02T03:04:05Z",
+		UsedBy: []dockerImageUse{
+			{Service: "svc", Generations: []int{3, 4}, Staged: true},
+			{Service: "web", Generations: []int{7}},
+		},
+	}, {Repo: "svc/old", Tag: "staged", Digest: "sha256:c"}}
+	var out bytes.Buffer
+	if err := renderDockerImageRows(&out, "table", rows); err != nil {
+		t.Fatalf("renderDockerImageRows: %v", err)
+	}
+	for _, want := range []string{"REPO", "USED BY", "sha256:bbbbbbbbbbbb ", "3.0 MB", "svc gen 3,4 +staged, web gen 7"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), strings.Repeat("b", 13)) {
+		t.Fatalf("table should compact digests:\n%s", out.String())
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+	"github.com/yeetrun/yeet/pkg/cli"
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/registry"
+)
+
+// registryMirrorUpstreams maps the registries `host set --registry-mirror`
+// accepts to their API endpoints.
+var registryMirrorUpstreams = map[string]string{
+	cli.RegistryMirrorDockerHub: "https://registry-1.docker.io",
+}
+
+var (
+	registryMirrorDockerConfigPath = "/etc/docker/daemon.json"
+	// reloadDockerDaemonFn applies daemon.json changes; registry-mirrors is
+	// reloadable, so running containers are left alone.
+	reloadDockerDaemonFn = func() error {
+		if out, err := exec.Command("systemctl", "reload", "docker").CombinedOutput(); err != nil {
+			return fmt.Errorf("reload docker: %w: %s", err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+	// listenRegistryMirrorFn binds the address the Docker daemon is pointed
+	// at.
+	listenRegistryMirrorFn = func(addr string) (net.Listener, error) {
+		return net.Listen("tcp", addr)
+	}
+)
+
+// registryMirrorState holds the mirror for the configured upstream, built on
+// first use, and the listener serving it while the mirror is enabled.
+type registryMirrorState struct {
+	mu       sync.Mutex
+	upstream string
+	mirror   *registry.Mirror
+	ln       net.Listener
+}
+
+// StartRegistryMirror starts serving the mirror if one is configured. Hosts
+// that never enabled the mirror do not hold its port.
+func (s *Server) StartRegistryMirror() error {
+	dv, err := s.getDB()
+	if err != nil {
+		return err
+	}
+	if !dv.RegistryMirror().Valid() {
+		return nil
+	}
+	return s.startRegistryMirrorListener()
+}
+
+func (s *Server) startRegistryMirrorListener() error {
+	st := &s.registryMirror
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.ln != nil {
+		return nil
+	}
+	if s.cfg.RegistryMirrorAddr == "" {
+		return fmt.Errorf("registry mirror listener is not configured")
+	}
+	ln, err := listenRegistryMirrorFn(s.cfg.RegistryMirrorAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for registry mirror on %s: %w", s.cfg.RegistryMirrorAddr, err)
+	}
+	st.ln = ln
+	go func() {
+		if err := http.Serve(ln, s.RegistryMirrorHandler()); err != nil && !errors.Is(err, net.ErrClosed) {
+			log.Printf("registry mirror server error: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (s *Server) stopRegistryMirrorListener() {
+	st := &s.registryMirror
+	st.mu.Lock()
+	ln := st.ln
+	st.ln = nil
+	st.mu.Unlock()
+	if ln != nil {
+		if err := ln.Close(); err != nil {
+			log.Printf("failed to close registry mirror listener: %v", err)
+		}
+	}
+}
+
+// RegistryMirrorHandler serves the pull-through mirror the Docker daemon is
+// pointed at. While the mirror is disabled every request fails, and the
+// daemon falls back to the upstream.
+func (s *Server) RegistryMirrorHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m, err := s.currentRegistryMirror()
+		if err != nil {
+			registry.WriteError(w, http.StatusInternalServerError, registry.ErrCodeUnsupported, err.Error(), nil)
+			return
+		}
+		if m == nil {
+			registry.WriteError(w, http.StatusNotFound, registry.ErrCodeUnsupported, "registry mirror is not enabled", nil)
+			return
+		}
+		m.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) currentRegistryMirror() (*registry.Mirror, error) {
+	dv, err := s.getDB()
+	if err != nil {
+		return nil, err
+	}
+	upstream := ""
+	if cfg := dv.RegistryMirror(); cfg.Valid() {
+		upstream = cfg.Upstream()
+	}
+	st := &s.registryMirror
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if upstream == "" {
+		st.upstream, st.mirror = "", nil
+		return nil, nil
+	}
+	if st.mirror != nil && st.upstream == upstream {
+		return st.mirror, nil
+	}
+	endpoint, ok := registryMirrorUpstreams[upstream]
+	if !ok {
+		return nil, fmt.Errorf("unsupported registry mirror upstream %q", upstream)
+	}
+	cache, err := registry.NewFilesystemStorage(s.registryMirrorCacheDir(upstream))
+	if err != nil {
+		return nil, err
+	}
+	m, err := registry.NewMirror(registry.MirrorConfig{Upstream: endpoint, Cache: cache})
+	if err != nil {
+		return nil, err
+	}
+	st.upstream, st.mirror = upstream, m
+	return m, nil
+}
+
+func (s *Server) registryMirrorCacheDir(upstream string) string {
+	return filepath.Join(s.cfg.RootDir, "registry-mirror", upstream)
+}
+
+// evictRegistryMirrorCaches drops images the mirror caches fetched more than
+// registry.DefaultMirrorCacheMaxAge ago, including caches of upstreams that
+// are no longer enabled. Repos are reported under their upstream, as in
+// docker.io/library/nginx.
+func (s *Server) evictRegistryMirrorCaches(ctx context.Context, dryRun bool) (registry.GCResult, error) {
+	var out registry.GCResult
+	var errs []error
+	for _, upstream := range slices.Sorted(maps.Keys(registryMirrorUpstreams)) {
+		dir := s.registryMirrorCacheDir(upstream)
+		if _, err := os.Stat(dir); err != nil {
+			if !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		cache, err := registry.NewFilesystemStorage(dir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		result, err := registry.EvictMirrorCache(ctx, cache, registry.DefaultMirrorCacheMaxAge, time.Now(), dryRun)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("evict %s mirror cache: %w", upstream, err))
+		}
+		for _, tag := range result.Tags {
+			tag.Repo = upstream + "/" + tag.Repo
+			out.Tags = append(out.Tags, tag)
+		}
+		for _, c := range result.Content {
+			if c.Repo != "" {
+				c.Repo = upstream + "/" + c.Repo
+			}
+			out.Content = append(out.Content, c)
+		}
+		out.Kept += result.Kept
+	}
+	return out, errors.Join(errs...)
+}
+
+// SetRegistryMirror enables the mirror for req.Upstream, or disables it when
+// the upstream is empty, and points the Docker daemon at it.
+func (s *Server) SetRegistryMirror(_ context.Context, req catchrpc.RegistryMirrorSetRequest) (catchrpc.RegistryMirrorSetResult, error) {
+	upstream := strings.TrimSpace(req.Upstream)
+	if upstream != "" && s.containerRuntime().IsPodman() {
+		return catchrpc.RegistryMirrorSetResult{}, fmt.Errorf("the registry mirror configures the Docker daemon; this host runs podman, which reads mirrors from /etc/containers/registries.conf")
+	}
+	if _, ok := registryMirrorUpstreams[upstream]; upstream != "" && !ok {
+		return catchrpc.RegistryMirrorSetResult{}, fmt.Errorf("unsupported registry mirror upstream %q", upstream)
+	}
+	if s.cfg.RegistryMirrorAddr == "" {
+		return catchrpc.RegistryMirrorSetResult{}, fmt.Errorf("registry mirror listener is not configured")
+	}
+	result := catchrpc.RegistryMirrorSetResult{Upstream: upstream}
+	if upstream != "" {
+		// Bind before the daemon is pointed at the address, so a port held
+		// by something else fails here rather than on every pull.
+		if err := s.startRegistryMirrorListener(); err != nil {
+			return catchrpc.RegistryMirrorSetResult{}, err
+		}
+		result.URL = "http://" + s.cfg.RegistryMirrorAddr
+	}
+	dockerChanged, err := setDockerRegistryMirror(registryMirrorDockerConfigPath, "http://"+s.cfg.RegistryMirrorAddr, upstream != "")
+	if err != nil {
+		return catchrpc.RegistryMirrorSetResult{}, err
+	}
+	if dockerChanged {
+		if err := reloadDockerDaemonFn(); err != nil {
+			return catchrpc.RegistryMirrorSetResult{}, err
+		}
+		result.DockerReloaded = true
+	}
+	_, err = s.cfg.DB.MutateData(func(d *db.Data) error {
+		current := ""
+		if d.RegistryMirror != nil {
+			current = d.RegistryMirror.Upstream
+		}
+		result.Changed = dockerChanged || current != upstream
+		if upstream == "" {
+			d.RegistryMirror = nil
+		} else {
+			d.RegistryMirror = &db.RegistryMirrorConfig{Upstream: upstream}
+		}
+		return nil
+	})
+	if err != nil {
+		return catchrpc.RegistryMirrorSetResult{}, err
+	}
+	if upstream == "" {
+		s.stopRegistryMirrorListener()
+	}
+	return result, nil
+}
+
+// setDockerRegistryMirror adds mirrorURL to the front of registry-mirrors in
+// the Docker daemon config, or removes it, keeping any other mirrors. It
+// reports whether the file changed.
+func setDockerRegistryMirror(path, mirrorURL string, enabled bool) (bool, error) {
+	cfg := map[string]any{}
+	raw, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to read docker config %s: %w", path, err)
+	}
+	if len(strings.TrimSpace(string(raw))) > 0 {
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			return false, fmt.Errorf("failed to parse docker config %s: %w", path, err)
+		}
+	}
+	var current []string
+	if existing, ok := cfg["registry-mirrors"]; ok {
+		items, ok := existing.([]any)
+		if !ok {
+			return false, fmt.Errorf("docker config %s has non-list registry-mirrors", path)
+		}
+		for _, item := range items {
+			mirror, ok := item.(string)
+			if !ok {
+				return false, fmt.Errorf("docker config %s has non-string registry-mirrors entry", path)
+			}
+			current = append(current, mirror)
+		}
+	}
+	var next []string
+	if enabled {
+		next = append(next, mirrorURL)
+	}
+	for _, mirror := range current {
+		if strings.TrimSuffix(mirror, "/") != mirrorURL {
+			next = append(next, mirror)
+		}
+	}
+	if slices.Equal(current, next) {
+		return false, nil
+	}
+	if len(next) == 0 {
+		delete(cfg, "registry-mirrors")
+	} else {
+		cfg["registry-mirrors"] = next
+	}
+	out, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return false, fmt.Errorf("failed to render docker config %s: %w", path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return false, fmt.Errorf("failed to create docker config dir %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, append(out, '\n'), 0o644); err != nil {
+		return false, fmt.Errorf("failed to write docker config %s: %w", path, err)
+	}
+	return true, nil
+}
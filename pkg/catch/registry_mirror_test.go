@@ -0,0 +1,221 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+	"github.com/yeetrun/yeet/pkg/cli"
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/registry"
+)
+
+func TestSetDockerRegistryMirrorKeepsOtherSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.json")
+	if err := os.WriteFile(path, []byte(`{"features":{"containerd-snapshotter":true},"registry-mirrors":["https://mirror.example"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	read := func() map[string]any {
+		t.Helper()
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cfg map[string]any
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+
+	const mirror = "http://127.0.0.1:41549"
+	changed, err := setDockerRegistryMirror(path, mirror, true)
+	if err != nil || !changed {
+		t.Fatalf("enable = %v, %v; want changed", changed, err)
+	}
+	cfg := read()
+	if want := []any{mirror, "https://mirror.example"}; !reflect.DeepEqual(cfg["registry-mirrors"], want) {
+		t.Fatalf("registry-mirrors = %v, want %v", cfg["registry-mirrors"], want)
+	}
+	if cfg["features"] == nil {
+		t.Fatalf("features dropped: %v", cfg)
+	}
+	if changed, err := setDockerRegistryMirror(path, mirror, true); err != nil || changed {
+		t.Fatalf("enable again = %v, %v; want unchanged", changed, err)
+	}
+
+	if changed, err := setDockerRegistryMirror(path, mirror, false); err != nil || !changed {
+		t.Fatalf("disable = %v, %v; want changed", changed, err)
+	}
+	if want := []any{"https://mirror.example"}; !reflect.DeepEqual(read()["registry-mirrors"], want) {
+		t.Fatalf("registry-mirrors = %v, want %v", read()["registry-mirrors"], want)
+	}
+
+	missing := filepath.Join(t.TempDir(), "docker", "daemon.json")
+	if changed, err := setDockerRegistryMirror(missing, mirror, false); err != nil || changed {
+		t.Fatalf("disable without config = %v, %v; want unchanged", changed, err)
+	}
+}
+
+// stubRegistryMirrorListen binds an ephemeral port in place of the
+// configured mirror address and records the addresses requested.
+func stubRegistryMirrorListen(t *testing.T) *[]string {
+	t.Helper()
+	old := listenRegistryMirrorFn
+	var addrs []string
+	listenRegistryMirrorFn = func(addr string) (net.Listener, error) {
+		addrs = append(addrs, addr)
+		return net.Listen("tcp", "127.0.0.1:0")
+	}
+	t.Cleanup(func() { listenRegistryMirrorFn = old })
+	return &addrs
+}
+
+func TestStartRegistryMirrorOnlyBindsWhenConfigured(t *testing.T) {
+	server := newTestServer(t)
+	server.cfg.RegistryMirrorAddr = "127.0.0.1:41549"
+	addrs := stubRegistryMirrorListen(t)
+	if err := server.StartRegistryMirror(); err != nil || len(*addrs) != 0 {
+		t.Fatalf("StartRegistryMirror without mirror = %v, listens %v; want no listener", err, *addrs)
+	}
+
+	if _, err := server.cfg.DB.MutateData(func(d *db.Data) error {
+		d.RegistryMirror = &db.RegistryMirrorConfig{Upstream: "docker.io"}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.StartRegistryMirror(); err != nil {
+		t.Fatalf("StartRegistryMirror: %v", err)
+	}
+	t.Cleanup(server.stopRegistryMirrorListener)
+	if want := []string{"127.0.0.1:41549"}; !reflect.DeepEqual(*addrs, want) {
+		t.Fatalf("listens = %v, want %v", *addrs, want)
+	}
+
+	server.stopRegistryMirrorListener()
+	listenRegistryMirrorFn = func(string) (net.Listener, error) {
+		return nil, errors.New("address already in use")
+	}
+	if err := server.StartRegistryMirror(); err == nil || !strings.Contains(err.Error(), "address already in use") {
+		t.Fatalf("StartRegistryMirror with busy port = %v, want listen error", err)
+	}
+}
+
+func TestSetRegistryMirrorUpdatesDBAndDocker(t *testing.T) {
+	server := newTestServer(t)
+	server.cfg.RegistryMirrorAddr = "127.0.0.1:41549"
+	addrs := stubRegistryMirrorListen(t)
+	oldPath, oldReload := registryMirrorDockerConfigPath, reloadDockerDaemonFn
+	t.Cleanup(func() { registryMirrorDockerConfigPath, reloadDockerDaemonFn = oldPath, oldReload })
+	registryMirrorDockerConfigPath = filepath.Join(t.TempDir(), "daemon.json")
+	reloads := 0
+	reloadDockerDaemonFn = func() error {
+		reloads++
+		return nil
+	}
+	ctx := context.Background()
+
+	got, err := server.SetRegistryMirror(ctx, catchrpc.RegistryMirrorSetRequest{Upstream: cli.RegistryMirrorDockerHub})
+	if err != nil {
+		t.Fatalf("SetRegistryMirror: %v", err)
+	}
+	want := catchrpc.RegistryMirrorSetResult{Upstream: "docker.io", URL: "http://127.0.0.1:41549", Changed: true, DockerReloaded: true}
+	if got != want || reloads != 1 {
+		t.Fatalf("result = %#v reloads=%d, want %#v and one reload", got, reloads, want)
+	}
+	dv, err := server.getDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dv.RegistryMirror().Upstream() != "docker.io" {
+		t.Fatalf("db upstream = %q, want docker.io", dv.RegistryMirror().Upstream())
+	}
+	if len(*addrs) != 1 || server.registryMirror.ln == nil {
+		t.Fatalf("mirror listens = %v, want one bound listener", *addrs)
+	}
+
+	got, err = server.SetRegistryMirror(ctx, catchrpc.RegistryMirrorSetRequest{Upstream: "docker.io"})
+	if err != nil || got.Changed || reloads != 1 {
+		t.Fatalf("repeat = %#v, %v reloads=%d; want unchanged", got, err, reloads)
+	}
+
+	got, err = server.SetRegistryMirror(ctx, catchrpc.RegistryMirrorSetRequest{})
+	if err != nil || !got.Changed || !got.DockerReloaded {
+		t.Fatalf("disable = %#v, %v; want changed and reloaded", got, err)
+	}
+	if dv, _ := server.getDB(); dv.RegistryMirror().Valid() {
+		t.Fatal("registry mirror still configured after disable")
+	}
+	if server.registryMirror.ln != nil {
+		t.Fatal("registry mirror still listening after disable")
+	}
+
+	if _, err := server.SetRegistryMirror(ctx, catchrpc.RegistryMirrorSetRequest{Upstream: "ghcr.io"}); err == nil {
+		t.Fatal("SetRegistryMirror accepted an unsupported upstream")
+	}
+}
+
+func TestRegistryMirrorHandlerServesConfiguredUpstream(t *testing.T) {
+	upstreamStorage, err := registry.NewFilesystemStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, err := upstreamStorage.PutManifest(context.Background(), "library/nginx", "latest", []byte(`{"schemaVersion":2}`), "application/vnd.oci.image.manifest.v1+json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstream := httptest.NewServer(registry.NewHandler(upstreamStorage))
+	t.Cleanup(upstream.Close)
+	oldUpstreams := registryMirrorUpstreams
+	t.Cleanup(func() { registryMirrorUpstreams = oldUpstreams })
+	registryMirrorUpstreams = map[string]string{"docker.io": upstream.URL}
+
+	server := newTestServer(t)
+	get := func() *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		server.RegistryMirrorHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v2/library/nginx/manifests/latest", nil))
+		return rr
+	}
+	if rr := get(); rr.Code != http.StatusNotFound {
+		t.Fatalf("disabled mirror status = %d, want 404", rr.Code)
+	}
+
+	server.cfg.RegistryMirrorAddr = "127.0.0.1:41549"
+	stubRegistryMirrorListen(t)
+	t.Cleanup(server.stopRegistryMirrorListener)
+	oldPath, oldReload := registryMirrorDockerConfigPath, reloadDockerDaemonFn
+	t.Cleanup(func() { registryMirrorDockerConfigPath, reloadDockerDaemonFn = oldPath, oldReload })
+	registryMirrorDockerConfigPath = filepath.Join(t.TempDir(), "daemon.json")
+	reloadDockerDaemonFn = func() error { return nil }
+	if _, err := server.SetRegistryMirror(context.Background(), catchrpc.RegistryMirrorSetRequest{Upstream: "docker.io"}); err != nil {
+		t.Fatal(err)
+	}
+	rr := get()
+	if rr.Code != http.StatusOK || rr.Header().Get("Docker-Content-Digest") != digest {
+		t.Fatalf("mirror status=%d digest=%q, want 200 %s", rr.Code, rr.Header().Get("Docker-Content-Digest"), digest)
+	}
+	if _, err := os.Stat(filepath.Join(server.registryMirrorCacheDir("docker.io"), "manifests")); err != nil {
+		t.Fatalf("mirror cache not created: %v", err)
+	}
+}
+
+func TestRegistryMirrorRPCRequiresManagePermission(t *testing.T) {
+	got, err := rpcMethodPermissions(catchrpc.RPCMethodRegistryMirrorSet)
+	if err != nil || !got.has(permissionManage) || got.has(permissionRead) {
+		t.Fatalf("permissions = %v, %v; want manage", got, err)
+	}
+}
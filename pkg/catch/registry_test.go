@@ -13,9 +13,11 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/yeetrun/yeet/pkg/db"
 	"github.com/yeetrun/yeet/pkg/registry"
 	"github.com/yeetrun/yeet/pkg/svc"
@@ -125,6 +127,50 @@ func TestRegistryPutManifestRunInstalls(t *testing.T) {
 	}
 }
 
+func TestRegistryPutManifestLoadsPushIntoPodman(t *testing.T) {
+	server := newTestServer(t)
+	if _, err := server.cfg.DB.MutateData(func(d *db.Data) error {
+		d.ContainerRuntime = string(svc.ContainerRuntimePodman)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	server.cfg.InternalRegistryAddr = "127.0.0.1:5000"
+	storage := server.registry.storage
+	storage.newInstaller = func(*Server, FileInstallerCfg) (registryInstaller, error) {
+		return &stubInstaller{}, nil
+	}
+	oldLoad := loadPodmanImageFn
+	t.Cleanup(func() { loadPodmanImageFn = oldLoad })
+	var loads []string
+	loadErr := error(nil)
+	loadPodmanImageFn = func(_ context.Context, addr, repo, reference, digest string) error {
+		loads = append(loads, addr+" "+repo+":"+reference+"@"+digest)
+		return loadErr
+	}
+
+	manifest := []byte(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json"}`)
+	digest, err := storage.PutManifest(context.Background(), "svc/app", "run", manifest, "application/vnd.oci.image.manifest.v1+json")
+	if err != nil {
+		t.Fatalf("PutManifest: %v", err)
+	}
+	if want := []string{"127.0.0.1:5000 svc/app:run@" + digest}; !reflect.DeepEqual(loads, want) {
+		t.Fatalf("podman loads = %v, want %v", loads, want)
+	}
+
+	loadErr = errors.New("pull failed")
+	if _, err := storage.PutManifest(context.Background(), "other/app", "run", manifest, "application/vnd.oci.image.manifest.v1+json"); err == nil {
+		t.Fatal("PutManifest succeeded after podman load failed")
+	}
+	dv, err := server.cfg.DB.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := dv.Images().GetOk(db.ImageRepoName("other/app")); ok {
+		t.Fatal("failed podman load recorded image refs")
+	}
+}
+
 func TestRegistryPutManifestRejectsInvalid(t *testing.T) {
 	server := newTestServer(t)
 	storage := server.registry.storage
@@ -590,3 +636,34 @@ func TestInternalRegistryStageComposeFailsInstallerWriteAndClose(t *testing.T) {
 		})
 	}
 }
+
+func TestRegistryPutManifestRejectsForeignPlatform(t *testing.T) {
+	server := newTestServer(t)
+	storage := server.registry.storage
+	oldPlatform := registryHostPlatform
+	t.Cleanup(func() { registryHostPlatform = oldPlatform })
+	registryHostPlatform = ocispec.Platform{OS: "linux", Architecture: "arm64"}
+	installs := 0
+	storage.newInstaller = func(*Server, FileInstallerCfg) (registryInstaller, error) {
+		installs++
+		return &stubInstaller{}, nil
+	}
+	index := func(arch string) []byte {
+		return []byte(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[` +
+			`{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":"sha256:aaa","size":1,"platform":{"os":"linux","architecture":"` + arch + `"}}]}`)
+	}
+
+	_, err := storage.PutManifest(context.Background(), "svc/app", "run", index("amd64"), ocispec.MediaTypeImageIndex)
+	if err == nil || !strings.Contains(err.Error(), "built for linux/amd64, but this host runs linux/arm64") {
+		t.Fatalf("PutManifest error = %v, want platform mismatch", err)
+	}
+	if dv, _ := server.cfg.DB.Get(); dv.Images().Len() != 0 || installs != 0 {
+		t.Fatalf("foreign image recorded: images=%d installs=%d", dv.Images().Len(), installs)
+	}
+	if _, err := storage.PutManifest(context.Background(), "svc/app", "run", index("arm64"), ocispec.MediaTypeImageIndex); err != nil {
+		t.Fatalf("PutManifest with host platform: %v", err)
+	}
+	if installs != 1 {
+		t.Fatalf("installs = %d, want 1", installs)
+	}
+}
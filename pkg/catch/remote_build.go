@@ -0,0 +1,544 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+	"github.com/yeetrun/yeet/pkg/cli"
+	"github.com/yeetrun/yeet/pkg/registry"
+	"github.com/yeetrun/yeet/pkg/svc"
+)
+
+const (
+	remoteBuildManifestMaxBytes = 16 << 20
+	// remoteBuildBlobGracePeriod keeps unreferenced blobs around long enough
+	// for a build that already asked which blobs are missing to use them.
+	remoteBuildBlobGracePeriod = time.Hour
+
+	remoteBuildOnlyMessage = "--build=remote is only valid for Dockerfile payloads"
+
+	// remoteBuildTagPrefix starts the internal registry tag of a built image.
+	remoteBuildTagPrefix = "build-"
+	// remoteBuildImageManifestMaxBytes bounds a manifest read back from a
+	// built image.
+	remoteBuildImageManifestMaxBytes = 4 << 20
+	// remoteBuildLayerCacheMaxBytes caps a service's local BuildKit layer
+	// cache; a larger cache is dropped after the build.
+	remoteBuildLayerCacheMaxBytes = 10 << 30
+)
+
+var remoteBuildDigestPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// remoteBuildComposeTemplate matches the compose file yeet run generates for
+// an image ref.
+const remoteBuildComposeTemplate = `services:
+  %s:
+    image: %s
+    restart: unless-stopped
+    volumes:
+      - "./:/data"
+`
+
+// buildCacheDir holds the content-addressed build context blobs of a
+// service and its BuildKit layer cache.
+func (s *Server) buildCacheDir(sn string) string {
+	return filepath.Join(s.cfg.RootDir, "build-cache", sn)
+}
+
+func (s *Server) buildBlobDir(sn string) string {
+	return filepath.Join(s.buildCacheDir(sn), "blobs")
+}
+
+func (s *Server) buildLayerCacheDir(sn string) string {
+	return filepath.Join(s.buildCacheDir(sn), "layers")
+}
+
+// buildContextMissing reports which of digests are not cached for service.
+func (s *Server) buildContextMissing(service string, digests []string) (catchrpc.BuildContextMissingResponse, error) {
+	var resp catchrpc.BuildContextMissingResponse
+	blobDir := s.buildBlobDir(service)
+	for _, digest := range digests {
+		if !remoteBuildDigestPattern.MatchString(digest) {
+			return resp, fmt.Errorf("invalid build context digest %q", digest)
+		}
+		if _, err := os.Stat(filepath.Join(blobDir, digest)); err != nil {
+			resp.Missing = append(resp.Missing, digest)
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) remoteBuildLock(sn string) *sync.Mutex {
+	lock, _ := s.remoteBuildLocks.LoadOrStore(sn, &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+// runRemoteBuild builds the uploaded Dockerfile context with the host runtime
+// and installs the image like an image ref payload.
+func (e *ttyExecer) runRemoteBuild(flags cli.RunFlags, argsIn []string) error {
+	cfg, err := e.runFileInstallerCfg(flags, argsIn)
+	if err != nil {
+		return err
+	}
+	image, err := e.s.buildRemoteImage(e.ctx, e.sn, e.payloadReader(), e.rw, e.newCmdContext)
+	if err != nil {
+		return err
+	}
+	cfg.PayloadName = "compose.yml"
+	compose := fmt.Sprintf(remoteBuildComposeTemplate, e.sn, image)
+	return e.runInstall("run", strings.NewReader(compose), cfg)
+}
+
+type remoteBuildCmdFunc func(ctx context.Context, name string, args ...string) *exec.Cmd
+
+// buildRemoteImage unpacks a build context upload and runs docker build on
+// it, streaming the build output to out. It returns the image it built.
+func (s *Server) buildRemoteImage(ctx context.Context, sn string, in io.Reader, out io.Writer, newCmd remoteBuildCmdFunc) (string, error) {
+	lock := s.remoteBuildLock(sn)
+	lock.Lock()
+	defer lock.Unlock()
+
+	blobDir := s.buildBlobDir(sn)
+	if err := os.MkdirAll(blobDir, 0o700); err != nil {
+		return "", fmt.Errorf("create build cache: %w", err)
+	}
+	manifest, err := receiveBuildContext(in, blobDir)
+	if err != nil {
+		return "", err
+	}
+	workDir, err := os.MkdirTemp(s.buildCacheDir(sn), "build-")
+	if err != nil {
+		return "", fmt.Errorf("create build directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+	dockerfile, contextDir, err := materializeBuildContext(manifest, blobDir, workDir)
+	if err != nil {
+		return "", err
+	}
+
+	platform := "linux/" + runtime.GOARCH
+	hash := manifest.BuildHash(platform)
+	tag := remoteBuildTagPrefix + hash[:12]
+	image := fmt.Sprintf("%s/%s:%s", svc.InternalRegistryHost, sn, tag)
+	rt := s.containerRuntime()
+	args := []string{"build",
+		"--platform", platform,
+		"-t", image,
+		"-f", dockerfile,
+		"--label", catchrpc.ImageLabelBuildHash + "=" + hash,
+	}
+	layerCache := s.buildLayerCacheArgs(ctx, rt, sn, newCmd)
+	args = append(args, layerCache...)
+	cmd := newCmd(ctx, rt.Command(), append(args, contextDir)...)
+	cmd.Env = append(cmd.Env, "DOCKER_BUILDKIT=1")
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s build: %w", rt.Command(), err)
+	}
+	if err := s.registerBuiltImage(ctx, rt, sn, tag, image, workDir, newCmd); err != nil {
+		return "", err
+	}
+	pruneBuildBlobs(blobDir, manifest, time.Now().Add(-remoteBuildBlobGracePeriod))
+	if slices.Contains(layerCache, "--cache-to") {
+		pruneBuildLayerCache(s.buildLayerCacheDir(sn), remoteBuildLayerCacheMaxBytes)
+	}
+	return image, nil
+}
+
+// buildLayerCacheArgs keeps the layers of a service's builds in its own
+// BuildKit cache directory, so they survive builder cache pruning and other
+// services' builds. Podman cannot export a local cache and reuses the layers
+// in its image store instead, and so does a Docker builder whose driver
+// cannot export one, such as the default docker driver.
+func (s *Server) buildLayerCacheArgs(ctx context.Context, rt svc.ContainerRuntime, sn string, newCmd remoteBuildCmdFunc) []string {
+	if rt == svc.ContainerRuntimePodman {
+		return []string{"--layers"}
+	}
+	if driver := buildxDriver(ctx, rt, newCmd); !buildxDriverExportsCache(driver) {
+		log.Printf("buildx driver %q cannot export a local cache; %s builds reuse the builder cache only", driver, sn)
+		return nil
+	}
+	dir := s.buildLayerCacheDir(sn)
+	args := []string{"--cache-to", "type=local,mode=max,dest=" + dir}
+	// Importing a cache that was never exported fails the build.
+	if _, err := os.Stat(filepath.Join(dir, "index.json")); err == nil {
+		args = append(args, "--cache-from", "type=local,src="+dir)
+	}
+	return args
+}
+
+// buildxDriver returns the driver of the current buildx builder, or "" when
+// it cannot be determined, for example without the buildx plugin.
+func buildxDriver(ctx context.Context, rt svc.ContainerRuntime, newCmd remoteBuildCmdFunc) string {
+	out, err := newCmd(ctx, rt.Command(), "buildx", "inspect").Output()
+	if err != nil {
+		return ""
+	}
+	for line := range strings.Lines(string(out)) {
+		if value, ok := strings.CutPrefix(line, "Driver:"); ok {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// buildxDriverExportsCache reports whether a buildx driver supports the local
+// cache exporter. The docker driver only does with the containerd image
+// store, which cannot be told apart here, so it is treated as unsupported.
+func buildxDriverExportsCache(driver string) bool {
+	switch driver {
+	case "docker-container", "kubernetes", "remote":
+		return true
+	}
+	return false
+}
+
+// pruneBuildLayerCache drops the blobs of a local BuildKit cache that its
+// index.json no longer reaches; every export adds blobs but only rewrites the
+// index. A cache still larger than maxBytes is removed so the next build
+// starts a fresh one.
+func pruneBuildLayerCache(dir string, maxBytes int64) {
+	blobDir := filepath.Join(dir, "blobs", "sha256")
+	keep := make(map[string]bool)
+	if !markBuildLayerCacheBlobs(blobDir, filepath.Join(dir, "index.json"), keep) {
+		return
+	}
+	entries, err := os.ReadDir(blobDir)
+	if err != nil {
+		return
+	}
+	var size int64
+	for _, entry := range entries {
+		if !keep[entry.Name()] {
+			_ = os.Remove(filepath.Join(blobDir, entry.Name()))
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			size += info.Size()
+		}
+	}
+	if size > maxBytes {
+		log.Printf("build layer cache %s is %d bytes, over the %d byte limit; removing it", dir, size, maxBytes)
+		_ = os.RemoveAll(dir)
+	}
+}
+
+// markBuildLayerCacheBlobs marks the blobs the index or manifest at p
+// references, following nested indexes and manifests. It reports false when
+// p cannot be read or decoded, so a half-written cache is left alone.
+func markBuildLayerCacheBlobs(blobDir, p string, keep map[string]bool) bool {
+	raw, err := os.ReadFile(p)
+	if err != nil {
+		return false
+	}
+	var parsed struct {
+		Manifests []ocispec.Descriptor `json:"manifests"`
+		Config    *ocispec.Descriptor  `json:"config"`
+		Layers    []ocispec.Descriptor `json:"layers"`
+	}
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return false
+	}
+	descs := append(parsed.Manifests, parsed.Layers...)
+	if parsed.Config != nil {
+		descs = append(descs, *parsed.Config)
+	}
+	for _, desc := range descs {
+		sum := desc.Digest.Encoded()
+		if desc.Digest.Algorithm() != "sha256" || !remoteBuildDigestPattern.MatchString(sum) || keep[sum] {
+			continue
+		}
+		keep[sum] = true
+		if strings.HasSuffix(desc.MediaType, "manifest.v1+json") || strings.HasSuffix(desc.MediaType, "index.v1+json") || strings.HasSuffix(desc.MediaType, "manifest.list.v2+json") {
+			if !markBuildLayerCacheBlobs(blobDir, filepath.Join(blobDir, sum), keep) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// registerBuiltImage copies the built image from the runtime's store into the
+// internal registry and records its tag, as a push would.
+func (s *Server) registerBuiltImage(ctx context.Context, rt svc.ContainerRuntime, sn, tag, image, workDir string, newCmd remoteBuildCmdFunc) error {
+	if s.registry == nil {
+		return fmt.Errorf("internal registry is not configured")
+	}
+	archive := filepath.Join(workDir, "image.tar")
+	args := []string{"save", "-o", archive}
+	if rt == svc.ContainerRuntimePodman {
+		args = append(args, "--format", "oci-archive")
+	}
+	var stderr strings.Builder
+	cmd := newCmd(ctx, rt.Command(), append(args, image)...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s save %s: %w: %s", rt.Command(), image, err, strings.TrimSpace(stderr.String()))
+	}
+	f, err := os.Open(archive)
+	if err != nil {
+		return fmt.Errorf("open saved image: %w", err)
+	}
+	defer f.Close()
+	root, err := importOCIArchiveBlobs(ctx, s.registry.storage.base, f)
+	if err != nil {
+		return fmt.Errorf("import %s: %w", image, err)
+	}
+	if _, err := s.registry.storage.putBuiltImage(ctx, sn, tag, root); err != nil {
+		return fmt.Errorf("register %s: %w", image, err)
+	}
+	return nil
+}
+
+// importOCIArchiveBlobs stores the blobs of an OCI layout archive that are
+// not in storage yet and returns the one image its index.json names.
+func importOCIArchiveBlobs(ctx context.Context, storage registry.Storage, r io.Reader) (ocispec.Descriptor, error) {
+	var index *ocispec.Index
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return ocispec.Descriptor{}, fmt.Errorf("read image archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(hdr.Name)
+		if name == "index.json" {
+			index = new(ocispec.Index)
+			if err := json.NewDecoder(io.LimitReader(tr, remoteBuildImageManifestMaxBytes)).Decode(index); err != nil {
+				return ocispec.Descriptor{}, fmt.Errorf("decode image archive index: %w", err)
+			}
+			continue
+		}
+		sum, ok := strings.CutPrefix(name, "blobs/sha256/")
+		if !ok || !remoteBuildDigestPattern.MatchString(sum) {
+			continue
+		}
+		digest := "sha256:" + sum
+		if storage.BlobExists(ctx, digest) {
+			continue
+		}
+		session, err := storage.NewUpload(ctx)
+		if err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		if _, err := storage.CopyChunk(ctx, session.UUID, tr); err != nil {
+			_ = storage.AbortUpload(ctx, session.UUID)
+			return ocispec.Descriptor{}, fmt.Errorf("store blob %s: %w", digest, err)
+		}
+		if _, err := storage.CompleteUpload(ctx, session.UUID, digest); err != nil {
+			_ = storage.AbortUpload(ctx, session.UUID)
+			return ocispec.Descriptor{}, fmt.Errorf("store blob %s: %w", digest, err)
+		}
+	}
+	if index == nil {
+		return ocispec.Descriptor{}, fmt.Errorf("image archive has no index.json")
+	}
+	if len(index.Manifests) != 1 {
+		return ocispec.Descriptor{}, fmt.Errorf("image archive holds %d images, want 1", len(index.Manifests))
+	}
+	return index.Manifests[0], nil
+}
+
+// receiveBuildContext reads the manifest and the missing blobs from a build
+// context upload, storing each blob in blobDir after checking its digest.
+func receiveBuildContext(in io.Reader, blobDir string) (catchrpc.BuildContextManifest, error) {
+	var manifest catchrpc.BuildContextManifest
+	tr := tar.NewReader(in)
+	hdr, err := tr.Next()
+	if err != nil {
+		return manifest, fmt.Errorf("read build context: %w", err)
+	}
+	if hdr.Name != catchrpc.BuildContextManifestName {
+		return manifest, fmt.Errorf("build context must start with %s, got %q", catchrpc.BuildContextManifestName, hdr.Name)
+	}
+	if hdr.Size > remoteBuildManifestMaxBytes {
+		return manifest, fmt.Errorf("build context manifest is %d bytes; the limit is %d", hdr.Size, remoteBuildManifestMaxBytes)
+	}
+	if err := json.NewDecoder(io.LimitReader(tr, remoteBuildManifestMaxBytes)).Decode(&manifest); err != nil {
+		return manifest, fmt.Errorf("decode build context manifest: %w", err)
+	}
+	if err := validateBuildContextManifest(manifest); err != nil {
+		return manifest, err
+	}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return manifest, fmt.Errorf("read build context: %w", err)
+		}
+		digest, ok := strings.CutPrefix(hdr.Name, catchrpc.BuildContextBlobDir+"/")
+		if !ok || !remoteBuildDigestPattern.MatchString(digest) || hdr.Typeflag != tar.TypeReg {
+			return manifest, fmt.Errorf("unexpected build context entry %q", hdr.Name)
+		}
+		if err := storeBuildBlob(tr, blobDir, digest); err != nil {
+			return manifest, err
+		}
+	}
+	for _, digest := range buildContextDigests(manifest) {
+		if _, err := os.Stat(filepath.Join(blobDir, digest)); err != nil {
+			return manifest, fmt.Errorf("build context blob %s is missing; retry the build", digest)
+		}
+	}
+	return manifest, nil
+}
+
+func validateBuildContextManifest(manifest catchrpc.BuildContextManifest) error {
+	if !remoteBuildDigestPattern.MatchString(manifest.DockerfileSHA256) {
+		return fmt.Errorf("build context manifest has an invalid Dockerfile digest")
+	}
+	seen := make(map[string]bool, len(manifest.Entries))
+	for _, entry := range manifest.Entries {
+		p := entry.Path
+		if p == "" || p == "." || p != path.Clean(p) || path.IsAbs(p) || p == ".." || strings.HasPrefix(p, "../") {
+			return fmt.Errorf("invalid build context path %q", p)
+		}
+		if seen[p] {
+			return fmt.Errorf("duplicate build context path %q", p)
+		}
+		seen[p] = true
+		if entry.Link == "" && !remoteBuildDigestPattern.MatchString(entry.SHA256) {
+			return fmt.Errorf("build context file %q has an invalid digest", p)
+		}
+	}
+	return nil
+}
+
+func buildContextDigests(manifest catchrpc.BuildContextManifest) []string {
+	digests := []string{manifest.DockerfileSHA256}
+	for _, entry := range manifest.Entries {
+		if entry.Link == "" {
+			digests = append(digests, entry.SHA256)
+		}
+	}
+	return digests
+}
+
+func storeBuildBlob(r io.Reader, blobDir, digest string) error {
+	tmp, err := os.CreateTemp(blobDir, ".upload-")
+	if err != nil {
+		return fmt.Errorf("store build context blob: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	h := sha256.New()
+	_, copyErr := io.Copy(io.MultiWriter(tmp, h), r)
+	closeErr := tmp.Close()
+	if err := errors.Join(copyErr, closeErr); err != nil {
+		return fmt.Errorf("store build context blob: %w", err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != digest {
+		return fmt.Errorf("build context blob %s has digest %s; a file changed during upload", digest, got)
+	}
+	return os.Rename(tmp.Name(), filepath.Join(blobDir, digest))
+}
+
+// materializeBuildContext lays the manifest out under workDir and returns
+// the Dockerfile and context paths. Symlinks are created last so no file is
+// ever written through one.
+func materializeBuildContext(manifest catchrpc.BuildContextManifest, blobDir, workDir string) (dockerfile, contextDir string, _ error) {
+	dockerfile = filepath.Join(workDir, "Dockerfile")
+	if err := copyBuildBlob(filepath.Join(blobDir, manifest.DockerfileSHA256), dockerfile, 0o644); err != nil {
+		return "", "", err
+	}
+	contextDir = filepath.Join(workDir, "context")
+	if err := os.Mkdir(contextDir, 0o755); err != nil {
+		return "", "", err
+	}
+	for _, entry := range manifest.Entries {
+		if entry.Link != "" {
+			continue
+		}
+		dst := filepath.Join(contextDir, filepath.FromSlash(entry.Path))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return "", "", fmt.Errorf("build context %s: %w", entry.Path, err)
+		}
+		if err := copyBuildBlob(filepath.Join(blobDir, entry.SHA256), dst, os.FileMode(entry.Mode).Perm()); err != nil {
+			return "", "", err
+		}
+	}
+	for _, entry := range manifest.Entries {
+		if entry.Link == "" {
+			continue
+		}
+		dst := filepath.Join(contextDir, filepath.FromSlash(entry.Path))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return "", "", fmt.Errorf("build context %s: %w", entry.Path, err)
+		}
+		if err := os.Symlink(entry.Link, dst); err != nil {
+			return "", "", fmt.Errorf("build context %s: %w", entry.Path, err)
+		}
+	}
+	return dockerfile, contextDir, nil
+}
+
+func copyBuildBlob(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("open build context blob: %w", err)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return fmt.Errorf("write build context file: %w", err)
+	}
+	_, copyErr := io.Copy(out, in)
+	closeErr := out.Close()
+	if err := errors.Join(copyErr, closeErr); err != nil {
+		return fmt.Errorf("write build context file: %w", err)
+	}
+	// Mode is subject to the umask on create.
+	return os.Chmod(dst, mode)
+}
+
+// pruneBuildBlobs removes blobs the last build did not use once they are
+// older than before. The next build of the service mostly shares them.
+func pruneBuildBlobs(blobDir string, manifest catchrpc.BuildContextManifest, before time.Time) {
+	keep := make(map[string]bool)
+	for _, digest := range buildContextDigests(manifest) {
+		keep[digest] = true
+	}
+	entries, err := os.ReadDir(blobDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if keep[entry.Name()] {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(before) {
+			continue
+		}
+		_ = os.Remove(filepath.Join(blobDir, entry.Name()))
+	}
+}
@@ -0,0 +1,293 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/svc"
+)
+
+func testBuildDigest(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func testBuildContextUpload(t *testing.T, manifest catchrpc.BuildContextManifest, blobs map[string]string) io.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []struct{ name, content string }{{catchrpc.BuildContextManifestName, string(raw)}}
+	for name, content := range blobs {
+		entries = append(entries, struct{ name, content string }{catchrpc.BuildContextBlobDir + "/" + name, content})
+	}
+	for _, entry := range entries {
+		if err := tw.WriteHeader(&tar.Header{Name: entry.name, Mode: 0o644, Size: int64(len(entry.content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+// testOCIImageArchive returns an OCI layout archive like `docker save`
+// writes, holding one image, and the digest of its manifest.
+func testOCIImageArchive(t *testing.T) ([]byte, string) {
+	t.Helper()
+	config := `{"architecture":"` + runtime.GOARCH + `","os":"linux","rootfs":{"type":"layers","diff_ids":[]}}`
+	layer := "layer"
+	manifest := `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json",` +
+		`"config":{"mediaType":"application/vnd.oci.image.config.v1+json","digest":"sha256:` + testBuildDigest(config) + `","size":` + strconv.Itoa(len(config)) + `},` +
+		`"layers":[{"mediaType":"application/vnd.oci.image.layer.v1.tar","digest":"sha256:` + testBuildDigest(layer) + `","size":` + strconv.Itoa(len(layer)) + `}]}`
+	index := `{"schemaVersion":2,"manifests":[{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":"sha256:` + testBuildDigest(manifest) + `","size":` + strconv.Itoa(len(manifest)) + `}]}`
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, entry := range []struct{ name, content string }{
+		{"oci-layout", `{"imageLayoutVersion":"1.0.0"}`},
+		{"blobs/sha256/" + testBuildDigest(config), config},
+		{"blobs/sha256/" + testBuildDigest(layer), layer},
+		{"blobs/sha256/" + testBuildDigest(manifest), manifest},
+		{"index.json", index},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: entry.name, Mode: 0o644, Size: int64(len(entry.content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes(), "sha256:" + testBuildDigest(manifest)
+}
+
+func TestBuildRemoteImageReusesCachedBlobs(t *testing.T) {
+	server := newTestServer(t)
+	dockerfile := "FROM scratch\nCOPY app.txt /\n"
+	app := "hello\n"
+	manifest := catchrpc.BuildContextManifest{
+		DockerfileSHA256: testBuildDigest(dockerfile),
+		Entries: []catchrpc.BuildContextEntry{
+			{Path: "Dockerfile", Mode: 0o644, SHA256: testBuildDigest(dockerfile)},
+			{Path: "app.txt", Mode: 0o600, SHA256: testBuildDigest(app)},
+			{Path: "current", Link: "app.txt"},
+		},
+	}
+	archive, manifestDigest := testOCIImageArchive(t)
+	var gotArgs []string
+	var gotApp string
+	newCmd := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		if args[0] == "save" {
+			if err := os.WriteFile(args[2], archive, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			return exec.CommandContext(ctx, "true")
+		}
+		if args[0] == "buildx" {
+			return exec.CommandContext(ctx, "echo", "Name: yeet\nDriver: docker-container")
+		}
+		gotArgs = append([]string{name}, args...)
+		raw, _ := os.ReadFile(filepath.Join(args[len(args)-1], "current"))
+		gotApp = string(raw)
+		return exec.CommandContext(ctx, "true")
+	}
+	if _, err := server.cfg.DB.MutateData(func(d *db.Data) error {
+		d.Images = map[db.ImageRepoName]*db.ImageRepo{"web": {Refs: map[db.ImageRef]db.ImageManifest{
+			"build-0123456789ab": {BlobHash: "sha256:" + testBuildDigest("old")},
+			"run":                {BlobHash: "sha256:" + testBuildDigest("run")},
+		}}}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	upload := testBuildContextUpload(t, manifest, map[string]string{
+		testBuildDigest(dockerfile): dockerfile,
+		testBuildDigest(app):        app,
+	})
+	image, err := server.buildRemoteImage(context.Background(), "web", upload, io.Discard, newCmd)
+	if err != nil {
+		t.Fatalf("buildRemoteImage: %v", err)
+	}
+	hash := manifest.BuildHash("linux/" + runtime.GOARCH)
+	if want := "catchit.dev/web:build-" + hash[:12]; image != want {
+		t.Fatalf("image = %q, want %q", image, want)
+	}
+	if !slices.Contains(gotArgs, catchrpc.ImageLabelBuildHash+"="+hash) || gotApp != app {
+		t.Fatalf("docker args = %q, context app = %q", gotArgs, gotApp)
+	}
+	cacheDir := server.buildLayerCacheDir("web")
+	if !slices.Contains(gotArgs, "type=local,mode=max,dest="+cacheDir) || slices.Contains(gotArgs, "--cache-from") {
+		t.Fatalf("docker args = %q, want cache export only on the first build", gotArgs)
+	}
+	dv, err := server.getDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := dv.Images().Get("web").Refs()
+	if mf, ok := refs.GetOk(db.ImageRef("build-" + hash[:12])); !ok || mf.BlobHash != manifestDigest {
+		t.Fatalf("build ref = %+v, %v; want manifest %s", mf, ok, manifestDigest)
+	}
+	if refs.Contains("build-0123456789ab") || !refs.Contains("run") {
+		t.Fatalf("refs after build = %v, want the old build tag dropped and run kept", refs.AsMap())
+	}
+	if _, err := server.registry.storage.GetManifest(context.Background(), "web", "build-"+hash[:12]); err != nil {
+		t.Fatalf("registry manifest for build ref: %v", err)
+	}
+	if err := os.MkdirAll(cacheDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cacheDir, "index.json"), []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	missing, err := server.buildContextMissing("web", []string{testBuildDigest(app), testBuildDigest("new\n")})
+	if err != nil {
+		t.Fatalf("buildContextMissing: %v", err)
+	}
+	if !slices.Equal(missing.Missing, []string{testBuildDigest("new\n")}) {
+		t.Fatalf("missing = %q, want only the new blob", missing.Missing)
+	}
+	// A second build sends no blobs at all.
+	if _, err := server.buildRemoteImage(context.Background(), "web", testBuildContextUpload(t, manifest, nil), io.Discard, newCmd); err != nil {
+		t.Fatalf("cached buildRemoteImage: %v", err)
+	}
+	if !slices.Contains(gotArgs, "type=local,src="+cacheDir) {
+		t.Fatalf("docker args = %q, want the service layer cache imported", gotArgs)
+	}
+}
+
+func TestBuildRemoteImageRejectsBadUploads(t *testing.T) {
+	dockerfile := "FROM scratch\n"
+	base := catchrpc.BuildContextManifest{DockerfileSHA256: testBuildDigest(dockerfile)}
+	tests := []struct {
+		name     string
+		manifest catchrpc.BuildContextManifest
+		blobs    map[string]string
+		want     string
+	}{
+		{
+			name:     "escaping path",
+			manifest: catchrpc.BuildContextManifest{DockerfileSHA256: base.DockerfileSHA256, Entries: []catchrpc.BuildContextEntry{{Path: "../etc/passwd", SHA256: testBuildDigest(dockerfile)}}},
+			blobs:    map[string]string{testBuildDigest(dockerfile): dockerfile},
+			want:     "invalid build context path",
+		},
+		{
+			name:     "digest mismatch",
+			manifest: base,
+			blobs:    map[string]string{testBuildDigest(dockerfile): "FROM busybox\n"},
+			want:     "a file changed during upload",
+		},
+		{
+			name:     "missing blob",
+			manifest: base,
+			want:     "is missing",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			newCmd := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+				t.Fatal("docker build ran for a bad upload")
+				return nil
+			}
+			_, err := newTestServer(t).buildRemoteImage(context.Background(), "web", testBuildContextUpload(t, tc.manifest, tc.blobs), io.Discard, newCmd)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("buildRemoteImage error = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuildLayerCacheArgsFollowBuildxDriver(t *testing.T) {
+	server := newTestServer(t)
+	dir := server.buildLayerCacheDir("web")
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{"docker-container", "Name: yeet\nDriver: docker-container\n", []string{"--cache-to", "type=local,mode=max,dest=" + dir}},
+		{"docker", "Name: default\nDriver: docker\n", nil},
+		{"no buildx", "", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			newCmd := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+				if tc.output == "" {
+					return exec.CommandContext(ctx, "false")
+				}
+				return exec.CommandContext(ctx, "printf", "%s", tc.output)
+			}
+			got := server.buildLayerCacheArgs(context.Background(), svc.ContainerRuntimeDocker, "web", newCmd)
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("buildLayerCacheArgs = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPruneBuildLayerCache(t *testing.T) {
+	dir := t.TempDir()
+	blobDir := filepath.Join(dir, "blobs", "sha256")
+	if err := os.MkdirAll(blobDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeBlob := func(data string) string {
+		sum := testBuildDigest(data)
+		if err := os.WriteFile(filepath.Join(blobDir, sum), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return sum
+	}
+	layer := writeBlob("layer")
+	config := writeBlob(`{"layers":[]}`)
+	stale := writeBlob("stale layer")
+	index := writeBlob(`{"schemaVersion":2,"manifests":[{"mediaType":"application/vnd.oci.image.layer.v1.tar+gzip","digest":"sha256:` + layer + `"},{"mediaType":"application/vnd.buildkit.cacheconfig.v0","digest":"sha256:` + config + `"}]}`)
+	top := `{"schemaVersion":2,"manifests":[{"mediaType":"application/vnd.oci.image.index.v1+json","digest":"sha256:` + index + `"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "index.json"), []byte(top), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	pruneBuildLayerCache(dir, 1<<20)
+	for _, sum := range []string{layer, config, index} {
+		if _, err := os.Stat(filepath.Join(blobDir, sum)); err != nil {
+			t.Fatalf("referenced blob %s was pruned: %v", sum, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(blobDir, stale)); !os.IsNotExist(err) {
+		t.Fatalf("stale blob stat = %v, want removed", err)
+	}
+
+	pruneBuildLayerCache(dir, 8)
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("oversized cache stat = %v, want removed", err)
+	}
+}
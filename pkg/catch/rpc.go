@@ -165,12 +165,16 @@ func (s *Server) dispatchRPCWithContext(ctx context.Context, req catchrpc.Reques
 	case "catch.ServiceInfo":
 		return s.handleRPCServiceInfo(ctx, req)
 	case "catch.ArtifactHashes":
-		return s.handleRPCArtifactHashes(req)
+		return s.handleRPCArtifactHashes(ctx, req)
+	case catchrpc.RPCMethodBuildContextMissing:
+		return s.handleRPCBuildContextMissing(req)
 	case "catch.ZFSServiceRootCandidates", catchrpc.RPCMethodServiceRootDefaults:
 		return s.handleRPCStorageRead(ctx, req)
 	case catchrpc.RPCMethodHostStoragePlan, catchrpc.RPCMethodHostStorageApply,
 		catchrpc.RPCMethodHostStorageFinalize, catchrpc.RPCMethodHostStorageCleanup,
-		catchrpc.RPCMethodISOPoolPlan, catchrpc.RPCMethodISOPoolApply:
+		catchrpc.RPCMethodISOPoolPlan, catchrpc.RPCMethodISOPoolApply,
+		catchrpc.RPCMethodRegistryMirrorSet, catchrpc.RPCMethodContainerRuntimeSet,
+		catchrpc.RPCMethodImagePolicySet:
 		return s.handleRPCHostMutation(ctx, req)
 	case "catch.VMDefaults":
 		return s.handleRPCVMDefaults(ctx, req)
@@ -190,6 +194,12 @@ func (s *Server) handleRPCHostMutation(ctx context.Context, req catchrpc.Request
 		return s.handleRPCHostStorage(ctx, req)
 	case catchrpc.RPCMethodISOPoolPlan, catchrpc.RPCMethodISOPoolApply:
 		return s.handleRPCISOPool(ctx, req)
+	case catchrpc.RPCMethodRegistryMirrorSet:
+		return s.handleRPCRegistryMirrorSet(ctx, req)
+	case catchrpc.RPCMethodContainerRuntimeSet:
+		return s.handleRPCContainerRuntimeSet(ctx, req)
+	case catchrpc.RPCMethodImagePolicySet:
+		return s.handleRPCImagePolicySet(req)
 	default:
 		return newRPCError(req.ID, catchrpc.ErrMethodNotFound, "method not found", req.Method)
 	}
@@ -211,7 +221,7 @@ func (s *Server) handleRPCServiceInfo(ctx context.Context, req catchrpc.Request)
 	return newRPCResponse(req.ID, resp)
 }
 
-func (s *Server) handleRPCArtifactHashes(req catchrpc.Request) catchrpc.Response {
+func (s *Server) handleRPCArtifactHashes(ctx context.Context, req catchrpc.Request) catchrpc.Response {
 	var params catchrpc.ArtifactHashesRequest
 	if rpcErr := decodeRPCParams(req.Params, &params); rpcErr != nil {
 		return responseFromRPCError(req.ID, rpcErr)
@@ -224,6 +234,29 @@ func (s *Server) handleRPCArtifactHashes(req catchrpc.Request) catchrpc.Response
 	if err != nil {
 		return newRPCError(req.ID, catchrpc.ErrInternal, "failed to get artifact hashes", err.Error())
 	}
+	if params.Images && resp.Found {
+		// Without images the client treats the payload as changed, so a
+		// failed lookup only costs a redeploy.
+		if resp.Images, err = s.artifactImages(ctx, service); err != nil {
+			log.Printf("artifact images for %s: %v", service, err)
+		}
+	}
+	return newRPCResponse(req.ID, resp)
+}
+
+func (s *Server) handleRPCBuildContextMissing(req catchrpc.Request) catchrpc.Response {
+	var params catchrpc.BuildContextMissingRequest
+	if rpcErr := decodeRPCParams(req.Params, &params); rpcErr != nil {
+		return responseFromRPCError(req.ID, rpcErr)
+	}
+	service, rpcErr := validateServiceParam(params.Service)
+	if rpcErr != nil {
+		return responseFromRPCError(req.ID, rpcErr)
+	}
+	resp, err := s.buildContextMissing(service, params.Digests)
+	if err != nil {
+		return newRPCError(req.ID, catchrpc.ErrInvalidParams, "invalid build context digests", err.Error())
+	}
 	return newRPCResponse(req.ID, resp)
 }
 
@@ -359,6 +392,42 @@ func (s *Server) handleRPCISOPoolApply(ctx context.Context, req catchrpc.Request
 	}
 	return newRPCResponse(req.ID, resp)
 }
+
+func (s *Server) handleRPCRegistryMirrorSet(ctx context.Context, req catchrpc.Request) catchrpc.Response {
+	var params catchrpc.RegistryMirrorSetRequest
+	if rpcErr := decodeRPCParams(req.Params, &params); rpcErr != nil {
+		return responseFromRPCError(req.ID, rpcErr)
+	}
+	resp, err := s.SetRegistryMirror(ctx, params)
+	if err != nil {
+		return newRPCError(req.ID, catchrpc.ErrInternal, "failed to set registry mirror", err.Error())
+	}
+	return newRPCResponse(req.ID, resp)
+}
+
+func (s *Server) handleRPCContainerRuntimeSet(ctx context.Context, req catchrpc.Request) catchrpc.Response {
+	var params catchrpc.ContainerRuntimeSetRequest
+	if rpcErr := decodeRPCParams(req.Params, &params); rpcErr != nil {
+		return responseFromRPCError(req.ID, rpcErr)
+	}
+	resp, err := s.SetContainerRuntime(ctx, params)
+	if err != nil {
+		return newRPCError(req.ID, catchrpc.ErrInternal, "failed to set container runtime", err.Error())
+	}
+	return newRPCResponse(req.ID, resp)
+}
+
+func (s *Server) handleRPCImagePolicySet(req catchrpc.Request) catchrpc.Response {
+	var params catchrpc.ImagePolicySetRequest
+	if rpcErr := decodeRPCParams(req.Params, &params); rpcErr != nil {
+		return responseFromRPCError(req.ID, rpcErr)
+	}
+	resp, err := s.SetImagePolicy(params)
+	if err != nil {
+		return newRPCError(req.ID, catchrpc.ErrInternal, "failed to set image policy", err.Error())
+	}
+	return newRPCResponse(req.ID, resp)
+}
 func (s *Server) handleRPCVMDefaults(ctx context.Context, req catchrpc.Request) catchrpc.Response {
 	var params catchrpc.VMDefaultsRequest
 	if rpcErr := decodeRPCParams(req.Params, &params); rpcErr != nil {
@@ -505,6 +574,10 @@ func normalizeExecRequest(req catchrpc.ExecRequest) (catchrpc.ExecRequest, error
 		return req, nil
 	case catchrpc.ExecTargetVMSSHProxy:
 		return normalizeVMSSHProxyExecRequest(req)
+	case catchrpc.ExecTargetVMAgentExec:
+		return normalizeServiceExecRequest(req)
+	case catchrpc.ExecTargetVMAgentPush, catchrpc.ExecTargetVMAgentPull:
+		return normalizeVMAgentFileExecRequest(req)
 	default:
 		return catchrpc.ExecRequest{}, fmt.Errorf("unknown exec target %q", req.Target)
 	}
@@ -528,6 +601,21 @@ func normalizeVMSSHProxyExecRequest(req catchrpc.ExecRequest) (catchrpc.ExecRequ
 	return req, nil
 }
 
+func normalizeVMAgentFileExecRequest(req catchrpc.ExecRequest) (catchrpc.ExecRequest, error) {
+	if _, err := normalizeServiceExecRequest(req); err != nil {
+		return catchrpc.ExecRequest{}, err
+	}
+	want := 1
+	if req.Target == catchrpc.ExecTargetVMAgentPush {
+		want = 2
+	}
+	if len(req.Args) != want || strings.TrimSpace(req.Args[0]) == "" {
+		return catchrpc.ExecRequest{}, fmt.Errorf("expected a guest file path")
+	}
+	req.TTY = false
+	return req, nil
+}
+
 func (s *Server) handleExecWS(w http.ResponseWriter, r *http.Request) {
 	conn, ok := upgradeRPCWebSocket(w, r)
 	if !ok {
@@ -642,6 +730,11 @@ func writeExecRunResult(conn *websocket.Conn, err error) {
 		writeExecExit(conn, 0, "")
 		return
 	}
+	var exitErr vmAgentExitError
+	if errors.As(err, &exitErr) {
+		writeExecExit(conn, exitErr.code, "")
+		return
+	}
 	writeExecExit(conn, 1, err.Error())
 }
 
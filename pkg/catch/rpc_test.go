@@ -1216,7 +1216,7 @@ func TestRPCArtifactHashesMissingService(t *testing.T) {
 	if err != nil {
 		t.Fatalf("marshal params: %v", err)
 	}
-	resp := server.handleRPCArtifactHashes(catchrpc.Request{
+	resp := server.handleRPCArtifactHashes(context.Background(), catchrpc.Request{
 		ID:     json.RawMessage("1"),
 		Params: params,
 	})
@@ -0,0 +1,731 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+	"github.com/yeetrun/yeet/pkg/cli"
+	"github.com/yeetrun/yeet/pkg/copyutil"
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/serviceid"
+	"github.com/yeetrun/yeet/pkg/svc"
+)
+
+const (
+	serviceArchivePayloadDir = "payload"
+	serviceArchiveEnvName    = "env"
+	serviceArchiveRootDir    = "root"
+	serviceArchiveComposeDir = "compose"
+
+	// serviceArchiveManifestLimit bounds manifest reads from untrusted archives.
+	serviceArchiveManifestLimit = 1 << 20
+)
+
+// serviceArchivePayloadArtifacts lists the user payload artifacts in the order
+// export prefers them. TypeScript and Python payloads also persist a generated
+// compose file, so their source artifact must win.
+var serviceArchivePayloadArtifacts = []db.ArtifactName{
+	db.ArtifactBinary,
+	db.ArtifactTypeScriptFile,
+	db.ArtifactPythonFile,
+	db.ArtifactDockerComposeFile,
+}
+
+// serviceArchiveSkippedRootDirs are rebuilt by the installer on import, so
+// the root copy only carries service-owned state.
+var serviceArchiveSkippedRootDirs = []string{"bin", "env", "run"}
+
+var (
+	isServiceRunningForExport = func(s *Server, name string) (bool, error) {
+		return s.IsServiceRunning(name)
+	}
+	serviceRunnerForExport = func(s *Server, service *db.Service) (ServiceRunner, error) {
+		return s.serviceRootRestoreRunner(service)
+	}
+)
+
+// serviceArchiveFile is one archive entry read from Source, or from Data or
+// the Size bytes Open returns when it does not live in a plain file.
+type serviceArchiveFile struct {
+	Name   string
+	Source string
+	Data   []byte
+	Open   func() (io.ReadCloser, error)
+	Size   int64
+}
+
+type serviceArchivePlan struct {
+	Manifest catchrpc.ServiceArchiveManifest
+	Files    []serviceArchiveFile
+	Root     string
+}
+
+func (e *ttyExecer) serviceExportCmdFunc(name string, flags cli.ServiceExportFlags) error {
+	sv, err := e.s.serviceView(name)
+	if err != nil {
+		return err
+	}
+	plan, err := e.s.serviceArchivePlan(sv, flags.Data)
+	if err != nil {
+		return err
+	}
+	if !flags.Data {
+		return writeServiceArchive(e.rw, plan)
+	}
+	return e.s.withServiceStoppedForExport(sv.AsStruct(), func() error {
+		return writeServiceArchive(e.rw, plan)
+	})
+}
+
+func (s *Server) serviceArchivePlan(sv db.ServiceView, includeData bool) (serviceArchivePlan, error) {
+	if err := validateServiceArchiveSource(sv); err != nil {
+		return serviceArchivePlan{}, err
+	}
+	service := sv.AsStruct()
+	payloadName, payloadPath, err := serviceArchivePayload(sv)
+	if err != nil {
+		return serviceArchivePlan{}, err
+	}
+	manifest := catchrpc.ServiceArchiveManifest{
+		Format:      catchrpc.ServiceArchiveFormat,
+		Service:     service.Name,
+		ServiceType: string(service.ServiceType),
+		Generation:  service.Generation,
+		ServiceRoot: s.serviceRootFromView(sv),
+		Payload:     path.Join(serviceArchivePayloadDir, string(payloadName)),
+		Data:        includeData,
+		Publish:     slices.Clone(service.Publish),
+		Network:     serviceArchiveNetwork(sv),
+		Identity:    serviceArchiveIdentity(service),
+		Sandbox:     serviceArchiveSandbox(sv),
+		Snapshots:   snapshotPolicyRPC(service.SnapshotPolicy),
+		QuotaBytes:  service.QuotaBytes,
+	}
+	if payloadName == db.ArtifactBinary {
+		if manifest.PayloadArgs, err = serviceArchivePayloadArgs(sv, payloadPath); err != nil {
+			return serviceArchivePlan{}, err
+		}
+	}
+	if manifest.Schedule, err = serviceArchiveSchedule(sv); err != nil {
+		return serviceArchivePlan{}, err
+	}
+	plan := serviceArchivePlan{Files: []serviceArchiveFile{{Name: manifest.Payload, Source: payloadPath}}}
+	if envPath, ok := activeGenerationArtifactPath(sv, db.ArtifactEnvFile); ok {
+		manifest.Env = true
+		plan.Files = append(plan.Files, serviceArchiveFile{Name: serviceArchiveEnvName, Source: envPath})
+	}
+	if err := addServiceArchiveComposeProject(sv, &manifest, &plan); err != nil {
+		return serviceArchivePlan{}, err
+	}
+	if err := s.addServiceArchiveImages(context.Background(), sv, &manifest, &plan); err != nil {
+		return serviceArchivePlan{}, err
+	}
+	if includeData {
+		plan.Root = manifest.ServiceRoot
+	}
+	plan.Manifest = manifest
+	return plan, nil
+}
+
+// addServiceArchiveComposeProject adds the override files, interpolation env
+// files and profiles the current generation layers on its compose file.
+func addServiceArchiveComposeProject(sv db.ServiceView, manifest *catchrpc.ServiceArchiveManifest, plan *serviceArchivePlan) error {
+	if sv.ServiceType() != db.ServiceTypeDockerCompose {
+		return nil
+	}
+	service := sv.AsStruct()
+	if files := service.Artifacts.ComposeFiles(db.Gen(service.Generation)); len(files) > 1 {
+		for n, source := range files[1:] {
+			plan.Files = append(plan.Files, serviceArchiveFile{Name: serviceArchiveComposeOverrideName(n), Source: source})
+		}
+		manifest.ComposeOverrides = len(files) - 1
+	}
+	project, err := svc.ComposeProjectFromArtifacts(service.Artifacts, service.Generation)
+	if err != nil {
+		return err
+	}
+	for n, source := range project.EnvFiles {
+		plan.Files = append(plan.Files, serviceArchiveFile{Name: serviceArchiveComposeEnvName(n), Source: source})
+	}
+	manifest.ComposeEnvFiles = len(project.EnvFiles)
+	manifest.Profiles = project.Profiles
+	return nil
+}
+
+func serviceArchiveComposeOverrideName(n int) string {
+	return path.Join(serviceArchiveComposeDir, fmt.Sprintf("override.%d.yml", n))
+}
+
+func serviceArchiveComposeEnvName(n int) string {
+	return path.Join(serviceArchiveComposeDir, fmt.Sprintf("env.%d", n))
+}
+
+func validateServiceArchiveSource(sv db.ServiceView) error {
+	switch sv.Name() {
+	case CatchService, SystemService:
+		return fmt.Errorf("cannot export reserved service %q", sv.Name())
+	}
+	switch sv.ServiceType() {
+	case db.ServiceTypeSystemd, db.ServiceTypeDockerCompose:
+		return nil
+	case db.ServiceTypeVM:
+		return fmt.Errorf("service %q is a VM; VM export is not supported", sv.Name())
+	default:
+		return fmt.Errorf("service %q has not been installed", sv.Name())
+	}
+}
+
+func serviceArchivePayload(sv db.ServiceView) (db.ArtifactName, string, error) {
+	for _, name := range serviceArchivePayloadArtifacts {
+		if path, ok := activeGenerationArtifactPath(sv, name); ok {
+			return name, path, nil
+		}
+	}
+	return "", "", fmt.Errorf("service %q has no payload artifact for generation %d", sv.Name(), sv.Generation())
+}
+
+// serviceArchivePayloadArgs recovers binary arguments from the installed unit
+// because they are only persisted in the rendered ExecStart.
+func serviceArchivePayloadArgs(sv db.ServiceView, payload string) ([]string, error) {
+	unitPath, ok := activeGenerationArtifactPath(sv, db.ArtifactSystemdUnit)
+	if !ok {
+		return nil, nil
+	}
+	raw, err := os.ReadFile(unitPath)
+	if err != nil {
+		return nil, fmt.Errorf("read systemd unit for export: %w", err)
+	}
+	lines, _ := splitNativeSandboxUnit(string(raw))
+	_, argv, err := nativeSandboxExecStart(lines)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := serviceSandboxPolicyForExactGeneration(sv.AsStruct(), sv.Generation())
+	if err != nil {
+		return nil, err
+	}
+	payloadArgv, err := nativeSandboxPayloadArgv(argv, nativeSandboxUnitRequest{CurrentPolicy: policy, Payload: payload})
+	if err != nil {
+		return nil, fmt.Errorf("recover payload args for export: %w", err)
+	}
+	if len(payloadArgv) == 1 {
+		return nil, nil
+	}
+	return payloadArgv[1:], nil
+}
+
+func serviceArchiveSchedule(sv db.ServiceView) (string, error) {
+	timerPath, ok := activeGenerationArtifactPath(sv, db.ArtifactSystemdTimerFile)
+	if !ok {
+		return "", nil
+	}
+	timer, err := readSystemdTimerConfig(timerPath)
+	if err != nil {
+		return "", fmt.Errorf("read timer for export: %w", err)
+	}
+	return timer.OnCalendar, nil
+}
+
+func serviceArchiveNetwork(sv db.ServiceView) *catchrpc.ServiceNetworkSettings {
+	desired := desiredServiceNetworkConfig(sv)
+	if len(desired.Modes) == 0 {
+		return nil
+	}
+	return &catchrpc.ServiceNetworkSettings{
+		Modes:         slices.Clone(desired.Modes),
+		TSVersion:     desired.TSVersion,
+		TSExitNode:    desired.TSExitNode,
+		TSTags:        slices.Clone(desired.TSTags),
+		MacvlanParent: desired.MacvlanParent,
+		MacvlanVLAN:   desired.MacvlanVLAN,
+		MacvlanMAC:    desired.MacvlanMAC,
+	}
+}
+
+// serviceArchiveIdentity keeps only the requested account names; numeric IDs
+// are host-specific and are resolved again on import.
+func serviceArchiveIdentity(service *db.Service) *catchrpc.ServiceIdentity {
+	if service.Identity == nil || service.Identity.RequestedUser == "" {
+		return nil
+	}
+	return &catchrpc.ServiceIdentity{
+		RequestedUser:  service.Identity.RequestedUser,
+		RequestedGroup: service.Identity.RequestedGroup,
+	}
+}
+
+func serviceArchiveSandbox(sv db.ServiceView) *catchrpc.ServiceSandbox {
+	sandbox := serviceSandboxInfo(sv)
+	if sandbox == nil || sandbox.State == "legacy" {
+		return nil
+	}
+	return sandbox
+}
+
+func (s *Server) withServiceStoppedForExport(service *db.Service, operation func() error) (retErr error) {
+	running, err := isServiceRunningForExport(s, service.Name)
+	if err != nil {
+		return fmt.Errorf("check service %q before export: %w", service.Name, err)
+	}
+	if !running {
+		return operation()
+	}
+	runner, err := serviceRunnerForExport(s, service)
+	if err != nil {
+		return err
+	}
+	if err := runner.Stop(); err != nil {
+		return fmt.Errorf("stop service %q for a consistent export: %w", service.Name, err)
+	}
+	log.Printf("stopped service %q for export", service.Name)
+	defer func() {
+		if err := runner.Start(); err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("restart service %q after export: %w", service.Name, err))
+		}
+	}()
+	return operation()
+}
+
+func writeServiceArchive(w io.Writer, plan serviceArchivePlan) (retErr error) {
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return fmt.Errorf("create archive compressor: %w", err)
+	}
+	defer func() {
+		if err := zw.Close(); err != nil && retErr == nil {
+			retErr = fmt.Errorf("finish archive compression: %w", err)
+		}
+	}()
+	tw := tar.NewWriter(zw)
+	if err := writeServiceArchiveEntries(tw, plan); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("finish archive: %w", err)
+	}
+	return nil
+}
+
+func writeServiceArchiveEntries(tw *tar.Writer, plan serviceArchivePlan) error {
+	if err := writeServiceArchiveManifest(tw, plan.Manifest); err != nil {
+		return err
+	}
+	for _, file := range plan.Files {
+		if err := writeServiceArchiveFile(tw, file); err != nil {
+			return fmt.Errorf("archive %s: %w", file.Name, err)
+		}
+	}
+	if plan.Root == "" {
+		return nil
+	}
+	if err := copyutil.AppendDirectory(tw, plan.Root, serviceArchiveRootDir, copyutil.TarOptions{
+		Filter: serviceArchiveRootFilter(plan.Root),
+	}); err != nil {
+		return fmt.Errorf("archive service root: %w", err)
+	}
+	return nil
+}
+
+func writeServiceArchiveFile(tw *tar.Writer, file serviceArchiveFile) error {
+	if file.Source != "" {
+		return copyutil.AppendFile(tw, file.Source, file.Name)
+	}
+	r := io.Reader(bytes.NewReader(file.Data))
+	size := int64(len(file.Data))
+	if file.Open != nil {
+		rc, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = rc.Close() }()
+		r, size = rc, file.Size
+	}
+	hdr := &tar.Header{Name: file.Name, Mode: 0o644, Size: size, ModTime: time.Now()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := io.CopyN(tw, r, size)
+	return err
+}
+
+func writeServiceArchiveManifest(tw *tar.Writer, manifest catchrpc.ServiceArchiveManifest) error {
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode archive manifest: %w", err)
+	}
+	hdr := &tar.Header{
+		Name:    catchrpc.ServiceArchiveManifestName,
+		Mode:    0o600,
+		Size:    int64(len(raw)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("write archive manifest: %w", err)
+	}
+	if _, err := tw.Write(raw); err != nil {
+		return fmt.Errorf("write archive manifest: %w", err)
+	}
+	return nil
+}
+
+func serviceArchiveRootFilter(root string) copyutil.TarFilter {
+	root = filepath.Clean(root)
+	return func(p string, _ os.DirEntry) (bool, error) {
+		if filepath.Dir(p) != root {
+			return true, nil
+		}
+		return !slices.Contains(serviceArchiveSkippedRootDirs, filepath.Base(p)), nil
+	}
+}
+
+func (e *ttyExecer) serviceImportCmdFunc() (retErr error) {
+	name := e.sn
+	if err := e.s.validateServiceImportTarget(name); err != nil {
+		return err
+	}
+	stage, err := os.MkdirTemp(e.s.cfg.ServicesRoot, "."+name+".import-")
+	if err != nil {
+		return fmt.Errorf("create import staging directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(stage) }()
+
+	if err := extractServiceArchive(e.payloadReader(), stage); err != nil {
+		return err
+	}
+	manifest, err := readServiceArchiveManifest(stage)
+	if err != nil {
+		return err
+	}
+	defer e.s.cleanupFailedServiceImport(name, &retErr)()
+	if err := e.s.placeServiceImportRoot(stage, name, manifest); err != nil {
+		return err
+	}
+	if err := e.s.restoreServiceArchiveImages(e.ctx, stage, manifest); err != nil {
+		return err
+	}
+	if err := e.installServiceImport(stage, name, manifest); err != nil {
+		return err
+	}
+	if manifest.QuotaBytes > 0 {
+		if err := e.s.setServiceQuota(e.ctx, name, manifest.QuotaBytes); err != nil {
+			_, _ = fmt.Fprintf(e.rw, "warning: service imported, but its %s quota was not applied: %v\n", formatBytesInt(manifest.QuotaBytes), err)
+		}
+	}
+	return nil
+}
+
+// cleanupFailedServiceImport returns a func that, when *errp is set and the
+// import did not get as far as registering the service, removes what the
+// import placed in the service root. validateServiceImportTarget guarantees
+// the root held nothing before.
+func (s *Server) cleanupFailedServiceImport(name string, errp *error) func() {
+	root := s.defaultServiceRootDir(name)
+	_, statErr := os.Stat(root)
+	existed := statErr == nil
+	return func() {
+		if *errp == nil {
+			return
+		}
+		if _, err := s.serviceView(name); !errors.Is(err, errServiceNotFound) {
+			return
+		}
+		if !existed {
+			_ = os.RemoveAll(root)
+			return
+		}
+		entries, _ := os.ReadDir(root)
+		for _, entry := range entries {
+			_ = os.RemoveAll(filepath.Join(root, entry.Name()))
+		}
+	}
+}
+
+func (s *Server) validateServiceImportTarget(name string) error {
+	if name == CatchService || name == SystemService {
+		return fmt.Errorf("cannot import into reserved service name %q", name)
+	}
+	if err := serviceid.Validate(name); err != nil {
+		return err
+	}
+	if _, err := s.serviceView(name); err == nil {
+		return fmt.Errorf("service %q already exists; import under another name with --as", name)
+	} else if !errors.Is(err, errServiceNotFound) {
+		return err
+	}
+	entries, err := os.ReadDir(s.defaultServiceRootDir(name))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("inspect service root for import: %w", err)
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("service root %s already exists; remove it or import under another name with --as", s.defaultServiceRootDir(name))
+	}
+	return nil
+}
+
+func extractServiceArchive(r io.Reader, dest string) error {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("open service archive: %w", err)
+	}
+	defer zr.Close()
+	first := true
+	err = copyutil.ExtractTarWithOptions(zr, dest, copyutil.ExtractOptions{
+		ValidateEntry: func(entry copyutil.TarEntry) error {
+			if first && entry.Name != catchrpc.ServiceArchiveManifestName {
+				return fmt.Errorf("service archive must start with %s", catchrpc.ServiceArchiveManifestName)
+			}
+			first = false
+			return nil
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("extract service archive: %w", err)
+	}
+	return nil
+}
+
+func readServiceArchiveManifest(stage string) (catchrpc.ServiceArchiveManifest, error) {
+	f, err := os.Open(filepath.Join(stage, catchrpc.ServiceArchiveManifestName))
+	if err != nil {
+		return catchrpc.ServiceArchiveManifest{}, fmt.Errorf("read service archive manifest: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	var manifest catchrpc.ServiceArchiveManifest
+	if err := json.NewDecoder(io.LimitReader(f, serviceArchiveManifestLimit)).Decode(&manifest); err != nil {
+		return catchrpc.ServiceArchiveManifest{}, fmt.Errorf("decode service archive manifest: %w", err)
+	}
+	if err := validateServiceArchiveManifest(manifest); err != nil {
+		return catchrpc.ServiceArchiveManifest{}, err
+	}
+	return manifest, nil
+}
+
+func validateServiceArchiveManifest(manifest catchrpc.ServiceArchiveManifest) error {
+	if manifest.Format != catchrpc.ServiceArchiveFormat {
+		return fmt.Errorf("unsupported service archive format %d", manifest.Format)
+	}
+	switch db.ServiceType(manifest.ServiceType) {
+	case db.ServiceTypeSystemd, db.ServiceTypeDockerCompose:
+	default:
+		return fmt.Errorf("unsupported service archive type %q", manifest.ServiceType)
+	}
+	dir, file := path.Split(manifest.Payload)
+	if path.Clean(dir) != serviceArchivePayloadDir || !slices.Contains(serviceArchivePayloadArtifacts, db.ArtifactName(file)) {
+		return fmt.Errorf("invalid service archive payload %q", manifest.Payload)
+	}
+	if manifest.ComposeOverrides < 0 || manifest.ComposeEnvFiles < 0 {
+		return fmt.Errorf("invalid service archive compose file count")
+	}
+	if manifest.QuotaBytes < 0 {
+		return fmt.Errorf("invalid service archive quota %d", manifest.QuotaBytes)
+	}
+	for _, image := range manifest.Images {
+		if _, err := parseRepo(image.Repo); err != nil || !strings.HasPrefix(image.Digest, "sha256:") || !remoteBuildDigestPattern.MatchString(strings.TrimPrefix(image.Digest, "sha256:")) {
+			return fmt.Errorf("invalid service archive image %q", image.Repo)
+		}
+	}
+	if manifest.ComposeOverrides != 0 || manifest.ComposeEnvFiles != 0 || len(manifest.Profiles) != 0 || len(manifest.Images) != 0 {
+		if file != string(db.ArtifactDockerComposeFile) {
+			return fmt.Errorf("service archive has compose project files without a compose payload")
+		}
+	}
+	return validateComposeProfiles(manifest.Profiles)
+}
+
+func (s *Server) placeServiceImportRoot(stage, name string, manifest catchrpc.ServiceArchiveManifest) error {
+	root := s.defaultServiceRootDir(name)
+	if manifest.Data {
+		if err := copyutil.MoveTree(filepath.Join(stage, serviceArchiveRootDir), root); err != nil {
+			return fmt.Errorf("restore service root: %w", err)
+		}
+	}
+	if manifest.ServiceType != string(db.ServiceTypeDockerCompose) || manifest.ServiceRoot == "" {
+		return nil
+	}
+	payload := filepath.Join(stage, filepath.FromSlash(manifest.Payload))
+	if filepath.Base(payload) != string(db.ArtifactDockerComposeFile) {
+		return nil
+	}
+	files := []string{payload}
+	for n := range manifest.ComposeOverrides {
+		files = append(files, filepath.Join(stage, filepath.FromSlash(serviceArchiveComposeOverrideName(n))))
+	}
+	for _, file := range files {
+		if err := rewriteComposeFileRoot(file, manifest.ServiceRoot, root); err != nil {
+			return fmt.Errorf("rewrite compose paths for import: %w", err)
+		}
+	}
+	return nil
+}
+
+func (e *ttyExecer) installServiceImport(stage, name string, manifest catchrpc.ServiceArchiveManifest) error {
+	if manifest.Env {
+		if err := e.stageServiceImportEnv(filepath.Join(stage, serviceArchiveEnvName)); err != nil {
+			return err
+		}
+	}
+	cfg := serviceImportInstallerCfg(e.fileInstaller(netFlags{}, manifest.PayloadArgs), name, manifest)
+	overrides, envFiles, err := serviceImportComposeProject(stage, manifest)
+	if err != nil {
+		return err
+	}
+	cfg.ComposeFiles, cfg.ComposeEnvFiles = overrides, envFiles
+	payload, err := os.Open(filepath.Join(stage, filepath.FromSlash(manifest.Payload)))
+	if err != nil {
+		return fmt.Errorf("open imported payload: %w", err)
+	}
+	defer func() { _ = payload.Close() }()
+	return e.runInstall("run", payload, cfg)
+}
+
+// serviceImportComposeProject encodes the archived compose project files the
+// way the yeet client sends them to run.
+func serviceImportComposeProject(stage string, manifest catchrpc.ServiceArchiveManifest) (overrides, envFiles []string, err error) {
+	read := func(name string) (string, error) {
+		raw, err := os.ReadFile(filepath.Join(stage, filepath.FromSlash(name)))
+		if err != nil {
+			return "", fmt.Errorf("read imported compose file: %w", err)
+		}
+		return composeProjectFlagPrefix + base64.StdEncoding.EncodeToString(raw), nil
+	}
+	for n := range manifest.ComposeOverrides {
+		value, err := read(serviceArchiveComposeOverrideName(n))
+		if err != nil {
+			return nil, nil, err
+		}
+		overrides = append(overrides, value)
+	}
+	for n := range manifest.ComposeEnvFiles {
+		value, err := read(serviceArchiveComposeEnvName(n))
+		if err != nil {
+			return nil, nil, err
+		}
+		envFiles = append(envFiles, value)
+	}
+	return overrides, envFiles, nil
+}
+
+func (e *ttyExecer) stageServiceImportEnv(envPath string) error {
+	env, err := os.Open(envPath)
+	if err != nil {
+		return fmt.Errorf("open imported env file: %w", err)
+	}
+	defer func() { _ = env.Close() }()
+	cfg := e.fileInstaller(netFlags{}, nil)
+	cfg.EnvFile = true
+	cfg.StageOnly = true
+	return e.runInstall("env", env, cfg)
+}
+
+func serviceImportInstallerCfg(cfg FileInstallerCfg, name string, manifest catchrpc.ServiceArchiveManifest) FileInstallerCfg {
+	cfg.PayloadName = path.Base(manifest.Payload)
+	cfg.Publish = slices.Clone(manifest.Publish)
+	cfg.Profiles = slices.Clone(manifest.Profiles)
+	if manifest.Network != nil {
+		cfg.Network = networkOptsFromDesired(serviceImportNetwork(*manifest.Network, name != manifest.Service), "")
+	}
+	if manifest.Schedule != "" {
+		cfg.Timer = &svc.TimerConfig{OnCalendar: manifest.Schedule, Persistent: true}
+	}
+	if manifest.Snapshots != nil {
+		cfg.SnapshotPolicyChange = true
+		cfg.SnapshotPolicy = snapshotPolicyFromRPC(manifest.Snapshots)
+	}
+	if db.ServiceType(manifest.ServiceType) != db.ServiceTypeSystemd {
+		return cfg
+	}
+	if manifest.Identity != nil {
+		cfg.RunAs = serviceImportRunAs(*manifest.Identity)
+		cfg.RunAsSet = true
+	}
+	if manifest.Sandbox != nil {
+		cfg.Sandbox = serviceImportSandbox(*manifest.Sandbox)
+	}
+	return cfg
+}
+
+// serviceImportNetwork drops the macvlan MAC for renamed imports so a copy
+// can run next to its source without a duplicate hardware address.
+func serviceImportNetwork(settings catchrpc.ServiceNetworkSettings, renamed bool) db.ServiceNetworkConfig {
+	cfg := db.ServiceNetworkConfig{
+		Modes:         slices.Clone(settings.Modes),
+		TSVersion:     settings.TSVersion,
+		TSExitNode:    settings.TSExitNode,
+		TSTags:        slices.Clone(settings.TSTags),
+		MacvlanParent: settings.MacvlanParent,
+		MacvlanVLAN:   settings.MacvlanVLAN,
+		MacvlanMAC:    settings.MacvlanMAC,
+	}
+	if renamed {
+		cfg.MacvlanMAC = ""
+	}
+	return cfg
+}
+
+func serviceImportRunAs(identity catchrpc.ServiceIdentity) string {
+	if identity.RequestedGroup == "" {
+		return identity.RequestedUser
+	}
+	return identity.RequestedUser + ":" + identity.RequestedGroup
+}
+
+func serviceImportSandbox(sandbox catchrpc.ServiceSandbox) cli.SandboxOptions {
+	return cli.SandboxOptions{
+		State:       sandbox.State,
+		StateSet:    true,
+		ReadOnly:    serviceImportSandboxExposures(sandbox.ReadOnly),
+		ReadOnlySet: len(sandbox.ReadOnly) > 0,
+		Writable:    serviceImportSandboxExposures(sandbox.Writable),
+		WritableSet: len(sandbox.Writable) > 0,
+	}
+}
+
+func serviceImportSandboxExposures(exposures []catchrpc.ServiceSandboxExposure) []cli.SandboxExposure {
+	if len(exposures) == 0 {
+		return nil
+	}
+	out := make([]cli.SandboxExposure, len(exposures))
+	for i, exposure := range exposures {
+		out[i] = cli.SandboxExposure{Source: exposure.Source, Destination: strings.TrimSpace(exposure.Destination)}
+	}
+	return out
+}
+
+func snapshotPolicyFromRPC(policy *catchrpc.SnapshotPolicy) *db.SnapshotPolicy {
+	if policy == nil {
+		return nil
+	}
+	out := &db.SnapshotPolicy{
+		MaxAge: policy.MaxAge,
+		Events: slices.Clone(policy.Events),
+	}
+	if policy.Enabled != nil {
+		out.Enabled = boolPointer(*policy.Enabled)
+	}
+	if policy.KeepLast != nil {
+		out.KeepLast = intPointer(*policy.KeepLast)
+	}
+	if policy.Required != nil {
+		out.Required = boolPointer(*policy.Required)
+	}
+	return out
+}
@@ -0,0 +1,256 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/registry"
+	"github.com/yeetrun/yeet/pkg/svc"
+	"tailscale.com/util/mak"
+)
+
+// Internal registry images a compose service names travel with its export:
+// every manifest, config and layer blob is stored under images/blobs, and
+// the manifest lists the images to register again on import. Images from
+// other registries are pulled again by the target.
+const serviceArchiveImageBlobDir = "images/blobs/sha256"
+
+func serviceArchiveImageBlobName(dgst string) string {
+	return path.Join(serviceArchiveImageBlobDir, strings.TrimPrefix(dgst, "sha256:"))
+}
+
+// addServiceArchiveImages adds the internal images the current generation's
+// compose files name. Export fails when one is not in the registry, since
+// the imported service could not start without it.
+func (s *Server) addServiceArchiveImages(ctx context.Context, sv db.ServiceView, manifest *catchrpc.ServiceArchiveManifest, plan *serviceArchivePlan) error {
+	if sv.ServiceType() != db.ServiceTypeDockerCompose {
+		return nil
+	}
+	service := sv.AsStruct()
+	var images []catchrpc.ServiceArchiveImage
+	seen := map[string]bool{}
+	for _, file := range service.Artifacts.ComposeFiles(db.Gen(service.Generation)) {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("read compose file: %w", err)
+		}
+		for _, m := range internalImageRefRE.FindAllStringSubmatch(string(raw), -1) {
+			if seen[m[0]] {
+				continue
+			}
+			seen[m[0]] = true
+			image, err := s.serviceArchiveImage(ctx, m[0], strings.TrimSuffix(m[1], "/"), m[2], m[3])
+			if err != nil {
+				return err
+			}
+			images = append(images, image)
+		}
+	}
+	if len(images) == 0 {
+		return nil
+	}
+	storage := s.registry.storage
+	blobs := map[string]bool{}
+	for _, image := range images {
+		desc := ocispec.Descriptor{MediaType: image.MediaType, Digest: digest.Digest(image.Digest)}
+		if err := collectServiceArchiveImageBlobs(ctx, storage, image.Repo, desc, blobs, plan); err != nil {
+			return fmt.Errorf("export %s/%s: %w", svc.InternalRegistryHost, image.Repo, err)
+		}
+	}
+	manifest.Images = images
+	return nil
+}
+
+// serviceArchiveImage resolves one compose image reference to the manifest
+// the registry holds for it.
+func (s *Server) serviceArchiveImage(ctx context.Context, ref, repo, tag, dgst string) (catchrpc.ServiceArchiveImage, error) {
+	if s.registry == nil || s.registry.storage == nil {
+		return catchrpc.ServiceArchiveImage{}, fmt.Errorf("compose file names %s, but the internal registry is not running", ref)
+	}
+	storage := s.registry.storage
+	reference := dgst
+	if reference == "" {
+		if tag == "" {
+			tag = "latest"
+		}
+		reference = tag
+	}
+	mf, err := storage.GetManifest(ctx, repo, reference)
+	if err != nil {
+		return catchrpc.ServiceArchiveImage{}, fmt.Errorf("compose file names %s, which is not in the internal registry; push it again before exporting: %w", ref, err)
+	}
+	_ = mf.Data.Close()
+	image := catchrpc.ServiceArchiveImage{Repo: repo, Digest: mf.Digest, MediaType: mf.MediaType}
+	if dgst == "" {
+		image.Tag = tag
+	}
+	return image, nil
+}
+
+// collectServiceArchiveImageBlobs adds the manifest desc, its children and
+// the blobs they reference to plan, each once.
+func collectServiceArchiveImageBlobs(ctx context.Context, storage *internalRegistryStorage, repo string, desc ocispec.Descriptor, blobs map[string]bool, plan *serviceArchivePlan) error {
+	dgst := desc.Digest.String()
+	if blobs[dgst] {
+		return nil
+	}
+	mf, err := storage.base.GetManifest(ctx, storage.storageRepo(repo), dgst)
+	if err != nil {
+		return fmt.Errorf("read manifest %s: %w", dgst, err)
+	}
+	data, err := io.ReadAll(io.LimitReader(mf.Data, remoteBuildImageManifestMaxBytes+1))
+	_ = mf.Data.Close()
+	if err != nil {
+		return fmt.Errorf("read manifest %s: %w", dgst, err)
+	}
+	if len(data) > remoteBuildImageManifestMaxBytes {
+		return fmt.Errorf("manifest %s is larger than %d bytes", dgst, remoteBuildImageManifestMaxBytes)
+	}
+	blobs[dgst] = true
+	plan.Files = append(plan.Files, serviceArchiveFile{Name: serviceArchiveImageBlobName(dgst), Data: data})
+	var parsed struct {
+		Manifests []ocispec.Descriptor `json:"manifests"`
+		Config    *ocispec.Descriptor  `json:"config"`
+		Layers    []ocispec.Descriptor `json:"layers"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return fmt.Errorf("decode manifest %s: %w", dgst, err)
+	}
+	for _, child := range parsed.Manifests {
+		if err := collectServiceArchiveImageBlobs(ctx, storage, repo, child, blobs, plan); err != nil {
+			return err
+		}
+	}
+	refs := parsed.Layers
+	if parsed.Config != nil {
+		refs = append(refs, *parsed.Config)
+	}
+	for _, blob := range refs {
+		dgst := blob.Digest.String()
+		if blobs[dgst] {
+			continue
+		}
+		size, err := storage.base.BlobSize(ctx, dgst)
+		if err != nil {
+			return fmt.Errorf("blob %s is missing from the internal registry: %w", dgst, err)
+		}
+		blobs[dgst] = true
+		plan.Files = append(plan.Files, serviceArchiveFile{
+			Name: serviceArchiveImageBlobName(dgst),
+			Size: size,
+			Open: func() (io.ReadCloser, error) { return storage.base.GetBlob(ctx, dgst) },
+		})
+	}
+	return nil
+}
+
+// restoreServiceArchiveImages stores the archived blobs in the internal
+// registry and registers each image under its repo, like a push.
+func (s *Server) restoreServiceArchiveImages(ctx context.Context, stage string, manifest catchrpc.ServiceArchiveManifest) error {
+	if len(manifest.Images) == 0 {
+		return nil
+	}
+	if s.registry == nil || s.registry.storage == nil {
+		return fmt.Errorf("service archive holds internal registry images, but the internal registry is not running")
+	}
+	storage := s.registry.storage
+	dir := filepath.Join(stage, filepath.FromSlash(serviceArchiveImageBlobDir))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("read archived images: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !remoteBuildDigestPattern.MatchString(entry.Name()) {
+			return fmt.Errorf("unexpected archived image blob %q", entry.Name())
+		}
+		if err := putServiceArchiveBlob(ctx, storage.base, "sha256:"+entry.Name(), filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	for _, image := range manifest.Images {
+		if err := storage.putImportedImage(ctx, image); err != nil {
+			return fmt.Errorf("register %s/%s: %w", svc.InternalRegistryHost, image.Repo, err)
+		}
+	}
+	return nil
+}
+
+func putServiceArchiveBlob(ctx context.Context, storage registry.Storage, dgst, src string) error {
+	if storage.BlobExists(ctx, dgst) {
+		return nil
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	session, err := storage.NewUpload(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := storage.CopyChunk(ctx, session.UUID, f); err != nil {
+		_ = storage.AbortUpload(ctx, session.UUID)
+		return fmt.Errorf("store blob %s: %w", dgst, err)
+	}
+	if _, err := storage.CompleteUpload(ctx, session.UUID, dgst); err != nil {
+		_ = storage.AbortUpload(ctx, session.UUID)
+		return fmt.Errorf("store blob %s: %w", dgst, err)
+	}
+	return nil
+}
+
+// putImportedImage stores the manifest tree of an imported image, whose
+// blobs are already in storage, and records its tag like a push does.
+func (s *internalRegistryStorage) putImportedImage(ctx context.Context, image catchrpc.ServiceArchiveImage) error {
+	s.gcMu.RLock()
+	defer s.gcMu.RUnlock()
+	if _, err := parseRepo(image.Repo); err != nil {
+		return err
+	}
+	desc := ocispec.Descriptor{MediaType: image.MediaType, Digest: digest.Digest(image.Digest)}
+	if err := s.putManifestTree(ctx, image.Repo, image.Digest, desc); err != nil {
+		return err
+	}
+	if image.Tag == "" {
+		return nil
+	}
+	if err := s.putManifestTree(ctx, image.Repo, image.Tag, desc); err != nil {
+		return err
+	}
+	if s.s.containerRuntime().IsPodman() {
+		if err := loadPodmanImageFn(ctx, s.s.cfg.InternalRegistryAddr, image.Repo, image.Tag, image.Digest); err != nil {
+			log.Printf("registry podman load failed for %q:%q: %v", image.Repo, image.Tag, err)
+			return err
+		}
+	}
+	_, err := s.s.cfg.DB.MutateData(func(d *db.Data) error {
+		ir, ok := d.Images[db.ImageRepoName(image.Repo)]
+		if !ok {
+			ir = &db.ImageRepo{Refs: make(map[db.ImageRef]db.ImageManifest, 1)}
+			mak.Set(&d.Images, db.ImageRepoName(image.Repo), ir)
+		}
+		ir.Refs[db.ImageRef(image.Tag)] = db.ImageManifest{
+			ContentType: image.MediaType,
+			BlobHash:    image.Digest,
+			PushedAt:    time.Now().UTC().Format(time.RFC3339),
+		}
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,501 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/opencontainers/go-digest"
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+	"github.com/yeetrun/yeet/pkg/cli"
+	"github.com/yeetrun/yeet/pkg/copyutil"
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/svc"
+)
+
+func addServiceArchiveTestService(t *testing.T, s *Server, service *db.Service) {
+	t.Helper()
+	if _, _, err := s.cfg.DB.MutateService(service.Name, func(_ *db.Data, sv *db.Service) error {
+		*sv = *service
+		return nil
+	}); err != nil {
+		t.Fatalf("MutateService: %v", err)
+	}
+}
+
+func writeServiceArchiveTestFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+}
+
+func newServiceArchiveComposeService(t *testing.T, s *Server) *db.Service {
+	t.Helper()
+	root := s.defaultServiceRootDir("svc-a")
+	composePath := filepath.Join(root, "bin", "compose.yml-3")
+	envPath := filepath.Join(root, "bin", "env-3")
+	writeServiceArchiveTestFile(t, composePath, "services:\n  app:\n    image: nginx\n")
+	writeServiceArchiveTestFile(t, envPath, "TOKEN=abc\n")
+	writeServiceArchiveTestFile(t, filepath.Join(root, "data", "state.db"), "state")
+	writeServiceArchiveTestFile(t, filepath.Join(root, "bin", "old"), "skip")
+	keep := 4
+	service := &db.Service{
+		Name:        "svc-a",
+		ServiceType: db.ServiceTypeDockerCompose,
+		Generation:  3,
+		Publish:     []string{"8080:80"},
+		Artifacts: db.ArtifactStore{
+			db.ArtifactDockerComposeFile: {Refs: map[db.ArtifactRef]string{db.Gen(3): composePath}},
+			db.ArtifactEnvFile:           {Refs: map[db.ArtifactRef]string{db.Gen(3): envPath}},
+		},
+		SnapshotPolicy: &db.SnapshotPolicy{KeepLast: &keep},
+		QuotaBytes:     1 << 30,
+	}
+	addServiceArchiveTestService(t, s, service)
+	return service
+}
+
+func TestServiceArchivePlanForComposeService(t *testing.T) {
+	s := newTestServer(t)
+	service := newServiceArchiveComposeService(t, s)
+	sv, err := s.serviceView(service.Name)
+	if err != nil {
+		t.Fatalf("serviceView: %v", err)
+	}
+
+	plan, err := s.serviceArchivePlan(sv, true)
+	if err != nil {
+		t.Fatalf("serviceArchivePlan: %v", err)
+	}
+	m := plan.Manifest
+	if m.Format != catchrpc.ServiceArchiveFormat || m.Service != "svc-a" || m.Generation != 3 {
+		t.Fatalf("manifest identity = %+v", m)
+	}
+	if m.ServiceType != string(db.ServiceTypeDockerCompose) || m.Payload != "payload/compose.yml" {
+		t.Fatalf("manifest payload = %q/%q", m.ServiceType, m.Payload)
+	}
+	if !m.Env || !m.Data || !reflect.DeepEqual(m.Publish, []string{"8080:80"}) {
+		t.Fatalf("manifest env/data/publish = %v/%v/%v", m.Env, m.Data, m.Publish)
+	}
+	if m.Snapshots == nil || m.Snapshots.KeepLast == nil || *m.Snapshots.KeepLast != 4 {
+		t.Fatalf("manifest snapshots = %+v", m.Snapshots)
+	}
+	if m.QuotaBytes != 1<<30 {
+		t.Fatalf("manifest quota = %d, want %d", m.QuotaBytes, 1<<30)
+	}
+	if plan.Root != s.defaultServiceRootDir("svc-a") || len(plan.Files) != 2 {
+		t.Fatalf("plan root/files = %q/%+v", plan.Root, plan.Files)
+	}
+}
+
+func TestServiceArchivePlanRejectsVMAndReservedServices(t *testing.T) {
+	s := newTestServer(t)
+	addServiceArchiveTestService(t, s, &db.Service{Name: "vm-a", ServiceType: db.ServiceTypeVM, Generation: 1})
+	addServiceArchiveTestService(t, s, &db.Service{Name: CatchService, ServiceType: db.ServiceTypeSystemd, Generation: 1})
+	for _, name := range []string{"vm-a", CatchService} {
+		sv, err := s.serviceView(name)
+		if err != nil {
+			t.Fatalf("serviceView(%s): %v", name, err)
+		}
+		if _, err := s.serviceArchivePlan(sv, false); err == nil {
+			t.Fatalf("serviceArchivePlan(%s) succeeded, want error", name)
+		}
+	}
+}
+
+func TestServiceArchiveRoundTrip(t *testing.T) {
+	s := newTestServer(t)
+	service := newServiceArchiveComposeService(t, s)
+	sv, err := s.serviceView(service.Name)
+	if err != nil {
+		t.Fatalf("serviceView: %v", err)
+	}
+	plan, err := s.serviceArchivePlan(sv, true)
+	if err != nil {
+		t.Fatalf("serviceArchivePlan: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := writeServiceArchive(&buf, plan); err != nil {
+		t.Fatalf("writeServiceArchive: %v", err)
+	}
+
+	stage := t.TempDir()
+	if err := extractServiceArchive(&buf, stage); err != nil {
+		t.Fatalf("extractServiceArchive: %v", err)
+	}
+	manifest, err := readServiceArchiveManifest(stage)
+	if err != nil {
+		t.Fatalf("readServiceArchiveManifest: %v", err)
+	}
+	if !reflect.DeepEqual(manifest, plan.Manifest) {
+		t.Fatalf("manifest = %+v, want %+v", manifest, plan.Manifest)
+	}
+	for name, want := range map[string]string{
+		"payload/compose.yml": "services:\n  app:\n    image: nginx\n",
+		"env":                 "TOKEN=abc\n",
+		"root/data/state.db":  "state",
+	} {
+		got, err := os.ReadFile(filepath.Join(stage, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatalf("ReadFile(%s): %v", name, err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(stage, "root", "bin")); !os.IsNotExist(err) {
+		t.Fatalf("root/bin should be excluded from archive, stat err = %v", err)
+	}
+}
+
+func TestServiceArchiveCarriesComposeProject(t *testing.T) {
+	s := newTestServer(t)
+	service := newServiceArchiveComposeService(t, s)
+	bin := filepath.Join(s.defaultServiceRootDir(service.Name), "bin")
+	override := filepath.Join(bin, "docker-compose.override-0.3.yml")
+	composeEnv := filepath.Join(bin, "compose-0.3.env")
+	profiles := filepath.Join(bin, "compose-profiles.3")
+	writeServiceArchiveTestFile(t, override, "services:\n  app:\n    image: nginx:1.27\n")
+	writeServiceArchiveTestFile(t, composeEnv, "TAG=1\n")
+	writeServiceArchiveTestFile(t, profiles, "debug\n")
+	service.Artifacts[db.ArtifactDockerComposeOverride(0)] = &db.Artifact{Refs: map[db.ArtifactRef]string{db.Gen(3): override}}
+	service.Artifacts[db.ArtifactDockerComposeEnv(0)] = &db.Artifact{Refs: map[db.ArtifactRef]string{db.Gen(3): composeEnv}}
+	service.Artifacts[db.ArtifactDockerComposeProfiles] = &db.Artifact{Refs: map[db.ArtifactRef]string{db.Gen(3): profiles}}
+	addServiceArchiveTestService(t, s, service)
+	sv, err := s.serviceView(service.Name)
+	if err != nil {
+		t.Fatalf("serviceView: %v", err)
+	}
+	plan, err := s.serviceArchivePlan(sv, false)
+	if err != nil {
+		t.Fatalf("serviceArchivePlan: %v", err)
+	}
+	if m := plan.Manifest; m.ComposeOverrides != 1 || m.ComposeEnvFiles != 1 || !reflect.DeepEqual(m.Profiles, []string{"debug"}) {
+		t.Fatalf("manifest compose project = %d/%d/%q", m.ComposeOverrides, m.ComposeEnvFiles, m.Profiles)
+	}
+	var buf bytes.Buffer
+	if err := writeServiceArchive(&buf, plan); err != nil {
+		t.Fatalf("writeServiceArchive: %v", err)
+	}
+	stage := t.TempDir()
+	if err := extractServiceArchive(&buf, stage); err != nil {
+		t.Fatalf("extractServiceArchive: %v", err)
+	}
+	overrides, envFiles, err := serviceImportComposeProject(stage, plan.Manifest)
+	if err != nil {
+		t.Fatalf("serviceImportComposeProject: %v", err)
+	}
+	if len(overrides) != 1 || len(envFiles) != 1 {
+		t.Fatalf("imported compose project = %q/%q", overrides, envFiles)
+	}
+	if raw, err := decodeComposeProjectFlag("--compose-file", overrides[0]); err != nil || !strings.Contains(string(raw), "nginx:1.27") {
+		t.Fatalf("imported override = %q, %v", raw, err)
+	}
+	if raw, err := decodeComposeProjectFlag("--compose-env-file", envFiles[0]); err != nil || string(raw) != "TAG=1\n" {
+		t.Fatalf("imported compose env = %q, %v", raw, err)
+	}
+}
+
+func putServiceArchiveTestBlob(t *testing.T, s *Server, data string) string {
+	t.Helper()
+	dgst := digest.FromString(data).String()
+	src := filepath.Join(t.TempDir(), "blob")
+	writeServiceArchiveTestFile(t, src, data)
+	if err := putServiceArchiveBlob(context.Background(), s.registry.storage.base, dgst, src); err != nil {
+		t.Fatalf("putServiceArchiveBlob: %v", err)
+	}
+	return dgst
+}
+
+func TestServiceArchiveCarriesInternalImages(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.registry.storage.newInstaller = func(*Server, FileInstallerCfg) (registryInstaller, error) {
+		return &stubInstaller{}, nil
+	}
+	config := putServiceArchiveTestBlob(t, s, `{"architecture":"amd64"}`)
+	layer := putServiceArchiveTestBlob(t, s, "layer")
+	const mediaType = "application/vnd.oci.image.manifest.v1+json"
+	raw := `{"schemaVersion":2,"config":{"digest":"` + config + `","size":24},"layers":[{"digest":"` + layer + `","size":5}]}`
+	imageDigest, err := s.registry.storage.PutManifest(ctx, "svc-a/app", "run", []byte(raw), mediaType)
+	if err != nil {
+		t.Fatalf("PutManifest: %v", err)
+	}
+	service := newServiceArchiveComposeService(t, s)
+	writeServiceArchiveTestFile(t, service.Artifacts[db.ArtifactDockerComposeFile].Refs[db.Gen(3)], "services:\n  app:\n    image: catchit.dev/svc-a/app:run\n")
+	sv, err := s.serviceView(service.Name)
+	if err != nil {
+		t.Fatalf("serviceView: %v", err)
+	}
+	plan, err := s.serviceArchivePlan(sv, false)
+	if err != nil {
+		t.Fatalf("serviceArchivePlan: %v", err)
+	}
+	want := []catchrpc.ServiceArchiveImage{{Repo: "svc-a/app", Tag: "run", Digest: imageDigest, MediaType: mediaType}}
+	if !reflect.DeepEqual(plan.Manifest.Images, want) {
+		t.Fatalf("manifest images = %+v, want %+v", plan.Manifest.Images, want)
+	}
+	var buf bytes.Buffer
+	if err := writeServiceArchive(&buf, plan); err != nil {
+		t.Fatalf("writeServiceArchive: %v", err)
+	}
+	stage := t.TempDir()
+	if err := extractServiceArchive(&buf, stage); err != nil {
+		t.Fatalf("extractServiceArchive: %v", err)
+	}
+
+	target := newTestServer(t)
+	target.registry.storage.newInstaller = s.registry.storage.newInstaller
+	if err := target.restoreServiceArchiveImages(ctx, stage, plan.Manifest); err != nil {
+		t.Fatalf("restoreServiceArchiveImages: %v", err)
+	}
+	for _, dgst := range []string{config, layer, imageDigest} {
+		if !target.registry.storage.base.BlobExists(ctx, dgst) {
+			t.Fatalf("blob %s was not restored", dgst)
+		}
+	}
+	mf, err := target.registry.storage.GetManifest(ctx, "svc-a/app", "run")
+	if err != nil {
+		t.Fatalf("GetManifest: %v", err)
+	}
+	_ = mf.Data.Close()
+	if mf.Digest != imageDigest {
+		t.Fatalf("restored digest = %s, want %s", mf.Digest, imageDigest)
+	}
+	dv, err := target.getDB()
+	if err != nil {
+		t.Fatalf("getDB: %v", err)
+	}
+	if ref, ok := dv.AsStruct().Images["svc-a/app"].Refs["run"]; !ok || ref.BlobHash != imageDigest {
+		t.Fatalf("restored image record = %+v, %v", ref, ok)
+	}
+}
+
+func TestServiceArchiveRejectsMissingInternalImage(t *testing.T) {
+	s := newTestServer(t)
+	service := newServiceArchiveComposeService(t, s)
+	writeServiceArchiveTestFile(t, service.Artifacts[db.ArtifactDockerComposeFile].Refs[db.Gen(3)], "services:\n  app:\n    image: catchit.dev/svc-a/app:gone\n")
+	sv, err := s.serviceView(service.Name)
+	if err != nil {
+		t.Fatalf("serviceView: %v", err)
+	}
+	if _, err := s.serviceArchivePlan(sv, false); err == nil || !strings.Contains(err.Error(), "not in the internal registry") {
+		t.Fatalf("serviceArchivePlan err = %v, want missing image error", err)
+	}
+}
+
+func TestCleanupFailedServiceImport(t *testing.T) {
+	s := newTestServer(t)
+	root := s.defaultServiceRootDir("svc-b")
+
+	failed := errors.New("install failed")
+	cleanup := s.cleanupFailedServiceImport("svc-b", &failed)
+	writeServiceArchiveTestFile(t, filepath.Join(root, "data", "state.db"), "state")
+	cleanup()
+	if _, err := os.Stat(root); !os.IsNotExist(err) {
+		t.Fatalf("service root after failed import: %v, want removed", err)
+	}
+
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cleanup = s.cleanupFailedServiceImport("svc-b", &failed)
+	writeServiceArchiveTestFile(t, filepath.Join(root, "data", "state.db"), "state")
+	cleanup()
+	if entries, err := os.ReadDir(root); err != nil || len(entries) != 0 {
+		t.Fatalf("pre-existing service root after failed import = %v, %v; want empty", entries, err)
+	}
+
+	newServiceArchiveComposeService(t, s)
+	registered := s.defaultServiceRootDir("svc-a")
+	s.cleanupFailedServiceImport("svc-a", &failed)()
+	if _, err := os.Stat(filepath.Join(registered, "data", "state.db")); err != nil {
+		t.Fatalf("registered service root was cleaned up: %v", err)
+	}
+}
+
+func TestExtractServiceArchiveRequiresManifestFirst(t *testing.T) {
+	src := t.TempDir()
+	payload := filepath.Join(src, "compose.yml")
+	writeServiceArchiveTestFile(t, payload, "services: {}\n")
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("zstd.NewWriter: %v", err)
+	}
+	tw := tar.NewWriter(zw)
+	if err := copyutil.AppendFile(tw, payload, "payload/compose.yml"); err != nil {
+		t.Fatalf("AppendFile: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tar Close: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zstd Close: %v", err)
+	}
+
+	err = extractServiceArchive(&buf, t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "must start with manifest.json") {
+		t.Fatalf("extractServiceArchive error = %v, want manifest-first error", err)
+	}
+}
+
+func TestValidateServiceArchiveManifest(t *testing.T) {
+	valid := catchrpc.ServiceArchiveManifest{
+		Format:      catchrpc.ServiceArchiveFormat,
+		Service:     "svc-a",
+		ServiceType: string(db.ServiceTypeSystemd),
+		Payload:     "payload/binary",
+	}
+	if err := validateServiceArchiveManifest(valid); err != nil {
+		t.Fatalf("validateServiceArchiveManifest(valid): %v", err)
+	}
+	tests := map[string]func(*catchrpc.ServiceArchiveManifest){
+		"format":        func(m *catchrpc.ServiceArchiveManifest) { m.Format = 99 },
+		"vm":            func(m *catchrpc.ServiceArchiveManifest) { m.ServiceType = string(db.ServiceTypeVM) },
+		"payload dir":   func(m *catchrpc.ServiceArchiveManifest) { m.Payload = "root/binary" },
+		"payload name":  func(m *catchrpc.ServiceArchiveManifest) { m.Payload = "payload/env" },
+		"payload depth": func(m *catchrpc.ServiceArchiveManifest) { m.Payload = "payload/x/binary" },
+		"quota":         func(m *catchrpc.ServiceArchiveManifest) { m.QuotaBytes = -1 },
+		"image digest": func(m *catchrpc.ServiceArchiveManifest) {
+			m.Payload = "payload/compose.yml"
+			m.Images = []catchrpc.ServiceArchiveImage{{Repo: "svc-a/app", Digest: "sha256:../x"}}
+		},
+	}
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := valid
+			mutate(&m)
+			if err := validateServiceArchiveManifest(m); err == nil {
+				t.Fatalf("validateServiceArchiveManifest succeeded for %+v", m)
+			}
+		})
+	}
+}
+
+func TestValidateServiceImportTarget(t *testing.T) {
+	s := newTestServer(t)
+	newServiceArchiveComposeService(t, s)
+	writeServiceArchiveTestFile(t, filepath.Join(s.defaultServiceRootDir("leftover"), "data", "x"), "x")
+
+	if err := s.validateServiceImportTarget("svc-b"); err != nil {
+		t.Fatalf("validateServiceImportTarget(svc-b): %v", err)
+	}
+	for _, name := range []string{"svc-a", "leftover", CatchService, "Bad Name"} {
+		if err := s.validateServiceImportTarget(name); err == nil {
+			t.Fatalf("validateServiceImportTarget(%q) succeeded, want error", name)
+		}
+	}
+}
+
+func TestServiceImportInstallerCfg(t *testing.T) {
+	enabled := true
+	manifest := catchrpc.ServiceArchiveManifest{
+		Service:     "svc-a",
+		ServiceType: string(db.ServiceTypeSystemd),
+		Payload:     "payload/binary",
+		Schedule:    "*-*-* 02:30:00",
+		Publish:     []string{"8080:80"},
+		Network: &catchrpc.ServiceNetworkSettings{
+			Modes:         []string{"macvlan"},
+			MacvlanParent: "eth0",
+			MacvlanMAC:    "02:00:00:00:00:01",
+		},
+		Identity:  &catchrpc.ServiceIdentity{RequestedUser: "app", RequestedGroup: "staff"},
+		Sandbox:   &catchrpc.ServiceSandbox{State: "on", Writable: []catchrpc.ServiceSandboxExposure{{Source: "/srv/data"}}},
+		Snapshots: &catchrpc.SnapshotPolicy{Enabled: &enabled},
+	}
+
+	cfg := serviceImportInstallerCfg(FileInstallerCfg{}, "svc-b", manifest)
+	if cfg.PayloadName != "binary" || !reflect.DeepEqual(cfg.Publish, manifest.Publish) {
+		t.Fatalf("payload/publish = %q/%v", cfg.PayloadName, cfg.Publish)
+	}
+	if cfg.Timer == nil || cfg.Timer.OnCalendar != manifest.Schedule {
+		t.Fatalf("timer = %+v", cfg.Timer)
+	}
+	if cfg.RunAs != "app:staff" || !cfg.RunAsSet {
+		t.Fatalf("run as = %q/%v", cfg.RunAs, cfg.RunAsSet)
+	}
+	wantSandbox := cli.SandboxOptions{
+		State:       "on",
+		StateSet:    true,
+		Writable:    []cli.SandboxExposure{{Source: "/srv/data"}},
+		WritableSet: true,
+	}
+	if !reflect.DeepEqual(cfg.Sandbox, wantSandbox) {
+		t.Fatalf("sandbox = %+v, want %+v", cfg.Sandbox, wantSandbox)
+	}
+	if !cfg.SnapshotPolicyChange || cfg.SnapshotPolicy == nil || cfg.SnapshotPolicy.Enabled == nil || !*cfg.SnapshotPolicy.Enabled {
+		t.Fatalf("snapshot policy = %v/%+v", cfg.SnapshotPolicyChange, cfg.SnapshotPolicy)
+	}
+	if cfg.Network.Macvlan.Mac != "" {
+		t.Fatalf("renamed import kept macvlan MAC %q", cfg.Network.Macvlan.Mac)
+	}
+
+	same := serviceImportInstallerCfg(FileInstallerCfg{}, "svc-a", manifest)
+	if same.Network.Macvlan.Mac != manifest.Network.MacvlanMAC {
+		t.Fatalf("import under original name dropped macvlan MAC: %q", same.Network.Macvlan.Mac)
+	}
+}
+
+type recordingExportRunner struct {
+	calls []string
+	start error
+}
+
+func (r *recordingExportRunner) SetNewCmd(func(string, ...string) *exec.Cmd) {}
+func (r *recordingExportRunner) Start() error                                { r.calls = append(r.calls, "start"); return r.start }
+func (r *recordingExportRunner) Stop() error                                 { r.calls = append(r.calls, "stop"); return nil }
+func (r *recordingExportRunner) Restart() error                              { return nil }
+func (r *recordingExportRunner) Logs(*svc.LogOptions) error                  { return nil }
+func (r *recordingExportRunner) Remove() error                               { return nil }
+
+func TestWithServiceStoppedForExport(t *testing.T) {
+	oldRunning, oldRunner := isServiceRunningForExport, serviceRunnerForExport
+	t.Cleanup(func() { isServiceRunningForExport, serviceRunnerForExport = oldRunning, oldRunner })
+
+	runner := &recordingExportRunner{start: errors.New("boom")}
+	running := true
+	isServiceRunningForExport = func(*Server, string) (bool, error) { return running, nil }
+	serviceRunnerForExport = func(*Server, *db.Service) (ServiceRunner, error) { return runner, nil }
+
+	s := newTestServer(t)
+	err := s.withServiceStoppedForExport(&db.Service{Name: "svc-a"}, func() error {
+		runner.calls = append(runner.calls, "archive")
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "restart service") {
+		t.Fatalf("withServiceStoppedForExport error = %v, want restart error", err)
+	}
+	if want := []string{"stop", "archive", "start"}; !reflect.DeepEqual(runner.calls, want) {
+		t.Fatalf("calls = %v, want %v", runner.calls, want)
+	}
+
+	runner.calls = nil
+	running = false
+	if err := s.withServiceStoppedForExport(&db.Service{Name: "svc-a"}, func() error {
+		runner.calls = append(runner.calls, "archive")
+		return nil
+	}); err != nil {
+		t.Fatalf("withServiceStoppedForExport stopped service: %v", err)
+	}
+	if want := []string{"archive"}; !reflect.DeepEqual(runner.calls, want) {
+		t.Fatalf("calls = %v, want %v", runner.calls, want)
+	}
+}
@@ -99,6 +99,8 @@ func (s *Server) serviceInfoWithContext(ctx context.Context, sn string) (catchrp
 		return resp, err
 	}
 	info.Snapshots = &snapshots
+	info.Storage = s.serviceStorage(ctx, sv, true)
+	info.AutoUpdate = serviceAutoUpdateInfo(sv)
 
 	resp.Found = true
 	resp.Info = info
@@ -139,10 +141,43 @@ func (s *Server) populateVMRuntimeInfo(ctx context.Context, sv db.ServiceView, e
 	if err != nil {
 		return err
 	}
+	if vmInfo != nil {
+		vmInfo.UserData = vmUserDataStatus(ctx, sv.VM())
+		vmInfo.Metrics = s.runningVMMetrics(ctx, sv)
+	}
 	info.VM = vmInfo
 	return nil
 }
 
+// runningVMMetrics samples a VM's resource usage; stopped VMs have none.
+func (s *Server) runningVMMetrics(ctx context.Context, sv db.ServiceView) *catchrpc.ServiceVMMetrics {
+	if running, err := s.isVMServiceRunning(sv.Name()); err != nil || !running {
+		return nil
+	}
+	target := vmMetricsTargetForView(sv.Name(), sv.VM())
+	return sampleVMMetrics(ctx, []vmMetricsTarget{target})[target.Service]
+}
+
+// vmUserDataStatus asks the guest agent how first-boot user-data went. VMs
+// provisioned without user-data report nothing.
+func vmUserDataStatus(ctx context.Context, vm db.VMConfigView) string {
+	if !vm.Valid() || vm.UserDataSHA256() == "" {
+		return ""
+	}
+	socketPath := strings.TrimSpace(vm.Sockets().VsockSocketPath)
+	if socketPath == "" {
+		return "unknown"
+	}
+	state, err := queryVMGuestReadyFn(ctx, socketPath)
+	if err != nil {
+		return "unknown (VM agent unavailable)"
+	}
+	if state.UserData == "" {
+		return "pending"
+	}
+	return state.UserData
+}
+
 func serviceIdentityInfo(sv db.ServiceView) *catchrpc.ServiceIdentity {
 	if !sv.Valid() || sv.ServiceType() != db.ServiceTypeSystemd {
 		return nil
@@ -531,6 +566,7 @@ func serviceVMInfo(vm db.VMConfigView, discovered map[string]vmDiscoveredIP, iso
 			SocketPath: socketPath,
 		},
 		SetupState: vm.SetupState(),
+		Power:      serviceVMPowerInfo(vm.Power()),
 	}
 	if strings.TrimSpace(ssh.User) != "" {
 		out.SSH = &catchrpc.ServiceVMSSH{User: ssh.User, Host: vmSSHHostFromNetworks(vm, discovered)}
@@ -541,6 +577,19 @@ func serviceVMInfo(vm db.VMConfigView, discovered map[string]vmDiscoveredIP, iso
 	return out, nil
 }
 
+func serviceVMPowerInfo(view db.VMPowerConfigView) *catchrpc.ServiceVMPower {
+	var power db.VMPowerConfig
+	if view.Valid() {
+		power = *view.AsStruct()
+	}
+	return &catchrpc.ServiceVMPower{
+		Autostart:       !power.NoAutostart,
+		StartOrder:      power.StartOrder,
+		ShutdownTimeout: vmShutdownTimeout(power).String(),
+		IdleStop:        power.IdleStop,
+	}
+}
+
 func serviceVMBalloonInfo(balloon db.VMBalloonConfig) catchrpc.ServiceVMBalloon {
 	return catchrpc.ServiceVMBalloon{
 		Mode:       balloon.Mode,
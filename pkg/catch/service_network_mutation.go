@@ -2047,6 +2047,9 @@ func (s *isoComposeNetworkStage) resolveBaseAndReserve(ctx context.Context) erro
 	if !ok {
 		return fmt.Errorf("service %q generation %d has no Docker Compose artifact", s.plan.name, s.plan.previous.Generation)
 	}
+	if err := composeProjectISOError(s.plan.previous.Artifacts, s.plan.previous.Generation); err != nil {
+		return err
+	}
 	s.base = base
 	s.options = svc.ComposeResolveOptions{
 		ProjectName: svc.ComposeProjectName(s.plan.name), ProjectDir: serviceDataDirForRoot(s.root), Files: []string{base},
@@ -2411,7 +2414,7 @@ func stageRegularDockerComposeNetwork(installer *FileInstaller, env netns.Servic
 	if err != nil {
 		return err
 	}
-	raw, err := renderDockerComposeNetwork(env, services)
+	raw, err := renderDockerComposeNetwork(installer.s.containerRuntime(), env, services)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,210 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/yeetrun/yeet/pkg/db"
+)
+
+// serviceQuotaProjectIDBase keeps yeet-assigned project IDs clear of the low
+// IDs administrators usually hand out in /etc/projid.
+const serviceQuotaProjectIDBase uint32 = 100000
+
+// xfsQuotaRunner runs xfs_quota. Its foreign filesystem mode also manages ext4
+// project quotas, so one tool covers every supported non-ZFS filesystem.
+type xfsQuotaRunner func(context.Context, ...string) (stdout string, stderr string, err error)
+
+// projectQuotaFS describes the filesystem holding a non-ZFS service root.
+// Type is empty when the filesystem has no project quota support.
+type projectQuotaFS struct {
+	Type       string
+	MountPoint string
+}
+
+var projectQuotaFSForPath = detectProjectQuotaFS
+
+func runXFSQuotaCommand(ctx context.Context, args ...string) (string, string, error) {
+	cmd := exec.CommandContext(ctx, "xfs_quota", args...)
+	var stdout strings.Builder
+	var stderr strings.Builder
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return stdout.String(), stderr.String(), err
+}
+
+func detectProjectQuotaFS(path string) (projectQuotaFS, error) {
+	fsType, err := projectQuotaFSType(path)
+	if err != nil || fsType == "" {
+		return projectQuotaFS{}, err
+	}
+	mountPoint, err := mountPointForPath(path)
+	if err != nil {
+		return projectQuotaFS{}, err
+	}
+	return projectQuotaFS{Type: fsType, MountPoint: mountPoint}, nil
+}
+
+// mountPointForPath walks up from path until the parent is on another device.
+func mountPointForPath(path string) (string, error) {
+	path = filepath.Clean(path)
+	dev, err := pathDevice(path)
+	if err != nil {
+		return "", err
+	}
+	for path != "/" {
+		parent := filepath.Dir(path)
+		parentDev, err := pathDevice(parent)
+		if err != nil {
+			return "", err
+		}
+		if parentDev != dev {
+			break
+		}
+		path = parent
+	}
+	return path, nil
+}
+
+func pathDevice(path string) (uint64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("inspect device for %s", path)
+	}
+	return uint64(stat.Dev), nil
+}
+
+func (fs projectQuotaFS) xfsQuotaArgs(command string) []string {
+	args := []string{"-x"}
+	if fs.Type != "xfs" {
+		args = append(args, "-f")
+	}
+	return append(args, "-c", command, fs.MountPoint)
+}
+
+func (s *Server) runXFSQuota(ctx context.Context, fs projectQuotaFS, command string) (string, error) {
+	runner := s.xfsQuotaRunner
+	if runner == nil {
+		runner = runXFSQuotaCommand
+	}
+	stdout, stderr, err := runner(ctx, fs.xfsQuotaArgs(command)...)
+	if err == nil {
+		return stdout, nil
+	}
+	if stderr = strings.TrimSpace(stderr); stderr != "" {
+		return "", fmt.Errorf("xfs_quota %q on %s failed: %s", command, fs.MountPoint, stderr)
+	}
+	return "", fmt.Errorf("xfs_quota %q on %s failed: %w", command, fs.MountPoint, err)
+}
+
+// setServiceQuota limits the service root to quota bytes, or removes the
+// limit when quota is zero, and records the result.
+func (s *Server) setServiceQuota(ctx context.Context, name string, quota int64) error {
+	sv, err := s.serviceView(name)
+	if err != nil {
+		return err
+	}
+	if sv.ServiceType() == db.ServiceTypeVM {
+		return fmt.Errorf("--quota does not apply to VMs; VM storage is limited by the VM disk size")
+	}
+	if dataset := sv.ServiceRootZFS(); dataset != "" {
+		err = setZFSRefquota(ctx, s.zfsRunner, dataset, quota)
+	} else {
+		err = s.setServiceProjectQuota(ctx, sv, quota)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = s.cfg.DB.MutateData(func(d *db.Data) error {
+		service, ok := d.Services[name]
+		if !ok {
+			return fmt.Errorf("service %q not found", name)
+		}
+		service.QuotaBytes = quota
+		return nil
+	})
+	return err
+}
+
+func setZFSRefquota(ctx context.Context, runner zfsCommandRunner, dataset string, quota int64) error {
+	if runner == nil {
+		runner = runZFSCommand
+	}
+	value := "none"
+	if quota > 0 {
+		value = strconv.FormatInt(quota, 10)
+	}
+	_, stderr, err := runner(ctx, "set", "refquota="+value, dataset)
+	if err != nil {
+		return formatZFSCommandError("zfs set refquota "+dataset, stderr, err)
+	}
+	return nil
+}
+
+func (s *Server) setServiceProjectQuota(ctx context.Context, sv db.ServiceView, quota int64) error {
+	if quota == 0 && sv.QuotaProjectID() == 0 {
+		return nil
+	}
+	root := s.serviceRootFromView(sv)
+	fs, err := projectQuotaFSForPath(root)
+	if err != nil {
+		return err
+	}
+	if fs.Type == "" {
+		return fmt.Errorf("service root %s does not support quotas; use a ZFS service root or an xfs or ext4 filesystem mounted with project quotas", root)
+	}
+	id, err := s.serviceQuotaProjectID(sv.Name())
+	if err != nil {
+		return err
+	}
+	if quota > 0 {
+		if _, err := s.runXFSQuota(ctx, fs, fmt.Sprintf("project -s -p %s %d", root, id)); err != nil {
+			return err
+		}
+	}
+	_, err = s.runXFSQuota(ctx, fs, fmt.Sprintf("limit -p bhard=%d %d", quota, id))
+	return err
+}
+
+// serviceQuotaProjectID returns the service's project ID, assigning the next
+// free one on first use so IDs stay stable across limit changes.
+func (s *Server) serviceQuotaProjectID(name string) (uint32, error) {
+	var id uint32
+	_, err := s.cfg.DB.MutateData(func(d *db.Data) error {
+		service, ok := d.Services[name]
+		if !ok {
+			return fmt.Errorf("service %q not found", name)
+		}
+		if service.QuotaProjectID == 0 {
+			service.QuotaProjectID = nextServiceQuotaProjectID(d.Services)
+		}
+		id = service.QuotaProjectID
+		return nil
+	})
+	return id, err
+}
+
+func nextServiceQuotaProjectID(services map[string]*db.Service) uint32 {
+	next := serviceQuotaProjectIDBase
+	for _, service := range services {
+		if service != nil && service.QuotaProjectID >= next {
+			next = service.QuotaProjectID + 1
+		}
+	}
+	return next
+}
@@ -0,0 +1,30 @@
+//go:build linux
+
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// projectQuotaFSType returns the name of a filesystem that supports project
+// quotas, or "" for any other filesystem.
+func projectQuotaFSType(path string) (string, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return "", fmt.Errorf("inspect filesystem for %s: %w", path, err)
+	}
+	switch stat.Type {
+	case unix.XFS_SUPER_MAGIC:
+		return "xfs", nil
+	case unix.EXT4_SUPER_MAGIC:
+		return "ext4", nil
+	default:
+		return "", nil
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+func projectQuotaFSType(string) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yeetrun/yeet/pkg/db"
+)
+
+func setQuotaTestServices(t *testing.T, server *Server, services ...*db.Service) {
+	t.Helper()
+	data := &db.Data{Services: map[string]*db.Service{}}
+	for _, service := range services {
+		data.Services[service.Name] = service
+	}
+	if err := server.cfg.DB.Set(data); err != nil {
+		t.Fatalf("DB.Set: %v", err)
+	}
+}
+
+func stubProjectQuotaFS(t *testing.T, fs projectQuotaFS) {
+	t.Helper()
+	old := projectQuotaFSForPath
+	t.Cleanup(func() { projectQuotaFSForPath = old })
+	projectQuotaFSForPath = func(string) (projectQuotaFS, error) { return fs, nil }
+}
+
+func TestSetServiceQuotaSetsZFSRefquota(t *testing.T) {
+	server := newTestServer(t)
+	setQuotaTestServices(t, server, &db.Service{Name: "api", ServiceType: db.ServiceTypeDockerCompose, ServiceRootZFS: "tank/apps/api"})
+	var calls [][]string
+	server.zfsRunner = func(_ context.Context, args ...string) (string, string, error) {
+		calls = append(calls, append([]string(nil), args...))
+		return "", "", nil
+	}
+
+	if err := server.setServiceQuota(context.Background(), "api", 20<<30); err != nil {
+		t.Fatalf("setServiceQuota: %v", err)
+	}
+	if err := server.setServiceQuota(context.Background(), "api", 0); err != nil {
+		t.Fatalf("setServiceQuota clear: %v", err)
+	}
+	want := [][]string{
+		{"set", "refquota=21474836480", "tank/apps/api"},
+		{"set", "refquota=none", "tank/apps/api"},
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("zfs calls = %q, want %q", calls, want)
+	}
+	sv, err := server.serviceView("api")
+	if err != nil || sv.QuotaBytes() != 0 {
+		t.Fatalf("QuotaBytes = %d, %v; want cleared", sv.QuotaBytes(), err)
+	}
+}
+
+func TestSetServiceQuotaAssignsProjectQuota(t *testing.T) {
+	server := newTestServer(t)
+	root := filepath.Join(server.cfg.ServicesRoot, "web")
+	setQuotaTestServices(t, server,
+		&db.Service{Name: "api", ServiceType: db.ServiceTypeSystemd, QuotaProjectID: serviceQuotaProjectIDBase + 4},
+		&db.Service{Name: "web", ServiceType: db.ServiceTypeSystemd, ServiceRoot: root},
+	)
+	stubProjectQuotaFS(t, projectQuotaFS{Type: "ext4", MountPoint: "/srv"})
+	var calls [][]string
+	server.xfsQuotaRunner = func(_ context.Context, args ...string) (string, string, error) {
+		calls = append(calls, append([]string(nil), args...))
+		return "", "", nil
+	}
+
+	if err := server.setServiceQuota(context.Background(), "web", 1<<30); err != nil {
+		t.Fatalf("setServiceQuota: %v", err)
+	}
+	want := [][]string{
+		{"-x", "-f", "-c", "project -s -p " + root + " 100005", "/srv"},
+		{"-x", "-f", "-c", "limit -p bhard=1073741824 100005", "/srv"},
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("xfs_quota calls = %q, want %q", calls, want)
+	}
+	sv, err := server.serviceView("web")
+	if err != nil || sv.QuotaBytes() != 1<<30 || sv.QuotaProjectID() != 100005 {
+		t.Fatalf("service quota = %d project %d, %v", sv.QuotaBytes(), sv.QuotaProjectID(), err)
+	}
+}
+
+func TestSetServiceQuotaRejectsUnsupportedRoots(t *testing.T) {
+	server := newTestServer(t)
+	setQuotaTestServices(t, server,
+		&db.Service{Name: "api", ServiceType: db.ServiceTypeSystemd},
+		&db.Service{Name: "vm", ServiceType: db.ServiceTypeVM},
+	)
+	stubProjectQuotaFS(t, projectQuotaFS{})
+
+	err := server.setServiceQuota(context.Background(), "api", 1<<30)
+	if err == nil || !strings.Contains(err.Error(), "does not support quotas") {
+		t.Fatalf("setServiceQuota error = %v, want unsupported filesystem", err)
+	}
+	err = server.setServiceQuota(context.Background(), "vm", 1<<30)
+	if err == nil || !strings.Contains(err.Error(), "VM") {
+		t.Fatalf("setServiceQuota VM error = %v, want VM rejection", err)
+	}
+	sv, _ := server.serviceView("api")
+	if sv.QuotaBytes() != 0 {
+		t.Fatalf("QuotaBytes = %d after failure, want 0", sv.QuotaBytes())
+	}
+}
+
+func TestSetServiceQuotaReportsXFSQuotaErrors(t *testing.T) {
+	server := newTestServer(t)
+	setQuotaTestServices(t, server, &db.Service{Name: "api", ServiceType: db.ServiceTypeSystemd})
+	stubProjectQuotaFS(t, projectQuotaFS{Type: "xfs", MountPoint: "/data"})
+	server.xfsQuotaRunner = func(_ context.Context, args ...string) (string, string, error) {
+		return "", "xfs_quota: cannot set limits: Operation not permitted", errors.New("exit status 1")
+	}
+
+	err := server.setServiceQuota(context.Background(), "api", 1<<30)
+	if err == nil || !strings.Contains(err.Error(), "Operation not permitted") {
+		t.Fatalf("setServiceQuota error = %v, want xfs_quota stderr", err)
+	}
+}
+
+func TestMountPointForPath(t *testing.T) {
+	dir := t.TempDir()
+	mountPoint, err := mountPointForPath(dir)
+	if err != nil {
+		t.Fatalf("mountPointForPath: %v", err)
+	}
+	if !strings.HasPrefix(dir, mountPoint) {
+		t.Fatalf("mount point %q is not a parent of %q", mountPoint, dir)
+	}
+}
@@ -1076,13 +1076,7 @@ func (s *Server) downDockerComposeForRootMigration(service *db.Service, oldRoot
 	if !serviceRootMigrationHasDockerCompose(service) {
 		return nil
 	}
-	composeService, err := svc.NewDockerComposeService(
-		s.cfg.DB,
-		service.View(),
-		serviceDataDirForRoot(oldRoot),
-		serviceRunDirForRoot(oldRoot),
-		svc.WithTailscaleGuardRunner(s.catchRunnerPath()),
-	)
+	composeService, err := s.newDockerComposeServiceAt(service.View(), oldRoot)
 	if err != nil {
 		return fmt.Errorf("load old docker compose project: %w", err)
 	}
@@ -54,8 +54,11 @@ func (p effectivePolicy) Allows(event snapshotEvent) bool {
 }
 
 type snapshotCreateRequest struct {
-	Service    string
-	Dataset    string
+	Service string
+	Dataset string
+	// Companions are datasets snapshotted atomically with Dataset under the
+	// same snapshot name, such as a VM's zvol data disks.
+	Companions []string
 	Event      snapshotEvent
 	Generation *int
 	Now        time.Time
@@ -472,6 +475,9 @@ func runZFSSnapshot(ctx context.Context, runner zfsCommandRunner, req snapshotCr
 		args = append(args, "-o", "com.yeetrun:protected=true")
 	}
 	args = append(args, snapshotName)
+	for _, companion := range req.Companions {
+		args = append(args, companion+"@"+vmSnapshotShortName(snapshotName))
+	}
 	_, stderr, err := runner(ctx, args...)
 	return stderr, err
 }
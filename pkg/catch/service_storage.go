@@ -0,0 +1,208 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/svc"
+)
+
+var dockerVolumeMountpointsForStorage = dockerComposeVolumeMountpoints
+
+// serviceStorage reports disk usage for the service root. Detailed reports
+// also walk plain service roots and Docker volumes; status skips those so it
+// stays fast with many services. It returns nil when usage is unavailable.
+func (s *Server) serviceStorage(ctx context.Context, sv db.ServiceView, detailed bool) *catchrpc.ServiceStorage {
+	if !sv.Valid() || sv.ServiceType() == db.ServiceTypeVM {
+		return nil
+	}
+	storage, err := s.serviceRootStorage(ctx, sv, detailed)
+	if err != nil || storage == nil {
+		return nil
+	}
+	if detailed && sv.ServiceType() == db.ServiceTypeDockerCompose {
+		if volumes, err := dockerVolumeUsage(ctx, s.containerRuntime(), sv.Name()); err == nil {
+			storage.Volumes = volumes
+		}
+	}
+	return storage
+}
+
+func (s *Server) serviceRootStorage(ctx context.Context, sv db.ServiceView, detailed bool) (*catchrpc.ServiceStorage, error) {
+	if dataset := sv.ServiceRootZFS(); dataset != "" {
+		return zfsDatasetStorage(ctx, s.zfsRunner, dataset)
+	}
+	root := s.serviceRootFromView(sv)
+	if sv.QuotaBytes() > 0 && sv.QuotaProjectID() != 0 {
+		return s.projectQuotaStorage(ctx, root, sv.QuotaProjectID())
+	}
+	if !detailed {
+		return nil, nil
+	}
+	return filesystemStorage(root)
+}
+
+// zfsDatasetStorage reads usage from the dataset itself. ZFS already caps
+// available at the refquota, and referenced excludes snapshot space.
+func zfsDatasetStorage(ctx context.Context, runner zfsCommandRunner, dataset string) (*catchrpc.ServiceStorage, error) {
+	if runner == nil {
+		runner = runZFSCommand
+	}
+	stdout, stderr, err := runner(ctx, "get", "-Hp", "-o", "property,value", "referenced,available,usedbysnapshots,refquota", dataset)
+	if err != nil {
+		return nil, formatZFSCommandError("zfs get "+dataset, stderr, err)
+	}
+	values, err := parseZFSPropertyValues(stdout)
+	if err != nil {
+		return nil, fmt.Errorf("zfs get %s: %w", dataset, err)
+	}
+	return &catchrpc.ServiceStorage{
+		Backend:   "zfs",
+		Used:      values["referenced"],
+		Available: values["available"],
+		Quota:     values["refquota"],
+		Snapshots: values["usedbysnapshots"],
+	}, nil
+}
+
+func parseZFSPropertyValues(output string) (map[string]int64, error) {
+	values := map[string]int64{}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		n, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q", fields[0], fields[1])
+		}
+		values[fields[0]] = n
+	}
+	return values, nil
+}
+
+// projectQuotaStorage reads the project's block usage and hard limit. Free
+// space is the smaller of the remaining quota and the filesystem's free space.
+func (s *Server) projectQuotaStorage(ctx context.Context, root string, id uint32) (*catchrpc.ServiceStorage, error) {
+	fs, err := projectQuotaFSForPath(root)
+	if err != nil {
+		return nil, err
+	}
+	if fs.Type == "" {
+		return nil, fmt.Errorf("service root %s no longer supports project quotas", root)
+	}
+	out, err := s.runXFSQuota(ctx, fs, fmt.Sprintf("quota -p -b -N -v %d", id))
+	if err != nil {
+		return nil, err
+	}
+	used, quota, err := parseXFSQuotaBlocks(out)
+	if err != nil {
+		return nil, err
+	}
+	storage := &catchrpc.ServiceStorage{Backend: fs.Type, Used: used, Quota: quota}
+	storage.Available = max(quota-used, 0)
+	if free, err := hostStorageFreeBytes(root); err == nil && free < uint64(storage.Available) {
+		storage.Available = int64(free)
+	}
+	return storage, nil
+}
+
+// parseXFSQuotaBlocks parses a headerless "quota -b" line of the form
+// "<device> <used> <soft> <hard> <warn> <grace>", with sizes in KiB.
+func parseXFSQuotaBlocks(output string) (used, hard int64, err error) {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		usedKiB, usedErr := strconv.ParseInt(fields[1], 10, 64)
+		hardKiB, hardErr := strconv.ParseInt(fields[3], 10, 64)
+		if usedErr == nil && hardErr == nil {
+			return usedKiB << 10, hardKiB << 10, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("unexpected xfs_quota output %q", strings.TrimSpace(output))
+}
+
+func filesystemStorage(root string) (*catchrpc.ServiceStorage, error) {
+	fsType, err := projectQuotaFSType(root)
+	if err != nil {
+		return nil, err
+	}
+	if fsType == "" {
+		fsType = "filesystem"
+	}
+	used, err := hostStorageBytesToCopy(root, nil)
+	if err != nil {
+		return nil, err
+	}
+	free, err := hostStorageFreeBytes(root)
+	if err != nil {
+		return nil, err
+	}
+	return &catchrpc.ServiceStorage{
+		Backend:   fsType,
+		Used:      clampStorageBytes(used),
+		Available: clampStorageBytes(free),
+	}, nil
+}
+
+func dockerVolumeUsage(ctx context.Context, rt svc.ContainerRuntime, service string) (int64, error) {
+	mountpoints, err := dockerVolumeMountpointsForStorage(ctx, rt, svc.ComposeProjectName(service))
+	if err != nil {
+		return 0, err
+	}
+	var total uint64
+	for _, mountpoint := range mountpoints {
+		size, err := hostStorageBytesToCopy(mountpoint, nil)
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	return clampStorageBytes(total), nil
+}
+
+// dockerComposeVolumeMountpoints lists host paths of the named volumes that
+// Compose created for project on rt.
+func dockerComposeVolumeMountpoints(ctx context.Context, rt svc.ContainerRuntime, project string) ([]string, error) {
+	docker, err := rt.LookPath()
+	if err != nil {
+		return nil, err
+	}
+	names, err := dockerOutputFields(ctx, docker, "volume", "ls", "-q", "--filter", "label=com.docker.compose.project="+project)
+	if err != nil || len(names) == 0 {
+		return nil, err
+	}
+	args := append([]string{"volume", "inspect", "--format", "{{.Mountpoint}}"}, names...)
+	return dockerOutputFields(ctx, docker, args...)
+}
+
+func dockerOutputFields(ctx context.Context, docker string, args ...string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, docker, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("docker %s: %w: %s", strings.Join(args[:2], " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.Fields(string(out)), nil
+}
+
+func clampStorageBytes(n uint64) int64 {
+	if n > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(n)
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/svc"
+)
+
+func TestServiceStorageReadsZFSDataset(t *testing.T) {
+	server := newTestServer(t)
+	setQuotaTestServices(t, server, &db.Service{Name: "api", ServiceType: db.ServiceTypeSystemd, ServiceRootZFS: "tank/apps/api"})
+	server.zfsRunner = func(_ context.Context, args ...string) (string, string, error) {
+		want := []string{"get", "-Hp", "-o", "property,value", "referenced,available,usedbysnapshots,refquota", "tank/apps/api"}
+		if !reflect.DeepEqual(args, want) {
+			t.Fatalf("zfs args = %q, want %q", args, want)
+		}
+		return "referenced\t1024\navailable\t4096\nusedbysnapshots\t512\nrefquota\t5120\n", "", nil
+	}
+	sv, _ := server.serviceView("api")
+
+	got := server.serviceStorage(context.Background(), sv, false)
+	want := &catchrpc.ServiceStorage{Backend: "zfs", Used: 1024, Available: 4096, Quota: 5120, Snapshots: 512}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("serviceStorage = %+v, want %+v", got, want)
+	}
+}
+
+func TestServiceStorageReadsProjectQuota(t *testing.T) {
+	server := newTestServer(t)
+	root := t.TempDir()
+	setQuotaTestServices(t, server, &db.Service{
+		Name: "api", ServiceType: db.ServiceTypeSystemd, ServiceRoot: root,
+		QuotaBytes: 2 << 20, QuotaProjectID: 100001,
+	})
+	stubProjectQuotaFS(t, projectQuotaFS{Type: "xfs", MountPoint: "/data"})
+	server.xfsQuotaRunner = func(_ context.Context, args ...string) (string, string, error) {
+		want := []string{"-x", "-c", "quota -p -b -N -v 100001", "/data"}
+		if !reflect.DeepEqual(args, want) {
+			t.Fatalf("xfs_quota args = %q, want %q", args, want)
+		}
+		return "/dev/sdb1   512   0   2048   00 [--------]\n", "", nil
+	}
+	sv, _ := server.serviceView("api")
+
+	got := server.serviceStorage(context.Background(), sv, false)
+	if got == nil || got.Backend != "xfs" || got.Used != 512<<10 || got.Quota != 2<<20 {
+		t.Fatalf("serviceStorage = %+v", got)
+	}
+	if got.Available <= 0 || got.Available > (2048-512)<<10 {
+		t.Fatalf("Available = %d, want capped by remaining quota", got.Available)
+	}
+}
+
+func TestServiceStorageWalksPlainRootsOnlyWhenDetailed(t *testing.T) {
+	server := newTestServer(t)
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "data.bin"), make([]byte, 3000), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	volume := t.TempDir()
+	if err := os.WriteFile(filepath.Join(volume, "db"), make([]byte, 700), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	old := dockerVolumeMountpointsForStorage
+	t.Cleanup(func() { dockerVolumeMountpointsForStorage = old })
+	var project string
+	dockerVolumeMountpointsForStorage = func(_ context.Context, _ svc.ContainerRuntime, p string) ([]string, error) {
+		project = p
+		return []string{volume}, nil
+	}
+	setQuotaTestServices(t, server, &db.Service{Name: "api", ServiceType: db.ServiceTypeDockerCompose, ServiceRoot: root})
+	sv, _ := server.serviceView("api")
+
+	if got := server.serviceStorage(context.Background(), sv, false); got != nil {
+		t.Fatalf("status storage = %+v, want nil for a plain root without quota", got)
+	}
+	got := server.serviceStorage(context.Background(), sv, true)
+	if got == nil || got.Used != 3000 || got.Volumes != 700 || got.Available <= 0 {
+		t.Fatalf("detailed storage = %+v", got)
+	}
+	if want := svc.ComposeProjectName("api"); project != want {
+		t.Fatalf("volume project = %q, want %q", project, want)
+	}
+}
+
+func TestParseXFSQuotaBlocksRejectsUnexpectedOutput(t *testing.T) {
+	if _, _, err := parseXFSQuotaBlocks("xfs_quota: no such project\n"); err == nil {
+		t.Fatal("parseXFSQuotaBlocks returned nil error")
+	}
+}
@@ -23,9 +23,11 @@ type statusSnapshotCommandContext func(context.Context, string, ...string) *exec
 
 var newStatusSnapshotCommand statusSnapshotCommandContext = exec.CommandContext
 
+// dockerPSStatusRow is a `ps --format {{json .}}` row. Docker prints Labels
+// as "k=v,k=v"; podman prints an object.
 type dockerPSStatusRow struct {
-	Labels string `json:"Labels"`
-	State  string `json:"State"`
+	Labels json.RawMessage `json:"Labels"`
+	State  string          `json:"State"`
 }
 
 func parseDockerComposeStatusSnapshot(raw []byte) (map[string]svc.DockerComposeStatus, error) {
@@ -42,7 +44,7 @@ func parseDockerComposeStatusSnapshot(raw []byte) (map[string]svc.DockerComposeS
 			continue
 		}
 		parsedRows++
-		labels := parseDockerPSLabels(row.Labels)
+		labels := row.labels()
 		project := labels["com.docker.compose.project"]
 		component := labels["com.docker.compose.service"]
 		serviceName, ok := statusServiceNameFromComposeProject(project)
@@ -77,7 +79,19 @@ func parseDockerPSStatusRow(line string) (dockerPSStatusRow, bool) {
 }
 
 func (row dockerPSStatusRow) wellFormed() bool {
-	return strings.TrimSpace(row.Labels) != "" && strings.TrimSpace(row.State) != ""
+	return len(row.labels()) != 0 && strings.TrimSpace(row.State) != ""
+}
+
+func (row dockerPSStatusRow) labels() map[string]string {
+	var raw string
+	if err := json.Unmarshal(row.Labels, &raw); err == nil {
+		return parseDockerPSLabels(raw)
+	}
+	var labels map[string]string
+	if err := json.Unmarshal(row.Labels, &labels); err != nil {
+		return nil
+	}
+	return labels
 }
 
 func parseDockerPSLabels(raw string) map[string]string {
@@ -146,8 +160,8 @@ func systemdShowStateStatus(loadState, activeState string) svc.Status {
 	return svc.StatusUnknown
 }
 
-func collectDockerComposeStatusSnapshot(ctx context.Context, newCmd statusSnapshotCommandContext) (map[string]svc.DockerComposeStatus, error) {
-	cmd := newCmd(ctx, "docker", "ps", "-a", "--filter", "label=com.docker.compose.project", "--format", "{{json .}}")
+func collectDockerComposeStatusSnapshot(ctx context.Context, rt svc.ContainerRuntime, newCmd statusSnapshotCommandContext) (map[string]svc.DockerComposeStatus, error) {
+	cmd := newCmd(ctx, rt.Command(), "ps", "-a", "--filter", "label=com.docker.compose.project", "--format", "{{json .}}")
 	out, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("docker ps status snapshot: %w", err)
@@ -181,7 +195,7 @@ func (s *Server) collectStatusSnapshot(ctx context.Context, newCmd statusSnapsho
 	services := dv.AsStruct().Services
 	dockerStatuses := map[string]svc.DockerComposeStatus{}
 	if len(serviceNamesByType(services, db.ServiceTypeDockerCompose)) > 0 {
-		dockerStatuses, err = collectDockerComposeStatusSnapshot(ctx, newCmd)
+		dockerStatuses, err = collectDockerComposeStatusSnapshot(ctx, s.containerRuntime(), newCmd)
 		if err != nil {
 			return nil, err
 		}
@@ -43,6 +43,18 @@ func TestParseDockerComposeStatusSnapshotGroupsYeetProjects(t *testing.T) {
 	}
 }
 
+func TestParseDockerComposeStatusSnapshotAcceptsPodmanLabels(t *testing.T) {
+	raw := `{"Names":["catch-web-api-1"],"State":"running","Labels":{"com.docker.compose.project":"catch-web","com.docker.compose.service":"api"}}`
+	got, err := parseDockerComposeStatusSnapshot([]byte(raw))
+	if err != nil {
+		t.Fatalf("parseDockerComposeStatusSnapshot returned error: %v", err)
+	}
+	want := map[string]svc.DockerComposeStatus{"web": {"api": svc.StatusRunning}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("podman snapshot = %#v, want %#v", got, want)
+	}
+}
+
 func TestParseDockerComposeStatusSnapshotRejectsOnlyMalformedOutput(t *testing.T) {
 	_, err := parseDockerComposeStatusSnapshot([]byte("bad-json\nalso-bad\n"))
 	if err == nil || !strings.Contains(err.Error(), "no valid docker status rows") {
@@ -126,7 +138,7 @@ func TestCollectDockerComposeStatusSnapshotBuildsCommandAndParsesOutput(t *testi
 		return statusSnapshotFakeCommand(t, ctx, raw)
 	})
 
-	got, err := collectDockerComposeStatusSnapshot(context.Background(), newCmd)
+	got, err := collectDockerComposeStatusSnapshot(context.Background(), svc.ContainerRuntimeDocker, newCmd)
 	if err != nil {
 		t.Fatalf("collectDockerComposeStatusSnapshot returned error: %v", err)
 	}
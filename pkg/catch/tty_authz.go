@@ -13,7 +13,8 @@ import (
 
 func execRequestPermissions(req catchrpc.ExecRequest) (permissionSet, error) {
 	switch req.Target {
-	case catchrpc.ExecTargetHostShell, catchrpc.ExecTargetServiceShell:
+	case catchrpc.ExecTargetHostShell, catchrpc.ExecTargetServiceShell,
+		catchrpc.ExecTargetVMAgentExec, catchrpc.ExecTargetVMAgentPush, catchrpc.ExecTargetVMAgentPull:
 		return newPermissionSet(permissionSSH), nil
 	case catchrpc.ExecTargetVMSSHProxy:
 		return newPermissionSet(permissionRead), nil
@@ -57,11 +58,24 @@ func dockerCommandPermissions(args []string) (permissionSet, error) {
 		return newPermissionSet(permissionRead), nil
 	case "pull", "update":
 		return newPermissionSet(permissionManage), nil
+	case "images":
+		return dockerImagesCommandPermissions(args[1:])
 	default:
 		return nil, fmt.Errorf("unclassified docker command %q", args[0])
 	}
 }
 
+func dockerImagesCommandPermissions(args []string) (permissionSet, error) {
+	_, positionals, err := cli.ParseDockerImages(args)
+	if err != nil {
+		return nil, fmt.Errorf("unclassified docker images command: %w", err)
+	}
+	if len(positionals) > 0 && positionals[0] == cli.DockerImagesActionPrune {
+		return newPermissionSet(permissionManage), nil
+	}
+	return newPermissionSet(permissionRead), nil
+}
+
 func snapshotsCommandPermissions(args []string) (permissionSet, error) {
 	if len(args) == 0 {
 		return nil, fmt.Errorf("unclassified snapshots command")
@@ -100,7 +114,7 @@ func serviceCommandPermissions(args []string) (permissionSet, error) {
 	switch args[0] {
 	case "generations":
 		return newPermissionSet(permissionRead), nil
-	case "set", "rollback":
+	case "set", "rollback", "export", "import":
 		return newPermissionSet(permissionManage), nil
 	default:
 		return nil, fmt.Errorf("unclassified service command %q", args[0])
@@ -140,7 +154,12 @@ func vmCommandPermissions(args []string) (permissionSet, error) {
 			return newPermissionSet(permissionRead), nil
 		}
 		return newPermissionSet(permissionManage), nil
-	case "console", "set", "kernel":
+	case "disk":
+		if len(args) > 1 && args[1] == "ls" {
+			return newPermissionSet(permissionRead), nil
+		}
+		return newPermissionSet(permissionManage), nil
+	case "console", "set", "kernel", "hibernate", "resume", "migrate":
 		return newPermissionSet(permissionManage), nil
 	default:
 		return nil, fmt.Errorf("unclassified vm command %q", args[0])
@@ -171,7 +190,7 @@ func vmImagesCommandPermissions(args []string) (permissionSet, error) {
 	switch args[0] {
 	case "ls", "catalog":
 		return newPermissionSet(permissionRead), nil
-	case "update", "import", "rm", "prune":
+	case "update", "import", "capture", "rm", "prune":
 		return newPermissionSet(permissionManage), nil
 	default:
 		return nil, fmt.Errorf("unclassified vm images command %q", args[0])
@@ -51,6 +51,8 @@ func TestTTYAuthorizationCommandPermissions(t *testing.T) {
 		{name: "ip", args: []string{"ip"}, want: permissionRead},
 		{name: "docker outdated", args: []string{"docker", "outdated"}, want: permissionRead},
 		{name: "docker update", args: []string{"docker", "update"}, want: permissionManage},
+		{name: "docker images", args: []string{"docker", "images", "--format=json"}, want: permissionRead},
+		{name: "docker images prune", args: []string{"docker", "images", "prune"}, want: permissionManage},
 		{name: "snapshots list", args: []string{"snapshots", "list"}, want: permissionRead},
 		{name: "snapshots defaults show", args: []string{"snapshots", "defaults", "show"}, want: permissionRead},
 		{name: "snapshots defaults set", args: []string{"snapshots", "defaults", "set", "--enabled=true"}, want: permissionManage},
@@ -60,6 +62,8 @@ func TestTTYAuthorizationCommandPermissions(t *testing.T) {
 		{name: "service set cron", args: []string{"service", "set", "--cron=30 2 * * *"}, want: permissionManage},
 		{name: "service set run as", args: []string{"service", "set", "--run-as=app"}, want: permissionManage},
 		{name: "service set sandbox", args: []string{"service", "set", "--sandbox=on"}, want: permissionManage},
+		{name: "service export", args: []string{"service", "export", "--data"}, want: permissionManage},
+		{name: "service import", args: []string{"service", "import"}, want: permissionManage},
 		{name: "tailscale status", args: []string{"tailscale", "status"}, want: permissionRead},
 		{name: "tailscale update", args: []string{"tailscale", "update"}, want: permissionManage},
 		{name: "vm images ls", args: []string{"vm", "images", "ls"}, want: permissionRead},
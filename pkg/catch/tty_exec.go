@@ -17,6 +17,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -127,6 +128,8 @@ type ttyExecer struct {
 	bypassPtyInput           bool
 	traceStart               time.Time
 	serviceOperationLockHeld bool
+	vmAgentExec              atomic.Pointer[vmAgentExecSession]
+	vmMigration              *vmMigrationImport
 
 	// Optional override for tests.
 	serviceRunnerFn                          func() (ServiceRunner, error)
@@ -213,7 +216,8 @@ func (e *ttyExecer) run() error {
 	}
 
 	err = e.exec()
-	if err != nil {
+	var exitErr vmAgentExitError
+	if err != nil && !errors.As(err, &exitErr) {
 		writef(e.rawRW, "Error: %v\n", err)
 	}
 	if ptySession != nil {
@@ -260,8 +264,13 @@ func (e *ttyExecer) shouldStartPtySession() bool {
 	return e.isPty && !e.isTransparentTTYCommand()
 }
 
+// isTransparentTTYCommand reports whether the remote end allocates its own
+// terminal, so catch must pass bytes through without a local PTY.
 func (e *ttyExecer) isTransparentTTYCommand() bool {
-	return len(e.args) == 2 && e.args[0] == "vm" && e.args[1] == "console"
+	if e.target == catchrpc.ExecTargetVMAgentExec {
+		return true
+	}
+	return len(e.args) >= 2 && e.args[0] == "vm" && e.args[1] == "console"
 }
 
 func dupPtyFile(stdin *os.File) (*os.File, error) {
@@ -309,6 +318,8 @@ func (e *ttyExecer) shouldBypassPtyInput() bool {
 	switch e.args[0] {
 	case "run", "copy", "stage":
 		return true
+	case "service":
+		return len(e.args) > 1 && e.args[1] == "import"
 	case "vm":
 		return vmCommandShouldBypassPtyInput(e.args[1:])
 	default:
@@ -330,6 +341,8 @@ func vmCommandShouldBypassPtyInput(args []string) bool {
 	case "runtime":
 		action, _, ok := cli.FindVMRuntimeAction(args[1:])
 		return ok && action == cli.VMRuntimeActionImport
+	case "migrate":
+		return len(args) > 1 && args[1] == "receive"
 	default:
 		return false
 	}
@@ -346,6 +359,10 @@ func (e *ttyExecer) ResizeTTY(cols, rows int) {
 	if !e.isPty {
 		return
 	}
+	if session := e.vmAgentExec.Load(); session != nil {
+		_ = session.Resize(cols, rows)
+		return
+	}
 	if tty, ok := e.rw.(*os.File); ok {
 		setWinsizeForTTY(tty, cols, rows)
 	}
@@ -364,6 +381,12 @@ func (e *ttyExecer) exec() error {
 		})
 	case catchrpc.ExecTargetVMSSHProxy:
 		return e.vmSSHProxyCmdFunc(e.args)
+	case catchrpc.ExecTargetVMAgentExec:
+		return e.vmAgentExecCmdFunc(e.args)
+	case catchrpc.ExecTargetVMAgentPush:
+		return e.vmAgentPushCmdFunc(e.args)
+	case catchrpc.ExecTargetVMAgentPull:
+		return e.vmAgentPullCmdFunc(e.args)
 	}
 	if len(e.args) == 0 {
 		return nil
@@ -516,10 +539,19 @@ func (e *ttyExecer) dispatch(args []string) error {
 }
 
 func (e *ttyExecer) serviceMutationTarget(cmd string, args []string) (string, error) {
-	if cmd != "service" || len(args) == 0 || args[0] != "rollback" {
+	if cmd != "service" || len(args) == 0 {
+		return e.sn, nil
+	}
+	var rest []string
+	var err error
+	switch args[0] {
+	case "rollback":
+		rest, err = cli.ParseServiceRollback(argsWithServiceDefault(args[1:], e.sn))
+	case "export":
+		_, rest, err = cli.ParseServiceExport(argsWithServiceDefault(args[1:], e.sn))
+	default:
 		return e.sn, nil
 	}
-	rest, err := cli.ParseServiceRollback(argsWithServiceDefault(args[1:], e.sn))
 	if err != nil {
 		return "", err
 	}
@@ -825,7 +857,7 @@ func (e *ttyExecer) newCmdContext(ctx context.Context, name string, args ...stri
 	rw := e.rw
 
 	c.Stdin = rw
-	if e.isPty && filepath.Base(name) == "docker" && len(args) > 0 && args[0] == "compose" {
+	if e.isPty && isComposeCommand(name, args) {
 		// Ensure compose starts on a clean line without wrapping stdout/stderr,
 		// so it still detects a TTY and renders its own progress UI.
 		_, _ = fmt.Fprint(rw, "\r\033[K")
@@ -864,13 +896,23 @@ func (e *ttyExecer) shouldSuppressCmdOutput(name string, args []string) bool {
 		mode = catchrpc.ProgressPlain
 	}
 	if mode == catchrpc.ProgressPlain || mode == catchrpc.ProgressQuiet {
-		if filepath.Base(name) == "docker" && len(args) > 0 && args[0] == "compose" {
+		if isComposeCommand(name, args) {
 			return dockerComposeSubcommand(args[1:]) != "logs"
 		}
 	}
 	return false
 }
 
+// isComposeCommand reports whether name and args run compose on either
+// container runtime.
+func isComposeCommand(name string, args []string) bool {
+	switch svc.ContainerRuntime(filepath.Base(name)) {
+	case svc.ContainerRuntimeDocker, svc.ContainerRuntimePodman:
+		return len(args) > 0 && args[0] == "compose"
+	}
+	return false
+}
+
 func dockerComposeSubcommand(args []string) string {
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
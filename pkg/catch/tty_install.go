@@ -325,6 +325,12 @@ func (e *ttyExecer) runCmdFunc(flags cli.RunFlags, argsIn []string) error {
 	if len(argsIn) > 0 && isVMImagePayload(argsIn[0]) {
 		return e.runVMPayload(flags, argsIn)
 	}
+	switch flags.Build {
+	case cli.RunBuildRemote:
+		return e.runRemoteBuild(flags, argsIn)
+	case cli.RunBuildLocal:
+		return fmt.Errorf("--build=local runs on the yeet client; do not send it to catch")
+	}
 	cfg, err := e.runFileInstallerCfg(flags, argsIn)
 	if err != nil {
 		return err
@@ -336,6 +342,12 @@ func (e *ttyExecer) runVMPayload(flags cli.RunFlags, args []string) error {
 	if flags.Sandbox.HasChange() {
 		return errors.New(sandboxNativePayloadOnlyMessage)
 	}
+	if flags.HasComposeProject() {
+		return errors.New(composeProjectOnlyMessage)
+	}
+	if flags.Build != "" {
+		return errors.New(remoteBuildOnlyMessage)
+	}
 	if flags.CronSet {
 		return errors.New(scheduledNativeOnlyMessage)
 	}
@@ -363,6 +375,9 @@ func (e *ttyExecer) runVMPayload(flags cli.RunFlags, args []string) error {
 }
 
 func (e *ttyExecer) runFileInstallerCfg(flags cli.RunFlags, argsIn []string) (FileInstallerCfg, error) {
+	if flags.UserData != "" {
+		return FileInstallerCfg{}, fmt.Errorf("--user-data is only valid for VM payloads")
+	}
 	snapshotFlags, err := snapshotFlagsFromRunFlags(flags)
 	if err != nil {
 		return FileInstallerCfg{}, err
@@ -374,6 +389,9 @@ func (e *ttyExecer) runFileInstallerCfg(flags cli.RunFlags, argsIn []string) (Fi
 	cfg.RunAs = flags.RunAs
 	cfg.RunAsSet = flags.RunAsSet
 	cfg.Sandbox = flags.Sandbox
+	cfg.ComposeFiles = flags.ComposeFiles
+	cfg.ComposeEnvFiles = flags.ComposeEnvFiles
+	cfg.Profiles = flags.Profiles
 	cfg.snapshotPolicyFlags = snapshotFlags
 	if flags.CronSet {
 		onCalendar, err := cronutil.CronToCalender(flags.Cron)
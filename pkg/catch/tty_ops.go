@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -28,15 +29,8 @@ import (
 )
 
 var (
-	checkMountCommand = func(mountType string) error {
-		mountCmd := fmt.Sprintf("/sbin/mount.%s", mountType)
-		_, err := os.Stat(mountCmd)
-		if err != nil {
-			return fmt.Errorf("mount command %q not found", mountCmd)
-		}
-		return nil
-	}
-	mountVolume = func(vol db.Volume) error {
+	checkMountCommand = defaultCheckMountCommand
+	mountVolume       = func(vol db.Volume) error {
 		return (&systemdMounter{v: vol}).mount()
 	}
 	unmountVolume = func(e *ttyExecer, vol db.Volume) error {
@@ -85,6 +79,18 @@ func (e *ttyExecer) dockerCmdFunc(args []string) error {
 			return fmt.Errorf("docker outdated takes no remote arguments")
 		}
 		return e.dockerOutdatedCmdFunc(flags)
+	case "images":
+		flags, remaining, err := cli.ParseDockerImages(args)
+		if err != nil {
+			return err
+		}
+		if len(remaining) == 0 {
+			return e.dockerImagesListCmdFunc(flags)
+		}
+		if remaining[0] != cli.DockerImagesActionPrune {
+			return fmt.Errorf("docker images takes no remote arguments")
+		}
+		return e.dockerImagesPruneCmdFunc(flags)
 	default:
 		return fmt.Errorf("unknown docker command %q", subcmd)
 	}
@@ -230,10 +236,12 @@ func (e *ttyExecer) dockerUpdateCmdFunc() error {
 		return err
 	}
 	if err := e.s.withServiceSnapshot(e.ctx, snapshotOperation{
-		Service:   sv.AsStruct(),
-		Event:     snapshotEventDockerUpdate,
-		Writer:    e.rw,
-		Operation: func() error { return dockerComposeUpdate(docker) },
+		Service: sv.AsStruct(),
+		Event:   snapshotEventDockerUpdate,
+		Writer:  e.rw,
+		Operation: func() error {
+			return e.s.updateCompose(e.ctx, e.sn, docker, func() error { return dockerComposeUpdate(docker) })
+		},
 	}); err != nil {
 		ui.FailStep(err.Error())
 		return err
@@ -500,7 +508,56 @@ func (e *ttyExecer) umountCmdFunc(args []string) error {
 	if err != nil {
 		return err
 	}
-	return e.unmountAndDeleteVolume(vol, mountName)
+	// Drop the requirements first so stopping the mount unit does not also
+	// stop the services that require it.
+	if vol.Services != "" {
+		if err := removeMountRequirements(vol); err != nil {
+			return fmt.Errorf("failed to remove mount requirements: %w", err)
+		}
+	}
+	if err := e.unmountAndDeleteVolume(vol, mountName); err != nil {
+		return errors.Join(err, e.restoreMountRequirements(vol))
+	}
+	for _, secret := range []string{vol.Credentials, vol.KnownHosts} {
+		if secret == "" {
+			continue
+		}
+		if err := os.Remove(secret); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove mount credentials: %w", err)
+		}
+	}
+	e.printUngatedComposeServices(vol)
+	return nil
+}
+
+// printUngatedComposeServices notes the compose services that lost their
+// mount gate; their containers run without restart policies until the next
+// deploy renders the project without the gate.
+func (e *ttyExecer) printUngatedComposeServices(vol db.Volume) {
+	for _, name := range strings.Fields(vol.Services) {
+		sv, err := e.s.serviceView(name)
+		if err != nil || sv.ServiceType() != db.ServiceTypeDockerCompose {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(systemdSystemDir, composeMountUnitName(name))); err == nil {
+			continue
+		}
+		e.printf("note: %s keeps its restart policies off until its next deploy\n", name)
+	}
+}
+
+func (e *ttyExecer) restoreMountRequirements(vol db.Volume) error {
+	if vol.Services == "" {
+		return nil
+	}
+	units, err := e.mountRequirementUnits(strings.Fields(vol.Services))
+	if err != nil {
+		return fmt.Errorf("failed to restore mount requirements: %w", err)
+	}
+	if err := installMountRequirements(vol, units); err != nil {
+		return fmt.Errorf("failed to restore mount requirements: %w", err)
+	}
+	return nil
 }
 
 func umountNameFromArgs(args []string) (string, error) {
@@ -567,61 +624,142 @@ func (e *ttyExecer) mountCmdFunc(flags cli.MountFlags, args []string) error {
 }
 
 func (e *ttyExecer) mountCreateCmdFunc(flags cli.MountFlags, args []string) error {
-	source := args[0]
-	mountName, err := mountNameFromArgs(args)
+	vol, err := e.newMountVolume(flags, args)
 	if err != nil {
 		return err
 	}
+	// Check the appropriate mounter is installed before touching any state.
+	if err := checkMountCommand(vol.Type); err != nil {
+		return err
+	}
+	units, err := e.mountRequirementUnits(flags.Services)
+	if err != nil {
+		return err
+	}
+	var credentials []byte
+	if flags.Credentials != "" {
+		if credentials, err = e.mountCredentials(flags.Credentials, vol.Type); err != nil {
+			return err
+		}
+		vol.Credentials = mountCredentialsPath(e.s.cfg.RootDir, vol.Name)
+	}
+	var knownHosts []byte
+	switch {
+	case vol.Type == mountTypeSSHFS:
+		if knownHosts, err = mountKnownHosts(vol.Src, vol.Opts, flags.HostKey); err != nil {
+			return err
+		}
+		vol.KnownHosts = mountKnownHostsPath(e.s.cfg.RootDir, vol.Name)
+	case flags.HostKey != "":
+		return fmt.Errorf("--host-key only applies to sshfs mounts")
+	}
 
-	mountType := flags.Type
-	// Check the appropriate mounter is installed by stating /sbin/mount.<type>.
-	if err := checkMountCommand(mountType); err != nil {
+	commitWarning, err := e.publishMountVolume(vol)
+	if err != nil {
 		return err
 	}
+	if err := applyMountVolume(vol, credentials, knownHosts, units); err != nil {
+		return errors.Join(commitWarning, err)
+	}
+	if commitWarning != nil {
+		return commitWarning
+	}
 
-	opts := flags.Opts
-	target := filepath.Join(e.s.cfg.MountsRoot, mountName)
-	deps := flags.Deps
-	vol := db.Volume{
-		Name: mountName,
-		Src:  source,
-		Path: target,
-		Type: mountType,
-		Opts: opts,
-		Deps: strings.Join(deps, " "),
+	if _, err := fmt.Fprintf(e.rw, "Mounted %s at %s\n", vol.Src, vol.Path); err != nil {
+		return fmt.Errorf("failed to write mount result: %w", err)
+	}
+	return nil
+}
+
+func (e *ttyExecer) newMountVolume(flags cli.MountFlags, args []string) (db.Volume, error) {
+	mountType := normalizeMountType(flags.Type)
+	mountName, err := mountNameFromArgs(mountType, args)
+	if err != nil {
+		return db.Volume{}, err
+	}
+	return db.Volume{
+		Name:     mountName,
+		Src:      args[0],
+		Path:     filepath.Join(e.s.cfg.MountsRoot, mountName),
+		Type:     mountType,
+		Opts:     flags.Opts,
+		Deps:     strings.Join(flags.Deps, " "),
+		Services: strings.Join(flags.Services, " "),
+	}, nil
+}
+
+// mountCredentials reads the credentials the client streamed on stdin. A
+// path would name a file on the client, which catch cannot read.
+func (e *ttyExecer) mountCredentials(flag, mountType string) ([]byte, error) {
+	if flag != "-" {
+		return nil, fmt.Errorf("--credentials must be streamed on stdin with --credentials=-")
+	}
+	return readMountCredentials(e.payloadReader(), mountType)
+}
+
+func (e *ttyExecer) mountRequirementUnits(services []string) ([]mountRequirementUnit, error) {
+	if len(services) == 0 {
+		return nil, nil
+	}
+	dv, err := e.s.cfg.DB.Get()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get services: %w", err)
+	}
+	rt := e.s.containerRuntime()
+	var units []mountRequirementUnit
+	for _, name := range services {
+		sv, ok := dv.Services().GetOk(name)
+		if !ok {
+			return nil, fmt.Errorf("service %q not found", name)
+		}
+		if unit := mountRequirementUnitForService(sv, rt); !slices.Contains(units, unit) {
+			units = append(units, unit)
+		}
 	}
+	return units, nil
+}
+
+func (e *ttyExecer) publishMountVolume(vol db.Volume) (commitWarning error, err error) {
 	_, mutationErr := mutateMountVolumeData(e.s.cfg.DB, func(d *db.Data) error {
-		if _, ok := d.Volumes[mountName]; ok {
-			return fmt.Errorf("volume %q already exists; please remove it first", mountName)
+		if _, ok := d.Volumes[vol.Name]; ok {
+			return fmt.Errorf("volume %q already exists; please remove it first", vol.Name)
 		}
-		mak.Set(&d.Volumes, mountName, &vol)
+		mak.Set(&d.Volumes, vol.Name, &vol)
 		return nil
 	})
-	var commitWarning error
-	if mutationErr != nil {
-		if !dbMutationCommitted(mutationErr) {
-			return fmt.Errorf("failed to save data: %w", mutationErr)
+	if mutationErr == nil {
+		return nil, nil
+	}
+	if !dbMutationCommitted(mutationErr) {
+		return nil, fmt.Errorf("failed to save data: %w", mutationErr)
+	}
+	return fmt.Errorf("failed to durably save mounted volume: %w", mutationErr), nil
+}
+
+func applyMountVolume(vol db.Volume, credentials, knownHosts []byte, units []mountRequirementUnit) error {
+	if vol.Credentials != "" {
+		if err := writeMountCredentials(vol.Credentials, credentials); err != nil {
+			return err
 		}
-		commitWarning = fmt.Errorf("failed to durably save mounted volume: %w", mutationErr)
 	}
-	if err := mountVolume(vol); err != nil {
-		return errors.Join(commitWarning, fmt.Errorf("failed to mount %s at %s: %w", source, target, err))
+	if vol.KnownHosts != "" {
+		if err := writeMountCredentials(vol.KnownHosts, knownHosts); err != nil {
+			return err
+		}
 	}
-	if commitWarning != nil {
-		return commitWarning
+	if err := mountVolume(vol); err != nil {
+		return fmt.Errorf("failed to mount %s at %s: %w", vol.Src, vol.Path, err)
 	}
-
-	if _, err := fmt.Fprintf(e.rw, "Mounted %s at %s\n", source, target); err != nil {
-		return fmt.Errorf("failed to write mount result: %w", err)
+	if err := installMountRequirements(vol, units); err != nil {
+		return fmt.Errorf("failed to add mount requirements to services: %w", err)
 	}
 	return nil
 }
 
-func mountNameFromArgs(args []string) (string, error) {
-	source := args[0]
-	_, srcPath, ok := strings.Cut(source, ":")
-	if !ok {
-		return "", fmt.Errorf("source %q must be in the format host:path", source)
+func mountNameFromArgs(mountType string, args []string) (string, error) {
+	srcPath, err := mountSourcePath(mountType, args[0])
+	if err != nil {
+		return "", err
 	}
 	mountName := filepath.Base(srcPath)
 	if len(args) == 2 {
@@ -635,11 +773,16 @@ func mountNameFromArgs(args []string) (string, error) {
 
 func printMounts(w io.Writer, volumes map[string]*db.Volume) error {
 	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
-	if _, err := fmt.Fprintln(tw, "NAME\tSRC\tPATH\tTYPE\tOPTS"); err != nil {
+	if _, err := fmt.Fprintln(tw, "NAME\tSRC\tPATH\tTYPE\tOPTS\tSERVICES\tSTATUS"); err != nil {
 		return fmt.Errorf("failed to write mount header: %w", err)
 	}
-	for _, v := range volumes {
-		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", v.Name, v.Src, v.Path, v.Type, v.Opts); err != nil {
+	for _, name := range slices.Sorted(maps.Keys(volumes)) {
+		v := volumes[name]
+		services := strings.ReplaceAll(v.Services, " ", ",")
+		if services == "" {
+			services = "-"
+		}
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", v.Name, v.Src, v.Path, v.Type, v.Opts, services, mountHealthCheck(v.Path)); err != nil {
 			return fmt.Errorf("failed to write mount row: %w", err)
 		}
 	}
@@ -1156,6 +1299,9 @@ func (e *ttyExecer) printVMIPs() (bool, error) {
 			return true, fmt.Errorf("failed to write IP address: %w", err)
 		}
 	}
+	if err := printVMPublishedPorts(e.rw, sv.AsStruct()); err != nil {
+		return true, fmt.Errorf("failed to write published ports: %w", err)
+	}
 	return true, nil
 }
 
@@ -658,6 +658,139 @@ func TestMountCmdCreatePersistsVolumeAndRunsMount(t *testing.T) {
 	}
 }
 
+func TestMountCmdCreateStoresCredentialsAndServiceRequirements(t *testing.T) {
+	server := newTestServer(t)
+	server.cfg.DB.Set(&db.Data{Services: map[string]*db.Service{
+		"jellyfin": {Name: "jellyfin", ServiceType: db.ServiceTypeDockerCompose},
+		"api":      {Name: "api", ServiceType: db.ServiceTypeSystemd},
+	}})
+
+	oldCheckMountCommand := checkMountCommand
+	oldMountVolume := mountVolume
+	oldInstall := installMountRequirements
+	t.Cleanup(func() {
+		checkMountCommand = oldCheckMountCommand
+		mountVolume = oldMountVolume
+		installMountRequirements = oldInstall
+	})
+	var checkedType string
+	checkMountCommand = func(mountType string) error {
+		checkedType = mountType
+		return nil
+	}
+	var credentialsAtMount string
+	mountVolume = func(vol db.Volume) error {
+		raw, err := os.ReadFile(vol.Credentials)
+		credentialsAtMount = string(raw)
+		return err
+	}
+	var gotUnits []mountRequirementUnit
+	installMountRequirements = func(_ db.Volume, units []mountRequirementUnit) error {
+		gotUnits = units
+		return nil
+	}
+
+	execer := &ttyExecer{
+		ctx: context.Background(),
+		s:   server,
+		rw: readWriter{
+			Reader: strings.NewReader("username=media\npassword=secret\n"),
+			Writer: &bytes.Buffer{},
+		},
+	}
+	flags := cli.MountFlags{Type: "smb", Opts: "defaults", Credentials: "-", Services: []string{"jellyfin", "api"}}
+	if err := execer.mountCmdFunc(flags, []string{"//nas/media"}); err != nil {
+		t.Fatalf("mountCmdFunc: %v", err)
+	}
+	if checkedType != "cifs" {
+		t.Fatalf("checked mount type = %q, want cifs", checkedType)
+	}
+	if credentialsAtMount != "username=media\npassword=secret\n" {
+		t.Fatalf("credentials at mount = %q", credentialsAtMount)
+	}
+	wantUnits := []mountRequirementUnit{
+		{Dir: systemdSystemDir, Unit: "yeet-jellyfin-compose.service", Content: composeMountUnitContent("jellyfin", svc.ContainerRuntimeDocker), Compose: "jellyfin", Runtime: svc.ContainerRuntimeDocker},
+		{Dir: systemdSystemDir, Unit: "api.service"},
+	}
+	if !reflect.DeepEqual(gotUnits, wantUnits) {
+		t.Fatalf("requirement units = %#v, want %#v", gotUnits, wantUnits)
+	}
+	dv, err := server.cfg.DB.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vol := dv.Volumes().Get("media")
+	if vol.Type() != "cifs" || vol.Services() != "jellyfin api" || vol.Credentials() != mountCredentialsPath(server.cfg.RootDir, "media") {
+		t.Fatalf("persisted volume = %+v", vol.AsStruct())
+	}
+}
+
+func TestMountCmdCreateRejectsUnknownServiceBeforeSaving(t *testing.T) {
+	server := newTestServer(t)
+	oldCheckMountCommand := checkMountCommand
+	checkMountCommand = func(string) error { return nil }
+	t.Cleanup(func() { checkMountCommand = oldCheckMountCommand })
+
+	execer := &ttyExecer{ctx: context.Background(), s: server, rw: &bytes.Buffer{}}
+	err := execer.mountCmdFunc(cli.MountFlags{Type: "bind", Services: []string{"ghost"}}, []string{"/srv/shared"})
+	if err == nil || !strings.Contains(err.Error(), `service "ghost" not found`) {
+		t.Fatalf("mountCmdFunc error = %v, want unknown service", err)
+	}
+	dv, err := server.cfg.DB.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dv.Volumes().Len() != 0 {
+		t.Fatal("volume saved despite unknown service")
+	}
+}
+
+func TestMountCmdCreateRequiresStreamedCredentials(t *testing.T) {
+	server := newTestServer(t)
+	oldCheckMountCommand := checkMountCommand
+	checkMountCommand = func(string) error { return nil }
+	t.Cleanup(func() { checkMountCommand = oldCheckMountCommand })
+
+	execer := &ttyExecer{ctx: context.Background(), s: server, rw: &bytes.Buffer{}}
+	err := execer.mountCmdFunc(cli.MountFlags{Type: "cifs", Credentials: "/home/me/nas.cred"}, []string{"//nas/media"})
+	if err == nil || !strings.Contains(err.Error(), "--credentials=-") {
+		t.Fatalf("mountCmdFunc error = %v, want stdin requirement", err)
+	}
+}
+
+func TestPrintMountsSortsAndReportsHealth(t *testing.T) {
+	oldHealth := mountHealthCheck
+	mountHealthCheck = func(path string) string {
+		if path == "/mnt/media" {
+			return "stale"
+		}
+		return "ok"
+	}
+	t.Cleanup(func() { mountHealthCheck = oldHealth })
+
+	var out bytes.Buffer
+	err := printMounts(&out, map[string]*db.Volume{
+		"media":  {Name: "media", Src: "//nas/media", Path: "/mnt/media", Type: "cifs", Opts: "defaults", Services: "jellyfin api"},
+		"backup": {Name: "backup", Src: "host:/srv", Path: "/mnt/backup", Type: "nfs", Opts: "defaults"},
+	})
+	if err != nil {
+		t.Fatalf("printMounts: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("printMounts output:\n%s", out.String())
+	}
+	if fields := strings.Fields(lines[0]); fields[len(fields)-1] != "STATUS" {
+		t.Fatalf("header = %q", lines[0])
+	}
+	if fields := strings.Fields(lines[1]); fields[0] != "backup" || fields[5] != "-" || fields[6] != "ok" {
+		t.Fatalf("first row = %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); fields[0] != "media" || fields[5] != "jellyfin,api" || fields[6] != "stale" {
+		t.Fatalf("second row = %q", lines[2])
+	}
+}
+
 func TestMountCmdCreateDoesNotMountAfterPrePublicationFailure(t *testing.T) {
 	server := newTestServer(t)
 	oldCheckMountCommand := checkMountCommand
@@ -865,6 +998,44 @@ func TestUmountCmdFuncRunsUnmountAndDeletesVolume(t *testing.T) {
 	}
 }
 
+func TestUmountCmdFuncRemovesCredentialsAndRequirements(t *testing.T) {
+	server := newTestServer(t)
+	credentials := mountCredentialsPath(server.cfg.RootDir, "media")
+	if err := writeMountCredentials(credentials, []byte("username=media\n")); err != nil {
+		t.Fatal(err)
+	}
+	server.cfg.DB.Set(&db.Data{Volumes: map[string]*db.Volume{
+		"media": {Name: "media", Src: "//nas/media", Path: "/mnt/media", Type: "cifs", Credentials: credentials, Services: "api"},
+	}})
+
+	oldUnmountVolume := unmountVolume
+	oldRemove := removeMountRequirements
+	t.Cleanup(func() {
+		unmountVolume = oldUnmountVolume
+		removeMountRequirements = oldRemove
+	})
+	var order []string
+	removeMountRequirements = func(vol db.Volume) error {
+		order = append(order, "requirements:"+vol.Name)
+		return nil
+	}
+	unmountVolume = func(_ *ttyExecer, vol db.Volume) error {
+		order = append(order, "umount:"+vol.Name)
+		return nil
+	}
+
+	execer := &ttyExecer{ctx: context.Background(), s: server, rw: &bytes.Buffer{}}
+	if err := execer.umountCmdFunc([]string{"media"}); err != nil {
+		t.Fatalf("umountCmdFunc: %v", err)
+	}
+	if want := []string{"requirements:media", "umount:media"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("umount order = %v, want %v", order, want)
+	}
+	if _, err := os.Stat(credentials); !os.IsNotExist(err) {
+		t.Fatalf("credentials stat = %v, want removed", err)
+	}
+}
+
 func TestUmountCmdFuncKeepsVolumeWhenUnmountFails(t *testing.T) {
 	server := newTestServer(t)
 	seedTTYOpsVolumes(t, server)
@@ -681,10 +681,62 @@ func (e *ttyExecer) statusCmdFunc(flags cli.StatusFlags) error {
 	if !render {
 		return nil
 	}
+	e.addServiceStatusStorage(statuses)
+	if flags.Wide {
+		e.addServiceStatusVMMetrics(statuses)
+	}
 	sortServiceStatuses(statuses)
 	return renderServiceStatuses(e.rw, flags.Format, statuses)
 }
 
+func (e *ttyExecer) addServiceStatusStorage(statuses []ServiceStatusData) {
+	ctx := e.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for i := range statuses {
+		sv, err := e.s.serviceView(statuses[i].ServiceName)
+		if err != nil {
+			continue
+		}
+		statuses[i].Storage = e.s.serviceStorage(ctx, sv, false)
+	}
+}
+
+// addServiceStatusVMMetrics samples all running VMs at once so the CPU
+// sampling delay is paid once per status call, not once per VM.
+func (e *ttyExecer) addServiceStatusVMMetrics(statuses []ServiceStatusData) {
+	ctx := e.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var targets []vmMetricsTarget
+	index := map[string]int{}
+	for i, status := range statuses {
+		if status.ServiceType != ServiceDataTypeVM || !serviceStatusRunning(status) {
+			continue
+		}
+		sv, err := e.s.serviceView(status.ServiceName)
+		if err != nil || !sv.VM().Valid() {
+			continue
+		}
+		targets = append(targets, vmMetricsTargetForView(status.ServiceName, sv.VM()))
+		index[status.ServiceName] = i
+	}
+	for name, metrics := range sampleVMMetrics(ctx, targets) {
+		statuses[index[name]].VMMetrics = metrics
+	}
+}
+
+func serviceStatusRunning(status ServiceStatusData) bool {
+	for _, component := range status.ComponentStatus {
+		if component.Status != ComponentStatusRunning {
+			return false
+		}
+	}
+	return len(status.ComponentStatus) != 0
+}
+
 func (e *ttyExecer) ensureServicesAvailable() error {
 	dv, err := e.s.cfg.DB.Get()
 	if err != nil {
@@ -1359,5 +1411,9 @@ func (s *dockerComposeServiceRunner) Logs(opts *svc.LogOptions) error {
 }
 
 func (s *dockerComposeServiceRunner) Remove() error {
-	return s.DockerComposeService.Remove()
+	removeErr := s.DockerComposeService.Remove()
+	if err := removeComposeMountUnit(s.DockerComposeService.Name); err != nil {
+		return errors.Join(removeErr, err)
+	}
+	return removeErr
 }
@@ -104,6 +104,17 @@ func (e *ttyExecer) serviceCmdFunc(args []string) error {
 			return err
 		}
 		return e.serviceGenerationsCmdFunc(rest[0], flags)
+	case "export":
+		flags, rest, err := cli.ParseServiceExport(argsWithServiceDefault(args[1:], e.sn))
+		if err != nil {
+			return err
+		}
+		return e.serviceExportCmdFunc(rest[0], flags)
+	case "import":
+		if len(args) > 1 {
+			return fmt.Errorf("unexpected service import args: %s", strings.Join(args[1:], " "))
+		}
+		return e.serviceImportCmdFunc()
 	default:
 		return fmt.Errorf("unknown service command %q", args[0])
 	}
@@ -141,7 +152,7 @@ func hasServiceCommandArg(args []string) bool {
 func (e *ttyExecer) serviceSetCmdFunc(flags cli.ServiceSetFlags) error {
 	changes := serviceSetChangesFromFlags(flags)
 	if !changes.any() {
-		return fmt.Errorf("service set requires --cron, --run-as, sandbox settings, network settings, --service-root, snapshot settings, or published ports")
+		return fmt.Errorf("service set requires --cron, --run-as, sandbox settings, network settings, --service-root, snapshot settings, --quota, --auto-update, --image-policy, or published ports")
 	}
 	if err := validateServiceSetMutationCombination(flags, changes); err != nil {
 		return err
@@ -193,6 +204,7 @@ func (e *ttyExecer) applyServiceSetNetworkChange(flags cli.ServiceSetFlags) erro
 }
 
 func (e *ttyExecer) applyServiceSetNonNetworkChanges(flags cli.ServiceSetFlags, changes serviceSetChanges) error {
+	rootMoved := changes.root
 	if changes.identity {
 		if err := e.validateServiceSetIdentityType(); err != nil {
 			return err
@@ -210,7 +222,42 @@ func (e *ttyExecer) applyServiceSetNonNetworkChanges(flags cli.ServiceSetFlags,
 		return err
 	}
 	if changes.snapshot {
-		return e.s.updateServiceSnapshotPolicy(e.sn, flags)
+		if err := e.s.updateServiceSnapshotPolicy(e.sn, flags); err != nil {
+			return err
+		}
+	}
+	if changes.autoUpdate {
+		if err := e.s.setServiceAutoUpdate(e.sn, flags.AutoUpdate, flags.AutoUpdateWindow); err != nil {
+			return err
+		}
+	}
+	if changes.imagePolicy {
+		if err := e.s.setServiceImagePolicy(e.sn, flags.ImagePolicy); err != nil {
+			return err
+		}
+	}
+	return e.applyServiceSetQuotaChange(flags, changes.quota, rootMoved)
+}
+
+// applyServiceSetQuotaChange applies --quota. A moved root starts without a
+// limit, so an existing quota is carried over to the new root.
+func (e *ttyExecer) applyServiceSetQuotaChange(flags cli.ServiceSetFlags, quotaChanged, rootMoved bool) error {
+	ctx := e.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if quotaChanged {
+		return e.s.setServiceQuota(ctx, e.sn, flags.Quota)
+	}
+	if !rootMoved {
+		return nil
+	}
+	sv, err := e.s.serviceView(e.sn)
+	if err != nil || sv.QuotaBytes() == 0 {
+		return err
+	}
+	if err := e.s.setServiceQuota(ctx, e.sn, sv.QuotaBytes()); err != nil {
+		_, _ = fmt.Fprintf(e.rw, "warning: service root moved, but its %s quota was not applied: %v\n", formatBytesInt(sv.QuotaBytes()), err)
 	}
 	return nil
 }
@@ -247,7 +294,7 @@ func validateServiceSetScheduleCombination(flags cli.ServiceSetFlags, changes se
 }
 
 func validateServiceSetNetworkCombination(changes serviceSetChanges) error {
-	if changes.network && (changes.root || changes.publish || changes.snapshot) {
+	if changes.network && (changes.root || changes.publish || changes.snapshot || changes.quota || changes.autoUpdate || changes.imagePolicy) {
 		return fmt.Errorf("network changes can only be combined with --run-as; apply other service settings with separate service set commands")
 	}
 	return nil
@@ -264,29 +311,35 @@ func validateServiceSetIdentityCombination(flags cli.ServiceSetFlags, changes se
 }
 
 type serviceSetChanges struct {
-	schedule bool
-	sandbox  bool
-	identity bool
-	network  bool
-	root     bool
-	publish  bool
-	snapshot bool
+	schedule    bool
+	sandbox     bool
+	identity    bool
+	network     bool
+	root        bool
+	publish     bool
+	snapshot    bool
+	quota       bool
+	autoUpdate  bool
+	imagePolicy bool
 }
 
 func serviceSetChangesFromFlags(flags cli.ServiceSetFlags) serviceSetChanges {
 	return serviceSetChanges{
-		schedule: flags.CronSet,
-		sandbox:  flags.Sandbox.HasChange(),
-		identity: flags.RunAsSet,
-		network:  flags.HasNetworkChange(),
-		root:     strings.TrimSpace(flags.ServiceRoot) != "" || flags.ZFS,
-		publish:  len(flags.Publish) != 0 || flags.PublishReset,
-		snapshot: flags.SnapshotChange,
+		schedule:    flags.CronSet,
+		sandbox:     flags.Sandbox.HasChange(),
+		identity:    flags.RunAsSet,
+		network:     flags.HasNetworkChange(),
+		root:        strings.TrimSpace(flags.ServiceRoot) != "" || flags.ZFS,
+		publish:     len(flags.Publish) != 0 || flags.PublishReset,
+		snapshot:    flags.SnapshotChange,
+		quota:       flags.QuotaSet,
+		autoUpdate:  flags.HasAutoUpdateChange(),
+		imagePolicy: flags.ImagePolicy != "",
 	}
 }
 
 func (c serviceSetChanges) any() bool {
-	return c.schedule || c.sandbox || c.identity || c.network || c.root || c.publish || c.snapshot
+	return c.schedule || c.sandbox || c.identity || c.network || c.root || c.publish || c.snapshot || c.quota || c.autoUpdate || c.imagePolicy
 }
 
 func (e *ttyExecer) validateServiceSetIdentityType() error {
@@ -562,6 +562,43 @@ func TestServiceSetSnapshotInheritClearsOverride(t *testing.T) {
 	}
 }
 
+func TestServiceSetQuotaAppliesWithSnapshotChange(t *testing.T) {
+	server := newTestServer(t)
+	name := "svc-quota"
+	if err := server.cfg.DB.Set(&db.Data{Services: map[string]*db.Service{
+		name: {Name: name, ServiceType: db.ServiceTypeSystemd, ServiceRootZFS: "tank/apps/svc-quota"},
+	}}); err != nil {
+		t.Fatalf("DB.Set: %v", err)
+	}
+	var zfsCalls [][]string
+	server.zfsRunner = func(_ context.Context, args ...string) (string, string, error) {
+		zfsCalls = append(zfsCalls, append([]string(nil), args...))
+		return "", "", nil
+	}
+	execer := &ttyExecer{s: server, sn: name, rw: &bytes.Buffer{}, isPty: false}
+	flags := cli.ServiceSetFlags{Snapshots: "off", SnapshotChange: true, Quota: 1 << 30, QuotaSet: true}
+	if err := execer.serviceSetCmdFunc(flags); err != nil {
+		t.Fatalf("serviceSetCmdFunc: %v", err)
+	}
+	want := [][]string{{"set", "refquota=1073741824", "tank/apps/svc-quota"}}
+	if !reflect.DeepEqual(zfsCalls, want) {
+		t.Fatalf("zfs calls = %q, want %q", zfsCalls, want)
+	}
+	sv, _ := server.serviceView(name)
+	if sv.QuotaBytes() != 1<<30 || sv.SnapshotPolicy().Enabled().Get() {
+		t.Fatalf("quota = %d snapshots = %v", sv.QuotaBytes(), sv.SnapshotPolicy().Enabled().Get())
+	}
+}
+
+func TestServiceSetQuotaRejectsNetworkCombination(t *testing.T) {
+	server := newTestServer(t)
+	execer := &ttyExecer{s: server, sn: "svc-quota", rw: &bytes.Buffer{}, isPty: false}
+	err := execer.serviceSetCmdFunc(cli.ServiceSetFlags{Net: "host", NetSet: true, Quota: 1 << 30, QuotaSet: true})
+	if err == nil || !strings.Contains(err.Error(), "network changes can only be combined with --run-as") {
+		t.Fatalf("serviceSetCmdFunc error = %v, want network combination error", err)
+	}
+}
+
 func TestServiceSetSnapshotInheritRejectsFieldFlagsBeforeRootMigration(t *testing.T) {
 	server := newTestServer(t)
 	oldRoot := filepath.Join(t.TempDir(), "old-root")
@@ -664,6 +664,8 @@ func TestShouldBypassPtyInputModes(t *testing.T) {
 		{name: "run", isPty: true, args: []string{"run"}, want: true},
 		{name: "copy", isPty: true, args: []string{"copy"}, want: true},
 		{name: "stage", isPty: true, args: []string{"stage"}, want: true},
+		{name: "service import", isPty: true, args: []string{"service", "import"}, want: true},
+		{name: "service export", isPty: true, args: []string{"service", "export"}},
 		{name: "legacy cron", isPty: true, args: []string{"cron"}},
 		{name: "vm images import stdin", isPty: true, args: []string{"vm", "images", "import", "foo/bar", "--stdin"}, want: true},
 		{name: "vm images import stdin flags before action", isPty: true, args: []string{"vm", "images", "--format=json", "import", "foo/bar", "--stdin"}, want: true},
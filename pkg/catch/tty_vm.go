@@ -12,6 +12,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/yeetrun/yeet/pkg/cli"
 	"github.com/yeetrun/yeet/pkg/db"
@@ -19,6 +20,11 @@ import (
 
 var errVMConsoleDetached = errors.New("VM console detached")
 
+const (
+	vmConsoleDetachKey    = 0x1d // Ctrl-]
+	vmConsoleInterruptKey = 0x03 // Ctrl-C
+)
+
 var runVMCmdFunc = func(e *ttyExecer, flags cli.RunFlags, payload string) error {
 	return e.runVM(flags, payload)
 }
@@ -50,8 +56,16 @@ func (e *ttyExecer) vmCmdFunc(args []string) error {
 		return e.vmKernelRemoteCmdFunc(args[1:])
 	case "memory":
 		return e.vmMemoryRemoteCmdFunc(args[1:])
+	case "hibernate":
+		return e.vmHibernateCmdFunc(args[1:])
+	case "resume":
+		return e.vmResumeCmdFunc(args[1:])
 	case "runtime":
 		return e.vmRuntimeRemoteCmdFunc(args[1:])
+	case "disk":
+		return e.vmDiskCmdFunc(args[1:])
+	case "migrate":
+		return e.vmMigrateCmdFunc(args[1:])
 	default:
 		return fmt.Errorf("unknown vm command %q", args[0])
 	}
@@ -172,10 +186,14 @@ func (e *ttyExecer) vmRuntimeUpgradeCmd(flags cli.VMRuntimeFlags, service string
 }
 
 func (e *ttyExecer) vmConsoleRemoteCmdFunc(args []string) error {
-	if len(args) != 0 {
-		return fmt.Errorf("vm console takes no remote arguments")
+	flags, rest, err := cli.ParseVMConsole(args)
+	if err != nil {
+		return err
 	}
-	return e.vmConsoleCmdFunc()
+	if len(rest) != 0 {
+		return fmt.Errorf("unexpected vm console args: %s", strings.Join(rest, " "))
+	}
+	return e.vmConsoleCmdFunc(flags)
 }
 
 func (e *ttyExecer) vmSetCmdFunc(args []string) error {
@@ -231,44 +249,49 @@ func (e *ttyExecer) vmImageCache() vmImageCache {
 	}
 }
 
-func (e *ttyExecer) vmConsoleCmdFunc() error {
-	sv, err := e.s.serviceView(e.sn)
+func (e *ttyExecer) vmConsoleCmdFunc(flags cli.VMConsoleFlags) error {
+	socketPath, logPath, err := e.vmConsolePaths()
 	if err != nil {
 		return err
 	}
-	if sv.ServiceType() != db.ServiceTypeVM {
-		return fmt.Errorf("service %q is type %q; vm console requires type \"vm\"", e.sn, sv.ServiceType())
-	}
-	vm := sv.VM()
-	if !vm.Valid() {
-		return fmt.Errorf("service %q has no VM console socket", e.sn)
-	}
-	socketPath := strings.TrimSpace(vm.Console().SocketPath)
-	if socketPath == "" {
-		return fmt.Errorf("service %q has no VM console socket", e.sn)
+	if err := e.printVMConsoleHistory(logPath, flags); err != nil {
+		return err
 	}
 	conn, err := net.Dial("unix", socketPath)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
+		if errors.Is(err, os.ErrNotExist) && !flags.Attach {
 			return vmConsoleJournalFunc(e)
 		}
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("VM %q is not running; its console cannot be attached", e.sn)
+		}
 		return fmt.Errorf("connect VM console socket %s: %w", socketPath, err)
 	}
 	defer func() { _ = conn.Close() }()
+	// Only one session types at a time. A plain console types when it can
+	// and watches while another session holds the console; --attach insists.
+	release, lockErr := e.s.lockVMConsoleWriter(e.sn)
+	writer := lockErr == nil
+	if writer {
+		defer release()
+	} else if flags.Attach {
+		return lockErr
+	}
 	if e.ctx != nil {
 		go func() {
 			<-e.ctx.Done()
 			_ = conn.Close()
 		}()
 	}
-	writef(e.rw, "Connected to VM console. Escape: press Enter, then type ~.\r\n")
-
-	go func() {
-		_ = copyVMConsoleInput(conn, e.rw)
-		if unixConn, ok := conn.(*net.UnixConn); ok {
-			_ = unixConn.CloseWrite()
-		}
-	}()
+	switch {
+	case flags.Attach:
+		writef(e.rw, "Attached to VM console. Detach: Ctrl-], or Enter then ~.\r\n")
+	case writer:
+		writef(e.rw, "Connected to VM console. Escape: press Enter, then type ~.\r\n")
+	default:
+		writef(e.rw, "VM console is attached in another session; watching read-only. Quit: Ctrl-] or Ctrl-C\r\n")
+	}
+	go e.forwardVMConsoleInput(conn, writer, flags.Attach)
 
 	_, err = io.Copy(e.rw, conn)
 	if isExpectedVMConsoleCopyError(err) {
@@ -277,9 +300,70 @@ func (e *ttyExecer) vmConsoleCmdFunc() error {
 	return err
 }
 
-func copyVMConsoleInput(dst io.Writer, src io.Reader) error {
+// vmConsolePaths returns the console socket and serial log of the VM.
+func (e *ttyExecer) vmConsolePaths() (socketPath, logPath string, _ error) {
+	sv, err := e.s.serviceView(e.sn)
+	if err != nil {
+		return "", "", err
+	}
+	if sv.ServiceType() != db.ServiceTypeVM {
+		return "", "", fmt.Errorf("service %q is type %q; vm console requires type \"vm\"", e.sn, sv.ServiceType())
+	}
+	vm := sv.VM()
+	if !vm.Valid() {
+		return "", "", fmt.Errorf("service %q has no VM console socket", e.sn)
+	}
+	socketPath = strings.TrimSpace(vm.Console().SocketPath)
+	if socketPath == "" {
+		return "", "", fmt.Errorf("service %q has no VM console socket", e.sn)
+	}
+	logPath = strings.TrimSpace(vm.Console().LogPath)
+	if logPath == "" {
+		logPath = vmConsoleLogPath(socketPath)
+	}
+	return socketPath, logPath, nil
+}
+
+// forwardVMConsoleInput sends keystrokes to the guest from the session that
+// holds the console. A watching session only looks for the quit keys and
+// never half-closes the connection, because the broker drops clients on
+// input EOF. Ctrl-] detaches only with --attach, so a plain console keeps
+// passing it to the guest as it always has.
+func (e *ttyExecer) forwardVMConsoleInput(conn net.Conn, writer, attach bool) {
+	if !writer {
+		if errors.Is(copyVMConsoleInput(io.Discard, e.rw, vmConsoleKeys{detach: true, interrupt: true}), errVMConsoleDetached) {
+			_ = conn.Close()
+		}
+		return
+	}
+	_ = copyVMConsoleInput(conn, e.rw, vmConsoleKeys{detach: attach})
+	if unixConn, ok := conn.(*net.UnixConn); ok {
+		_ = unixConn.CloseWrite()
+	}
+}
+
+// lockVMConsoleWriter makes sure only one session types into a VM console at
+// a time; watchers are not limited.
+func (s *Server) lockVMConsoleWriter(service string) (func(), error) {
+	if _, loaded := s.vmConsoleWriters.LoadOrStore(service, struct{}{}); loaded {
+		return nil, fmt.Errorf("VM %q console is already attached in another session; watch it with `yeet vm console %s`", service, service)
+	}
+	return func() { s.vmConsoleWriters.Delete(service) }, nil
+}
+
+// vmConsoleKeys selects the single keys that end a console session besides
+// Enter then ~.
+type vmConsoleKeys struct {
+	detach    bool // Ctrl-]
+	interrupt bool // Ctrl-C; a typing session passes it to the guest
+}
+
+// copyVMConsoleInput forwards src to dst until the user detaches with Enter
+// then ~, or one of keys.
+func copyVMConsoleInput(dst io.Writer, src io.Reader, keys vmConsoleKeys) error {
 	buf := make([]byte, 1)
 	state := newVMConsoleInputState(dst)
+	state.keys = keys
 	for {
 		n, readErr := src.Read(buf)
 		if n > 0 {
@@ -297,6 +381,7 @@ type vmConsoleInputState struct {
 	dst          io.Writer
 	atLineStart  bool
 	pendingTilde bool
+	keys         vmConsoleKeys
 }
 
 func newVMConsoleInputState(dst io.Writer) *vmConsoleInputState {
@@ -304,6 +389,9 @@ func newVMConsoleInputState(dst io.Writer) *vmConsoleInputState {
 }
 
 func (s *vmConsoleInputState) write(b byte) error {
+	if (s.keys.detach && b == vmConsoleDetachKey) || (s.keys.interrupt && b == vmConsoleInterruptKey) {
+		return errVMConsoleDetached
+	}
 	if s.pendingTilde {
 		if b == '.' {
 			return errVMConsoleDetached
@@ -346,6 +434,27 @@ func vmConsoleInputLineBreak(b byte) bool {
 	return b == '\n' || b == '\r'
 }
 
+// printVMConsoleHistory prints console output from the VM's serial log.
+// Guest lines are rewritten with CRLF because the client terminal is raw
+// while the console is open.
+func (e *ttyExecer) printVMConsoleHistory(logPath string, flags cli.VMConsoleFlags) error {
+	if flags.Lines == 0 && flags.Since == "" {
+		return nil
+	}
+	since, err := parseVMConsoleSince(flags.Since, time.Now())
+	if err != nil {
+		return err
+	}
+	lines, err := readVMConsoleLog(logPath, since, flags.Lines)
+	if err != nil {
+		return fmt.Errorf("read VM console history: %w", err)
+	}
+	for _, line := range lines {
+		writef(e.rw, "%s\r\n", strings.TrimRight(line, "\r"))
+	}
+	return nil
+}
+
 func (e *ttyExecer) vmConsoleJournalCmdFunc() error {
 	cmd := e.newCmd("journalctl", "-u", vmSystemdUnitName(e.sn), "-f", "-o", "cat", "-n", "200")
 	if err := cmd.Run(); err != nil {
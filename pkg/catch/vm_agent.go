@@ -25,9 +25,16 @@ const (
 )
 
 type vmAgentRequest struct {
-	Protocol  int    `json:"protocol"`
-	Type      string `json:"type"`
-	RequestID string `json:"request_id"`
+	Protocol  int      `json:"protocol"`
+	Type      string   `json:"type"`
+	RequestID string   `json:"request_id"`
+	Argv      []string `json:"argv,omitempty"`
+	TTY       bool     `json:"tty,omitempty"`
+	Rows      int      `json:"rows,omitempty"`
+	Cols      int      `json:"cols,omitempty"`
+	Term      string   `json:"term,omitempty"`
+	Path      string   `json:"path,omitempty"`
+	Mode      uint32   `json:"mode,omitempty"`
 }
 
 type vmAgentError struct {
@@ -41,9 +48,23 @@ type vmAgentResponse struct {
 	RequestID  string             `json:"request_id"`
 	Interfaces []vmAgentInterface `json:"interfaces,omitempty"`
 	SSHReady   *bool              `json:"ssh_ready,omitempty"`
+	UserData   string             `json:"user_data,omitempty"`
+	Size       int64              `json:"size,omitempty"`
+	Mode       uint32             `json:"mode,omitempty"`
+	Frozen     int                `json:"frozen,omitempty"`
+	Metrics    *vmAgentMetrics    `json:"metrics,omitempty"`
 	Error      *vmAgentError      `json:"error,omitempty"`
 }
 
+// vmAgentMetrics is the guest's view of its memory and root filesystem.
+type vmAgentMetrics struct {
+	MemoryTotalBytes     int64 `json:"memory_total_bytes"`
+	MemoryAvailableBytes int64 `json:"memory_available_bytes"`
+	RootTotalBytes       int64 `json:"root_total_bytes"`
+	RootAvailableBytes   int64 `json:"root_available_bytes"`
+	SSHSessions          int   `json:"ssh_sessions"`
+}
+
 type vmAgentInterface struct {
 	Name string   `json:"name"`
 	MAC  string   `json:"mac,omitempty"`
@@ -58,6 +79,10 @@ type vmAgentNetworkState struct {
 type vmAgentGuestReadyState struct {
 	Network  vmAgentNetworkState
 	SSHReady bool
+	// UserData is the first-boot user-data status: running, done, or
+	// "failed: <step>". It is empty when the guest has none or has not
+	// started it yet.
+	UserData string
 }
 
 func queryVMNetworkState(ctx context.Context, socketPath string) (vmAgentNetworkState, error) {
@@ -85,9 +110,39 @@ func queryVMGuestReady(ctx context.Context, socketPath string) (vmAgentGuestRead
 	return vmAgentGuestReadyState{
 		Network:  vmAgentNetworkState{Interfaces: usableVMAgentInterfaces(resp.Interfaces)},
 		SSHReady: *resp.SSHReady,
+		UserData: resp.UserData,
 	}, nil
 }
 
+// queryVMGuestMetrics fails on agents that predate guest_metrics; callers
+// fall back to what the host can see.
+func queryVMGuestMetrics(ctx context.Context, socketPath string) (vmAgentMetrics, error) {
+	resp, err := queryVMAgent(ctx, socketPath, "guest_metrics")
+	if err != nil {
+		return vmAgentMetrics{}, err
+	}
+	if resp.Error != nil {
+		return vmAgentMetrics{}, fmt.Errorf("VM agent error %s: %s", resp.Error.Code, resp.Error.Message)
+	}
+	if resp.Type != "guest_metrics" || resp.Metrics == nil {
+		return vmAgentMetrics{}, fmt.Errorf("VM agent returned no guest metrics")
+	}
+	return *resp.Metrics, nil
+}
+
+// requestVMGuestShutdown asks the guest to shut down cleanly. The agent
+// answers before the guest goes down; Firecracker exits once it has.
+func requestVMGuestShutdown(ctx context.Context, socketPath string) error {
+	resp, err := queryVMAgent(ctx, socketPath, "shutdown")
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("VM agent error %s: %s", resp.Error.Code, resp.Error.Message)
+	}
+	return nil
+}
+
 func queryVMAgent(ctx context.Context, socketPath string, requestType string) (vmAgentResponse, error) {
 	conn, r, cleanup, err := connectVMAgent(ctx, socketPath)
 	if err != nil {
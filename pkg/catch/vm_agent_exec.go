@@ -0,0 +1,411 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yeetrun/yeet/pkg/db"
+)
+
+// Protocol v2 stream requests take over the vsock connection after the JSON
+// request line. Both sides then exchange frames of the form
+// [kind u8][len u32 BE][payload]; see guest/yeet-agent/src/frame.rs.
+const (
+	vmAgentStreamProtocolVersion = 2
+
+	vmAgentFrameStdin  byte = 1
+	vmAgentFrameStdout byte = 2
+	vmAgentFrameStderr byte = 3
+	vmAgentFrameExit   byte = 4
+	vmAgentFrameResize byte = 5
+	vmAgentFrameEOF    byte = 6
+
+	vmAgentMaxFrameLen   = 1 << 20
+	vmAgentStreamChunk   = 32 << 10
+	vmAgentFreezeTimeout = 30 * time.Second
+)
+
+// vmAgentStream is a protocol v2 connection. Only the connect handshake is
+// bounded by vmAgentQueryTimeout; afterwards the stream lives until it is
+// closed or ctx is done.
+type vmAgentStream struct {
+	conn net.Conn
+	r    *bufio.Reader
+	wmu  sync.Mutex
+	stop func() bool
+}
+
+func openVMAgentStream(ctx context.Context, socketPath string, req vmAgentRequest) (*vmAgentStream, error) {
+	socketPath = strings.TrimSpace(socketPath)
+	if socketPath == "" {
+		return nil, fmt.Errorf("VM agent vsock socket path is empty")
+	}
+	dialCtx, cancel := context.WithTimeout(ctx, vmAgentQueryTimeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(dialCtx, "unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("connect VM agent vsock: %w", err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(vmAgentQueryTimeout))
+	r := bufio.NewReader(conn)
+	req.Protocol = vmAgentStreamProtocolVersion
+	req.RequestID = vmAgentRequestID
+	if err := sendVMAgentRequest(conn, r, req); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return &vmAgentStream{
+		conn: conn,
+		r:    r,
+		stop: context.AfterFunc(ctx, func() { _ = conn.Close() }),
+	}, nil
+}
+
+func (s *vmAgentStream) Close() error {
+	s.stop()
+	return s.conn.Close()
+}
+
+func (s *vmAgentStream) readResponse(requestType string) (vmAgentResponse, error) {
+	line, err := s.r.ReadBytes('\n')
+	if err != nil {
+		return vmAgentResponse{}, fmt.Errorf("read VM agent response: %w", err)
+	}
+	var resp vmAgentResponse
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return vmAgentResponse{}, fmt.Errorf("decode VM agent response: %w", err)
+	}
+	if err := validateVMAgentStreamResponse(resp, requestType); err != nil {
+		return vmAgentResponse{}, err
+	}
+	return resp, nil
+}
+
+func validateVMAgentStreamResponse(resp vmAgentResponse, requestType string) error {
+	if resp.Error != nil {
+		if resp.Error.Code == "protocol_mismatch" {
+			return fmt.Errorf("VM agent does not support %s; update yeet-agent in the guest: %s", requestType, resp.Error.Message)
+		}
+		return fmt.Errorf("VM agent error %s: %s", resp.Error.Code, resp.Error.Message)
+	}
+	if resp.Protocol != vmAgentStreamProtocolVersion {
+		return fmt.Errorf("VM agent protocol version = %d, want %d", resp.Protocol, vmAgentStreamProtocolVersion)
+	}
+	if resp.Type != requestType {
+		return fmt.Errorf("VM agent response type = %q, want %q", resp.Type, requestType)
+	}
+	if resp.RequestID != vmAgentRequestID {
+		return fmt.Errorf("VM agent response request_id = %q, want %q", resp.RequestID, vmAgentRequestID)
+	}
+	return nil
+}
+
+func (s *vmAgentStream) writeFrame(kind byte, payload []byte) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	var header [5]byte
+	header[0] = kind
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if _, err := s.conn.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := s.conn.Write(payload)
+	return err
+}
+
+// readFrame returns io.EOF only when the stream ends cleanly between frames.
+func (s *vmAgentStream) readFrame() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(s.r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, nil, fmt.Errorf("VM agent stream ended inside a frame")
+		}
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(header[1:])
+	if n > vmAgentMaxFrameLen {
+		return 0, nil, fmt.Errorf("VM agent frame of %d bytes exceeds %d", n, vmAgentMaxFrameLen)
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(s.r, payload); err != nil {
+		return 0, nil, fmt.Errorf("VM agent stream ended inside a frame: %w", err)
+	}
+	return header[0], payload, nil
+}
+
+// copyFrames sends r as stdin frames followed by an EOF frame. When r fails
+// no EOF frame is sent, so the guest discards what it received.
+func (s *vmAgentStream) copyFrames(r io.Reader) error {
+	buf := make([]byte, vmAgentStreamChunk)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if werr := s.writeFrame(vmAgentFrameStdin, buf[:n]); werr != nil {
+				return werr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return s.writeFrame(vmAgentFrameEOF, nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+type vmAgentExecRequest struct {
+	Argv []string
+	TTY  bool
+	Rows int
+	Cols int
+	Term string
+}
+
+// vmAgentExecSession is a command running in the guest through the agent.
+type vmAgentExecSession struct {
+	stream *vmAgentStream
+}
+
+// vmAgentExitError carries a guest command's non-zero exit code back to the
+// exec client instead of the generic failure code.
+type vmAgentExitError struct {
+	code int
+}
+
+func (e vmAgentExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+func startVMAgentExec(ctx context.Context, socketPath string, req vmAgentExecRequest) (*vmAgentExecSession, error) {
+	stream, err := openVMAgentStream(ctx, socketPath, vmAgentRequest{
+		Type: "exec",
+		Argv: req.Argv,
+		TTY:  req.TTY,
+		Rows: req.Rows,
+		Cols: req.Cols,
+		Term: req.Term,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stream.readResponse("exec"); err != nil {
+		_ = stream.Close()
+		return nil, err
+	}
+	return &vmAgentExecSession{stream: stream}, nil
+}
+
+func (s *vmAgentExecSession) Resize(cols, rows int) error {
+	var payload [4]byte
+	binary.BigEndian.PutUint16(payload[:2], clampUint16(rows))
+	binary.BigEndian.PutUint16(payload[2:], clampUint16(cols))
+	return s.stream.writeFrame(vmAgentFrameResize, payload[:])
+}
+
+func clampUint16(v int) uint16 {
+	return uint16(min(max(v, 0), math.MaxUint16))
+}
+
+// Wait forwards stdin to the guest and guest output to stdout and stderr until
+// the command exits, then returns its exit code.
+func (s *vmAgentExecSession) Wait(stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	defer func() { _ = s.stream.Close() }()
+	if stdin != nil {
+		go func() { _ = s.stream.copyFrames(stdin) }()
+	}
+	for {
+		kind, payload, err := s.stream.readFrame()
+		if errors.Is(err, io.EOF) {
+			return 0, fmt.Errorf("VM agent closed the exec stream before the command exited")
+		}
+		if err != nil {
+			return 0, fmt.Errorf("read VM agent exec output: %w", err)
+		}
+		switch kind {
+		case vmAgentFrameStdout:
+			_, err = stdout.Write(payload)
+		case vmAgentFrameStderr:
+			_, err = stderr.Write(payload)
+		case vmAgentFrameExit:
+			if len(payload) != 4 {
+				return 0, fmt.Errorf("VM agent exit frame has %d bytes, want 4", len(payload))
+			}
+			return int(int32(binary.BigEndian.Uint32(payload))), nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// pushVMAgentFile writes r to guestPath in the guest and returns the number of
+// bytes the agent stored. A mode of zero keeps the mode of an existing file.
+func pushVMAgentFile(ctx context.Context, socketPath, guestPath string, mode os.FileMode, r io.Reader) (int64, error) {
+	stream, err := openVMAgentStream(ctx, socketPath, vmAgentRequest{Type: "file_push", Path: guestPath, Mode: uint32(mode.Perm())})
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = stream.Close() }()
+	if err := stream.copyFrames(r); err != nil {
+		return 0, fmt.Errorf("send %s to VM agent: %w", guestPath, err)
+	}
+	resp, err := stream.readResponse("file_push")
+	if err != nil {
+		return 0, err
+	}
+	return resp.Size, nil
+}
+
+// pullVMAgentFile copies guestPath from the guest into w.
+func pullVMAgentFile(ctx context.Context, socketPath, guestPath string, w io.Writer) (int64, error) {
+	stream, err := openVMAgentStream(ctx, socketPath, vmAgentRequest{Type: "file_pull", Path: guestPath})
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = stream.Close() }()
+	if _, err := stream.readResponse("file_pull"); err != nil {
+		return 0, err
+	}
+	return stream.copyFileFrames(w, guestPath)
+}
+
+func (s *vmAgentStream) copyFileFrames(w io.Writer, guestPath string) (int64, error) {
+	var written int64
+	for {
+		kind, payload, err := s.readFrame()
+		if errors.Is(err, io.EOF) {
+			return written, fmt.Errorf("VM agent closed the stream before the end of %s", guestPath)
+		}
+		if err != nil {
+			return written, err
+		}
+		switch kind {
+		case vmAgentFrameStdout:
+			n, err := w.Write(payload)
+			written += int64(n)
+			if err != nil {
+				return written, err
+			}
+		case vmAgentFrameStderr:
+			return written, fmt.Errorf("VM agent failed to read %s: %s", guestPath, payload)
+		case vmAgentFrameEOF:
+			return written, nil
+		}
+	}
+}
+
+// freezeVMGuestFilesystems asks the agent to freeze the guest's block-backed
+// filesystems and returns how many it froze. The agent thaws on its own if no
+// thaw follows within a minute.
+func freezeVMGuestFilesystems(ctx context.Context, socketPath string) (int, error) {
+	return vmAgentFilesystemRequest(ctx, socketPath, "fs_freeze")
+}
+
+func thawVMGuestFilesystems(ctx context.Context, socketPath string) (int, error) {
+	return vmAgentFilesystemRequest(ctx, socketPath, "fs_thaw")
+}
+
+func vmAgentFilesystemRequest(ctx context.Context, socketPath, requestType string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, vmAgentFreezeTimeout)
+	defer cancel()
+	stream, err := openVMAgentStream(ctx, socketPath, vmAgentRequest{Type: requestType})
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = stream.Close() }()
+	resp, err := stream.readResponse(requestType)
+	if err != nil {
+		return 0, err
+	}
+	return resp.Frozen, nil
+}
+
+var (
+	startVMAgentExecFunc = startVMAgentExec
+	pushVMAgentFileFunc  = pushVMAgentFile
+	pullVMAgentFileFunc  = pullVMAgentFile
+)
+
+// vmAgentSocketPath returns the host side of the VM's vsock device, which
+// carries agent traffic without depending on guest networking or sshd.
+func (e *ttyExecer) vmAgentSocketPath() (string, error) {
+	sv, err := e.s.serviceView(e.sn)
+	if err != nil {
+		return "", err
+	}
+	if sv.ServiceType() != db.ServiceTypeVM || !sv.VM().Valid() {
+		return "", fmt.Errorf("service %q is not a VM service", e.sn)
+	}
+	socket := strings.TrimSpace(sv.VM().Sockets().VsockSocketPath)
+	if socket == "" {
+		return "", fmt.Errorf("VM %q has no guest agent vsock socket", e.sn)
+	}
+	return socket, nil
+}
+
+func (e *ttyExecer) vmAgentExecCmdFunc(args []string) error {
+	socket, err := e.vmAgentSocketPath()
+	if err != nil {
+		return err
+	}
+	session, err := startVMAgentExecFunc(e.ctx, socket, vmAgentExecRequest{
+		Argv: args,
+		TTY:  e.isPty,
+		Rows: e.ptyReq.Window.Height,
+		Cols: e.ptyReq.Window.Width,
+		Term: e.ptyReq.Term,
+	})
+	if err != nil {
+		return err
+	}
+	e.vmAgentExec.Store(session)
+	defer e.vmAgentExec.Store(nil)
+	code, err := session.Wait(e.rawRW, e.rawRW, e.rawRW)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return vmAgentExitError{code: code}
+	}
+	return nil
+}
+
+func (e *ttyExecer) vmAgentPushCmdFunc(args []string) error {
+	socket, err := e.vmAgentSocketPath()
+	if err != nil {
+		return err
+	}
+	mode, err := strconv.ParseUint(args[1], 8, 32)
+	if err != nil {
+		return fmt.Errorf("invalid file mode %q", args[1])
+	}
+	_, err = pushVMAgentFileFunc(e.ctx, socket, args[0], os.FileMode(mode), e.rawRW)
+	return err
+}
+
+func (e *ttyExecer) vmAgentPullCmdFunc(args []string) error {
+	socket, err := e.vmAgentSocketPath()
+	if err != nil {
+		return err
+	}
+	_, err = pullVMAgentFileFunc(e.ctx, socket, args[0], e.rawRW)
+	return err
+}
@@ -0,0 +1,274 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+)
+
+type fakeVMAgentStreamConn struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c fakeVMAgentStreamConn) respond(resp vmAgentResponse) {
+	c.t.Helper()
+	if err := json.NewEncoder(c.conn).Encode(resp); err != nil {
+		c.t.Errorf("write response: %v", err)
+	}
+}
+
+func (c fakeVMAgentStreamConn) frame(kind byte, payload []byte) {
+	c.t.Helper()
+	var header [5]byte
+	header[0] = kind
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if _, err := c.conn.Write(append(header[:], payload...)); err != nil {
+		c.t.Errorf("write frame: %v", err)
+	}
+}
+
+func (c fakeVMAgentStreamConn) readFrame() (byte, []byte, bool) {
+	var header [5]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return 0, nil, false
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(header[1:]))
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return 0, nil, false
+	}
+	return header[0], payload, true
+}
+
+// startFakeVMAgentStream serves one protocol v2 connection with handle after
+// the vsock CONNECT handshake and request line.
+func startFakeVMAgentStream(t *testing.T, handle func(vmAgentRequest, fakeVMAgentStreamConn)) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("/tmp", "yeet-vsock-*")
+	if err != nil {
+		t.Fatalf("temp dir: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+	socketPath := filepath.Join(dir, "vsock.sock")
+	ln, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("listen unix: %v", err)
+	}
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		_ = ln.Close()
+		<-done
+	})
+	go func() {
+		defer close(done)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		if line, err := r.ReadString('\n'); err != nil || strings.TrimSpace(line) != "CONNECT 7788" {
+			t.Errorf("connect line = %q, %v", line, err)
+			return
+		}
+		_, _ = conn.Write([]byte("OK 1024\n"))
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			t.Errorf("read request: %v", err)
+			return
+		}
+		var req vmAgentRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			t.Errorf("decode request: %v", err)
+			return
+		}
+		handle(req, fakeVMAgentStreamConn{t: t, conn: conn, r: r})
+	}()
+	return socketPath
+}
+
+func vmAgentStreamOK(req vmAgentRequest) vmAgentResponse {
+	return vmAgentResponse{Protocol: req.Protocol, Type: req.Type, RequestID: req.RequestID}
+}
+
+func TestVMAgentExecStreamsOutputAndExitCode(t *testing.T) {
+	resized := make(chan []byte, 1)
+	socket := startFakeVMAgentStream(t, func(req vmAgentRequest, c fakeVMAgentStreamConn) {
+		if req.Protocol != 2 || req.Type != "exec" || !req.TTY || req.Rows != 24 || req.Cols != 80 || req.Term != "xterm" {
+			t.Errorf("exec request = %#v", req)
+		}
+		if got := strings.Join(req.Argv, " "); got != "uname -a" {
+			t.Errorf("argv = %q", got)
+		}
+		c.respond(vmAgentStreamOK(req))
+		kind, payload, ok := c.readFrame()
+		if !ok || kind != vmAgentFrameResize {
+			t.Errorf("first frame = %d %v", kind, ok)
+		}
+		resized <- payload
+		c.frame(vmAgentFrameStdout, []byte("Linux devbox\n"))
+		c.frame(vmAgentFrameStderr, []byte("warning\n"))
+		c.frame(vmAgentFrameExit, []byte{0, 0, 0, 7})
+	})
+
+	session, err := startVMAgentExec(context.Background(), socket, vmAgentExecRequest{
+		Argv: []string{"uname", "-a"}, TTY: true, Rows: 24, Cols: 80, Term: "xterm",
+	})
+	if err != nil {
+		t.Fatalf("startVMAgentExec: %v", err)
+	}
+	if err := session.Resize(120, 40); err != nil {
+		t.Fatalf("Resize: %v", err)
+	}
+	var stdout, stderr bytes.Buffer
+	code, err := session.Wait(nil, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if code != 7 || stdout.String() != "Linux devbox\n" || stderr.String() != "warning\n" {
+		t.Fatalf("code=%d stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+	if got := <-resized; !bytes.Equal(got, []byte{0, 40, 0, 120}) {
+		t.Fatalf("resize payload = %v, want rows 40 cols 120", got)
+	}
+}
+
+func TestVMAgentExecExplainsProtocolMismatch(t *testing.T) {
+	socket := startFakeVMAgentStream(t, func(req vmAgentRequest, c fakeVMAgentStreamConn) {
+		resp := vmAgentStreamOK(req)
+		resp.Protocol = 1
+		resp.Error = &vmAgentError{Code: "protocol_mismatch", Message: "unsupported protocol 2"}
+		c.respond(resp)
+	})
+
+	_, err := startVMAgentExec(context.Background(), socket, vmAgentExecRequest{})
+	if err == nil || !strings.Contains(err.Error(), "update yeet-agent in the guest") {
+		t.Fatalf("err = %v, want update hint", err)
+	}
+}
+
+func TestPushVMAgentFileSendsFramesThenReadsSize(t *testing.T) {
+	received := make(chan string, 1)
+	socket := startFakeVMAgentStream(t, func(req vmAgentRequest, c fakeVMAgentStreamConn) {
+		if req.Type != "file_push" || req.Path != "/etc/motd" || req.Mode != 0o640 {
+			t.Errorf("push request = %#v", req)
+		}
+		var body bytes.Buffer
+		for {
+			kind, payload, ok := c.readFrame()
+			if !ok {
+				t.Errorf("stream ended before eof frame")
+				return
+			}
+			if kind == vmAgentFrameEOF {
+				break
+			}
+			body.Write(payload)
+		}
+		received <- body.String()
+		resp := vmAgentStreamOK(req)
+		resp.Size = int64(body.Len())
+		c.respond(resp)
+	})
+
+	n, err := pushVMAgentFile(context.Background(), socket, "/etc/motd", 0o640, strings.NewReader("welcome\n"))
+	if err != nil {
+		t.Fatalf("pushVMAgentFile: %v", err)
+	}
+	if n != 8 || <-received != "welcome\n" {
+		t.Fatalf("size = %d", n)
+	}
+}
+
+func TestPullVMAgentFileCopiesFramesUntilEOF(t *testing.T) {
+	socket := startFakeVMAgentStream(t, func(req vmAgentRequest, c fakeVMAgentStreamConn) {
+		if req.Type != "file_pull" || req.Path != "/var/log/app.log" {
+			t.Errorf("pull request = %#v", req)
+		}
+		resp := vmAgentStreamOK(req)
+		resp.Size, resp.Mode = 10, 0o644
+		c.respond(resp)
+		c.frame(vmAgentFrameStdout, []byte("line one\n"))
+		c.frame(vmAgentFrameStdout, []byte("2"))
+		c.frame(vmAgentFrameEOF, nil)
+	})
+
+	var out bytes.Buffer
+	n, err := pullVMAgentFile(context.Background(), socket, "/var/log/app.log", &out)
+	if err != nil {
+		t.Fatalf("pullVMAgentFile: %v", err)
+	}
+	if n != 10 || out.String() != "line one\n2" {
+		t.Fatalf("n=%d out=%q", n, out.String())
+	}
+}
+
+func TestPullVMAgentFileReportsGuestReadErrors(t *testing.T) {
+	socket := startFakeVMAgentStream(t, func(req vmAgentRequest, c fakeVMAgentStreamConn) {
+		c.respond(vmAgentStreamOK(req))
+		c.frame(vmAgentFrameStdout, []byte("part"))
+		c.frame(vmAgentFrameStderr, []byte("Input/output error"))
+	})
+
+	_, err := pullVMAgentFile(context.Background(), socket, "/data/blob", io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "Input/output error") {
+		t.Fatalf("err = %v, want guest read error", err)
+	}
+}
+
+func TestFreezeVMGuestFilesystemsReturnsFrozenCount(t *testing.T) {
+	socket := startFakeVMAgentStream(t, func(req vmAgentRequest, c fakeVMAgentStreamConn) {
+		if req.Type != "fs_freeze" {
+			t.Errorf("request type = %q", req.Type)
+		}
+		resp := vmAgentStreamOK(req)
+		resp.Frozen = 2
+		c.respond(resp)
+	})
+
+	n, err := freezeVMGuestFilesystems(context.Background(), socket)
+	if err != nil || n != 2 {
+		t.Fatalf("freeze = %d, %v", n, err)
+	}
+}
+
+func TestNormalizeExecRequestValidatesVMAgentFileTargets(t *testing.T) {
+	push, err := normalizeExecRequest(catchrpc.ExecRequest{
+		Target:  catchrpc.ExecTargetVMAgentPush,
+		Service: "devbox",
+		Args:    []string{"/etc/motd", "644"},
+		TTY:     true,
+	})
+	if err != nil {
+		t.Fatalf("normalize push: %v", err)
+	}
+	if push.TTY {
+		t.Fatalf("push request kept TTY")
+	}
+	for _, req := range []catchrpc.ExecRequest{
+		{Target: catchrpc.ExecTargetVMAgentPush, Service: "devbox", Args: []string{"/etc/motd"}},
+		{Target: catchrpc.ExecTargetVMAgentPull, Service: "devbox"},
+		{Target: catchrpc.ExecTargetVMAgentPull, Args: []string{"/etc/motd"}},
+	} {
+		if _, err := normalizeExecRequest(req); err == nil {
+			t.Fatalf("normalizeExecRequest(%#v) succeeded", req)
+		}
+	}
+}
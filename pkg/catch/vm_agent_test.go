@@ -173,3 +173,20 @@ func TestUsableVMAgentInterfacesRejectsUnroutableAddresses(t *testing.T) {
 		t.Fatalf("usable interfaces = %#v, want only routable IPv4", got)
 	}
 }
+
+func TestQueryVMGuestReadyReportsUserDataStatus(t *testing.T) {
+	socketPath, requests := startFakeVsockAgentWithRequests(t, `{"protocol":1,"type":"guest_ready","request_id":"test","interfaces":[{"name":"eth0","up":true,"ips":["10.0.4.183"]}],"ssh_ready":true,"user_data":"failed: runcmd 2"}`)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	got, err := queryVMGuestReady(ctx, socketPath)
+	if err != nil {
+		t.Fatalf("queryVMGuestReady: %v", err)
+	}
+	if req := <-requests; req.Type != "guest_ready" {
+		t.Fatalf("agent request type = %q, want guest_ready", req.Type)
+	}
+	if !got.SSHReady || got.UserData != "failed: runcmd 2" {
+		t.Fatalf("guest ready = %#v, want ssh ready with user-data failure", got)
+	}
+}
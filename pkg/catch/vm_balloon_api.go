@@ -27,6 +27,7 @@ type vmBalloonStats struct {
 	ActualBytes          int64
 	FreeMemoryBytes      int64
 	AvailableMemoryBytes int64
+	TotalMemoryBytes     int64
 }
 
 type firecrackerBalloonAPI struct{}
@@ -42,6 +43,7 @@ type firecrackerBalloonStatsResponse struct {
 	ActualPages     int64 `json:"actual_pages"`
 	FreeMemory      int64 `json:"free_memory"`
 	AvailableMemory int64 `json:"available_memory"`
+	TotalMemory     int64 `json:"total_memory"`
 }
 
 func (firecrackerBalloonAPI) SetTarget(ctx context.Context, socket string, targetBytes int64) error {
@@ -62,6 +64,7 @@ func (firecrackerBalloonAPI) Stats(ctx context.Context, socket string) (vmBalloo
 		ActualBytes:          raw.ActualPages * firecrackerBalloonPageSize,
 		FreeMemoryBytes:      raw.FreeMemory,
 		AvailableMemoryBytes: raw.AvailableMemory,
+		TotalMemoryBytes:     raw.TotalMemory,
 	}, nil
 }
 
@@ -22,9 +22,14 @@ const (
 )
 
 var (
-	vmGuestBaseIDPattern = regexp.MustCompile(`^guest-(ubuntu|nixos)-([0-9]+\.[0-9]+)-amd64-v[1-9][0-9]*$`)
+	vmGuestBaseIDPattern = regexp.MustCompile(`^guest-(ubuntu|nixos|debian|fedora)-([0-9]+(?:\.[0-9]+)?)-amd64-v[1-9][0-9]*$`)
 	vmKernelIDPattern    = regexp.MustCompile(`^kernel-linux-([0-9]+\.[0-9]+(?:\.[0-9]+)*)-yeet-v([1-9][0-9]*)$`)
-	vmOSVersionPattern   = regexp.MustCompile(`^[0-9]+\.[0-9]+$`)
+	vmOSVersionPattern   = regexp.MustCompile(`^[0-9]+(?:\.[0-9]+)?$`)
+
+	// vmGuestBaseRequiredFamilies must have channels in every guest-base
+	// catalog; vmGuestBaseOptionalFamilies may appear once they are published.
+	vmGuestBaseRequiredFamilies = []string{"nixos-26.05-amd64", "ubuntu-26.04-amd64"}
+	vmGuestBaseOptionalFamilies = []string{"debian-13-amd64", "fedora-44-amd64"}
 )
 
 type vmGuestBaseCatalogRef struct {
@@ -224,13 +229,26 @@ func (c vmGuestBaseCatalog) validate(requireTrustedURL bool) error {
 	if err != nil {
 		return err
 	}
-	wantFamilies := []string{"nixos-26.05-amd64", "ubuntu-26.04-amd64"}
-	if !sameVMComponentKeys(c.Channels, wantFamilies) {
-		return fmt.Errorf("VM guest-base catalog channels must contain exactly %s", strings.Join(wantFamilies, ", "))
+	if err := c.validateFamilies(); err != nil {
+		return err
 	}
 	return c.validateChannels(byID)
 }
 
+func (c vmGuestBaseCatalog) validateFamilies() error {
+	for _, family := range vmGuestBaseRequiredFamilies {
+		if _, ok := c.Channels[family]; !ok {
+			return fmt.Errorf("VM guest-base catalog channels must contain %s", strings.Join(vmGuestBaseRequiredFamilies, ", "))
+		}
+	}
+	for family := range c.Channels {
+		if !slices.Contains(vmGuestBaseRequiredFamilies, family) && !slices.Contains(vmGuestBaseOptionalFamilies, family) {
+			return fmt.Errorf("VM guest-base catalog has unknown channel family %q", family)
+		}
+	}
+	return nil
+}
+
 func (c vmGuestBaseCatalog) validatedRefs(requireTrustedURL bool) (map[string]vmGuestBaseCatalogRef, error) {
 	byID := make(map[string]vmGuestBaseCatalogRef, len(c.GuestBases))
 	for _, ref := range c.GuestBases {
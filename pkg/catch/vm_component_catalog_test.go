@@ -46,6 +46,45 @@ func TestVMComponentCatalogStrictDecodeAndImmutableChannelResolution(t *testing.
 	}
 }
 
+func TestVMGuestBaseCatalogAcceptsDebianAndFedoraFamilies(t *testing.T) {
+	var guest map[string]any
+	if err := json.Unmarshal(vmGuestBaseCatalogFixture(t), &guest); err != nil {
+		t.Fatalf("decode fixture: %v", err)
+	}
+	sha := strings.Repeat("c", 64)
+	guest["guest_bases"] = append(guest["guest_bases"].([]any), map[string]any{
+		"guest_base_id":   "guest-debian-13-amd64-v1",
+		"os":              "debian",
+		"os_version":      "13",
+		"architecture":    "amd64",
+		"manifest_url":    "https://github.com/yeetrun/yeet-vm-images/releases/download/guest-debian-13-amd64-v1/guest-manifest.json",
+		"manifest_sha256": sha,
+	})
+	channels := guest["channels"].(map[string]any)
+	channels["debian-13-amd64"] = map[string]any{"stable": map[string]any{"guest_base_id": "guest-debian-13-amd64-v1", "manifest_sha256": sha}, "candidate": nil}
+	channels["fedora-44-amd64"] = map[string]any{"stable": nil, "candidate": nil}
+	raw, err := json.Marshal(guest)
+	if err != nil {
+		t.Fatalf("encode fixture: %v", err)
+	}
+	catalog, err := decodeVMGuestBaseCatalog(raw, true)
+	if err != nil {
+		t.Fatalf("decode guest catalog: %v", err)
+	}
+	if ref, ok := catalog.GuestBaseForChannel("debian-13-amd64", "stable"); !ok || ref.OSVersion != "13" {
+		t.Fatalf("debian stable = %#v ok=%v", ref, ok)
+	}
+
+	channels["arch-2026.10-amd64"] = map[string]any{"stable": nil, "candidate": nil}
+	raw, err = json.Marshal(guest)
+	if err != nil {
+		t.Fatalf("encode fixture: %v", err)
+	}
+	if _, err := decodeVMGuestBaseCatalog(raw, true); err == nil || !strings.Contains(err.Error(), "unknown channel family") {
+		t.Fatalf("decode unknown family error = %v", err)
+	}
+}
+
 func TestVMComponentCatalogRejectsUnknownAndConflictingFields(t *testing.T) {
 	var guest map[string]any
 	if err := json.Unmarshal(vmGuestBaseCatalogFixture(t), &guest); err != nil {
@@ -0,0 +1,152 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// vmConsoleLogName sits next to the console socket in the VM run
+	// directory; VMConsoleConfig.LogPath records it.
+	vmConsoleLogName = "serial.log"
+	// vmConsoleLogMaxBytes caps the log; one rotated copy is kept, so
+	// history covers at most twice this much output.
+	vmConsoleLogMaxBytes = 8 << 20
+	// vmConsoleLogTimeFormat stamps each line in UTC. It has a fixed width
+	// so the text always starts at the same offset.
+	vmConsoleLogTimeFormat = "2006-01-02T15:04:05.000000Z"
+)
+
+// vmConsoleLogPath returns the serial log for the console at socketPath.
+func vmConsoleLogPath(socketPath string) string {
+	return filepath.Join(filepath.Dir(socketPath), vmConsoleLogName)
+}
+
+// vmConsoleLog appends guest console output to the serial log, stamping each
+// line with the time its first byte arrived. The file is opened per write so
+// a restarted launch attempt or a removed run directory needs no cleanup.
+type vmConsoleLog struct {
+	path string
+	now  func() time.Time
+
+	mu          sync.Mutex
+	atLineStart bool
+}
+
+func newVMConsoleLog(path string) *vmConsoleLog {
+	return &vmConsoleLog{path: path, now: time.Now, atLineStart: true}
+}
+
+func (l *vmConsoleLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var b strings.Builder
+	stamp := l.now().UTC().Format(vmConsoleLogTimeFormat)
+	for _, c := range p {
+		if l.atLineStart {
+			b.WriteString(stamp)
+			b.WriteByte(' ')
+		}
+		b.WriteByte(c)
+		l.atLineStart = c == '\n'
+	}
+	if err := l.rotateIfFull(); err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	_, writeErr := f.WriteString(b.String())
+	if err := errors.Join(writeErr, f.Close()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (l *vmConsoleLog) rotateIfFull() error {
+	info, err := os.Stat(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Size() < vmConsoleLogMaxBytes {
+		return nil
+	}
+	return os.Rename(l.path, l.path+".1")
+}
+
+// readVMConsoleLog returns the last lines of console output logged at or
+// after since, without their stamps. lines <= 0 means no limit.
+func readVMConsoleLog(path string, since time.Time, lines int) ([]string, error) {
+	var out []string
+	for _, p := range []string{path + ".1", path} {
+		f, err := os.Open(p)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sc := bufio.NewScanner(f)
+		sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+		for sc.Scan() {
+			at, text := splitVMConsoleLogLine(sc.Text())
+			if !since.IsZero() && (at.IsZero() || at.Before(since)) {
+				continue
+			}
+			out = append(out, text)
+			if lines > 0 && len(out) > lines {
+				out = out[1:]
+			}
+		}
+		err = errors.Join(sc.Err(), f.Close())
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", p, err)
+		}
+	}
+	return out, nil
+}
+
+func splitVMConsoleLogLine(line string) (time.Time, string) {
+	n := len(vmConsoleLogTimeFormat)
+	if len(line) <= n || line[n] != ' ' {
+		return time.Time{}, line
+	}
+	at, err := time.Parse(vmConsoleLogTimeFormat, line[:n])
+	if err != nil {
+		return time.Time{}, line
+	}
+	return at, line[n+1:]
+}
+
+// parseVMConsoleSince accepts a duration ago (10m) or an absolute time.
+func parseVMConsoleSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("--since duration must be positive")
+		}
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", time.DateTime, time.DateOnly} {
+		if at, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return at, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q; use a duration like 10m or a time like 2006-01-02T15:04:05", value)
+}
@@ -36,6 +36,10 @@ type VMConsoleProxyConfig struct {
 	ServiceRoot          string
 	DiskPath             string
 	OnGuestReboot        func(context.Context, VMConsoleProxyConfig) error
+
+	// RestoreHibernation starts Firecracker without its config file and
+	// loads the pending hibernation snapshot instead of cold booting.
+	RestoreHibernation bool
 }
 
 type vmConsoleProcessConstructor func(context.Context, VMConsoleProxyConfig) (*exec.Cmd, func(), error)
@@ -69,6 +73,7 @@ func runVMConsoleProxyWithProcessConstructor(ctx context.Context, cfg VMConsoleP
 		return runVMRuntimeConsoleProxy(ctx, cfg, listener, constructProcess)
 	}
 
+	cfg.RestoreHibernation = vmHibernationPending(cfg.ServiceRoot)
 	cmd, cleanupProcess, err := constructProcess(ctx, cfg)
 	if err != nil {
 		return err
@@ -81,16 +86,32 @@ func runVMConsoleProxyWithProcessConstructor(ctx context.Context, cfg VMConsoleP
 	defer func() { _ = console.Close() }()
 
 	guestStopped := make(chan vmGuestStopKind, 1)
-	broker := newVMConsoleBroker(console, os.Stdout, guestStopped)
+	broker := newVMConsoleBroker(console, vmConsoleOutput(cfg.ConsoleSocket), guestStopped)
 	go broker.accept(listener)
 	go broker.copyOutput()
+	if err := restoreVMHibernation(ctx, cfg); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return err
+	}
 	err = waitVMConsoleProcess(cmd, guestStopped)
+	if errors.Is(err, ErrVMGuestReboot) && vmRunGuestShutdownPending.Load() {
+		// The guest rebooted because the stop asked it to shut down.
+		return nil
+	}
 	if errors.Is(err, ErrVMGuestReboot) {
 		runVMGuestRebootHook(ctx, cfg)
 	}
 	return err
 }
 
+// vmConsoleOutput sends guest console output to the unit's journal and the
+// serial log `vm console --since/--lines` reads. The journal comes first so
+// a failing log write does not cost it output.
+func vmConsoleOutput(consoleSocket string) io.Writer {
+	return io.MultiWriter(os.Stdout, newVMConsoleLog(vmConsoleLogPath(consoleSocket)))
+}
+
 func runVMGuestRebootHook(ctx context.Context, cfg VMConsoleProxyConfig) {
 	if cfg.OnGuestReboot == nil {
 		return
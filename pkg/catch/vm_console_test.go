@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/yeetrun/yeet/pkg/catchrpc"
+	"github.com/yeetrun/yeet/pkg/cli"
 	"github.com/yeetrun/yeet/pkg/db"
 )
 
@@ -60,7 +61,7 @@ func TestVMConsoleConnectsToUnixSocket(t *testing.T) {
 		rw:       readWriter{Reader: strings.NewReader(""), Writer: &out},
 		progress: catchrpc.ProgressQuiet,
 	}
-	if err := execer.vmConsoleCmdFunc(); err != nil {
+	if err := execer.vmConsoleCmdFunc(cli.VMConsoleFlags{}); err != nil {
 		t.Fatalf("vmConsoleCmdFunc: %v", err)
 	}
 	if got := out.String(); !strings.Contains(got, "login: ") {
@@ -103,7 +104,7 @@ func TestVMConsoleFallsBackToJournalWhenSocketMissing(t *testing.T) {
 		rw:       &bytes.Buffer{},
 		progress: catchrpc.ProgressQuiet,
 	}
-	if err := execer.vmConsoleCmdFunc(); err != nil {
+	if err := execer.vmConsoleCmdFunc(cli.VMConsoleFlags{}); err != nil {
 		t.Fatalf("vmConsoleCmdFunc: %v", err)
 	}
 	if !called {
@@ -143,7 +144,7 @@ func TestVMConsoleStopsWhenContextCanceled(t *testing.T) {
 	rw := readWriter{Reader: inputReader, Writer: io.Discard}
 	done := make(chan error, 1)
 	go func() {
-		done <- (&ttyExecer{ctx: ctx, s: server, sn: "devbox", rw: rw}).vmConsoleCmdFunc()
+		done <- (&ttyExecer{ctx: ctx, s: server, sn: "devbox", rw: rw}).vmConsoleCmdFunc(cli.VMConsoleFlags{Attach: true})
 	}()
 
 	conn := <-accepted
@@ -161,7 +162,7 @@ func TestVMConsoleStopsWhenContextCanceled(t *testing.T) {
 
 func TestCopyVMConsoleInputStopsOnEscapeSequence(t *testing.T) {
 	var out bytes.Buffer
-	err := copyVMConsoleInput(&out, strings.NewReader("echo hi\n~.\nignored"))
+	err := copyVMConsoleInput(&out, strings.NewReader("echo hi\n~.\nignored"), vmConsoleKeys{})
 	if !errors.Is(err, errVMConsoleDetached) {
 		t.Fatalf("copyVMConsoleInput error = %v, want detach", err)
 	}
@@ -170,6 +171,159 @@ func TestCopyVMConsoleInputStopsOnEscapeSequence(t *testing.T) {
 	}
 }
 
+func TestCopyVMConsoleInputDetachKeys(t *testing.T) {
+	var out bytes.Buffer
+	err := copyVMConsoleInput(&out, strings.NewReader("top\x03q\x1dignored"), vmConsoleKeys{detach: true})
+	if !errors.Is(err, errVMConsoleDetached) {
+		t.Fatalf("copyVMConsoleInput error = %v, want detach on Ctrl-]", err)
+	}
+	if out.String() != "top\x03q" {
+		t.Fatalf("copied input = %q, want Ctrl-C passed to an attached guest", out.String())
+	}
+	if err := copyVMConsoleInput(io.Discard, strings.NewReader("ls\x03"), vmConsoleKeys{detach: true, interrupt: true}); !errors.Is(err, errVMConsoleDetached) {
+		t.Fatalf("read-only copy error = %v, want quit on Ctrl-C", err)
+	}
+	out.Reset()
+	if err := copyVMConsoleInput(&out, strings.NewReader("\x1d\x03"), vmConsoleKeys{}); err != nil {
+		t.Fatalf("plain copy error = %v, want keys passed through", err)
+	}
+	if out.String() != "\x1d\x03" {
+		t.Fatalf("copied input = %q, want Ctrl-] and Ctrl-C passed to the guest", out.String())
+	}
+}
+
+func TestVMConsoleWatchDoesNotForwardInput(t *testing.T) {
+	socketPath := filepath.Join(shortUnixSocketDirForTest(t), "console.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("listen unix: %v", err)
+	}
+	defer listener.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		data, _ := io.ReadAll(conn)
+		received <- string(data)
+	}()
+
+	server := newTestServer(t)
+	if _, _, err := server.cfg.DB.MutateService("devbox", func(_ *db.Data, s *db.Service) error {
+		s.ServiceType = db.ServiceTypeVM
+		s.VM = &db.VMConfig{Console: db.VMConsoleConfig{SocketPath: socketPath}}
+		return nil
+	}); err != nil {
+		t.Fatalf("seed VM service: %v", err)
+	}
+	release, err := server.lockVMConsoleWriter("devbox")
+	if err != nil {
+		t.Fatalf("lock console: %v", err)
+	}
+	defer release()
+	if err := (&ttyExecer{ctx: context.Background(), s: server, sn: "devbox", rw: &bytes.Buffer{}}).vmConsoleCmdFunc(cli.VMConsoleFlags{Attach: true}); err == nil || !strings.Contains(err.Error(), "already attached") {
+		t.Fatalf("second --attach error = %v, want already attached", err)
+	}
+	var out bytes.Buffer
+	execer := &ttyExecer{ctx: context.Background(), s: server, sn: "devbox", rw: readWriter{Reader: strings.NewReader("reboot\n\x1d"), Writer: &out}}
+	if err := execer.vmConsoleCmdFunc(cli.VMConsoleFlags{}); err != nil {
+		t.Fatalf("vmConsoleCmdFunc: %v", err)
+	}
+	if got := <-received; got != "" {
+		t.Fatalf("guest received %q from a read-only session", got)
+	}
+	if !strings.Contains(out.String(), "read-only") {
+		t.Fatalf("output = %q, want read-only hint", out.String())
+	}
+}
+
+func TestLockVMConsoleWriterAllowsOneWriter(t *testing.T) {
+	server := newTestServer(t)
+	release, err := server.lockVMConsoleWriter("devbox")
+	if err != nil {
+		t.Fatalf("first lock: %v", err)
+	}
+	if _, err := server.lockVMConsoleWriter("devbox"); err == nil || !strings.Contains(err.Error(), "already attached") {
+		t.Fatalf("second lock error = %v, want already attached", err)
+	}
+	otherRelease, err := server.lockVMConsoleWriter("other")
+	if err != nil {
+		t.Fatalf("other VM lock: %v", err)
+	}
+	otherRelease()
+	release()
+	release, err = server.lockVMConsoleWriter("devbox")
+	if err != nil {
+		t.Fatalf("lock after release: %v", err)
+	}
+	release()
+}
+
+func TestVMConsoleLogStampsLinesAndReadsHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), vmConsoleLogName)
+	now := time.Date(2025, 3, 4, 12, 0, 0, 0, time.UTC)
+	log := newVMConsoleLog(path)
+	log.now = func() time.Time { return now }
+	for _, chunk := range []string{"booting\r\n", "log", "in: \n"} {
+		if _, err := log.Write([]byte(chunk)); err != nil {
+			t.Fatalf("write log: %v", err)
+		}
+		now = now.Add(10 * time.Minute)
+	}
+	got, err := readVMConsoleLog(path, time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("readVMConsoleLog: %v", err)
+	}
+	if strings.Join(got, "|") != "booting|login: " {
+		t.Fatalf("history = %q", got)
+	}
+	got, err = readVMConsoleLog(path, time.Date(2025, 3, 4, 12, 5, 0, 0, time.UTC), 0)
+	if err != nil || strings.Join(got, "|") != "login: " {
+		t.Fatalf("history since = %q, %v", got, err)
+	}
+	got, err = readVMConsoleLog(path, time.Time{}, 1)
+	if err != nil || strings.Join(got, "|") != "login: " {
+		t.Fatalf("history lines = %q, %v", got, err)
+	}
+	if got, err := readVMConsoleLog(filepath.Join(t.TempDir(), "missing.log"), time.Time{}, 5); err != nil || len(got) != 0 {
+		t.Fatalf("missing log = %q, %v", got, err)
+	}
+}
+
+func TestParseVMConsoleSince(t *testing.T) {
+	now := time.Date(2025, 3, 4, 12, 30, 0, 0, time.Local)
+	got, err := parseVMConsoleSince("10m", now)
+	if err != nil || !got.Equal(now.Add(-10*time.Minute)) {
+		t.Fatalf("since 10m = %v, %v", got, err)
+	}
+	got, err = parseVMConsoleSince("2025-03-04 08:00:00", now)
+	if err != nil || !got.Equal(time.Date(2025, 3, 4, 8, 0, 0, 0, time.Local)) {
+		t.Fatalf("since absolute = %v, %v", got, err)
+	}
+	for _, bad := range []string{"-5m", "yesterday"} {
+		if _, err := parseVMConsoleSince(bad, now); err == nil {
+			t.Fatalf("--since %q accepted", bad)
+		}
+	}
+}
+
+func TestPrintVMConsoleHistoryUsesCRLF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), vmConsoleLogName)
+	if _, err := newVMConsoleLog(path).Write([]byte("booting\r\nlogin: \n")); err != nil {
+		t.Fatalf("write log: %v", err)
+	}
+	var out bytes.Buffer
+	execer := &ttyExecer{ctx: context.Background(), sn: "devbox", rw: &out}
+	if err := execer.printVMConsoleHistory(path, cli.VMConsoleFlags{Lines: 20}); err != nil {
+		t.Fatalf("printVMConsoleHistory: %v", err)
+	}
+	if out.String() != "booting\r\nlogin: \r\n" {
+		t.Fatalf("history = %q", out.String())
+	}
+}
+
 func TestRunVMConsoleProxyBridgesPTYToSocket(t *testing.T) {
 	dir := shortUnixSocketDirForTest(t)
 	fakeJailer := filepath.Join(dir, "jailer")
@@ -0,0 +1,647 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/yeetrun/yeet/pkg/cli"
+	"github.com/yeetrun/yeet/pkg/db"
+)
+
+const vmDataDiskDefaultName = "data"
+
+// Data disk names double as the ext4 label, which is limited to 16 bytes.
+var vmDataDiskNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]{0,15}$`)
+
+var (
+	vmDataDiskRunner               vmCommandRunner
+	isServiceRunningForVMDataDisks = (*Server).IsServiceRunning
+)
+
+type vmDataDiskTarget struct {
+	Service *db.Service
+	Root    string
+}
+
+type vmDataDiskRow struct {
+	Name         string `json:"name"`
+	Backend      string `json:"backend"`
+	Bytes        int64  `json:"bytes"`
+	Path         string `json:"path"`
+	Guest        string `json:"guest,omitempty"`
+	DetachedFrom string `json:"detachedFrom,omitempty"`
+}
+
+type vmDataDiskListing struct {
+	Disks    []vmDataDiskRow `json:"disks"`
+	Detached []vmDataDiskRow `json:"detached"`
+}
+
+func (e *ttyExecer) vmDiskCmdFunc(args []string) error {
+	flags, rest, err := cli.ParseVMDisk(args)
+	if err != nil {
+		return err
+	}
+	switch rest[0] {
+	case "ls":
+		return e.s.printVMDataDisks(e.rw, e.sn, flags.Format)
+	case "add":
+		return e.s.addVMDataDisk(e.ctx, e.sn, flags, e.rw)
+	case "resize":
+		return e.s.resizeVMDataDisk(e.ctx, e.sn, rest[1], flags.Size, e.rw)
+	case "rm":
+		return e.s.removeVMDataDisk(e.ctx, e.sn, rest[1], flags.Keep, e.rw)
+	default:
+		return fmt.Errorf("unknown vm disk action %q", rest[0])
+	}
+}
+
+func (s *Server) vmDataDiskTarget(name string) (*db.DataView, vmDataDiskTarget, error) {
+	dv, err := s.getDB()
+	if err != nil {
+		return nil, vmDataDiskTarget{}, err
+	}
+	sv, ok := dv.Services().GetOk(name)
+	if !ok {
+		return nil, vmDataDiskTarget{}, fmt.Errorf("service %q not found", name)
+	}
+	service := sv.AsStruct()
+	if service.ServiceType != db.ServiceTypeVM || service.VM == nil {
+		return nil, vmDataDiskTarget{}, fmt.Errorf("service %q is not a VM service", name)
+	}
+	return dv, vmDataDiskTarget{Service: service, Root: s.serviceRootFromView(sv)}, nil
+}
+
+// stoppedVMDataDiskTarget loads a VM whose drive set may change. Firecracker
+// only reads drives at boot, and a hibernation snapshot pins the drive set it
+// was taken with, so the VM must be cold stopped.
+func (s *Server) stoppedVMDataDiskTarget(name string) (*db.DataView, vmDataDiskTarget, error) {
+	dv, target, err := s.vmDataDiskTarget(name)
+	if err != nil {
+		return nil, vmDataDiskTarget{}, err
+	}
+	if strings.TrimSpace(target.Service.VM.SetupState) != "ready" {
+		return nil, vmDataDiskTarget{}, fmt.Errorf("VM %q is not ready", name)
+	}
+	runningCheck := isServiceRunningForVMDataDisks
+	if runningCheck == nil {
+		runningCheck = (*Server).IsServiceRunning
+	}
+	running, err := runningCheck(s, name)
+	if err != nil {
+		return nil, vmDataDiskTarget{}, err
+	}
+	if running {
+		return nil, vmDataDiskTarget{}, fmt.Errorf("cannot change VM disks while %q is running; stop it first", name)
+	}
+	if vmHibernationPending(target.Root) {
+		return nil, vmDataDiskTarget{}, fmt.Errorf("VM %q is hibernated; resume and stop it before changing its disks", name)
+	}
+	return dv, target, nil
+}
+
+func (s *Server) printVMDataDisks(w io.Writer, name, formatOut string) error {
+	dv, target, err := s.vmDataDiskTarget(name)
+	if err != nil {
+		return err
+	}
+	listing := vmDataDiskListing{Disks: []vmDataDiskRow{}, Detached: []vmDataDiskRow{}}
+	for i, disk := range target.Service.VM.DataDisks {
+		row := vmDataDiskRowFromConfig(disk)
+		row.Guest = vmDataDiskGuestDevice(i)
+		listing.Disks = append(listing.Disks, row)
+	}
+	for _, detachedName := range sortedVMDetachedDiskNames(dv) {
+		row := vmDataDiskRowFromConfig(*dv.VMDetachedDisks().Get(detachedName).AsStruct())
+		row.Name = detachedName
+		listing.Detached = append(listing.Detached, row)
+	}
+	switch strings.TrimSpace(formatOut) {
+	case "", "table":
+		return renderVMDataDisksTable(w, listing)
+	case "json":
+		return json.NewEncoder(w).Encode(listing)
+	case "json-pretty":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(listing)
+	default:
+		return fmt.Errorf("unsupported vm disk format %q", formatOut)
+	}
+}
+
+func sortedVMDetachedDiskNames(dv *db.DataView) []string {
+	var names []string
+	for name := range dv.VMDetachedDisks().All() {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func vmDataDiskRowFromConfig(disk db.VMDataDiskConfig) vmDataDiskRow {
+	return vmDataDiskRow{
+		Name:         disk.Name,
+		Backend:      disk.Backend,
+		Bytes:        disk.Bytes,
+		Path:         disk.Path,
+		DetachedFrom: disk.DetachedFrom,
+	}
+}
+
+func renderVMDataDisksTable(w io.Writer, listing vmDataDiskListing) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tBACKEND\tSIZE\tGUEST\tPATH")
+	for _, row := range listing.Disks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", row.Name, row.Backend, formatBytesInt(row.Bytes), row.Guest, row.Path)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(listing.Detached) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "DETACHED\tBACKEND\tSIZE\tFROM\tPATH")
+	for _, row := range listing.Detached {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", row.Name, row.Backend, formatBytesInt(row.Bytes), row.DetachedFrom, row.Path)
+	}
+	return tw.Flush()
+}
+
+// vmDataDiskGuestDevice names the virtio block device a data disk gets in the
+// guest. The root disk is always vda and data disks follow in order.
+func vmDataDiskGuestDevice(index int) string {
+	return fmt.Sprintf("/dev/vd%c", 'b'+index)
+}
+
+func (s *Server) addVMDataDisk(ctx context.Context, name string, flags cli.VMDiskFlags, w io.Writer) error {
+	dv, target, err := s.stoppedVMDataDiskTarget(name)
+	if err != nil {
+		return err
+	}
+	if flags.From != "" {
+		return s.attachVMDataDisk(ctx, dv, target, flags, w)
+	}
+	disk, steps, err := planVMDataDiskCreate(target, flags)
+	if err != nil {
+		return err
+	}
+	if err := runVMDataDiskSteps(ctx, disk, steps); err != nil {
+		return err
+	}
+	disks := append(slices.Clone(target.Service.VM.DataDisks), disk)
+	if err := s.commitVMDataDisks(target, disks, nil); err != nil {
+		if dbMutationCommitted(err) {
+			return err
+		}
+		return errors.Join(err, destroyVMDataDiskStorage(ctx, disk))
+	}
+	writef(w, "Added data disk %s (%s, %s) to %s as %s with ext4 label %s.\n", disk.Name, disk.Backend, formatBytesInt(disk.Bytes), name, vmDataDiskGuestDevice(len(disks)-1), disk.Name)
+	return nil
+}
+
+func planVMDataDiskCreate(target vmDataDiskTarget, flags cli.VMDiskFlags) (db.VMDataDiskConfig, []vmDiskPlanStep, error) {
+	diskName, err := vmDataDiskNameForAdd(target.Service.VM.DataDisks, flags.Name)
+	if err != nil {
+		return db.VMDataDiskConfig{}, nil, err
+	}
+	bytes, err := parseVMSize(flags.Size)
+	if err != nil {
+		return db.VMDataDiskConfig{}, nil, err
+	}
+	if bytes <= 0 {
+		return db.VMDataDiskConfig{}, nil, fmt.Errorf("data disk size must be positive")
+	}
+	backend := vmDiskBackendRaw
+	if flags.ZFS {
+		backend = vmDiskBackendZVOL
+	}
+	diskPath, err := vmDataDiskPlacement(target, diskName, backend)
+	if err != nil {
+		return db.VMDataDiskConfig{}, nil, err
+	}
+	disk := db.VMDataDiskConfig{Name: diskName, Backend: backend, Bytes: bytes, Path: diskPath}
+	steps, err := vmDataDiskCreateSteps(disk)
+	if err != nil {
+		return db.VMDataDiskConfig{}, nil, err
+	}
+	return disk, steps, nil
+}
+
+func vmDataDiskNameForAdd(existing []db.VMDataDiskConfig, requested string) (string, error) {
+	if requested != "" {
+		if err := validateVMDataDiskName(requested); err != nil {
+			return "", err
+		}
+		if findVMDataDisk(existing, requested) >= 0 {
+			return "", fmt.Errorf("VM already has a data disk named %q", requested)
+		}
+		return requested, nil
+	}
+	name := vmDataDiskDefaultName
+	for i := 2; findVMDataDisk(existing, name) >= 0; i++ {
+		name = fmt.Sprintf("%s%d", vmDataDiskDefaultName, i)
+	}
+	return name, nil
+}
+
+func validateVMDataDiskName(name string) error {
+	if !vmDataDiskNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid data disk name %q: use 1-16 lowercase letters, digits, or dashes, starting with a letter", name)
+	}
+	if name == "root" || name == "rootfs" {
+		return fmt.Errorf("data disk name %q is reserved", name)
+	}
+	return nil
+}
+
+func findVMDataDisk(disks []db.VMDataDiskConfig, name string) int {
+	return slices.IndexFunc(disks, func(disk db.VMDataDiskConfig) bool { return disk.Name == name })
+}
+
+// vmDataDiskPlacement keeps data disks inside the VM's own storage so removing
+// the VM removes them too: raw disks live in the service data directory and
+// zvols are children of the service dataset.
+func vmDataDiskPlacement(target vmDataDiskTarget, name, backend string) (string, error) {
+	if backend != vmDiskBackendZVOL {
+		return filepath.Join(serviceDataDirForRoot(target.Root), "disk-"+name+".raw"), nil
+	}
+	parent, err := vmDataDiskZVOLParent(target.Service)
+	if err != nil {
+		return "", err
+	}
+	dataset := parent + "/disk-" + name
+	if err := validateZFSName("data disk dataset", dataset, true); err != nil {
+		return "", err
+	}
+	return "/dev/zvol/" + dataset, nil
+}
+
+func vmDataDiskZVOLParent(service *db.Service) (string, error) {
+	if dataset := strings.Trim(strings.TrimSpace(service.ServiceRootZFS), "/"); dataset != "" {
+		return dataset, nil
+	}
+	if service.VM.Disk.Backend == vmDiskBackendZVOL {
+		dataset, err := vmSnapshotDataset(service.VM.Disk)
+		if err != nil {
+			return "", err
+		}
+		return path.Dir(dataset), nil
+	}
+	return "", fmt.Errorf("VM %q is not on ZFS; --zfs data disks need a ZFS service root", service.Name)
+}
+
+func vmDataDiskDataset(disk db.VMDataDiskConfig) string {
+	return strings.TrimPrefix(strings.TrimSpace(disk.Path), "/dev/zvol/")
+}
+
+func vmDataDiskCreateSteps(disk db.VMDataDiskConfig) ([]vmDiskPlanStep, error) {
+	size := fmt.Sprintf("%d", disk.Bytes)
+	mkfs := []string{"mkfs.ext4", "-q", "-F", "-L", disk.Name, disk.Path}
+	if disk.Backend != vmDiskBackendZVOL {
+		if _, err := os.Stat(disk.Path); err == nil {
+			return nil, fmt.Errorf("data disk file %s already exists", disk.Path)
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		return []vmDiskPlanStep{
+			{Phase: vmDiskPhaseDataCreate, Command: []string{"qemu-img", "create", "-f", "raw", disk.Path, size}},
+			{Phase: vmDiskPhaseDataCreate, Command: mkfs},
+		}, nil
+	}
+	return []vmDiskPlanStep{
+		{Phase: vmDiskPhaseDataCreate, Command: []string{"zfs", "create", "-s", "-V", size, vmDataDiskDataset(disk)}},
+		{Phase: vmDiskPhaseDataCreate, Command: vmZVOLSettleCommand()},
+		{Phase: vmDiskPhaseDataCreate, Command: mkfs},
+	}, nil
+}
+
+func runVMDataDiskSteps(ctx context.Context, disk db.VMDataDiskConfig, steps []vmDiskPlanStep) error {
+	runner := vmDataDiskRunner
+	if runner == nil {
+		runner = runVMCommand
+	}
+	return runVMDiskStepsWithRunner(ctx, vmDiskPlan{Path: disk.Path}, steps, runner, nil)
+}
+
+func destroyVMDataDiskStorage(ctx context.Context, disk db.VMDataDiskConfig) error {
+	if disk.Backend != vmDiskBackendZVOL {
+		if err := os.Remove(disk.Path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove data disk %s: %w", disk.Path, err)
+		}
+		return nil
+	}
+	return runVMDataDiskSteps(ctx, disk, []vmDiskPlanStep{
+		{Command: []string{"zfs", "destroy", "-r", vmDataDiskDataset(disk)}},
+	})
+}
+
+func (s *Server) resizeVMDataDisk(ctx context.Context, name, diskName, size string, w io.Writer) error {
+	_, target, err := s.stoppedVMDataDiskTarget(name)
+	if err != nil {
+		return err
+	}
+	disks := slices.Clone(target.Service.VM.DataDisks)
+	idx := findVMDataDisk(disks, diskName)
+	if idx < 0 {
+		return fmt.Errorf("VM %q has no data disk %q", name, diskName)
+	}
+	bytes, err := parseVMSize(size)
+	if err != nil {
+		return err
+	}
+	disk := disks[idx]
+	steps, err := vmDiskResizeStepsFromConfig(db.VMDiskConfig{Backend: disk.Backend, Bytes: disk.Bytes, Path: disk.Path}, bytes)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		writef(w, "Data disk %s is already %s.\n", diskName, formatBytesInt(disk.Bytes))
+		return nil
+	}
+	if err := runVMDataDiskSteps(ctx, disk, steps); err != nil {
+		return err
+	}
+	disks[idx].Bytes = bytes
+	if err := s.commitVMDataDisks(target, disks, nil); err != nil {
+		return err
+	}
+	writef(w, "Resized data disk %s to %s.\n", diskName, formatBytesInt(bytes))
+	return nil
+}
+
+func (s *Server) removeVMDataDisk(ctx context.Context, name, diskName string, keep bool, w io.Writer) error {
+	dv, target, err := s.stoppedVMDataDiskTarget(name)
+	if err != nil {
+		return err
+	}
+	disks := slices.Clone(target.Service.VM.DataDisks)
+	idx := findVMDataDisk(disks, diskName)
+	if idx < 0 {
+		return fmt.Errorf("VM %q has no data disk %q", name, diskName)
+	}
+	disk := disks[idx]
+	remaining := slices.Delete(disks, idx, idx+1)
+	if keep {
+		return s.detachVMDataDisk(ctx, dv, target, disk, remaining, w)
+	}
+	// Drop the drive before deleting its storage so a failed delete leaves an
+	// orphaned disk rather than a VM that cannot boot.
+	if err := s.commitVMDataDisks(target, remaining, nil); err != nil {
+		return err
+	}
+	if err := destroyVMDataDiskStorage(ctx, disk); err != nil {
+		return fmt.Errorf("removed data disk %s from %s but failed to delete it: %w", diskName, name, err)
+	}
+	writef(w, "Removed data disk %s from %s and deleted %s.\n", diskName, name, disk.Path)
+	return nil
+}
+
+func (s *Server) detachVMDataDisk(ctx context.Context, dv *db.DataView, target vmDataDiskTarget, disk db.VMDataDiskConfig, remaining []db.VMDataDiskConfig, w io.Writer) error {
+	name := target.Service.Name
+	detachedName := name + "-" + disk.Name
+	if dv.VMDetachedDisks().Contains(detachedName) {
+		return fmt.Errorf("a detached disk named %q already exists; attach it elsewhere first", detachedName)
+	}
+	detachedPath, err := s.vmDetachedDiskPath(disk, detachedName)
+	if err != nil {
+		return err
+	}
+	if err := moveVMDataDisk(ctx, disk.Backend, disk.Path, detachedPath); err != nil {
+		return err
+	}
+	detached := disk
+	detached.Path = detachedPath
+	detached.DetachedFrom = name
+	err = s.commitVMDataDisks(target, remaining, func(d *db.Data) error {
+		if d.VMDetachedDisks[detachedName] != nil {
+			return fmt.Errorf("a detached disk named %q already exists", detachedName)
+		}
+		if d.VMDetachedDisks == nil {
+			d.VMDetachedDisks = make(map[string]*db.VMDataDiskConfig)
+		}
+		d.VMDetachedDisks[detachedName] = &detached
+		return nil
+	})
+	if err != nil {
+		if dbMutationCommitted(err) {
+			return err
+		}
+		return errors.Join(err, moveVMDataDisk(ctx, disk.Backend, detachedPath, disk.Path))
+	}
+	writef(w, "Detached data disk %s from %s as %s; attach it with `yeet vm disk add <vm> --from=%s`.\n", disk.Name, name, detachedName, detachedName)
+	return nil
+}
+
+// vmDetachedDiskPath parks a detached disk outside every VM so it survives
+// the VM it came from being removed.
+func (s *Server) vmDetachedDiskPath(disk db.VMDataDiskConfig, detachedName string) (string, error) {
+	if disk.Backend != vmDiskBackendZVOL {
+		return filepath.Join(s.cfg.RootDir, "vm-disks", detachedName+".raw"), nil
+	}
+	pool, _, _ := strings.Cut(vmDataDiskDataset(disk), "/")
+	dataset := pool + "/yeet/vm-disks/" + detachedName
+	if err := validateZFSName("detached disk dataset", dataset, true); err != nil {
+		return "", err
+	}
+	return "/dev/zvol/" + dataset, nil
+}
+
+func (s *Server) attachVMDataDisk(ctx context.Context, dv *db.DataView, target vmDataDiskTarget, flags cli.VMDiskFlags, w io.Writer) error {
+	dView, ok := dv.VMDetachedDisks().GetOk(flags.From)
+	if !ok {
+		return fmt.Errorf("detached disk %q not found; see yeet vm disk ls", flags.From)
+	}
+	detached := *dView.AsStruct()
+	requested := flags.Name
+	if requested == "" {
+		requested = detached.Name
+	}
+	diskName, err := vmDataDiskNameForAdd(target.Service.VM.DataDisks, requested)
+	if err != nil {
+		return err
+	}
+	diskPath, err := vmDataDiskPlacement(target, diskName, detached.Backend)
+	if err != nil {
+		return err
+	}
+	if err := moveVMDataDisk(ctx, detached.Backend, detached.Path, diskPath); err != nil {
+		return err
+	}
+	disk := db.VMDataDiskConfig{Name: diskName, Backend: detached.Backend, Bytes: detached.Bytes, Path: diskPath}
+	if err := s.finishVMDataDiskAttach(ctx, target, flags.From, detached, disk); err != nil {
+		return err
+	}
+	writef(w, "Attached disk %s to %s as data disk %s (%s).\n", flags.From, target.Service.Name, diskName, vmDataDiskGuestDevice(len(target.Service.VM.DataDisks)))
+	return nil
+}
+
+func (s *Server) finishVMDataDiskAttach(ctx context.Context, target vmDataDiskTarget, detachedName string, detached, disk db.VMDataDiskConfig) error {
+	relabel := disk.Name != detached.Name
+	if relabel {
+		if err := runVMDataDiskSteps(ctx, disk, []vmDiskPlanStep{{Command: []string{"e2label", disk.Path, disk.Name}}}); err != nil {
+			return errors.Join(err, moveVMDataDisk(ctx, disk.Backend, disk.Path, detached.Path))
+		}
+	}
+	disks := append(slices.Clone(target.Service.VM.DataDisks), disk)
+	err := s.commitVMDataDisks(target, disks, func(d *db.Data) error {
+		if d.VMDetachedDisks[detachedName] == nil {
+			return fmt.Errorf("detached disk %q changed during attach", detachedName)
+		}
+		delete(d.VMDetachedDisks, detachedName)
+		return nil
+	})
+	if err == nil || dbMutationCommitted(err) {
+		return err
+	}
+	return errors.Join(err, moveVMDataDisk(ctx, disk.Backend, disk.Path, detached.Path))
+}
+
+func moveVMDataDisk(ctx context.Context, backend, from, to string) error {
+	if backend == vmDiskBackendZVOL {
+		disk := db.VMDataDiskConfig{Path: from}
+		return runVMDataDiskSteps(ctx, disk, []vmDiskPlanStep{
+			{Command: []string{"zfs", "rename", "-p", vmDataDiskDataset(disk), strings.TrimPrefix(to, "/dev/zvol/")}},
+			{Command: vmZVOLSettleCommand()},
+		})
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0o755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(to); err == nil {
+		return fmt.Errorf("data disk file %s already exists", to)
+	}
+	if err := os.Rename(from, to); err != nil {
+		return fmt.Errorf("move data disk: %w", err)
+	}
+	return nil
+}
+
+// commitVMDataDisks points the VM's Firecracker drives at disks and records
+// them, restoring the previous Firecracker config if the DB write fails.
+func (s *Server) commitVMDataDisks(target vmDataDiskTarget, disks []db.VMDataDiskConfig, mutate func(*db.Data) error) error {
+	configPath := filepath.Join(serviceRunDirForRoot(target.Root), "firecracker.json")
+	oldConfig, err := rewriteVMDataDiskDrives(configPath, disks)
+	if err != nil {
+		return err
+	}
+	_, err = s.cfg.DB.MutateData(func(d *db.Data) error {
+		service := d.Services[target.Service.Name]
+		if service == nil || service.VM == nil {
+			return fmt.Errorf("service %q is not a VM service", target.Service.Name)
+		}
+		service.VM.DataDisks = disks
+		if mutate != nil {
+			return mutate(d)
+		}
+		return nil
+	})
+	if err == nil || dbMutationCommitted(err) {
+		return err
+	}
+	if restoreErr := writeVMFile(configPath, oldConfig, 0o644); restoreErr != nil {
+		return errors.Join(err, fmt.Errorf("restore VM firecracker config: %w", restoreErr))
+	}
+	return err
+}
+
+func rewriteVMDataDiskDrives(configPath string, disks []db.VMDataDiskConfig) ([]byte, error) {
+	raw, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("read VM firecracker config: %w", err)
+	}
+	var cfg firecrackerConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("decode VM firecracker config: %w", err)
+	}
+	cfg.Drives = vmFirecrackerDrivesWithDataDisks(cfg.Drives, disks)
+	rendered, err := renderFirecrackerConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeVMFile(configPath, rendered, 0o644); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// vmFirecrackerDrivesWithDataDisks keeps the root drive first and replaces
+// every other drive with the VM's data disks, in order.
+func vmFirecrackerDrivesWithDataDisks(drives []firecrackerDrive, disks []db.VMDataDiskConfig) []firecrackerDrive {
+	var out []firecrackerDrive
+	for _, drive := range drives {
+		if drive.IsRootDevice || drive.DriveID == "rootfs" {
+			out = append(out, drive)
+		}
+	}
+	for _, disk := range disks {
+		out = append(out, firecrackerDrive{
+			// Firecracker drive IDs only allow letters, digits and underscores.
+			DriveID:    "data_" + strings.ReplaceAll(disk.Name, "-", "_"),
+			PathOnHost: disk.Path,
+		})
+	}
+	return out
+}
+
+// vmDataDiskSnapshotDatasets returns the zvol data disks that are snapshotted
+// together with the root disk. Raw data disks cannot be snapshotted.
+func vmDataDiskSnapshotDatasets(disks []db.VMDataDiskConfig) []string {
+	var datasets []string
+	for _, disk := range disks {
+		if disk.Backend == vmDiskBackendZVOL {
+			datasets = append(datasets, vmDataDiskDataset(disk))
+		}
+	}
+	return datasets
+}
+
+func warnVMRawDataDisksNotSnapshotted(w io.Writer, service string, disks []db.VMDataDiskConfig) {
+	for _, disk := range disks {
+		if disk.Backend != vmDiskBackendZVOL {
+			writeSnapshotWarning(w, "warning: VM %q data disk %s is a raw file and is not part of the recovery point\n", service, disk.Name)
+		}
+	}
+}
+
+// destroyVMDataDiskSnapshots removes the data disk halves of root disk
+// snapshots. Disks attached after a snapshot was taken have no matching
+// snapshot, so missing ones are skipped.
+func (s *Server) destroyVMDataDiskSnapshots(ctx context.Context, datasets []string, snapshots []string) error {
+	runner := s.zfsRunner
+	if runner == nil {
+		runner = runZFSCommand
+	}
+	var errs []error
+	for _, snapshot := range snapshots {
+		short := vmSnapshotShortName(snapshot)
+		for _, dataset := range datasets {
+			companion := dataset + "@" + short
+			exists, err := zfsDatasetExists(ctx, runner, companion)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if exists {
+				errs = append(errs, destroySnapshot(ctx, runner, companion))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,298 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yeetrun/yeet/pkg/cli"
+	"github.com/yeetrun/yeet/pkg/db"
+)
+
+func seedVMForDataDisks(t *testing.T, server *Server, names ...string) {
+	t.Helper()
+	services := make(map[string]*db.Service)
+	for _, name := range names {
+		root := filepath.Join(server.cfg.ServicesRoot, name)
+		rootDisk := filepath.Join(serviceDataDirForRoot(root), "rootfs.raw")
+		if err := os.MkdirAll(serviceDataDirForRoot(root), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		config, err := renderFirecrackerConfig(firecrackerConfig{
+			Drives: []firecrackerDrive{{DriveID: "rootfs", PathOnHost: rootDisk, IsRootDevice: true}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writeVMFile(filepath.Join(serviceRunDirForRoot(root), "firecracker.json"), config, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		services[name] = &db.Service{
+			Name:        name,
+			ServiceType: db.ServiceTypeVM,
+			ServiceRoot: root,
+			VM: &db.VMConfig{
+				SetupState: "ready",
+				Disk:       db.VMDiskConfig{Backend: vmDiskBackendRaw, Bytes: 10 << 30, Path: rootDisk},
+			},
+		}
+	}
+	if err := server.cfg.DB.Set(&db.Data{Services: services}); err != nil {
+		t.Fatalf("seed db: %v", err)
+	}
+}
+
+func stubVMDataDiskHooks(t *testing.T, running bool) *[][]string {
+	t.Helper()
+	oldRunner := vmDataDiskRunner
+	oldRunning := isServiceRunningForVMDataDisks
+	t.Cleanup(func() {
+		vmDataDiskRunner = oldRunner
+		isServiceRunningForVMDataDisks = oldRunning
+	})
+	var commands [][]string
+	vmDataDiskRunner = func(_ context.Context, command []string) error {
+		commands = append(commands, append([]string(nil), command...))
+		if command[0] == "qemu-img" {
+			return os.WriteFile(command[4], nil, 0o600)
+		}
+		return nil
+	}
+	isServiceRunningForVMDataDisks = func(*Server, string) (bool, error) { return running, nil }
+	return &commands
+}
+
+func vmDataDiskDriveIDs(t *testing.T, server *Server, name string) []string {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join(serviceRunDirForRoot(filepath.Join(server.cfg.ServicesRoot, name)), "firecracker.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg firecrackerConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, drive := range cfg.Drives {
+		ids = append(ids, drive.DriveID)
+	}
+	return ids
+}
+
+func TestAddVMDataDiskCreatesRawDiskAndDrive(t *testing.T) {
+	server := newTestServer(t)
+	seedVMForDataDisks(t, server, "devbox")
+	commands := stubVMDataDiskHooks(t, false)
+
+	var out bytes.Buffer
+	if err := server.addVMDataDisk(context.Background(), "devbox", cli.VMDiskFlags{Size: "20G"}, &out); err != nil {
+		t.Fatalf("addVMDataDisk: %v", err)
+	}
+	if err := server.addVMDataDisk(context.Background(), "devbox", cli.VMDiskFlags{Name: "pg-wal", Size: "5G"}, io.Discard); err != nil {
+		t.Fatalf("addVMDataDisk pg-wal: %v", err)
+	}
+	diskPath := filepath.Join(server.cfg.ServicesRoot, "devbox", "data", "disk-data.raw")
+	want := [][]string{
+		{"qemu-img", "create", "-f", "raw", diskPath, "21474836480"},
+		{"mkfs.ext4", "-q", "-F", "-L", "data", diskPath},
+	}
+	if !reflect.DeepEqual((*commands)[:2], want) {
+		t.Fatalf("commands = %#v, want %#v", (*commands)[:2], want)
+	}
+	if !strings.Contains(out.String(), "as /dev/vdb with ext4 label data") {
+		t.Fatalf("output = %q", out.String())
+	}
+	if got, want := vmDataDiskDriveIDs(t, server, "devbox"), []string{"rootfs", "data_data", "data_pg_wal"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("drive IDs = %#v, want %#v", got, want)
+	}
+	dv, err := server.getDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	disks := dv.Services().Get("devbox").VM().AsStruct().DataDisks
+	if len(disks) != 2 || disks[0].Name != "data" || disks[0].Bytes != 20<<30 || disks[1].Name != "pg-wal" {
+		t.Fatalf("data disks = %#v", disks)
+	}
+}
+
+func TestAddVMDataDiskRequiresStoppedVM(t *testing.T) {
+	server := newTestServer(t)
+	seedVMForDataDisks(t, server, "devbox")
+	stubVMDataDiskHooks(t, true)
+	err := server.addVMDataDisk(context.Background(), "devbox", cli.VMDiskFlags{Size: "20G"}, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "stop it first") {
+		t.Fatalf("addVMDataDisk error = %v", err)
+	}
+}
+
+func TestAddVMDataDiskZFSRequiresZFSServiceRoot(t *testing.T) {
+	server := newTestServer(t)
+	seedVMForDataDisks(t, server, "devbox")
+	stubVMDataDiskHooks(t, false)
+	err := server.addVMDataDisk(context.Background(), "devbox", cli.VMDiskFlags{Size: "20G", ZFS: true}, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "not on ZFS") {
+		t.Fatalf("addVMDataDisk error = %v", err)
+	}
+}
+
+func TestDetachAndAttachVMDataDiskMovesDiskBetweenVMs(t *testing.T) {
+	server := newTestServer(t)
+	seedVMForDataDisks(t, server, "old", "new")
+	stubVMDataDiskHooks(t, false)
+	ctx := context.Background()
+	if err := server.addVMDataDisk(ctx, "old", cli.VMDiskFlags{Size: "1G"}, io.Discard); err != nil {
+		t.Fatalf("addVMDataDisk: %v", err)
+	}
+
+	if err := server.removeVMDataDisk(ctx, "old", "data", true, io.Discard); err != nil {
+		t.Fatalf("removeVMDataDisk --keep: %v", err)
+	}
+	parked := filepath.Join(server.cfg.RootDir, "vm-disks", "old-data.raw")
+	if _, err := os.Stat(parked); err != nil {
+		t.Fatalf("detached disk not parked: %v", err)
+	}
+	if got := vmDataDiskDriveIDs(t, server, "old"); !reflect.DeepEqual(got, []string{"rootfs"}) {
+		t.Fatalf("old drive IDs = %#v", got)
+	}
+	var listing bytes.Buffer
+	if err := server.printVMDataDisks(&listing, "new", "table"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(listing.String(), "old-data") {
+		t.Fatalf("listing = %q, want detached disk", listing.String())
+	}
+
+	if err := server.addVMDataDisk(ctx, "new", cli.VMDiskFlags{From: "old-data"}, io.Discard); err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+	attached := filepath.Join(server.cfg.ServicesRoot, "new", "data", "disk-data.raw")
+	if _, err := os.Stat(attached); err != nil {
+		t.Fatalf("attached disk missing: %v", err)
+	}
+	dv, err := server.getDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dv.VMDetachedDisks().Len() != 0 {
+		t.Fatalf("detached disks = %d, want 0", dv.VMDetachedDisks().Len())
+	}
+	disks := dv.Services().Get("new").VM().AsStruct().DataDisks
+	if len(disks) != 1 || disks[0].Path != attached || disks[0].Bytes != 1<<30 {
+		t.Fatalf("new data disks = %#v", disks)
+	}
+}
+
+func TestRemoveVMDataDiskDeletesRawFile(t *testing.T) {
+	server := newTestServer(t)
+	seedVMForDataDisks(t, server, "devbox")
+	stubVMDataDiskHooks(t, false)
+	ctx := context.Background()
+	if err := server.addVMDataDisk(ctx, "devbox", cli.VMDiskFlags{Size: "1G"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.removeVMDataDisk(ctx, "devbox", "data", false, io.Discard); err != nil {
+		t.Fatalf("removeVMDataDisk: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(server.cfg.ServicesRoot, "devbox", "data", "disk-data.raw")); !os.IsNotExist(err) {
+		t.Fatalf("disk file still present: %v", err)
+	}
+	if err := server.removeVMDataDisk(ctx, "devbox", "data", false, io.Discard); err == nil || !strings.Contains(err.Error(), "no data disk") {
+		t.Fatalf("second remove error = %v", err)
+	}
+}
+
+func TestResizeVMDataDiskZVOL(t *testing.T) {
+	server := newTestServer(t)
+	seedVMForDataDisks(t, server, "devbox")
+	if _, err := server.cfg.DB.MutateData(func(d *db.Data) error {
+		d.Services["devbox"].VM.DataDisks = []db.VMDataDiskConfig{{
+			Name: "data", Backend: vmDiskBackendZVOL, Bytes: 1 << 30, Path: "/dev/zvol/tank/yeet/devbox/disk-data",
+		}}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	commands := stubVMDataDiskHooks(t, false)
+	if err := server.resizeVMDataDisk(context.Background(), "devbox", "data", "2G", io.Discard); err != nil {
+		t.Fatalf("resizeVMDataDisk: %v", err)
+	}
+	want := [][]string{
+		{"zfs", "set", "volsize=2147483648", "tank/yeet/devbox/disk-data"},
+		vmZVOLSettleCommand(),
+		{"e2fsck", "-pf", "/dev/zvol/tank/yeet/devbox/disk-data"},
+		{"resize2fs", "/dev/zvol/tank/yeet/devbox/disk-data"},
+	}
+	if !reflect.DeepEqual(*commands, want) {
+		t.Fatalf("commands = %#v, want %#v", *commands, want)
+	}
+	dv, err := server.getDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := dv.Services().Get("devbox").VM().AsStruct().DataDisks[0].Bytes; got != 2<<30 {
+		t.Fatalf("data disk bytes = %d", got)
+	}
+}
+
+func TestVMDataDiskPlacementUsesServiceDataset(t *testing.T) {
+	target := vmDataDiskTarget{
+		Service: &db.Service{Name: "devbox", ServiceRootZFS: "tank/yeet/services/devbox", VM: &db.VMConfig{}},
+		Root:    "/srv/devbox",
+	}
+	got, err := vmDataDiskPlacement(target, "data", vmDiskBackendZVOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "/dev/zvol/tank/yeet/services/devbox/disk-data" {
+		t.Fatalf("zvol placement = %q", got)
+	}
+	server := &Server{cfg: Config{RootDir: "/var/lib/yeet"}}
+	parked, err := server.vmDetachedDiskPath(db.VMDataDiskConfig{Backend: vmDiskBackendZVOL, Path: got}, "devbox-data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parked != "/dev/zvol/tank/yeet/vm-disks/devbox-data" {
+		t.Fatalf("detached placement = %q", parked)
+	}
+}
+
+func TestVMDataDiskNameForAdd(t *testing.T) {
+	existing := []db.VMDataDiskConfig{{Name: "data"}, {Name: "data2"}}
+	if got, err := vmDataDiskNameForAdd(existing, ""); err != nil || got != "data3" {
+		t.Fatalf("default name = %q, %v", got, err)
+	}
+	for _, bad := range []string{"data", "Data", "rootfs", "1disk", "a-very-long-disk-name"} {
+		if _, err := vmDataDiskNameForAdd(existing, bad); err == nil {
+			t.Fatalf("vmDataDiskNameForAdd(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestCreateServiceSnapshotIncludesCompanions(t *testing.T) {
+	var got []string
+	runner := func(_ context.Context, args ...string) (string, string, error) {
+		got = args
+		return "", "", nil
+	}
+	name, err := createServiceSnapshot(context.Background(), runner, snapshotCreateRequest{
+		Service: "devbox", Dataset: "tank/devbox/root", Companions: []string{"tank/devbox/disk-data"},
+		Event: snapshotEventVMManual,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	short := vmSnapshotShortName(name)
+	if got[len(got)-2] != name || got[len(got)-1] != "tank/devbox/disk-data@"+short {
+		t.Fatalf("zfs snapshot args = %#v", got)
+	}
+}
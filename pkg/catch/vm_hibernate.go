@@ -0,0 +1,488 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/yeetrun/yeet/pkg/cli"
+	"github.com/yeetrun/yeet/pkg/db"
+)
+
+const (
+	vmHibernateDirName           = "hibernate"
+	vmHibernateStateFile         = "vmstate"
+	vmHibernateMemoryFile        = "memory"
+	vmHibernateManifestFile      = "hibernation.json"
+	vmHibernateOnShutdownFile    = "on-host-shutdown"
+	vmHibernateRestoreFailedFile = "restore-failed"
+
+	// vmHibernateTimeout bounds writing guest memory to disk, both for
+	// `vm hibernate` and on host shutdown. The hibernate drop-in raises the
+	// TimeoutStopSec of VMs that hibernate on host shutdown above it.
+	vmHibernateTimeout = 90 * time.Second
+	// vmHibernateDropInName sorts before the power drop-in, so a stop
+	// timeout set with `vm set --shutdown-timeout` still applies.
+	vmHibernateDropInName = "yeet-hibernate.conf"
+	// vmHibernateRestoreTimeout bounds waiting for the Firecracker API and
+	// loading the snapshot when a hibernated VM starts.
+	vmHibernateRestoreTimeout = 60 * time.Second
+	vmHibernateResumeTimeout  = 2 * time.Minute
+	vmHibernatePollInterval   = 250 * time.Millisecond
+)
+
+var (
+	vmHibernateIsRunning                        = (*Server).IsServiceRunning
+	vmHibernateStopUnit                         = func(name string) error { return (&vmRunner{name: name}).Stop() }
+	vmHibernateStartUnit                        = func(name string) error { return (&vmRunner{name: name}).Start() }
+	vmHibernatePause        vmFirecrackerPauser = firecrackerSnapshotAPI{}
+	vmHibernateHostStopping                     = systemdHostStopping
+	vmHibernateNow                              = time.Now
+)
+
+// vmHibernatePaths are the files of a VM memory snapshot. They live in the
+// service data directory next to the VM disk, which the jail binds writable
+// at the same canonical path so Firecracker can use them directly.
+type vmHibernatePaths struct {
+	Dir           string
+	State         string
+	Memory        string
+	Manifest      string
+	OnShutdown    string
+	RestoreFailed string
+}
+
+func vmHibernatePathsForRoot(serviceRoot string) vmHibernatePaths {
+	dir := filepath.Join(serviceDataDirForRoot(serviceRoot), vmHibernateDirName)
+	return vmHibernatePaths{
+		Dir:           dir,
+		State:         filepath.Join(dir, vmHibernateStateFile),
+		Memory:        filepath.Join(dir, vmHibernateMemoryFile),
+		Manifest:      filepath.Join(dir, vmHibernateManifestFile),
+		OnShutdown:    filepath.Join(dir, vmHibernateOnShutdownFile),
+		RestoreFailed: filepath.Join(dir, vmHibernateRestoreFailedFile),
+	}
+}
+
+// vmHibernationManifest is written only after Firecracker finished the
+// snapshot, so its presence means the snapshot files are complete.
+type vmHibernationManifest struct {
+	StatePath  string    `json:"statePath"`
+	MemoryPath string    `json:"memoryPath"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func readVMHibernationManifest(paths vmHibernatePaths) (vmHibernationManifest, bool, error) {
+	raw, err := os.ReadFile(paths.Manifest)
+	if errors.Is(err, os.ErrNotExist) {
+		return vmHibernationManifest{}, false, nil
+	}
+	if err != nil {
+		return vmHibernationManifest{}, false, err
+	}
+	var manifest vmHibernationManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return vmHibernationManifest{}, false, fmt.Errorf("decode VM hibernation manifest: %w", err)
+	}
+	return manifest, true, nil
+}
+
+func vmHibernationPending(serviceRoot string) bool {
+	if strings.TrimSpace(serviceRoot) == "" {
+		return false
+	}
+	_, ok, err := readVMHibernationManifest(vmHibernatePathsForRoot(serviceRoot))
+	return ok && err == nil
+}
+
+func removeVMHibernationSnapshot(paths vmHibernatePaths) {
+	for _, path := range []string{paths.Manifest, paths.State, paths.Memory} {
+		_ = os.Remove(path)
+	}
+}
+
+// hibernateVMProcess pauses the VM and writes a full Firecracker snapshot of
+// its memory and device state. The VM is left paused on success so the
+// caller can stop it; on failure it is resumed and partial files removed.
+func hibernateVMProcess(ctx context.Context, socket string, paths vmHibernatePaths) (vmHibernationManifest, error) {
+	if err := os.MkdirAll(paths.Dir, 0o700); err != nil {
+		return vmHibernationManifest{}, fmt.Errorf("create VM hibernation directory: %w", err)
+	}
+	removeVMHibernationSnapshot(paths)
+	_ = os.Remove(paths.RestoreFailed)
+	if err := vmHibernatePause.Pause(ctx, socket); err != nil {
+		return vmHibernationManifest{}, fmt.Errorf("pause VM: %w", err)
+	}
+	manifest, err := writeVMHibernationSnapshot(ctx, socket, paths)
+	if err == nil {
+		return manifest, nil
+	}
+	removeVMHibernationSnapshot(paths)
+	resumeCtx, cancel := vmSnapshotRecoveryContext(ctx)
+	defer cancel()
+	if resumeErr := vmHibernatePause.Resume(resumeCtx, socket); resumeErr != nil {
+		return vmHibernationManifest{}, fmt.Errorf("%v; additionally failed to resume VM: %w", err, resumeErr)
+	}
+	return vmHibernationManifest{}, err
+}
+
+func writeVMHibernationSnapshot(ctx context.Context, socket string, paths vmHibernatePaths) (vmHibernationManifest, error) {
+	if err := firecrackerCreateFullSnapshot(ctx, socket, paths.State, paths.Memory); err != nil {
+		return vmHibernationManifest{}, fmt.Errorf("create VM memory snapshot: %w", err)
+	}
+	manifest := vmHibernationManifest{StatePath: paths.State, MemoryPath: paths.Memory, CreatedAt: vmHibernateNow().UTC()}
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return vmHibernationManifest{}, err
+	}
+	if err := writeTextFileAtomically(paths.Manifest, append(raw, '\n'), 0o600); err != nil {
+		return vmHibernationManifest{}, fmt.Errorf("write VM hibernation manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+func firecrackerCreateFullSnapshot(ctx context.Context, socket, statePath, memoryPath string) error {
+	return firecrackerJSON(ctx, socket, http.MethodPut, "http://unix/snapshot/create", map[string]string{
+		"snapshot_type": "Full",
+		"snapshot_path": statePath,
+		"mem_file_path": memoryPath,
+	})
+}
+
+func firecrackerLoadSnapshot(ctx context.Context, socket, statePath, memoryPath string) error {
+	return firecrackerJSON(ctx, socket, http.MethodPut, "http://unix/snapshot/load", map[string]any{
+		"snapshot_path": statePath,
+		"mem_backend":   map[string]string{"backend_type": "File", "backend_path": memoryPath},
+		"resume_vm":     true,
+	})
+}
+
+// restoreVMHibernation loads the pending snapshot into a Firecracker process
+// that was started without a config file. The snapshot is single use: it is
+// removed whether or not the load succeeds, and a failure leaves a note for
+// `vm resume` so the next start cold boots instead of retrying a bad image.
+// Firecracker maps the memory file privately, so unlinking it after the load
+// is safe.
+func restoreVMHibernation(ctx context.Context, cfg VMConsoleProxyConfig) error {
+	if !cfg.RestoreHibernation {
+		return nil
+	}
+	paths := vmHibernatePathsForRoot(cfg.ServiceRoot)
+	manifest, ok, err := readVMHibernationManifest(paths)
+	if err == nil && !ok {
+		err = fmt.Errorf("VM hibernation manifest is missing")
+	}
+	if err == nil {
+		restoreCtx, cancel := context.WithTimeout(ctx, vmHibernateRestoreTimeout)
+		err = loadVMHibernationSnapshot(restoreCtx, cfg.APISocket, manifest)
+		cancel()
+	}
+	removeVMHibernationSnapshot(paths)
+	if err != nil {
+		err = fmt.Errorf("resume VM %s from hibernation: %w", cfg.Service, err)
+		_ = writeTextFileAtomically(paths.RestoreFailed, []byte(err.Error()+"\n"), 0o600)
+		return err
+	}
+	_ = os.Remove(paths.RestoreFailed)
+	return nil
+}
+
+func loadVMHibernationSnapshot(ctx context.Context, socket string, manifest vmHibernationManifest) error {
+	if err := waitVMHibernateAPISocket(ctx, socket); err != nil {
+		return err
+	}
+	return firecrackerLoadSnapshot(ctx, socket, manifest.StatePath, manifest.MemoryPath)
+}
+
+func waitVMHibernateAPISocket(ctx context.Context, socket string) error {
+	for {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "unix", socket)
+		if err == nil {
+			return conn.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wait for Firecracker API socket: %w", err)
+		case <-time.After(vmHibernatePollInterval):
+		}
+	}
+}
+
+// VMRunContext returns the context vm-run runs under. It is cancelled on
+// SIGINT or SIGTERM. When SIGTERM arrives because the host is shutting down
+// and the VM opted in with `vm hibernate --on-host-shutdown=on`, the VM is
+// hibernated first so it resumes when the host boots again. Otherwise the
+// guest agent gets the VM's shutdown timeout to shut the guest down cleanly.
+func VMRunContext(parent context.Context, serviceRoot, apiSocket string) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			if sig == syscall.SIGTERM && !hibernateVMOnHostShutdown(serviceRoot, apiSocket) {
+				shutdownVMGuestOnStop(ctx, serviceRoot)
+			}
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
+func hibernateVMOnHostShutdown(serviceRoot, apiSocket string) bool {
+	if strings.TrimSpace(serviceRoot) == "" || strings.TrimSpace(apiSocket) == "" {
+		return false
+	}
+	paths := vmHibernatePathsForRoot(serviceRoot)
+	if _, err := os.Stat(paths.OnShutdown); err != nil || !vmHibernateHostStopping() {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), vmHibernateTimeout)
+	defer cancel()
+	if _, err := hibernateVMProcess(ctx, apiSocket, paths); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: hibernate VM on host shutdown: %v\n", err)
+		return false
+	}
+	fmt.Fprintln(os.Stderr, "VM hibernated for host shutdown")
+	return true
+}
+
+// systemdHostStopping reports whether systemd is shutting the host down, as
+// opposed to stopping a single unit.
+func systemdHostStopping() bool {
+	out, _ := exec.Command("systemctl", "is-system-running").Output()
+	return strings.TrimSpace(string(out)) == "stopping"
+}
+
+func (s *Server) hibernateVM(ctx context.Context, name string, w io.Writer) error {
+	_, vm, err := s.vmSnapshotService(name)
+	if err != nil {
+		return err
+	}
+	running, err := vmHibernateIsRunning(s, name)
+	if err != nil {
+		return err
+	}
+	if !running {
+		return fmt.Errorf("VM %q is not running", name)
+	}
+	socket := strings.TrimSpace(vm.Sockets.APISocketPath)
+	if socket == "" {
+		return fmt.Errorf("service %q has no Firecracker API socket", name)
+	}
+	root, err := s.serviceRootDir(name)
+	if err != nil {
+		return err
+	}
+	hibernateCtx, cancel := context.WithTimeout(ctx, vmHibernateTimeout)
+	defer cancel()
+	manifest, err := hibernateVMProcess(hibernateCtx, socket, vmHibernatePathsForRoot(root))
+	if err != nil {
+		return fmt.Errorf("hibernate VM %q: %w", name, err)
+	}
+	if err := s.setVMHibernation(name, func(h *db.VMHibernationConfig) {
+		h.StatePath = manifest.StatePath
+		h.MemoryPath = manifest.MemoryPath
+		h.CreatedAt = manifest.CreatedAt.Format(time.RFC3339)
+	}); err != nil {
+		return err
+	}
+	if err := vmHibernateStopUnit(name); err != nil {
+		return fmt.Errorf("stop hibernated VM %q: %w", name, err)
+	}
+	writef(w, "Hibernated VM %s (memory snapshot: %s)\n", name, manifest.MemoryPath)
+	return nil
+}
+
+func (s *Server) resumeVM(ctx context.Context, name string, w io.Writer) error {
+	if _, _, err := s.vmSnapshotService(name); err != nil {
+		return err
+	}
+	root, err := s.serviceRootDir(name)
+	if err != nil {
+		return err
+	}
+	paths := vmHibernatePathsForRoot(root)
+	if _, ok, err := readVMHibernationManifest(paths); err != nil || !ok {
+		_ = s.clearVMHibernationSnapshot(name)
+		return fmt.Errorf("VM %q is not hibernated; use `yeet start %s` to boot it", name, name)
+	}
+	running, err := vmHibernateIsRunning(s, name)
+	if err != nil {
+		return err
+	}
+	if running {
+		return fmt.Errorf("VM %q is already running", name)
+	}
+	if err := vmHibernateStartUnit(name); err != nil {
+		return fmt.Errorf("start VM %q: %w", name, err)
+	}
+	waitErr := waitVMHibernationConsumed(ctx, paths)
+	if err := s.clearVMHibernationSnapshot(name); err != nil {
+		return err
+	}
+	if waitErr != nil {
+		return fmt.Errorf("VM %q: %w", name, waitErr)
+	}
+	writef(w, "Resumed VM %s\n", name)
+	return nil
+}
+
+// waitVMHibernationConsumed waits for vm-run to load and remove the snapshot.
+func waitVMHibernationConsumed(ctx context.Context, paths vmHibernatePaths) error {
+	ctx, cancel := context.WithTimeout(ctx, vmHibernateResumeTimeout)
+	defer cancel()
+	for {
+		if raw, err := os.ReadFile(paths.RestoreFailed); err == nil {
+			return fmt.Errorf("%s; the VM cold booted instead", strings.TrimSpace(string(raw)))
+		}
+		if _, err := os.Stat(paths.Manifest); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for the VM to resume from hibernation")
+		case <-time.After(vmHibernatePollInterval):
+		}
+	}
+}
+
+// setVMHibernationPolicy records whether the VM hibernates instead of cold
+// stopping when the host shuts down. vm-run reads the marker file because it
+// has no access to the database at shutdown.
+func vmHibernateDropInPath(service string) string {
+	return filepath.Join(vmSystemdSystemDir, vmSystemdUnitName(service)+".d", vmHibernateDropInName)
+}
+
+// writeVMHibernateDropIn gives a VM that hibernates on host shutdown a stop
+// timeout long enough to write its memory, and removes it again when the VM
+// opts out. Other VMs keep the unit's own TimeoutStopSec.
+func writeVMHibernateDropIn(service string, onHostShutdown bool) error {
+	if !onHostShutdown {
+		path := vmHibernateDropInPath(service)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err := removeVMHibernateDropIn(service); err != nil {
+			return err
+		}
+		return vmPowerSystemctl("daemon-reload")
+	}
+	content := fmt.Sprintf("# Written by catch from `yeet vm hibernate --on-host-shutdown`; do not edit.\n[Service]\nTimeoutStopSec=%d\n",
+		int((vmHibernateTimeout + vmShutdownStopMargin).Seconds()))
+	changed, err := writeTextFileIfChanged(vmHibernateDropInPath(service), content, 0o644)
+	if err != nil || !changed {
+		return err
+	}
+	return vmPowerSystemctl("daemon-reload")
+}
+
+// removeVMHibernateDropIn removes the drop-in and its directory if nothing
+// else is in it.
+func removeVMHibernateDropIn(service string) error {
+	path := vmHibernateDropInPath(service)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove VM hibernate drop-in %s: %w", path, err)
+	}
+	_ = os.Remove(filepath.Dir(path))
+	return nil
+}
+
+func (s *Server) setVMHibernationPolicy(name string, onHostShutdown bool, w io.Writer) error {
+	if _, _, err := s.vmSnapshotService(name); err != nil {
+		return err
+	}
+	root, err := s.serviceRootDir(name)
+	if err != nil {
+		return err
+	}
+	paths := vmHibernatePathsForRoot(root)
+	if onHostShutdown {
+		if err := os.MkdirAll(paths.Dir, 0o700); err != nil {
+			return fmt.Errorf("create VM hibernation directory: %w", err)
+		}
+		if err := writeTextFileAtomically(paths.OnShutdown, []byte("on\n"), 0o600); err != nil {
+			return err
+		}
+	} else if err := os.Remove(paths.OnShutdown); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := s.setVMHibernation(name, func(h *db.VMHibernationConfig) { h.OnHostShutdown = onHostShutdown }); err != nil {
+		return err
+	}
+	if err := writeVMHibernateDropIn(name, onHostShutdown); err != nil {
+		return fmt.Errorf("update VM stop timeout: %w", err)
+	}
+	state := "off"
+	if onHostShutdown {
+		state = "on"
+	}
+	writef(w, "VM %s hibernate on host shutdown: %s\n", name, state)
+	return nil
+}
+
+func (s *Server) clearVMHibernationSnapshot(name string) error {
+	return s.setVMHibernation(name, func(h *db.VMHibernationConfig) {
+		h.StatePath, h.MemoryPath, h.CreatedAt = "", "", ""
+	})
+}
+
+func (s *Server) setVMHibernation(name string, update func(*db.VMHibernationConfig)) error {
+	_, _, err := s.cfg.DB.MutateService(name, func(_ *db.Data, service *db.Service) error {
+		if service.VM == nil {
+			return fmt.Errorf("service %q is not a VM service", name)
+		}
+		hibernation := db.VMHibernationConfig{}
+		if service.VM.Hibernation != nil {
+			hibernation = *service.VM.Hibernation
+		}
+		update(&hibernation)
+		service.VM.Hibernation = &hibernation
+		if hibernation == (db.VMHibernationConfig{}) {
+			service.VM.Hibernation = nil
+		}
+		return nil
+	})
+	return err
+}
+
+func (e *ttyExecer) vmHibernateCmdFunc(args []string) error {
+	flags, rest, err := cli.ParseVMHibernate(args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return fmt.Errorf("unexpected vm hibernate args: %s", strings.Join(rest, " "))
+	}
+	if flags.OnHostShutdown != "" {
+		return e.s.setVMHibernationPolicy(e.sn, flags.OnHostShutdown == "on", e.rw)
+	}
+	return e.s.hibernateVM(e.ctx, e.sn, e.rw)
+}
+
+func (e *ttyExecer) vmResumeCmdFunc(args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("unexpected vm resume args: %s", strings.Join(args, " "))
+	}
+	return e.s.resumeVM(e.ctx, e.sn, e.rw)
+}
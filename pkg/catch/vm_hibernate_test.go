@@ -0,0 +1,338 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yeetrun/yeet/pkg/db"
+)
+
+// newFirecrackerRecordingServer serves the Firecracker API on a unix socket,
+// recording every request. Paths listed in failures answer with that status.
+func newFirecrackerRecordingServer(t *testing.T, failures map[string]int) (string, func() []firecrackerUnixHTTPRequest) {
+	t.Helper()
+	socketPath := filepath.Join(shortUnixSocketDirForTest(t), "firecracker.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("listen unix: %v", err)
+	}
+	var mu sync.Mutex
+	var requests []firecrackerUnixHTTPRequest
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, firecrackerUnixHTTPRequest{Method: r.Method, Path: r.URL.Path, Body: string(raw)})
+		mu.Unlock()
+		if status, ok := failures[r.URL.Path]; ok {
+			w.WriteHeader(status)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+	return socketPath, func() []firecrackerUnixHTTPRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]firecrackerUnixHTTPRequest(nil), requests...)
+	}
+}
+
+func firecrackerRequestPaths(requests []firecrackerUnixHTTPRequest) []string {
+	var paths []string
+	for _, req := range requests {
+		paths = append(paths, req.Method+" "+req.Path)
+	}
+	return paths
+}
+
+func stubVMHibernateHooks(t *testing.T, running bool) *[]string {
+	t.Helper()
+	var units []string
+	oldRunning, oldStop, oldStart, oldPause := vmHibernateIsRunning, vmHibernateStopUnit, vmHibernateStartUnit, vmHibernatePause
+	vmHibernateIsRunning = func(*Server, string) (bool, error) { return running, nil }
+	vmHibernateStopUnit = func(name string) error {
+		units = append(units, "stop "+name)
+		return nil
+	}
+	vmHibernateStartUnit = func(name string) error {
+		units = append(units, "start "+name)
+		return nil
+	}
+	t.Cleanup(func() {
+		vmHibernateIsRunning, vmHibernateStopUnit, vmHibernateStartUnit, vmHibernatePause = oldRunning, oldStop, oldStart, oldPause
+	})
+	return &units
+}
+
+func seedVMForHibernate(t *testing.T, server *Server, name, root, socket string) {
+	t.Helper()
+	if err := server.cfg.DB.Set(&db.Data{Services: map[string]*db.Service{
+		name: {
+			Name:        name,
+			ServiceType: db.ServiceTypeVM,
+			ServiceRoot: root,
+			VM: &db.VMConfig{
+				SetupState: "ready",
+				Sockets:    db.VMSocketConfig{APISocketPath: socket},
+			},
+		},
+	}}); err != nil {
+		t.Fatalf("seed db: %v", err)
+	}
+}
+
+func TestHibernateVMProcessWritesFullSnapshot(t *testing.T) {
+	socket, requests := newFirecrackerRecordingServer(t, nil)
+	paths := vmHibernatePathsForRoot(t.TempDir())
+	manifest, err := hibernateVMProcess(context.Background(), socket, paths)
+	if err != nil {
+		t.Fatalf("hibernateVMProcess: %v", err)
+	}
+	got := requests()
+	if want := []string{"PATCH /vm", "PUT /snapshot/create"}; !reflect.DeepEqual(firecrackerRequestPaths(got), want) {
+		t.Fatalf("requests = %#v, want %#v", firecrackerRequestPaths(got), want)
+	}
+	var body map[string]string
+	if err := json.Unmarshal([]byte(got[1].Body), &body); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"snapshot_type": "Full", "snapshot_path": paths.State, "mem_file_path": paths.Memory}
+	if !reflect.DeepEqual(body, want) {
+		t.Fatalf("snapshot body = %#v, want %#v", body, want)
+	}
+	stored, ok, err := readVMHibernationManifest(paths)
+	if err != nil || !ok || stored.MemoryPath != manifest.MemoryPath || stored.StatePath != paths.State {
+		t.Fatalf("manifest = %#v, %v, %v", stored, ok, err)
+	}
+}
+
+func TestHibernateVMProcessResumesAfterSnapshotFailure(t *testing.T) {
+	socket, requests := newFirecrackerRecordingServer(t, map[string]int{"/snapshot/create": http.StatusBadRequest})
+	paths := vmHibernatePathsForRoot(t.TempDir())
+	if err := os.MkdirAll(paths.Dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(paths.Memory, []byte("partial"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := hibernateVMProcess(context.Background(), socket, paths)
+	if err == nil || !strings.Contains(err.Error(), "create VM memory snapshot") {
+		t.Fatalf("hibernateVMProcess error = %v", err)
+	}
+	if want := []string{"PATCH /vm", "PUT /snapshot/create", "PATCH /vm"}; !reflect.DeepEqual(firecrackerRequestPaths(requests()), want) {
+		t.Fatalf("requests = %#v, want %#v", firecrackerRequestPaths(requests()), want)
+	}
+	if !strings.Contains(requests()[2].Body, "Resumed") {
+		t.Fatalf("last request = %#v, want resume", requests()[2])
+	}
+	for _, path := range []string{paths.Memory, paths.Manifest} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("%s left behind after failed hibernate: %v", path, err)
+		}
+	}
+}
+
+func TestRestoreVMHibernationLoadsAndConsumesSnapshot(t *testing.T) {
+	socket, requests := newFirecrackerRecordingServer(t, nil)
+	root := t.TempDir()
+	paths := vmHibernatePathsForRoot(root)
+	if _, err := hibernateVMProcess(context.Background(), socket, paths); err != nil {
+		t.Fatalf("hibernateVMProcess: %v", err)
+	}
+	if !vmHibernationPending(root) {
+		t.Fatal("hibernation not pending after snapshot")
+	}
+	cfg := VMConsoleProxyConfig{Service: "devbox", ServiceRoot: root, APISocket: socket, RestoreHibernation: true}
+	if err := restoreVMHibernation(context.Background(), cfg); err != nil {
+		t.Fatalf("restoreVMHibernation: %v", err)
+	}
+	last := requests()[len(requests())-1]
+	if last.Path != "/snapshot/load" {
+		t.Fatalf("last request = %#v, want snapshot load", last)
+	}
+	var body struct {
+		SnapshotPath string            `json:"snapshot_path"`
+		MemBackend   map[string]string `json:"mem_backend"`
+		ResumeVM     bool              `json:"resume_vm"`
+	}
+	if err := json.Unmarshal([]byte(last.Body), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.SnapshotPath != paths.State || body.MemBackend["backend_path"] != paths.Memory || body.MemBackend["backend_type"] != "File" || !body.ResumeVM {
+		t.Fatalf("load body = %#v", body)
+	}
+	if vmHibernationPending(root) {
+		t.Fatal("hibernation still pending after restore")
+	}
+}
+
+func TestRestoreVMHibernationFailureLeavesNoteAndDropsSnapshot(t *testing.T) {
+	socket, _ := newFirecrackerRecordingServer(t, map[string]int{"/snapshot/load": http.StatusBadRequest})
+	root := t.TempDir()
+	paths := vmHibernatePathsForRoot(root)
+	if _, err := hibernateVMProcess(context.Background(), socket, paths); err != nil {
+		t.Fatalf("hibernateVMProcess: %v", err)
+	}
+	err := restoreVMHibernation(context.Background(), VMConsoleProxyConfig{Service: "devbox", ServiceRoot: root, APISocket: socket, RestoreHibernation: true})
+	if err == nil || !strings.Contains(err.Error(), "resume VM devbox from hibernation") {
+		t.Fatalf("restoreVMHibernation error = %v", err)
+	}
+	if vmHibernationPending(root) {
+		t.Fatal("failed snapshot still pending; the next start would not cold boot")
+	}
+	if raw, err := os.ReadFile(paths.RestoreFailed); err != nil || !strings.Contains(string(raw), "snapshot/load") {
+		t.Fatalf("restore-failed note = %q, %v", raw, err)
+	}
+}
+
+func TestServerHibernateAndResumeVM(t *testing.T) {
+	server := newTestServer(t)
+	root := t.TempDir()
+	socket, _ := newFirecrackerRecordingServer(t, nil)
+	seedVMForHibernate(t, server, "devbox", root, socket)
+	units := stubVMHibernateHooks(t, true)
+
+	var out bytes.Buffer
+	if err := server.hibernateVM(context.Background(), "devbox", &out); err != nil {
+		t.Fatalf("hibernateVM: %v", err)
+	}
+	if !strings.Contains(out.String(), "Hibernated VM devbox") {
+		t.Fatalf("hibernate output = %q", out.String())
+	}
+	paths := vmHibernatePathsForRoot(root)
+	dv, err := server.getDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hibernation := dv.Services().Get("devbox").VM().Hibernation()
+	if !hibernation.Valid() || hibernation.MemoryPath() != paths.Memory || hibernation.CreatedAt() == "" {
+		t.Fatalf("hibernation record = %#v", hibernation.AsStruct())
+	}
+
+	vmHibernateIsRunning = func(*Server, string) (bool, error) { return false, nil }
+	vmHibernateStartUnit = func(name string) error {
+		*units = append(*units, "start "+name)
+		removeVMHibernationSnapshot(paths)
+		return nil
+	}
+	out.Reset()
+	if err := server.resumeVM(context.Background(), "devbox", &out); err != nil {
+		t.Fatalf("resumeVM: %v", err)
+	}
+	if want := []string{"stop devbox", "start devbox"}; !reflect.DeepEqual(*units, want) {
+		t.Fatalf("unit actions = %#v, want %#v", *units, want)
+	}
+	dv, err = server.getDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dv.Services().Get("devbox").VM().Hibernation().Valid() {
+		t.Fatalf("hibernation record not cleared: %#v", dv.Services().Get("devbox").VM().Hibernation().AsStruct())
+	}
+}
+
+func TestServerHibernateVMRequiresRunningVM(t *testing.T) {
+	server := newTestServer(t)
+	seedVMForHibernate(t, server, "devbox", t.TempDir(), "/run/devbox.sock")
+	stubVMHibernateHooks(t, false)
+	if err := server.hibernateVM(context.Background(), "devbox", io.Discard); err == nil || !strings.Contains(err.Error(), "not running") {
+		t.Fatalf("hibernateVM error = %v", err)
+	}
+	if err := server.resumeVM(context.Background(), "devbox", io.Discard); err == nil || !strings.Contains(err.Error(), "not hibernated") {
+		t.Fatalf("resumeVM error = %v", err)
+	}
+}
+
+func TestWaitVMHibernationConsumedReportsRestoreFailure(t *testing.T) {
+	paths := vmHibernatePathsForRoot(t.TempDir())
+	if err := os.MkdirAll(paths.Dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(paths.RestoreFailed, []byte("resume VM devbox from hibernation: bad snapshot\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := waitVMHibernationConsumed(ctx, paths); err == nil || !strings.Contains(err.Error(), "cold booted") {
+		t.Fatalf("waitVMHibernationConsumed error = %v", err)
+	}
+}
+
+func TestSetVMHibernationPolicyWritesMarker(t *testing.T) {
+	withVMPowerSystemd(t)
+	server := newTestServer(t)
+	root := t.TempDir()
+	seedVMForHibernate(t, server, "devbox", root, "/run/devbox.sock")
+	paths := vmHibernatePathsForRoot(root)
+	if err := server.setVMHibernationPolicy("devbox", true, io.Discard); err != nil {
+		t.Fatalf("setVMHibernationPolicy on: %v", err)
+	}
+	if _, err := os.Stat(paths.OnShutdown); err != nil {
+		t.Fatalf("marker missing: %v", err)
+	}
+	dv, _ := server.getDB()
+	if !dv.Services().Get("devbox").VM().Hibernation().OnHostShutdown() {
+		t.Fatal("OnHostShutdown not recorded")
+	}
+	if dropIn, err := os.ReadFile(vmHibernateDropInPath("devbox")); err != nil || !strings.Contains(string(dropIn), "TimeoutStopSec=120") {
+		t.Fatalf("drop-in with hibernation = %q, %v", dropIn, err)
+	}
+	if _, err := os.Stat(vmPowerDropInPath("devbox")); !os.IsNotExist(err) {
+		t.Fatalf("power drop-in written for default VM: %v", err)
+	}
+	if err := server.setVMHibernationPolicy("devbox", false, io.Discard); err != nil {
+		t.Fatalf("setVMHibernationPolicy off: %v", err)
+	}
+	if _, err := os.Stat(paths.OnShutdown); !os.IsNotExist(err) {
+		t.Fatalf("marker still present: %v", err)
+	}
+	dv, _ = server.getDB()
+	if dv.Services().Get("devbox").VM().Hibernation().Valid() {
+		t.Fatal("empty hibernation record not removed")
+	}
+	if _, err := os.Stat(vmHibernateDropInPath("devbox")); !os.IsNotExist(err) {
+		t.Fatalf("hibernate drop-in still present: %v", err)
+	}
+}
+
+func TestHibernateVMOnHostShutdownOnlyWhenHostStopping(t *testing.T) {
+	socket, requests := newFirecrackerRecordingServer(t, nil)
+	root := t.TempDir()
+	paths := vmHibernatePathsForRoot(root)
+	if err := os.MkdirAll(paths.Dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(paths.OnShutdown, []byte("on\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	stopping := false
+	old := vmHibernateHostStopping
+	vmHibernateHostStopping = func() bool { return stopping }
+	t.Cleanup(func() { vmHibernateHostStopping = old })
+
+	hibernateVMOnHostShutdown(root, socket)
+	if len(requests()) != 0 || vmHibernationPending(root) {
+		t.Fatalf("hibernated on a unit stop: %#v", requests())
+	}
+	stopping = true
+	hibernateVMOnHostShutdown(root, socket)
+	if !vmHibernationPending(root) {
+		t.Fatal("VM not hibernated on host shutdown")
+	}
+}
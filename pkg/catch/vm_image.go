@@ -149,12 +149,12 @@ func (a vmProvisionArtifacts) Components() *db.VMComponentsConfig {
 }
 
 type vmProvisionComponentSelection struct {
-	GuestBaseID             string
-	GuestBaseManifestSHA256 string
-	KernelID                string
-	KernelManifestSHA256    string
-	RuntimeID               string
-	RuntimeManifestSHA256   string
+	GuestBaseID             string `json:"guestBaseID,omitempty"`
+	GuestBaseManifestSHA256 string `json:"guestBaseManifestSHA256,omitempty"`
+	KernelID                string `json:"kernelID,omitempty"`
+	KernelManifestSHA256    string `json:"kernelManifestSHA256,omitempty"`
+	RuntimeID               string `json:"runtimeID,omitempty"`
+	RuntimeManifestSHA256   string `json:"runtimeManifestSHA256,omitempty"`
 }
 
 type vmProvisionComponentRefs struct {
@@ -1229,7 +1229,7 @@ func (m vmImageManifest) validateRuntimeMetadata() error {
 		return fmt.Errorf("VM image manifest default_user %q is invalid", m.DefaultUser)
 	}
 	switch strings.TrimSpace(m.MetadataDriver) {
-	case "", "ubuntu", "nixos":
+	case "", "ubuntu", "nixos", "debian", "fedora":
 	default:
 		return fmt.Errorf("VM image manifest metadata_driver %q is unsupported", m.MetadataDriver)
 	}
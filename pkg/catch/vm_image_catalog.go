@@ -186,7 +186,7 @@ func (i vmImageCatalogImage) validateRuntimeMetadata(payload string) error {
 		return fmt.Errorf("VM image catalog entry %s has invalid default_user %q", payload, i.DefaultUser)
 	}
 	switch strings.TrimSpace(i.MetadataDriver) {
-	case "", "ubuntu", "nixos":
+	case "", "ubuntu", "nixos", "debian", "fedora":
 	default:
 		return fmt.Errorf("VM image catalog entry %s has unsupported metadata_driver %q", payload, i.MetadataDriver)
 	}
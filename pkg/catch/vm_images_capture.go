@@ -0,0 +1,371 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/yeetrun/yeet/pkg/db"
+	"golang.org/x/sys/unix"
+)
+
+const vmImageCaptureRootFSName = "rootfs.ext4"
+
+var (
+	vmImageCaptureRunner    vmCommandRunner = runVMCommand
+	vmImageCaptureIsRunning                 = (*Server).IsServiceRunning
+)
+
+// vmImageCaptureScrubGlobs are the machine-specific files a captured root
+// filesystem must not carry into new VMs. Provisioning rewrites all of them
+// from the new VM's metadata on first boot.
+var vmImageCaptureScrubGlobs = []string{
+	"etc/ssh/ssh_host_*",
+	"etc/hostname",
+	"etc/netplan/99-yeet.yaml",
+	"etc/systemd/network/10-yeet-*.network",
+	"etc/yeet-vm/hostname",
+	"etc/yeet-vm/systemd-network/10-yeet-*.network",
+	"etc/systemd/system/yeet-user-data.service",
+	"etc/systemd/system/multi-user.target.wants/yeet-user-data.service",
+	"usr/local/lib/yeet-vm/user-data",
+	strings.TrimPrefix(vmUserDataStatusPath, "/"),
+}
+
+type vmImageCaptureRequest struct {
+	VM       string
+	Name     string
+	Snapshot string
+}
+
+// captureVMImage copies a VM's root disk, or one of its recovery points, into
+// a local VM image that new VMs can be created from.
+func (s *Server) captureVMImage(ctx context.Context, importer localVMImageImporter, req vmImageCaptureRequest, w io.Writer) (localVMImageRef, error) {
+	if err := validateLocalVMImageNameForImport(req.Name, importer.Catalog); err != nil {
+		return localVMImageRef{}, err
+	}
+	service, vm, err := s.vmSnapshotService(req.VM)
+	if err != nil {
+		return localVMImageRef{}, err
+	}
+	// Stage next to the image cache: a root disk rarely fits in a tmpfs /tmp.
+	if err := os.MkdirAll(importer.CacheRoot, 0o755); err != nil {
+		return localVMImageRef{}, fmt.Errorf("create VM image cache: %w", err)
+	}
+	stagingDir, err := os.MkdirTemp(importer.CacheRoot, ".capture-*")
+	if err != nil {
+		return localVMImageRef{}, fmt.Errorf("create VM capture staging dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(stagingDir) }()
+
+	rootFS := filepath.Join(stagingDir, vmImageCaptureRootFSName)
+	if strings.TrimSpace(req.Snapshot) != "" {
+		err = s.copyVMRecoveryPointDisk(ctx, service, req.Snapshot, rootFS, w)
+	} else {
+		err = s.copyLiveVMDisk(ctx, service.Name, vm, rootFS, w)
+	}
+	if err != nil {
+		return localVMImageRef{}, err
+	}
+	writef(w, "Scrubbing machine-specific state from %s.\n", req.VM)
+	guestInit, err := scrubVMImageCaptureRootFS(ctx, rootFS, vmImageCaptureRunner)
+	if err != nil {
+		return localVMImageRef{}, err
+	}
+	if err := writeVMImageCaptureManifest(stagingDir, req.Name, vm.Image, guestInit); err != nil {
+		return localVMImageRef{}, err
+	}
+	return importer.ImportDir(ctx, localVMImageImportRequest{Name: req.Name}, stagingDir)
+}
+
+// copyLiveVMDisk copies the current root disk. A running VM is paused, with
+// its filesystems frozen when the guest agent allows it, only long enough to
+// take a zvol snapshot or, for raw disks, to copy the disk file.
+func (s *Server) copyLiveVMDisk(ctx context.Context, name string, vm db.VMConfig, dst string, w io.Writer) error {
+	running, err := vmImageCaptureIsRunning(s, name)
+	if err != nil {
+		return err
+	}
+	if vm.Disk.Backend != vmDiskBackendZVOL {
+		writef(w, "Copying root disk of %s.\n", name)
+		return s.withVMPausedForCapture(ctx, name, vm, running, func() error {
+			return vmImageCaptureRunner(ctx, []string{"cp", "--sparse=always", vm.Disk.Path, dst})
+		})
+	}
+	dataset, err := vmSnapshotDataset(vm.Disk)
+	if err != nil {
+		return err
+	}
+	suffix, err := currentVMRestoreTempSuffixFunc()()
+	if err != nil {
+		return fmt.Errorf("generate VM capture snapshot suffix: %w", err)
+	}
+	snapshot := dataset + "@capture-" + suffix
+	err = s.withVMPausedForCapture(ctx, name, vm, running, func() error {
+		runner := s.zfsRunner
+		if runner == nil {
+			runner = runZFSCommand
+		}
+		_, stderr, err := runner(ctx, "snapshot", snapshot)
+		if err != nil {
+			return formatZFSCommandError("zfs snapshot "+snapshot, stderr, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	writef(w, "Copying root disk of %s.\n", name)
+	copyErr := s.copyVMZVOLSnapshotToFile(ctx, snapshot, dataset, dst)
+	return errors.Join(copyErr, destroySnapshot(ctx, s.zfsRunner, snapshot))
+}
+
+func (s *Server) withVMPausedForCapture(ctx context.Context, name string, vm db.VMConfig, running bool, fn func() error) error {
+	if !running {
+		return fn()
+	}
+	socket := strings.TrimSpace(vm.Sockets.APISocketPath)
+	if socket == "" {
+		return fmt.Errorf("service %q has no Firecracker API socket", name)
+	}
+	controller := currentVMSnapshotController()
+	thaw := freezeVMSnapshotGuest(ctx, name, vm.Sockets.VsockSocketPath)
+	if err := controller.Pause(ctx, socket); err != nil {
+		thaw()
+		return fmt.Errorf("pause VM %q: %w", name, err)
+	}
+	var err error
+	if vm.Disk.Backend == vmDiskBackendZVOL {
+		err = currentVMSnapshotDiskFlusher()(vm.Disk.Path)
+	}
+	if err == nil {
+		err = fn()
+	}
+	resumeCtx, cancel := vmSnapshotRecoveryContext(ctx)
+	defer cancel()
+	resumeErr := controller.Resume(resumeCtx, socket)
+	thaw()
+	if resumeErr != nil {
+		return errors.Join(err, fmt.Errorf("resume VM %q: %w", name, resumeErr))
+	}
+	return err
+}
+
+func (s *Server) copyVMRecoveryPointDisk(ctx context.Context, service *db.Service, selector, dst string, w io.Writer) error {
+	point, _, err := s.resolveRecoveryPoint(ctx, service.Name, selector)
+	if err != nil {
+		return err
+	}
+	if err := validateVMRecoveryPoint(service, point); err != nil {
+		return err
+	}
+	writef(w, "Copying root disk of %s from recovery point %s.\n", service.Name, point.ShortName)
+	return s.copyVMZVOLSnapshotToFile(ctx, point.Name, point.Dataset, dst)
+}
+
+// copyVMZVOLSnapshotToFile reads a zvol snapshot through a temporary clone,
+// because snapshot devices are hidden unless snapdev=visible.
+func (s *Server) copyVMZVOLSnapshotToFile(ctx context.Context, snapshot, dataset, dst string) error {
+	suffix, err := currentVMRestoreTempSuffixFunc()()
+	if err != nil {
+		return fmt.Errorf("generate temporary VM capture dataset suffix: %w", err)
+	}
+	tempDataset := strings.Trim(dataset, "/") + "-capture-" + suffix
+	if err := zfsCloneSnapshot(ctx, s.zfsRunner, snapshot, tempDataset); err != nil {
+		return err
+	}
+	device := zvolDevicePath(tempDataset)
+	copyErr := currentVMRestoreZVOLDeviceWaiter()(ctx, device)
+	if copyErr == nil {
+		copyErr = vmImageCaptureRunner(ctx, []string{"dd", "if=" + device, "of=" + dst, "bs=16M", "conv=sparse"})
+	}
+	return vmRestoreTempCleanupError(tempDataset, copyErr, zfsDestroyDataset(ctx, s.zfsRunner, tempDataset))
+}
+
+// scrubVMImageCaptureRootFS mounts a captured root filesystem and removes its
+// machine-specific state. It reports whether the image boots through the yeet
+// guest init so the captured manifest keeps fast boot.
+func scrubVMImageCaptureRootFS(ctx context.Context, rootFS string, runner vmCommandRunner) (guestInit bool, retErr error) {
+	mountRoot, err := os.MkdirTemp("", "yeet-vm-capture-rootfs-*")
+	if err != nil {
+		return false, fmt.Errorf("create VM capture mount dir: %w", err)
+	}
+	defer func() {
+		retErr = joinVMMetadataDeferredError(retErr, os.RemoveAll(mountRoot), "remove VM capture mount dir")
+	}()
+	if err := runner(ctx, vmRootFSMountCommand(rootFS, mountRoot)); err != nil {
+		return false, fmt.Errorf("mount captured VM rootfs: %w", err)
+	}
+	defer func() {
+		retErr = joinVMMetadataDeferredError(retErr, runner(ctx, []string{"umount", mountRoot}), "unmount captured VM rootfs")
+	}()
+	if err := scrubVMGuestMachineState(mountRoot); err != nil {
+		return false, fmt.Errorf("scrub captured VM rootfs: %w", err)
+	}
+	_, err = os.Lstat(filepath.Join(mountRoot, vmGuestInitPath))
+	return err == nil, nil
+}
+
+// scrubVMGuestMachineState removes machine state from the guest filesystem
+// at root. Every path is resolved beneath root without following symlinks;
+// a symlinked directory on the way fails the scrub.
+func scrubVMGuestMachineState(root string) error {
+	rootDir, err := os.Open(root)
+	if err != nil {
+		return err
+	}
+	defer rootDir.Close()
+	rootFD := int(rootDir.Fd())
+	for _, pattern := range vmImageCaptureScrubGlobs {
+		if err := removeVMGuestMatches(rootFD, pattern); err != nil {
+			return err
+		}
+	}
+	// An empty machine-id makes systemd generate a new one on first boot;
+	// deleting it would instead leave the guest in first-boot setup.
+	if err := truncateVMGuestFile(rootFD, "etc/machine-id"); err != nil {
+		return err
+	}
+	return withVMGuestDir(rootFD, "var/lib/dbus", func(dirFD int) error {
+		var st unix.Stat_t
+		if err := unix.Fstatat(dirFD, "machine-id", &st, unix.AT_SYMLINK_NOFOLLOW); err != nil || st.Mode&unix.S_IFMT != unix.S_IFREG {
+			return nil
+		}
+		return ignoreVMGuestNotExist(unix.Unlinkat(dirFD, "machine-id", 0))
+	})
+}
+
+// removeVMGuestMatches removes the entries matching pattern, whose last
+// element alone may hold wildcards. Symlinks are removed, never followed.
+func removeVMGuestMatches(rootFD int, pattern string) error {
+	dir, base := path.Split(pattern)
+	return withVMGuestDir(rootFD, path.Clean(dir), func(dirFD int) error {
+		names, err := readVMGuestDirNames(dirFD)
+		if err != nil {
+			return fmt.Errorf("read guest %s: %w", dir, err)
+		}
+		for _, name := range names {
+			if ok, _ := path.Match(base, name); !ok {
+				continue
+			}
+			if err := ignoreVMGuestNotExist(unix.Unlinkat(dirFD, name, 0)); err != nil {
+				return fmt.Errorf("remove guest %s: %w", path.Join(dir, name), err)
+			}
+		}
+		return nil
+	})
+}
+
+func truncateVMGuestFile(rootFD int, name string) error {
+	fd, err := openVMGuestPathAt(rootFD, name, unix.O_WRONLY|unix.O_NONBLOCK)
+	if errors.Is(err, unix.ENOENT) {
+		return nil
+	}
+	if errors.Is(err, unix.ELOOP) || errors.Is(err, unix.EXDEV) || errors.Is(err, unix.ENXIO) {
+		return fmt.Errorf("guest %s is not a regular file beneath the root", name)
+	}
+	if err != nil {
+		return fmt.Errorf("open guest %s: %w", name, err)
+	}
+	f := os.NewFile(uintptr(fd), name)
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("guest %s is not a regular file", name)
+	}
+	return f.Truncate(0)
+}
+
+// withVMGuestDir runs fn on the guest directory dir. A missing directory is
+// skipped; one reached through a symlink is an error.
+func withVMGuestDir(rootFD int, dir string, fn func(dirFD int) error) error {
+	fd, err := openVMGuestPathAt(rootFD, dir, unix.O_RDONLY|unix.O_DIRECTORY)
+	if errors.Is(err, unix.ENOENT) || errors.Is(err, unix.ENOTDIR) {
+		return nil
+	}
+	if errors.Is(err, unix.ELOOP) || errors.Is(err, unix.EXDEV) {
+		return fmt.Errorf("guest %s is reached through a symlink; refusing to scrub it", dir)
+	}
+	if err != nil {
+		return fmt.Errorf("open guest %s: %w", dir, err)
+	}
+	defer unix.Close(fd)
+	return fn(fd)
+}
+
+func readVMGuestDirNames(dirFD int) ([]string, error) {
+	// Read through a duplicate so closing the *os.File leaves dirFD open.
+	dup, err := unix.Dup(dirFD)
+	if err != nil {
+		return nil, err
+	}
+	f := os.NewFile(uintptr(dup), "")
+	defer f.Close()
+	return f.Readdirnames(-1)
+}
+
+// openVMGuestPathByComponent opens name one element at a time, refusing
+// symlinks, where openat2 is unavailable.
+func openVMGuestPathByComponent(rootFD int, name string, flags int) (int, error) {
+	elems := strings.Split(path.Clean(name), "/")
+	fd := rootFD
+	for i, elem := range elems {
+		if elem == ".." {
+			return -1, unix.EXDEV
+		}
+		f := unix.O_RDONLY | unix.O_DIRECTORY
+		if i == len(elems)-1 {
+			f = flags
+		}
+		next, err := unix.Openat(fd, elem, f|unix.O_CLOEXEC|unix.O_NOFOLLOW, 0)
+		if fd != rootFD {
+			_ = unix.Close(fd)
+		}
+		if err != nil {
+			return -1, err
+		}
+		fd = next
+	}
+	return fd, nil
+}
+
+func ignoreVMGuestNotExist(err error) error {
+	if errors.Is(err, unix.ENOENT) {
+		return nil
+	}
+	return err
+}
+
+func writeVMImageCaptureManifest(dir, name string, image db.VMImageConfig, guestInit bool) error {
+	manifest := vmImageManifest{
+		Name:            name,
+		ImageProfile:    "captured",
+		Distro:          image.Distro,
+		DistroVersion:   image.DistroVersion,
+		DefaultUser:     image.DefaultUser,
+		GuestSystemInit: image.GuestSystemInit,
+		MetadataDriver:  image.MetadataDriver,
+		RootFS:          vmImageCaptureRootFSName,
+	}
+	if guestInit {
+		manifest.GuestInit = vmGuestInitPath
+	}
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "manifest.json"), append(raw, '\n'), 0o644)
+}
@@ -0,0 +1,11 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin
+
+package catch
+
+func openVMGuestPathAt(rootFD int, name string, flags int) (int, error) {
+	return openVMGuestPathByComponent(rootFD, name, flags)
+}
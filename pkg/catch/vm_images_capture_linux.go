@@ -0,0 +1,28 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package catch
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// openVMGuestPathAt opens name beneath the guest root rootFD without
+// following any symlink, so a captured guest cannot point the scrub at host
+// files.
+func openVMGuestPathAt(rootFD int, name string, flags int) (int, error) {
+	how := &unix.OpenHow{
+		Flags:   uint64(flags | unix.O_CLOEXEC | unix.O_NOFOLLOW),
+		Resolve: unix.RESOLVE_BENEATH | unix.RESOLVE_NO_MAGICLINKS | unix.RESOLVE_NO_SYMLINKS,
+	}
+	fd, err := unix.Openat2(rootFD, name, how)
+	if err == nil || !errors.Is(err, unix.ENOSYS) {
+		return fd, err
+	}
+	return openVMGuestPathByComponent(rootFD, name, flags)
+}
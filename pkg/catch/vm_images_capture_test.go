@@ -0,0 +1,223 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func stubVMImageCapture(t *testing.T, running bool, guest map[string]string) *[]string {
+	t.Helper()
+	oldRunner, oldRunning := vmImageCaptureRunner, vmImageCaptureIsRunning
+	t.Cleanup(func() { vmImageCaptureRunner, vmImageCaptureIsRunning = oldRunner, oldRunning })
+	var calls []string
+	vmImageCaptureIsRunning = func(*Server, string) (bool, error) { return running, nil }
+	vmImageCaptureRunner = func(_ context.Context, command []string) error {
+		calls = append(calls, command[0])
+		switch command[0] {
+		case "cp":
+			raw, err := os.ReadFile(command[2])
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(command[3], raw, 0o644)
+		case "dd":
+			return os.WriteFile(strings.TrimPrefix(command[2], "of="), []byte("zvol-disk"), 0o644)
+		case "mount":
+			root := command[len(command)-1]
+			for rel, content := range guest {
+				if err := os.MkdirAll(filepath.Dir(filepath.Join(root, rel)), 0o755); err != nil {
+					return err
+				}
+				if err := os.WriteFile(filepath.Join(root, rel), []byte(content), 0o644); err != nil {
+					return err
+				}
+			}
+		case "umount":
+			for rel := range guest {
+				if _, err := os.Stat(filepath.Join(command[1], rel)); err == nil {
+					calls = append(calls, "kept:"+rel)
+				}
+			}
+		}
+		return nil
+	}
+	return &calls
+}
+
+func testLocalVMImageImporter(t *testing.T) localVMImageImporter {
+	t.Helper()
+	return localVMImageImporter{
+		CacheRoot: t.TempDir(),
+		EnsureManagedAsset: func(context.Context) (vmImageAsset, error) {
+			return fakeManagedVMImageAsset(t), nil
+		},
+	}
+}
+
+func TestCaptureVMImageFromStoppedRawVM(t *testing.T) {
+	server := newTestServer(t)
+	seedVMForResize(t, server, "devbox", t.TempDir(), vmDiskBackendRaw)
+	calls := stubVMImageCapture(t, false, map[string]string{
+		"etc/ssh/ssh_host_ed25519_key":             "private",
+		"etc/machine-id":                           "0123456789abcdef\n",
+		"etc/hostname":                             "devbox\n",
+		"etc/systemd/network/10-yeet-eth0.network": "[Match]\n",
+		"var/lib/yeet-vm/user-data.status":         "done\n",
+		"etc/passwd":                               "root:x:0:0::/root:/bin/bash\n",
+		"usr/local/lib/yeet-vm/yeet-init":          "#!/bin/sh\n",
+	})
+	importer := testLocalVMImageImporter(t)
+
+	ref, err := server.captureVMImage(context.Background(), importer, vmImageCaptureRequest{VM: "devbox", Name: "local/golden"}, io.Discard)
+	if err != nil {
+		t.Fatalf("captureVMImage: %v", err)
+	}
+	want := []string{"cp", "mount", "umount", "kept:etc/machine-id", "kept:etc/passwd", "kept:usr/local/lib/yeet-vm/yeet-init"}
+	got := append([]string(nil), *calls...)
+	if len(got) > 3 {
+		sort.Strings(got[3:])
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("calls = %#v, want %#v", got, want)
+	}
+	if ref.Payload != "vm://local/golden" {
+		t.Fatalf("payload = %q", ref.Payload)
+	}
+	rootFS, err := os.ReadFile(filepath.Join(ref.Root, ref.RootFS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rootFS) != "disk" {
+		t.Fatalf("captured rootfs = %q, want VM disk contents", rootFS)
+	}
+	manifest, err := readLocalVMImageBlobManifest(ref.Root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.GuestInit != vmGuestInitPath || manifest.ImageProfile != "captured" {
+		t.Fatalf("manifest = %#v", manifest)
+	}
+	entries, err := os.ReadDir(importer.CacheRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".capture-") {
+			t.Fatalf("staging dir %s left behind", entry.Name())
+		}
+	}
+}
+
+func TestCaptureVMImagePausesRunningZVOLVMOnlyForSnapshot(t *testing.T) {
+	stubVMSnapshotDiskFlusher(t)
+	server := newTestServer(t)
+	seedVMForResize(t, server, "devbox", t.TempDir(), vmDiskBackendZVOL)
+	stubVMImageCapture(t, true, nil)
+	pauser := &recordingVMFirecrackerPauser{}
+	oldController, oldSuffix, oldWaiter := vmSnapshotFirecracker, vmRestoreTempSuffixFunc, vmRestoreZVOLDeviceWaiter
+	vmSnapshotFirecracker = pauser
+	vmRestoreTempSuffixFunc = func() (string, error) { return "abc123", nil }
+	vmRestoreZVOLDeviceWaiter = func(context.Context, ...string) error { return nil }
+	t.Cleanup(func() {
+		vmSnapshotFirecracker, vmRestoreTempSuffixFunc, vmRestoreZVOLDeviceWaiter = oldController, oldSuffix, oldWaiter
+	})
+	var zfsCalls []string
+	server.zfsRunner = func(_ context.Context, args ...string) (string, string, error) {
+		zfsCalls = append(zfsCalls, strings.Join(args, " ")+" "+strings.Join(pauser.calls, ","))
+		return "", "", nil
+	}
+
+	dataset := "flash/yeet/vms/devbox/vm/d-abc/root"
+	dst := filepath.Join(t.TempDir(), "rootfs.ext4")
+	dv, err := server.getDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm := dv.Services().Get("devbox").VM().AsStruct()
+	if err := server.copyLiveVMDisk(context.Background(), "devbox", *vm, dst, io.Discard); err != nil {
+		t.Fatalf("copyLiveVMDisk: %v", err)
+	}
+	want := []string{
+		"snapshot " + dataset + "@capture-abc123 pause",
+		"clone " + dataset + "@capture-abc123 " + dataset + "-capture-abc123 pause,resume",
+		"destroy -r " + dataset + "-capture-abc123 pause,resume",
+		"destroy " + dataset + "@capture-abc123 pause,resume",
+	}
+	if !reflect.DeepEqual(zfsCalls, want) {
+		t.Fatalf("zfs calls = %#v, want %#v", zfsCalls, want)
+	}
+	if raw, err := os.ReadFile(dst); err != nil || string(raw) != "zvol-disk" {
+		t.Fatalf("captured disk = %q, %v", raw, err)
+	}
+}
+
+func TestScrubVMGuestMachineStateKeepsDBusMachineIDSymlink(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "etc", "machine-id"), "0123456789abcdef\n", 0o644)
+	if err := os.MkdirAll(filepath.Join(root, "var", "lib", "dbus"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/etc/machine-id", filepath.Join(root, "var", "lib", "dbus", "machine-id")); err != nil {
+		t.Fatal(err)
+	}
+	if err := scrubVMGuestMachineState(root); err != nil {
+		t.Fatalf("scrubVMGuestMachineState: %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(root, "etc", "machine-id")); err != nil || info.Size() != 0 {
+		t.Fatalf("machine-id = %v, %v; want empty file", info, err)
+	}
+	if _, err := os.Lstat(filepath.Join(root, "var", "lib", "dbus", "machine-id")); err != nil {
+		t.Fatalf("dbus machine-id symlink removed: %v", err)
+	}
+}
+
+func TestScrubVMGuestMachineStateRefusesSymlinkedDirs(t *testing.T) {
+	host := t.TempDir()
+	writeFile(t, filepath.Join(host, "hostname"), "host\n", 0o644)
+	writeFile(t, filepath.Join(host, "ssh_host_ed25519_key"), "key\n", 0o600)
+	root := t.TempDir()
+	if err := os.Symlink(host, filepath.Join(root, "etc")); err != nil {
+		t.Fatal(err)
+	}
+	err := scrubVMGuestMachineState(root)
+	if err == nil || !strings.Contains(err.Error(), "through a symlink") {
+		t.Fatalf("scrubVMGuestMachineState error = %v, want symlinked directory refused", err)
+	}
+	for _, name := range []string{"hostname", "ssh_host_ed25519_key"} {
+		if _, err := os.Stat(filepath.Join(host, name)); err != nil {
+			t.Fatalf("host %s touched through guest symlink: %v", name, err)
+		}
+	}
+}
+
+func TestScrubVMGuestMachineStateRemovesSymlinksWithoutFollowing(t *testing.T) {
+	host := t.TempDir()
+	target := filepath.Join(host, "hostname")
+	writeFile(t, target, "host\n", 0o644)
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "etc", "ssh", "ssh_host_rsa_key"), "key\n", 0o600)
+	if err := os.Symlink(target, filepath.Join(root, "etc", "hostname")); err != nil {
+		t.Fatal(err)
+	}
+	if err := scrubVMGuestMachineState(root); err != nil {
+		t.Fatalf("scrubVMGuestMachineState: %v", err)
+	}
+	for _, name := range []string{"etc/hostname", "etc/ssh/ssh_host_rsa_key"} {
+		if _, err := os.Lstat(filepath.Join(root, name)); !os.IsNotExist(err) {
+			t.Fatalf("guest %s survived scrub: %v", name, err)
+		}
+	}
+	if raw, err := os.ReadFile(target); err != nil || string(raw) != "host\n" {
+		t.Fatalf("symlink target = %q, %v; want untouched", raw, err)
+	}
+}
@@ -18,7 +18,7 @@ import (
 	"github.com/yeetrun/yeet/pkg/cmdutil"
 )
 
-const vmImagesUsage = "usage: yeet vm images [ls|catalog|update|import <name>|rm <name>|prune]"
+const vmImagesUsage = "usage: yeet vm images [ls|catalog|update|import <name>|capture <vm> <name>|rm <name>|prune]"
 
 type vmImageListRow struct {
 	Payload      string `json:"payload"`
@@ -83,6 +83,11 @@ func (e *ttyExecer) vmImagesActionCmdFunc(flags cli.VMImagesFlags, action string
 		return e.vmImagesUpdateCmdFunc(flags, args)
 	case "import":
 		return vmImagesNameAction(args, func(name string) error { return e.vmImagesImportCmdFunc(flags, name) })
+	case "capture":
+		if len(args) != 2 {
+			return fmt.Errorf("%s", vmImagesUsage)
+		}
+		return e.vmImagesCaptureCmdFunc(flags, args[0], args[1])
 	case "rm":
 		return vmImagesNameAction(args, func(name string) error { return e.vmImagesRemoveCmdFunc(flags, name) })
 	case "prune":
@@ -302,22 +307,10 @@ func (e *ttyExecer) vmImagesImportCmdFunc(flags cli.VMImagesFlags, name string)
 	if !flags.Stdin {
 		return fmt.Errorf("use yeet vm images import from the client")
 	}
-	cache := e.vmImageCache()
-	catalog, err := cache.FetchCatalog(e.vmImagesContext())
+	importer, err := e.localVMImageImporter(flags)
 	if err != nil {
 		return err
 	}
-	defaultImage, ok := catalog.DefaultImage()
-	if !ok {
-		return fmt.Errorf("VM image catalog has no default image for local import")
-	}
-	importer := localVMImageImporter{
-		CacheRoot: cache.Root,
-		Catalog:   &catalog,
-		EnsureManagedAsset: func(ctx context.Context) (vmImageAsset, error) {
-			return vmImageEnsureCatalogFunc(ctx, cache, defaultImage, e.vmImagesProgressUI(flags))
-		},
-	}
 	ref, err := importer.Import(e.vmImagesContext(), localVMImageImportRequest{
 		Name:             name,
 		Reader:           e.payloadReader(),
@@ -329,6 +322,47 @@ func (e *ttyExecer) vmImagesImportCmdFunc(flags cli.VMImagesFlags, name string)
 	return renderVMImageListRows(e.rw, flags.Format, []vmImageListRow{vmImageListRowFromLocalRef(ref, "imported")})
 }
 
+func (e *ttyExecer) vmImagesCaptureCmdFunc(flags cli.VMImagesFlags, vm, name string) error {
+	importer, err := e.localVMImageImporter(flags)
+	if err != nil {
+		return err
+	}
+	var progress io.Writer = e.rw
+	if strings.TrimSpace(flags.Format) != "table" {
+		progress = io.Discard
+	}
+	ref, err := e.s.captureVMImage(e.vmImagesContext(), importer, vmImageCaptureRequest{
+		VM:       vm,
+		Name:     name,
+		Snapshot: flags.Snapshot,
+	}, progress)
+	if err != nil {
+		return err
+	}
+	return renderVMImageListRows(e.rw, flags.Format, []vmImageListRow{vmImageListRowFromLocalRef(ref, "captured")})
+}
+
+// localVMImageImporter returns an importer that pairs local root filesystems
+// with the kernel and runtime of the catalog's default image.
+func (e *ttyExecer) localVMImageImporter(flags cli.VMImagesFlags) (localVMImageImporter, error) {
+	cache := e.vmImageCache()
+	catalog, err := cache.FetchCatalog(e.vmImagesContext())
+	if err != nil {
+		return localVMImageImporter{}, err
+	}
+	defaultImage, ok := catalog.DefaultImage()
+	if !ok {
+		return localVMImageImporter{}, fmt.Errorf("VM image catalog has no default image for local import")
+	}
+	return localVMImageImporter{
+		CacheRoot: cache.Root,
+		Catalog:   &catalog,
+		EnsureManagedAsset: func(ctx context.Context) (vmImageAsset, error) {
+			return vmImageEnsureCatalogFunc(ctx, cache, defaultImage, e.vmImagesProgressUI(flags))
+		},
+	}, nil
+}
+
 func (e *ttyExecer) vmImagesRemoveCmdFunc(flags cli.VMImagesFlags, name string) error {
 	if !flags.Yes {
 		return fmt.Errorf("rerun with --yes to remove local VM image %q", name)
@@ -1422,7 +1422,7 @@ func TestVMImagesCmdRejectsInvalidAction(t *testing.T) {
 	server := newTestServer(t)
 	execer := &ttyExecer{ctx: context.Background(), s: server, rw: &bytes.Buffer{}}
 	err := execer.vmImagesCmdFunc(cli.VMImagesFlags{Format: "table"}, []string{"refresh"})
-	if err == nil || !strings.Contains(err.Error(), "usage: yeet vm images [ls|catalog|update|import <name>|rm <name>|prune]") {
+	if err == nil || !strings.Contains(err.Error(), "usage: yeet vm images [ls|catalog|update|import <name>|capture <vm> <name>|rm <name>|prune]") {
 		t.Fatalf("error = %v", err)
 	}
 }
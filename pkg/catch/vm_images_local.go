@@ -110,13 +110,35 @@ func (i localVMImageImporter) Import(ctx context.Context, req localVMImageImport
 	return i.importExtracted(cacheRoot, req, managed, stagingDir)
 }
 
+// ImportDir imports a bundle that is already laid out in dir, such as a
+// captured VM disk. req.Reader is ignored.
+func (i localVMImageImporter) ImportDir(ctx context.Context, req localVMImageImportRequest, dir string) (localVMImageRef, error) {
+	cacheRoot, err := i.validateImporter(req.Name)
+	if err != nil {
+		return localVMImageRef{}, err
+	}
+	managed, err := i.EnsureManagedAsset(ctx)
+	if err != nil {
+		return localVMImageRef{}, fmt.Errorf("ensure managed VM image asset: %w", err)
+	}
+	return i.importExtracted(cacheRoot, req, managed, dir)
+}
+
 func (i localVMImageImporter) validateImportRequest(req localVMImageImportRequest) (string, error) {
-	if err := validateLocalVMImageNameForImport(req.Name, i.Catalog); err != nil {
+	cacheRoot, err := i.validateImporter(req.Name)
+	if err != nil {
 		return "", err
 	}
 	if req.Reader == nil {
 		return "", fmt.Errorf("local VM image bundle reader is required")
 	}
+	return cacheRoot, nil
+}
+
+func (i localVMImageImporter) validateImporter(name string) (string, error) {
+	if err := validateLocalVMImageNameForImport(name, i.Catalog); err != nil {
+		return "", err
+	}
 	cacheRoot := strings.TrimSpace(i.CacheRoot)
 	if cacheRoot == "" {
 		return "", fmt.Errorf("local VM image cache root is required")
@@ -160,6 +160,7 @@ func addVMJailConfigResources(plan *vmJailPlan, cfg VMConsoleProxyConfig, fcConf
 			return err
 		}
 	}
+	plan.addBind(vmHibernatePathsForRoot(cfg.ServiceRoot).Dir, false, true, true)
 	plan.SocketLinks = append(plan.SocketLinks, vmJailSocketLink{
 		HostPath: cfg.APISocket,
 		JailPath: vmJailCanonicalPath(plan.JailRoot, cfg.APISocket),
@@ -255,7 +256,9 @@ func vmJailerCommandArgs(cfg VMConsoleProxyConfig, identity vmRuntimeIdentity) [
 		"--resource-limit", "no-file=" + strconv.Itoa(vmJailerNoFileLimit),
 		"--",
 		"--api-sock", cfg.APISocket,
-		"--config-file", cfg.ConfigFile,
+	}
+	if !cfg.RestoreHibernation {
+		args = append(args, "--config-file", cfg.ConfigFile)
 	}
 	return args
 }
@@ -85,6 +85,10 @@ func TestBuildVMJailPlanPreservesCanonicalResourcePaths(t *testing.T) {
 			t.Fatalf("bind = %#v for %s", bind, resource.path)
 		}
 	}
+	hibernateDir := vmHibernatePathsForRoot(root).Dir
+	if bind, ok := findVMJailBind(plan.Binds, hibernateDir); !ok || bind.ReadOnly || !bind.OwnedByRuntime || !bind.CreateDirectory {
+		t.Fatalf("hibernate bind = %#v, %v; want writable delegated directory", bind, ok)
+	}
 	for _, bind := range plan.Binds {
 		if strings.Contains(bind.Source, "check"+"points") {
 			t.Fatalf("jail plan contains retired checkpoint bind: %#v", bind)
@@ -129,6 +133,12 @@ func TestVMJailerCommandArgsDropIdentityAndPreserveSystemdCgroup(t *testing.T) {
 			t.Fatalf("args contain forbidden baseline flag %q: %#v", forbidden, got)
 		}
 	}
+
+	cfg.RestoreHibernation = true
+	got = vmJailerCommandArgs(cfg, vmRuntimeIdentity{UID: 812, GID: 813})
+	if !reflect.DeepEqual(got, want[:len(want)-2]) {
+		t.Fatalf("restore args = %#v, want no --config-file", got)
+	}
 }
 
 func TestPrepareVMConsoleProcessRequiresJailer(t *testing.T) {
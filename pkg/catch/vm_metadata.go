@@ -29,6 +29,7 @@ type vmMetadataConfig struct {
 	Networks       []vmGuestNetwork
 	FastBoot       bool
 	MetadataDriver string
+	UserData       *vmUserData
 
 	HostKeyDir string
 }
@@ -71,6 +72,11 @@ func writeVMMetadata(root string, cfg vmMetadataConfig) error {
 	if err := writeVMMetadataFile(filepath.Join(dir, "authorized_keys"), []byte(cfg.SSHKey+"\n"), 0o600); err != nil {
 		return err
 	}
+	if cfg.UserData != nil {
+		if err := writeVMMetadataFile(filepath.Join(dir, "user-data"), cfg.UserData.Raw, 0o600); err != nil {
+			return err
+		}
+	}
 	return writeVMMetadataFile(filepath.Join(dir, "network.yaml"), []byte(renderVMNetworkYAML(cfg.Networks)), 0o644)
 }
 
@@ -272,18 +278,38 @@ func writeVMGuestMetadataFiles(root string, cfg vmMetadataConfig) error {
 	if err := validateVMMetadata(cfg); err != nil {
 		return err
 	}
-	switch strings.TrimSpace(cfg.MetadataDriver) {
-	case "", "ubuntu":
-		return writeVMGuestUbuntuMetadataFiles(root, cfg)
-	case "nixos":
+	driver := strings.TrimSpace(cfg.MetadataDriver)
+	if driver == "nixos" {
 		return writeVMGuestNixOSMetadataFiles(root, cfg)
-	default:
+	}
+	if driver == "" {
+		driver = "ubuntu"
+	}
+	distro, ok := vmGuestSystemdDistros[driver]
+	if !ok {
 		return fmt.Errorf("unsupported VM metadata driver %q", cfg.MetadataDriver)
 	}
+	return writeVMGuestSystemdMetadataFiles(root, cfg, distro)
 }
 
-func writeVMGuestUbuntuMetadataFiles(root string, cfg vmMetadataConfig) error {
-	if err := writeVMGuestBaseFiles(root, cfg); err != nil {
+// vmGuestSystemdDistro describes how a conventional systemd guest differs
+// from Ubuntu when yeet writes its metadata into the root filesystem.
+type vmGuestSystemdDistro struct {
+	// SSHUnit is the distro's OpenSSH server unit, without ".service".
+	SSHUnit string
+	// Netplan is set when legacy boots configure the network through
+	// netplan. Other guests get systemd-networkd units in both boot modes.
+	Netplan bool
+}
+
+var vmGuestSystemdDistros = map[string]vmGuestSystemdDistro{
+	"ubuntu": {SSHUnit: "ssh", Netplan: true},
+	"debian": {SSHUnit: "ssh"},
+	"fedora": {SSHUnit: "sshd"},
+}
+
+func writeVMGuestSystemdMetadataFiles(root string, cfg vmMetadataConfig, distro vmGuestSystemdDistro) error {
+	if err := writeVMGuestBaseFiles(root, cfg, distro); err != nil {
 		return err
 	}
 	if err := writeVMGuestSSHAccess(root, cfg); err != nil {
@@ -298,17 +324,20 @@ func writeVMGuestUbuntuMetadataFiles(root string, cfg vmMetadataConfig) error {
 	if err := writeVMGuestSerialAutologin(root, cfg.User); err != nil {
 		return err
 	}
-	if err := writeVMGuestReadyUnit(root, cfg.FastBoot); err != nil {
+	if err := writeVMGuestReadyUnit(root, cfg.FastBoot, distro.SSHUnit); err != nil {
 		return err
 	}
 	if err := writeVMGuestGrowRootUnit(root); err != nil {
 		return err
 	}
+	if err := writeVMGuestUserDataUnit(root, cfg.UserData); err != nil {
+		return err
+	}
 	return maskVMGuestSystemdUnit(root, "systemd-networkd-wait-online.service")
 }
 
-func writeVMGuestBaseFiles(root string, cfg vmMetadataConfig) error {
-	if !cfg.FastBoot {
+func writeVMGuestBaseFiles(root string, cfg vmMetadataConfig, distro vmGuestSystemdDistro) error {
+	if !cfg.FastBoot && distro.Netplan {
 		return writeVMGuestLegacyBaseFiles(root, cfg)
 	}
 	return writeVMGuestFastBaseFiles(root, cfg)
@@ -722,18 +751,19 @@ func writeVMGuestPrivileges(root, user string) error {
 	return writeVMGuestFile(root, "etc/sysctl.d/90-yeet-vm.conf", []byte("net.ipv4.ping_group_range = 0 2147483647\n"), 0o644)
 }
 
-func writeVMGuestReadyUnit(root string, fastBoot bool) error {
+func writeVMGuestReadyUnit(root string, fastBoot bool, sshUnit string) error {
 	if err := writeVMGuestFile(root, "usr/local/lib/yeet-vm/guest-ready", []byte(vmGuestReadyScript), 0o755); err != nil {
 		return err
 	}
 	if !fastBoot {
-		if err := writeVMGuestFile(root, "etc/systemd/system/yeet-guest-ready.service", []byte(vmGuestLegacyReadyService), 0o644); err != nil {
+		service := fmt.Sprintf(vmGuestLegacyReadyServiceFormat, sshUnit, sshUnit)
+		if err := writeVMGuestFile(root, "etc/systemd/system/yeet-guest-ready.service", []byte(service), 0o644); err != nil {
 			return err
 		}
 		if err := writeVMGuestSystemdSymlink(root, "multi-user.target.wants/yeet-guest-ready.service", "../yeet-guest-ready.service"); err != nil {
 			return err
 		}
-		return writeVMGuestSystemdSymlink(root, "multi-user.target.wants/ssh.service", "/usr/lib/systemd/system/ssh.service")
+		return writeVMGuestSystemdSymlink(root, "multi-user.target.wants/"+sshUnit+".service", "/usr/lib/systemd/system/"+sshUnit+".service")
 	}
 	if err := writeVMGuestFile(root, "etc/systemd/system/yeet-sshd.service", []byte(vmGuestSSHDService), 0o644); err != nil {
 		return err
@@ -747,10 +777,10 @@ func writeVMGuestReadyUnit(root string, fastBoot bool) error {
 	if err := writeVMGuestSystemdSymlink(root, "multi-user.target.wants/yeet-guest-ready.service", "../yeet-guest-ready.service"); err != nil {
 		return err
 	}
-	if err := maskVMGuestSystemdUnit(root, "ssh.service"); err != nil {
+	if err := maskVMGuestSystemdUnit(root, sshUnit+".service"); err != nil {
 		return err
 	}
-	return maskVMGuestSystemdUnit(root, "ssh.socket")
+	return maskVMGuestSystemdUnit(root, sshUnit+".socket")
 }
 
 func writeVMGuestGrowRootUnit(root string) error {
@@ -777,10 +807,10 @@ RemainAfterExit=yes
 WantedBy=multi-user.target
 `
 
-const vmGuestLegacyReadyService = `[Unit]
+const vmGuestLegacyReadyServiceFormat = `[Unit]
 Description=yeet-ready guest marker
-After=network-online.target ssh.service serial-getty@ttyS0.service
-Wants=network-online.target ssh.service serial-getty@ttyS0.service
+After=network-online.target %s.service serial-getty@ttyS0.service
+Wants=network-online.target %s.service serial-getty@ttyS0.service
 
 [Service]
 Type=oneshot
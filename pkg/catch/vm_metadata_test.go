@@ -454,6 +454,64 @@ func TestWriteVMGuestMetadataFilesUsesLegacyGuestConfigWithoutFastBoot(t *testin
 	}
 }
 
+func TestWriteVMGuestMetadataFilesUsesNetworkdForDebianAndFedora(t *testing.T) {
+	for _, tt := range []struct {
+		driver  string
+		sshUnit string
+	}{
+		{driver: "debian", sshUnit: "ssh.service"},
+		{driver: "fedora", sshUnit: "sshd.service"},
+	} {
+		t.Run(tt.driver, func(t *testing.T) {
+			root := t.TempDir()
+			stubVMGuestChown(t)
+			cfg := validVMMetadataConfig()
+			cfg.FastBoot = false
+			cfg.MetadataDriver = tt.driver
+			if err := writeVMGuestMetadataFiles(root, cfg); err != nil {
+				t.Fatalf("writeVMGuestMetadataFiles: %v", err)
+			}
+
+			assertFileContains(t, filepath.Join(root, "etc", "systemd", "network", "10-yeet-eth0.network"), "Address=192.168.100.12/24")
+			assertFileContains(t, filepath.Join(root, "etc", "systemd", "system", "yeet-guest-ready.service"), "Wants=network-online.target "+tt.sshUnit)
+			target, err := os.Readlink(filepath.Join(root, "etc", "systemd", "system", "multi-user.target.wants", tt.sshUnit))
+			if err != nil || target != "/usr/lib/systemd/system/"+tt.sshUnit {
+				t.Fatalf("%s enable symlink = %q, %v", tt.sshUnit, target, err)
+			}
+			if _, err := os.Lstat(filepath.Join(root, "etc", "netplan", "99-yeet.yaml")); !os.IsNotExist(err) {
+				t.Fatalf("netplan file exists for %s metadata: %v", tt.driver, err)
+			}
+		})
+	}
+}
+
+func TestWriteVMGuestMetadataFilesMasksFedoraSSHDInFastBoot(t *testing.T) {
+	root := t.TempDir()
+	stubVMGuestChown(t)
+	cfg := validVMMetadataConfig()
+	cfg.MetadataDriver = "fedora"
+	if err := writeVMGuestMetadataFiles(root, cfg); err != nil {
+		t.Fatalf("writeVMGuestMetadataFiles: %v", err)
+	}
+	for _, unit := range []string{"sshd.service", "sshd.socket"} {
+		target, err := os.Readlink(filepath.Join(root, "etc", "systemd", "system", unit))
+		if err != nil || target != "/dev/null" {
+			t.Fatalf("%s mask = %q, %v", unit, target, err)
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(root, "etc", "systemd", "system", "ssh.service")); !os.IsNotExist(err) {
+		t.Fatalf("fedora metadata masked ssh.service: %v", err)
+	}
+}
+
+func TestWriteVMGuestMetadataFilesRejectsUnknownDriver(t *testing.T) {
+	cfg := validVMMetadataConfig()
+	cfg.MetadataDriver = "arch"
+	if err := writeVMGuestMetadataFiles(t.TempDir(), cfg); err == nil || !strings.Contains(err.Error(), `unsupported VM metadata driver "arch"`) {
+		t.Fatalf("writeVMGuestMetadataFiles error = %v, want unsupported driver", err)
+	}
+}
+
 func TestWriteVMGuestMetadataFilesCreatesMissingLoginUser(t *testing.T) {
 	root := t.TempDir()
 	stubVMGuestChown(t)
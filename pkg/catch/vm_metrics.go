@@ -0,0 +1,178 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+	"github.com/yeetrun/yeet/pkg/db"
+)
+
+// VM metrics combine host counters with what the guest agent reports. CPU is
+// read from the VM unit's cgroup: the jailer runs without --cgroup flags, so
+// Firecracker stays in the unit's cgroup next to the runner.
+var (
+	queryVMGuestMetricsFn                = queryVMGuestMetrics
+	vmMetricsBalloonAPI     vmBalloonAPI = firecrackerBalloonAPI{}
+	vmMetricsCgroupRoot                  = "/sys/fs/cgroup/system.slice"
+	vmMetricsNetRoot                     = "/sys/class/net"
+	vmMetricsCPUSampleDelay              = 250 * time.Millisecond
+)
+
+// vmMetricsTarget is what sampling needs from one running VM.
+type vmMetricsTarget struct {
+	Service     string
+	AgentSocket string
+	APISocket   string
+	Taps        []string
+}
+
+func vmMetricsTargetForView(name string, vm db.VMConfigView) vmMetricsTarget {
+	target := vmMetricsTarget{
+		Service:     name,
+		AgentSocket: strings.TrimSpace(vm.Sockets().VsockSocketPath),
+		APISocket:   strings.TrimSpace(vm.Sockets().APISocketPath),
+	}
+	for _, network := range vm.Networks().AsSlice() {
+		if tap := strings.TrimSpace(network.Tap); tap != "" {
+			target.Taps = append(target.Taps, tap)
+		}
+	}
+	return target
+}
+
+// sampleVMMetrics samples every target concurrently. CPU usage needs two
+// cgroup reads, so this takes at least vmMetricsCPUSampleDelay.
+func sampleVMMetrics(ctx context.Context, targets []vmMetricsTarget) map[string]*catchrpc.ServiceVMMetrics {
+	out := make(map[string]*catchrpc.ServiceVMMetrics, len(targets))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			metrics := sampleOneVMMetrics(ctx, target)
+			mu.Lock()
+			out[target.Service] = metrics
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+func sampleOneVMMetrics(ctx context.Context, target vmMetricsTarget) *catchrpc.ServiceVMMetrics {
+	metrics := &catchrpc.ServiceVMMetrics{}
+	metrics.CPU = sampleVMCPU(ctx, target.Service)
+	if guest, err := queryVMGuestMetricsFn(ctx, target.AgentSocket); err == nil {
+		metrics.Memory = vmUsage("agent", guest.MemoryTotalBytes, guest.MemoryAvailableBytes)
+		metrics.Disk = vmUsage("agent", guest.RootTotalBytes, guest.RootAvailableBytes)
+	}
+	if metrics.Memory == nil && target.APISocket != "" {
+		metrics.Memory = vmBalloonMemoryUsage(ctx, target.APISocket)
+	}
+	metrics.Network = readVMTapTraffic(target.Taps)
+	return metrics
+}
+
+func sampleVMCPU(ctx context.Context, service string) *catchrpc.ServiceVMCPU {
+	path := filepath.Join(vmMetricsCgroupRoot, vmSystemdUnitName(service), "cpu.stat")
+	before, err := readCgroupCPUUsage(path)
+	if err != nil {
+		return nil
+	}
+	start := time.Now()
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-time.After(vmMetricsCPUSampleDelay):
+	}
+	after, err := readCgroupCPUUsage(path)
+	if err != nil {
+		return nil
+	}
+	cpu := &catchrpc.ServiceVMCPU{UsageSeconds: after.Seconds()}
+	if elapsed := time.Since(start); elapsed > 0 && after >= before {
+		cpu.Percent = float64(after-before) / float64(elapsed) * 100
+	}
+	return cpu
+}
+
+// readCgroupCPUUsage returns usage_usec from a cgroup v2 cpu.stat file.
+func readCgroupCPUUsage(path string) (time.Duration, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok || key != "usage_usec" {
+			continue
+		}
+		usec, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parse %s usage_usec: %w", path, err)
+		}
+		return time.Duration(usec) * time.Microsecond, nil
+	}
+	return 0, fmt.Errorf("%s has no usage_usec", path)
+}
+
+// vmBalloonMemoryUsage covers guests whose agent predates guest_metrics. The
+// balloon device only reports statistics when ballooning is enabled.
+func vmBalloonMemoryUsage(ctx context.Context, socket string) *catchrpc.ServiceVMUsage {
+	stats, err := vmMetricsBalloonAPI.Stats(ctx, socket)
+	if err != nil {
+		return nil
+	}
+	return vmUsage("balloon", stats.TotalMemoryBytes, stats.AvailableMemoryBytes)
+}
+
+func vmUsage(source string, total, available int64) *catchrpc.ServiceVMUsage {
+	if total <= 0 || available < 0 || available > total {
+		return nil
+	}
+	return &catchrpc.ServiceVMUsage{Source: source, Total: total, Used: total - available, Available: available}
+}
+
+// readVMTapTraffic sums the host side of the VM's TAPs. The host receives
+// what the guest sends, so rx and tx swap.
+func readVMTapTraffic(taps []string) *catchrpc.ServiceVMNetTraffic {
+	var traffic catchrpc.ServiceVMNetTraffic
+	found := false
+	for _, tap := range taps {
+		rx, rxErr := readVMTapCounter(tap, "rx_bytes")
+		tx, txErr := readVMTapCounter(tap, "tx_bytes")
+		if rxErr != nil || txErr != nil {
+			continue
+		}
+		traffic.RxBytes += tx
+		traffic.TxBytes += rx
+		found = true
+	}
+	if !found {
+		return nil
+	}
+	return &traffic
+}
+
+func readVMTapCounter(tap, counter string) (int64, error) {
+	raw, err := os.ReadFile(filepath.Join(vmMetricsNetRoot, tap, "statistics", counter))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(raw)), 10, 64)
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+)
+
+func TestQueryVMGuestMetrics(t *testing.T) {
+	socketPath, requests := startFakeVsockAgentWithRequests(t, `{"protocol":1,"type":"guest_metrics","request_id":"test","metrics":{"memory_total_bytes":2048,"memory_available_bytes":512,"root_total_bytes":8192,"root_available_bytes":4096}}`)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	got, err := queryVMGuestMetrics(ctx, socketPath)
+	if err != nil {
+		t.Fatalf("queryVMGuestMetrics: %v", err)
+	}
+	if req := <-requests; req.Type != "guest_metrics" {
+		t.Fatalf("agent request type = %q, want guest_metrics", req.Type)
+	}
+	want := vmAgentMetrics{MemoryTotalBytes: 2048, MemoryAvailableBytes: 512, RootTotalBytes: 8192, RootAvailableBytes: 4096}
+	if got != want {
+		t.Fatalf("metrics = %#v, want %#v", got, want)
+	}
+}
+
+func TestQueryVMGuestMetricsRejectsOldAgent(t *testing.T) {
+	socketPath, _ := startFakeVsockAgentWithRequests(t, `{"protocol":1,"type":"guest_metrics","request_id":"test","error":{"code":"unknown_request","message":"unknown request type"}}`)
+	if _, err := queryVMGuestMetrics(context.Background(), socketPath); err == nil {
+		t.Fatal("queryVMGuestMetrics succeeded against an agent without guest_metrics")
+	}
+}
+
+func withVMMetricsHostFiles(t *testing.T) (cgroupRoot, netRoot string) {
+	t.Helper()
+	oldCgroup, oldNet, oldDelay, oldQuery, oldBalloon := vmMetricsCgroupRoot, vmMetricsNetRoot, vmMetricsCPUSampleDelay, queryVMGuestMetricsFn, vmMetricsBalloonAPI
+	t.Cleanup(func() {
+		vmMetricsCgroupRoot, vmMetricsNetRoot, vmMetricsCPUSampleDelay, queryVMGuestMetricsFn, vmMetricsBalloonAPI = oldCgroup, oldNet, oldDelay, oldQuery, oldBalloon
+	})
+	vmMetricsCgroupRoot = t.TempDir()
+	vmMetricsNetRoot = t.TempDir()
+	vmMetricsCPUSampleDelay = 0
+	return vmMetricsCgroupRoot, vmMetricsNetRoot
+}
+
+func writeVMMetricsFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSampleVMMetricsCombinesHostAndAgent(t *testing.T) {
+	cgroupRoot, netRoot := withVMMetricsHostFiles(t)
+	writeVMMetricsFile(t, filepath.Join(cgroupRoot, vmSystemdUnitName("devbox"), "cpu.stat"), "usage_usec 42000000\nuser_usec 40000000\n")
+	writeVMMetricsFile(t, filepath.Join(netRoot, "yv-devbox0", "statistics", "rx_bytes"), "100\n")
+	writeVMMetricsFile(t, filepath.Join(netRoot, "yv-devbox0", "statistics", "tx_bytes"), "900\n")
+	queryVMGuestMetricsFn = func(context.Context, string) (vmAgentMetrics, error) {
+		return vmAgentMetrics{MemoryTotalBytes: 2048, MemoryAvailableBytes: 512, RootTotalBytes: 8192, RootAvailableBytes: 6144}, nil
+	}
+
+	got := sampleVMMetrics(context.Background(), []vmMetricsTarget{{Service: "devbox", AgentSocket: "agent.sock", Taps: []string{"yv-devbox0", "missing"}}})["devbox"]
+	if got == nil || got.CPU == nil || got.CPU.UsageSeconds != 42 {
+		t.Fatalf("cpu = %#v, want 42s of usage", got)
+	}
+	if want := (&catchrpc.ServiceVMUsage{Source: "agent", Total: 2048, Used: 1536, Available: 512}); !reflect.DeepEqual(got.Memory, want) {
+		t.Fatalf("memory = %#v, want %#v", got.Memory, want)
+	}
+	if want := (&catchrpc.ServiceVMUsage{Source: "agent", Total: 8192, Used: 2048, Available: 6144}); !reflect.DeepEqual(got.Disk, want) {
+		t.Fatalf("disk = %#v, want %#v", got.Disk, want)
+	}
+	if want := (&catchrpc.ServiceVMNetTraffic{RxBytes: 900, TxBytes: 100}); !reflect.DeepEqual(got.Network, want) {
+		t.Fatalf("network = %#v, want guest view %#v", got.Network, want)
+	}
+}
+
+func TestSampleVMMetricsFallsBackToBalloonMemory(t *testing.T) {
+	withVMMetricsHostFiles(t)
+	queryVMGuestMetricsFn = func(context.Context, string) (vmAgentMetrics, error) {
+		return vmAgentMetrics{}, errors.New("unknown request type")
+	}
+	vmMetricsBalloonAPI = &recordingVMBalloonAPI{stats: map[string]vmBalloonStats{
+		"api.sock": {TotalMemoryBytes: 4096, AvailableMemoryBytes: 1024},
+	}}
+
+	got := sampleOneVMMetrics(context.Background(), vmMetricsTarget{Service: "devbox", APISocket: "api.sock"})
+	if want := (&catchrpc.ServiceVMUsage{Source: "balloon", Total: 4096, Used: 3072, Available: 1024}); !reflect.DeepEqual(got.Memory, want) {
+		t.Fatalf("memory = %#v, want %#v", got.Memory, want)
+	}
+	if got.CPU != nil || got.Disk != nil || got.Network != nil {
+		t.Fatalf("metrics = %#v, want only balloon memory", got)
+	}
+}
+
+func TestReadCgroupCPUUsageRequiresUsage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cpu.stat")
+	writeVMMetricsFile(t, path, "user_usec 1\n")
+	if _, err := readCgroupCPUUsage(path); err == nil {
+		t.Fatal("readCgroupCPUUsage accepted cpu.stat without usage_usec")
+	}
+}
@@ -0,0 +1,946 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/yeetrun/yeet/pkg/cli"
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/serviceid"
+)
+
+// A VM migration stream is a zstd tar. The manifest comes first, followed by
+// the disks in manifest order, each split into chunks named
+// disks/<disk-id>/<offset>. Raw disks skip all-zero chunks so sparse files
+// stay sparse; zfs disks carry a contiguous `zfs send` stream.
+const (
+	vmMigrationFormat         = 1
+	vmMigrationManifestName   = "manifest.json"
+	vmMigrationManifestLimit  = 1 << 20
+	vmMigrationDiskDir        = "disks"
+	vmMigrationRootDiskID     = "root"
+	vmMigrationDataDiskPrefix = "data-"
+	vmMigrationDiskRaw        = "raw"
+	vmMigrationDiskZFS        = "zfs"
+	vmMigrationChunkBytes     = 1 << 20
+
+	vmMigrationPrecopySnapshot = "yeet-migrate-precopy"
+	vmMigrationFinalSnapshot   = "yeet-migrate"
+)
+
+var (
+	vmMigrationIsRunning  = (*Server).IsServiceRunning
+	vmMigrationZFSSend    = startVMMigrationZFSSend
+	vmMigrationZFSReceive = startVMMigrationZFSReceive
+)
+
+type vmMigrationManifest struct {
+	Format         int                            `json:"format"`
+	Service        string                         `json:"service"`
+	Precopy        bool                           `json:"precopy,omitempty"`
+	Payload        string                         `json:"payload"`
+	Components     *vmProvisionComponentSelection `json:"components,omitempty"`
+	CPUs           int                            `json:"cpus"`
+	MemoryBytes    int64                          `json:"memoryBytes"`
+	Balloon        string                         `json:"balloon,omitempty"`
+	MemoryMinBytes int64                          `json:"memoryMinBytes,omitempty"`
+	Networks       []vmMigrationNetwork           `json:"networks,omitempty"`
+	Publish        []string                       `json:"publish,omitempty"`
+	Disks          []vmMigrationDisk              `json:"disks"`
+}
+
+type vmMigrationNetwork struct {
+	Mode string `json:"mode"`
+	MAC  string `json:"mac,omitempty"`
+	VLAN int    `json:"vlan,omitempty"`
+}
+
+type vmMigrationDisk struct {
+	ID          string `json:"id"`
+	Format      string `json:"format"`
+	Bytes       int64  `json:"bytes"`
+	Incremental bool   `json:"incremental,omitempty"`
+}
+
+type vmMigrationSourceDisk struct {
+	vmMigrationDisk
+	Path    string
+	Dataset string
+}
+
+type vmMigrationSendOptions struct {
+	// ZFS sends zvol disks as zfs streams; the target must be on ZFS too.
+	ZFS bool
+	// Precopy sends a full copy of the zvol disks while the VM keeps running.
+	Precopy bool
+	// Incremental sends the zvol disks on top of an earlier pre-copy.
+	Incremental bool
+}
+
+// vmMigrationImport is a received migration that `vm migrate finish` turns
+// into a VM. It is threaded through provisioning so the staged disks replace
+// the fresh image disk.
+type vmMigrationImport struct {
+	Service  string
+	Stage    string
+	Manifest vmMigrationManifest
+}
+
+func (e *ttyExecer) vmMigrateCmdFunc(args []string) error {
+	flags, action, err := cli.ParseVMMigrateStep(args)
+	if err != nil {
+		return err
+	}
+	switch action {
+	case cli.VMMigrateStepSend:
+		opts := vmMigrationSendOptions{ZFS: flags.ZFS, Precopy: flags.Precopy, Incremental: flags.Incremental}
+		return e.s.sendVMMigration(e.ctx, e.sn, opts, e.rw)
+	case cli.VMMigrateStepReceive:
+		return e.s.receiveVMMigration(e.ctx, e.sn, e.payloadReader())
+	case cli.VMMigrateStepFinish:
+		return e.finishVMMigration()
+	default:
+		return e.s.discardVMMigration(e.ctx, e.sn)
+	}
+}
+
+// sendVMMigration writes a migration stream for the VM's disks. Only a
+// pre-copy may run while the VM is up; the final stream needs it stopped so
+// the disks are consistent.
+func (s *Server) sendVMMigration(ctx context.Context, name string, opts vmMigrationSendOptions, w io.Writer) (retErr error) {
+	if opts.Precopy && (!opts.ZFS || opts.Incremental) {
+		return fmt.Errorf("a migration pre-copy is a full zfs stream")
+	}
+	service, vm, err := s.vmSnapshotService(name)
+	if err != nil {
+		return err
+	}
+	if err := s.checkVMMigrationSource(name, opts.Precopy); err != nil {
+		return err
+	}
+	disks := vmMigrationSourceDisks(vm, opts)
+	snapshot := vmMigrationFinalSnapshot
+	if opts.Precopy {
+		snapshot = vmMigrationPrecopySnapshot
+	}
+	if err := s.snapshotVMMigrationDisks(ctx, disks, snapshot); err != nil {
+		return err
+	}
+	if !opts.Precopy {
+		// The pre-copy snapshot is kept until the final stream is sent.
+		defer func() {
+			retErr = errors.Join(retErr, s.destroyVMDataDiskSnapshots(ctx, vmMigrationSourceDatasets(disks), vmMigrationSnapshots()))
+		}()
+	}
+	manifest, err := newVMMigrationManifest(service, vm, opts.Precopy, disks)
+	if err != nil {
+		return err
+	}
+	return writeVMMigrationStream(ctx, w, manifest, disks, snapshot)
+}
+
+func (s *Server) checkVMMigrationSource(name string, precopy bool) error {
+	root, err := s.serviceRootDir(name)
+	if err != nil {
+		return err
+	}
+	if vmHibernationPending(root) {
+		return fmt.Errorf("VM %q is hibernated; resume and stop it before migrating", name)
+	}
+	if precopy {
+		return nil
+	}
+	running, err := vmMigrationIsRunning(s, name)
+	if err != nil {
+		return err
+	}
+	if running {
+		return fmt.Errorf("VM %q is running; stop it before sending its disks", name)
+	}
+	return nil
+}
+
+// vmMigrationSourceDisks lists the root disk and then the data disks in
+// attach order, which is also the order the target attaches them in.
+func vmMigrationSourceDisks(vm db.VMConfig, opts vmMigrationSendOptions) []vmMigrationSourceDisk {
+	all := []vmMigrationSourceDisk{newVMMigrationSourceDisk(vmMigrationRootDiskID, vm.Disk.Backend, vm.Disk.Path, vm.Disk.Bytes, opts)}
+	for _, disk := range vm.DataDisks {
+		all = append(all, newVMMigrationSourceDisk(vmMigrationDataDiskPrefix+disk.Name, disk.Backend, disk.Path, disk.Bytes, opts))
+	}
+	if !opts.Precopy {
+		return all
+	}
+	var zfs []vmMigrationSourceDisk
+	for _, disk := range all {
+		if disk.Format == vmMigrationDiskZFS {
+			zfs = append(zfs, disk)
+		}
+	}
+	return zfs
+}
+
+func newVMMigrationSourceDisk(id, backend, diskPath string, bytes int64, opts vmMigrationSendOptions) vmMigrationSourceDisk {
+	disk := vmMigrationSourceDisk{
+		vmMigrationDisk: vmMigrationDisk{ID: id, Format: vmMigrationDiskRaw, Bytes: bytes},
+		Path:            diskPath,
+	}
+	if opts.ZFS && backend == vmDiskBackendZVOL {
+		disk.Format = vmMigrationDiskZFS
+		disk.Dataset = strings.TrimPrefix(strings.TrimSpace(diskPath), "/dev/zvol/")
+		disk.Incremental = opts.Incremental
+	}
+	return disk
+}
+
+func vmMigrationSourceDatasets(disks []vmMigrationSourceDisk) []string {
+	var datasets []string
+	for _, disk := range disks {
+		if disk.Dataset != "" {
+			datasets = append(datasets, disk.Dataset)
+		}
+	}
+	return datasets
+}
+
+func vmMigrationSnapshots() []string {
+	return []string{vmMigrationPrecopySnapshot, vmMigrationFinalSnapshot}
+}
+
+// snapshotVMMigrationDisks snapshots every zfs disk in one command so the
+// disks are captured at the same instant.
+func (s *Server) snapshotVMMigrationDisks(ctx context.Context, disks []vmMigrationSourceDisk, snapshot string) error {
+	datasets := vmMigrationSourceDatasets(disks)
+	if len(datasets) == 0 {
+		return nil
+	}
+	// A snapshot left behind by an interrupted migration would make the
+	// new one fail.
+	if err := s.destroyVMDataDiskSnapshots(ctx, datasets, []string{snapshot}); err != nil {
+		return err
+	}
+	runner := s.zfsRunner
+	if runner == nil {
+		runner = runZFSCommand
+	}
+	args := []string{"snapshot"}
+	for _, dataset := range datasets {
+		args = append(args, dataset+"@"+snapshot)
+	}
+	if _, stderr, err := runner(ctx, args...); err != nil {
+		return formatZFSCommandError("zfs snapshot", stderr, err)
+	}
+	return nil
+}
+
+func newVMMigrationManifest(service *db.Service, vm db.VMConfig, precopy bool, disks []vmMigrationSourceDisk) (vmMigrationManifest, error) {
+	balloon, err := effectiveExistingVMBalloonConfig(vm.MemoryBytes, vm.Balloon)
+	if err != nil {
+		return vmMigrationManifest{}, err
+	}
+	manifest := vmMigrationManifest{
+		Format:      vmMigrationFormat,
+		Service:     service.Name,
+		Precopy:     precopy,
+		Payload:     vm.Image.Payload,
+		Components:  vmMigrationComponentSelection(vm.Components),
+		CPUs:        vm.CPUs,
+		MemoryBytes: vm.MemoryBytes,
+		Balloon:     balloon.Mode,
+		Publish:     service.Publish,
+	}
+	if balloon.Mode == vmBalloonModeAuto {
+		manifest.MemoryMinBytes = balloon.MinBytes
+	}
+	for _, network := range vm.Networks {
+		manifest.Networks = append(manifest.Networks, vmMigrationNetwork{Mode: network.Mode, MAC: network.MAC, VLAN: network.VLAN})
+	}
+	for _, disk := range disks {
+		manifest.Disks = append(manifest.Disks, disk.vmMigrationDisk)
+	}
+	return manifest, nil
+}
+
+func vmMigrationComponentSelection(components *db.VMComponentsConfig) *vmProvisionComponentSelection {
+	if components == nil {
+		return nil
+	}
+	return &vmProvisionComponentSelection{
+		GuestBaseID:             components.GuestBase.ID,
+		GuestBaseManifestSHA256: components.GuestBase.ManifestSHA256,
+		KernelID:                components.Kernel.ID,
+		KernelManifestSHA256:    components.Kernel.ManifestSHA256,
+		RuntimeID:               components.Runtime.Configured.ID,
+		RuntimeManifestSHA256:   components.Runtime.Configured.ManifestSHA256,
+	}
+}
+
+func writeVMMigrationStream(ctx context.Context, w io.Writer, manifest vmMigrationManifest, disks []vmMigrationSourceDisk, snapshot string) (retErr error) {
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return fmt.Errorf("create migration stream compressor: %w", err)
+	}
+	defer func() {
+		if err := zw.Close(); err != nil && retErr == nil {
+			retErr = fmt.Errorf("finish migration stream compression: %w", err)
+		}
+	}()
+	tw := tar.NewWriter(zw)
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode migration manifest: %w", err)
+	}
+	if err := writeVMMigrationEntry(tw, vmMigrationManifestName, raw); err != nil {
+		return err
+	}
+	for _, disk := range disks {
+		if err := writeVMMigrationDisk(ctx, tw, disk, snapshot); err != nil {
+			return fmt.Errorf("send VM disk %s: %w", disk.ID, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("finish migration stream: %w", err)
+	}
+	return nil
+}
+
+func writeVMMigrationDisk(ctx context.Context, tw *tar.Writer, disk vmMigrationSourceDisk, snapshot string) error {
+	if disk.Format == vmMigrationDiskRaw {
+		f, err := os.Open(disk.Path)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		return writeVMMigrationChunks(tw, disk.ID, f, true)
+	}
+	args := []string{"-c"}
+	if disk.Incremental {
+		args = append(args, "-i", "@"+vmMigrationPrecopySnapshot)
+	}
+	stream, wait, err := vmMigrationZFSSend(ctx, append(args, disk.Dataset+"@"+snapshot)...)
+	if err != nil {
+		return err
+	}
+	writeErr := writeVMMigrationChunks(tw, disk.ID, stream, false)
+	if writeErr != nil {
+		// Closing the pipe stops zfs send instead of leaving it blocked.
+		_ = stream.Close()
+	}
+	return errors.Join(writeErr, wait())
+}
+
+func writeVMMigrationChunks(tw *tar.Writer, id string, r io.Reader, skipZero bool) error {
+	buf := make([]byte, vmMigrationChunkBytes)
+	zero := make([]byte, vmMigrationChunkBytes)
+	var offset int64
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 && !(skipZero && bytes.Equal(buf[:n], zero[:n])) {
+			name := vmMigrationDiskDir + "/" + id + "/" + strconv.FormatInt(offset, 10)
+			if err := writeVMMigrationEntry(tw, name, buf[:n]); err != nil {
+				return err
+			}
+		}
+		offset += int64(n)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func writeVMMigrationEntry(tw *tar.Writer, name string, data []byte) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(data))}); err != nil {
+		return fmt.Errorf("write migration entry %s: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("write migration entry %s: %w", name, err)
+	}
+	return nil
+}
+
+func startVMMigrationZFSSend(ctx context.Context, args ...string) (io.ReadCloser, func() error, error) {
+	cmd := exec.CommandContext(ctx, "zfs", append([]string{"send"}, args...)...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("start zfs send: %w", err)
+	}
+	return stdout, func() error {
+		if err := cmd.Wait(); err != nil {
+			return formatZFSCommandError("zfs send", stderr.String(), err)
+		}
+		return nil
+	}, nil
+}
+
+func startVMMigrationZFSReceive(ctx context.Context, args ...string) (io.WriteCloser, func() error, error) {
+	cmd := exec.CommandContext(ctx, "zfs", append([]string{"recv"}, args...)...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("start zfs recv: %w", err)
+	}
+	return stdin, func() error {
+		if err := cmd.Wait(); err != nil {
+			return formatZFSCommandError("zfs recv", stderr.String(), err)
+		}
+		return nil
+	}, nil
+}
+
+// receiveVMMigration stages the disks from a migration stream until `vm
+// migrate finish` builds the VM from them. Raw disks land in a hidden
+// directory next to the service roots and zfs disks in a dataset next to the
+// service datasets. The manifest is only written once every disk arrived, so
+// finish never sees a partial stage.
+func (s *Server) receiveVMMigration(ctx context.Context, name string, r io.Reader) error {
+	if err := s.validateVMMigrationTarget(name); err != nil {
+		return err
+	}
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("open migration stream: %w", err)
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+	manifest, err := readVMMigrationStreamManifest(tr, name)
+	if err != nil {
+		return err
+	}
+	stage := s.vmMigrationStageDir(name)
+	if err := os.MkdirAll(stage, 0o700); err != nil {
+		return fmt.Errorf("create migration staging directory: %w", err)
+	}
+	manifestPath := filepath.Join(stage, vmMigrationManifestName)
+	if err := os.Remove(manifestPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	receiver := &vmMigrationReceiver{ctx: ctx, s: s, service: name, stage: stage, disks: manifest.Disks, cur: -1}
+	if err := receiver.receive(tr); err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode migration manifest: %w", err)
+	}
+	return writeVMFile(manifestPath, raw, 0o600)
+}
+
+func (s *Server) validateVMMigrationTarget(name string) error {
+	if err := serviceid.Validate(name); err != nil {
+		return err
+	}
+	if _, err := s.serviceView(name); err == nil {
+		return fmt.Errorf("service %q already exists on this host", name)
+	} else if !errors.Is(err, errServiceNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) vmMigrationStageDir(name string) string {
+	return filepath.Join(s.configuredServicesRoot(), "."+name+".migrate")
+}
+
+// vmMigrationStageDataset returns the dataset zfs disk streams are received
+// under, reporting false when the services root is not on ZFS.
+func (s *Server) vmMigrationStageDataset(ctx context.Context, name string) (string, bool, error) {
+	dataset, ok, err := zfsDatasetForMountpoint(ctx, s.zfsRunner, s.configuredServicesRoot())
+	if err != nil || !ok {
+		return "", false, err
+	}
+	return dataset + "/" + name + ".migrate", true, nil
+}
+
+func readVMMigrationStreamManifest(tr *tar.Reader, name string) (vmMigrationManifest, error) {
+	hdr, err := tr.Next()
+	if err != nil {
+		return vmMigrationManifest{}, fmt.Errorf("read migration stream: %w", err)
+	}
+	if hdr.Name != vmMigrationManifestName {
+		return vmMigrationManifest{}, fmt.Errorf("migration stream must start with %s", vmMigrationManifestName)
+	}
+	var manifest vmMigrationManifest
+	if err := json.NewDecoder(io.LimitReader(tr, vmMigrationManifestLimit)).Decode(&manifest); err != nil {
+		return vmMigrationManifest{}, fmt.Errorf("decode migration manifest: %w", err)
+	}
+	if err := validateVMMigrationManifest(manifest, name); err != nil {
+		return vmMigrationManifest{}, err
+	}
+	return manifest, nil
+}
+
+func validateVMMigrationManifest(manifest vmMigrationManifest, name string) error {
+	if manifest.Format != vmMigrationFormat {
+		return fmt.Errorf("unsupported migration stream format %d", manifest.Format)
+	}
+	if manifest.Service != name {
+		return fmt.Errorf("migration stream is for %q, not %q", manifest.Service, name)
+	}
+	seen := map[string]bool{}
+	for _, disk := range manifest.Disks {
+		if err := validateVMMigrationDiskID(disk.ID); err != nil {
+			return err
+		}
+		if err := validateVMMigrationDiskFormat(disk, manifest.Precopy); err != nil {
+			return err
+		}
+		if seen[disk.ID] {
+			return fmt.Errorf("migration stream lists disk %s twice", disk.ID)
+		}
+		seen[disk.ID] = true
+	}
+	if !manifest.Precopy && !seen[vmMigrationRootDiskID] {
+		return fmt.Errorf("migration stream has no root disk")
+	}
+	return nil
+}
+
+func validateVMMigrationDiskID(id string) error {
+	if id == vmMigrationRootDiskID {
+		return nil
+	}
+	name, ok := strings.CutPrefix(id, vmMigrationDataDiskPrefix)
+	if !ok {
+		return fmt.Errorf("invalid migration disk %q", id)
+	}
+	return validateVMDataDiskName(name)
+}
+
+func validateVMMigrationDiskFormat(disk vmMigrationDisk, precopy bool) error {
+	switch {
+	case disk.Bytes <= 0:
+		return fmt.Errorf("migration disk %s has no size", disk.ID)
+	case disk.Format != vmMigrationDiskRaw && disk.Format != vmMigrationDiskZFS:
+		return fmt.Errorf("migration disk %s has unsupported format %q", disk.ID, disk.Format)
+	case precopy && (disk.Format != vmMigrationDiskZFS || disk.Incremental):
+		return fmt.Errorf("migration pre-copy disk %s must be a full zfs stream", disk.ID)
+	case disk.Incremental && disk.Format != vmMigrationDiskZFS:
+		return fmt.Errorf("migration disk %s cannot be incremental", disk.ID)
+	}
+	return nil
+}
+
+func parseVMMigrationChunkName(name string) (string, int64, error) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 3 || parts[0] != vmMigrationDiskDir {
+		return "", 0, fmt.Errorf("unexpected migration stream entry %q", name)
+	}
+	offset, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || offset < 0 {
+		return "", 0, fmt.Errorf("unexpected migration stream entry %q", name)
+	}
+	return parts[1], offset, nil
+}
+
+// vmMigrationSink receives the chunks of one disk.
+type vmMigrationSink interface {
+	WriteChunk(offset int64, r io.Reader, size int64) error
+	Close() error
+}
+
+// vmMigrationReceiver walks the disks in manifest order. Disks without any
+// chunks, such as an all-zero raw disk, are still created.
+type vmMigrationReceiver struct {
+	ctx     context.Context
+	s       *Server
+	service string
+	stage   string
+	disks   []vmMigrationDisk
+	cur     int
+	sink    vmMigrationSink
+}
+
+func (r *vmMigrationReceiver) receive(tr *tar.Reader) (retErr error) {
+	defer func() {
+		if retErr != nil && r.sink != nil {
+			_ = r.sink.Close()
+		}
+	}()
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return r.advance(len(r.disks))
+		}
+		if err != nil {
+			return fmt.Errorf("read migration stream: %w", err)
+		}
+		id, offset, err := parseVMMigrationChunkName(hdr.Name)
+		if err != nil {
+			return err
+		}
+		if err := r.advanceTo(id); err != nil {
+			return err
+		}
+		if err := r.sink.WriteChunk(offset, tr, hdr.Size); err != nil {
+			return fmt.Errorf("receive VM disk %s: %w", id, err)
+		}
+	}
+}
+
+func (r *vmMigrationReceiver) advanceTo(id string) error {
+	idx := -1
+	for i, disk := range r.disks {
+		if disk.ID == id {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return fmt.Errorf("migration stream has data for unknown disk %s", id)
+	}
+	if idx < r.cur {
+		return fmt.Errorf("migration stream disk %s is out of order", id)
+	}
+	return r.advance(idx)
+}
+
+func (r *vmMigrationReceiver) advance(idx int) error {
+	for r.cur < idx {
+		if r.sink != nil {
+			err := r.sink.Close()
+			r.sink = nil
+			if err != nil {
+				return fmt.Errorf("receive VM disk %s: %w", r.disks[r.cur].ID, err)
+			}
+		}
+		r.cur++
+		if r.cur == len(r.disks) {
+			return nil
+		}
+		sink, err := r.openSink(r.disks[r.cur])
+		if err != nil {
+			return fmt.Errorf("receive VM disk %s: %w", r.disks[r.cur].ID, err)
+		}
+		r.sink = sink
+	}
+	return nil
+}
+
+func (r *vmMigrationReceiver) openSink(disk vmMigrationDisk) (vmMigrationSink, error) {
+	if disk.Format == vmMigrationDiskRaw {
+		return openVMMigrationRawSink(filepath.Join(r.stage, disk.ID+".raw"), disk.Bytes)
+	}
+	stageDataset, ok, err := r.s.vmMigrationStageDataset(r.ctx, r.service)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("the services root on this host is not on ZFS; it cannot receive zfs disk streams")
+	}
+	return r.s.openVMMigrationZFSSink(r.ctx, stageDataset, disk)
+}
+
+type vmMigrationRawSink struct {
+	f    *os.File
+	size int64
+}
+
+func openVMMigrationRawSink(path string, size int64) (vmMigrationSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Truncate(size); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &vmMigrationRawSink{f: f, size: size}, nil
+}
+
+func (s *vmMigrationRawSink) WriteChunk(offset int64, r io.Reader, size int64) error {
+	if offset > s.size || size > s.size-offset {
+		return fmt.Errorf("chunk at offset %d overruns the %d byte disk", offset, s.size)
+	}
+	_, err := io.Copy(io.NewOffsetWriter(s.f, offset), io.LimitReader(r, size))
+	return err
+}
+
+func (s *vmMigrationRawSink) Close() error {
+	return s.f.Close()
+}
+
+type vmMigrationZFSSink struct {
+	stdin io.WriteCloser
+	wait  func() error
+	next  int64
+}
+
+func (s *Server) openVMMigrationZFSSink(ctx context.Context, stageDataset string, disk vmMigrationDisk) (vmMigrationSink, error) {
+	dataset := stageDataset + "/" + disk.ID
+	if err := validateZFSName("migration staging dataset", dataset, true); err != nil {
+		return nil, err
+	}
+	args := []string{"-F", dataset}
+	if !disk.Incremental {
+		if err := s.resetVMMigrationStageDataset(ctx, stageDataset, dataset); err != nil {
+			return nil, err
+		}
+		args = []string{dataset}
+	}
+	stdin, wait, err := vmMigrationZFSReceive(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &vmMigrationZFSSink{stdin: stdin, wait: wait}, nil
+}
+
+// resetVMMigrationStageDataset makes room for a full stream, dropping any
+// copy left by an interrupted migration.
+func (s *Server) resetVMMigrationStageDataset(ctx context.Context, stageDataset, dataset string) error {
+	runner := s.zfsRunner
+	if runner == nil {
+		runner = runZFSCommand
+	}
+	if _, stderr, err := runner(ctx, "create", "-p", "-o", "canmount=off", stageDataset); err != nil {
+		return formatZFSCommandError("zfs create", stderr, err)
+	}
+	if _, stderr, err := runner(ctx, "destroy", "-r", dataset); err != nil && !zfsDestroyDatasetMissing(stderr) {
+		return formatZFSCommandError("zfs destroy", stderr, err)
+	}
+	return nil
+}
+
+func (s *vmMigrationZFSSink) WriteChunk(offset int64, r io.Reader, size int64) error {
+	if offset != s.next {
+		return fmt.Errorf("zfs stream chunk at offset %d, want %d", offset, s.next)
+	}
+	n, err := io.Copy(s.stdin, io.LimitReader(r, size))
+	s.next += n
+	return err
+}
+
+func (s *vmMigrationZFSSink) Close() error {
+	_ = s.stdin.Close()
+	return s.wait()
+}
+
+// finishVMMigration provisions the VM from a received migration. It goes
+// through the normal provisioning path, pinned to the source's components,
+// so networking, metadata, the unit and the guest_ready wait all match a
+// fresh `yeet run`.
+func (e *ttyExecer) finishVMMigration() error {
+	migration, err := e.s.loadVMMigration(e.sn)
+	if err != nil {
+		return err
+	}
+	e.vmMigration = &migration
+	defer func() { e.vmMigration = nil }()
+	if err := e.provisionVM(vmMigrationRunFlags(migration.Manifest), migration.Manifest.Payload); err != nil {
+		return err
+	}
+	return e.s.discardVMMigration(e.ctx, e.sn)
+}
+
+func (s *Server) loadVMMigration(name string) (vmMigrationImport, error) {
+	stage := s.vmMigrationStageDir(name)
+	raw, err := os.ReadFile(filepath.Join(stage, vmMigrationManifestName))
+	if os.IsNotExist(err) {
+		return vmMigrationImport{}, fmt.Errorf("no migration has been received for %q", name)
+	}
+	if err != nil {
+		return vmMigrationImport{}, err
+	}
+	var manifest vmMigrationManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return vmMigrationImport{}, fmt.Errorf("decode migration manifest: %w", err)
+	}
+	if err := validateVMMigrationManifest(manifest, name); err != nil {
+		return vmMigrationImport{}, err
+	}
+	if manifest.Precopy {
+		return vmMigrationImport{}, fmt.Errorf("migration of %q has only been pre-copied; send the final stream first", name)
+	}
+	return vmMigrationImport{Service: name, Stage: stage, Manifest: manifest}, nil
+}
+
+// vmMigrationRunFlags rebuilds the `yeet run` flags for the migrated VM. The
+// LAN MAC and VLAN are kept so DHCP reservations follow the VM; the parent
+// interface is resolved on the target.
+func vmMigrationRunFlags(manifest vmMigrationManifest) cli.RunFlags {
+	flags := cli.RunFlags{
+		CPUs:    manifest.CPUs,
+		Memory:  strconv.FormatInt(manifest.MemoryBytes, 10),
+		Balloon: manifest.Balloon,
+		Publish: manifest.Publish,
+		Restart: true,
+	}
+	if manifest.MemoryMinBytes > 0 {
+		flags.MemoryMin = strconv.FormatInt(manifest.MemoryMinBytes, 10)
+	}
+	for _, disk := range manifest.Disks {
+		if disk.ID == vmMigrationRootDiskID {
+			flags.Disk = strconv.FormatInt(disk.Bytes, 10)
+		}
+	}
+	var modes []string
+	for _, network := range manifest.Networks {
+		modes = append(modes, network.Mode)
+		if network.Mode == "lan" {
+			flags.MacvlanMac = network.MAC
+			flags.MacvlanVlan = network.VLAN
+		}
+	}
+	flags.Net = strings.Join(modes, ",")
+	return flags
+}
+
+// componentSelection pins provisioning to the source's components. A nil
+// migration selects the catalog defaults.
+func (m *vmMigrationImport) componentSelection() vmProvisionComponentSelection {
+	if m == nil || m.Manifest.Components == nil {
+		return vmProvisionComponentSelection{}
+	}
+	return *m.Manifest.Components
+}
+
+func (m *vmMigrationImport) checkComponents(artifacts vmProvisionArtifacts) error {
+	if m == nil || m.Manifest.Components == nil {
+		return nil
+	}
+	got := vmMigrationComponentSelection(artifacts.Components())
+	if got == nil || *got != *m.Manifest.Components {
+		return fmt.Errorf("this host cannot provide the VM components %q was built with (guest base %s, kernel %s, runtime %s)",
+			m.Service, m.Manifest.Components.GuestBaseID, m.Manifest.Components.KernelID, m.Manifest.Components.RuntimeID)
+	}
+	return nil
+}
+
+// dataDisks places the migrated data disks in the new VM's storage, next to
+// its root disk and with the same backend, as `vm disk add` would.
+func (m *vmMigrationImport) dataDisks(root resolvedServiceRoot, disk vmDiskPlan) []db.VMDataDiskConfig {
+	if m == nil {
+		return nil
+	}
+	var disks []db.VMDataDiskConfig
+	for _, staged := range m.Manifest.Disks {
+		name, ok := strings.CutPrefix(staged.ID, vmMigrationDataDiskPrefix)
+		if !ok {
+			continue
+		}
+		diskPath := filepath.Join(serviceDataDirForRoot(root.Root), "disk-"+name+".raw")
+		if disk.Backend == vmDiskBackendZVOL {
+			diskPath = "/dev/zvol/" + path.Dir(disk.Path) + "/disk-" + name
+		}
+		disks = append(disks, db.VMDataDiskConfig{Name: name, Backend: disk.Backend, Bytes: staged.Bytes, Path: diskPath})
+	}
+	return disks
+}
+
+// placeDisks moves the staged disks to where the plan expects them. zfs
+// streams are renamed into place; raw copies are moved, or written into a
+// fresh zvol when this host keeps VM disks on ZFS.
+func (m *vmMigrationImport) placeDisks(ctx context.Context, s *Server, plan vmProvisionPlan) error {
+	targets := map[string]db.VMDataDiskConfig{
+		vmMigrationRootDiskID: {Name: vmMigrationRootDiskID, Backend: plan.Disk.Backend, Bytes: plan.Shape.DiskBytes, Path: plan.DiskPath},
+	}
+	for _, disk := range plan.DataDisks {
+		targets[vmMigrationDataDiskPrefix+disk.Name] = disk
+	}
+	for _, disk := range m.Manifest.Disks {
+		if err := m.placeDisk(ctx, s, disk, targets[disk.ID]); err != nil {
+			return fmt.Errorf("place migrated disk %s: %w", disk.ID, err)
+		}
+	}
+	return nil
+}
+
+func (m *vmMigrationImport) placeDisk(ctx context.Context, s *Server, disk vmMigrationDisk, target db.VMDataDiskConfig) error {
+	staged := filepath.Join(m.Stage, disk.ID+".raw")
+	switch {
+	case disk.Format == vmMigrationDiskZFS && target.Backend == vmDiskBackendZVOL:
+		stageDataset, ok, err := s.vmMigrationStageDataset(ctx, m.Service)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("the services root on this host is not on ZFS")
+		}
+		return moveVMDataDisk(ctx, vmDiskBackendZVOL, "/dev/zvol/"+stageDataset+"/"+disk.ID, target.Path)
+	case disk.Format == vmMigrationDiskZFS:
+		return fmt.Errorf("the disk was received as a zfs stream, but this VM keeps its disks in raw files")
+	case target.Backend == vmDiskBackendZVOL:
+		return runVMDataDiskSteps(ctx, target, []vmDiskPlanStep{
+			{Command: []string{"zfs", "create", "-p", "-s", "-V", strconv.FormatInt(target.Bytes, 10), vmDataDiskDataset(target)}},
+			{Command: vmZVOLSettleCommand()},
+			{Command: []string{"dd", "if=" + staged, "of=" + target.Path, "bs=16M", "conv=sparse", "status=none"}},
+		})
+	default:
+		if err := os.MkdirAll(filepath.Dir(target.Path), 0o755); err != nil {
+			return err
+		}
+		return runVMDataDiskSteps(ctx, target, []vmDiskPlanStep{
+			{Command: []string{"mv", "-T", staged, target.Path}},
+		})
+	}
+}
+
+// discardVMMigration removes everything a migration left on this host: the
+// staged disks on the target and the migration snapshots on the source.
+func (s *Server) discardVMMigration(ctx context.Context, name string) error {
+	var errs []error
+	if err := os.RemoveAll(s.vmMigrationStageDir(name)); err != nil {
+		errs = append(errs, fmt.Errorf("remove migration staging directory: %w", err))
+	}
+	errs = append(errs, s.destroyVMMigrationStageDataset(ctx, name))
+	errs = append(errs, s.destroyLocalVMMigrationSnapshots(ctx, name))
+	return errors.Join(errs...)
+}
+
+func (s *Server) destroyVMMigrationStageDataset(ctx context.Context, name string) error {
+	dataset, ok, err := s.vmMigrationStageDataset(ctx, name)
+	if err != nil || !ok {
+		return err
+	}
+	runner := s.zfsRunner
+	if runner == nil {
+		runner = runZFSCommand
+	}
+	if _, stderr, err := runner(ctx, "destroy", "-r", dataset); err != nil && !zfsDestroyDatasetMissing(stderr) {
+		return formatZFSCommandError("zfs destroy", stderr, err)
+	}
+	return nil
+}
+
+func (s *Server) destroyLocalVMMigrationSnapshots(ctx context.Context, name string) error {
+	if _, err := s.serviceView(name); errors.Is(err, errServiceNotFound) {
+		return nil
+	}
+	_, vm, err := s.vmSnapshotService(name)
+	if err != nil {
+		return err
+	}
+	datasets := vmDataDiskSnapshotDatasets(vm.DataDisks)
+	if vm.Disk.Backend == vmDiskBackendZVOL {
+		datasets = append(datasets, vmDataDiskDataset(db.VMDataDiskConfig{Path: vm.Disk.Path}))
+	}
+	if len(datasets) == 0 {
+		return nil
+	}
+	return s.destroyVMDataDiskSnapshots(ctx, datasets, vmMigrationSnapshots())
+}
@@ -0,0 +1,345 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/yeetrun/yeet/pkg/db"
+)
+
+func stubVMMigrationRunning(t *testing.T, running bool) {
+	t.Helper()
+	old := vmMigrationIsRunning
+	t.Cleanup(func() { vmMigrationIsRunning = old })
+	vmMigrationIsRunning = func(*Server, string) (bool, error) { return running, nil }
+}
+
+func vmMigrationStreamEntries(t *testing.T, stream []byte) []string {
+	t.Helper()
+	zr, err := zstd.NewReader(bytes.NewReader(stream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return names
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+}
+
+func TestVMMigrationRawRoundTripKeepsDisksSparse(t *testing.T) {
+	source := newTestServer(t)
+	seedVMForDataDisks(t, source, "devbox")
+	stubVMMigrationRunning(t, false)
+	root := filepath.Join(source.cfg.ServicesRoot, "devbox")
+	rootDisk := make([]byte, 3*vmMigrationChunkBytes)
+	copy(rootDisk[vmMigrationChunkBytes+10:], "root data")
+	dataDisk := make([]byte, vmMigrationChunkBytes+4)
+	copy(dataDisk[vmMigrationChunkBytes:], "tail")
+	dataPath := filepath.Join(serviceDataDirForRoot(root), "disk-data.raw")
+	writeFile(t, filepath.Join(serviceDataDirForRoot(root), "rootfs.raw"), string(rootDisk), 0o600)
+	writeFile(t, dataPath, string(dataDisk), 0o600)
+	if _, _, err := source.cfg.DB.MutateService("devbox", func(_ *db.Data, s *db.Service) error {
+		s.VM.Disk.Bytes = int64(len(rootDisk))
+		s.VM.DataDisks = []db.VMDataDiskConfig{{Name: "data", Backend: vmDiskBackendRaw, Bytes: int64(len(dataDisk)), Path: dataPath}}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var stream bytes.Buffer
+	if err := source.sendVMMigration(context.Background(), "devbox", vmMigrationSendOptions{}, &stream); err != nil {
+		t.Fatalf("sendVMMigration: %v", err)
+	}
+	wantEntries := []string{"manifest.json", "disks/root/1048576", "disks/data-data/1048576"}
+	if got := vmMigrationStreamEntries(t, stream.Bytes()); !reflect.DeepEqual(got, wantEntries) {
+		t.Fatalf("stream entries = %q, want %q", got, wantEntries)
+	}
+
+	target := newTestServer(t)
+	if err := target.receiveVMMigration(context.Background(), "devbox", &stream); err != nil {
+		t.Fatalf("receiveVMMigration: %v", err)
+	}
+	stage := target.vmMigrationStageDir("devbox")
+	for id, want := range map[string][]byte{"root": rootDisk, "data-data": dataDisk} {
+		got, err := os.ReadFile(filepath.Join(stage, id+".raw"))
+		if err != nil || !bytes.Equal(got, want) {
+			t.Fatalf("staged %s differs (len %d, err %v)", id, len(got), err)
+		}
+	}
+	migration, err := target.loadVMMigration("devbox")
+	if err != nil {
+		t.Fatalf("loadVMMigration: %v", err)
+	}
+	wantDisks := []vmMigrationDisk{
+		{ID: "root", Format: vmMigrationDiskRaw, Bytes: int64(len(rootDisk))},
+		{ID: "data-data", Format: vmMigrationDiskRaw, Bytes: int64(len(dataDisk))},
+	}
+	if !reflect.DeepEqual(migration.Manifest.Disks, wantDisks) {
+		t.Fatalf("manifest disks = %+v, want %+v", migration.Manifest.Disks, wantDisks)
+	}
+}
+
+func TestSendVMMigrationRequiresStoppedVM(t *testing.T) {
+	server := newTestServer(t)
+	seedVMForDataDisks(t, server, "devbox")
+	stubVMMigrationRunning(t, true)
+	err := server.sendVMMigration(context.Background(), "devbox", vmMigrationSendOptions{}, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "stop it before sending its disks") {
+		t.Fatalf("sendVMMigration error = %v, want running VM rejection", err)
+	}
+}
+
+func TestVMMigrationZFSPrecopyThenIncremental(t *testing.T) {
+	source := newTestServer(t)
+	seedVMForDataDisks(t, source, "devbox")
+	stubVMMigrationRunning(t, true)
+	if _, _, err := source.cfg.DB.MutateService("devbox", func(_ *db.Data, s *db.Service) error {
+		s.VM.Disk = db.VMDiskConfig{Backend: vmDiskBackendZVOL, Bytes: 10 << 30, Path: "/dev/zvol/flash/yeet/devbox/root"}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var sourceZFS []string
+	source.zfsRunner = func(_ context.Context, args ...string) (string, string, error) {
+		sourceZFS = append(sourceZFS, strings.Join(args, " "))
+		if args[0] == "list" {
+			return "", "cannot open '" + args[len(args)-1] + "': dataset does not exist", errors.New("exit status 1")
+		}
+		return "", "", nil
+	}
+	target := newTestServer(t)
+	var targetZFS []string
+	target.zfsRunner = func(_ context.Context, args ...string) (string, string, error) {
+		targetZFS = append(targetZFS, strings.Join(args, " "))
+		if args[0] == "list" {
+			return "flash/yeet\t" + target.cfg.ServicesRoot + "\n", "", nil
+		}
+		return "", "", nil
+	}
+	oldSend, oldReceive := vmMigrationZFSSend, vmMigrationZFSReceive
+	t.Cleanup(func() { vmMigrationZFSSend, vmMigrationZFSReceive = oldSend, oldReceive })
+	vmMigrationZFSSend = func(_ context.Context, args ...string) (io.ReadCloser, func() error, error) {
+		return io.NopCloser(strings.NewReader("send " + strings.Join(args, " "))), func() error { return nil }, nil
+	}
+	var received []string
+	vmMigrationZFSReceive = func(_ context.Context, args ...string) (io.WriteCloser, func() error, error) {
+		r, w := io.Pipe()
+		done := make(chan string, 1)
+		go func() {
+			body, _ := io.ReadAll(r)
+			done <- strings.Join(args, " ") + " <- " + string(body)
+		}()
+		return w, func() error { received = append(received, <-done); return nil }, nil
+	}
+
+	for _, opts := range []vmMigrationSendOptions{{ZFS: true, Precopy: true}, {ZFS: true, Incremental: true}} {
+		if opts.Incremental {
+			stubVMMigrationRunning(t, false)
+		}
+		var stream bytes.Buffer
+		if err := source.sendVMMigration(context.Background(), "devbox", opts, &stream); err != nil {
+			t.Fatalf("sendVMMigration(%+v): %v", opts, err)
+		}
+		if err := target.receiveVMMigration(context.Background(), "devbox", &stream); err != nil {
+			t.Fatalf("receiveVMMigration(%+v): %v", opts, err)
+		}
+	}
+
+	wantReceived := []string{
+		"flash/yeet/devbox.migrate/root <- send -c flash/yeet/devbox/root@yeet-migrate-precopy",
+		"-F flash/yeet/devbox.migrate/root <- send -c -i @yeet-migrate-precopy flash/yeet/devbox/root@yeet-migrate",
+	}
+	if !reflect.DeepEqual(received, wantReceived) {
+		t.Fatalf("received = %q, want %q", received, wantReceived)
+	}
+	if !slicesContain(sourceZFS, "snapshot flash/yeet/devbox/root@yeet-migrate-precopy") ||
+		!slicesContain(sourceZFS, "snapshot flash/yeet/devbox/root@yeet-migrate") {
+		t.Fatalf("source zfs commands = %q, want both migration snapshots", sourceZFS)
+	}
+	if !slicesContain(targetZFS, "create -p -o canmount=off flash/yeet/devbox.migrate") {
+		t.Fatalf("target zfs commands = %q, want staging dataset", targetZFS)
+	}
+	if _, err := target.loadVMMigration("devbox"); err != nil {
+		t.Fatalf("loadVMMigration after final stream: %v", err)
+	}
+}
+
+func TestLoadVMMigrationRejectsPrecopyOnlyStage(t *testing.T) {
+	server := newTestServer(t)
+	manifest := vmMigrationManifest{
+		Format:  vmMigrationFormat,
+		Service: "devbox",
+		Precopy: true,
+		Disks:   []vmMigrationDisk{{ID: "root", Format: vmMigrationDiskZFS, Bytes: 1 << 30}},
+	}
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(server.vmMigrationStageDir("devbox"), vmMigrationManifestName), string(raw), 0o600)
+	if _, err := server.loadVMMigration("devbox"); err == nil || !strings.Contains(err.Error(), "only been pre-copied") {
+		t.Fatalf("loadVMMigration error = %v, want pre-copy rejection", err)
+	}
+}
+
+func TestValidateVMMigrationManifest(t *testing.T) {
+	valid := func() vmMigrationManifest {
+		return vmMigrationManifest{
+			Format:  vmMigrationFormat,
+			Service: "devbox",
+			Disks: []vmMigrationDisk{
+				{ID: "root", Format: vmMigrationDiskRaw, Bytes: 1 << 30},
+				{ID: "data-pg", Format: vmMigrationDiskZFS, Bytes: 1 << 30},
+			},
+		}
+	}
+	tests := []struct {
+		name   string
+		mutate func(*vmMigrationManifest)
+		want   string
+	}{
+		{name: "valid", mutate: func(*vmMigrationManifest) {}},
+		{name: "format", mutate: func(m *vmMigrationManifest) { m.Format = 9 }, want: "unsupported migration stream format"},
+		{name: "service", mutate: func(m *vmMigrationManifest) { m.Service = "other" }, want: `is for "other"`},
+		{name: "no root", mutate: func(m *vmMigrationManifest) { m.Disks = m.Disks[1:] }, want: "no root disk"},
+		{name: "bad disk", mutate: func(m *vmMigrationManifest) { m.Disks[1].ID = "../etc" }, want: "invalid migration disk"},
+		{name: "bad data disk name", mutate: func(m *vmMigrationManifest) { m.Disks[1].ID = "data-Bad" }, want: "invalid data disk name"},
+		{name: "duplicate", mutate: func(m *vmMigrationManifest) { m.Disks[1].ID = "root" }, want: "twice"},
+		{name: "raw incremental", mutate: func(m *vmMigrationManifest) { m.Disks[0].Incremental = true }, want: "cannot be incremental"},
+		{name: "raw precopy", mutate: func(m *vmMigrationManifest) { m.Precopy = true }, want: "must be a full zfs stream"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			manifest := valid()
+			tc.mutate(&manifest)
+			err := validateVMMigrationManifest(manifest, "devbox")
+			if tc.want == "" {
+				if err != nil {
+					t.Fatalf("validateVMMigrationManifest: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("validateVMMigrationManifest error = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestVMMigrationRunFlagsKeepShapeAndLANIdentity(t *testing.T) {
+	flags := vmMigrationRunFlags(vmMigrationManifest{
+		CPUs:           4,
+		MemoryBytes:    8 << 30,
+		Balloon:        vmBalloonModeAuto,
+		MemoryMinBytes: 2 << 30,
+		Networks: []vmMigrationNetwork{
+			{Mode: "svc", MAC: "02:00:00:00:00:01"},
+			{Mode: "lan", MAC: "02:00:00:00:00:02", VLAN: 4},
+		},
+		Disks: []vmMigrationDisk{{ID: "root", Bytes: 64 << 30}, {ID: "data-pg", Bytes: 1 << 30}},
+	})
+	if flags.CPUs != 4 || flags.Memory != "8589934592" || flags.MemoryMin != "2147483648" || flags.Balloon != vmBalloonModeAuto {
+		t.Fatalf("shape flags = %+v", flags)
+	}
+	if flags.Disk != "68719476736" || flags.Net != "svc,lan" || flags.MacvlanMac != "02:00:00:00:00:02" || flags.MacvlanVlan != 4 {
+		t.Fatalf("disk and network flags = %+v", flags)
+	}
+	if !flags.Restart || flags.MacvlanParent != "" {
+		t.Fatalf("flags = %+v, want restart with a target-resolved parent", flags)
+	}
+}
+
+func TestVMMigrationPlacesStagedDisks(t *testing.T) {
+	server := newTestServer(t)
+	server.zfsRunner = func(_ context.Context, args ...string) (string, string, error) {
+		return "flash/yeet\t" + server.cfg.ServicesRoot + "\n", "", nil
+	}
+	commands := stubVMDataDiskHooks(t, false)
+	migration := &vmMigrationImport{
+		Service: "devbox",
+		Stage:   server.vmMigrationStageDir("devbox"),
+		Manifest: vmMigrationManifest{Disks: []vmMigrationDisk{
+			{ID: "root", Format: vmMigrationDiskZFS, Bytes: 64 << 30},
+			{ID: "data-pg", Format: vmMigrationDiskRaw, Bytes: 1 << 30},
+		}},
+	}
+	root := resolvedServiceRoot{Root: filepath.Join(server.cfg.ServicesRoot, "devbox"), Dataset: "flash/yeet/devbox", ZFS: true}
+	disk := vmDiskPlan{Backend: vmDiskBackendZVOL, Path: "flash/yeet/devbox/root"}
+	dataDisks := migration.dataDisks(root, disk)
+	wantData := []db.VMDataDiskConfig{{Name: "pg", Backend: vmDiskBackendZVOL, Bytes: 1 << 30, Path: "/dev/zvol/flash/yeet/devbox/disk-pg"}}
+	if !reflect.DeepEqual(dataDisks, wantData) {
+		t.Fatalf("dataDisks = %+v, want %+v", dataDisks, wantData)
+	}
+	plan := vmProvisionPlan{
+		Shape:     vmShape{DiskBytes: 64 << 30},
+		Disk:      disk,
+		DiskPath:  vmDiskPathForRuntime(disk),
+		DataDisks: dataDisks,
+	}
+	if err := migration.placeDisks(context.Background(), server, plan); err != nil {
+		t.Fatalf("placeDisks: %v", err)
+	}
+	want := [][]string{
+		{"zfs", "rename", "-p", "flash/yeet/devbox.migrate/root", "flash/yeet/devbox/root"},
+		vmZVOLSettleCommand(),
+		{"zfs", "create", "-p", "-s", "-V", "1073741824", "flash/yeet/devbox/disk-pg"},
+		vmZVOLSettleCommand(),
+		{"dd", "if=" + filepath.Join(migration.Stage, "data-pg.raw"), "of=/dev/zvol/flash/yeet/devbox/disk-pg", "bs=16M", "conv=sparse", "status=none"},
+	}
+	if !reflect.DeepEqual(*commands, want) {
+		t.Fatalf("commands = %q, want %q", *commands, want)
+	}
+
+	plan.Disk.Backend = vmDiskBackendRaw
+	if err := migration.placeDisks(context.Background(), server, plan); err == nil || !strings.Contains(err.Error(), "raw files") {
+		t.Fatalf("placeDisks into raw storage error = %v, want zfs stream rejection", err)
+	}
+}
+
+func TestVMMigrationComponentsMustMatch(t *testing.T) {
+	migration := &vmMigrationImport{Service: "devbox", Manifest: vmMigrationManifest{
+		Components: &vmProvisionComponentSelection{GuestBaseID: "ubuntu-24.04-amd64-1", KernelID: "kernel-6.1-1", RuntimeID: "firecracker-1.12"},
+	}}
+	if got := migration.componentSelection(); got.KernelID != "kernel-6.1-1" {
+		t.Fatalf("componentSelection = %+v", got)
+	}
+	matching := vmProvisionArtifacts{
+		GuestBase: db.VMGuestBaseConfig{ID: "ubuntu-24.04-amd64-1"},
+		Kernel:    db.VMKernelArtifactConfig{ID: "kernel-6.1-1"},
+		Runtime:   db.VMRuntimeArtifactConfig{ID: "firecracker-1.12"},
+	}
+	if err := migration.checkComponents(matching); err != nil {
+		t.Fatalf("checkComponents: %v", err)
+	}
+	matching.Kernel.ID = "kernel-6.1-2"
+	if err := migration.checkComponents(matching); err == nil || !strings.Contains(err.Error(), "kernel-6.1-1") {
+		t.Fatalf("checkComponents error = %v, want kernel mismatch", err)
+	}
+	var none *vmMigrationImport
+	if got := none.componentSelection(); got != (vmProvisionComponentSelection{}) {
+		t.Fatalf("nil componentSelection = %+v", got)
+	}
+}
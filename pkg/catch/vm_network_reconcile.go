@@ -154,7 +154,10 @@ func (s *Server) reconcileVMNetworks(ctx context.Context) error {
 			return fmt.Errorf("reconcile VM ISO network %q: %w", service, err)
 		}
 	}
-	return runVMNetworkLifecycleCommands(nil, cleanupCmds, "reconcile VM networks")
+	if err := runVMNetworkLifecycleCommands(nil, cleanupCmds, "reconcile VM networks"); err != nil {
+		return err
+	}
+	return s.syncVMPublishedPorts("")
 }
 
 func (s *Server) EnsureVMNetwork(ctx context.Context, service string) error {
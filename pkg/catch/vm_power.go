@@ -0,0 +1,450 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/yeetrun/yeet/pkg/cli"
+	"github.com/yeetrun/yeet/pkg/db"
+)
+
+// VM power policy lives in three places: the unit's enablement (autostart), a
+// systemd drop-in next to the unit (start order and stop timeout), and a
+// settings file vm-run reads when systemd stops it (graceful shutdown).
+const (
+	vmDefaultShutdownTimeout = 30 * time.Second
+	// vmShutdownStopMargin leaves vm-run time to clean up after the guest
+	// shut down, within the unit's TimeoutStopSec.
+	vmShutdownStopMargin = 30 * time.Second
+	// vmUnitTimeoutStop is the unit's own TimeoutStopSec, sized for the
+	// default graceful shutdown.
+	vmUnitTimeoutStop      = vmDefaultShutdownTimeout + vmShutdownStopMargin
+	vmPowerDropInName      = "yeet-power.conf"
+	vmPowerSettingsFile    = "vm-power.json"
+	vmShutdownRequestLimit = 5 * time.Second
+	// vmIdleTrafficThreshold is how many bytes per check still count as
+	// idle, so ARP, NTP and DHCP renewals do not keep a VM running.
+	vmIdleTrafficThreshold = 16 << 10
+)
+
+var (
+	vmPowerSystemctl          = runVMSystemctl
+	requestVMGuestShutdownFn  = requestVMGuestShutdown
+	vmIdleStopCheckInterval   = time.Minute
+	vmIdleStopUnit            = func(name string) error { return (&vmRunner{name: name}).Stop() }
+	vmRunGuestShutdownPending atomic.Bool
+)
+
+// vmSetPower applies the power flags to the stored policy. It returns nil when
+// the result is the default policy.
+func vmSetPower(current *db.VMPowerConfig, flags cli.VMSetFlags) (*db.VMPowerConfig, error) {
+	power := db.VMPowerConfig{}
+	if current != nil {
+		power = *current
+	}
+	if flags.Autostart != "" {
+		power.NoAutostart = flags.Autostart == "off"
+	}
+	if flags.StartOrderSet {
+		power.StartOrder = flags.StartOrder
+	}
+	if flags.ShutdownTimeout != "" {
+		timeout, err := time.ParseDuration(flags.ShutdownTimeout)
+		if err != nil || timeout < 0 {
+			return nil, fmt.Errorf("invalid --shutdown-timeout %q", flags.ShutdownTimeout)
+		}
+		power.ShutdownTimeout = timeout.String()
+		if timeout == vmDefaultShutdownTimeout {
+			power.ShutdownTimeout = ""
+		}
+	}
+	if err := applyVMSetIdleStop(&power, flags.IdleStop); err != nil {
+		return nil, err
+	}
+	if power == (db.VMPowerConfig{}) {
+		return nil, nil
+	}
+	return &power, nil
+}
+
+func applyVMSetIdleStop(power *db.VMPowerConfig, raw string) error {
+	switch raw {
+	case "":
+		return nil
+	case "off":
+		power.IdleStop = ""
+		return nil
+	}
+	idle, err := time.ParseDuration(raw)
+	if err != nil || idle < time.Minute {
+		return fmt.Errorf("invalid --idle-stop %q", raw)
+	}
+	power.IdleStop = idle.String()
+	return nil
+}
+
+// vmShutdownTimeout is how long the guest gets to shut down; zero skips the
+// guest agent.
+func vmShutdownTimeout(power db.VMPowerConfig) time.Duration {
+	if power.ShutdownTimeout == "" {
+		return vmDefaultShutdownTimeout
+	}
+	timeout, err := time.ParseDuration(power.ShutdownTimeout)
+	if err != nil || timeout < 0 {
+		return vmDefaultShutdownTimeout
+	}
+	return timeout
+}
+
+func vmIdleStopAfter(power db.VMPowerConfig) time.Duration {
+	idle, err := time.ParseDuration(power.IdleStop)
+	if err != nil || idle <= 0 {
+		return 0
+	}
+	return idle
+}
+
+// updateVMPowerSettings changes the power policy. Like published ports, it
+// applies to a running VM without a restart.
+func (s *Server) updateVMPowerSettings(name string, flags cli.VMSetFlags) error {
+	_, _, err := s.cfg.DB.MutateService(name, func(_ *db.Data, service *db.Service) error {
+		if service.ServiceType != db.ServiceTypeVM || service.VM == nil {
+			return fmt.Errorf("service %q is not a VM service", name)
+		}
+		power, err := vmSetPower(service.VM.Power, flags)
+		if err != nil {
+			return err
+		}
+		service.VM.Power = power
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if flags.Autostart != "" {
+		action := "enable"
+		if flags.Autostart == "off" {
+			action = "disable"
+		}
+		if err := vmPowerSystemctl(action, vmSystemdUnitName(name)); err != nil {
+			return err
+		}
+	}
+	return s.syncVMPowerUnits()
+}
+
+// vmPowerUnit is the power policy of one VM unit.
+type vmPowerUnit struct {
+	Service     string
+	Root        string
+	AgentSocket string
+	Power       db.VMPowerConfig
+}
+
+func (s *Server) vmPowerUnits() ([]vmPowerUnit, error) {
+	dv, err := s.getDB()
+	if err != nil {
+		return nil, err
+	}
+	var units []vmPowerUnit
+	for name, sv := range dv.Services().All() {
+		if sv.ServiceType() != db.ServiceTypeVM || !sv.VM().Valid() {
+			continue
+		}
+		unit := vmPowerUnit{
+			Service:     name,
+			Root:        s.serviceRootFromView(sv),
+			AgentSocket: strings.TrimSpace(sv.VM().Sockets().VsockSocketPath),
+		}
+		if power := sv.VM().Power(); power.Valid() {
+			unit.Power = *power.AsStruct()
+		}
+		units = append(units, unit)
+	}
+	sort.Slice(units, func(i, j int) bool { return units[i].Service < units[j].Service })
+	return units, nil
+}
+
+// syncVMPowerUnits rewrites every VM's power drop-in and vm-run settings. A
+// VM's drop-in names its predecessors, so one VM's start order touches the
+// others.
+func (s *Server) syncVMPowerUnits() error {
+	units, err := s.vmPowerUnits()
+	if err != nil {
+		return err
+	}
+	changed := false
+	var errs []error
+	for _, unit := range units {
+		wrote, err := writeVMPowerDropIn(unit.Service, renderVMPowerDropIn(unit, units))
+		changed = changed || wrote
+		errs = append(errs, err, writeVMRunPowerSettings(unit))
+	}
+	if changed {
+		errs = append(errs, vmPowerSystemctl("daemon-reload"))
+	}
+	return errors.Join(errs...)
+}
+
+// renderVMPowerDropIn returns the drop-in for unit, or "" when the unit
+// needs nothing beyond its defaults.
+func renderVMPowerDropIn(unit vmPowerUnit, all []vmPowerUnit) string {
+	var after []string
+	for _, other := range all {
+		if other.Service != unit.Service && !other.Power.NoAutostart && other.Power.StartOrder < unit.Power.StartOrder {
+			after = append(after, vmSystemdUnitName(other.Service))
+		}
+	}
+	stop := vmShutdownTimeout(unit.Power) + vmShutdownStopMargin
+	var b strings.Builder
+	if len(after) > 0 {
+		fmt.Fprintf(&b, "[Unit]\nAfter=%s\n", strings.Join(after, " "))
+	}
+	if stop > vmUnitTimeoutStop {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[Service]\nTimeoutStopSec=%d\n", int(stop.Seconds()))
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "# Written by catch from `yeet vm set`; do not edit.\n" + b.String()
+}
+
+func vmPowerDropInPath(service string) string {
+	return filepath.Join(vmSystemdSystemDir, vmSystemdUnitName(service)+".d", vmPowerDropInName)
+}
+
+// writeVMPowerDropIn installs or removes the drop-in and reports whether
+// systemd needs a reload.
+func writeVMPowerDropIn(service, content string) (bool, error) {
+	path := vmPowerDropInPath(service)
+	existing, err := os.ReadFile(path)
+	switch {
+	case err != nil && !os.IsNotExist(err):
+		return false, err
+	case content == "" && err != nil:
+		return false, nil
+	case content == "":
+		return true, removeVMPowerDropIn(service)
+	case err == nil && bytes.Equal(existing, []byte(content)):
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return false, err
+	}
+	if err := writeVMSystemdUnitAtomic(path, []byte(content), 0o644); err != nil {
+		return false, fmt.Errorf("write VM power drop-in %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// removeVMPowerDropIn removes the drop-in and its directory if nothing else
+// is in it.
+func removeVMPowerDropIn(service string) error {
+	path := vmPowerDropInPath(service)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove VM power drop-in %s: %w", path, err)
+	}
+	_ = os.Remove(filepath.Dir(path))
+	return nil
+}
+
+// vmRunPowerSettings is what vm-run needs to shut the guest down when
+// systemd stops the unit. vm-run cannot read the catch database.
+type vmRunPowerSettings struct {
+	ShutdownTimeout string `json:"shutdownTimeout"`
+	AgentSocket     string `json:"agentSocket,omitempty"`
+}
+
+func vmRunPowerSettingsPath(serviceRoot string) string {
+	return filepath.Join(serviceDataDirForRoot(serviceRoot), vmPowerSettingsFile)
+}
+
+func writeVMRunPowerSettings(unit vmPowerUnit) error {
+	if strings.TrimSpace(unit.Root) == "" {
+		return nil
+	}
+	path := vmRunPowerSettingsPath(unit.Root)
+	if unit.Power == (db.VMPowerConfig{}) {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	raw, err := json.Marshal(vmRunPowerSettings{
+		ShutdownTimeout: vmShutdownTimeout(unit.Power).String(),
+		AgentSocket:     unit.AgentSocket,
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeTextFileAtomically(path, append(raw, '\n'), 0o644)
+}
+
+// readVMRunPowerSettings falls back to the defaults when catch wrote no
+// settings, which is the case for VMs on the default policy.
+func readVMRunPowerSettings(serviceRoot string) (time.Duration, string) {
+	settings := vmRunPowerSettings{AgentSocket: filepath.Join(serviceRunDirForRoot(serviceRoot), "vsock.sock")}
+	if raw, err := os.ReadFile(vmRunPowerSettingsPath(serviceRoot)); err == nil {
+		_ = json.Unmarshal(raw, &settings)
+	}
+	timeout, err := time.ParseDuration(settings.ShutdownTimeout)
+	if err != nil {
+		timeout = vmDefaultShutdownTimeout
+	}
+	return timeout, settings.AgentSocket
+}
+
+// shutdownVMGuestOnStop asks the guest agent to shut the guest down and
+// waits for Firecracker to exit, which ends vm-run, or for the timeout. It
+// returns early, leaving Firecracker to be killed, when the agent does not
+// answer or the VM was just hibernated and is paused.
+func shutdownVMGuestOnStop(ctx context.Context, serviceRoot string) {
+	if strings.TrimSpace(serviceRoot) == "" || vmHibernationPending(serviceRoot) {
+		return
+	}
+	timeout, socket := readVMRunPowerSettings(serviceRoot)
+	if timeout <= 0 || strings.TrimSpace(socket) == "" {
+		return
+	}
+	vmRunGuestShutdownPending.Store(true)
+	requestCtx, cancel := context.WithTimeout(ctx, vmShutdownRequestLimit)
+	err := requestVMGuestShutdownFn(requestCtx, socket)
+	cancel()
+	if err != nil {
+		vmRunGuestShutdownPending.Store(false)
+		fmt.Fprintf(os.Stderr, "warning: graceful VM shutdown: %v\n", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "asked the guest to shut down; waiting up to %s\n", timeout)
+	select {
+	case <-ctx.Done():
+	case <-time.After(timeout):
+		fmt.Fprintf(os.Stderr, "guest did not shut down within %s; stopping Firecracker\n", timeout)
+	}
+}
+
+// vmIdleState tracks one VM between idle-stop checks.
+type vmIdleState struct {
+	Traffic    int64
+	LastActive time.Time
+}
+
+// nextVMIdleState records a sample. A VM counts as active the first time it
+// is seen, while anyone is logged in over SSH, or when its traffic since the
+// last check is above vmIdleTrafficThreshold.
+func nextVMIdleState(prev vmIdleState, seen bool, traffic int64, sshSessions int, now time.Time) vmIdleState {
+	next := vmIdleState{Traffic: traffic, LastActive: prev.LastActive}
+	delta := traffic - prev.Traffic
+	if !seen || sshSessions > 0 || delta < 0 || delta > vmIdleTrafficThreshold {
+		next.LastActive = now
+	}
+	return next
+}
+
+func (s *Server) runVMIdleStopper(ctx context.Context) {
+	ticker := time.NewTicker(vmIdleStopCheckInterval)
+	defer ticker.Stop()
+	states := map[string]vmIdleState{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.checkVMIdleStop(ctx, states, time.Now()); err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf("VM idle stop check failed: %v", err)
+			}
+		}
+	}
+}
+
+// checkVMIdleStop samples every running VM with an idle-stop policy and stops
+// the ones idle for longer than their window. states carries samples between
+// checks; VMs that stopped or lost the policy are dropped from it.
+func (s *Server) checkVMIdleStop(ctx context.Context, states map[string]vmIdleState, now time.Time) error {
+	dv, err := s.getDB()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	tracked := map[string]bool{}
+	for name, sv := range dv.Services().All() {
+		idle := vmIdleStopWindow(sv)
+		if idle <= 0 {
+			continue
+		}
+		keep, err := s.checkOneVMIdleStop(ctx, states, name, sv.VM(), idle, now)
+		tracked[name] = keep
+		errs = append(errs, err)
+	}
+	for name := range states {
+		if !tracked[name] {
+			delete(states, name)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func vmIdleStopWindow(sv db.ServiceView) time.Duration {
+	if sv.ServiceType() != db.ServiceTypeVM || !sv.VM().Valid() || !sv.VM().Power().Valid() {
+		return 0
+	}
+	return vmIdleStopAfter(*sv.VM().Power().AsStruct())
+}
+
+// checkOneVMIdleStop reports whether the VM is still running and tracked.
+func (s *Server) checkOneVMIdleStop(ctx context.Context, states map[string]vmIdleState, name string, vm db.VMConfigView, idle time.Duration, now time.Time) (bool, error) {
+	running, err := s.isServiceTypeRunning(name, db.ServiceTypeVM)
+	if err != nil || !running {
+		return false, err
+	}
+	prev, seen := states[name]
+	state := nextVMIdleState(prev, seen, vmIdleTraffic(vmMetricsTargetForView(name, vm)), vmIdleSSHSessions(ctx, vm), now)
+	states[name] = state
+	if now.Sub(state.LastActive) < idle {
+		return true, nil
+	}
+	log.Printf("stopping VM %q after %s without network traffic or SSH sessions", name, idle)
+	return false, vmIdleStopUnit(name)
+}
+
+func vmIdleTraffic(target vmMetricsTarget) int64 {
+	traffic := readVMTapTraffic(target.Taps)
+	if traffic == nil {
+		return 0
+	}
+	return traffic.RxBytes + traffic.TxBytes
+}
+
+// vmIdleSSHSessions asks the guest agent; an agent that cannot answer counts
+// as no sessions, and an SSH session that moves data still shows as traffic.
+func vmIdleSSHSessions(ctx context.Context, vm db.VMConfigView) int {
+	socket := strings.TrimSpace(vm.Sockets().VsockSocketPath)
+	if socket == "" {
+		return 0
+	}
+	metrics, err := queryVMGuestMetricsFn(ctx, socket)
+	if err != nil {
+		return 0
+	}
+	return metrics.SSHSessions
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yeetrun/yeet/pkg/cli"
+	"github.com/yeetrun/yeet/pkg/db"
+	"github.com/yeetrun/yeet/pkg/svc"
+)
+
+func TestVMSetPower(t *testing.T) {
+	got, err := vmSetPower(nil, cli.VMSetFlags{Autostart: "off", StartOrder: 10, StartOrderSet: true, ShutdownTimeout: "90s", IdleStop: "30m"})
+	if err != nil {
+		t.Fatalf("vmSetPower: %v", err)
+	}
+	want := &db.VMPowerConfig{NoAutostart: true, StartOrder: 10, ShutdownTimeout: "1m30s", IdleStop: "30m0s"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("power = %#v, want %#v", got, want)
+	}
+	got, err = vmSetPower(got, cli.VMSetFlags{Autostart: "on", StartOrderSet: true, IdleStop: "off"})
+	if err != nil || !reflect.DeepEqual(got, &db.VMPowerConfig{ShutdownTimeout: "1m30s"}) {
+		t.Fatalf("update = %#v, %v", got, err)
+	}
+	if got, err := vmSetPower(&db.VMPowerConfig{IdleStop: "1h0m0s"}, cli.VMSetFlags{IdleStop: "off"}); err != nil || got != nil {
+		t.Fatalf("reset = %#v, %v, want default policy", got, err)
+	}
+	if _, err := vmSetPower(nil, cli.VMSetFlags{IdleStop: "5s"}); err == nil {
+		t.Fatal("vmSetPower accepted a 5s idle stop")
+	}
+}
+
+func TestSplitVMSetPowerFlags(t *testing.T) {
+	rest, power := splitVMSetPowerFlags(cli.VMSetFlags{CPUs: 2, Autostart: "on", StartOrderSet: true, StartOrder: 3})
+	if !reflect.DeepEqual(rest, cli.VMSetFlags{CPUs: 2}) {
+		t.Fatalf("rest = %#v", rest)
+	}
+	if !reflect.DeepEqual(power, cli.VMSetFlags{Autostart: "on", StartOrderSet: true, StartOrder: 3}) {
+		t.Fatalf("power = %#v", power)
+	}
+}
+
+func TestRenderVMPowerDropIn(t *testing.T) {
+	units := []vmPowerUnit{
+		{Service: "db"},
+		{Service: "manual", Power: db.VMPowerConfig{NoAutostart: true}},
+		{Service: "web", Power: db.VMPowerConfig{StartOrder: 10, ShutdownTimeout: "2m0s"}},
+	}
+	if got := renderVMPowerDropIn(units[0], units); got != "" {
+		t.Fatalf("default unit drop-in = %q, want none", got)
+	}
+	want := "# Written by catch from `yeet vm set`; do not edit.\n" +
+		"[Unit]\nAfter=yeet-vm-db.service\n\n[Service]\nTimeoutStopSec=150\n"
+	if got := renderVMPowerDropIn(units[2], units); got != want {
+		t.Fatalf("drop-in = %q, want %q", got, want)
+	}
+}
+
+func withVMPowerSystemd(t *testing.T) *[]string {
+	t.Helper()
+	oldDir, oldSystemctl := vmSystemdSystemDir, vmPowerSystemctl
+	t.Cleanup(func() { vmSystemdSystemDir, vmPowerSystemctl = oldDir, oldSystemctl })
+	vmSystemdSystemDir = t.TempDir()
+	var calls []string
+	vmPowerSystemctl = func(args ...string) error {
+		calls = append(calls, strings.Join(args, " "))
+		return nil
+	}
+	return &calls
+}
+
+func addTestVMService(t *testing.T, server *Server, name string, power *db.VMPowerConfig) {
+	t.Helper()
+	_, _, err := server.cfg.DB.MutateService(name, func(_ *db.Data, service *db.Service) error {
+		service.ServiceType = db.ServiceTypeVM
+		service.VM = &db.VMConfig{Power: power, Sockets: db.VMSocketConfig{VsockSocketPath: "/run/" + name + "/vsock.sock"}}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateVMPowerSettingsWritesUnitsAndRunnerSettings(t *testing.T) {
+	calls := withVMPowerSystemd(t)
+	server := newTestServer(t)
+	addTestVMService(t, server, "db", nil)
+	addTestVMService(t, server, "web", nil)
+
+	flags := cli.VMSetFlags{Autostart: "off", StartOrder: 5, StartOrderSet: true, ShutdownTimeout: "10s"}
+	if err := server.updateVMServiceSettings(context.Background(), "web", flags); err != nil {
+		t.Fatalf("updateVMServiceSettings: %v", err)
+	}
+	if want := []string{"disable yeet-vm-web.service", "daemon-reload"}; !reflect.DeepEqual(*calls, want) {
+		t.Fatalf("systemctl = %#v, want %#v", *calls, want)
+	}
+	dropIn, err := os.ReadFile(vmPowerDropInPath("web"))
+	if err != nil || !strings.Contains(string(dropIn), "After=yeet-vm-db.service") {
+		t.Fatalf("drop-in = %q, %v", dropIn, err)
+	}
+	sv, err := server.serviceView("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeout, socket := readVMRunPowerSettings(server.serviceRootFromView(sv))
+	if timeout != 10*time.Second || socket != "/run/web/vsock.sock" {
+		t.Fatalf("runner settings = %s %q", timeout, socket)
+	}
+
+	*calls = nil
+	if err := server.updateVMServiceSettings(context.Background(), "web", cli.VMSetFlags{Autostart: "on", StartOrderSet: true, ShutdownTimeout: "30s"}); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if _, err := os.Stat(vmPowerDropInPath("web")); !os.IsNotExist(err) {
+		t.Fatalf("drop-in still present: %v", err)
+	}
+	if _, err := os.Stat(vmRunPowerSettingsPath(server.serviceRootFromView(sv))); !os.IsNotExist(err) {
+		t.Fatalf("runner settings still present: %v", err)
+	}
+	if want := []string{"enable yeet-vm-web.service", "daemon-reload"}; !reflect.DeepEqual(*calls, want) {
+		t.Fatalf("systemctl = %#v, want %#v", *calls, want)
+	}
+}
+
+func TestShutdownVMGuestOnStopUsesAgent(t *testing.T) {
+	root := t.TempDir()
+	if err := writeVMRunPowerSettings(vmPowerUnit{Service: "devbox", Root: root, AgentSocket: "agent.sock", Power: db.VMPowerConfig{ShutdownTimeout: "1ms"}}); err != nil {
+		t.Fatal(err)
+	}
+	oldRequest := requestVMGuestShutdownFn
+	t.Cleanup(func() {
+		requestVMGuestShutdownFn = oldRequest
+		vmRunGuestShutdownPending.Store(false)
+	})
+	var asked string
+	requestVMGuestShutdownFn = func(_ context.Context, socket string) error {
+		asked = socket
+		return nil
+	}
+	shutdownVMGuestOnStop(context.Background(), root)
+	if asked != "agent.sock" || !vmRunGuestShutdownPending.Load() {
+		t.Fatalf("asked %q, pending %v", asked, vmRunGuestShutdownPending.Load())
+	}
+
+	vmRunGuestShutdownPending.Store(false)
+	requestVMGuestShutdownFn = func(context.Context, string) error { return errors.New("agent down") }
+	shutdownVMGuestOnStop(context.Background(), root)
+	if vmRunGuestShutdownPending.Load() {
+		t.Fatal("shutdown still pending after the agent failed")
+	}
+}
+
+func TestRequestVMGuestShutdown(t *testing.T) {
+	socketPath, requests := startFakeVsockAgentWithRequests(t, `{"protocol":1,"type":"shutdown","request_id":"test"}`)
+	if err := requestVMGuestShutdown(context.Background(), socketPath); err != nil {
+		t.Fatalf("requestVMGuestShutdown: %v", err)
+	}
+	if req := <-requests; req.Type != "shutdown" {
+		t.Fatalf("agent request type = %q, want shutdown", req.Type)
+	}
+}
+
+func TestNextVMIdleState(t *testing.T) {
+	start := time.Unix(1000, 0)
+	state := nextVMIdleState(vmIdleState{}, false, 5000, 0, start)
+	if !state.LastActive.Equal(start) {
+		t.Fatalf("first sample not active: %#v", state)
+	}
+	later := start.Add(time.Minute)
+	if got := nextVMIdleState(state, true, 5000+vmIdleTrafficThreshold, 0, later); !got.LastActive.Equal(start) {
+		t.Fatalf("background traffic counted as activity: %#v", got)
+	}
+	if got := nextVMIdleState(state, true, 5000, 1, later); !got.LastActive.Equal(later) {
+		t.Fatalf("SSH session not counted as activity: %#v", got)
+	}
+	if got := nextVMIdleState(state, true, 1<<20, 0, later); !got.LastActive.Equal(later) {
+		t.Fatalf("traffic not counted as activity: %#v", got)
+	}
+}
+
+func TestCheckVMIdleStopStopsIdleVM(t *testing.T) {
+	withVMMetricsHostFiles(t)
+	server := newTestServer(t)
+	addTestVMService(t, server, "devbox", &db.VMPowerConfig{IdleStop: "30m0s"})
+	addTestVMService(t, server, "busy", nil)
+	oldStatus, oldStop := serverVMStatusFunc, vmIdleStopUnit
+	t.Cleanup(func() { serverVMStatusFunc, vmIdleStopUnit = oldStatus, oldStop })
+	serverVMStatusFunc = func(string) (svc.Status, error) { return svc.StatusRunning, nil }
+	var stopped []string
+	vmIdleStopUnit = func(name string) error {
+		stopped = append(stopped, name)
+		return nil
+	}
+	queryVMGuestMetricsFn = func(context.Context, string) (vmAgentMetrics, error) { return vmAgentMetrics{}, nil }
+
+	states := map[string]vmIdleState{}
+	start := time.Unix(1000, 0)
+	for _, now := range []time.Time{start, start.Add(29 * time.Minute)} {
+		if err := server.checkVMIdleStop(context.Background(), states, now); err != nil {
+			t.Fatalf("checkVMIdleStop: %v", err)
+		}
+	}
+	if len(stopped) != 0 {
+		t.Fatalf("stopped %v before the idle window", stopped)
+	}
+	if err := server.checkVMIdleStop(context.Background(), states, start.Add(30*time.Minute)); err != nil {
+		t.Fatalf("checkVMIdleStop: %v", err)
+	}
+	if !reflect.DeepEqual(stopped, []string{"devbox"}) {
+		t.Fatalf("stopped = %v, want devbox", stopped)
+	}
+	if _, ok := states["devbox"]; ok {
+		t.Fatal("stopped VM still tracked")
+	}
+}
+
+func TestRemoveVMPowerDropInKeepsOtherDropIns(t *testing.T) {
+	withVMPowerSystemd(t)
+	if _, err := writeVMPowerDropIn("devbox", "[Unit]\nAfter=x.service\n"); err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(filepath.Dir(vmPowerDropInPath("devbox")), "local.conf")
+	if err := os.WriteFile(other, []byte("[Service]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeVMPowerDropIn("devbox"); err != nil {
+		t.Fatalf("removeVMPowerDropIn: %v", err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Fatalf("other drop-in removed: %v", err)
+	}
+}
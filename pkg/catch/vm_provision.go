@@ -57,8 +57,10 @@ type vmProvisionPlan struct {
 	Components  *db.VMComponentsConfig
 	Disk        vmDiskPlan
 	DiskPath    string
+	DataDisks   []db.VMDataDiskConfig
 	Network     vmNetworkPlan
 	SvcNetwork  *db.SvcNetwork
+	Publish     []string
 	Metadata    vmMetadataConfig
 
 	FirecrackerConfigPath  string
@@ -258,6 +260,12 @@ func validateVMProvisionFlags(flags cli.RunFlags) error {
 	if _, err := normalizeVMProvisionImagePolicy(flags.ImagePolicy); err != nil {
 		return err
 	}
+	if _, err := vmUserDataFromFlag(flags.UserData); err != nil {
+		return err
+	}
+	if err := validateVMPublish(flags.Publish, vmRequestedNetworkModes(flags.Net)); err != nil {
+		return err
+	}
 	return validateVMNetworkOptions(vmRequestedNetworkModes(flags.Net), flags.MacvlanParent, flags.MacvlanVlan, flags.MacvlanMac)
 }
 
@@ -388,6 +396,9 @@ func (e *ttyExecer) vmProvisionInputs(flags cli.RunFlags, payload string, ui Pro
 		return inputs, err
 	}
 	inputs.Artifacts = artifacts
+	if err := e.vmMigration.checkComponents(artifacts); err != nil {
+		return inputs, err
+	}
 	if _, err := artifacts.Image.RequireJailer(); err != nil {
 		return inputs, err
 	}
@@ -420,7 +431,7 @@ func resolveVMProvisionArtifactsDefault(e *ttyExecer, ctx context.Context, flags
 			if err != nil {
 				return vmProvisionArtifacts{}, err
 			}
-			refs, err := resolveVMProvisionComponentRefs(imageRef, catalogs, vmProvisionComponentSelection{}, policy.Channel)
+			refs, err := resolveVMProvisionComponentRefs(imageRef, catalogs, e.vmMigration.componentSelection(), policy.Channel)
 			if err != nil {
 				return vmProvisionArtifacts{}, err
 			}
@@ -1046,6 +1057,7 @@ func (e *ttyExecer) finishVMProvision(ctx context.Context, plan vmProvisionPlan,
 		ui.DoneStep("")
 		committed = true
 	}
+	commitWarning = errors.Join(commitWarning, e.publishVMProvisionPorts(plan), e.s.syncVMPowerUnits())
 	if restart {
 		doneStart := e.traceBlock("vm start")
 		ready, err = e.startVMAfterProvision(ctx, plan, ui)
@@ -1093,6 +1105,15 @@ func (e *ttyExecer) commitVMProvisionForFinish(plan vmProvisionPlan, payload str
 	return e.commitVMProvision(plan, payload, snapshotPolicyFlags)
 }
 
+// publishVMProvisionPorts installs the new VM's published ports once it is
+// committed. A failure leaves the VM in place; vm set -p retries it.
+func (e *ttyExecer) publishVMProvisionPorts(plan vmProvisionPlan) error {
+	if len(plan.Publish) == 0 {
+		return nil
+	}
+	return e.s.syncVMPublishedPorts("")
+}
+
 func cleanupFailedVMSystemdUnit(plan vmProvisionPlan) error {
 	systemctl := vmProvisionSystemctlFunc
 	if systemctl == nil {
@@ -1369,11 +1390,19 @@ func (e *ttyExecer) newVMProvisionPlan(flags cli.RunFlags, payload string, resol
 	}
 	guestUser := vmGuestUserForImage(payload, image.Manifest)
 	metadataDriver := vmMetadataDriverForImage(payload, image.Manifest)
+	userData, err := vmUserDataFromFlag(flags.UserData)
+	if err != nil {
+		return vmProvisionPlan{}, err
+	}
+	if err := validateVMUserDataDriver(userData, metadataDriver); err != nil {
+		return vmProvisionPlan{}, err
+	}
 
 	runDir := serviceRunDirForRoot(resolvedRoot.Root)
 	binDir := serviceBinDirForRoot(resolvedRoot.Root)
 	diskPlan := newVMProvisionDiskPlan(e.sn, resolvedRoot, shape, image)
 	diskPath := vmDiskPathForRuntime(diskPlan)
+	dataDisks := e.vmMigration.dataDisks(resolvedRoot, diskPlan)
 
 	firecrackerPath := filepath.Join(runDir, "firecracker.json")
 	apiSocket := filepath.Join(runDir, "firecracker.sock")
@@ -1402,12 +1431,12 @@ func (e *ttyExecer) newVMProvisionPlan(flags cli.RunFlags, payload string, resol
 			InitrdPath:      image.Paths.InitrdPath,
 			BootArgs:        bootArgs,
 		},
-		Drives: []firecrackerDrive{{
+		Drives: vmFirecrackerDrivesWithDataDisks([]firecrackerDrive{{
 			DriveID:      "rootfs",
 			PathOnHost:   diskPath,
 			IsRootDevice: true,
 			IsReadOnly:   false,
-		}},
+		}}, dataDisks),
 		NetworkInterfaces: networkPlan.FirecrackerInterfaces(),
 		MachineConfig: firecrackerMachineConfig{
 			VCPUCount:  shape.CPUs,
@@ -1472,9 +1501,11 @@ func (e *ttyExecer) newVMProvisionPlan(flags cli.RunFlags, payload string, resol
 		Components:             components,
 		Disk:                   diskPlan,
 		DiskPath:               diskPath,
+		DataDisks:              dataDisks,
 		Network:                networkPlan,
 		SvcNetwork:             svcNet,
-		Metadata:               vmMetadataConfig{Hostname: e.sn, User: guestUser, SSHKey: sshKey, Networks: networkPlan.MetadataNetworks(), FastBoot: fastBoot, MetadataDriver: metadataDriver, HostKeyDir: filepath.Join(resolvedRoot.Root, "metadata", "ssh-host-keys")},
+		Publish:                normalizePublish(flags.Publish),
+		Metadata:               vmMetadataConfig{Hostname: e.sn, User: guestUser, SSHKey: sshKey, Networks: networkPlan.MetadataNetworks(), FastBoot: fastBoot, MetadataDriver: metadataDriver, HostKeyDir: filepath.Join(resolvedRoot.Root, "metadata", "ssh-host-keys"), UserData: userData},
 		FirecrackerConfigPath:  firecrackerPath,
 		FirecrackerConfig:      firecrackerConfig,
 		SystemdUnitStagePath:   filepath.Join(binDir, unitName),
@@ -1593,9 +1624,12 @@ func (e *ttyExecer) applyVMProvisionDisk(ctx context.Context, plan vmProvisionPl
 	ui.StartStep(vmRunStepDisk)
 	doneDisk := e.traceBlock("vm disk provision")
 	var err error
-	if plan.Disk.Backend == vmDiskBackendZVOL {
+	switch {
+	case e.vmMigration != nil:
+		err = e.vmMigration.placeDisks(ctx, e.s, plan)
+	case plan.Disk.Backend == vmDiskBackendZVOL:
 		err = runVMProvisionDiskPlanWithProgress(ctx, plan.Disk, vmProvisionDiskRunner, ui.UpdateDetail)
-	} else {
+	default:
 		err = runVMProvisionDiskPlan(ctx, plan.Disk, vmProvisionDiskRunner)
 	}
 	doneDisk()
@@ -1773,7 +1807,10 @@ func stageVMProvisionVerifiedFile(source io.Reader, parent, target, label string
 
 //nolint:cyclop // Generation and ISO allocation checks stay adjacent to the atomic DB mutation.
 func (e *ttyExecer) commitVMProvision(plan vmProvisionPlan, payload string, snapshotPolicyFlags *cli.ServiceSetFlags) error {
-	_, _, err := e.s.cfg.DB.MutateService(e.sn, func(_ *db.Data, s *db.Service) error {
+	_, _, err := e.s.cfg.DB.MutateService(e.sn, func(d *db.Data, s *db.Service) error {
+		if err := checkVMPublishConflicts(d, e.sn, plan.Publish); err != nil {
+			return err
+		}
 		if plan.Network.hasNetworkMode("iso") {
 			if s.ISO == nil || s.ISO.Kind != string(iso.PayloadVM) || s.ISO.RemoveRequested || s.ISO.State != string(iso.StateReserved) {
 				return fmt.Errorf("service %q no longer has its reserved VM ISO allocation", e.sn)
@@ -1789,6 +1826,7 @@ func (e *ttyExecer) commitVMProvision(plan vmProvisionPlan, payload string, snap
 		if plan.SvcNetwork != nil {
 			s.SvcNetwork = plan.SvcNetwork
 		}
+		s.Publish = plan.Publish
 		imageRootFS := plan.Image.DiskRootFSPath()
 		imageKernel := plan.Image.Paths.KernelPath
 		imageDigest := ""
@@ -1822,16 +1860,18 @@ func (e *ttyExecer) commitVMProvision(plan vmProvisionPlan, payload string, snap
 				Bytes:   plan.Shape.DiskBytes,
 				Path:    plan.DiskPath,
 			},
-			Networks: plan.Network.DBNetworks(),
-			SSH:      db.VMSSHConfig{User: plan.Metadata.User},
-			Console:  db.VMConsoleConfig{SocketPath: plan.SerialSocket, LogPath: plan.SerialLog},
+			DataDisks: plan.DataDisks,
+			Networks:  plan.Network.DBNetworks(),
+			SSH:       db.VMSSHConfig{User: plan.Metadata.User},
+			Console:   db.VMConsoleConfig{SocketPath: plan.SerialSocket, LogPath: plan.SerialLog},
 			Sockets: db.VMSocketConfig{
 				APISocketPath:   plan.APISocket,
 				VsockSocketPath: plan.VsockSocket,
 				VsockGuestCID:   vmAgentGuestCID,
 			},
-			PIDFile:    plan.PIDFile,
-			SetupState: "ready",
+			PIDFile:        plan.PIDFile,
+			SetupState:     "ready",
+			UserDataSHA256: plan.Metadata.UserData.SHA256(),
 		}
 		if snapshotPolicyFlags != nil {
 			if err := applySnapshotFlagsToService(s, *snapshotPolicyFlags); err != nil {
@@ -2879,6 +2879,7 @@ func newVMProvisionTestExecer(t *testing.T, server *Server, service string) (*tt
 		return "ssh-ed25519 AAAATEST user@example", nil
 	}
 	vmProvisionSystemdDir = systemdDir
+	withVMPowerSystemd(t)
 	systemctlCalls := [][]string{}
 	vmProvisionSystemctlFunc = func(args ...string) error {
 		systemctlCalls = append(systemctlCalls, append([]string(nil), args...))
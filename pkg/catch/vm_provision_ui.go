@@ -208,6 +208,9 @@ func vmProvisionSuccessBlockLines(s vmProvisionSuccess) []string {
 			"Console  yeet vm console "+service,
 		)
 	}
+	if s.Plan.Metadata.UserData != nil {
+		lines = append(lines, "Setup    user-data runs on first boot; check yeet info "+service)
+	}
 	return append(lines,
 		"",
 		"SSH      yeet ssh "+service,
@@ -0,0 +1,367 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yeetrun/yeet/pkg/cli"
+	"github.com/yeetrun/yeet/pkg/db"
+)
+
+// VM published ports are DNAT rules in the host network namespace that
+// forward a host port to the VM's svc address. Catch owns both chains
+// outright and rebuilds them from the DB on every change.
+const (
+	vmPublishPreroutingChain = "YEET_VM_PREROUTING"
+	vmPublishOutputChain     = "YEET_VM_OUTPUT"
+)
+
+// vmPublishedPort is one HOST:GUEST[/udp] mapping.
+type vmPublishedPort struct {
+	Proto     string
+	HostPort  uint16
+	GuestPort uint16
+}
+
+type vmPublishRule struct {
+	vmPublishedPort
+	Service string
+	GuestIP netip.Addr
+}
+
+func (p vmPublishedPort) hostKey() string {
+	return strconv.Itoa(int(p.HostPort)) + "/" + p.Proto
+}
+
+func parseVMPublishedPorts(publish []string) ([]vmPublishedPort, error) {
+	ports := make([]vmPublishedPort, 0, len(publish))
+	seen := map[string]bool{}
+	for _, mapping := range normalizePublish(publish) {
+		port, err := parseVMPublishedPort(mapping)
+		if err != nil {
+			return nil, err
+		}
+		if seen[port.hostKey()] {
+			return nil, fmt.Errorf("host port %s is published twice", port.hostKey())
+		}
+		seen[port.hostKey()] = true
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+func parseVMPublishedPort(mapping string) (vmPublishedPort, error) {
+	port := vmPublishedPort{Proto: "tcp"}
+	spec := mapping
+	if rest, ok := strings.CutSuffix(spec, "/udp"); ok {
+		spec, port.Proto = rest, "udp"
+	}
+	host, guest, ok := strings.Cut(spec, ":")
+	if !ok || strings.Contains(guest, ":") {
+		return vmPublishedPort{}, fmt.Errorf("invalid published port %q: VMs take HOST:GUEST[/udp]", mapping)
+	}
+	var err error
+	if port.HostPort, err = parseVMPublishPortNumber(host); err != nil {
+		return vmPublishedPort{}, fmt.Errorf("invalid published port %q: %w", mapping, err)
+	}
+	if port.GuestPort, err = parseVMPublishPortNumber(guest); err != nil {
+		return vmPublishedPort{}, fmt.Errorf("invalid published port %q: %w", mapping, err)
+	}
+	return port, nil
+}
+
+func parseVMPublishPortNumber(raw string) (uint16, error) {
+	n, err := strconv.ParseUint(strings.TrimSpace(raw), 10, 16)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("port %q must be between 1 and 65535", raw)
+	}
+	return uint16(n), nil
+}
+
+// validateVMPublish checks that the ports parse and that the VM's network can
+// carry them. With lan as well, the guest routes replies out of its LAN
+// interface, so forwarded connections would never complete.
+func validateVMPublish(publish []string, modes []string) error {
+	if len(normalizePublish(publish)) == 0 {
+		return nil
+	}
+	if _, err := parseVMPublishedPorts(publish); err != nil {
+		return err
+	}
+	if !vmModeListContains(modes, "svc") {
+		return fmt.Errorf("published ports forward to the VM's svc address; use --net=svc")
+	}
+	if vmModeListContains(modes, "lan") {
+		return fmt.Errorf("published ports need svc as the VM's only network; a VM on lan is reachable at its LAN address instead")
+	}
+	return nil
+}
+
+// checkVMPublishConflicts rejects host ports another service already
+// publishes: another VM, a service published with -p, or a compose service
+// whose file declares ports.
+func checkVMPublishConflicts(d *db.Data, service string, publish []string) error {
+	ports, err := parseVMPublishedPorts(publish)
+	if err != nil || len(ports) == 0 {
+		return err
+	}
+	taken := map[string]string{}
+	for name, other := range d.Services {
+		if name == service || other == nil {
+			continue
+		}
+		owner := fmt.Sprintf("service %q", name)
+		if other.ServiceType == db.ServiceTypeVM {
+			owner = fmt.Sprintf("VM %q", name)
+		}
+		for _, key := range publishedHostPortKeys(servicePublishedPorts(other)) {
+			taken[key] = owner
+		}
+	}
+	for _, port := range ports {
+		if owner, ok := taken[port.hostKey()]; ok {
+			return fmt.Errorf("host port %s is already published by %s", port.hostKey(), owner)
+		}
+	}
+	return nil
+}
+
+// servicePublishedPorts returns the port mappings a service holds on the
+// host. Compose files can declare ports without -p, so the latest compose
+// file is read as well; an unreadable file contributes nothing.
+func servicePublishedPorts(service *db.Service) []string {
+	publish := slices.Clone(service.Publish)
+	if service.ServiceType != db.ServiceTypeDockerCompose {
+		return publish
+	}
+	path, ok := service.Artifacts.Latest(db.ArtifactDockerComposeFile)
+	if !ok {
+		return publish
+	}
+	declared, err := readComposeFilePorts(path)
+	if err != nil {
+		return publish
+	}
+	return append(publish, declared...)
+}
+
+// publishedHostPortKeys expands compose short-syntax mappings
+// ([IP:]HOST[-END]:CONTAINER[/PROTO]) to host port keys. Mappings without a
+// host port get an ephemeral one and hold nothing.
+func publishedHostPortKeys(publish []string) []string {
+	var keys []string
+	for _, mapping := range normalizePublish(publish) {
+		proto := "tcp"
+		if rest, ok := strings.CutSuffix(mapping, "/udp"); ok {
+			mapping, proto = rest, "udp"
+		}
+		last := strings.LastIndexByte(mapping, ':')
+		if last < 0 {
+			continue
+		}
+		host := mapping[:last]
+		if i := strings.LastIndexByte(host, ':'); i >= 0 {
+			host = host[i+1:]
+		}
+		first, end, _ := strings.Cut(host, "-")
+		if end == "" {
+			end = first
+		}
+		lo, errLo := strconv.ParseUint(first, 10, 16)
+		hi, errHi := strconv.ParseUint(end, 10, 16)
+		if errLo != nil || errHi != nil || lo == 0 || hi < lo {
+			continue
+		}
+		for port := lo; port <= hi; port++ {
+			keys = append(keys, strconv.FormatUint(port, 10)+"/"+proto)
+		}
+	}
+	return keys
+}
+
+// vmSetPublish returns the published ports after vm set. -p lists the full
+// set, so dropping a port needs --publish-reset, like service set.
+func vmSetPublish(current []string, flags cli.VMSetFlags) ([]string, error) {
+	desired := normalizePublish(flags.Publish)
+	if flags.PublishReset {
+		return desired, nil
+	}
+	for _, port := range normalizePublish(current) {
+		if !slices.Contains(desired, port) {
+			return nil, fmt.Errorf("vm set -p replaces the published ports and would drop %s; repeat it with -p or pass --publish-reset", port)
+		}
+	}
+	return desired, nil
+}
+
+func hasVMSetPublishChange(flags cli.VMSetFlags) bool {
+	return len(flags.Publish) != 0 || flags.PublishReset
+}
+
+func vmSetChangesOnlyPublish(flags cli.VMSetFlags) bool {
+	if !hasVMSetPublishChange(flags) {
+		return false
+	}
+	flags.Publish, flags.PublishReset = nil, false
+	return reflect.DeepEqual(flags, cli.VMSetFlags{})
+}
+
+// updateVMPublishedPorts changes only the DNAT rules, so unlike other vm set
+// changes it does not need the VM stopped.
+func (s *Server) updateVMPublishedPorts(name string, flags cli.VMSetFlags) error {
+	_, err := s.cfg.DB.MutateData(func(d *db.Data) error {
+		service := d.Services[name]
+		if service == nil || service.ServiceType != db.ServiceTypeVM || service.VM == nil {
+			return fmt.Errorf("service %q is not a VM service", name)
+		}
+		publish, err := vmSetPublish(service.Publish, flags)
+		if err != nil {
+			return err
+		}
+		if err := validateVMPublish(publish, vmRequestedNetworkModes(vmNetworkModesForServiceSet(service.VM.Networks))); err != nil {
+			return err
+		}
+		if err := checkVMPublishConflicts(d, name, publish); err != nil {
+			return err
+		}
+		service.Publish = publish
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return s.syncVMPublishedPorts("")
+}
+
+// printVMPublishedPorts lists the host ports forwarded to the VM, after its
+// IP addresses in `yeet ip`.
+func printVMPublishedPorts(w io.Writer, service *db.Service) error {
+	if service == nil || service.SvcNetwork == nil || !service.SvcNetwork.IPv4.IsValid() {
+		return nil
+	}
+	ports, err := parseVMPublishedPorts(service.Publish)
+	if err != nil {
+		return err
+	}
+	for _, port := range ports {
+		guest := net.JoinHostPort(service.SvcNetwork.IPv4.String(), strconv.Itoa(int(port.GuestPort)))
+		if _, err := fmt.Fprintf(w, "published %s -> %s\n", port.hostKey(), guest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func vmPublishRulesFromData(d *db.Data, exclude string) []vmPublishRule {
+	if d == nil {
+		return nil
+	}
+	var rules []vmPublishRule
+	for name, service := range d.Services {
+		if name == exclude || service == nil || service.ServiceType != db.ServiceTypeVM {
+			continue
+		}
+		if service.SvcNetwork == nil || !service.SvcNetwork.IPv4.IsValid() {
+			continue
+		}
+		ports, err := parseVMPublishedPorts(service.Publish)
+		if err != nil {
+			continue
+		}
+		for _, port := range ports {
+			rules = append(rules, vmPublishRule{vmPublishedPort: port, Service: name, GuestIP: service.SvcNetwork.IPv4})
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Proto != rules[j].Proto {
+			return rules[i].Proto < rules[j].Proto
+		}
+		return rules[i].HostPort < rules[j].HostPort
+	})
+	return rules
+}
+
+// syncVMPublishedPorts rebuilds the VM DNAT chains from the DB, leaving out
+// exclude when that VM is being removed.
+func (s *Server) syncVMPublishedPorts(exclude string) error {
+	dv, err := s.getDB()
+	if err != nil {
+		return err
+	}
+	runner := vmNetworkReconcileRunner
+	if runner == nil {
+		runner = execVMNetworkCommand
+	}
+	if err := syncVMPublishRules(runner, vmPublishRulesFromData(dv.AsStruct(), exclude)); err != nil {
+		return fmt.Errorf("sync VM published ports: %w", err)
+	}
+	return nil
+}
+
+func syncVMPublishRules(run vmNetworkCommandRunner, rules []vmPublishRule) error {
+	if len(rules) == 0 && run(vmPublishIPTables("-L", vmPublishPreroutingChain, "-n")) != nil {
+		// Nothing is published and the chains were never created.
+		return nil
+	}
+	for _, hook := range [][2]string{{"PREROUTING", vmPublishPreroutingChain}, {"OUTPUT", vmPublishOutputChain}} {
+		if err := ensureVMPublishChain(run, hook[0], hook[1]); err != nil {
+			return err
+		}
+		if err := replaceVMPublishChain(run, hook[1], rules); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureVMPublishChain hooks chain into builtin for traffic addressed to the
+// host itself, so forwarded traffic for other addresses is left alone.
+func ensureVMPublishChain(run vmNetworkCommandRunner, builtin, chain string) error {
+	if run(vmPublishIPTables("-L", chain, "-n")) != nil {
+		if err := run(vmPublishIPTables("-N", chain)); err != nil {
+			return err
+		}
+	}
+	jump := []string{builtin, "-m", "addrtype", "--dst-type", "LOCAL", "-j", chain}
+	if run(vmPublishIPTables(append([]string{"-C"}, jump...)...)) == nil {
+		return nil
+	}
+	return run(vmPublishIPTables(append([]string{"-A"}, jump...)...))
+}
+
+func replaceVMPublishChain(run vmNetworkCommandRunner, chain string, rules []vmPublishRule) error {
+	if err := run(vmPublishIPTables("-F", chain)); err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if err := run(vmPublishIPTables(append([]string{"-A", chain}, vmPublishDNATArgs(rule)...)...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func vmPublishDNATArgs(rule vmPublishRule) []string {
+	return []string{
+		"-p", rule.Proto,
+		"-m", rule.Proto,
+		"--dport", strconv.Itoa(int(rule.HostPort)),
+		"-j", "DNAT",
+		"--to-destination", net.JoinHostPort(rule.GuestIP.String(), strconv.Itoa(int(rule.GuestPort))),
+	}
+}
+
+func vmPublishIPTables(args ...string) []string {
+	return append([]string{"iptables", "-w", "-t", "nat"}, args...)
+}
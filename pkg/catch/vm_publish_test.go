@@ -0,0 +1,211 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/yeetrun/yeet/pkg/cli"
+	"github.com/yeetrun/yeet/pkg/db"
+)
+
+func TestParseVMPublishedPorts(t *testing.T) {
+	ports, err := parseVMPublishedPorts([]string{"8080:80", "53:5353/udp"})
+	if err != nil {
+		t.Fatalf("parseVMPublishedPorts: %v", err)
+	}
+	want := []vmPublishedPort{
+		{Proto: "tcp", HostPort: 8080, GuestPort: 80},
+		{Proto: "udp", HostPort: 53, GuestPort: 5353},
+	}
+	if !reflect.DeepEqual(ports, want) {
+		t.Fatalf("ports = %#v, want %#v", ports, want)
+	}
+
+	for _, tc := range []struct {
+		publish []string
+		want    string
+	}{
+		{publish: []string{"80"}, want: "VMs take HOST:GUEST"},
+		{publish: []string{"127.0.0.1:80:80"}, want: "VMs take HOST:GUEST"},
+		{publish: []string{"0:80"}, want: "between 1 and 65535"},
+		{publish: []string{"80:70000"}, want: "between 1 and 65535"},
+		{publish: []string{"80:80", "80:8080"}, want: "80/tcp is published twice"},
+	} {
+		if _, err := parseVMPublishedPorts(tc.publish); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("parseVMPublishedPorts(%q) error = %v, want %q", tc.publish, err, tc.want)
+		}
+	}
+}
+
+func TestValidateVMPublishNeedsSvcOnly(t *testing.T) {
+	if err := validateVMPublish([]string{"80:80"}, vmRequestedNetworkModes("")); err != nil {
+		t.Fatalf("default svc network: %v", err)
+	}
+	if err := validateVMPublish(nil, vmRequestedNetworkModes("lan")); err != nil {
+		t.Fatalf("no ports on lan: %v", err)
+	}
+	for net, want := range map[string]string{
+		"lan":     "use --net=svc",
+		"iso":     "use --net=svc",
+		"svc,lan": "svc as the VM's only network",
+	} {
+		if err := validateVMPublish([]string{"80:80"}, vmRequestedNetworkModes(net)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("net %s error = %v, want %q", net, err, want)
+		}
+	}
+}
+
+func TestCheckVMPublishConflicts(t *testing.T) {
+	compose := filepath.Join(t.TempDir(), "compose.yml")
+	content := "services:\n  app:\n    ports:\n      - \"8080:80\"\n  metrics:\n    ports:\n      - published: 9090\n        target: 9090\n        protocol: udp\n"
+	if err := os.WriteFile(compose, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d := &db.Data{Services: map[string]*db.Service{
+		"web": {Name: "web", ServiceType: db.ServiceTypeVM, Publish: []string{"80:80"}},
+		"app": {Name: "app", ServiceType: db.ServiceTypeDockerCompose, Publish: []string{"443:443"}, Artifacts: db.ArtifactStore{
+			db.ArtifactDockerComposeFile: {Refs: map[db.ArtifactRef]string{"latest": compose}},
+		}},
+		"api": {Name: "api", ServiceType: db.ServiceTypeSystemd, Publish: []string{"127.0.0.1:5000-5002:5000-5002", "3000"}},
+	}}
+	if err := checkVMPublishConflicts(d, "web", []string{"80:8080"}); err != nil {
+		t.Fatalf("own port: %v", err)
+	}
+	if err := checkVMPublishConflicts(d, "devbox", []string{"80:80/udp", "9090:9090", "3000:3000", "5003:5003"}); err != nil {
+		t.Fatalf("free ports: %v", err)
+	}
+	for _, tc := range []struct {
+		publish string
+		want    string
+	}{
+		{"80:80", `host port 80/tcp is already published by VM "web"`},
+		{"443:443", `host port 443/tcp is already published by service "app"`},
+		{"8080:8080", `host port 8080/tcp is already published by service "app"`},
+		{"9090:9090/udp", `host port 9090/udp is already published by service "app"`},
+		{"5001:22", `host port 5001/tcp is already published by service "api"`},
+	} {
+		err := checkVMPublishConflicts(d, "devbox", []string{tc.publish})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: error = %v, want %q", tc.publish, err, tc.want)
+		}
+	}
+}
+
+func TestVMSetPublishRequiresResetToDropPorts(t *testing.T) {
+	current := []string{"80:80", "443:443"}
+	if _, err := vmSetPublish(current, cli.VMSetFlags{Publish: []string{"80:80"}}); err == nil || !strings.Contains(err.Error(), "would drop 443:443") {
+		t.Fatalf("error = %v, want drop guard", err)
+	}
+	got, err := vmSetPublish(current, cli.VMSetFlags{Publish: []string{"80:80", "443:443", "22:22"}})
+	if err != nil || !reflect.DeepEqual(got, []string{"80:80", "443:443", "22:22"}) {
+		t.Fatalf("add = %#v, %v", got, err)
+	}
+	got, err = vmSetPublish(current, cli.VMSetFlags{PublishReset: true})
+	if err != nil || len(got) != 0 {
+		t.Fatalf("reset = %#v, %v", got, err)
+	}
+	if !vmSetChangesOnlyPublish(cli.VMSetFlags{Publish: []string{"80:80"}}) {
+		t.Fatal("publish-only change not detected")
+	}
+	if vmSetChangesOnlyPublish(cli.VMSetFlags{Publish: []string{"80:80"}, CPUs: 2}) {
+		t.Fatal("publish with vcpus reported as publish-only")
+	}
+}
+
+func TestSyncVMPublishRulesRebuildsChains(t *testing.T) {
+	var commands []string
+	run := func(command []string) error {
+		commands = append(commands, strings.Join(command, " "))
+		return nil
+	}
+	rules := []vmPublishRule{{
+		vmPublishedPort: vmPublishedPort{Proto: "tcp", HostPort: 8080, GuestPort: 80},
+		Service:         "web",
+		GuestIP:         netip.MustParseAddr("192.168.100.12"),
+	}}
+	if err := syncVMPublishRules(run, rules); err != nil {
+		t.Fatalf("syncVMPublishRules: %v", err)
+	}
+	want := []string{
+		"iptables -w -t nat -L YEET_VM_PREROUTING -n",
+		"iptables -w -t nat -C PREROUTING -m addrtype --dst-type LOCAL -j YEET_VM_PREROUTING",
+		"iptables -w -t nat -F YEET_VM_PREROUTING",
+		"iptables -w -t nat -A YEET_VM_PREROUTING -p tcp -m tcp --dport 8080 -j DNAT --to-destination 192.168.100.12:80",
+		"iptables -w -t nat -L YEET_VM_OUTPUT -n",
+		"iptables -w -t nat -C OUTPUT -m addrtype --dst-type LOCAL -j YEET_VM_OUTPUT",
+		"iptables -w -t nat -F YEET_VM_OUTPUT",
+		"iptables -w -t nat -A YEET_VM_OUTPUT -p tcp -m tcp --dport 8080 -j DNAT --to-destination 192.168.100.12:80",
+	}
+	if !reflect.DeepEqual(commands, want) {
+		t.Fatalf("commands = %#v, want %#v", commands, want)
+	}
+}
+
+func TestSyncVMPublishRulesSkipsMissingChainsWithoutRules(t *testing.T) {
+	var commands []string
+	run := func(command []string) error {
+		commands = append(commands, strings.Join(command, " "))
+		return errors.New("no chain")
+	}
+	if err := syncVMPublishRules(run, nil); err != nil {
+		t.Fatalf("syncVMPublishRules: %v", err)
+	}
+	if len(commands) != 1 {
+		t.Fatalf("commands = %#v, want only the chain probe", commands)
+	}
+}
+
+func TestUpdateVMPublishedPortsSyncsRunningVM(t *testing.T) {
+	server := newTestServer(t)
+	_, _, err := server.cfg.DB.MutateService("web", func(_ *db.Data, service *db.Service) error {
+		service.ServiceType = db.ServiceTypeVM
+		service.VM = &db.VMConfig{Networks: []db.VMNetworkConfig{{Mode: "svc"}}}
+		service.SvcNetwork = &db.SvcNetwork{IPv4: netip.MustParseAddr("192.168.100.12")}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldRunner := vmNetworkReconcileRunner
+	t.Cleanup(func() { vmNetworkReconcileRunner = oldRunner })
+	var dnat []string
+	vmNetworkReconcileRunner = func(command []string) error {
+		if slices.Contains(command, "DNAT") {
+			dnat = append(dnat, strings.Join(command, " "))
+		}
+		return nil
+	}
+
+	if err := server.updateVMServiceSettings(context.Background(), "web", cli.VMSetFlags{Publish: []string{"8080:80"}}); err != nil {
+		t.Fatalf("updateVMServiceSettings: %v", err)
+	}
+	sv, err := server.serviceView("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sv.AsStruct().Publish; !reflect.DeepEqual(got, []string{"8080:80"}) {
+		t.Fatalf("publish = %#v", got)
+	}
+	if len(dnat) != 2 || !strings.Contains(dnat[0], "--dport 8080 -j DNAT --to-destination 192.168.100.12:80") {
+		t.Fatalf("dnat = %#v, want prerouting and output rules", dnat)
+	}
+	var out bytes.Buffer
+	if err := printVMPublishedPorts(&out, sv.AsStruct()); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "published 8080/tcp -> 192.168.100.12:80\n" {
+		t.Fatalf("printVMPublishedPorts = %q", got)
+	}
+}
@@ -52,6 +52,8 @@ type vmSettingsPlan struct {
 	TransitionFromISO     bool
 	NetworkChanged        bool
 	SvcNetwork            *db.SvcNetwork
+	NewPublish            []string
+	PublishChanged        bool
 	OldMetadata           vmMetadataConfig
 	Metadata              vmMetadataConfig
 	RewriteMetadata       bool
@@ -61,7 +63,40 @@ type vmSettingsPlan struct {
 	FirecrackerConfig     []byte
 }
 
-func (s *Server) updateVMServiceSettings(ctx context.Context, name string, flags cli.VMSetFlags) (retErr error) {
+func (s *Server) updateVMServiceSettings(ctx context.Context, name string, flags cli.VMSetFlags) error {
+	flags, power := splitVMSetPowerFlags(flags)
+	if !cli.HasVMSetPowerChange(power) {
+		return s.updateVMResourceSettings(ctx, name, flags)
+	}
+	if _, err := vmSetPower(nil, power); err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(flags, cli.VMSetFlags{}) {
+		if err := s.updateVMResourceSettings(ctx, name, flags); err != nil {
+			return err
+		}
+	}
+	return s.updateVMPowerSettings(name, power)
+}
+
+// splitVMSetPowerFlags separates the power policy, which applies to a running
+// VM, from the rest of a vm set.
+func splitVMSetPowerFlags(flags cli.VMSetFlags) (cli.VMSetFlags, cli.VMSetFlags) {
+	power := cli.VMSetFlags{
+		Autostart:       flags.Autostart,
+		StartOrder:      flags.StartOrder,
+		StartOrderSet:   flags.StartOrderSet,
+		ShutdownTimeout: flags.ShutdownTimeout,
+		IdleStop:        flags.IdleStop,
+	}
+	flags.Autostart, flags.StartOrder, flags.StartOrderSet, flags.ShutdownTimeout, flags.IdleStop = "", 0, false, "", ""
+	return flags, power
+}
+
+func (s *Server) updateVMResourceSettings(ctx context.Context, name string, flags cli.VMSetFlags) error {
+	if vmSetChangesOnlyPublish(flags) {
+		return s.updateVMPublishedPorts(name, flags)
+	}
 	if hasCatchVMSetNetworkChange(flags) {
 		return s.withISOOperationLock(ctx, func() error {
 			return s.updateVMServiceSettingsLocked(ctx, name, flags)
@@ -95,7 +130,11 @@ func (s *Server) updateVMServiceSettingsLocked(ctx context.Context, name string,
 	if dbMutationCommitted(commitErr) {
 		committed = true
 	}
-	return commitErr
+	if commitErr != nil || !plan.PublishChanged {
+		return commitErr
+	}
+	committed = true
+	return s.syncVMPublishedPorts("")
 }
 
 func (s *Server) planVMServiceSettings(ctx context.Context, name string, flags cli.VMSetFlags) (vmSettingsPlan, error) {
@@ -112,6 +151,9 @@ func (s *Server) planVMServiceSettings(ctx context.Context, name string, flags c
 	if err := s.applyVMNetworkSettings(ctx, dv, name, service, flags, &plan); err != nil {
 		return vmSettingsPlan{}, err
 	}
+	if err := applyVMPublishSettings(service, flags, &plan); err != nil {
+		return vmSettingsPlan{}, err
+	}
 	if err := plan.finalizeFirecrackerSettings(); err != nil {
 		return vmSettingsPlan{}, err
 	}
@@ -272,6 +314,32 @@ func (s *Server) applyVMNetworkSettings(ctx context.Context, dv *db.DataView, na
 	return nil
 }
 
+// applyVMPublishSettings keeps published ports valid for the network the VM
+// ends up on, whether or not this vm set changes them.
+func applyVMPublishSettings(service *db.Service, flags cli.VMSetFlags, plan *vmSettingsPlan) error {
+	plan.NewPublish = normalizePublish(service.Publish)
+	if hasVMSetPublishChange(flags) {
+		publish, err := vmSetPublish(service.Publish, flags)
+		if err != nil {
+			return err
+		}
+		plan.NewPublish = publish
+		plan.PublishChanged = true
+	}
+	return validateVMPublish(plan.NewPublish, vmRequestedNetworkModes(vmNetworkModesForServiceSet(plan.NewNetwork.DBNetworks())))
+}
+
+func applyVMSettingsPublishCommit(d *db.Data, name string, service *db.Service, plan vmSettingsPlan) error {
+	if !plan.PublishChanged {
+		return nil
+	}
+	if err := checkVMPublishConflicts(d, name, plan.NewPublish); err != nil {
+		return err
+	}
+	service.Publish = plan.NewPublish
+	return nil
+}
+
 func (p *vmSettingsPlan) finalizeFirecrackerSettings() error {
 	fc, fastBoot, rawFirecrackerConfig, firecrackerExisted, err := p.readFirecrackerConfig()
 	if err != nil {
@@ -991,6 +1059,9 @@ func (s *Server) commitVMServiceSettingsPlan(name string, plan vmSettingsPlan) e
 		service.VM.MemoryBytes = plan.NewMemoryBytes
 		service.VM.Balloon = plan.NewBalloon
 		service.VM.Disk.Bytes = plan.NewDiskBytes
+		if err := applyVMSettingsPublishCommit(d, name, service, plan); err != nil {
+			return err
+		}
 		if plan.NetworkChanged {
 			if plan.NewISO != nil {
 				if !sameVMISOAllocation(service.ISO, plan.NewISO) || service.ISO.RemoveRequested || service.ISO.State != string(iso.StateReserved) {
@@ -100,6 +100,7 @@ func (r *vmRunner) Remove() error {
 	if removeErr != nil {
 		removeErr = fmt.Errorf("failed to remove VM systemd unit %s: %w", r.unitPath(), removeErr)
 	}
+	removeErr = errors.Join(removeErr, removeVMHibernateDropIn(r.name), removeVMPowerDropIn(r.name))
 	reloadErr := r.systemctl("daemon-reload")
 	resetErr := r.systemctl("reset-failed", r.unit())
 	if vmSystemdUnitMissingError(resetErr, r.unit()) {
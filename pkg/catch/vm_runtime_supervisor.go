@@ -384,6 +384,7 @@ func launchVMRuntimeAttempt(
 	attemptCfg := cfg
 	attemptCfg.Firecracker = artifact.Firecracker
 	attemptCfg.Jailer = artifact.Jailer
+	attemptCfg.RestoreHibernation = vmHibernationPending(cfg.ServiceRoot)
 	cmd, cleanupProcess, err := constructProcess(ctx, attemptCfg)
 	if err != nil {
 		return nil, err
@@ -413,8 +414,14 @@ func launchVMRuntimeAttempt(
 		return nil, err
 	}
 	guestStopped := make(chan vmGuestStopKind, 1)
-	broker := newVMConsoleBroker(console, os.Stdout, guestStopped)
+	broker := newVMConsoleBroker(console, vmConsoleOutput(attemptCfg.ConsoleSocket), guestStopped)
 	go broker.copyOutput()
+	if err := restoreVMHibernation(ctx, attemptCfg); err != nil {
+		_ = cmd.Process.Kill()
+		<-waiter.done
+		_ = console.Close()
+		return nil, err
+	}
 	cleanupOnError = false
 	return &vmRuntimeLaunchAttempt{
 		artifact: artifact, version: version, cmd: cmd, console: console, broker: broker,
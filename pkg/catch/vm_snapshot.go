@@ -8,8 +8,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net"
 	"net/http"
 	"os"
@@ -30,6 +32,8 @@ var (
 	vmSnapshotIsRunning                       = (*Server).IsServiceRunning
 	vmSnapshotFirecracker vmFirecrackerPauser = firecrackerSnapshotAPI{}
 	vmSnapshotDiskFlusher                     = flushVMSnapshotDisk
+	vmSnapshotGuestFreeze                     = freezeVMGuestFilesystems
+	vmSnapshotGuestThaw                       = thawVMGuestFilesystems
 )
 
 const vmSnapshotRecoveryTimeout = 30 * time.Second
@@ -39,14 +43,16 @@ type vmSnapshotResult struct {
 }
 
 type vmSnapshotPlan struct {
-	Service  *db.Service
-	Dataset  string
-	Policy   effectivePolicy
-	Flags    cli.SnapshotsCreateFlags
-	Running  bool
-	Socket   string
-	DiskPath string
-	Snapshot vmFirecrackerPauser
+	Service     *db.Service
+	Dataset     string
+	DataDisks   []db.VMDataDiskConfig
+	Policy      effectivePolicy
+	Flags       cli.SnapshotsCreateFlags
+	Running     bool
+	Socket      string
+	AgentSocket string
+	DiskPath    string
+	Snapshot    vmFirecrackerPauser
 }
 
 func (s *Server) createVMSnapshot(ctx context.Context, name string, flags cli.SnapshotsCreateFlags, w io.Writer) error {
@@ -54,11 +60,14 @@ func (s *Server) createVMSnapshot(ctx context.Context, name string, flags cli.Sn
 	if err != nil {
 		return err
 	}
+	warnVMRawDataDisksNotSnapshotted(w, name, plan.DataDisks)
 	result, err := s.executeVMSnapshotPlan(ctx, name, plan)
 	if err != nil {
 		return err
 	}
-	if _, err := s.pruneServiceSnapshotsForDataset(ctx, plan.Dataset, plan.Service, plan.Policy, time.Now(), result.Name); err != nil {
+	pruned, err := s.pruneServiceSnapshotsForDataset(ctx, plan.Dataset, plan.Service, plan.Policy, time.Now(), result.Name)
+	err = errors.Join(err, s.destroyVMDataDiskSnapshots(ctx, vmDataDiskSnapshotDatasets(plan.DataDisks), pruned))
+	if err != nil {
 		writeSnapshotWarning(w, "warning: failed to prune VM snapshots for %q: %v\n", name, err)
 	}
 	printVMSnapshotResult(w, result)
@@ -89,7 +98,7 @@ func (s *Server) newVMSnapshotPlan(name string, flags cli.SnapshotsCreateFlags)
 	if running && socket == "" {
 		return vmSnapshotPlan{}, fmt.Errorf("service %q has no Firecracker API socket", name)
 	}
-	return vmSnapshotPlan{Service: service, Dataset: dataset, Policy: policy, Flags: flags, Running: running, Socket: socket, DiskPath: vm.Disk.Path, Snapshot: currentVMSnapshotController()}, nil
+	return vmSnapshotPlan{Service: service, Dataset: dataset, DataDisks: vm.DataDisks, Policy: policy, Flags: flags, Running: running, Socket: socket, AgentSocket: vm.Sockets.VsockSocketPath, DiskPath: vm.Disk.Path, Snapshot: currentVMSnapshotController()}, nil
 }
 
 func currentVMSnapshotRunning(s *Server, name string) (bool, error) {
@@ -108,26 +117,51 @@ func currentVMSnapshotController() vmFirecrackerPauser {
 }
 
 func (s *Server) executeVMSnapshotPlan(ctx context.Context, name string, plan vmSnapshotPlan) (vmSnapshotResult, error) {
+	companions := vmDataDiskSnapshotDatasets(plan.DataDisks)
 	if !plan.Running {
-		return s.createPausedVMSnapshot(ctx, plan.Service, plan.Dataset, plan.Flags)
+		return s.createPausedVMSnapshot(ctx, plan.Service, plan.Dataset, companions, plan.Flags)
 	}
+	thaw := freezeVMSnapshotGuest(ctx, name, plan.AgentSocket)
 	if err := plan.Snapshot.Pause(ctx, plan.Socket); err != nil {
+		thaw()
 		return vmSnapshotResult{}, fmt.Errorf("pause VM %q: %w", name, err)
 	}
-	flushErr := currentVMSnapshotDiskFlusher()(plan.DiskPath)
+	flushErr := flushVMSnapshotDisks(plan.DiskPath, plan.DataDisks)
 	var result vmSnapshotResult
 	var snapErr error
 	if flushErr != nil {
 		snapErr = fmt.Errorf("flush VM %q disk before snapshot: %w", name, flushErr)
 	} else {
-		result, snapErr = s.createPausedVMSnapshot(ctx, plan.Service, plan.Dataset, plan.Flags)
+		result, snapErr = s.createPausedVMSnapshot(ctx, plan.Service, plan.Dataset, companions, plan.Flags)
 	}
 	resumeCtx, cancel := vmSnapshotRecoveryContext(ctx)
 	defer cancel()
 	resumeErr := plan.Snapshot.Resume(resumeCtx, plan.Socket)
+	thaw()
 	return finishVMSnapshotResume(name, result, snapErr, resumeErr)
 }
 
+// freezeVMSnapshotGuest freezes the guest filesystems through the VM agent so
+// the snapshot of a running VM is filesystem-consistent. Guests without a
+// protocol v2 agent are snapshotted crash-consistent, as before. The returned
+// func thaws the guest and must run after the VM resumes.
+func freezeVMSnapshotGuest(ctx context.Context, name, agentSocket string) func() {
+	if strings.TrimSpace(agentSocket) == "" || vmSnapshotGuestFreeze == nil {
+		return func() {}
+	}
+	if _, err := vmSnapshotGuestFreeze(ctx, agentSocket); err != nil {
+		log.Printf("VM %q guest filesystems not frozen, snapshot is crash-consistent: %v", name, err)
+		return func() {}
+	}
+	return func() {
+		thawCtx, cancel := vmSnapshotRecoveryContext(ctx)
+		defer cancel()
+		if _, err := vmSnapshotGuestThaw(thawCtx, agentSocket); err != nil {
+			log.Printf("thaw VM %q guest filesystems (the agent thaws on its own after a minute): %v", name, err)
+		}
+	}
+}
+
 func currentVMSnapshotDiskFlusher() func(string) error {
 	if vmSnapshotDiskFlusher != nil {
 		return vmSnapshotDiskFlusher
@@ -135,6 +169,24 @@ func currentVMSnapshotDiskFlusher() func(string) error {
 	return flushVMSnapshotDisk
 }
 
+// flushVMSnapshotDisks flushes the root disk and every zvol data disk that is
+// snapshotted with it.
+func flushVMSnapshotDisks(rootPath string, dataDisks []db.VMDataDiskConfig) error {
+	flush := currentVMSnapshotDiskFlusher()
+	if err := flush(rootPath); err != nil {
+		return err
+	}
+	for _, disk := range dataDisks {
+		if disk.Backend != vmDiskBackendZVOL {
+			continue
+		}
+		if err := flush(disk.Path); err != nil {
+			return fmt.Errorf("data disk %s: %w", disk.Name, err)
+		}
+	}
+	return nil
+}
+
 func flushVMSnapshotDisk(path string) error {
 	path = filepath.Clean(strings.TrimSpace(path))
 	if !strings.HasPrefix(path, "/dev/zvol/") || path == "/dev/zvol" {
@@ -209,9 +261,9 @@ func vmSnapshotDataset(disk db.VMDiskConfig) (string, error) {
 	return dataset, nil
 }
 
-func (s *Server) createPausedVMSnapshot(ctx context.Context, service *db.Service, dataset string, flags cli.SnapshotsCreateFlags) (vmSnapshotResult, error) {
+func (s *Server) createPausedVMSnapshot(ctx context.Context, service *db.Service, dataset string, companions []string, flags cli.SnapshotsCreateFlags) (vmSnapshotResult, error) {
 	name, err := createServiceSnapshot(ctx, s.zfsRunner, snapshotCreateRequest{
-		Service: service.Name, Dataset: dataset, Event: snapshotEventVMManual,
+		Service: service.Name, Dataset: dataset, Companions: companions, Event: snapshotEventVMManual,
 		Now: time.Now(), Comment: flags.Comment, Checkpoint: recoveryModeDisk,
 	})
 	if err != nil {
@@ -475,3 +475,73 @@ func newFirecrackerUnixHTTPTestServer(t *testing.T, status int) (string, <-chan
 	})
 	return socketPath, requests
 }
+
+func TestCreateVMSnapshotFreezesGuestAroundPause(t *testing.T) {
+	stubVMSnapshotDiskFlusher(t)
+	server := newTestServer(t)
+	seedVMForResize(t, server, "devbox", t.TempDir(), vmDiskBackendZVOL)
+	if _, _, err := server.cfg.DB.MutateService("devbox", func(_ *db.Data, s *db.Service) error {
+		s.VM.Sockets.VsockSocketPath = "/run/yeet/vms/devbox/vsock.sock"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	pauser := &recordingVMFirecrackerPauser{}
+	server.zfsRunner = func(context.Context, ...string) (string, string, error) { return "", "", nil }
+	oldRunning, oldController, oldFreeze, oldThaw := vmSnapshotIsRunning, vmSnapshotFirecracker, vmSnapshotGuestFreeze, vmSnapshotGuestThaw
+	vmSnapshotIsRunning = func(*Server, string) (bool, error) { return true, nil }
+	vmSnapshotFirecracker = pauser
+	vmSnapshotGuestFreeze = func(_ context.Context, socket string) (int, error) {
+		if socket != "/run/yeet/vms/devbox/vsock.sock" {
+			t.Fatalf("freeze socket = %q", socket)
+		}
+		pauser.calls = append(pauser.calls, "freeze")
+		return 1, nil
+	}
+	vmSnapshotGuestThaw = func(context.Context, string) (int, error) {
+		pauser.calls = append(pauser.calls, "thaw")
+		return 1, nil
+	}
+	t.Cleanup(func() {
+		vmSnapshotIsRunning, vmSnapshotFirecracker, vmSnapshotGuestFreeze, vmSnapshotGuestThaw = oldRunning, oldController, oldFreeze, oldThaw
+	})
+
+	if err := server.createVMSnapshot(context.Background(), "devbox", cli.SnapshotsCreateFlags{}, io.Discard); err != nil {
+		t.Fatalf("createVMSnapshot: %v", err)
+	}
+	if want := []string{"freeze", "pause", "resume", "thaw"}; !reflect.DeepEqual(pauser.calls, want) {
+		t.Fatalf("calls = %#v, want %#v", pauser.calls, want)
+	}
+}
+
+func TestCreateVMSnapshotContinuesWhenGuestFreezeFails(t *testing.T) {
+	stubVMSnapshotDiskFlusher(t)
+	server := newTestServer(t)
+	seedVMForResize(t, server, "devbox", t.TempDir(), vmDiskBackendZVOL)
+	if _, _, err := server.cfg.DB.MutateService("devbox", func(_ *db.Data, s *db.Service) error {
+		s.VM.Sockets.VsockSocketPath = "/run/yeet/vms/devbox/vsock.sock"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	pauser := &recordingVMFirecrackerPauser{}
+	server.zfsRunner = func(context.Context, ...string) (string, string, error) { return "", "", nil }
+	oldRunning, oldController, oldFreeze, oldThaw := vmSnapshotIsRunning, vmSnapshotFirecracker, vmSnapshotGuestFreeze, vmSnapshotGuestThaw
+	vmSnapshotIsRunning = func(*Server, string) (bool, error) { return true, nil }
+	vmSnapshotFirecracker = pauser
+	vmSnapshotGuestFreeze = func(context.Context, string) (int, error) { return 0, errVMSnapshotTest }
+	vmSnapshotGuestThaw = func(context.Context, string) (int, error) {
+		t.Fatal("thaw called after a failed freeze")
+		return 0, nil
+	}
+	t.Cleanup(func() {
+		vmSnapshotIsRunning, vmSnapshotFirecracker, vmSnapshotGuestFreeze, vmSnapshotGuestThaw = oldRunning, oldController, oldFreeze, oldThaw
+	})
+
+	if err := server.createVMSnapshot(context.Background(), "devbox", cli.SnapshotsCreateFlags{}, io.Discard); err != nil {
+		t.Fatalf("createVMSnapshot: %v", err)
+	}
+	if want := []string{"pause", "resume"}; !reflect.DeepEqual(pauser.calls, want) {
+		t.Fatalf("calls = %#v, want %#v", pauser.calls, want)
+	}
+}
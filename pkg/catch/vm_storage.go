@@ -30,6 +30,7 @@ const (
 	vmDiskPhaseZVOLBaseWrite   = "zvol-base-write"
 	vmDiskPhaseZVOLClone       = "zvol-clone"
 	vmDiskPhaseZVOLResize      = "zvol-resize"
+	vmDiskPhaseDataCreate      = "data-create"
 )
 
 type vmDiskPlan struct {
@@ -91,6 +92,8 @@ func vmDiskProgressLabel(phase string) string {
 		return "Writing image to ZFS base"
 	case vmDiskPhaseZVOLClone:
 		return "Cloning VM disk"
+	case vmDiskPhaseDataCreate:
+		return "Creating data disk"
 	default:
 		return ""
 	}
@@ -120,14 +120,15 @@ RestartForceExitStatus=75
 RestartPreventExitStatus=76
 RestartSec=1
 KillMode=mixed
-TimeoutStopSec=10
+TimeoutStopSec=%d
 
 [Install]
 WantedBy=multi-user.target
 `, cfg.Service, systemdVMWorkingDirectory(cfg.WorkingDirectory),
 		strings.Join(systemdVMExecArguments(cleanupArgs), " "),
 		strings.Join(systemdVMExecArguments(networkArgs), " "),
-		strings.Join(systemdVMExecArguments(startArgs), " "))
+		strings.Join(systemdVMExecArguments(startArgs), " "),
+		int(vmUnitTimeoutStop.Seconds()))
 }
 
 func systemdVMExecArguments(values []string) []string {
@@ -95,7 +95,7 @@ RestartForceExitStatus=75
 RestartPreventExitStatus=76
 RestartSec=1
 KillMode=mixed
-TimeoutStopSec=10
+TimeoutStopSec=60
 
 [Install]
 WantedBy=multi-user.target
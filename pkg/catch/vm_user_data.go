@@ -0,0 +1,455 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// vmUserDataFlagPrefix marks --user-data values the client has already
+	// read and encoded; catch never reads user-data paths from its own disk.
+	vmUserDataFlagPrefix = "base64:"
+	vmUserDataMaxBytes   = 64 << 10
+	vmUserDataStatusPath = "/var/lib/yeet-vm/user-data.status"
+)
+
+var vmUserDataPackagePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9+_.:=~-]*$`)
+
+// vmUserData is the cloud-init style subset yeet applies on a VM's first
+// boot: users, write_files, packages, and runcmd, in that order.
+type vmUserData struct {
+	Users      []vmUserDataUser    `yaml:"-"`
+	WriteFiles []vmUserDataFile    `yaml:"write_files"`
+	Packages   []string            `yaml:"packages"`
+	RunCmd     []vmUserDataCommand `yaml:"runcmd"`
+
+	Raw []byte `yaml:"-"`
+}
+
+type vmUserDataUser struct {
+	Name              string         `yaml:"name"`
+	Groups            vmUserDataList `yaml:"groups"`
+	Sudo              string         `yaml:"sudo"`
+	Shell             string         `yaml:"shell"`
+	SSHAuthorizedKeys []string       `yaml:"ssh_authorized_keys"`
+}
+
+type vmUserDataFile struct {
+	Path        string `yaml:"path"`
+	Content     string `yaml:"content"`
+	Encoding    string `yaml:"encoding"`
+	Permissions string `yaml:"permissions"`
+	Owner       string `yaml:"owner"`
+	Append      bool   `yaml:"append"`
+}
+
+// vmUserDataCommand is one runcmd entry. A string runs through sh -c; a list
+// runs as an argv without a shell, matching cloud-init.
+type vmUserDataCommand struct {
+	Shell string
+	Argv  []string
+}
+
+func (c *vmUserDataCommand) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Decode(&c.Shell)
+	case yaml.SequenceNode:
+		return node.Decode(&c.Argv)
+	default:
+		return fmt.Errorf("line %d: runcmd entries must be a string or a list", node.Line)
+	}
+}
+
+// vmUserDataList accepts either a YAML list or a comma-separated string.
+type vmUserDataList []string
+
+func (l *vmUserDataList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = nil
+		for _, item := range strings.Split(node.Value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*l = append(*l, item)
+			}
+		}
+		return nil
+	}
+	var items []string
+	if err := node.Decode(&items); err != nil {
+		return err
+	}
+	*l = items
+	return nil
+}
+
+// vmUserDataFromFlag decodes the client-encoded --user-data value. An empty
+// value means no user-data.
+func vmUserDataFromFlag(value string) (*vmUserData, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	encoded, ok := strings.CutPrefix(value, vmUserDataFlagPrefix)
+	if !ok {
+		return nil, fmt.Errorf("--user-data must be sent by the yeet client; pass a local file path")
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode --user-data: %w", err)
+	}
+	return parseVMUserData(raw)
+}
+
+func parseVMUserData(raw []byte) (*vmUserData, error) {
+	if len(raw) > vmUserDataMaxBytes {
+		return nil, fmt.Errorf("user-data is %d bytes; the limit is %d", len(raw), vmUserDataMaxBytes)
+	}
+	var doc struct {
+		vmUserData `yaml:",inline"`
+		Users      []yaml.Node `yaml:"users"`
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse user-data: %w", err)
+	}
+	users, err := decodeVMUserDataUsers(doc.Users)
+	if err != nil {
+		return nil, err
+	}
+	data := doc.vmUserData
+	data.Users = users
+	data.Raw = append([]byte(nil), raw...)
+	if err := validateVMUserData(&data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// decodeVMUserDataUsers skips cloud-init's "default" entry: yeet always
+// creates the image's default user.
+func decodeVMUserDataUsers(nodes []yaml.Node) ([]vmUserDataUser, error) {
+	var users []vmUserDataUser
+	for i := range nodes {
+		node := &nodes[i]
+		if node.Kind == yaml.ScalarNode && node.Value == "default" {
+			continue
+		}
+		if err := checkVMUserDataUserKeys(node); err != nil {
+			return nil, err
+		}
+		var user vmUserDataUser
+		if err := node.Decode(&user); err != nil {
+			return nil, fmt.Errorf("parse user-data users: %w", err)
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// checkVMUserDataUserKeys rejects user keys yeet does not apply, so a
+// password or other unsupported setting is never silently dropped.
+func checkVMUserDataUserKeys(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: user-data users entries must be mappings", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch key := node.Content[i].Value; key {
+		case "name", "groups", "sudo", "shell", "ssh_authorized_keys":
+		default:
+			return fmt.Errorf("line %d: user-data users field %q is not supported", node.Content[i].Line, key)
+		}
+	}
+	return nil
+}
+
+func validateVMUserData(data *vmUserData) error {
+	if len(data.Users)+len(data.WriteFiles)+len(data.Packages)+len(data.RunCmd) == 0 {
+		return fmt.Errorf("user-data has no users, write_files, packages, or runcmd")
+	}
+	for _, user := range data.Users {
+		if err := validateVMUserDataUser(user); err != nil {
+			return err
+		}
+	}
+	for i := range data.WriteFiles {
+		if err := normalizeVMUserDataFile(&data.WriteFiles[i]); err != nil {
+			return err
+		}
+	}
+	return validateVMUserDataCommands(data)
+}
+
+func validateVMUserDataCommands(data *vmUserData) error {
+	for _, pkg := range data.Packages {
+		if !vmUserDataPackagePattern.MatchString(pkg) {
+			return fmt.Errorf("user-data package %q is not a valid package name", pkg)
+		}
+	}
+	for i, cmd := range data.RunCmd {
+		if strings.TrimSpace(cmd.Shell) == "" && len(cmd.Argv) == 0 {
+			return fmt.Errorf("user-data runcmd entry %d is empty", i+1)
+		}
+	}
+	return nil
+}
+
+func validateVMUserDataUser(user vmUserDataUser) error {
+	if !vmUserPattern.MatchString(user.Name) {
+		return fmt.Errorf("user-data user name %q is invalid", user.Name)
+	}
+	for _, group := range user.Groups {
+		if !vmUserPattern.MatchString(group) {
+			return fmt.Errorf("user-data group %q for user %s is invalid", group, user.Name)
+		}
+	}
+	if user.Shell != "" && !path.IsAbs(user.Shell) {
+		return fmt.Errorf("user-data shell for user %s must be an absolute path", user.Name)
+	}
+	fields := append([]string{user.Sudo, user.Shell}, user.SSHAuthorizedKeys...)
+	for _, field := range fields {
+		if strings.ContainsFunc(field, isVMMetadataControlChar) {
+			return fmt.Errorf("user-data user %s contains control characters", user.Name)
+		}
+	}
+	return nil
+}
+
+func normalizeVMUserDataFile(file *vmUserDataFile) error {
+	if !path.IsAbs(file.Path) || path.Clean(file.Path) == "/" || strings.ContainsFunc(file.Path, isVMMetadataControlChar) {
+		return fmt.Errorf("user-data write_files path %q must be an absolute file path", file.Path)
+	}
+	file.Path = path.Clean(file.Path)
+	if err := decodeVMUserDataFileContent(file); err != nil {
+		return err
+	}
+	if file.Permissions == "" {
+		file.Permissions = "0644"
+	}
+	if mode, err := strconv.ParseUint(file.Permissions, 8, 32); err != nil || mode > 0o7777 {
+		return fmt.Errorf("user-data write_files %s: permissions %q must be octal", file.Path, file.Permissions)
+	}
+	return validateVMUserDataOwner(file)
+}
+
+func decodeVMUserDataFileContent(file *vmUserDataFile) error {
+	switch file.Encoding {
+	case "", "text/plain":
+	case "b64", "base64":
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(file.Content))
+		if err != nil {
+			return fmt.Errorf("user-data write_files %s: decode base64 content: %w", file.Path, err)
+		}
+		file.Content = string(decoded)
+	default:
+		return fmt.Errorf("user-data write_files %s: unsupported encoding %q", file.Path, file.Encoding)
+	}
+	file.Encoding = ""
+	return nil
+}
+
+func validateVMUserDataOwner(file *vmUserDataFile) error {
+	if file.Owner == "" {
+		file.Owner = "root:root"
+	}
+	for _, part := range strings.Split(file.Owner, ":") {
+		if !vmUserPattern.MatchString(part) {
+			return fmt.Errorf("user-data write_files %s: owner %q must be USER[:GROUP]", file.Path, file.Owner)
+		}
+	}
+	return nil
+}
+
+func (d *vmUserData) SHA256() string {
+	if d == nil {
+		return ""
+	}
+	sum := sha256.Sum256(d.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// validateVMUserDataDriver rejects user-data for guests whose metadata driver
+// has no first-boot runner.
+func validateVMUserDataDriver(data *vmUserData, driver string) error {
+	if data == nil {
+		return nil
+	}
+	switch strings.TrimSpace(driver) {
+	case "", "ubuntu", "debian", "fedora":
+		return nil
+	default:
+		return fmt.Errorf("--user-data is not supported for %s VM images", driver)
+	}
+}
+
+func writeVMGuestUserDataUnit(root string, data *vmUserData) error {
+	if data == nil {
+		return nil
+	}
+	if err := writeVMGuestFile(root, "usr/local/lib/yeet-vm/user-data", []byte(renderVMUserDataScript(data)), 0o700); err != nil {
+		return err
+	}
+	if err := writeVMGuestFile(root, "etc/systemd/system/yeet-user-data.service", []byte(vmGuestUserDataService), 0o644); err != nil {
+		return err
+	}
+	return writeVMGuestSystemdSymlink(root, "multi-user.target.wants/yeet-user-data.service", "../yeet-user-data.service")
+}
+
+func renderVMUserDataScript(data *vmUserData) string {
+	var b strings.Builder
+	b.WriteString(vmGuestUserDataScriptHeader)
+	for _, user := range data.Users {
+		renderVMUserDataUser(&b, user)
+	}
+	for _, file := range data.WriteFiles {
+		renderVMUserDataFile(&b, file)
+	}
+	if len(data.Packages) > 0 {
+		fmt.Fprintf(&b, "install_packages %s || fail 'install packages'\n", vmUserDataShellWords(data.Packages))
+	}
+	for i, cmd := range data.RunCmd {
+		line := "sh -c " + vmUserDataShellQuote(cmd.Shell)
+		if len(cmd.Argv) > 0 {
+			line = vmUserDataShellWords(cmd.Argv)
+		}
+		fmt.Fprintf(&b, "%s || fail 'runcmd %d'\n", line, i+1)
+	}
+	b.WriteString("report done\n")
+	return b.String()
+}
+
+func renderVMUserDataUser(b *strings.Builder, user vmUserDataUser) {
+	name := vmUserDataShellQuote(user.Name)
+	fail := vmUserDataShellQuote("create user " + user.Name)
+	shell := ""
+	if user.Shell != "" {
+		shell = " -s " + vmUserDataShellQuote(user.Shell)
+	}
+	fmt.Fprintf(b, "id -u %s >/dev/null 2>&1 || useradd -m%s %s || fail %s\n", name, shell, name, fail)
+	for _, group := range user.Groups {
+		group := vmUserDataShellQuote(group)
+		fmt.Fprintf(b, "getent group %s >/dev/null || groupadd %s || fail %s\n", group, group, fail)
+		fmt.Fprintf(b, "usermod -aG %s %s || fail %s\n", group, name, fail)
+	}
+	if user.Sudo != "" && user.Sudo != "false" {
+		sudoers := vmUserDataShellQuote(user.Name + " " + user.Sudo)
+		file := vmUserDataShellQuote("/etc/sudoers.d/90-yeet-user-data-" + user.Name)
+		fmt.Fprintf(b, "printf '%%s\\n' %s >%s && chmod 0440 %s || fail %s\n", sudoers, file, file, fail)
+	}
+	if len(user.SSHAuthorizedKeys) > 0 {
+		keys := vmUserDataShellQuote(strings.Join(user.SSHAuthorizedKeys, "\n") + "\n")
+		fmt.Fprintf(b, "add_authorized_keys %s %s || fail %s\n", name, keys, fail)
+	}
+}
+
+func renderVMUserDataFile(b *strings.Builder, file vmUserDataFile) {
+	target := vmUserDataShellQuote(file.Path)
+	redirect := ">"
+	if file.Append {
+		redirect = ">>"
+	}
+	content := base64.StdEncoding.EncodeToString([]byte(file.Content))
+	fmt.Fprintf(b, "mkdir -p \"$(dirname %s)\" && printf '%%s' '%s' | base64 -d %s%s && chmod %s %s && chown %s %s || fail %s\n",
+		target, content, redirect, target, file.Permissions, target, vmUserDataShellQuote(file.Owner), target, vmUserDataShellQuote("write "+file.Path))
+}
+
+func vmUserDataShellWords(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = vmUserDataShellQuote(word)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func vmUserDataShellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+const vmGuestUserDataService = `[Unit]
+Description=yeet first-boot user-data
+After=network-online.target yeet-guest-ready.service
+Wants=network-online.target
+
+[Service]
+Type=oneshot
+ExecStart=/usr/local/lib/yeet-vm/user-data
+RemainAfterExit=yes
+TimeoutStartSec=30min
+StandardOutput=journal+console
+
+[Install]
+WantedBy=multi-user.target
+`
+
+// vmGuestUserDataScriptHeader runs the user-data once. The status file is
+// what the guest agent reports back through guest_ready.
+const vmGuestUserDataScriptHeader = `#!/bin/sh
+set -u
+status=` + vmUserDataStatusPath + `
+
+report() {
+	printf '%s\n' "$1" >"$status.tmp" && mv "$status.tmp" "$status"
+}
+
+fail() {
+	report "failed: $1"
+	command -v logger >/dev/null 2>&1 && logger "yeet-user-data failed: $1" || true
+	exit 1
+}
+
+retry() {
+	n=0
+	until "$@"; do
+		n=$((n + 1))
+		[ "$n" -ge 30 ] && return 1
+		sleep 2
+	done
+}
+
+install_packages() {
+	if command -v apt-get >/dev/null 2>&1; then
+		export DEBIAN_FRONTEND=noninteractive
+		retry apt-get update && retry apt-get install -y "$@"
+	elif command -v dnf >/dev/null 2>&1; then
+		retry dnf install -y "$@"
+	elif command -v apk >/dev/null 2>&1; then
+		retry apk add "$@"
+	else
+		echo "no supported package manager" >&2
+		return 1
+	fi
+}
+
+add_authorized_keys() {
+	home="$(getent passwd "$1" | cut -d: -f6)"
+	[ -n "$home" ] || return 1
+	install -d -m 0700 -o "$1" -g "$(id -gn "$1")" "$home/.ssh" &&
+		printf '%s' "$2" >>"$home/.ssh/authorized_keys" &&
+		chmod 0600 "$home/.ssh/authorized_keys" &&
+		chown "$1:$(id -gn "$1")" "$home/.ssh/authorized_keys"
+}
+
+mkdir -p "$(dirname "$status")"
+if [ -f "$status" ]; then
+	case "$(cat "$status")" in
+	running) report "failed: interrupted by reboot" ;;
+	esac
+	exit 0
+fi
+report running
+`
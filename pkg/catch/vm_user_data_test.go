@@ -0,0 +1,238 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package catch
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yeetrun/yeet/pkg/db"
+)
+
+const testVMUserData = `#cloud-config
+users:
+  - default
+  - name: deploy
+    groups: docker, adm
+    sudo: ALL=(ALL) NOPASSWD:ALL
+    shell: /bin/bash
+    ssh_authorized_keys:
+      - ssh-ed25519 AAAATEST deploy@example
+write_files:
+  - path: /etc/app/config.env
+    content: |
+      MODE=prod
+  - path: /etc/app/blob
+    encoding: b64
+    content: aGVsbG8=
+    permissions: "0600"
+    owner: deploy:deploy
+packages:
+  - nginx
+  - jq
+runcmd:
+  - systemctl enable --now nginx
+  - [touch, "/var/lib/it's done"]
+`
+
+func TestParseVMUserDataSupportedKeys(t *testing.T) {
+	data, err := parseVMUserData([]byte(testVMUserData))
+	if err != nil {
+		t.Fatalf("parseVMUserData: %v", err)
+	}
+	if len(data.Users) != 1 || data.Users[0].Name != "deploy" {
+		t.Fatalf("users = %#v, want only deploy", data.Users)
+	}
+	if got := []string(data.Users[0].Groups); !reflect.DeepEqual(got, []string{"docker", "adm"}) {
+		t.Fatalf("groups = %#v, want docker, adm", got)
+	}
+	blob := data.WriteFiles[1]
+	if blob.Content != "hello" || blob.Encoding != "" || blob.Permissions != "0600" || blob.Owner != "deploy:deploy" {
+		t.Fatalf("b64 file = %#v, want decoded hello 0600 deploy:deploy", blob)
+	}
+	if plain := data.WriteFiles[0]; plain.Permissions != "0644" || plain.Owner != "root:root" {
+		t.Fatalf("plain file = %#v, want 0644 root:root defaults", plain)
+	}
+	if data.RunCmd[0].Shell != "systemctl enable --now nginx" || !reflect.DeepEqual(data.RunCmd[1].Argv, []string{"touch", "/var/lib/it's done"}) {
+		t.Fatalf("runcmd = %#v", data.RunCmd)
+	}
+	if data.SHA256() == "" {
+		t.Fatal("SHA256 is empty")
+	}
+}
+
+func TestParseVMUserDataRejectsUnsupportedInput(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{name: "unknown top-level key", raw: "bootcmd: [echo hi]\n", want: "bootcmd"},
+		{name: "unknown user key", raw: "users:\n  - name: a\n    passwd: x\n", want: `"passwd" is not supported`},
+		{name: "relative path", raw: "write_files:\n  - path: etc/motd\n    content: hi\n", want: "absolute file path"},
+		{name: "bad permissions", raw: "write_files:\n  - path: /etc/motd\n    permissions: rw\n", want: "must be octal"},
+		{name: "bad package", raw: "packages: [\"nginx; rm -rf /\"]\n", want: "not a valid package name"},
+		{name: "bad user", raw: "users:\n  - name: \"Bad User\"\n", want: "user name"},
+		{name: "empty", raw: "#cloud-config\n", want: "has no users"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseVMUserData([]byte(tt.raw))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("parseVMUserData error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVMUserDataFromFlagRequiresClientEncoding(t *testing.T) {
+	if data, err := vmUserDataFromFlag(""); err != nil || data != nil {
+		t.Fatalf("empty flag = %#v, %v; want nil, nil", data, err)
+	}
+	if _, err := vmUserDataFromFlag("./init.yaml"); err == nil || !strings.Contains(err.Error(), "sent by the yeet client") {
+		t.Fatalf("path flag error = %v, want client encoding error", err)
+	}
+	encoded := vmUserDataFlagPrefix + base64.StdEncoding.EncodeToString([]byte("packages: [jq]\n"))
+	data, err := vmUserDataFromFlag(encoded)
+	if err != nil {
+		t.Fatalf("vmUserDataFromFlag: %v", err)
+	}
+	if !reflect.DeepEqual(data.Packages, []string{"jq"}) {
+		t.Fatalf("packages = %#v, want jq", data.Packages)
+	}
+}
+
+func TestValidateVMUserDataDriverRejectsNixOS(t *testing.T) {
+	data := &vmUserData{Packages: []string{"jq"}}
+	if err := validateVMUserDataDriver(data, "ubuntu"); err != nil {
+		t.Fatalf("ubuntu driver: %v", err)
+	}
+	if err := validateVMUserDataDriver(data, "nixos"); err == nil || !strings.Contains(err.Error(), "nixos") {
+		t.Fatalf("nixos driver error = %v, want unsupported", err)
+	}
+	if err := validateVMUserDataDriver(nil, "nixos"); err != nil {
+		t.Fatalf("nil user-data on nixos: %v", err)
+	}
+}
+
+func TestRenderVMUserDataScriptOrdersAndQuotesSteps(t *testing.T) {
+	data, err := parseVMUserData([]byte(testVMUserData))
+	if err != nil {
+		t.Fatalf("parseVMUserData: %v", err)
+	}
+	script := renderVMUserDataScript(data)
+	steps := []string{
+		"useradd -m -s '/bin/bash' 'deploy'",
+		"usermod -aG 'docker' 'deploy'",
+		"printf '%s\\n' 'deploy ALL=(ALL) NOPASSWD:ALL' >'/etc/sudoers.d/90-yeet-user-data-deploy'",
+		"add_authorized_keys 'deploy' 'ssh-ed25519 AAAATEST deploy@example\n'",
+		"printf '%s' '" + base64.StdEncoding.EncodeToString([]byte("MODE=prod\n")) + "' | base64 -d >'/etc/app/config.env' && chmod 0644",
+		"chmod 0600 '/etc/app/blob' && chown 'deploy:deploy' '/etc/app/blob'",
+		"install_packages 'nginx' 'jq' || fail 'install packages'",
+		"sh -c 'systemctl enable --now nginx' || fail 'runcmd 1'",
+		`'touch' '/var/lib/it'\''s done' || fail 'runcmd 2'`,
+		"report done",
+	}
+	last := -1
+	for _, step := range steps {
+		idx := strings.Index(script, step)
+		if idx < 0 {
+			t.Fatalf("script missing %q:\n%s", step, script)
+		}
+		if idx < last {
+			t.Fatalf("script step %q is out of order:\n%s", step, script)
+		}
+		last = idx
+	}
+	if !strings.Contains(script, "status="+vmUserDataStatusPath) {
+		t.Fatalf("script does not write %s", vmUserDataStatusPath)
+	}
+}
+
+func TestWriteVMGuestMetadataFilesInstallsUserDataUnit(t *testing.T) {
+	root := t.TempDir()
+	data, err := parseVMUserData([]byte("runcmd:\n  - echo hi\n"))
+	if err != nil {
+		t.Fatalf("parseVMUserData: %v", err)
+	}
+	oldChown := vmGuestChown
+	vmGuestChown = func(string, int, int) error { return nil }
+	t.Cleanup(func() { vmGuestChown = oldChown })
+	cfg := vmMetadataConfig{Hostname: "devbox", User: "ubuntu", SSHKey: "ssh-ed25519 AAAATEST test", FastBoot: true, UserData: data}
+	if err := writeVMGuestMetadataFiles(root, cfg); err != nil {
+		t.Fatalf("writeVMGuestMetadataFiles: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(root, "usr/local/lib/yeet-vm/user-data"))
+	if err != nil {
+		t.Fatalf("stat user-data script: %v", err)
+	}
+	if info.Mode().Perm() != 0o700 {
+		t.Fatalf("user-data script mode = %v, want 0700", info.Mode().Perm())
+	}
+	target, err := os.Readlink(filepath.Join(root, "etc/systemd/system/multi-user.target.wants/yeet-user-data.service"))
+	if err != nil || target != "../yeet-user-data.service" {
+		t.Fatalf("user-data unit link = %q, %v", target, err)
+	}
+	if err := writeVMMetadata(root, cfg); err != nil {
+		t.Fatalf("writeVMMetadata: %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(root, "metadata", "user-data"))
+	if err != nil || string(raw) != "runcmd:\n  - echo hi\n" {
+		t.Fatalf("metadata user-data = %q, %v", raw, err)
+	}
+}
+
+func TestWriteVMGuestMetadataFilesSkipsUserDataUnitWithoutUserData(t *testing.T) {
+	root := t.TempDir()
+	oldChown := vmGuestChown
+	vmGuestChown = func(string, int, int) error { return nil }
+	t.Cleanup(func() { vmGuestChown = oldChown })
+	cfg := vmMetadataConfig{Hostname: "devbox", User: "ubuntu", SSHKey: "ssh-ed25519 AAAATEST test", FastBoot: true}
+	if err := writeVMGuestMetadataFiles(root, cfg); err != nil {
+		t.Fatalf("writeVMGuestMetadataFiles: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "etc/systemd/system/yeet-user-data.service")); !os.IsNotExist(err) {
+		t.Fatalf("user-data unit stat error = %v, want not exist", err)
+	}
+}
+
+func TestVMUserDataStatusReportsAgentState(t *testing.T) {
+	oldQuery := queryVMGuestReadyFn
+	t.Cleanup(func() { queryVMGuestReadyFn = oldQuery })
+	vm := func(sha string) db.VMConfigView {
+		return (&db.VMConfig{UserDataSHA256: sha, Sockets: db.VMSocketConfig{VsockSocketPath: "/run/vsock.sock"}}).View()
+	}
+
+	queryVMGuestReadyFn = func(context.Context, string) (vmAgentGuestReadyState, error) {
+		t.Fatal("agent queried for a VM without user-data")
+		return vmAgentGuestReadyState{}, nil
+	}
+	if got := vmUserDataStatus(context.Background(), vm("")); got != "" {
+		t.Fatalf("status without user-data = %q, want empty", got)
+	}
+
+	for _, tt := range []struct {
+		state vmAgentGuestReadyState
+		err   error
+		want  string
+	}{
+		{state: vmAgentGuestReadyState{UserData: "done"}, want: "done"},
+		{state: vmAgentGuestReadyState{}, want: "pending"},
+		{err: errors.New("dial"), want: "unknown (VM agent unavailable)"},
+	} {
+		queryVMGuestReadyFn = func(context.Context, string) (vmAgentGuestReadyState, error) {
+			return tt.state, tt.err
+		}
+		if got := vmUserDataStatus(context.Background(), vm("abc")); got != tt.want {
+			t.Fatalf("status = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -535,3 +535,27 @@ func (c *Client) ISOPoolApply(ctx context.Context, req ISOPoolApplyRequest) (ISO
 	err := c.Call(ctx, RPCMethodISOPoolApply, req, &resp)
 	return resp, err
 }
+
+func (c *Client) RegistryMirrorSet(ctx context.Context, req RegistryMirrorSetRequest) (RegistryMirrorSetResult, error) {
+	var resp RegistryMirrorSetResult
+	err := c.Call(ctx, RPCMethodRegistryMirrorSet, req, &resp)
+	return resp, err
+}
+
+func (c *Client) ContainerRuntimeSet(ctx context.Context, req ContainerRuntimeSetRequest) (ContainerRuntimeSetResult, error) {
+	var resp ContainerRuntimeSetResult
+	err := c.Call(ctx, RPCMethodContainerRuntimeSet, req, &resp)
+	return resp, err
+}
+
+func (c *Client) ImagePolicySet(ctx context.Context, req ImagePolicySetRequest) (ImagePolicySetResult, error) {
+	var resp ImagePolicySetResult
+	err := c.Call(ctx, RPCMethodImagePolicySet, req, &resp)
+	return resp, err
+}
+
+func (c *Client) BuildContextMissing(ctx context.Context, req BuildContextMissingRequest) (BuildContextMissingResponse, error) {
+	var resp BuildContextMissingResponse
+	err := c.Call(ctx, RPCMethodBuildContextMissing, req, &resp)
+	return resp, err
+}
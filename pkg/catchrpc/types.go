@@ -4,7 +4,12 @@
 
 package catchrpc
 
-import "encoding/json"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
 
 type Request struct {
 	JSONRPC string          `json:"jsonrpc"`
@@ -50,6 +55,9 @@ const (
 	ExecTargetHostShell      ExecTarget = "host-shell"
 	ExecTargetServiceShell   ExecTarget = "service-shell"
 	ExecTargetVMSSHProxy     ExecTarget = "vm-ssh-proxy"
+	ExecTargetVMAgentExec    ExecTarget = "vm-agent-exec"
+	ExecTargetVMAgentPush    ExecTarget = "vm-agent-push"
+	ExecTargetVMAgentPull    ExecTarget = "vm-agent-pull"
 )
 
 type ExecRequest struct {
@@ -115,6 +123,9 @@ type TailscaleSetupResponse struct {
 
 type ArtifactHashesRequest struct {
 	Service string `json:"service"`
+	// Images asks catch to also report the images of the service's running
+	// containers, resolving upstream digests for external images.
+	Images bool `json:"images,omitempty"`
 }
 
 type ArtifactHash struct {
@@ -122,11 +133,28 @@ type ArtifactHash struct {
 	SHA256 string `json:"sha256,omitempty"`
 }
 
+// ImageLabelBuildHash labels images built from a Dockerfile payload with the
+// hash of their build inputs.
+const ImageLabelBuildHash = "com.yeetrun.build-hash"
+
+// ArtifactImage is the image behind one running container of a service.
+type ArtifactImage struct {
+	Container     string `json:"container,omitempty"`
+	Image         string `json:"image,omitempty"`
+	RunningDigest string `json:"runningDigest,omitempty"`
+	LatestDigest  string `json:"latestDigest,omitempty"`
+	BuildHash     string `json:"buildHash,omitempty"`
+}
+
 type ArtifactHashesResponse struct {
-	Found   bool          `json:"found"`
-	Message string        `json:"message,omitempty"`
-	Payload *ArtifactHash `json:"payload,omitempty"`
-	Env     *ArtifactHash `json:"env,omitempty"`
+	Found   bool            `json:"found"`
+	Message string          `json:"message,omitempty"`
+	Payload *ArtifactHash   `json:"payload,omitempty"`
+	Env     *ArtifactHash   `json:"env,omitempty"`
+	Images  []ArtifactImage `json:"images,omitempty"`
+	// ComposeProject hashes the compose override files, interpolation env
+	// files, and profiles layered on a compose payload.
+	ComposeProject *ArtifactHash `json:"composeProject,omitempty"`
 }
 
 type ZFSRootDiscoveryState string
@@ -200,8 +228,103 @@ const (
 	RPCMethodHostStorageCleanup  = "catch.HostStorageCleanup"
 	RPCMethodISOPoolPlan         = "catch.ISOPoolPlan"
 	RPCMethodISOPoolApply        = "catch.ISOPoolApply"
+	RPCMethodRegistryMirrorSet   = "catch.RegistryMirrorSet"
+	RPCMethodContainerRuntimeSet = "catch.ContainerRuntimeSet"
+	RPCMethodImagePolicySet      = "catch.ImagePolicySet"
+)
+
+// RegistryMirrorSetRequest enables the pull-through mirror for Upstream, or
+// disables it when Upstream is empty.
+type RegistryMirrorSetRequest struct {
+	Upstream string `json:"upstream,omitempty"`
+}
+
+type RegistryMirrorSetResult struct {
+	Upstream       string `json:"upstream,omitempty"`
+	URL            string `json:"url,omitempty"`
+	Changed        bool   `json:"changed"`
+	DockerReloaded bool   `json:"dockerReloaded,omitempty"`
+}
+
+const RPCMethodBuildContextMissing = "catch.BuildContextMissing"
+
+// BuildContextMissingRequest asks which build context blobs catch has not
+// cached for Service, so a remote build uploads only those.
+type BuildContextMissingRequest struct {
+	Service string   `json:"service"`
+	Digests []string `json:"digests"`
+}
+
+type BuildContextMissingResponse struct {
+	Missing []string `json:"missing,omitempty"`
+}
+
+// A remote build upload is a tar whose first entry is the manifest. Each
+// missing blob follows as BuildContextBlobDir/<sha256>.
+const (
+	BuildContextManifestName = "manifest.json"
+	BuildContextBlobDir      = "blobs"
 )
 
+// BuildContextManifest lists a Dockerfile build context by content. Entries
+// are in the order the client walked the context directory.
+type BuildContextManifest struct {
+	DockerfileSHA256 string              `json:"dockerfileSha256"`
+	Entries          []BuildContextEntry `json:"entries"`
+}
+
+// BuildContextEntry is a regular file, with SHA256 and Mode, or a symlink,
+// with Link. Path is slash separated and relative to the context.
+type BuildContextEntry struct {
+	Path   string `json:"path"`
+	Mode   uint32 `json:"mode,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+	Link   string `json:"link,omitempty"`
+}
+
+// BuildHash returns the ImageLabelBuildHash of building the manifest for
+// platform, so local and remote builds of the same inputs match.
+func (m BuildContextManifest) BuildHash(platform string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "platform\x00%s\x00", platform)
+	fmt.Fprintf(h, "dockerfile\x00%s\x00", m.DockerfileSHA256)
+	for _, entry := range m.Entries {
+		if entry.Link != "" {
+			fmt.Fprintf(h, "link\x00%s\x00%s\x00", entry.Path, entry.Link)
+			continue
+		}
+		fmt.Fprintf(h, "file\x00%s\x00%o\x00%s\x00", entry.Path, entry.Mode, entry.SHA256)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ContainerRuntimeSetRequest selects the engine compose services run on:
+// docker or podman.
+type ContainerRuntimeSetRequest struct {
+	Runtime string `json:"runtime"`
+}
+
+type ContainerRuntimeSetResult struct {
+	Runtime string `json:"runtime"`
+	Changed bool   `json:"changed"`
+}
+
+// ImagePolicySetRequest changes the host image policy. An empty Mode keeps
+// the current one; TrustKeys, PEM encoded public keys, replace the trusted
+// keys when set.
+type ImagePolicySetRequest struct {
+	Mode      string   `json:"mode,omitempty"`
+	TrustKeys []string `json:"trustKeys,omitempty"`
+}
+
+// ImagePolicySetResult is the resulting policy; keys are listed by
+// fingerprint.
+type ImagePolicySetResult struct {
+	Mode      string   `json:"mode"`
+	TrustKeys []string `json:"trustKeys,omitempty"`
+	Changed   bool     `json:"changed"`
+}
+
 type ISOPoolPlanRequest struct {
 	Prefix string `json:"prefix"`
 }
@@ -395,6 +518,17 @@ type ServiceSnapshots struct {
 	Effective EffectiveSnapshotPolicy `json:"effective,omitempty"`
 }
 
+// ServiceStorage reports disk usage for a service root. Backend is zfs, xfs,
+// ext4, or filesystem. Quota is zero when no limit is set.
+type ServiceStorage struct {
+	Backend   string `json:"backend"`
+	Used      int64  `json:"used"`
+	Available int64  `json:"available"`
+	Quota     int64  `json:"quota,omitempty"`
+	Snapshots int64  `json:"snapshots,omitempty"`
+	Volumes   int64  `json:"volumes,omitempty"`
+}
+
 type SnapshotDefaultsResponse struct {
 	Defaults  SnapshotPolicy          `json:"defaults,omitempty"`
 	Effective EffectiveSnapshotPolicy `json:"effective,omitempty"`
@@ -412,20 +546,40 @@ type ServiceSandbox struct {
 }
 
 type ServiceInfo struct {
-	Name             string            `json:"name"`
-	ServiceType      string            `json:"serviceType,omitempty"`
-	DataType         string            `json:"dataType,omitempty"`
-	Generation       int               `json:"generation,omitempty"`
-	LatestGeneration int               `json:"latestGeneration,omitempty"`
-	Staged           bool              `json:"staged,omitempty"`
-	Paths            ServicePaths      `json:"paths,omitempty"`
-	Network          ServiceNetwork    `json:"network,omitempty"`
-	Status           ServiceStatus     `json:"status,omitempty"`
-	Images           []ServiceImage    `json:"images,omitempty"`
-	VM               *ServiceVM        `json:"vm,omitempty"`
-	Snapshots        *ServiceSnapshots `json:"snapshots,omitempty"`
-	Identity         *ServiceIdentity  `json:"identity,omitempty"`
-	Sandbox          *ServiceSandbox   `json:"sandbox,omitempty"`
+	Name             string             `json:"name"`
+	ServiceType      string             `json:"serviceType,omitempty"`
+	DataType         string             `json:"dataType,omitempty"`
+	Generation       int                `json:"generation,omitempty"`
+	LatestGeneration int                `json:"latestGeneration,omitempty"`
+	Staged           bool               `json:"staged,omitempty"`
+	Paths            ServicePaths       `json:"paths,omitempty"`
+	Network          ServiceNetwork     `json:"network,omitempty"`
+	Status           ServiceStatus      `json:"status,omitempty"`
+	Images           []ServiceImage     `json:"images,omitempty"`
+	VM               *ServiceVM         `json:"vm,omitempty"`
+	Snapshots        *ServiceSnapshots  `json:"snapshots,omitempty"`
+	Identity         *ServiceIdentity   `json:"identity,omitempty"`
+	Sandbox          *ServiceSandbox    `json:"sandbox,omitempty"`
+	Storage          *ServiceStorage    `json:"storage,omitempty"`
+	AutoUpdate       *ServiceAutoUpdate `json:"autoUpdate,omitempty"`
+}
+
+// ServiceAutoUpdate is the scheduled image update policy of a compose service
+// and its recent results, oldest first.
+type ServiceAutoUpdate struct {
+	Mode      string             `json:"mode"`
+	Window    string             `json:"window"`
+	LastCheck string             `json:"lastCheck,omitempty"`
+	History   []AutoUpdateResult `json:"history,omitempty"`
+}
+
+// AutoUpdateResult is the outcome of one scheduled image check. Result is
+// available, updated, rolled-back, or failed.
+type AutoUpdateResult struct {
+	Time   string `json:"time"`
+	Result string `json:"result"`
+	Images string `json:"images,omitempty"`
+	Error  string `json:"error,omitempty"`
 }
 
 type ServiceIdentity struct {
@@ -452,6 +606,50 @@ type ServiceVM struct {
 	Console      *ServiceVMConsole  `json:"console,omitempty"`
 	Networks     []ServiceVMNetwork `json:"networks,omitempty"`
 	SetupState   string             `json:"setupState,omitempty"`
+	UserData     string             `json:"userData,omitempty"`
+	Metrics      *ServiceVMMetrics  `json:"metrics,omitempty"`
+	Power        *ServiceVMPower    `json:"power,omitempty"`
+}
+
+// ServiceVMPower is the effective `vm set` power policy. A zero
+// ShutdownTimeout means the VM is stopped without asking the guest.
+type ServiceVMPower struct {
+	Autostart       bool   `json:"autostart"`
+	StartOrder      int    `json:"startOrder,omitempty"`
+	ShutdownTimeout string `json:"shutdownTimeout"`
+	IdleStop        string `json:"idleStop,omitempty"`
+}
+
+// ServiceVMMetrics is a resource sample of a running VM. A part is nil when
+// catch could not read it, for example when the guest agent is not up yet.
+type ServiceVMMetrics struct {
+	CPU     *ServiceVMCPU        `json:"cpu,omitempty"`
+	Memory  *ServiceVMUsage      `json:"memory,omitempty"`
+	Disk    *ServiceVMUsage      `json:"disk,omitempty"`
+	Network *ServiceVMNetTraffic `json:"network,omitempty"`
+}
+
+// ServiceVMCPU is measured on the host, so it includes Firecracker's own
+// overhead. Percent is relative to one CPU; a busy 2-vCPU VM reads 200.
+type ServiceVMCPU struct {
+	Percent      float64 `json:"percent"`
+	UsageSeconds float64 `json:"usageSeconds"`
+}
+
+// ServiceVMUsage is memory or root filesystem usage as the guest sees it.
+// Source is agent or balloon.
+type ServiceVMUsage struct {
+	Source    string `json:"source"`
+	Total     int64  `json:"total"`
+	Used      int64  `json:"used"`
+	Available int64  `json:"available"`
+}
+
+// ServiceVMNetTraffic counts bytes on the VM's TAP devices since they were
+// created, from the guest's point of view.
+type ServiceVMNetTraffic struct {
+	RxBytes int64 `json:"rxBytes"`
+	TxBytes int64 `json:"txBytes"`
 }
 
 type ServiceVMBalloon struct {
@@ -676,3 +874,54 @@ type ServiceImageRef struct {
 	Digest    string `json:"digest,omitempty"`
 	MediaType string `json:"mediaType,omitempty"`
 }
+
+const (
+	// ServiceArchiveFormat is the current service export archive format.
+	ServiceArchiveFormat = 1
+	// ServiceArchiveManifestName is always the first entry of a service
+	// export archive so clients can inspect it without reading the payload.
+	ServiceArchiveManifestName = "manifest.json"
+)
+
+// ServiceArchiveManifest describes a portable service export. Archives are
+// zstd-compressed tar streams holding the manifest, the current generation
+// payload under payload/, the env file under env, the compose project files
+// under compose/, the internal registry images the project names under
+// images/, and optionally the service root contents under root/.
+type ServiceArchiveManifest struct {
+	Format      int                     `json:"format"`
+	Service     string                  `json:"service"`
+	ServiceType string                  `json:"serviceType"`
+	Generation  int                     `json:"generation,omitempty"`
+	ServiceRoot string                  `json:"serviceRoot,omitempty"`
+	Payload     string                  `json:"payload"`
+	PayloadArgs []string                `json:"payloadArgs,omitempty"`
+	Env         bool                    `json:"env,omitempty"`
+	Data        bool                    `json:"data,omitempty"`
+	Schedule    string                  `json:"schedule,omitempty"`
+	Publish     []string                `json:"publish,omitempty"`
+	Network     *ServiceNetworkSettings `json:"network,omitempty"`
+	Identity    *ServiceIdentity        `json:"identity,omitempty"`
+	Sandbox     *ServiceSandbox         `json:"sandbox,omitempty"`
+	Snapshots   *SnapshotPolicy         `json:"snapshots,omitempty"`
+	// ComposeOverrides and ComposeEnvFiles count the compose override and
+	// interpolation env files stored as compose/override.N.yml and
+	// compose/env.N; Profiles are the enabled compose profiles.
+	ComposeOverrides int      `json:"composeOverrides,omitempty"`
+	ComposeEnvFiles  int      `json:"composeEnvFiles,omitempty"`
+	Profiles         []string `json:"profiles,omitempty"`
+	// Images are the internal registry images the compose project names;
+	// their blobs are stored as images/blobs/sha256/<hex>.
+	Images []ServiceArchiveImage `json:"images,omitempty"`
+	// QuotaBytes is the service root quota; zero means no limit.
+	QuotaBytes int64 `json:"quotaBytes,omitempty"`
+}
+
+// ServiceArchiveImage is an internal registry image carried by a service
+// export. Tag is empty when the compose project pins the image by digest.
+type ServiceArchiveImage struct {
+	Repo      string `json:"repo"`
+	Tag       string `json:"tag,omitempty"`
+	Digest    string `json:"digest"`
+	MediaType string `json:"mediaType"`
+}
@@ -7,10 +7,13 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"math"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/shayne/yargs"
 	"github.com/yeetrun/yeet/pkg/cronutil"
@@ -71,6 +74,7 @@ type RunFlags struct {
 	MemoryMin        string
 	Balloon          string
 	Disk             string
+	UserData         string
 	Net              string
 	TsVer            string
 	TsExit           string
@@ -87,6 +91,10 @@ type RunFlags struct {
 	Publish          []string
 	PublishReset     bool
 	EnvFile          string
+	ComposeFiles     []string
+	ComposeEnvFiles  []string
+	Profiles         []string
+	Build            string
 	ServiceRoot      string
 	ZFS              bool
 	Snapshots        string
@@ -98,6 +106,18 @@ type RunFlags struct {
 	Sandbox          SandboxOptions
 }
 
+// Dockerfile build locations accepted by run --build.
+const (
+	RunBuildLocal  = "local"
+	RunBuildRemote = "remote"
+)
+
+// HasComposeProject reports whether any compose override file, profile, or
+// interpolation env file was supplied.
+func (f RunFlags) HasComposeProject() bool {
+	return len(f.ComposeFiles) != 0 || len(f.ComposeEnvFiles) != 0 || len(f.Profiles) != 0
+}
+
 type ServiceSetFlags struct {
 	Cron             string
 	CronSet          bool
@@ -131,9 +151,20 @@ type ServiceSetFlags struct {
 	SnapshotRequired string
 	SnapshotEvents   string
 	SnapshotChange   bool
+	Quota            int64
+	QuotaSet         bool
+	AutoUpdate       string
+	AutoUpdateWindow string
+	ImagePolicy      string
 	Sandbox          SandboxOptions
 }
 
+// HasAutoUpdateChange reports whether the image auto-update policy was
+// supplied.
+func (f ServiceSetFlags) HasAutoUpdateChange() bool {
+	return f.AutoUpdate != "" || f.AutoUpdateWindow != ""
+}
+
 // HasNetworkChange reports whether any network setting was explicitly supplied.
 func (f ServiceSetFlags) HasNetworkChange() bool {
 	return f.NetSet || f.TsVerSet || f.TsExitSet || f.TsTagsSet ||
@@ -142,13 +173,17 @@ func (f ServiceSetFlags) HasNetworkChange() bool {
 }
 
 type HostSetFlags struct {
-	DataDir         string
-	ServicesRoot    string
-	ZFS             bool
-	MigrateServices string
-	ISOPool         string
-	Config          string
-	Yes             bool
+	DataDir          string
+	ServicesRoot     string
+	ZFS              bool
+	MigrateServices  string
+	ISOPool          string
+	RegistryMirror   string
+	ContainerRuntime string
+	ImagePolicy      string
+	TrustKeys        []string
+	Config           string
+	Yes              bool
 }
 
 type HostCleanupFlags struct {
@@ -167,12 +202,48 @@ type VMSetFlags struct {
 	MacvlanMac    string
 	MacvlanVlan   int
 	MacvlanParent string
+	Publish       []string
+	PublishReset  bool
+	// Power policy. These apply while the VM runs.
+	Autostart       string
+	StartOrder      int
+	StartOrderSet   bool
+	ShutdownTimeout string
+	IdleStop        string
 }
 
 type VMKernelFlags struct {
 	Restart bool
 }
 
+type VMDiskFlags struct {
+	Name   string
+	Size   string
+	ZFS    bool
+	From   string
+	Keep   bool
+	Format string
+}
+
+type VMMigrateFlags struct {
+	To string
+}
+
+// VMMigrateStepFlags are the catch-side steps the yeet CLI drives during
+// `vm migrate`; they are not part of the user-facing command.
+type VMMigrateStepFlags struct {
+	ZFS         bool
+	Precopy     bool
+	Incremental bool
+}
+
+const (
+	VMMigrateStepSend    = "send"
+	VMMigrateStepReceive = "receive"
+	VMMigrateStepFinish  = "finish"
+	VMMigrateStepAbort   = "abort"
+)
+
 type VMRuntimeFlags struct {
 	Format  string
 	To      string
@@ -182,6 +253,16 @@ type VMRuntimeFlags struct {
 	DryRun  bool
 }
 
+type VMHibernateFlags struct {
+	OnHostShutdown string
+}
+
+type VMConsoleFlags struct {
+	Attach bool
+	Since  string
+	Lines  int
+}
+
 type VMMemoryFlags struct {
 	Policy string
 	Format string
@@ -223,6 +304,18 @@ type ServiceSyncFlags struct {
 	Config string
 }
 
+type ServiceExportFlags struct {
+	Data bool
+}
+
+type ServiceImportFlags struct {
+	As string
+}
+
+type ServiceMoveFlags struct {
+	To string
+}
+
 type StageFlags struct {
 	Net           string
 	TsVer         string
@@ -257,6 +350,7 @@ type RemoveFlags struct {
 
 type StatusFlags struct {
 	Format string
+	Wide   bool
 }
 
 type DockerOutdatedFlags struct {
@@ -267,12 +361,25 @@ type DockerUpdateFlags struct {
 	Outdated bool
 }
 
+// DockerImagesActionPrune is the `docker images` action that garbage collects
+// the internal registry.
+const DockerImagesActionPrune = "prune"
+
+type DockerImagesFlags struct {
+	DryRun bool
+	// Every is the periodic prune interval, "off" to disable it, or empty to
+	// run a prune now.
+	Every  string
+	Format string
+}
+
 type VMImagesFlags struct {
 	Format           string
 	AllowLocalKernel bool
 	Stdin            bool
 	Yes              bool
 	DryRun           bool
+	Snapshot         string
 }
 
 type InfoFlags struct {
@@ -284,9 +391,12 @@ type EventsFlags struct {
 }
 
 type MountFlags struct {
-	Type string
-	Opts string
-	Deps []string
+	Type        string
+	Opts        string
+	Deps        []string
+	Credentials string
+	HostKey     string
+	Services    []string
 }
 
 type VersionFlags struct {
@@ -358,8 +468,9 @@ type snapshotsRestoreFlagsParsed struct {
 }
 
 type dockerPushFlagsParsed struct {
-	Run      bool `flag:"run"`
-	AllLocal bool `flag:"all-local"`
+	Run      bool   `flag:"run"`
+	AllLocal bool   `flag:"all-local"`
+	Platform string `flag:"platform"`
 }
 
 type runFlagsParsed struct {
@@ -373,6 +484,7 @@ type runFlagsParsed struct {
 	MemoryMin        string   `flag:"memory-min"`
 	Balloon          string   `flag:"balloon"`
 	Disk             string   `flag:"disk"`
+	UserData         string   `flag:"user-data"`
 	Net              string   `flag:"net"`
 	TsVer            string   `flag:"ts-ver"`
 	TsExit           string   `flag:"ts-exit"`
@@ -389,6 +501,10 @@ type runFlagsParsed struct {
 	Publish          []string `flag:"publish" short:"p"`
 	PublishReset     bool     `flag:"publish-reset"`
 	EnvFile          string   `flag:"env-file"`
+	ComposeFile      []string `flag:"compose-file" help:"Layer a compose override file on the compose payload; repeat to apply several in order"`
+	ComposeEnvFile   []string `flag:"compose-env-file" help:"Add a compose interpolation env file; repeat for multiple files"`
+	Profile          []string `flag:"profile" help:"Enable a compose profile; repeat for multiple profiles"`
+	Build            string   `flag:"build" help:"Where to build a Dockerfile payload: local (default) or remote on catch"`
 	ServiceRoot      string   `flag:"service-root"`
 	ZFS              bool     `flag:"zfs"`
 	Snapshots        string   `flag:"snapshots"`
@@ -428,16 +544,24 @@ type serviceSetFlagsParsed struct {
 	SnapshotMaxAge   string   `flag:"snapshot-max-age"`
 	SnapshotRequired string   `flag:"snapshot-required"`
 	SnapshotEvents   string   `flag:"snapshot-events"`
+	Quota            string   `flag:"quota" help:"Limit service root storage, e.g. 20G; none removes the limit"`
+	AutoUpdate       string   `flag:"auto-update" help:"Check compose images for upstream updates on a schedule: off, notify, or apply"`
+	AutoUpdateWindow string   `flag:"auto-update-window" help:"When scheduled image checks run in catch's local time, e.g. \"Sun 03:00\" or \"03:00\" for daily (default 03:00)"`
+	ImagePolicy      string   `flag:"image-policy" help:"Override the host image policy for this service: require-signed, off, or inherit"`
 }
 
 type hostSetFlagsParsed struct {
-	DataDir         string `flag:"data-dir" help:"Catch state directory (default /var/lib/yeet)"`
-	ServicesRoot    string `flag:"services-root" help:"Default service root (default: data directory/services)"`
-	ZFS             bool   `flag:"zfs" help:"Treat supplied storage targets as ZFS datasets or dataset prefixes"`
-	MigrateServices string `flag:"migrate-services" help:"Service migration mode: all, none"`
-	ISOPool         string `flag:"iso-pool" help:"Set the RFC1918 IPv4 /16 used by isolated networks before any allocation exists"`
-	Config          string `flag:"config" help:"Path to yeet.toml to update after service migration"`
-	Yes             bool   `flag:"yes" short:"y" help:"Confirm disruptive host changes without prompting"`
+	DataDir          string   `flag:"data-dir" help:"Catch state directory (default /var/lib/yeet)"`
+	ServicesRoot     string   `flag:"services-root" help:"Default service root (default: data directory/services)"`
+	ZFS              bool     `flag:"zfs" help:"Treat supplied storage targets as ZFS datasets or dataset prefixes"`
+	MigrateServices  string   `flag:"migrate-services" help:"Service migration mode: all, none"`
+	ISOPool          string   `flag:"iso-pool" help:"Set the RFC1918 IPv4 /16 used by isolated networks before any allocation exists"`
+	RegistryMirror   string   `flag:"registry-mirror" help:"Cache pulls from an upstream registry on catch: docker.io, or off"`
+	ContainerRuntime string   `flag:"container-runtime" help:"Engine compose services run on: docker or podman"`
+	ImagePolicy      string   `flag:"image-policy" help:"Require compose images to carry a cosign signature from a trusted key: require-signed, or off"`
+	TrustKeys        []string `flag:"trust-key" help:"Public key file signatures are checked against; repeat to trust several, replacing the current keys"`
+	Config           string   `flag:"config" help:"Path to yeet.toml to update after service migration"`
+	Yes              bool     `flag:"yes" short:"y" help:"Confirm disruptive host changes without prompting"`
 }
 
 type hostCleanupFlagsParsed struct {
@@ -450,21 +574,47 @@ type serviceGenerationsFlagsParsed struct {
 }
 
 type vmSetFlagsParsed struct {
-	CPUs          int    `flag:"vcpus"`
-	Memory        string `flag:"memory"`
-	MemoryMin     string `flag:"memory-min"`
-	Balloon       string `flag:"balloon"`
-	Disk          string `flag:"disk"`
-	Net           string `flag:"net"`
-	MacvlanMac    string `flag:"macvlan-mac"`
-	MacvlanVlan   int    `flag:"macvlan-vlan"`
-	MacvlanParent string `flag:"macvlan-parent"`
+	CPUs          int      `flag:"vcpus"`
+	Memory        string   `flag:"memory"`
+	MemoryMin     string   `flag:"memory-min"`
+	Balloon       string   `flag:"balloon"`
+	Disk          string   `flag:"disk"`
+	Net           string   `flag:"net"`
+	MacvlanMac    string   `flag:"macvlan-mac"`
+	MacvlanVlan   int      `flag:"macvlan-vlan"`
+	MacvlanParent string   `flag:"macvlan-parent"`
+	Publish       []string `flag:"publish" short:"p"`
+	PublishReset  bool     `flag:"publish-reset"`
+
+	Autostart       string `flag:"autostart"`
+	StartOrder      int    `flag:"start-order"`
+	ShutdownTimeout string `flag:"shutdown-timeout"`
+	IdleStop        string `flag:"idle-stop"`
 }
 
 type vmKernelFlagsParsed struct {
 	Restart bool `flag:"restart" help:"Restart the VM after syncing the selected kernel"`
 }
 
+type vmDiskFlagsParsed struct {
+	Name   string `flag:"name" help:"Data disk name; also the ext4 label in the guest (default data)"`
+	Size   string `flag:"size" help:"Data disk size for add or resize, e.g. 200G"`
+	ZFS    bool   `flag:"zfs" help:"Create the data disk as a ZFS zvol instead of a raw file"`
+	From   string `flag:"from" help:"Attach a detached data disk instead of creating one"`
+	Keep   bool   `flag:"keep" help:"Detach the data disk and keep it for another VM instead of deleting it"`
+	Format string `flag:"format" help:"Output format: table, json, json-pretty"`
+}
+
+type vmMigrateFlagsParsed struct {
+	To string `flag:"to" help:"Destination catch host"`
+}
+
+type vmMigrateStepFlagsParsed struct {
+	ZFS         bool `flag:"zfs"`
+	Precopy     bool `flag:"precopy"`
+	Incremental bool `flag:"incremental"`
+}
+
 type vmRuntimeFlagsParsed struct {
 	Format  string `flag:"format" help:"Output format: table, json, json-pretty"`
 	To      string `flag:"to" help:"Exact runtime ID or upstream version"`
@@ -473,6 +623,16 @@ type vmRuntimeFlagsParsed struct {
 	DryRun  bool   `flag:"dry-run" help:"Show what would be pruned without removing anything"`
 }
 
+type vmConsoleFlagsParsed struct {
+	Attach bool   `flag:"attach" help:"Insist on typing into the console and detach with Ctrl-]; fails while another session types"`
+	Since  string `flag:"since" help:"Print console output since a duration ago (10m) or a time (2025-01-02T15:04:05) from the serial log"`
+	Lines  int    `flag:"lines" short:"n" help:"Print the last N lines of console output before streaming"`
+}
+
+type vmHibernateFlagsParsed struct {
+	OnHostShutdown string `flag:"on-host-shutdown" help:"Hibernate instead of cold stopping when the host shuts down: on, off"`
+}
+
 type vmMemoryFlagsParsed struct {
 	Policy string `flag:"policy" help:"Host VM memory policy: safe, balanced, aggressive"`
 	Format string `flag:"format" help:"Output format: table, json, json-pretty"`
@@ -484,6 +644,18 @@ type serviceSyncFlagsParsed struct {
 	Config string `flag:"config"`
 }
 
+type serviceExportFlagsParsed struct {
+	Data bool `flag:"data" help:"Include a consistent copy of the service root"`
+}
+
+type serviceImportFlagsParsed struct {
+	As string `flag:"as" help:"Import under a different service name"`
+}
+
+type serviceMoveFlagsParsed struct {
+	To string `flag:"to" help:"Destination catch host"`
+}
+
 type removeFlagsParsed struct {
 	Clean       bool `flag:"clean" help:"Delete service data and the matching yeet.toml entry"`
 	Yes         bool `flag:"yes" short:"y" help:"Skip removal prompts; does not imply --clean or --clean-data"`
@@ -519,6 +691,7 @@ type logsFlagsParsed struct {
 
 type statusFlagsParsed struct {
 	Format string `flag:"format" default:"table"`
+	Wide   bool   `flag:"wide"`
 }
 
 type dockerOutdatedFlagsParsed struct {
@@ -529,11 +702,18 @@ type dockerUpdateFlagsParsed struct {
 	Outdated bool `flag:"outdated"`
 }
 
+type dockerImagesFlagsParsed struct {
+	DryRun bool   `flag:"dry-run" help:"Show what would be pruned without removing anything"`
+	Every  string `flag:"every" help:"Prune periodically: a duration of at least 1h like 24h, or off"`
+	Format string `flag:"format" help:"Output format: table, json, json-pretty"`
+}
+
 type vmImagesFlagsParsed struct {
 	AllowLocalKernel bool   `flag:"allow-local-kernel" help:"Allow an imported VM image bundle to provide vmlinux"`
 	Stdin            bool   `flag:"stdin" help:"Read an import bundle tar stream from stdin"`
 	Yes              bool   `flag:"yes" short:"y" help:"Skip confirmation prompts"`
 	DryRun           bool   `flag:"dry-run" help:"Show what would be pruned without removing anything"`
+	Snapshot         string `flag:"snapshot" help:"Capture from this recovery point instead of the live disk"`
 	Format           string `flag:"format" help:"Output format: table, json, json-pretty"`
 	Output           string `flag:"output" help:"Alias for --format"`
 }
@@ -550,6 +730,10 @@ type mountFlagsParsed struct {
 	Type string   `flag:"type" short:"t" default:"nfs"`
 	Opts string   `flag:"opts" short:"o" default:"defaults"`
 	Deps []string `flag:"deps"`
+
+	Credentials string   `flag:"credentials" help:"CIFS credentials file or sshfs private key, stored on the host"`
+	HostKey     string   `flag:"host-key" help:"sshfs server host key (\"ssh-ed25519 AAAA...\"); without it the first key seen is trusted"`
+	Services    []string `flag:"services" help:"Services that require the mount before they start (comma-separated or repeated)"`
 }
 
 type versionFlagsParsed struct {
@@ -603,6 +787,10 @@ type ServiceSyncArgs struct {
 	Service ServiceName `pos:"0?" help:"Service name"`
 }
 
+type ServiceImportArgs struct {
+	File string `pos:"0" help:"Service archive file"`
+}
+
 type DockerPushArgs struct {
 	Service ServiceName `pos:"0" help:"Service name"`
 	Image   string      `pos:"1" help:"Local image ref"`
@@ -616,6 +804,10 @@ type DockerUpdateArgs struct {
 	Services []ServiceName `pos:"0+" help:"Service names"`
 }
 
+type DockerImagesArgs struct {
+	Target string `pos:"0?" help:"Service name to filter by, or prune"`
+}
+
 type VMImagesArgs struct {
 	Action string `pos:"0?" help:"Action (update)"`
 }
@@ -635,11 +827,12 @@ func IsServiceArgSpec(spec yargs.ArgSpec) bool {
 }
 
 var remoteCommandInfos = map[string]CommandInfo{
-	"copy": {Name: "copy", Description: "Copy files between local paths and service data or VM guests", Usage: "[--force-proxy] [-avz] <src>... <dst>", Examples: []string{
+	"copy": {Name: "copy", Description: "Copy files between local paths and service data or VM guests", Usage: "[--force-proxy|--agent] [-avz] <src>... <dst>", Examples: []string{
 		"yeet copy ./config.yml svc:data/config.yml",
 		"yeet copy ./configs/*.yml devbox:~/configs/",
 		`yeet copy devbox:"/var/log/*.log" ./logs/`,
 		"yeet copy --force-proxy ./configs/ devbox:~/configs/",
+		"yeet copy --agent ./motd devbox:/etc/motd",
 	}, Aliases: []string{"cp"}},
 	"disable": {Name: "disable", Description: "Disable a service", ArgsSchema: ServiceArgs{}},
 	"edit":    {Name: "edit", Description: "Edit a service", ArgsSchema: ServiceArgs{}},
@@ -647,15 +840,18 @@ var remoteCommandInfos = map[string]CommandInfo{
 	"events":  {Name: "events", Description: "Show events for a service"},
 	"info":    {Name: "info", Description: "Show host info, or detailed service info when SVC is supplied", Usage: "[SVC] [--format=plain|json|json-pretty]", ArgsSchema: InfoArgs{}},
 	"logs":    {Name: "logs", Description: "Show logs of a service", ArgsSchema: ServiceArgs{}},
-	"mount": {Name: "mount", Description: "Mount a network filesystem on the host (global, not per-service)", Usage: "SOURCE [name] [--type=nfs] [--opts=defaults]", Examples: []string{
+	"mount": {Name: "mount", Description: "Mount a network filesystem or bind a host path (global, not per-service)", Usage: "SOURCE [name] [--type=nfs|cifs|sshfs|bind] [--opts=defaults] [--credentials=FILE] [--host-key=KEY] [--services=SVC,...]", Examples: []string{
 		"yeet mount host:/export data-share --type=nfs --opts=defaults",
+		"yeet mount //nas/media media --type=cifs --credentials=./nas.cred --services=jellyfin",
+		"yeet mount backup@host:/srv/backups backups --type=sshfs --credentials=~/.ssh/id_ed25519 --host-key=\"ssh-ed25519 AAAA...\"",
+		"yeet mount /srv/shared shared --type=bind --services=api,worker",
 		"yeet mount",
 	}},
 	"ip":      {Name: "ip", Description: "Show the connectable IP endpoints of a service"},
 	"umount":  {Name: "umount", Description: "Unmount a host mount by name", Usage: "NAME", Examples: []string{"yeet umount data-share"}},
 	"remove":  {Name: "remove", Description: "Remove a service", Aliases: []string{"rm"}, ArgsSchema: ServiceArgs{}, FlagsSchema: removeFlagsParsed{}},
 	"restart": {Name: "restart", Description: "Restart a service", ArgsSchema: ServiceArgs{}},
-	"run": {Name: "run", Description: "Install/update from a payload (binary, compose, image, Dockerfile, VM)", Usage: "SVC [PAYLOAD] [--cron=\"M H DOM MON DOW\"] [--run-as=USER[:GROUP]] [--sandbox=on|off] [--sandbox-ro=SOURCE[:DEST]] [--sandbox-rw=SOURCE[:DEST]] [--net=svc|ts|lan|iso] [-p HOST:CONTAINER] [--publish-reset] [--service-root=/abs/path|dataset] [--zfs] [--snapshots=on|off|inherit] [-- <payload args>] | --web [SVC] [PAYLOAD]", Examples: []string{
+	"run": {Name: "run", Description: "Install/update from a payload (binary, compose, image, Dockerfile, VM)", Usage: "SVC [PAYLOAD] [--cron=\"M H DOM MON DOW\"] [--run-as=USER[:GROUP]] [--sandbox=on|off] [--sandbox-ro=SOURCE[:DEST]] [--sandbox-rw=SOURCE[:DEST]] [--net=svc|ts|lan|iso] [-p HOST:CONTAINER] [--publish-reset] [--compose-file=PATH] [--profile=NAME] [--compose-env-file=PATH] [--build=local|remote] [--service-root=/abs/path|dataset] [--zfs] [--snapshots=on|off|inherit] [-- <payload args>] | --web [SVC] [PAYLOAD]", Examples: []string{
 		"yeet run --web",
 		"yeet run --web <svc>",
 		"yeet run --web <svc> ./compose.yml",
@@ -667,15 +863,20 @@ var remoteCommandInfos = map[string]CommandInfo{
 		"yeet run <svc> ./compose.yml --net=svc,ts --ts-tags=tag:app",
 		"yeet run <svc> vm://ubuntu/26.04 --net=svc",
 		"yeet run <svc> vm://ubuntu/26.04 --net=lan",
+		"yeet run <svc> vm://ubuntu/26.04 -p 8080:80",
 		"yeet run <svc> vm://nixos/26.05",
 		"yeet run <svc> vm://ubuntu/26.04 --image-policy=update",
+		"yeet run <svc> vm://ubuntu/26.04 --user-data=./init.yaml",
 		"yeet run <svc> ./compose.yml --service-root=tank/apps/<svc> --zfs",
 		"yeet run <svc> ./compose.yml --snapshots=off",
 		"yeet run --pull <svc> ./compose.yml",
 		"yeet run --force <svc> ./compose.yml",
 		"yeet run --env-file=prod.env <svc> ./compose.yml",
+		"yeet run <svc> ./compose.yml --compose-file=./prod.override.yml --profile=worker",
+		"yeet run <svc> ./compose.yml --compose-env-file=./versions.env",
 		"yeet run <svc> ghcr.io/org/app:latest",
 		"yeet run <svc> ./Dockerfile",
+		"yeet run <svc> ./Dockerfile --build=remote",
 	}, ArgsSchema: ServiceArgs{}, FlagsSchema: runFlagsParsed{}},
 	"start": {Name: "start", Description: "Start a service", ArgsSchema: ServiceArgs{}},
 	"stage": {Name: "stage", Description: "Upload a payload without applying it (use stage show/commit/clear)", Usage: "SVC PAYLOAD|show|commit|clear [-- <payload args>]", Examples: []string{
@@ -684,11 +885,12 @@ var remoteCommandInfos = map[string]CommandInfo{
 		"yeet stage <svc> commit",
 		"yeet stage <svc> clear",
 	}, ArgsSchema: ServiceArgs{}},
-	"status": {Name: "status", Description: "Show host or service status", Usage: "[SVC...] [--format=table|json|json-pretty]", Examples: []string{
+	"status": {Name: "status", Description: "Show host or service status", Usage: "[SVC...] [--format=table|json|json-pretty] [--wide]", Examples: []string{
 		"yeet status",
 		"yeet status <svc>",
 		"yeet status <svc-a> <svc-b>",
 		"yeet status <svc>@<catch-host>",
+		"yeet status --wide",
 	}},
 	"tailscale": {Name: "tailscale", Description: "Configure tailscale OAuth or run tailscale commands in a service netns", Usage: "--setup [--client-secret=...] | <svc> -- <tailscale args...>", Examples: []string{
 		"yeet tailscale --setup",
@@ -736,7 +938,7 @@ var remoteGroupInfos = map[string]GroupInfo{
 				"yeet docker update --outdated",
 			}},
 			"pull": {Name: "pull", Description: "Pull images for a compose service without restarting", Usage: "docker pull <svc>", ArgsSchema: ServiceArgs{}},
-			"push": {Name: "push", Description: "Push a local image into the internal registry", Usage: "docker push <svc> <image> [--run] [--all-local]", ArgsSchema: DockerPushArgs{}},
+			"push": {Name: "push", Description: "Push a local image into the internal registry", Usage: "docker push <svc> <image> [--run] [--all-local] [--platform=os/arch]", ArgsSchema: DockerPushArgs{}},
 			"outdated": {
 				Name:        "outdated",
 				Description: "Show Docker compose containers with upstream image updates",
@@ -748,26 +950,70 @@ var remoteGroupInfos = map[string]GroupInfo{
 				},
 				ArgsSchema: DockerOutdatedArgs{},
 			},
+			"images": {
+				Name:        "images",
+				Description: "List images in the internal registry, or garbage collect the ones no service generation references and mirror cache images older than 30 days",
+				Usage:       "docker images [SVC] [--format=table|json|json-pretty] | docker images prune [--dry-run] [--every=24h|off] [--format=table|json|json-pretty]",
+				Examples: []string{
+					"yeet docker images",
+					"yeet docker images <svc>",
+					"yeet docker images --format=json",
+					"yeet docker images prune --dry-run",
+					"yeet docker images prune",
+					"yeet docker images prune --every=24h",
+					"yeet docker images prune --every=off",
+				},
+				ArgsSchema:  DockerImagesArgs{},
+				FlagsSchema: dockerImagesFlagsParsed{},
+			},
 		},
 	},
 	"vm": {
 		Name:        "vm",
 		Description: "Manage VM-specific commands",
 		Commands: map[string]CommandInfo{
-			"console": {Name: "console", Description: "Stream VM serial console output", Usage: "vm console <svc>", ArgsSchema: ServiceArgs{}},
+			"console": {
+				Name:        "console",
+				Description: "Open the VM serial console",
+				Usage:       "vm console <vm> [--attach] [--since=TIME] [--lines=N]",
+				Examples: []string{
+					"yeet vm console <vm>",
+					"yeet vm console <vm> --attach",
+					"yeet vm console <vm> --lines=500",
+					"yeet vm console <vm> --since=10m",
+				},
+				ArgsSchema:  ServiceArgs{},
+				FlagsSchema: vmConsoleFlagsParsed{},
+			},
 			"set": {
 				Name:        "set",
-				Description: "Set resources and networking on a stopped VM",
-				Usage:       "vm set <vm> [--vcpus=N] [--memory=SIZE] [--memory-min=SIZE] [--balloon=auto|off] [--disk=SIZE] [--net=svc|lan|svc,lan|iso] [--macvlan-parent=IFACE] [--macvlan-vlan=ID] [--macvlan-mac=MAC]",
+				Description: "Set resources and networking on a stopped VM, or published ports and power policy on a running one",
+				Usage:       "vm set <vm> [--vcpus=N] [--memory=SIZE] [--memory-min=SIZE] [--balloon=auto|off] [--disk=SIZE] [--net=svc|lan|svc,lan|iso] [--macvlan-parent=IFACE] [--macvlan-vlan=ID] [--macvlan-mac=MAC] [-p HOST:GUEST] [--publish-reset] [--autostart=on|off] [--start-order=N] [--shutdown-timeout=60s] [--idle-stop=30m|off]",
 				Examples: []string{
 					"yeet vm set <vm> --vcpus=8 --memory=8g --disk=128g",
 					"yeet vm set <vm> --memory-min=1g --balloon=auto",
 					"yeet vm set <vm> --net=lan",
 					"yeet vm set <vm> --net=svc,lan --macvlan-parent=vmbr0 --macvlan-vlan=4",
 					"yeet vm set <vm> --net=iso",
+					"yeet vm set <vm> -p 80:80 -p 443:443",
+					"yeet vm set <vm> --publish-reset",
+					"yeet vm set <vm> --autostart=on --start-order=10 --shutdown-timeout=60s",
+					"yeet vm set <vm> --idle-stop=30m",
 				},
 				ArgsSchema: ServiceArgs{},
 			},
+			"hibernate": {
+				Name:        "hibernate",
+				Description: "Save a running VM's memory to disk and stop it",
+				Usage:       "vm hibernate <vm> [--on-host-shutdown=on|off]",
+				Examples: []string{
+					"yeet vm hibernate <vm>",
+					"yeet vm hibernate <vm> --on-host-shutdown=on",
+				},
+				ArgsSchema:  ServiceArgs{},
+				FlagsSchema: vmHibernateFlagsParsed{},
+			},
+			"resume": {Name: "resume", Description: "Start a hibernated VM from its memory snapshot", Usage: "vm resume <vm>", ArgsSchema: ServiceArgs{}},
 			"memory": {
 				Name:        "memory",
 				Description: "Show or set host VM memory policy",
@@ -782,7 +1028,7 @@ var remoteGroupInfos = map[string]GroupInfo{
 			"images": {
 				Name:        "images",
 				Description: "Show available VM images and manage VM image cache state",
-				Usage:       "vm images [ls|catalog|update|import <name> <dir>|rm <name>|prune] [--format=table|json|json-pretty]",
+				Usage:       "vm images [ls|catalog|update|import <name> <dir>|capture <vm> <name> [--snapshot=POINT]|rm <name>|prune] [--format=table|json|json-pretty]",
 				Examples: []string{
 					"yeet vm images catalog",
 					"yeet vm images",
@@ -791,6 +1037,8 @@ var remoteGroupInfos = map[string]GroupInfo{
 					"yeet vm images update vm://nixos/26.05",
 					"yeet vm images import foo/bar ./dist/my-vm",
 					"yeet vm images import kernel/test ./dist/my-vm --allow-local-kernel",
+					"yeet vm images capture devbox local/golden",
+					"yeet vm images capture devbox local/golden --snapshot=<point>",
 					"yeet vm images rm foo/bar --yes",
 					"yeet vm images prune",
 					"yeet vm images prune --dry-run",
@@ -808,6 +1056,29 @@ var remoteGroupInfos = map[string]GroupInfo{
 				},
 				FlagsSchema: vmKernelFlagsParsed{},
 			},
+			"disk": {
+				Name:        "disk",
+				Description: "Manage VM data disks",
+				Usage:       "vm disk ls <vm> [--format=table|json|json-pretty] | vm disk add <vm> [--name=NAME] (--size=SIZE [--zfs] | --from=DETACHED) | vm disk resize <vm> <disk> --size=SIZE | vm disk rm <vm> <disk> [--keep]",
+				Examples: []string{
+					"yeet vm disk ls <vm>",
+					"yeet vm disk add <vm> --size=200G",
+					"yeet vm disk add <vm> --name=pg --size=50G --zfs",
+					"yeet vm disk resize <vm> data --size=300G",
+					"yeet vm disk rm <vm> data --keep",
+					"yeet vm disk add <new-vm> --from=<vm>-data",
+				},
+				FlagsSchema: vmDiskFlagsParsed{},
+			},
+			"migrate": {
+				Name:        "migrate",
+				Description: "Move a VM and its disks to another catch host",
+				Usage:       "vm migrate <vm> --to=<host>",
+				Examples: []string{
+					"yeet vm migrate <vm> --to=<host>",
+				},
+				FlagsSchema: vmMigrateFlagsParsed{},
+			},
 			"runtime": {
 				Name:        "runtime",
 				Description: "Manage host Firecracker and jailer runtimes",
@@ -856,12 +1127,15 @@ var remoteGroupInfos = map[string]GroupInfo{
 			"set": {
 				Name:        "set",
 				Description: "Configure catch host storage and networking",
-				Usage:       "host set [--data-dir=PATH_OR_DATASET] [--services-root=PATH_OR_DATASET_PREFIX] [--zfs] [--migrate-services=all|none] [--iso-pool=RFC1918_IPV4/16] [--config=PATH] [--yes]",
+				Usage:       "host set [--data-dir=PATH_OR_DATASET] [--services-root=PATH_OR_DATASET_PREFIX] [--zfs] [--migrate-services=all|none] [--iso-pool=RFC1918_IPV4/16] [--registry-mirror=docker.io|off] [--container-runtime=docker|podman] [--image-policy=require-signed|off] [--trust-key=PATH] [--config=PATH] [--yes]",
 				Examples: []string{
 					"yeet host set --data-dir=/var/lib/yeet --services-root=/var/lib/yeet/services --migrate-services=all --yes",
 					"yeet host set --services-root=/srv/yeet/services --migrate-services=none",
 					"yeet host set --zfs --data-dir=flash/yeet/data --services-root=flash/yeet/services --migrate-services=all",
 					"yeet host set --iso-pool=172.30.0.0/16",
+					"yeet host set --registry-mirror=docker.io",
+					"yeet host set --container-runtime=podman",
+					"yeet host set --image-policy=require-signed --trust-key=cosign.pub",
 				},
 				FlagsSchema: hostSetFlagsParsed{},
 			},
@@ -874,7 +1148,7 @@ var remoteGroupInfos = map[string]GroupInfo{
 			"set": {
 				Name:        "set",
 				Description: "Set service settings",
-				Usage:       "service set <svc> [--cron=\"M H DOM MON DOW\"] [--run-as=USER[:GROUP]] [--sandbox=on|off] [--sandbox-ro=SOURCE[:DEST]] [--sandbox-rw=SOURCE[:DEST]] [-p HOST:CONTAINER] [--publish-reset] [--service-root=/abs/path|dataset] [--zfs] [--copy|--empty] [--snapshots=on|off|inherit] [--snapshot-keep-last=N] [--snapshot-max-age=7d] [--snapshot-events=run,docker-update] [--snapshot-required=true|false] [--net=host|svc|ts|lan|iso] [--ts-ver=VERSION] [--ts-exit=HOST] [--ts-tags=TAG] [--ts-auth-key=KEY] [--macvlan-parent=IFACE] [--macvlan-vlan=ID] [--macvlan-mac=MAC]",
+				Usage:       "service set <svc> [--cron=\"M H DOM MON DOW\"] [--run-as=USER[:GROUP]] [--sandbox=on|off] [--sandbox-ro=SOURCE[:DEST]] [--sandbox-rw=SOURCE[:DEST]] [-p HOST:CONTAINER] [--publish-reset] [--service-root=/abs/path|dataset] [--zfs] [--copy|--empty] [--snapshots=on|off|inherit] [--snapshot-keep-last=N] [--snapshot-max-age=7d] [--snapshot-events=run,docker-update] [--snapshot-required=true|false] [--quota=SIZE|none] [--auto-update=off|notify|apply] [--auto-update-window=\"Sun 03:00\"] [--image-policy=require-signed|off|inherit] [--net=host|svc|ts|lan|iso] [--ts-ver=VERSION] [--ts-exit=HOST] [--ts-tags=TAG] [--ts-auth-key=KEY] [--macvlan-parent=IFACE] [--macvlan-vlan=ID] [--macvlan-mac=MAC]",
 				Examples: []string{
 					"yeet service set <svc> -p 80:80 -p 443:443",
 					"yeet service set <svc> --publish-reset -p 443:443",
@@ -892,6 +1166,11 @@ var remoteGroupInfos = map[string]GroupInfo{
 					"yeet service set <svc> --service-root=/srv/apps/<svc> --empty",
 					"yeet service set <svc> --snapshots=off",
 					"yeet service set <svc> --snapshots=on --snapshot-keep-last=5 --snapshot-max-age=7d",
+					"yeet service set <svc> --quota=20G",
+					"yeet service set <svc> --quota=none",
+					"yeet service set <svc> --auto-update=apply --auto-update-window=\"Sun 03:00\"",
+					"yeet service set <svc> --auto-update=off",
+					"yeet service set <svc> --image-policy=require-signed",
 				},
 				ArgsSchema:  ServiceArgs{},
 				FlagsSchema: serviceSetFlagsParsed{},
@@ -920,6 +1199,38 @@ var remoteGroupInfos = map[string]GroupInfo{
 				},
 				ArgsSchema: ServiceSyncArgs{},
 			},
+			"export": {
+				Name:        "export",
+				Description: "Export a service as a portable archive on stdout",
+				Usage:       "service export <svc> [--data] > <svc>.yeet.tar.zst",
+				Examples: []string{
+					"yeet service export <svc> > <svc>.yeet.tar.zst",
+					"yeet service export <svc> --data > <svc>.yeet.tar.zst",
+				},
+				ArgsSchema:  ServiceArgs{},
+				FlagsSchema: serviceExportFlagsParsed{},
+			},
+			"import": {
+				Name:        "import",
+				Description: "Recreate a service from an exported archive",
+				Usage:       "service import <file> [--as=<svc>]",
+				Examples: []string{
+					"yeet service import <svc>.yeet.tar.zst",
+					"yeet --host=<host> service import <svc>.yeet.tar.zst --as=<new-svc>",
+				},
+				ArgsSchema:  ServiceImportArgs{},
+				FlagsSchema: serviceImportFlagsParsed{},
+			},
+			"move": {
+				Name:        "move",
+				Description: "Move a service and its data to another catch host; its tailnet name follows it, but published ports and the svc address are not re-pointed",
+				Usage:       "service move <svc> --to=<host>",
+				Examples: []string{
+					"yeet service move <svc> --to=<host>",
+				},
+				ArgsSchema:  ServiceArgs{},
+				FlagsSchema: serviceMoveFlagsParsed{},
+			},
 		},
 	},
 	"snapshots": {
@@ -1019,14 +1330,19 @@ var remoteGroupFlagSpecs = map[string]map[string]map[string]FlagSpec{
 		"pull":     {},
 		"push":     flagSpecsFromStruct(dockerPushFlagsParsed{}),
 		"outdated": flagSpecsFromStruct(dockerOutdatedFlagsParsed{}),
+		"images":   flagSpecsFromStruct(dockerImagesFlagsParsed{}),
 	},
 	"vm": {
-		"console": {},
-		"set":     flagSpecsFromStruct(vmSetFlagsParsed{}),
-		"memory":  flagSpecsFromStruct(vmMemoryFlagsParsed{}),
-		"images":  flagSpecsFromStruct(vmImagesFlagsParsed{}),
-		"kernel":  flagSpecsFromStruct(vmKernelFlagsParsed{}),
-		"runtime": flagSpecsFromStruct(vmRuntimeFlagsParsed{}),
+		"console":   flagSpecsFromStruct(vmConsoleFlagsParsed{}),
+		"set":       flagSpecsFromStruct(vmSetFlagsParsed{}),
+		"hibernate": flagSpecsFromStruct(vmHibernateFlagsParsed{}),
+		"resume":    {},
+		"memory":    flagSpecsFromStruct(vmMemoryFlagsParsed{}),
+		"images":    flagSpecsFromStruct(vmImagesFlagsParsed{}),
+		"kernel":    flagSpecsFromStruct(vmKernelFlagsParsed{}),
+		"disk":      flagSpecsFromStruct(vmDiskFlagsParsed{}),
+		"migrate":   flagSpecsFromStruct(vmMigrateFlagsParsed{}),
+		"runtime":   flagSpecsFromStruct(vmRuntimeFlagsParsed{}),
 	},
 	"env": {
 		"show": flagSpecsFromStruct(envShowFlagsParsed{}),
@@ -1043,6 +1359,9 @@ var remoteGroupFlagSpecs = map[string]map[string]map[string]FlagSpec{
 		"rollback":    {},
 		"generations": flagSpecsFromStruct(serviceGenerationsFlagsParsed{}),
 		"sync":        flagSpecsFromStruct(serviceSyncFlagsParsed{}),
+		"export":      flagSpecsFromStruct(serviceExportFlagsParsed{}),
+		"import":      flagSpecsFromStruct(serviceImportFlagsParsed{}),
+		"move":        flagSpecsFromStruct(serviceMoveFlagsParsed{}),
 	},
 	"snapshots": {
 		"list":      flagSpecsFromStruct(snapshotsListFlagsParsed{}),
@@ -1184,6 +1503,7 @@ func ParseRun(args []string) (RunFlags, []string, error) {
 		MemoryMin:        strings.TrimSpace(parsed.Flags.MemoryMin),
 		Balloon:          normalized.Balloon,
 		Disk:             strings.TrimSpace(parsed.Flags.Disk),
+		UserData:         strings.TrimSpace(parsed.Flags.UserData),
 		Net:              parsed.Flags.Net,
 		TsVer:            parsed.Flags.TsVer,
 		TsExit:           parsed.Flags.TsExit,
@@ -1200,6 +1520,10 @@ func ParseRun(args []string) (RunFlags, []string, error) {
 		Publish:          orderedFlagValues(parseArgs, "--publish", "-p"),
 		PublishReset:     parsed.Flags.PublishReset,
 		EnvFile:          parsed.Flags.EnvFile,
+		ComposeFiles:     orderedFlagValues(parseArgs, "--compose-file", ""),
+		ComposeEnvFiles:  orderedFlagValues(parseArgs, "--compose-env-file", ""),
+		Profiles:         orderedFlagValues(parseArgs, "--profile", ""),
+		Build:            normalized.Build,
 		ServiceRoot:      parsed.Flags.ServiceRoot,
 		ZFS:              parsed.Flags.ZFS,
 		Snapshots:        normalized.SnapshotMode,
@@ -1218,6 +1542,7 @@ type normalizedRunFlagValues struct {
 	SnapshotMode string
 	ImagePolicy  string
 	Balloon      string
+	Build        string
 }
 
 func normalizeRunFlagValues(parseArgs []string, flags runFlagsParsed) (normalizedRunFlagValues, error) {
@@ -1248,13 +1573,28 @@ func normalizeRunFlagValues(parseArgs []string, flags runFlagsParsed) (normalize
 	if err != nil {
 		return normalizedRunFlagValues{}, err
 	}
+	build, err := normalizeRunBuild(flags.Build)
+	if err != nil {
+		return normalizedRunFlagValues{}, err
+	}
 	return normalizedRunFlagValues{
 		SnapshotMode: snapshotMode,
 		ImagePolicy:  imagePolicy,
 		Balloon:      balloon,
+		Build:        build,
 	}, nil
 }
 
+func normalizeRunBuild(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "", RunBuildLocal, RunBuildRemote:
+		return value, nil
+	default:
+		return "", fmt.Errorf("--build must be local or remote")
+	}
+}
+
 func ParseServiceSet(args []string) (ServiceSetFlags, []string, error) {
 	if err := rejectServiceSetVMFlags(args); err != nil {
 		return ServiceSetFlags{}, nil, err
@@ -1273,19 +1613,35 @@ func ParseServiceSet(args []string) (ServiceSetFlags, []string, error) {
 	return flags, argsOut, nil
 }
 
+// RegistryMirrorDockerHub is the only upstream `host set --registry-mirror`
+// accepts.
+const RegistryMirrorDockerHub = "docker.io"
+
+// Image policies accepted by `host set --image-policy` and `service set
+// --image-policy`; inherit is only valid for services.
+const (
+	ImagePolicyRequireSigned = "require-signed"
+	ImagePolicyOff           = "off"
+	ImagePolicyInherit       = "inherit"
+)
+
 func ParseHostSet(args []string) (HostSetFlags, []string, error) {
 	parsed, err := parseFlags[hostSetFlagsParsed](args)
 	if err != nil {
 		return HostSetFlags{}, nil, err
 	}
 	flags := HostSetFlags{
-		DataDir:         strings.TrimSpace(parsed.Flags.DataDir),
-		ServicesRoot:    strings.TrimSpace(parsed.Flags.ServicesRoot),
-		ZFS:             parsed.Flags.ZFS,
-		MigrateServices: strings.TrimSpace(parsed.Flags.MigrateServices),
-		ISOPool:         strings.TrimSpace(parsed.Flags.ISOPool),
-		Config:          strings.TrimSpace(parsed.Flags.Config),
-		Yes:             parsed.Flags.Yes,
+		DataDir:          strings.TrimSpace(parsed.Flags.DataDir),
+		ServicesRoot:     strings.TrimSpace(parsed.Flags.ServicesRoot),
+		ZFS:              parsed.Flags.ZFS,
+		MigrateServices:  strings.TrimSpace(parsed.Flags.MigrateServices),
+		ISOPool:          strings.TrimSpace(parsed.Flags.ISOPool),
+		RegistryMirror:   strings.TrimSpace(parsed.Flags.RegistryMirror),
+		ContainerRuntime: strings.ToLower(strings.TrimSpace(parsed.Flags.ContainerRuntime)),
+		ImagePolicy:      strings.ToLower(strings.TrimSpace(parsed.Flags.ImagePolicy)),
+		TrustKeys:        parsed.Flags.TrustKeys,
+		Config:           strings.TrimSpace(parsed.Flags.Config),
+		Yes:              parsed.Flags.Yes,
 	}
 	if err := ValidateHostSetFlags(flags); err != nil {
 		return HostSetFlags{}, nil, err
@@ -1311,10 +1667,26 @@ func ParseHostCleanup(args []string) (HostCleanupFlags, []string, error) {
 func ValidateHostSetFlags(flags HostSetFlags) error {
 	switch flags.MigrateServices {
 	case "", "all", "none":
-		return nil
 	default:
 		return fmt.Errorf("--migrate-services must be all or none")
 	}
+	switch flags.RegistryMirror {
+	case "", "off", RegistryMirrorDockerHub:
+	default:
+		// The Docker daemon only honors registry-mirrors for Docker Hub.
+		return fmt.Errorf("--registry-mirror must be %s or off", RegistryMirrorDockerHub)
+	}
+	switch flags.ContainerRuntime {
+	case "", "docker", "podman":
+	default:
+		return fmt.Errorf("--container-runtime must be docker or podman")
+	}
+	switch flags.ImagePolicy {
+	case "", ImagePolicyRequireSigned, ImagePolicyOff:
+	default:
+		return fmt.Errorf("--image-policy must be %s or %s", ImagePolicyRequireSigned, ImagePolicyOff)
+	}
+	return nil
 }
 
 func rejectServiceSetVMFlags(args []string) error {
@@ -1347,6 +1719,18 @@ func serviceSetFlagsFromParsed(parsed serviceSetFlagsParsed, parseArgs []string)
 	if err != nil {
 		return ServiceSetFlags{}, err
 	}
+	quota, quotaSet, err := parseServiceQuota(parseArgs, parsed.Quota)
+	if err != nil {
+		return ServiceSetFlags{}, err
+	}
+	autoUpdate, autoUpdateWindow, err := parseServiceAutoUpdate(parseArgs, parsed.AutoUpdate, parsed.AutoUpdateWindow)
+	if err != nil {
+		return ServiceSetFlags{}, err
+	}
+	imagePolicy, err := parseServiceImagePolicy(parseArgs, parsed.ImagePolicy)
+	if err != nil {
+		return ServiceSetFlags{}, err
+	}
 	sandbox, err := parseSandboxOptions(
 		parseArgs,
 		parsed.Sandbox,
@@ -1394,6 +1778,11 @@ func serviceSetFlagsFromParsed(parsed serviceSetFlagsParsed, parseArgs []string)
 		SnapshotRequired: strings.TrimSpace(parsed.SnapshotRequired),
 		SnapshotEvents:   strings.TrimSpace(parsed.SnapshotEvents),
 		SnapshotChange:   hasAnySnapshotServiceSetFlag(parsed),
+		Quota:            quota,
+		QuotaSet:         quotaSet,
+		AutoUpdate:       autoUpdate,
+		AutoUpdateWindow: autoUpdateWindow,
+		ImagePolicy:      imagePolicy,
 		Sandbox:          sandbox,
 	}
 	if err := validateServiceSetFlags(flags, longFlagWasSupplied(parseArgs, "--service-root")); err != nil {
@@ -1551,7 +1940,7 @@ func validateServiceSetRootValue(flags ServiceSetFlags, rootChange bool) error {
 }
 
 func serviceSetHasNonCronChange(flags ServiceSetFlags, rootChange bool) bool {
-	return flags.RunAsSet || flags.HasNetworkChange() || rootChange || flags.Copy || flags.Empty || flags.SnapshotChange || hasServiceSetPublishChange(flags) || flags.Sandbox.HasChange()
+	return flags.RunAsSet || flags.HasNetworkChange() || rootChange || flags.Copy || flags.Empty || flags.SnapshotChange || flags.QuotaSet || flags.HasAutoUpdateChange() || flags.ImagePolicy != "" || hasServiceSetPublishChange(flags) || flags.Sandbox.HasChange()
 }
 
 func serviceSetHasChange(flags ServiceSetFlags, rootChange bool) bool {
@@ -1566,28 +1955,34 @@ func validateServiceSetCronExclusivity(flags ServiceSetFlags, serviceRootSet boo
 }
 
 type serviceSetChanges struct {
-	cron     bool
-	identity bool
-	network  bool
-	root     bool
-	publish  bool
-	snapshot bool
-	sandbox  bool
+	cron        bool
+	identity    bool
+	network     bool
+	root        bool
+	publish     bool
+	snapshot    bool
+	quota       bool
+	autoUpdate  bool
+	imagePolicy bool
+	sandbox     bool
 }
 
 func (changes serviceSetChanges) any() bool {
-	return changes.cron || changes.identity || changes.network || changes.root || changes.publish || changes.snapshot || changes.sandbox
+	return changes.cron || changes.identity || changes.network || changes.root || changes.publish || changes.snapshot || changes.quota || changes.autoUpdate || changes.imagePolicy || changes.sandbox
 }
 
 func serviceSetChangesFromFlags(flags ServiceSetFlags, serviceRootSet bool) serviceSetChanges {
 	return serviceSetChanges{
-		cron:     flags.CronSet,
-		identity: flags.RunAsSet,
-		network:  flags.HasNetworkChange(),
-		root:     serviceRootSet || flags.ZFS || flags.Copy || flags.Empty,
-		publish:  hasServiceSetPublishChange(flags),
-		snapshot: flags.SnapshotChange,
-		sandbox:  flags.Sandbox.HasChange(),
+		cron:        flags.CronSet,
+		identity:    flags.RunAsSet,
+		network:     flags.HasNetworkChange(),
+		root:        serviceRootSet || flags.ZFS || flags.Copy || flags.Empty,
+		publish:     hasServiceSetPublishChange(flags),
+		snapshot:    flags.SnapshotChange,
+		quota:       flags.QuotaSet,
+		autoUpdate:  flags.HasAutoUpdateChange(),
+		imagePolicy: flags.ImagePolicy != "",
+		sandbox:     flags.Sandbox.HasChange(),
 	}
 }
 
@@ -1849,6 +2244,17 @@ func ParseVMSet(args []string) (VMSetFlags, []string, error) {
 		MacvlanMac:    strings.TrimSpace(parsed.Flags.MacvlanMac),
 		MacvlanVlan:   parsed.Flags.MacvlanVlan,
 		MacvlanParent: strings.TrimSpace(parsed.Flags.MacvlanParent),
+		Publish:       orderedFlagValues(parseArgs, "--publish", "-p"),
+		PublishReset:  parsed.Flags.PublishReset,
+
+		Autostart:       strings.ToLower(strings.TrimSpace(parsed.Flags.Autostart)),
+		StartOrder:      parsed.Flags.StartOrder,
+		StartOrderSet:   hasNamedFlag(parseArgs, "--start-order"),
+		ShutdownTimeout: strings.TrimSpace(parsed.Flags.ShutdownTimeout),
+		IdleStop:        strings.ToLower(strings.TrimSpace(parsed.Flags.IdleStop)),
+	}
+	if err := validateVMSetPowerFlags(flags); err != nil {
+		return VMSetFlags{}, nil, err
 	}
 	if err := validateVMSetFlags(flags, hasUnknownVMSetFlag(parseArgs, specs)); err != nil {
 		return VMSetFlags{}, nil, err
@@ -1860,6 +2266,28 @@ func ParseVMSet(args []string) (VMSetFlags, []string, error) {
 	return flags, argsOut, nil
 }
 
+// validateVMSetPowerFlags checks the power policy flags. --idle-stop=off
+// clears the idle timer; --shutdown-timeout=0s skips the guest agent.
+func validateVMSetPowerFlags(flags VMSetFlags) error {
+	if flags.Autostart != "" && flags.Autostart != "on" && flags.Autostart != "off" {
+		return fmt.Errorf("--autostart must be on or off")
+	}
+	if flags.StartOrder < 0 {
+		return fmt.Errorf("--start-order must not be negative")
+	}
+	if flags.ShutdownTimeout != "" {
+		if d, err := time.ParseDuration(flags.ShutdownTimeout); err != nil || d < 0 {
+			return fmt.Errorf("--shutdown-timeout must be a duration like 60s")
+		}
+	}
+	if flags.IdleStop != "" && flags.IdleStop != "off" {
+		if d, err := time.ParseDuration(flags.IdleStop); err != nil || d < time.Minute {
+			return fmt.Errorf("--idle-stop must be off or a duration of at least 1m, like 30m")
+		}
+	}
+	return nil
+}
+
 func hasUnknownVMSetFlag(args []string, specs map[string]FlagSpec) bool {
 	for i := 0; i < len(args); i++ {
 		next, ok := nextParseArgIndex(args, i, specs)
@@ -1893,6 +2321,46 @@ func ParseVMMemory(args []string) (VMMemoryFlags, []string, error) {
 	return VMMemoryFlags{Policy: policy, Format: format}, argsOut, nil
 }
 
+// ParseVMConsole parses `vm console` flags. Without --attach the console types
+// when no other session does and watches otherwise; --since and --lines print
+// serial log history before streaming.
+func ParseVMConsole(args []string) (VMConsoleFlags, []string, error) {
+	parseArgs, extraArgs := splitArgsAtDoubleDash(args)
+	parsed, err := parseFlags[vmConsoleFlagsParsed](parseArgs)
+	if err != nil {
+		return VMConsoleFlags{}, nil, err
+	}
+	if parsed.Flags.Lines < 0 {
+		return VMConsoleFlags{}, nil, fmt.Errorf("--lines must be zero or greater")
+	}
+	flags := VMConsoleFlags{
+		Attach: parsed.Flags.Attach,
+		Since:  strings.TrimSpace(parsed.Flags.Since),
+		Lines:  parsed.Flags.Lines,
+	}
+	if longFlagWasSupplied(parseArgs, "--since") && flags.Since == "" {
+		return VMConsoleFlags{}, nil, fmt.Errorf("--since requires a time or duration")
+	}
+	argsOut := append(parsed.Args, extraArgs...)
+	return flags, argsOut, nil
+}
+
+// ParseVMHibernate parses `vm hibernate` flags. --on-host-shutdown only
+// changes the policy; it does not hibernate the VM.
+func ParseVMHibernate(args []string) (VMHibernateFlags, []string, error) {
+	parseArgs, extraArgs := splitArgsAtDoubleDash(args)
+	parsed, err := parseFlags[vmHibernateFlagsParsed](parseArgs)
+	if err != nil {
+		return VMHibernateFlags{}, nil, err
+	}
+	onHostShutdown := strings.ToLower(strings.TrimSpace(parsed.Flags.OnHostShutdown))
+	if longFlagWasSupplied(parseArgs, "--on-host-shutdown") && onHostShutdown != "on" && onHostShutdown != "off" {
+		return VMHibernateFlags{}, nil, fmt.Errorf("--on-host-shutdown must be on or off")
+	}
+	argsOut := append(parsed.Args, extraArgs...)
+	return VMHibernateFlags{OnHostShutdown: onHostShutdown}, argsOut, nil
+}
+
 func ParseVMKernel(args []string) (VMKernelFlags, []string, error) {
 	parseArgs, extraArgs := splitArgsAtDoubleDash(args)
 	parsed, err := parseFlags[vmKernelFlagsParsed](parseArgs)
@@ -1907,6 +2375,126 @@ func ParseVMKernel(args []string) (VMKernelFlags, []string, error) {
 	return flags, argsOut, nil
 }
 
+// ParseVMDisk parses `vm disk` after the VM has been bridged away, so the
+// returned args are the action followed by the disk name where one applies.
+func ParseVMDisk(args []string) (VMDiskFlags, []string, error) {
+	parseArgs, extraArgs := splitArgsAtDoubleDash(args)
+	parsed, err := parseFlags[vmDiskFlagsParsed](parseArgs)
+	if err != nil {
+		return VMDiskFlags{}, nil, err
+	}
+	format, err := normalizeOutputFormat("--format", parsed.Flags.Format)
+	if err != nil {
+		return VMDiskFlags{}, nil, err
+	}
+	flags := VMDiskFlags{
+		Name:   strings.TrimSpace(parsed.Flags.Name),
+		Size:   strings.TrimSpace(parsed.Flags.Size),
+		ZFS:    parsed.Flags.ZFS,
+		From:   strings.TrimSpace(parsed.Flags.From),
+		Keep:   parsed.Flags.Keep,
+		Format: format,
+	}
+	argsOut := append(parsed.Args, extraArgs...)
+	if len(argsOut) == 0 {
+		return VMDiskFlags{}, nil, fmt.Errorf("vm disk requires an action: ls, add, resize, rm")
+	}
+	if err := validateVMDiskAction(parseArgs, flags, argsOut); err != nil {
+		return VMDiskFlags{}, nil, err
+	}
+	return flags, argsOut, nil
+}
+
+func ParseVMMigrate(args []string) (VMMigrateFlags, []string, error) {
+	parsed, err := parseFlags[vmMigrateFlagsParsed](args)
+	if err != nil {
+		return VMMigrateFlags{}, nil, err
+	}
+	if len(parsed.Args) == 0 {
+		return VMMigrateFlags{}, nil, fmt.Errorf("vm migrate requires a VM")
+	}
+	if len(parsed.Args) != 1 {
+		return VMMigrateFlags{}, nil, fmt.Errorf("vm migrate requires exactly one VM")
+	}
+	to := strings.TrimSpace(parsed.Flags.To)
+	if to == "" {
+		return VMMigrateFlags{}, nil, fmt.Errorf("vm migrate requires --to=<host>")
+	}
+	return VMMigrateFlags{To: to}, parsed.Args, nil
+}
+
+// errVMMigrateStepUsage is returned when `vm migrate` reaches catch directly
+// instead of through the yeet CLI, which drives the steps on both hosts.
+var errVMMigrateStepUsage = errors.New("vm migrate runs from the yeet CLI: yeet vm migrate <vm> --to=<host>")
+
+// ParseVMMigrateStep parses the catch side of `vm migrate`. Only send takes
+// flags.
+func ParseVMMigrateStep(args []string) (VMMigrateStepFlags, string, error) {
+	parsed, err := parseFlags[vmMigrateStepFlagsParsed](args)
+	if err != nil || len(parsed.Args) != 1 {
+		return VMMigrateStepFlags{}, "", errVMMigrateStepUsage
+	}
+	flags := VMMigrateStepFlags{ZFS: parsed.Flags.ZFS, Precopy: parsed.Flags.Precopy, Incremental: parsed.Flags.Incremental}
+	switch action := parsed.Args[0]; action {
+	case VMMigrateStepSend:
+		return flags, action, nil
+	case VMMigrateStepReceive, VMMigrateStepFinish, VMMigrateStepAbort:
+		if flags != (VMMigrateStepFlags{}) {
+			return VMMigrateStepFlags{}, "", fmt.Errorf("vm migrate %s takes no flags", action)
+		}
+		return flags, action, nil
+	default:
+		return VMMigrateStepFlags{}, "", errVMMigrateStepUsage
+	}
+}
+
+type vmDiskActionRule struct {
+	allowedFlags []string
+	positionals  int
+	usage        string
+}
+
+var vmDiskActionRules = map[string]vmDiskActionRule{
+	"ls":     {allowedFlags: []string{"--format"}, positionals: 1, usage: "vm disk ls <vm> [--format=table|json|json-pretty]"},
+	"add":    {allowedFlags: []string{"--name", "--size", "--zfs", "--from"}, positionals: 1, usage: "vm disk add <vm> [--name=NAME] (--size=SIZE [--zfs] | --from=DETACHED)"},
+	"resize": {allowedFlags: []string{"--size"}, positionals: 2, usage: "vm disk resize <vm> <disk> --size=SIZE"},
+	"rm":     {allowedFlags: []string{"--keep"}, positionals: 2, usage: "vm disk rm <vm> <disk> [--keep]"},
+}
+
+func validateVMDiskAction(parseArgs []string, flags VMDiskFlags, positionals []string) error {
+	action := positionals[0]
+	rule, ok := vmDiskActionRules[action]
+	if !ok {
+		return fmt.Errorf("unknown vm disk action %q", action)
+	}
+	if len(positionals) != rule.positionals {
+		return fmt.Errorf("usage: %s", rule.usage)
+	}
+	for _, name := range []string{"--name", "--size", "--zfs", "--from", "--keep", "--format"} {
+		if longFlagWasSupplied(parseArgs, name) && !slices.Contains(rule.allowedFlags, name) {
+			return fmt.Errorf("%s is not supported by vm disk %s", name, action)
+		}
+	}
+	return validateVMDiskActionFlags(action, flags)
+}
+
+func validateVMDiskActionFlags(action string, flags VMDiskFlags) error {
+	switch action {
+	case "add":
+		if (flags.Size == "") == (flags.From == "") {
+			return fmt.Errorf("vm disk add requires exactly one of --size or --from")
+		}
+		if flags.ZFS && flags.From != "" {
+			return fmt.Errorf("--zfs cannot be combined with --from; a detached disk keeps its backend")
+		}
+	case "resize":
+		if flags.Size == "" {
+			return fmt.Errorf("vm disk resize requires --size")
+		}
+	}
+	return nil
+}
+
 func ParseVMRuntime(args []string) (VMRuntimeFlags, []string, error) {
 	parseArgs, extraArgs := splitArgsAtDoubleDash(args)
 	parsed, err := parseFlags[vmRuntimeFlagsParsed](parseArgs)
@@ -2231,7 +2819,15 @@ func hasVMSetChange(flags VMSetFlags) bool {
 		flags.NetworkChange ||
 		strings.TrimSpace(flags.MacvlanMac) != "" ||
 		flags.MacvlanVlan != 0 ||
-		strings.TrimSpace(flags.MacvlanParent) != ""
+		strings.TrimSpace(flags.MacvlanParent) != "" ||
+		len(flags.Publish) != 0 ||
+		flags.PublishReset ||
+		HasVMSetPowerChange(flags)
+}
+
+// HasVMSetPowerChange reports whether flags change the VM power policy.
+func HasVMSetPowerChange(flags VMSetFlags) bool {
+	return flags.Autostart != "" || flags.StartOrderSet || flags.ShutdownTimeout != "" || flags.IdleStop != ""
 }
 
 func normalizeVMBalloonMode(value string) (string, error) {
@@ -2268,6 +2864,155 @@ func normalizeSnapshotMode(value string) (string, error) {
 	}
 }
 
+// parseServiceQuota parses --quota as a binary size such as 512M or 20G. The
+// value "none" removes an existing limit and is reported as zero.
+func parseServiceQuota(parseArgs []string, value string) (int64, bool, error) {
+	if !longFlagWasSupplied(parseArgs, "--quota") {
+		return 0, false, nil
+	}
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(value, "none") {
+		return 0, true, nil
+	}
+	size, err := ParseByteSize(value)
+	if err != nil || size <= 0 {
+		return 0, false, fmt.Errorf("--quota must be a size such as 20G, or none")
+	}
+	return size, true, nil
+}
+
+// Auto-update modes accepted by `service set --auto-update`.
+const (
+	AutoUpdateOff    = "off"
+	AutoUpdateNotify = "notify"
+	AutoUpdateApply  = "apply"
+)
+
+// AutoUpdateWindow is a parsed --auto-update-window: a time of day, on one
+// weekday or every day.
+type AutoUpdateWindow struct {
+	Daily   bool
+	Weekday time.Weekday
+	Hour    int
+	Minute  int
+}
+
+// String formats w the way ParseAutoUpdateWindow accepts it.
+func (w AutoUpdateWindow) String() string {
+	clock := fmt.Sprintf("%02d:%02d", w.Hour, w.Minute)
+	if w.Daily {
+		return clock
+	}
+	return w.Weekday.String()[:3] + " " + clock
+}
+
+// ParseAutoUpdateWindow parses "HH:MM" as a daily window and "Day HH:MM",
+// with a three-letter or full weekday name, as a weekly one.
+func ParseAutoUpdateWindow(value string) (AutoUpdateWindow, error) {
+	fields := strings.Fields(value)
+	w := AutoUpdateWindow{Daily: true}
+	switch len(fields) {
+	case 1:
+	case 2:
+		day, ok := parseWeekday(fields[0])
+		if !ok {
+			return AutoUpdateWindow{}, fmt.Errorf("invalid weekday %q", fields[0])
+		}
+		w.Daily, w.Weekday = false, day
+	default:
+		return AutoUpdateWindow{}, fmt.Errorf("invalid window %q", value)
+	}
+	clock, err := time.Parse("15:04", fields[len(fields)-1])
+	if err != nil {
+		return AutoUpdateWindow{}, fmt.Errorf("invalid time of day %q", fields[len(fields)-1])
+	}
+	w.Hour, w.Minute = clock.Hour(), clock.Minute()
+	return w, nil
+}
+
+func parseWeekday(value string) (time.Weekday, bool) {
+	value = strings.ToLower(value)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if value == name || value == name[:3] {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// parseServiceAutoUpdate checks --auto-update and --auto-update-window and
+// returns them normalized; the window is returned in canonical form.
+func parseServiceAutoUpdate(parseArgs []string, mode, window string) (string, string, error) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	switch mode {
+	case "", AutoUpdateOff, AutoUpdateNotify, AutoUpdateApply:
+	default:
+		return "", "", fmt.Errorf("--auto-update must be off, notify, or apply")
+	}
+	if mode == "" && longFlagWasSupplied(parseArgs, "--auto-update") {
+		return "", "", fmt.Errorf("--auto-update must be off, notify, or apply")
+	}
+	window = strings.TrimSpace(window)
+	if window == "" {
+		if longFlagWasSupplied(parseArgs, "--auto-update-window") {
+			return "", "", fmt.Errorf("--auto-update-window must be a time such as \"Sun 03:00\" or \"03:00\"")
+		}
+		return mode, "", nil
+	}
+	if mode == AutoUpdateOff {
+		return "", "", fmt.Errorf("--auto-update-window cannot be combined with --auto-update=off")
+	}
+	parsed, err := ParseAutoUpdateWindow(window)
+	if err != nil {
+		return "", "", fmt.Errorf("--auto-update-window must be a time such as \"Sun 03:00\" or \"03:00\": %v", err)
+	}
+	return mode, parsed.String(), nil
+}
+
+// parseServiceImagePolicy checks --image-policy and returns it lowercased.
+func parseServiceImagePolicy(parseArgs []string, value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case ImagePolicyRequireSigned, ImagePolicyOff, ImagePolicyInherit:
+		return value, nil
+	case "":
+		if longFlagWasSupplied(parseArgs, "--image-policy") {
+			break
+		}
+		return "", nil
+	}
+	return "", fmt.Errorf("--image-policy must be %s, %s, or %s", ImagePolicyRequireSigned, ImagePolicyOff, ImagePolicyInherit)
+}
+
+var byteSizeUnits = map[string]int64{
+	"":  1,
+	"k": 1 << 10,
+	"m": 1 << 20,
+	"g": 1 << 30,
+	"t": 1 << 40,
+}
+
+// ParseByteSize parses a whole number of bytes with an optional binary unit
+// suffix: K, M, G, or T, optionally followed by B or iB.
+func ParseByteSize(value string) (int64, error) {
+	raw := strings.ToLower(strings.TrimSpace(value))
+	raw = strings.TrimSuffix(strings.TrimSuffix(raw, "ib"), "b")
+	digits := strings.TrimRight(raw, "kmgt")
+	mult, ok := byteSizeUnits[raw[len(digits):]]
+	if !ok || digits == "" {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	if n > math.MaxInt64/mult {
+		return 0, fmt.Errorf("size %q is too large", value)
+	}
+	return n * mult, nil
+}
+
 func normalizeVMImagePolicy(value string) (string, error) {
 	value = strings.ToLower(strings.TrimSpace(value))
 	switch value {
@@ -2537,6 +3282,52 @@ func ParseServiceGenerations(args []string) (ServiceGenerationsFlags, []string,
 	return ServiceGenerationsFlags{Format: format}, parsed.Args, nil
 }
 
+func ParseServiceExport(args []string) (ServiceExportFlags, []string, error) {
+	parsed, err := parseFlags[serviceExportFlagsParsed](args)
+	if err != nil {
+		return ServiceExportFlags{}, nil, err
+	}
+	if len(parsed.Args) == 0 {
+		return ServiceExportFlags{}, nil, fmt.Errorf("service export requires a service")
+	}
+	if len(parsed.Args) != 1 {
+		return ServiceExportFlags{}, nil, fmt.Errorf("service export requires exactly one service")
+	}
+	return ServiceExportFlags{Data: parsed.Flags.Data}, parsed.Args, nil
+}
+
+func ParseServiceImport(args []string) (ServiceImportFlags, []string, error) {
+	parsed, err := parseFlags[serviceImportFlagsParsed](args)
+	if err != nil {
+		return ServiceImportFlags{}, nil, err
+	}
+	if len(parsed.Args) == 0 {
+		return ServiceImportFlags{}, nil, fmt.Errorf("service import requires an archive file")
+	}
+	if len(parsed.Args) != 1 {
+		return ServiceImportFlags{}, nil, fmt.Errorf("service import requires exactly one archive file")
+	}
+	return ServiceImportFlags{As: strings.TrimSpace(parsed.Flags.As)}, parsed.Args, nil
+}
+
+func ParseServiceMove(args []string) (ServiceMoveFlags, []string, error) {
+	parsed, err := parseFlags[serviceMoveFlagsParsed](args)
+	if err != nil {
+		return ServiceMoveFlags{}, nil, err
+	}
+	if len(parsed.Args) == 0 {
+		return ServiceMoveFlags{}, nil, fmt.Errorf("service move requires a service")
+	}
+	if len(parsed.Args) != 1 {
+		return ServiceMoveFlags{}, nil, fmt.Errorf("service move requires exactly one service")
+	}
+	to := strings.TrimSpace(parsed.Flags.To)
+	if to == "" {
+		return ServiceMoveFlags{}, nil, fmt.Errorf("service move requires --to=<host>")
+	}
+	return ServiceMoveFlags{To: to}, parsed.Args, nil
+}
+
 func ParseServiceSync(args []string) (ServiceSyncFlags, []string, error) {
 	specs := remoteGroupFlagSpecs["service"]["sync"]
 	parseArgs, extraArgs := splitArgsForParsing(args, specs)
@@ -2705,7 +3496,7 @@ func ParseStatus(args []string) (StatusFlags, []string, error) {
 	if err != nil {
 		return StatusFlags{}, nil, err
 	}
-	flags := StatusFlags{Format: parsed.Flags.Format}
+	flags := StatusFlags{Format: parsed.Flags.Format, Wide: parsed.Flags.Wide}
 	argsOut := append(parsed.Args, extraArgs...)
 	return flags, argsOut, nil
 }
@@ -2732,6 +3523,48 @@ func ParseDockerUpdate(args []string) (DockerUpdateFlags, []string, error) {
 	return flags, argsOut, nil
 }
 
+func ParseDockerImages(args []string) (DockerImagesFlags, []string, error) {
+	parseArgs, extraArgs := splitArgsAtDoubleDash(args)
+	parsed, err := parseFlags[dockerImagesFlagsParsed](parseArgs)
+	if err != nil {
+		return DockerImagesFlags{}, nil, err
+	}
+	format, err := normalizeOutputFormat("--format", parsed.Flags.Format)
+	if err != nil {
+		return DockerImagesFlags{}, nil, err
+	}
+	flags := DockerImagesFlags{
+		DryRun: parsed.Flags.DryRun,
+		Every:  strings.ToLower(strings.TrimSpace(parsed.Flags.Every)),
+		Format: format,
+	}
+	positionals := append(parsed.Args, extraArgs...)
+	if len(positionals) == 0 || positionals[0] != DockerImagesActionPrune {
+		if flags.DryRun || longFlagWasSupplied(parseArgs, "--every") {
+			return DockerImagesFlags{}, nil, fmt.Errorf("--dry-run and --every only apply to docker images prune")
+		}
+		if len(positionals) > 1 {
+			return DockerImagesFlags{}, nil, fmt.Errorf("docker images takes at most one service")
+		}
+		return flags, positionals, nil
+	}
+	if len(positionals) > 1 {
+		return DockerImagesFlags{}, nil, fmt.Errorf("docker images prune takes no arguments")
+	}
+	if longFlagWasSupplied(parseArgs, "--every") && flags.Every == "" {
+		return DockerImagesFlags{}, nil, fmt.Errorf("--every must be off or a duration of at least 1h, like 24h")
+	}
+	if flags.Every != "" && flags.Every != "off" {
+		if d, err := time.ParseDuration(flags.Every); err != nil || d < time.Hour {
+			return DockerImagesFlags{}, nil, fmt.Errorf("--every must be off or a duration of at least 1h, like 24h")
+		}
+	}
+	if flags.Every != "" && flags.DryRun {
+		return DockerImagesFlags{}, nil, fmt.Errorf("--every cannot be combined with --dry-run")
+	}
+	return flags, positionals, nil
+}
+
 func ParseVMImages(args []string) (VMImagesFlags, []string, error) {
 	parseArgs, extraArgs := splitArgsAtDoubleDash(args)
 	parsed, err := parseFlags[vmImagesFlagsParsed](parseArgs)
@@ -2752,8 +3585,12 @@ func ParseVMImages(args []string) (VMImagesFlags, []string, error) {
 		Stdin:            parsed.Flags.Stdin,
 		Yes:              parsed.Flags.Yes,
 		DryRun:           parsed.Flags.DryRun,
+		Snapshot:         strings.TrimSpace(parsed.Flags.Snapshot),
 	}
 	argsOut := append(parsed.Args, extraArgs...)
+	if flags.Snapshot != "" && (len(argsOut) == 0 || argsOut[0] != "capture") {
+		return VMImagesFlags{}, nil, fmt.Errorf("--snapshot is only valid with vm images capture")
+	}
 	return flags, argsOut, nil
 }
 
@@ -2786,14 +3623,30 @@ func ParseMount(args []string) (MountFlags, []string, error) {
 		return MountFlags{}, nil, err
 	}
 	flags := MountFlags{
-		Type: parsed.Flags.Type,
-		Opts: parsed.Flags.Opts,
-		Deps: parsed.Flags.Deps,
+		Type:        parsed.Flags.Type,
+		Opts:        parsed.Flags.Opts,
+		Deps:        parsed.Flags.Deps,
+		Credentials: parsed.Flags.Credentials,
+		HostKey:     parsed.Flags.HostKey,
+		Services:    mountServiceNames(parsed.Flags.Services),
 	}
 	argsOut := append(parsed.Args, extraArgs...)
 	return flags, argsOut, nil
 }
 
+// mountServiceNames flattens repeated and comma-separated --services values.
+func mountServiceNames(values []string) []string {
+	var names []string
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
 func ParseVersion(args []string) (VersionFlags, []string, error) {
 	parseArgs, extraArgs := splitArgsAtDoubleDash(args)
 	parsed, err := parseFlags[versionFlagsParsed](parseArgs)
@@ -451,6 +451,41 @@ func TestParseRunSnapshotFlags(t *testing.T) {
 	}
 }
 
+func TestParseRunComposeProjectFlags(t *testing.T) {
+	flags, args, err := ParseRun([]string{"--compose-file=base.override.yml", "--profile", "worker", "--compose-file", "prod.override.yml", "--compose-env-file=versions.env", "--profile=debug", "compose.yml"})
+	if err != nil {
+		t.Fatalf("ParseRun: %v", err)
+	}
+	if want := []string{"base.override.yml", "prod.override.yml"}; !reflect.DeepEqual(flags.ComposeFiles, want) {
+		t.Fatalf("ComposeFiles = %#v, want %#v", flags.ComposeFiles, want)
+	}
+	if want := []string{"worker", "debug"}; !reflect.DeepEqual(flags.Profiles, want) {
+		t.Fatalf("Profiles = %#v, want %#v", flags.Profiles, want)
+	}
+	if want := []string{"versions.env"}; !reflect.DeepEqual(flags.ComposeEnvFiles, want) {
+		t.Fatalf("ComposeEnvFiles = %#v, want %#v", flags.ComposeEnvFiles, want)
+	}
+	if !flags.HasComposeProject() {
+		t.Fatal("HasComposeProject = false, want true")
+	}
+	if !reflect.DeepEqual(args, []string{"compose.yml"}) {
+		t.Fatalf("args = %#v", args)
+	}
+}
+
+func TestParseRunBuildFlag(t *testing.T) {
+	flags, args, err := ParseRun([]string{"--build=Remote", "Dockerfile"})
+	if err != nil {
+		t.Fatalf("ParseRun: %v", err)
+	}
+	if flags.Build != RunBuildRemote || !reflect.DeepEqual(args, []string{"Dockerfile"}) {
+		t.Fatalf("Build, args = %q, %#v; want remote, Dockerfile", flags.Build, args)
+	}
+	if _, _, err := ParseRun([]string{"--build=cloud", "Dockerfile"}); err == nil || !strings.Contains(err.Error(), "--build must be local or remote") {
+		t.Fatalf("ParseRun invalid build error = %v", err)
+	}
+}
+
 func TestParseRunWebFlag(t *testing.T) {
 	flags, args, err := ParseRun([]string{"--web", "payload.yml"})
 	if err != nil {
@@ -469,14 +504,15 @@ func TestParseRunVMFlags(t *testing.T) {
 		"--vcpus=4",
 		"--memory=4g",
 		"--disk=128g",
+		"--user-data=./init.yaml",
 		"--net=svc,lan",
 		"vm://ubuntu/26.04",
 	})
 	if err != nil {
 		t.Fatalf("ParseRun: %v", err)
 	}
-	if flags.CPUs != 4 || flags.Memory != "4g" || flags.Disk != "128g" {
-		t.Fatalf("VM flags = cpus %d memory %q disk %q", flags.CPUs, flags.Memory, flags.Disk)
+	if flags.CPUs != 4 || flags.Memory != "4g" || flags.Disk != "128g" || flags.UserData != "./init.yaml" {
+		t.Fatalf("VM flags = cpus %d memory %q disk %q user-data %q", flags.CPUs, flags.Memory, flags.Disk, flags.UserData)
 	}
 	if flags.Net != "svc,lan" {
 		t.Fatalf("Net = %q, want svc,lan", flags.Net)
@@ -1236,7 +1272,7 @@ func TestParseHostSetFlags(t *testing.T) {
 		Config:          "./yeet.toml",
 		Yes:             true,
 	}
-	if flags != want {
+	if !reflect.DeepEqual(flags, want) {
 		t.Fatalf("flags = %#v, want %#v", flags, want)
 	}
 	if !reflect.DeepEqual(args, []string{"extra"}) {
@@ -1280,6 +1316,43 @@ func TestParseHostSetISOPool(t *testing.T) {
 		t.Fatalf("flags/args = %#v/%#v", flags, args)
 	}
 }
+
+func TestParseHostSetRegistryMirror(t *testing.T) {
+	for _, value := range []string{"docker.io", "off"} {
+		flags, _, err := ParseHostSet([]string{"--registry-mirror", " " + value + " "})
+		if err != nil || flags.RegistryMirror != value {
+			t.Fatalf("ParseHostSet(%q) = %#v, %v", value, flags, err)
+		}
+	}
+	_, _, err := ParseHostSet([]string{"--registry-mirror=ghcr.io"})
+	if err == nil || !strings.Contains(err.Error(), "docker.io or off") {
+		t.Fatalf("ParseHostSet error = %v, want docker.io or off", err)
+	}
+}
+
+func TestParseHostSetContainerRuntime(t *testing.T) {
+	flags, _, err := ParseHostSet([]string{"--container-runtime", " Podman "})
+	if err != nil || flags.ContainerRuntime != "podman" {
+		t.Fatalf("ParseHostSet = %#v, %v", flags, err)
+	}
+	_, _, err = ParseHostSet([]string{"--container-runtime=containerd"})
+	if err == nil || !strings.Contains(err.Error(), "docker or podman") {
+		t.Fatalf("ParseHostSet error = %v, want docker or podman", err)
+	}
+}
+
+func TestParseHostSetImagePolicy(t *testing.T) {
+	flags, _, err := ParseHostSet([]string{"--image-policy=Require-Signed", "--trust-key=ci.pub", "--trust-key", "backup.pub"})
+	if err != nil || flags.ImagePolicy != ImagePolicyRequireSigned || !reflect.DeepEqual(flags.TrustKeys, []string{"ci.pub", "backup.pub"}) {
+		t.Fatalf("ParseHostSet = %#v, %v", flags, err)
+	}
+	for _, args := range [][]string{{"--image-policy=inherit"}, {"--image-policy=signed"}} {
+		if _, _, err := ParseHostSet(args); err == nil {
+			t.Fatalf("ParseHostSet(%q) returned nil error", args)
+		}
+	}
+}
+
 func TestParseVMSetFlags(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -1363,7 +1436,7 @@ func TestParseVMSetForwardsRemovedVMMIsolation(t *testing.T) {
 	if err != nil {
 		t.Fatalf("ParseVMSet: %v", err)
 	}
-	if flags != (VMSetFlags{}) {
+	if !reflect.DeepEqual(flags, VMSetFlags{}) {
 		t.Fatalf("flags = %#v, want none", flags)
 	}
 	want := []string{"devbox", "--vmm-isolation=jailer"}
@@ -1419,6 +1492,44 @@ func TestParseVMSetRejectsInvalidBalloonMode(t *testing.T) {
 	}
 }
 
+func TestParseVMSetPublishFlags(t *testing.T) {
+	flags, rest, err := ParseVMSet([]string{"-p", "80:80", "--publish=53:53/udp", "--publish-reset"})
+	if err != nil {
+		t.Fatalf("ParseVMSet: %v", err)
+	}
+	if len(rest) != 0 {
+		t.Fatalf("rest = %#v, want none", rest)
+	}
+	want := []string{"80:80", "53:53/udp"}
+	if !reflect.DeepEqual(flags.Publish, want) || !flags.PublishReset {
+		t.Fatalf("flags = %#v, want publish %#v with reset", flags, want)
+	}
+}
+
+func TestParseVMSetPowerFlags(t *testing.T) {
+	flags, _, err := ParseVMSet([]string{"--autostart=OFF", "--start-order=0", "--shutdown-timeout=90s", "--idle-stop=30m"})
+	if err != nil {
+		t.Fatalf("ParseVMSet: %v", err)
+	}
+	want := VMSetFlags{Autostart: "off", StartOrderSet: true, ShutdownTimeout: "90s", IdleStop: "30m"}
+	if !reflect.DeepEqual(flags, want) {
+		t.Fatalf("flags = %#v, want %#v", flags, want)
+	}
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"--autostart=maybe"}, want: "--autostart must be on or off"},
+		{args: []string{"--start-order=-1"}, want: "--start-order must not be negative"},
+		{args: []string{"--shutdown-timeout=soon"}, want: "--shutdown-timeout must be a duration"},
+		{args: []string{"--idle-stop=10s"}, want: "at least 1m"},
+	} {
+		if _, _, err := ParseVMSet(tc.args); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("ParseVMSet(%q) error = %v, want %q", tc.args, err, tc.want)
+		}
+	}
+}
+
 func TestParseVMMemoryCommand(t *testing.T) {
 	flags, rest, err := ParseVMMemory([]string{"set", "--policy=balanced"})
 	if err != nil {
@@ -1449,6 +1560,39 @@ func TestParseVMMemoryCommand(t *testing.T) {
 	}
 }
 
+func TestParseVMConsole(t *testing.T) {
+	flags, rest, err := ParseVMConsole([]string{"--attach", "-n", "50", "--since=10m"})
+	if err != nil || len(rest) != 0 {
+		t.Fatalf("ParseVMConsole = %#v, %#v, %v", flags, rest, err)
+	}
+	if want := (VMConsoleFlags{Attach: true, Since: "10m", Lines: 50}); flags != want {
+		t.Fatalf("flags = %#v, want %#v", flags, want)
+	}
+	if _, _, err := ParseVMConsole([]string{"--lines=-1"}); err == nil {
+		t.Fatal("ParseVMConsole accepted negative --lines")
+	}
+	if _, _, err := ParseVMConsole([]string{"--since="}); err == nil {
+		t.Fatal("ParseVMConsole accepted empty --since")
+	}
+}
+
+func TestParseVMHibernateCommand(t *testing.T) {
+	flags, rest, err := ParseVMHibernate(nil)
+	if err != nil || flags.OnHostShutdown != "" || len(rest) != 0 {
+		t.Fatalf("ParseVMHibernate() = %#v, %#v, %v", flags, rest, err)
+	}
+	flags, _, err = ParseVMHibernate([]string{"--on-host-shutdown=ON"})
+	if err != nil || flags.OnHostShutdown != "on" {
+		t.Fatalf("ParseVMHibernate on = %#v, %v", flags, err)
+	}
+	if _, _, err := ParseVMHibernate([]string{"--on-host-shutdown=maybe"}); err == nil || !strings.Contains(err.Error(), "on or off") {
+		t.Fatalf("ParseVMHibernate invalid error = %v", err)
+	}
+	if _, ok := RemoteGroupFlagSpecs()["vm"]["resume"]; !ok {
+		t.Fatal("vm resume flag specs missing")
+	}
+}
+
 func TestParseServiceSetSnapshotFlags(t *testing.T) {
 	flags, args, err := ParseServiceSet([]string{"svc", "--snapshots=off", "--snapshot-keep-last=3", "--snapshot-max-age=72h", "--snapshot-required=false", "--snapshot-events=run"})
 	if err != nil {
@@ -1478,6 +1622,103 @@ func TestParseServiceSetPublishFlags(t *testing.T) {
 	}
 }
 
+func TestParseServiceSetQuota(t *testing.T) {
+	tests := []struct {
+		name  string
+		arg   string
+		quota int64
+	}{
+		{name: "gigabytes", arg: "--quota=20G", quota: 20 << 30},
+		{name: "mebibytes", arg: "--quota=512MiB", quota: 512 << 20},
+		{name: "none", arg: "--quota=none", quota: 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			flags, _, err := ParseServiceSet([]string{"svc", tc.arg})
+			if err != nil {
+				t.Fatalf("ParseServiceSet: %v", err)
+			}
+			if !flags.QuotaSet || flags.Quota != tc.quota {
+				t.Fatalf("Quota = %d set=%v, want %d", flags.Quota, flags.QuotaSet, tc.quota)
+			}
+		})
+	}
+	for _, arg := range []string{"--quota=", "--quota=0", "--quota=20X", "--quota=-1G"} {
+		if _, _, err := ParseServiceSet([]string{"svc", arg}); err == nil || !strings.Contains(err.Error(), "--quota must be") {
+			t.Fatalf("ParseServiceSet(%s) error = %v, want quota error", arg, err)
+		}
+	}
+}
+
+func TestParseServiceSetAutoUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		mode   string
+		window string
+	}{
+		{name: "weekly", args: []string{"--auto-update=apply", "--auto-update-window=sunday 3:00"}, mode: "apply", window: "Sun 03:00"},
+		{name: "daily", args: []string{"--auto-update=NOTIFY", "--auto-update-window=23:30"}, mode: "notify", window: "23:30"},
+		{name: "off", args: []string{"--auto-update=off"}, mode: "off"},
+		{name: "window only", args: []string{"--auto-update-window=Mon 04:15"}, window: "Mon 04:15"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			flags, _, err := ParseServiceSet(append([]string{"svc"}, tc.args...))
+			if err != nil {
+				t.Fatalf("ParseServiceSet: %v", err)
+			}
+			if flags.AutoUpdate != tc.mode || flags.AutoUpdateWindow != tc.window || !flags.HasAutoUpdateChange() {
+				t.Fatalf("auto-update = %q %q, want %q %q", flags.AutoUpdate, flags.AutoUpdateWindow, tc.mode, tc.window)
+			}
+		})
+	}
+	for _, args := range [][]string{
+		{"--auto-update=always"},
+		{"--auto-update="},
+		{"--auto-update-window=Someday 03:00"},
+		{"--auto-update-window=25:00"},
+		{"--auto-update=off", "--auto-update-window=03:00"},
+		{"--auto-update=apply", "--cron=0 3 * * *"},
+	} {
+		if _, _, err := ParseServiceSet(append([]string{"svc"}, args...)); err == nil {
+			t.Fatalf("ParseServiceSet(%q) returned nil error", args)
+		}
+	}
+}
+
+func TestParseServiceSetImagePolicy(t *testing.T) {
+	for _, policy := range []string{"require-signed", "OFF", "inherit"} {
+		flags, _, err := ParseServiceSet([]string{"svc", "--image-policy=" + policy})
+		if err != nil || flags.ImagePolicy != strings.ToLower(policy) {
+			t.Fatalf("ParseServiceSet(%q) = %q, %v", policy, flags.ImagePolicy, err)
+		}
+	}
+	for _, args := range [][]string{{"--image-policy=signed"}, {"--image-policy="}, {"--image-policy=off", "--cron=0 3 * * *"}} {
+		if _, _, err := ParseServiceSet(append([]string{"svc"}, args...)); err == nil {
+			t.Fatalf("ParseServiceSet(%q) returned nil error", args)
+		}
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]int64{
+		"1024": 1024,
+		"4k":   4 << 10,
+		"2GB":  2 << 30,
+		"1TiB": 1 << 40,
+	}
+	for in, want := range tests {
+		got, err := ParseByteSize(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseByteSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	if _, err := ParseByteSize("9999999999T"); err == nil {
+		t.Fatal("ParseByteSize overflow returned nil error")
+	}
+}
+
 func TestParseServiceSetPublishOnlyDoesNotRequireServiceRoot(t *testing.T) {
 	if _, _, err := ParseServiceSet([]string{"svc", "-p", "80:80"}); err != nil {
 		t.Fatalf("ParseServiceSet publish-only: %v", err)
@@ -1590,6 +1831,105 @@ func TestParseServiceSyncFlags(t *testing.T) {
 	}
 }
 
+func TestParseServiceExportAndImport(t *testing.T) {
+	exportFlags, exportArgs, err := ParseServiceExport([]string{"sonarr", "--data"})
+	if err != nil {
+		t.Fatalf("ParseServiceExport error: %v", err)
+	}
+	if !exportFlags.Data || !reflect.DeepEqual(exportArgs, []string{"sonarr"}) {
+		t.Fatalf("ParseServiceExport = %#v %#v, want data for sonarr", exportFlags, exportArgs)
+	}
+	if _, _, err := ParseServiceExport(nil); err == nil || !strings.Contains(err.Error(), "service export requires a service") {
+		t.Fatalf("ParseServiceExport missing error = %v", err)
+	}
+	if _, _, err := ParseServiceExport([]string{"a", "b"}); err == nil || !strings.Contains(err.Error(), "exactly one service") {
+		t.Fatalf("ParseServiceExport extra error = %v", err)
+	}
+
+	importFlags, importArgs, err := ParseServiceImport([]string{"sonarr.yeet.tar.zst", "--as", " sonarr-copy "})
+	if err != nil {
+		t.Fatalf("ParseServiceImport error: %v", err)
+	}
+	if importFlags.As != "sonarr-copy" || !reflect.DeepEqual(importArgs, []string{"sonarr.yeet.tar.zst"}) {
+		t.Fatalf("ParseServiceImport = %#v %#v", importFlags, importArgs)
+	}
+	if _, _, err := ParseServiceImport(nil); err == nil || !strings.Contains(err.Error(), "requires an archive file") {
+		t.Fatalf("ParseServiceImport missing error = %v", err)
+	}
+	if _, _, err := ParseServiceImport([]string{"a", "b"}); err == nil || !strings.Contains(err.Error(), "exactly one archive file") {
+		t.Fatalf("ParseServiceImport extra error = %v", err)
+	}
+}
+
+func TestParseServiceMove(t *testing.T) {
+	flags, args, err := ParseServiceMove([]string{"sonarr", "--to", " edge-b "})
+	if err != nil {
+		t.Fatalf("ParseServiceMove error: %v", err)
+	}
+	if flags.To != "edge-b" || !reflect.DeepEqual(args, []string{"sonarr"}) {
+		t.Fatalf("ParseServiceMove = %#v %#v", flags, args)
+	}
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"--to=edge-b"}, want: "requires a service"},
+		{args: []string{"a", "b", "--to=edge-b"}, want: "exactly one service"},
+		{args: []string{"sonarr"}, want: "requires --to=<host>"},
+	} {
+		if _, _, err := ParseServiceMove(tc.args); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("ParseServiceMove(%q) error = %v, want %q", tc.args, err, tc.want)
+		}
+	}
+}
+
+func TestParseVMMigrate(t *testing.T) {
+	flags, args, err := ParseVMMigrate([]string{"devbox", "--to", " edge-b "})
+	if err != nil {
+		t.Fatalf("ParseVMMigrate error: %v", err)
+	}
+	if flags.To != "edge-b" || !reflect.DeepEqual(args, []string{"devbox"}) {
+		t.Fatalf("ParseVMMigrate = %#v %#v", flags, args)
+	}
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"--to=edge-b"}, want: "requires a VM"},
+		{args: []string{"a", "b", "--to=edge-b"}, want: "exactly one VM"},
+		{args: []string{"devbox"}, want: "requires --to=<host>"},
+	} {
+		if _, _, err := ParseVMMigrate(tc.args); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("ParseVMMigrate(%q) error = %v, want %q", tc.args, err, tc.want)
+		}
+	}
+}
+
+func TestParseVMMigrateStep(t *testing.T) {
+	flags, action, err := ParseVMMigrateStep([]string{"send", "--zfs", "--incremental"})
+	if err != nil {
+		t.Fatalf("ParseVMMigrateStep error: %v", err)
+	}
+	if action != VMMigrateStepSend || flags != (VMMigrateStepFlags{ZFS: true, Incremental: true}) {
+		t.Fatalf("ParseVMMigrateStep = %#v %q", flags, action)
+	}
+	if _, action, err := ParseVMMigrateStep([]string{"finish"}); err != nil || action != VMMigrateStepFinish {
+		t.Fatalf("ParseVMMigrateStep(finish) = %q, %v", action, err)
+	}
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"--to=edge-b"}, want: "runs from the yeet CLI"},
+		{args: []string{"devbox"}, want: "runs from the yeet CLI"},
+		{args: []string{"receive", "--zfs"}, want: "receive takes no flags"},
+	} {
+		if _, _, err := ParseVMMigrateStep(tc.args); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("ParseVMMigrateStep(%q) error = %v, want %q", tc.args, err, tc.want)
+		}
+	}
+}
+
 func TestParseInfoFlags(t *testing.T) {
 	flags, outArgs, err := ParseInfo([]string{"--format=json"})
 	if err != nil {
@@ -1826,7 +2166,7 @@ func TestRemoteCommandRegistryAndFlagSpecs(t *testing.T) {
 	if reg.SubCommands["run"].Info.Name != "run" {
 		t.Fatalf("registry run command = %#v", reg.SubCommands["run"])
 	}
-	if got := reg.SubCommands["run"].Info.Usage; got != "SVC [PAYLOAD] [--cron=\"M H DOM MON DOW\"] [--run-as=USER[:GROUP]] [--sandbox=on|off] [--sandbox-ro=SOURCE[:DEST]] [--sandbox-rw=SOURCE[:DEST]] [--net=svc|ts|lan|iso] [-p HOST:CONTAINER] [--publish-reset] [--service-root=/abs/path|dataset] [--zfs] [--snapshots=on|off|inherit] [-- <payload args>] | --web [SVC] [PAYLOAD]" {
+	if got := reg.SubCommands["run"].Info.Usage; got != "SVC [PAYLOAD] [--cron=\"M H DOM MON DOW\"] [--run-as=USER[:GROUP]] [--sandbox=on|off] [--sandbox-ro=SOURCE[:DEST]] [--sandbox-rw=SOURCE[:DEST]] [--net=svc|ts|lan|iso] [-p HOST:CONTAINER] [--publish-reset] [--compose-file=PATH] [--profile=NAME] [--compose-env-file=PATH] [--build=local|remote] [--service-root=/abs/path|dataset] [--zfs] [--snapshots=on|off|inherit] [-- <payload args>] | --web [SVC] [PAYLOAD]" {
 		t.Fatalf("run usage = %q", got)
 	}
 	if !containsString(reg.SubCommands["run"].Info.Examples, `yeet run <svc> ./job --cron="0 3 * * *" --run-as=backup --net=iso -- --daily`) {
@@ -1850,7 +2190,7 @@ func TestRemoteCommandRegistryAndFlagSpecs(t *testing.T) {
 	if reg.Groups["service"].Commands["set"].Info.Name != "set" {
 		t.Fatalf("registry service set command = %#v", reg.Groups["service"].Commands["set"])
 	}
-	if reg.Groups["service"].Commands["set"].Info.Usage != "service set <svc> [--cron=\"M H DOM MON DOW\"] [--run-as=USER[:GROUP]] [--sandbox=on|off] [--sandbox-ro=SOURCE[:DEST]] [--sandbox-rw=SOURCE[:DEST]] [-p HOST:CONTAINER] [--publish-reset] [--service-root=/abs/path|dataset] [--zfs] [--copy|--empty] [--snapshots=on|off|inherit] [--snapshot-keep-last=N] [--snapshot-max-age=7d] [--snapshot-events=run,docker-update] [--snapshot-required=true|false] [--net=host|svc|ts|lan|iso] [--ts-ver=VERSION] [--ts-exit=HOST] [--ts-tags=TAG] [--ts-auth-key=KEY] [--macvlan-parent=IFACE] [--macvlan-vlan=ID] [--macvlan-mac=MAC]" {
+	if reg.Groups["service"].Commands["set"].Info.Usage != "service set <svc> [--cron=\"M H DOM MON DOW\"] [--run-as=USER[:GROUP]] [--sandbox=on|off] [--sandbox-ro=SOURCE[:DEST]] [--sandbox-rw=SOURCE[:DEST]] [-p HOST:CONTAINER] [--publish-reset] [--service-root=/abs/path|dataset] [--zfs] [--copy|--empty] [--snapshots=on|off|inherit] [--snapshot-keep-last=N] [--snapshot-max-age=7d] [--snapshot-events=run,docker-update] [--snapshot-required=true|false] [--quota=SIZE|none] [--auto-update=off|notify|apply] [--auto-update-window=\"Sun 03:00\"] [--image-policy=require-signed|off|inherit] [--net=host|svc|ts|lan|iso] [--ts-ver=VERSION] [--ts-exit=HOST] [--ts-tags=TAG] [--ts-auth-key=KEY] [--macvlan-parent=IFACE] [--macvlan-vlan=ID] [--macvlan-mac=MAC]" {
 		t.Fatalf("service set usage = %q", reg.Groups["service"].Commands["set"].Info.Usage)
 	}
 	hostSet, ok := reg.Groups["host"].Commands["set"]
@@ -1860,7 +2200,7 @@ func TestRemoteCommandRegistryAndFlagSpecs(t *testing.T) {
 	if hostSet.Info.Name != "set" {
 		t.Fatalf("registry host set command = %#v", hostSet)
 	}
-	if hostSet.Info.Usage != "host set [--data-dir=PATH_OR_DATASET] [--services-root=PATH_OR_DATASET_PREFIX] [--zfs] [--migrate-services=all|none] [--iso-pool=RFC1918_IPV4/16] [--config=PATH] [--yes]" {
+	if hostSet.Info.Usage != "host set [--data-dir=PATH_OR_DATASET] [--services-root=PATH_OR_DATASET_PREFIX] [--zfs] [--migrate-services=all|none] [--iso-pool=RFC1918_IPV4/16] [--registry-mirror=docker.io|off] [--container-runtime=docker|podman] [--image-policy=require-signed|off] [--trust-key=PATH] [--config=PATH] [--yes]" {
 		t.Fatalf("host set usage = %q", hostSet.Info.Usage)
 	}
 	wantHostSetExamples := []string{
@@ -1868,12 +2208,15 @@ func TestRemoteCommandRegistryAndFlagSpecs(t *testing.T) {
 		"yeet host set --services-root=/srv/yeet/services --migrate-services=none",
 		"yeet host set --zfs --data-dir=flash/yeet/data --services-root=flash/yeet/services --migrate-services=all",
 		"yeet host set --iso-pool=172.30.0.0/16",
+		"yeet host set --registry-mirror=docker.io",
+		"yeet host set --container-runtime=podman",
+		"yeet host set --image-policy=require-signed --trust-key=cosign.pub",
 	}
 	if !reflect.DeepEqual(hostSet.Info.Examples, wantHostSetExamples) {
 		t.Fatalf("host set examples = %#v, want %#v", hostSet.Info.Examples, wantHostSetExamples)
 	}
 	hostSetHelp := yargs.GenerateGroupCommandHelp(reg.HelpConfig(), "host", "set", hostSetFlagsParsed{})
-	for _, want := range []string{"--data-dir", "--services-root", "--zfs", "--migrate-services", "--iso-pool", "--config", "--yes"} {
+	for _, want := range []string{"--data-dir", "--services-root", "--zfs", "--migrate-services", "--iso-pool", "--registry-mirror", "--container-runtime", "--image-policy", "--trust-key", "--config", "--yes"} {
 		if !strings.Contains(hostSetHelp, want) {
 			t.Fatalf("host set help missing %q:\n%s", want, hostSetHelp)
 		}
@@ -1917,6 +2260,11 @@ func TestRemoteCommandRegistryAndFlagSpecs(t *testing.T) {
 		"yeet service set <svc> --service-root=/srv/apps/<svc> --empty",
 		"yeet service set <svc> --snapshots=off",
 		"yeet service set <svc> --snapshots=on --snapshot-keep-last=5 --snapshot-max-age=7d",
+		"yeet service set <svc> --quota=20G",
+		"yeet service set <svc> --quota=none",
+		"yeet service set <svc> --auto-update=apply --auto-update-window=\"Sun 03:00\"",
+		"yeet service set <svc> --auto-update=off",
+		"yeet service set <svc> --image-policy=require-signed",
 	}
 	if !reflect.DeepEqual(reg.Groups["service"].Commands["set"].Info.Examples, wantServiceSetExamples) {
 		t.Fatalf("service set examples = %#v, want %#v", reg.Groups["service"].Commands["set"].Info.Examples, wantServiceSetExamples)
@@ -1938,10 +2286,10 @@ func TestRemoteCommandRegistryAndFlagSpecs(t *testing.T) {
 			t.Fatalf("service set help missing %q:\n%s", want, serviceSetHelp)
 		}
 	}
-	if reg.Groups["vm"].Commands["set"].Info.Usage != "vm set <vm> [--vcpus=N] [--memory=SIZE] [--memory-min=SIZE] [--balloon=auto|off] [--disk=SIZE] [--net=svc|lan|svc,lan|iso] [--macvlan-parent=IFACE] [--macvlan-vlan=ID] [--macvlan-mac=MAC]" {
+	if reg.Groups["vm"].Commands["set"].Info.Usage != "vm set <vm> [--vcpus=N] [--memory=SIZE] [--memory-min=SIZE] [--balloon=auto|off] [--disk=SIZE] [--net=svc|lan|svc,lan|iso] [--macvlan-parent=IFACE] [--macvlan-vlan=ID] [--macvlan-mac=MAC] [-p HOST:GUEST] [--publish-reset] [--autostart=on|off] [--start-order=N] [--shutdown-timeout=60s] [--idle-stop=30m|off]" {
 		t.Fatalf("vm set usage = %q", reg.Groups["vm"].Commands["set"].Info.Usage)
 	}
-	if got := reg.Groups["vm"].Commands["set"].Info.Description; got != "Set resources and networking on a stopped VM" {
+	if got := reg.Groups["vm"].Commands["set"].Info.Description; got != "Set resources and networking on a stopped VM, or published ports and power policy on a running one" {
 		t.Fatalf("vm set description = %q", got)
 	}
 	wantVMSetExamples := []string{
@@ -1950,6 +2298,10 @@ func TestRemoteCommandRegistryAndFlagSpecs(t *testing.T) {
 		"yeet vm set <vm> --net=lan",
 		"yeet vm set <vm> --net=svc,lan --macvlan-parent=vmbr0 --macvlan-vlan=4",
 		"yeet vm set <vm> --net=iso",
+		"yeet vm set <vm> -p 80:80 -p 443:443",
+		"yeet vm set <vm> --publish-reset",
+		"yeet vm set <vm> --autostart=on --start-order=10 --shutdown-timeout=60s",
+		"yeet vm set <vm> --idle-stop=30m",
 	}
 	if got := reg.Groups["vm"].Commands["set"].Info.Examples; !reflect.DeepEqual(got, wantVMSetExamples) {
 		t.Fatalf("vm set examples = %#v, want %#v", got, wantVMSetExamples)
@@ -2291,6 +2643,59 @@ func TestParseVMKernelSync(t *testing.T) {
 	}
 }
 
+func TestParseVMDisk(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantFlags VMDiskFlags
+		wantArgs  []string
+	}{
+		{name: "ls", args: []string{"ls", "--format=json"}, wantFlags: VMDiskFlags{Format: "json"}, wantArgs: []string{"ls"}},
+		{name: "add size", args: []string{"add", "--name=pg", "--size=200G", "--zfs"}, wantFlags: VMDiskFlags{Format: "table", Name: "pg", Size: "200G", ZFS: true}, wantArgs: []string{"add"}},
+		{name: "add from", args: []string{"add", "--from=old-data"}, wantFlags: VMDiskFlags{Format: "table", From: "old-data"}, wantArgs: []string{"add"}},
+		{name: "resize", args: []string{"resize", "data", "--size=300G"}, wantFlags: VMDiskFlags{Format: "table", Size: "300G"}, wantArgs: []string{"resize", "data"}},
+		{name: "rm keep", args: []string{"rm", "data", "--keep"}, wantFlags: VMDiskFlags{Format: "table", Keep: true}, wantArgs: []string{"rm", "data"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags, args, err := ParseVMDisk(tt.args)
+			if err != nil {
+				t.Fatalf("ParseVMDisk: %v", err)
+			}
+			if flags != tt.wantFlags {
+				t.Fatalf("flags = %#v, want %#v", flags, tt.wantFlags)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Fatalf("args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestParseVMDiskRejectsInvalidCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "missing action"},
+		{name: "unknown action", args: []string{"grow"}},
+		{name: "add without size or from", args: []string{"add"}},
+		{name: "add size and from", args: []string{"add", "--size=1G", "--from=old"}},
+		{name: "add zfs from", args: []string{"add", "--zfs", "--from=old"}},
+		{name: "resize without size", args: []string{"resize", "data"}},
+		{name: "resize without disk", args: []string{"resize", "--size=1G"}},
+		{name: "rm size", args: []string{"rm", "data", "--size=1G"}},
+		{name: "ls keep", args: []string{"ls", "--keep"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := ParseVMDisk(tt.args); err == nil {
+				t.Fatalf("ParseVMDisk(%q) succeeded", tt.args)
+			}
+		})
+	}
+}
+
 func TestParseVMRuntime(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -2485,6 +2890,11 @@ func TestParseAdditionalCommandFlags(t *testing.T) {
 		if flags.Format != "json" || len(args) != 0 {
 			t.Fatalf("ParseStatus format = %#v args=%v, want json no args", flags, args)
 		}
+
+		flags, _, err = ParseStatus([]string{"--wide"})
+		if err != nil || !flags.Wide {
+			t.Fatalf("ParseStatus wide = %#v, %v, want wide", flags, err)
+		}
 	})
 
 	t.Run("docker outdated", func(t *testing.T) {
@@ -2519,6 +2929,51 @@ func TestParseAdditionalCommandFlags(t *testing.T) {
 		}
 	})
 
+	t.Run("docker images", func(t *testing.T) {
+		flags, args, err := ParseDockerImages([]string{"prune", "--dry-run", "--format=json"})
+		if err != nil {
+			t.Fatalf("ParseDockerImages: %v", err)
+		}
+		if !flags.DryRun || flags.Format != "json" || flags.Every != "" {
+			t.Fatalf("ParseDockerImages = %#v, want dry-run json", flags)
+		}
+		if got := strings.Join(args, " "); got != "prune" {
+			t.Fatalf("ParseDockerImages args = %q, want prune", got)
+		}
+
+		flags, _, err = ParseDockerImages([]string{"prune", "--every=24h"})
+		if err != nil || flags.Every != "24h" || flags.Format != "table" {
+			t.Fatalf("ParseDockerImages every = %#v, %v, want 24h table", flags, err)
+		}
+		flags, _, err = ParseDockerImages([]string{"prune", "--every", "OFF"})
+		if err != nil || flags.Every != "off" {
+			t.Fatalf("ParseDockerImages every off = %#v, %v, want off", flags, err)
+		}
+
+		flags, args, err = ParseDockerImages([]string{"--format=json-pretty", "svc"})
+		if err != nil || flags.Format != "json-pretty" || strings.Join(args, " ") != "svc" {
+			t.Fatalf("ParseDockerImages list = %#v %q, %v; want json-pretty svc", flags, args, err)
+		}
+		if _, args, err = ParseDockerImages(nil); err != nil || len(args) != 0 {
+			t.Fatalf("ParseDockerImages() = %q, %v; want no args", args, err)
+		}
+
+		for _, args := range [][]string{
+			{"svc", "--dry-run"},
+			{"--every=24h"},
+			{"svc-a", "svc-b"},
+			{"prune", "extra"},
+			{"prune", "--every=10m"},
+			{"prune", "--every="},
+			{"prune", "--every=24h", "--dry-run"},
+			{"prune", "--format=yaml"},
+		} {
+			if _, _, err := ParseDockerImages(args); err == nil {
+				t.Fatalf("ParseDockerImages(%q) succeeded, want error", args)
+			}
+		}
+	})
+
 	t.Run("docker update", func(t *testing.T) {
 		flags, args, err := ParseDockerUpdate([]string{"--outdated"})
 		if err != nil {
@@ -2624,6 +3079,31 @@ func TestParseAdditionalCommandFlags(t *testing.T) {
 			t.Fatalf("ParseMount args = %q, want server:/export data", got)
 		}
 
+		flags, _, err = ParseMount([]string{"--type=sshfs", "--host-key", "ssh-ed25519 AAAAC3Nz", "backup@host:/srv"})
+		if err != nil {
+			t.Fatalf("ParseMount host key: %v", err)
+		}
+		if flags.HostKey != "ssh-ed25519 AAAAC3Nz" {
+			t.Fatalf("mount host key = %q, want ssh-ed25519 AAAAC3Nz", flags.HostKey)
+		}
+
+		flags, _, err = ParseMount([]string{
+			"--type=cifs",
+			"--credentials=-",
+			"--services", "api,worker",
+			"--services=db",
+			"//nas/share",
+		})
+		if err != nil {
+			t.Fatalf("ParseMount services: %v", err)
+		}
+		if flags.Credentials != "-" {
+			t.Fatalf("mount credentials = %q, want -", flags.Credentials)
+		}
+		if want := []string{"api", "worker", "db"}; !reflect.DeepEqual(flags.Services, want) {
+			t.Fatalf("mount services = %v, want %v", flags.Services, want)
+		}
+
 		flags, args, err = ParseMount(nil)
 		if err != nil {
 			t.Fatalf("ParseMount default: %v", err)
@@ -2732,6 +3212,23 @@ func TestParseVMImagesPrune(t *testing.T) {
 	}
 }
 
+func TestParseVMImagesCapture(t *testing.T) {
+	flags, args, err := ParseVMImages([]string{"capture", "devbox", "local/golden", "--snapshot", "a1b2"})
+	if err != nil {
+		t.Fatalf("ParseVMImages capture: %v", err)
+	}
+	if flags.Snapshot != "a1b2" {
+		t.Fatalf("Snapshot = %q, want a1b2", flags.Snapshot)
+	}
+	want := []string{"capture", "devbox", "local/golden"}
+	if !reflect.DeepEqual(args, want) {
+		t.Fatalf("args = %#v, want %#v", args, want)
+	}
+	if _, _, err := ParseVMImages([]string{"import", "foo", "./dir", "--snapshot=a1b2"}); err == nil {
+		t.Fatal("ParseVMImages accepted --snapshot outside capture")
+	}
+}
+
 func TestParseVMImagesListAlias(t *testing.T) {
 	flags, args, err := ParseVMImages([]string{"ls", "--output=json"})
 	if err != nil {
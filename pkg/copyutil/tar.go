@@ -123,6 +123,19 @@ func TarDirectoryWithOptions(w io.Writer, src string, prefix string, opts TarOpt
 	tw := tar.NewWriter(w)
 	defer closeTarWriter(tw, &err)
 
+	return AppendDirectory(tw, src, prefix, opts)
+}
+
+// AppendDirectory writes the entries of src into an existing tar writer.
+// Callers own tw and must close it once all entries have been written.
+func AppendDirectory(tw *tar.Writer, src string, prefix string, opts TarOptions) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("expected directory, got %q", src)
+	}
 	src = filepath.Clean(src)
 	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -164,6 +177,25 @@ func TarFileWithObserver(w io.Writer, src string, name string, observer TarObser
 	tw := tar.NewWriter(w)
 	defer closeTarWriter(tw, &err)
 
+	return appendFile(tw, src, name, info, observer)
+}
+
+// AppendFile writes a single file or symlink entry into an existing tar writer.
+func AppendFile(tw *tar.Writer, src string, name string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("expected file, got directory %q", src)
+	}
+	if name == "" {
+		name = filepath.Base(src)
+	}
+	return appendFile(tw, src, name, info, nil)
+}
+
+func appendFile(tw *tar.Writer, src string, name string, info fs.FileInfo, observer TarObserver) error {
 	hdr, err := tarHeaderForPath(src, info)
 	if err != nil {
 		return err
@@ -161,6 +161,45 @@ func TestTarDirectoryWithOptionsFiltersEntries(t *testing.T) {
 	}
 }
 
+func TestAppendDirectoryAndFileShareTarWriter(t *testing.T) {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "data"), 0o755); err != nil {
+		t.Fatalf("MkdirAll data: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "data", "state.db"), []byte("state"), 0o600); err != nil {
+		t.Fatalf("write state file: %v", err)
+	}
+	single := filepath.Join(t.TempDir(), "payload")
+	if err := os.WriteFile(single, []byte("payload"), 0o755); err != nil {
+		t.Fatalf("write payload file: %v", err)
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := AppendFile(tw, single, "payload/app"); err != nil {
+		t.Fatalf("AppendFile returned error: %v", err)
+	}
+	if err := AppendDirectory(tw, src, "root", TarOptions{}); err != nil {
+		t.Fatalf("AppendDirectory returned error: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar writer: %v", err)
+	}
+
+	dest := t.TempDir()
+	if err := ExtractTar(&buf, dest); err != nil {
+		t.Fatalf("ExtractTar returned error: %v", err)
+	}
+	assertFileContents(t, filepath.Join(dest, "payload", "app"), "payload")
+	assertFileContents(t, filepath.Join(dest, "root", "data", "state.db"), "state")
+	if err := AppendFile(tar.NewWriter(io.Discard), src, "dir"); err == nil || !strings.Contains(err.Error(), "expected file") {
+		t.Fatalf("AppendFile directory error = %v, want expected file", err)
+	}
+	if err := AppendDirectory(tar.NewWriter(io.Discard), single, "", TarOptions{}); err == nil || !strings.Contains(err.Error(), "expected directory") {
+		t.Fatalf("AppendDirectory file error = %v, want expected directory", err)
+	}
+}
+
 func TestExtractTarRejectsDangerousPaths(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -27,7 +27,7 @@ var (
 	syncDBDirectory = func(f *os.File) error { return f.Sync() }
 )
 
-//go:generate go run tailscale.com/cmd/viewer -type=Data,Service,ServiceIdentity,ServiceSandboxStore,ServiceSandboxPolicy,ServiceSandboxExposure,SnapshotPolicy,Volume,ImageRepo,Artifact,DockerNetwork,DockerEndpoint,TailscaleNetwork,EndpointPort,VMConfig,VMImageConfig,VMDiskConfig,VMNetworkConfig,VMSSHConfig,VMConsoleConfig,VMSocketConfig,VMBalloonConfig,VMHostConfig,ISOPool,ISOAllocation,ISOComponent,VMGuestBaseConfig,VMKernelArtifactConfig,VMRuntimeArtifactConfig,VMRuntimeTrialConfig,VMRuntimeLifecycleConfig,VMComponentsConfig,ServiceNetworkConfig --copyright=false
+//go:generate go run tailscale.com/cmd/viewer -type=Data,Service,ServiceIdentity,ServiceSandboxStore,ServiceSandboxPolicy,ServiceSandboxExposure,SnapshotPolicy,Volume,ImageRepo,Artifact,DockerNetwork,DockerEndpoint,TailscaleNetwork,EndpointPort,VMConfig,VMImageConfig,VMDiskConfig,VMDataDiskConfig,VMNetworkConfig,VMSSHConfig,VMConsoleConfig,VMSocketConfig,VMBalloonConfig,VMHostConfig,ISOPool,ISOAllocation,ISOComponent,VMGuestBaseConfig,VMKernelArtifactConfig,VMRuntimeArtifactConfig,VMRuntimeTrialConfig,VMRuntimeLifecycleConfig,VMComponentsConfig,VMHibernationConfig,VMPowerConfig,ServiceNetworkConfig,RegistryGCConfig,RegistryMirrorConfig,ImagePolicyConfig,AutoUpdateConfig,AutoUpdateResult --copyright=false
 
 // Data is the full JSON structure of the database.
 type Data struct {
@@ -38,6 +38,15 @@ type Data struct {
 	SnapshotDefaults *SnapshotPolicy `json:",omitempty"`
 	VMHost           *VMHostConfig   `json:",omitempty"`
 	ISOPool          *ISOPool        `json:",omitempty"`
+	// RegistryGC schedules garbage collection of the internal registry.
+	RegistryGC *RegistryGCConfig `json:",omitempty"`
+	// RegistryMirror enables the pull-through cache for an upstream registry.
+	RegistryMirror *RegistryMirrorConfig `json:",omitempty"`
+	// ContainerRuntime is the engine compose services run on: docker, the
+	// default when empty, or podman.
+	ContainerRuntime string `json:",omitempty"`
+	// ImagePolicy holds the keys compose images must be signed with.
+	ImagePolicy *ImagePolicyConfig `json:",omitempty"`
 
 	Services map[string]*Service
 
@@ -46,6 +55,10 @@ type Data struct {
 	Volumes map[string]*Volume
 
 	DockerNetworks map[string]*DockerNetwork
+
+	// VMDetachedDisks holds VM data disks detached with `vm disk rm --keep`,
+	// keyed by detached disk name.
+	VMDetachedDisks map[string]*VMDataDiskConfig `json:",omitempty"`
 }
 
 type ISOPool struct {
@@ -92,6 +105,31 @@ type VMHostConfig struct {
 	ProtectedRuntimeIDs []string `json:",omitempty"`
 }
 
+// RegistryGCConfig is the periodic `docker images prune` schedule.
+type RegistryGCConfig struct {
+	// Interval is how often garbage collection runs; empty disables it.
+	Interval string `json:",omitempty"`
+	// LastRun is when periodic garbage collection last finished, in RFC 3339.
+	LastRun string `json:",omitempty"`
+}
+
+// RegistryMirrorConfig is the upstream set with `host set --registry-mirror`.
+type RegistryMirrorConfig struct {
+	// Upstream is the mirrored registry host, e.g. docker.io.
+	Upstream string
+}
+
+// ImagePolicyConfig is the image trust policy set with `host set
+// --image-policy --trust-key`.
+type ImagePolicyConfig struct {
+	// Mode is require-signed when every compose service's images must be
+	// signed; empty leaves it to each service.
+	Mode string `json:",omitempty"`
+	// TrustKeys are the PEM encoded public keys signatures are checked
+	// against.
+	TrustKeys []string `json:",omitempty"`
+}
+
 type DockerNetwork struct {
 	NetworkID string
 	NetNS     string
@@ -144,6 +182,8 @@ type ImageRepo struct {
 type ImageManifest struct {
 	ContentType string
 	BlobHash    string
+	// PushedAt is when the manifest was pushed, in RFC 3339.
+	PushedAt string `json:",omitempty"`
 }
 
 type Volume struct {
@@ -154,6 +194,16 @@ type Volume struct {
 	Type string
 	Opts string
 	Deps string
+
+	// Credentials is the catch-managed file holding CIFS credentials or an
+	// sshfs private key.
+	Credentials string `json:",omitempty"`
+	// KnownHosts is the catch-managed known_hosts file an sshfs mount checks
+	// the server's host key against.
+	KnownHosts string `json:",omitempty"`
+	// Services lists, space separated, the services whose units require
+	// this mount.
+	Services string `json:",omitempty"`
 }
 
 type ServiceType string
@@ -221,6 +271,22 @@ type Service struct {
 	// Nil means all snapshot settings inherit from server defaults.
 	SnapshotPolicy *SnapshotPolicy `json:",omitempty"`
 
+	// QuotaBytes limits the size of the service root. Zero means no limit.
+	QuotaBytes int64 `json:",omitempty"`
+
+	// QuotaProjectID is the filesystem project ID that enforces QuotaBytes on
+	// xfs or ext4 roots. It is kept after the limit is removed so the same ID
+	// is reused when a limit is set again.
+	QuotaProjectID uint32 `json:",omitempty"`
+
+	// AutoUpdate is the `service set --auto-update` policy of a compose
+	// service. Nil means images are only updated on request.
+	AutoUpdate *AutoUpdateConfig `json:",omitempty"`
+
+	// ImagePolicy is the `service set --image-policy` override of the host
+	// image policy: require-signed or off. Empty inherits it.
+	ImagePolicy string `json:",omitempty"`
+
 	// Generation is the current generation of the service.
 	Generation int `json:",omitempty"`
 
@@ -264,6 +330,9 @@ type VMConfig struct {
 	MemoryBytes int64
 	Balloon     VMBalloonConfig
 	Disk        VMDiskConfig
+	// DataDisks are attached after the root disk, in order, so the first
+	// data disk is /dev/vdb in the guest.
+	DataDisks []VMDataDiskConfig `json:",omitempty"`
 
 	Networks []VMNetworkConfig
 	SSH      VMSSHConfig
@@ -272,6 +341,63 @@ type VMConfig struct {
 
 	PIDFile    string `json:",omitempty"`
 	SetupState string `json:",omitempty"`
+	// UserDataSHA256 is the digest of the first-boot user-data the VM was
+	// provisioned with, if any.
+	UserDataSHA256 string `json:",omitempty"`
+
+	Hibernation *VMHibernationConfig `json:",omitempty"`
+	Power       *VMPowerConfig       `json:",omitempty"`
+}
+
+// VMPowerConfig holds the `vm set` power policy. The zero value starts the
+// VM at boot and shuts it down gracefully with the default timeout.
+type VMPowerConfig struct {
+	// NoAutostart leaves the VM unit disabled so it stays stopped at boot.
+	NoAutostart bool `json:",omitempty"`
+	// StartOrder orders boot: a VM starts after autostart VMs with a lower
+	// order.
+	StartOrder int `json:",omitempty"`
+	// ShutdownTimeout is how long the guest agent gets to shut the guest
+	// down before Firecracker is killed; "0s" skips the agent.
+	ShutdownTimeout string `json:",omitempty"`
+	// IdleStop stops the VM after this long without network traffic or SSH
+	// sessions.
+	IdleStop string `json:",omitempty"`
+}
+
+// AutoUpdateConfig schedules upstream image checks for a compose service.
+type AutoUpdateConfig struct {
+	// Mode is notify, which only reports new images, or apply, which also
+	// updates the service and rolls it back if it does not come up.
+	Mode string
+	// Window is when checks run in catch's local time: "Sun 03:00" weekly or
+	// "03:00" daily. Empty means daily at 03:00.
+	Window string `json:",omitempty"`
+	// LastCheck is when the last scheduled check started, in RFC 3339.
+	LastCheck string `json:",omitempty"`
+	// History holds the most recent check results, oldest first.
+	History []AutoUpdateResult `json:",omitempty"`
+}
+
+// AutoUpdateResult is the outcome of one scheduled image check.
+type AutoUpdateResult struct {
+	// Time is when the check finished, in RFC 3339.
+	Time string
+	// Result is available, updated, rolled-back or failed.
+	Result string
+	// Images names the images that had updates, comma-separated.
+	Images string `json:",omitempty"`
+	// Error explains a failed check or the reason for a rollback.
+	Error string `json:",omitempty"`
+}
+
+// VMHibernationConfig tracks a Firecracker memory snapshot taken by
+// `vm hibernate` and whether the VM hibernates when the host shuts down.
+type VMHibernationConfig struct {
+	StatePath      string `json:",omitempty"`
+	MemoryPath     string `json:",omitempty"`
+	CreatedAt      string `json:",omitempty"`
+	OnHostShutdown bool   `json:",omitempty"`
 }
 
 type VMGuestBaseConfig struct {
@@ -349,6 +475,17 @@ type VMDiskConfig struct {
 	Path    string
 }
 
+// VMDataDiskConfig is an ext4 data disk attached to a VM alongside its root
+// disk, or a detached disk waiting to be attached to another VM.
+type VMDataDiskConfig struct {
+	Name    string
+	Backend string
+	Bytes   int64
+	Path    string
+	// DetachedFrom records the VM a detached disk was last attached to.
+	DetachedFrom string `json:",omitempty"`
+}
+
 type VMNetworkConfig struct {
 	Mode      string
 	Interface string
@@ -412,29 +549,23 @@ func Gen(gen int) ArtifactRef {
 type ArtifactStore map[ArtifactName]*Artifact
 
 func (as ArtifactStore) Gen(name ArtifactName, gen int) (string, bool) {
-	a, ok := as[name]
-	if !ok {
-		return "", false
-	}
-	r, ok := a.Refs[Gen(gen)]
-	return r, ok
+	return as.ref(name, Gen(gen))
 }
 
 func (as ArtifactStore) Staged(name ArtifactName) (string, bool) {
-	a, ok := as[name]
-	if !ok {
-		return "", false
-	}
-	r, ok := a.Refs["staged"]
-	return r, ok
+	return as.ref(name, "staged")
 }
 
 func (as ArtifactStore) Latest(name ArtifactName) (string, bool) {
+	return as.ref(name, "latest")
+}
+
+func (as ArtifactStore) ref(name ArtifactName, ref ArtifactRef) (string, bool) {
 	a, ok := as[name]
 	if !ok {
 		return "", false
 	}
-	r, ok := a.Refs["latest"]
+	r, ok := a.Refs[ref]
 	return r, ok
 }
 
@@ -462,8 +593,42 @@ const (
 	ArtifactTSBinary     ArtifactName = "tailscaled"
 	ArtifactTSConfig     ArtifactName = "tailscaled.json"
 	ArtifactNetNSResolv  ArtifactName = "resolv.conf"
+
+	// ArtifactDockerComposeProfiles lists the compose profiles enabled for a
+	// generation, one per line.
+	ArtifactDockerComposeProfiles ArtifactName = "compose.profiles"
 )
 
+// ArtifactDockerComposeOverride names the n-th compose override file layered
+// on ArtifactDockerComposeFile. Overrides are numbered from zero in the order
+// compose applies them.
+func ArtifactDockerComposeOverride(n int) ArtifactName {
+	return ArtifactName(fmt.Sprintf("compose.override.%d", n))
+}
+
+// ArtifactDockerComposeEnv names the n-th compose interpolation env file.
+func ArtifactDockerComposeEnv(n int) ArtifactName {
+	return ArtifactName(fmt.Sprintf("compose.env.%d", n))
+}
+
+// ComposeFiles returns the compose files recorded under ref: the
+// ArtifactDockerComposeFile followed by its overrides in the order compose
+// applies them. It returns nil when ref has no compose file.
+func (as ArtifactStore) ComposeFiles(ref ArtifactRef) []string {
+	base, ok := as.ref(ArtifactDockerComposeFile, ref)
+	if !ok {
+		return nil
+	}
+	files := []string{base}
+	for n := 0; ; n++ {
+		path, ok := as.ref(ArtifactDockerComposeOverride(n), ref)
+		if !ok {
+			return files
+		}
+		files = append(files, path)
+	}
+}
+
 // ArtifactRef is a reference to an artifact.
 //
 // It's either "latest", "staged", or a generation number like "gen-23".
@@ -26,6 +26,13 @@ func (src *Data) Clone() *Data {
 	if dst.ISOPool != nil {
 		dst.ISOPool = ptr.To(*src.ISOPool)
 	}
+	if dst.RegistryGC != nil {
+		dst.RegistryGC = ptr.To(*src.RegistryGC)
+	}
+	if dst.RegistryMirror != nil {
+		dst.RegistryMirror = ptr.To(*src.RegistryMirror)
+	}
+	dst.ImagePolicy = src.ImagePolicy.Clone()
 	if dst.Services != nil {
 		dst.Services = map[string]*Service{}
 		for k, v := range src.Services {
@@ -66,6 +73,16 @@ func (src *Data) Clone() *Data {
 			}
 		}
 	}
+	if dst.VMDetachedDisks != nil {
+		dst.VMDetachedDisks = map[string]*VMDataDiskConfig{}
+		for k, v := range src.VMDetachedDisks {
+			if v == nil {
+				dst.VMDetachedDisks[k] = nil
+			} else {
+				dst.VMDetachedDisks[k] = ptr.To(*v)
+			}
+		}
+	}
 	return dst
 }
 
@@ -75,10 +92,15 @@ var _DataCloneNeedsRegeneration = Data(struct {
 	SnapshotDefaults *SnapshotPolicy
 	VMHost           *VMHostConfig
 	ISOPool          *ISOPool
+	RegistryGC       *RegistryGCConfig
+	RegistryMirror   *RegistryMirrorConfig
+	ContainerRuntime string
+	ImagePolicy      *ImagePolicyConfig
 	Services         map[string]*Service
 	Images           map[ImageRepoName]*ImageRepo
 	Volumes          map[string]*Volume
 	DockerNetworks   map[string]*DockerNetwork
+	VMDetachedDisks  map[string]*VMDataDiskConfig
 }{})
 
 // Clone makes a deep copy of Service.
@@ -94,6 +116,7 @@ func (src *Service) Clone() *Service {
 	}
 	dst.Sandbox = src.Sandbox.Clone()
 	dst.SnapshotPolicy = src.SnapshotPolicy.Clone()
+	dst.AutoUpdate = src.AutoUpdate.Clone()
 	dst.Publish = append(src.Publish[:0:0], src.Publish...)
 	if dst.Artifacts != nil {
 		dst.Artifacts = map[ArtifactName]*Artifact{}
@@ -128,6 +151,10 @@ var _ServiceCloneNeedsRegeneration = Service(struct {
 	ServiceRoot            string
 	ServiceRootZFS         string
 	SnapshotPolicy         *SnapshotPolicy
+	QuotaBytes             int64
+	QuotaProjectID         uint32
+	AutoUpdate             *AutoUpdateConfig
+	ImagePolicy            string
 	Generation             int
 	LatestGeneration       int
 	Publish                []string
@@ -265,12 +292,15 @@ func (src *Volume) Clone() *Volume {
 
 // A compilation failure here means this code must be regenerated, with the command at the top of this file.
 var _VolumeCloneNeedsRegeneration = Volume(struct {
-	Name string
-	Src  string
-	Path string
-	Type string
-	Opts string
-	Deps string
+	Name        string
+	Src         string
+	Path        string
+	Type        string
+	Opts        string
+	Deps        string
+	Credentials string
+	KnownHosts  string
+	Services    string
 }{})
 
 // Clone makes a deep copy of ImageRepo.
@@ -415,25 +445,36 @@ func (src *VMConfig) Clone() *VMConfig {
 	dst := new(VMConfig)
 	*dst = *src
 	dst.Components = src.Components.Clone()
+	dst.DataDisks = append(src.DataDisks[:0:0], src.DataDisks...)
 	dst.Networks = append(src.Networks[:0:0], src.Networks...)
+	if dst.Hibernation != nil {
+		dst.Hibernation = ptr.To(*src.Hibernation)
+	}
+	if dst.Power != nil {
+		dst.Power = ptr.To(*src.Power)
+	}
 	return dst
 }
 
 // A compilation failure here means this code must be regenerated, with the command at the top of this file.
 var _VMConfigCloneNeedsRegeneration = VMConfig(struct {
-	Runtime     string
-	Image       VMImageConfig
-	Components  *VMComponentsConfig
-	CPUs        int
-	MemoryBytes int64
-	Balloon     VMBalloonConfig
-	Disk        VMDiskConfig
-	Networks    []VMNetworkConfig
-	SSH         VMSSHConfig
-	Console     VMConsoleConfig
-	Sockets     VMSocketConfig
-	PIDFile     string
-	SetupState  string
+	Runtime        string
+	Image          VMImageConfig
+	Components     *VMComponentsConfig
+	CPUs           int
+	MemoryBytes    int64
+	Balloon        VMBalloonConfig
+	Disk           VMDiskConfig
+	DataDisks      []VMDataDiskConfig
+	Networks       []VMNetworkConfig
+	SSH            VMSSHConfig
+	Console        VMConsoleConfig
+	Sockets        VMSocketConfig
+	PIDFile        string
+	SetupState     string
+	UserDataSHA256 string
+	Hibernation    *VMHibernationConfig
+	Power          *VMPowerConfig
 }{})
 
 // Clone makes a deep copy of VMImageConfig.
@@ -479,6 +520,26 @@ var _VMDiskConfigCloneNeedsRegeneration = VMDiskConfig(struct {
 	Path    string
 }{})
 
+// Clone makes a deep copy of VMDataDiskConfig.
+// The result aliases no memory with the original.
+func (src *VMDataDiskConfig) Clone() *VMDataDiskConfig {
+	if src == nil {
+		return nil
+	}
+	dst := new(VMDataDiskConfig)
+	*dst = *src
+	return dst
+}
+
+// A compilation failure here means this code must be regenerated, with the command at the top of this file.
+var _VMDataDiskConfigCloneNeedsRegeneration = VMDataDiskConfig(struct {
+	Name         string
+	Backend      string
+	Bytes        int64
+	Path         string
+	DetachedFrom string
+}{})
+
 // Clone makes a deep copy of VMNetworkConfig.
 // The result aliases no memory with the original.
 func (src *VMNetworkConfig) Clone() *VMNetworkConfig {
@@ -800,6 +861,44 @@ var _VMComponentsConfigCloneNeedsRegeneration = VMComponentsConfig(struct {
 	Runtime   VMRuntimeLifecycleConfig
 }{})
 
+// Clone makes a deep copy of VMHibernationConfig.
+// The result aliases no memory with the original.
+func (src *VMHibernationConfig) Clone() *VMHibernationConfig {
+	if src == nil {
+		return nil
+	}
+	dst := new(VMHibernationConfig)
+	*dst = *src
+	return dst
+}
+
+// A compilation failure here means this code must be regenerated, with the command at the top of this file.
+var _VMHibernationConfigCloneNeedsRegeneration = VMHibernationConfig(struct {
+	StatePath      string
+	MemoryPath     string
+	CreatedAt      string
+	OnHostShutdown bool
+}{})
+
+// Clone makes a deep copy of VMPowerConfig.
+// The result aliases no memory with the original.
+func (src *VMPowerConfig) Clone() *VMPowerConfig {
+	if src == nil {
+		return nil
+	}
+	dst := new(VMPowerConfig)
+	*dst = *src
+	return dst
+}
+
+// A compilation failure here means this code must be regenerated, with the command at the top of this file.
+var _VMPowerConfigCloneNeedsRegeneration = VMPowerConfig(struct {
+	NoAutostart     bool
+	StartOrder      int
+	ShutdownTimeout string
+	IdleStop        string
+}{})
+
 // Clone makes a deep copy of ServiceNetworkConfig.
 // The result aliases no memory with the original.
 func (src *ServiceNetworkConfig) Clone() *ServiceNetworkConfig {
@@ -823,3 +922,93 @@ var _ServiceNetworkConfigCloneNeedsRegeneration = ServiceNetworkConfig(struct {
 	MacvlanVLAN   int
 	MacvlanMAC    string
 }{})
+
+// Clone makes a deep copy of RegistryGCConfig.
+// The result aliases no memory with the original.
+func (src *RegistryGCConfig) Clone() *RegistryGCConfig {
+	if src == nil {
+		return nil
+	}
+	dst := new(RegistryGCConfig)
+	*dst = *src
+	return dst
+}
+
+// A compilation failure here means this code must be regenerated, with the command at the top of this file.
+var _RegistryGCConfigCloneNeedsRegeneration = RegistryGCConfig(struct {
+	Interval string
+	LastRun  string
+}{})
+
+// Clone makes a deep copy of RegistryMirrorConfig.
+// The result aliases no memory with the original.
+func (src *RegistryMirrorConfig) Clone() *RegistryMirrorConfig {
+	if src == nil {
+		return nil
+	}
+	dst := new(RegistryMirrorConfig)
+	*dst = *src
+	return dst
+}
+
+// A compilation failure here means this code must be regenerated, with the command at the top of this file.
+var _RegistryMirrorConfigCloneNeedsRegeneration = RegistryMirrorConfig(struct {
+	Upstream string
+}{})
+
+// Clone makes a deep copy of ImagePolicyConfig.
+// The result aliases no memory with the original.
+func (src *ImagePolicyConfig) Clone() *ImagePolicyConfig {
+	if src == nil {
+		return nil
+	}
+	dst := new(ImagePolicyConfig)
+	*dst = *src
+	dst.TrustKeys = append(src.TrustKeys[:0:0], src.TrustKeys...)
+	return dst
+}
+
+// A compilation failure here means this code must be regenerated, with the command at the top of this file.
+var _ImagePolicyConfigCloneNeedsRegeneration = ImagePolicyConfig(struct {
+	Mode      string
+	TrustKeys []string
+}{})
+
+// Clone makes a deep copy of AutoUpdateConfig.
+// The result aliases no memory with the original.
+func (src *AutoUpdateConfig) Clone() *AutoUpdateConfig {
+	if src == nil {
+		return nil
+	}
+	dst := new(AutoUpdateConfig)
+	*dst = *src
+	dst.History = append(src.History[:0:0], src.History...)
+	return dst
+}
+
+// A compilation failure here means this code must be regenerated, with the command at the top of this file.
+var _AutoUpdateConfigCloneNeedsRegeneration = AutoUpdateConfig(struct {
+	Mode      string
+	Window    string
+	LastCheck string
+	History   []AutoUpdateResult
+}{})
+
+// Clone makes a deep copy of AutoUpdateResult.
+// The result aliases no memory with the original.
+func (src *AutoUpdateResult) Clone() *AutoUpdateResult {
+	if src == nil {
+		return nil
+	}
+	dst := new(AutoUpdateResult)
+	*dst = *src
+	return dst
+}
+
+// A compilation failure here means this code must be regenerated, with the command at the top of this file.
+var _AutoUpdateResultCloneNeedsRegeneration = AutoUpdateResult(struct {
+	Time   string
+	Result string
+	Images string
+	Error  string
+}{})
@@ -1931,6 +1931,23 @@ func TestArtifactStoreRefs(t *testing.T) {
 	}
 }
 
+func TestArtifactStoreComposeFiles(t *testing.T) {
+	refs := ArtifactStore{
+		ArtifactDockerComposeFile:        {Refs: map[ArtifactRef]string{Gen(2): "/c/gen-2", Gen(3): "/c/gen-3"}},
+		ArtifactDockerComposeOverride(0): {Refs: map[ArtifactRef]string{Gen(3): "/o0/gen-3"}},
+		ArtifactDockerComposeOverride(1): {Refs: map[ArtifactRef]string{Gen(3): "/o1/gen-3"}},
+	}
+	if got, want := refs.ComposeFiles(Gen(3)), []string{"/c/gen-3", "/o0/gen-3", "/o1/gen-3"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ComposeFiles(gen-3) = %q, want %q", got, want)
+	}
+	if got, want := refs.ComposeFiles(Gen(2)), []string{"/c/gen-2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ComposeFiles(gen-2) = %q, want %q", got, want)
+	}
+	if got := refs.ComposeFiles("staged"); got != nil {
+		t.Fatalf("ComposeFiles(staged) = %q, want nil", got)
+	}
+}
+
 func TestProtoPortParseAndString(t *testing.T) {
 	var pp ProtoPort
 	if err := pp.Parse("6/443"); err != nil {
@@ -13,7 +13,7 @@ import (
 	"tailscale.com/types/views"
 )
 
-//go:generate go run tailscale.com/cmd/cloner  -clonefunc=false -type=Data,Service,ServiceIdentity,ServiceSandboxStore,ServiceSandboxPolicy,ServiceSandboxExposure,SnapshotPolicy,Volume,ImageRepo,Artifact,DockerNetwork,DockerEndpoint,TailscaleNetwork,EndpointPort,VMConfig,VMImageConfig,VMDiskConfig,VMNetworkConfig,VMSSHConfig,VMConsoleConfig,VMSocketConfig,VMBalloonConfig,VMHostConfig,ISOPool,ISOAllocation,ISOComponent,VMGuestBaseConfig,VMKernelArtifactConfig,VMRuntimeArtifactConfig,VMRuntimeTrialConfig,VMRuntimeLifecycleConfig,VMComponentsConfig,ServiceNetworkConfig
+//go:generate go run tailscale.com/cmd/cloner  -clonefunc=false -type=Data,Service,ServiceIdentity,ServiceSandboxStore,ServiceSandboxPolicy,ServiceSandboxExposure,SnapshotPolicy,Volume,ImageRepo,Artifact,DockerNetwork,DockerEndpoint,TailscaleNetwork,EndpointPort,VMConfig,VMImageConfig,VMDiskConfig,VMDataDiskConfig,VMNetworkConfig,VMSSHConfig,VMConsoleConfig,VMSocketConfig,VMBalloonConfig,VMHostConfig,ISOPool,ISOAllocation,ISOComponent,VMGuestBaseConfig,VMKernelArtifactConfig,VMRuntimeArtifactConfig,VMRuntimeTrialConfig,VMRuntimeLifecycleConfig,VMComponentsConfig,VMHibernationConfig,VMPowerConfig,ServiceNetworkConfig,RegistryGCConfig,RegistryMirrorConfig,ImagePolicyConfig,AutoUpdateConfig,AutoUpdateResult
 
 // View returns a read-only view of Data.
 func (p *Data) View() DataView {
@@ -88,6 +88,19 @@ func (v DataView) DataVersion() int                     { return v.ж.DataVersio
 func (v DataView) SnapshotDefaults() SnapshotPolicyView { return v.ж.SnapshotDefaults.View() }
 func (v DataView) VMHost() VMHostConfigView             { return v.ж.VMHost.View() }
 func (v DataView) ISOPool() ISOPoolView                 { return v.ж.ISOPool.View() }
+
+// RegistryGC schedules garbage collection of the internal registry.
+func (v DataView) RegistryGC() RegistryGCConfigView { return v.ж.RegistryGC.View() }
+
+// RegistryMirror enables the pull-through cache for an upstream registry.
+func (v DataView) RegistryMirror() RegistryMirrorConfigView { return v.ж.RegistryMirror.View() }
+
+// ContainerRuntime is the engine compose services run on: docker, the
+// default when empty, or podman.
+func (v DataView) ContainerRuntime() string { return v.ж.ContainerRuntime }
+
+// ImagePolicy holds the keys compose images must be signed with.
+func (v DataView) ImagePolicy() ImagePolicyConfigView { return v.ж.ImagePolicy.View() }
 func (v DataView) Services() views.MapFn[string, *Service, ServiceView] {
 	return views.MapFnOf(v.ж.Services, func(t *Service) ServiceView {
 		return t.View()
@@ -109,16 +122,29 @@ func (v DataView) DockerNetworks() views.MapFn[string, *DockerNetwork, DockerNet
 	})
 }
 
+// VMDetachedDisks holds VM data disks detached with `vm disk rm --keep`,
+// keyed by detached disk name.
+func (v DataView) VMDetachedDisks() views.MapFn[string, *VMDataDiskConfig, VMDataDiskConfigView] {
+	return views.MapFnOf(v.ж.VMDetachedDisks, func(t *VMDataDiskConfig) VMDataDiskConfigView {
+		return t.View()
+	})
+}
+
 // A compilation failure here means this code must be regenerated, with the command at the top of this file.
 var _DataViewNeedsRegeneration = Data(struct {
 	DataVersion      int
 	SnapshotDefaults *SnapshotPolicy
 	VMHost           *VMHostConfig
 	ISOPool          *ISOPool
+	RegistryGC       *RegistryGCConfig
+	RegistryMirror   *RegistryMirrorConfig
+	ContainerRuntime string
+	ImagePolicy      *ImagePolicyConfig
 	Services         map[string]*Service
 	Images           map[ImageRepoName]*ImageRepo
 	Volumes          map[string]*Volume
 	DockerNetworks   map[string]*DockerNetwork
+	VMDetachedDisks  map[string]*VMDataDiskConfig
 }{})
 
 // View returns a read-only view of Service.
@@ -210,6 +236,22 @@ func (v ServiceView) ServiceRootZFS() string { return v.ж.ServiceRootZFS }
 // Nil means all snapshot settings inherit from server defaults.
 func (v ServiceView) SnapshotPolicy() SnapshotPolicyView { return v.ж.SnapshotPolicy.View() }
 
+// QuotaBytes limits the size of the service root. Zero means no limit.
+func (v ServiceView) QuotaBytes() int64 { return v.ж.QuotaBytes }
+
+// QuotaProjectID is the filesystem project ID that enforces QuotaBytes on
+// xfs or ext4 roots. It is kept after the limit is removed so the same ID
+// is reused when a limit is set again.
+func (v ServiceView) QuotaProjectID() uint32 { return v.ж.QuotaProjectID }
+
+// AutoUpdate is the `service set --auto-update` policy of a compose
+// service. Nil means images are only updated on request.
+func (v ServiceView) AutoUpdate() AutoUpdateConfigView { return v.ж.AutoUpdate.View() }
+
+// ImagePolicy is the `service set --image-policy` override of the host
+// image policy: require-signed or off. Empty inherits it.
+func (v ServiceView) ImagePolicy() string { return v.ж.ImagePolicy }
+
 // Generation is the current generation of the service.
 func (v ServiceView) Generation() int { return v.ж.Generation }
 
@@ -251,6 +293,10 @@ var _ServiceViewNeedsRegeneration = Service(struct {
 	ServiceRoot            string
 	ServiceRootZFS         string
 	SnapshotPolicy         *SnapshotPolicy
+	QuotaBytes             int64
+	QuotaProjectID         uint32
+	AutoUpdate             *AutoUpdateConfig
+	ImagePolicy            string
 	Generation             int
 	LatestGeneration       int
 	Publish                []string
@@ -743,14 +789,29 @@ func (v VolumeView) Type() string { return v.ж.Type }
 func (v VolumeView) Opts() string { return v.ж.Opts }
 func (v VolumeView) Deps() string { return v.ж.Deps }
 
+// Credentials is the catch-managed file holding CIFS credentials or an
+// sshfs private key.
+func (v VolumeView) Credentials() string { return v.ж.Credentials }
+
+// KnownHosts is the catch-managed known_hosts file an sshfs mount checks
+// the server's host key against.
+func (v VolumeView) KnownHosts() string { return v.ж.KnownHosts }
+
+// Services lists, space separated, the services whose units require
+// this mount.
+func (v VolumeView) Services() string { return v.ж.Services }
+
 // A compilation failure here means this code must be regenerated, with the command at the top of this file.
 var _VolumeViewNeedsRegeneration = Volume(struct {
-	Name string
-	Src  string
-	Path string
-	Type string
-	Opts string
-	Deps string
+	Name        string
+	Src         string
+	Path        string
+	Type        string
+	Opts        string
+	Deps        string
+	Credentials string
+	KnownHosts  string
+	Services    string
 }{})
 
 // View returns a read-only view of ImageRepo.
@@ -1305,35 +1366,49 @@ func (v *VMConfigView) UnmarshalJSONFrom(dec *jsontext.Decoder) error {
 	return nil
 }
 
-func (v VMConfigView) Runtime() string                        { return v.ж.Runtime }
-func (v VMConfigView) Image() VMImageConfig                   { return v.ж.Image }
-func (v VMConfigView) Components() VMComponentsConfigView     { return v.ж.Components.View() }
-func (v VMConfigView) CPUs() int                              { return v.ж.CPUs }
-func (v VMConfigView) MemoryBytes() int64                     { return v.ж.MemoryBytes }
-func (v VMConfigView) Balloon() VMBalloonConfig               { return v.ж.Balloon }
-func (v VMConfigView) Disk() VMDiskConfig                     { return v.ж.Disk }
-func (v VMConfigView) Networks() views.Slice[VMNetworkConfig] { return views.SliceOf(v.ж.Networks) }
-func (v VMConfigView) SSH() VMSSHConfig                       { return v.ж.SSH }
-func (v VMConfigView) Console() VMConsoleConfig               { return v.ж.Console }
-func (v VMConfigView) Sockets() VMSocketConfig                { return v.ж.Sockets }
-func (v VMConfigView) PIDFile() string                        { return v.ж.PIDFile }
-func (v VMConfigView) SetupState() string                     { return v.ж.SetupState }
+func (v VMConfigView) Runtime() string                    { return v.ж.Runtime }
+func (v VMConfigView) Image() VMImageConfig               { return v.ж.Image }
+func (v VMConfigView) Components() VMComponentsConfigView { return v.ж.Components.View() }
+func (v VMConfigView) CPUs() int                          { return v.ж.CPUs }
+func (v VMConfigView) MemoryBytes() int64                 { return v.ж.MemoryBytes }
+func (v VMConfigView) Balloon() VMBalloonConfig           { return v.ж.Balloon }
+func (v VMConfigView) Disk() VMDiskConfig                 { return v.ж.Disk }
+
+// DataDisks are attached after the root disk, in order, so the first
+// data disk is /dev/vdb in the guest.
+func (v VMConfigView) DataDisks() views.Slice[VMDataDiskConfig] { return views.SliceOf(v.ж.DataDisks) }
+func (v VMConfigView) Networks() views.Slice[VMNetworkConfig]   { return views.SliceOf(v.ж.Networks) }
+func (v VMConfigView) SSH() VMSSHConfig                         { return v.ж.SSH }
+func (v VMConfigView) Console() VMConsoleConfig                 { return v.ж.Console }
+func (v VMConfigView) Sockets() VMSocketConfig                  { return v.ж.Sockets }
+func (v VMConfigView) PIDFile() string                          { return v.ж.PIDFile }
+func (v VMConfigView) SetupState() string                       { return v.ж.SetupState }
+
+// UserDataSHA256 is the digest of the first-boot user-data the VM was
+// provisioned with, if any.
+func (v VMConfigView) UserDataSHA256() string               { return v.ж.UserDataSHA256 }
+func (v VMConfigView) Hibernation() VMHibernationConfigView { return v.ж.Hibernation.View() }
+func (v VMConfigView) Power() VMPowerConfigView             { return v.ж.Power.View() }
 
 // A compilation failure here means this code must be regenerated, with the command at the top of this file.
 var _VMConfigViewNeedsRegeneration = VMConfig(struct {
-	Runtime     string
-	Image       VMImageConfig
-	Components  *VMComponentsConfig
-	CPUs        int
-	MemoryBytes int64
-	Balloon     VMBalloonConfig
-	Disk        VMDiskConfig
-	Networks    []VMNetworkConfig
-	SSH         VMSSHConfig
-	Console     VMConsoleConfig
-	Sockets     VMSocketConfig
-	PIDFile     string
-	SetupState  string
+	Runtime        string
+	Image          VMImageConfig
+	Components     *VMComponentsConfig
+	CPUs           int
+	MemoryBytes    int64
+	Balloon        VMBalloonConfig
+	Disk           VMDiskConfig
+	DataDisks      []VMDataDiskConfig
+	Networks       []VMNetworkConfig
+	SSH            VMSSHConfig
+	Console        VMConsoleConfig
+	Sockets        VMSocketConfig
+	PIDFile        string
+	SetupState     string
+	UserDataSHA256 string
+	Hibernation    *VMHibernationConfig
+	Power          *VMPowerConfig
 }{})
 
 // View returns a read-only view of VMImageConfig.
@@ -1506,6 +1581,90 @@ var _VMDiskConfigViewNeedsRegeneration = VMDiskConfig(struct {
 	Path    string
 }{})
 
+// View returns a read-only view of VMDataDiskConfig.
+func (p *VMDataDiskConfig) View() VMDataDiskConfigView {
+	return VMDataDiskConfigView{ж: p}
+}
+
+// VMDataDiskConfigView provides a read-only view over VMDataDiskConfig.
+//
+// Its methods should only be called if `Valid()` returns true.
+type VMDataDiskConfigView struct {
+	// ж is the underlying mutable value, named with a hard-to-type
+	// character that looks pointy like a pointer.
+	// It is named distinctively to make you think of how dangerous it is to escape
+	// to callers. You must not let callers be able to mutate it.
+	ж *VMDataDiskConfig
+}
+
+// Valid reports whether v's underlying value is non-nil.
+func (v VMDataDiskConfigView) Valid() bool { return v.ж != nil }
+
+// AsStruct returns a clone of the underlying value which aliases no memory with
+// the original.
+func (v VMDataDiskConfigView) AsStruct() *VMDataDiskConfig {
+	if v.ж == nil {
+		return nil
+	}
+	return v.ж.Clone()
+}
+
+// MarshalJSON implements [jsonv1.Marshaler].
+func (v VMDataDiskConfigView) MarshalJSON() ([]byte, error) {
+	return jsonv1.Marshal(v.ж)
+}
+
+// MarshalJSONTo implements [jsonv2.MarshalerTo].
+func (v VMDataDiskConfigView) MarshalJSONTo(enc *jsontext.Encoder) error {
+	return jsonv2.MarshalEncode(enc, v.ж)
+}
+
+// UnmarshalJSON implements [jsonv1.Unmarshaler].
+func (v *VMDataDiskConfigView) UnmarshalJSON(b []byte) error {
+	if v.ж != nil {
+		return errors.New("already initialized")
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	var x VMDataDiskConfig
+	if err := jsonv1.Unmarshal(b, &x); err != nil {
+		return err
+	}
+	v.ж = &x
+	return nil
+}
+
+// UnmarshalJSONFrom implements [jsonv2.UnmarshalerFrom].
+func (v *VMDataDiskConfigView) UnmarshalJSONFrom(dec *jsontext.Decoder) error {
+	if v.ж != nil {
+		return errors.New("already initialized")
+	}
+	var x VMDataDiskConfig
+	if err := jsonv2.UnmarshalDecode(dec, &x); err != nil {
+		return err
+	}
+	v.ж = &x
+	return nil
+}
+
+func (v VMDataDiskConfigView) Name() string    { return v.ж.Name }
+func (v VMDataDiskConfigView) Backend() string { return v.ж.Backend }
+func (v VMDataDiskConfigView) Bytes() int64    { return v.ж.Bytes }
+func (v VMDataDiskConfigView) Path() string    { return v.ж.Path }
+
+// DetachedFrom records the VM a detached disk was last attached to.
+func (v VMDataDiskConfigView) DetachedFrom() string { return v.ж.DetachedFrom }
+
+// A compilation failure here means this code must be regenerated, with the command at the top of this file.
+var _VMDataDiskConfigViewNeedsRegeneration = VMDataDiskConfig(struct {
+	Name         string
+	Backend      string
+	Bytes        int64
+	Path         string
+	DetachedFrom string
+}{})
+
 // View returns a read-only view of VMNetworkConfig.
 func (p *VMNetworkConfig) View() VMNetworkConfigView {
 	return VMNetworkConfigView{ж: p}
@@ -2760,6 +2919,176 @@ var _VMComponentsConfigViewNeedsRegeneration = VMComponentsConfig(struct {
 	Runtime   VMRuntimeLifecycleConfig
 }{})
 
+// View returns a read-only view of VMHibernationConfig.
+func (p *VMHibernationConfig) View() VMHibernationConfigView {
+	return VMHibernationConfigView{ж: p}
+}
+
+// VMHibernationConfigView provides a read-only view over VMHibernationConfig.
+//
+// Its methods should only be called if `Valid()` returns true.
+type VMHibernationConfigView struct {
+	// ж is the underlying mutable value, named with a hard-to-type
+	// character that looks pointy like a pointer.
+	// It is named distinctively to make you think of how dangerous it is to escape
+	// to callers. You must not let callers be able to mutate it.
+	ж *VMHibernationConfig
+}
+
+// Valid reports whether v's underlying value is non-nil.
+func (v VMHibernationConfigView) Valid() bool { return v.ж != nil }
+
+// AsStruct returns a clone of the underlying value which aliases no memory with
+// the original.
+func (v VMHibernationConfigView) AsStruct() *VMHibernationConfig {
+	if v.ж == nil {
+		return nil
+	}
+	return v.ж.Clone()
+}
+
+// MarshalJSON implements [jsonv1.Marshaler].
+func (v VMHibernationConfigView) MarshalJSON() ([]byte, error) {
+	return jsonv1.Marshal(v.ж)
+}
+
+// MarshalJSONTo implements [jsonv2.MarshalerTo].
+func (v VMHibernationConfigView) MarshalJSONTo(enc *jsontext.Encoder) error {
+	return jsonv2.MarshalEncode(enc, v.ж)
+}
+
+// UnmarshalJSON implements [jsonv1.Unmarshaler].
+func (v *VMHibernationConfigView) UnmarshalJSON(b []byte) error {
+	if v.ж != nil {
+		return errors.New("already initialized")
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	var x VMHibernationConfig
+	if err := jsonv1.Unmarshal(b, &x); err != nil {
+		return err
+	}
+	v.ж = &x
+	return nil
+}
+
+// UnmarshalJSONFrom implements [jsonv2.UnmarshalerFrom].
+func (v *VMHibernationConfigView) UnmarshalJSONFrom(dec *jsontext.Decoder) error {
+	if v.ж != nil {
+		return errors.New("already initialized")
+	}
+	var x VMHibernationConfig
+	if err := jsonv2.UnmarshalDecode(dec, &x); err != nil {
+		return err
+	}
+	v.ж = &x
+	return nil
+}
+
+func (v VMHibernationConfigView) StatePath() string    { return v.ж.StatePath }
+func (v VMHibernationConfigView) MemoryPath() string   { return v.ж.MemoryPath }
+func (v VMHibernationConfigView) CreatedAt() string    { return v.ж.CreatedAt }
+func (v VMHibernationConfigView) OnHostShutdown() bool { return v.ж.OnHostShutdown }
+
+// A compilation failure here means this code must be regenerated, with the command at the top of this file.
+var _VMHibernationConfigViewNeedsRegeneration = VMHibernationConfig(struct {
+	StatePath      string
+	MemoryPath     string
+	CreatedAt      string
+	OnHostShutdown bool
+}{})
+
+// View returns a read-only view of VMPowerConfig.
+func (p *VMPowerConfig) View() VMPowerConfigView {
+	return VMPowerConfigView{ж: p}
+}
+
+// VMPowerConfigView provides a read-only view over VMPowerConfig.
+//
+// Its methods should only be called if `Valid()` returns true.
+type VMPowerConfigView struct {
+	// ж is the underlying mutable value, named with a hard-to-type
+	// character that looks pointy like a pointer.
+	// It is named distinctively to make you think of how dangerous it is to escape
+	// to callers. You must not let callers be able to mutate it.
+	ж *VMPowerConfig
+}
+
+// Valid reports whether v's underlying value is non-nil.
+func (v VMPowerConfigView) Valid() bool { return v.ж != nil }
+
+// AsStruct returns a clone of the underlying value which aliases no memory with
+// the original.
+func (v VMPowerConfigView) AsStruct() *VMPowerConfig {
+	if v.ж == nil {
+		return nil
+	}
+	return v.ж.Clone()
+}
+
+// MarshalJSON implements [jsonv1.Marshaler].
+func (v VMPowerConfigView) MarshalJSON() ([]byte, error) {
+	return jsonv1.Marshal(v.ж)
+}
+
+// MarshalJSONTo implements [jsonv2.MarshalerTo].
+func (v VMPowerConfigView) MarshalJSONTo(enc *jsontext.Encoder) error {
+	return jsonv2.MarshalEncode(enc, v.ж)
+}
+
+// UnmarshalJSON implements [jsonv1.Unmarshaler].
+func (v *VMPowerConfigView) UnmarshalJSON(b []byte) error {
+	if v.ж != nil {
+		return errors.New("already initialized")
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	var x VMPowerConfig
+	if err := jsonv1.Unmarshal(b, &x); err != nil {
+		return err
+	}
+	v.ж = &x
+	return nil
+}
+
+// UnmarshalJSONFrom implements [jsonv2.UnmarshalerFrom].
+func (v *VMPowerConfigView) UnmarshalJSONFrom(dec *jsontext.Decoder) error {
+	if v.ж != nil {
+		return errors.New("already initialized")
+	}
+	var x VMPowerConfig
+	if err := jsonv2.UnmarshalDecode(dec, &x); err != nil {
+		return err
+	}
+	v.ж = &x
+	return nil
+}
+
+// NoAutostart leaves the VM unit disabled so it stays stopped at boot.
+func (v VMPowerConfigView) NoAutostart() bool { return v.ж.NoAutostart }
+
+// StartOrder orders boot: a VM starts after autostart VMs with a lower
+// order.
+func (v VMPowerConfigView) StartOrder() int { return v.ж.StartOrder }
+
+// ShutdownTimeout is how long the guest agent gets to shut the guest
+// down before Firecracker is killed; "0s" skips the agent.
+func (v VMPowerConfigView) ShutdownTimeout() string { return v.ж.ShutdownTimeout }
+
+// IdleStop stops the VM after this long without network traffic or SSH
+// sessions.
+func (v VMPowerConfigView) IdleStop() string { return v.ж.IdleStop }
+
+// A compilation failure here means this code must be regenerated, with the command at the top of this file.
+var _VMPowerConfigViewNeedsRegeneration = VMPowerConfig(struct {
+	NoAutostart     bool
+	StartOrder      int
+	ShutdownTimeout string
+	IdleStop        string
+}{})
+
 // View returns a read-only view of ServiceNetworkConfig.
 func (p *ServiceNetworkConfig) View() ServiceNetworkConfigView {
 	return ServiceNetworkConfigView{ж: p}
@@ -2845,3 +3174,416 @@ var _ServiceNetworkConfigViewNeedsRegeneration = ServiceNetworkConfig(struct {
 	MacvlanVLAN   int
 	MacvlanMAC    string
 }{})
+
+// View returns a read-only view of RegistryGCConfig.
+func (p *RegistryGCConfig) View() RegistryGCConfigView {
+	return RegistryGCConfigView{ж: p}
+}
+
+// RegistryGCConfigView provides a read-only view over RegistryGCConfig.
+//
+// Its methods should only be called if `Valid()` returns true.
+type RegistryGCConfigView struct {
+	// ж is the underlying mutable value, named with a hard-to-type
+	// character that looks pointy like a pointer.
+	// It is named distinctively to make you think of how dangerous it is to escape
+	// to callers. You must not let callers be able to mutate it.
+	ж *RegistryGCConfig
+}
+
+// Valid reports whether v's underlying value is non-nil.
+func (v RegistryGCConfigView) Valid() bool { return v.ж != nil }
+
+// AsStruct returns a clone of the underlying value which aliases no memory with
+// the original.
+func (v RegistryGCConfigView) AsStruct() *RegistryGCConfig {
+	if v.ж == nil {
+		return nil
+	}
+	return v.ж.Clone()
+}
+
+// MarshalJSON implements [jsonv1.Marshaler].
+func (v RegistryGCConfigView) MarshalJSON() ([]byte, error) {
+	return jsonv1.Marshal(v.ж)
+}
+
+// MarshalJSONTo implements [jsonv2.MarshalerTo].
+func (v RegistryGCConfigView) MarshalJSONTo(enc *jsontext.Encoder) error {
+	return jsonv2.MarshalEncode(enc, v.ж)
+}
+
+// UnmarshalJSON implements [jsonv1.Unmarshaler].
+func (v *RegistryGCConfigView) UnmarshalJSON(b []byte) error {
+	if v.ж != nil {
+		return errors.New("already initialized")
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	var x RegistryGCConfig
+	if err := jsonv1.Unmarshal(b, &x); err != nil {
+		return err
+	}
+	v.ж = &x
+	return nil
+}
+
+// UnmarshalJSONFrom implements [jsonv2.UnmarshalerFrom].
+func (v *RegistryGCConfigView) UnmarshalJSONFrom(dec *jsontext.Decoder) error {
+	if v.ж != nil {
+		return errors.New("already initialized")
+	}
+	var x RegistryGCConfig
+	if err := jsonv2.UnmarshalDecode(dec, &x); err != nil {
+		return err
+	}
+	v.ж = &x
+	return nil
+}
+
+// Interval is how often garbage collection runs; empty disables it.
+func (v RegistryGCConfigView) Interval() string { return v.ж.Interval }
+
+// LastRun is when periodic garbage collection last finished, in RFC 3339.
+func (v RegistryGCConfigView) LastRun() string { return v.ж.LastRun }
+
+// A compilation failure here means this code must be regenerated, with the command at the top of this file.
+var _RegistryGCConfigViewNeedsRegeneration = RegistryGCConfig(struct {
+	Interval string
+	LastRun  string
+}{})
+
+// View returns a read-only view of RegistryMirrorConfig.
+func (p *RegistryMirrorConfig) View() RegistryMirrorConfigView {
+	return RegistryMirrorConfigView{ж: p}
+}
+
+// RegistryMirrorConfigView provides a read-only view over RegistryMirrorConfig.
+//
+// Its methods should only be called if `Valid()` returns true.
+type RegistryMirrorConfigView struct {
+	// ж is the underlying mutable value, named with a hard-to-type
+	// character that looks pointy like a pointer.
+	// It is named distinctively to make you think of how dangerous it is to escape
+	// to callers. You must not let callers be able to mutate it.
+	ж *RegistryMirrorConfig
+}
+
+// Valid reports whether v's underlying value is non-nil.
+func (v RegistryMirrorConfigView) Valid() bool { return v.ж != nil }
+
+// AsStruct returns a clone of the underlying value which aliases no memory with
+// the original.
+func (v RegistryMirrorConfigView) AsStruct() *RegistryMirrorConfig {
+	if v.ж == nil {
+		return nil
+	}
+	return v.ж.Clone()
+}
+
+// MarshalJSON implements [jsonv1.Marshaler].
+func (v RegistryMirrorConfigView) MarshalJSON() ([]byte, error) {
+	return jsonv1.Marshal(v.ж)
+}
+
+// MarshalJSONTo implements [jsonv2.MarshalerTo].
+func (v RegistryMirrorConfigView) MarshalJSONTo(enc *jsontext.Encoder) error {
+	return jsonv2.MarshalEncode(enc, v.ж)
+}
+
+// UnmarshalJSON implements [jsonv1.Unmarshaler].
+func (v *RegistryMirrorConfigView) UnmarshalJSON(b []byte) error {
+	if v.ж != nil {
+		return errors.New("already initialized")
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	var x RegistryMirrorConfig
+	if err := jsonv1.Unmarshal(b, &x); err != nil {
+		return err
+	}
+	v.ж = &x
+	return nil
+}
+
+// UnmarshalJSONFrom implements [jsonv2.UnmarshalerFrom].
+func (v *RegistryMirrorConfigView) UnmarshalJSONFrom(dec *jsontext.Decoder) error {
+	if v.ж != nil {
+		return errors.New("already initialized")
+	}
+	var x RegistryMirrorConfig
+	if err := jsonv2.UnmarshalDecode(dec, &x); err != nil {
+		return err
+	}
+	v.ж = &x
+	return nil
+}
+
+// Upstream is the mirrored registry host, e.g. docker.io.
+func (v RegistryMirrorConfigView) Upstream() string { return v.ж.Upstream }
+
+// A compilation failure here means this code must be regenerated, with the command at the top of this file.
+var _RegistryMirrorConfigViewNeedsRegeneration = RegistryMirrorConfig(struct {
+	Upstream string
+}{})
+
+// View returns a read-only view of ImagePolicyConfig.
+func (p *ImagePolicyConfig) View() ImagePolicyConfigView {
+	return ImagePolicyConfigView{ж: p}
+}
+
+// ImagePolicyConfigView provides a read-only view over ImagePolicyConfig.
+//
+// Its methods should only be called if `Valid()` returns true.
+type ImagePolicyConfigView struct {
+	// ж is the underlying mutable value, named with a hard-to-type
+	// character that looks pointy like a pointer.
+	// It is named distinctively to make you think of how dangerous it is to escape
+	// to callers. You must not let callers be able to mutate it.
+	ж *ImagePolicyConfig
+}
+
+// Valid reports whether v's underlying value is non-nil.
+func (v ImagePolicyConfigView) Valid() bool { return v.ж != nil }
+
+// AsStruct returns a clone of the underlying value which aliases no memory with
+// the original.
+func (v ImagePolicyConfigView) AsStruct() *ImagePolicyConfig {
+	if v.ж == nil {
+		return nil
+	}
+	return v.ж.Clone()
+}
+
+// MarshalJSON implements [jsonv1.Marshaler].
+func (v ImagePolicyConfigView) MarshalJSON() ([]byte, error) {
+	return jsonv1.Marshal(v.ж)
+}
+
+// MarshalJSONTo implements [jsonv2.MarshalerTo].
+func (v ImagePolicyConfigView) MarshalJSONTo(enc *jsontext.Encoder) error {
+	return jsonv2.MarshalEncode(enc, v.ж)
+}
+
+// UnmarshalJSON implements [jsonv1.Unmarshaler].
+func (v *ImagePolicyConfigView) UnmarshalJSON(b []byte) error {
+	if v.ж != nil {
+		return errors.New("already initialized")
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	var x ImagePolicyConfig
+	if err := jsonv1.Unmarshal(b, &x); err != nil {
+		return err
+	}
+	v.ж = &x
+	return nil
+}
+
+// UnmarshalJSONFrom implements [jsonv2.UnmarshalerFrom].
+func (v *ImagePolicyConfigView) UnmarshalJSONFrom(dec *jsontext.Decoder) error {
+	if v.ж != nil {
+		return errors.New("already initialized")
+	}
+	var x ImagePolicyConfig
+	if err := jsonv2.UnmarshalDecode(dec, &x); err != nil {
+		return err
+	}
+	v.ж = &x
+	return nil
+}
+
+// Mode is require-signed when every compose service's images must be
+// signed; empty leaves it to each service.
+func (v ImagePolicyConfigView) Mode() string { return v.ж.Mode }
+
+// TrustKeys are the PEM encoded public keys signatures are checked
+// against.
+func (v ImagePolicyConfigView) TrustKeys() views.Slice[string] { return views.SliceOf(v.ж.TrustKeys) }
+
+// A compilation failure here means this code must be regenerated, with the command at the top of this file.
+var _ImagePolicyConfigViewNeedsRegeneration = ImagePolicyConfig(struct {
+	Mode      string
+	TrustKeys []string
+}{})
+
+// View returns a read-only view of AutoUpdateConfig.
+func (p *AutoUpdateConfig) View() AutoUpdateConfigView {
+	return AutoUpdateConfigView{ж: p}
+}
+
+// AutoUpdateConfigView provides a read-only view over AutoUpdateConfig.
+//
+// Its methods should only be called if `Valid()` returns true.
+type AutoUpdateConfigView struct {
+	// ж is the underlying mutable value, named with a hard-to-type
+	// character that looks pointy like a pointer.
+	// It is named distinctively to make you think of how dangerous it is to escape
+	// to callers. You must not let callers be able to mutate it.
+	ж *AutoUpdateConfig
+}
+
+// Valid reports whether v's underlying value is non-nil.
+func (v AutoUpdateConfigView) Valid() bool { return v.ж != nil }
+
+// AsStruct returns a clone of the underlying value which aliases no memory with
+// the original.
+func (v AutoUpdateConfigView) AsStruct() *AutoUpdateConfig {
+	if v.ж == nil {
+		return nil
+	}
+	return v.ж.Clone()
+}
+
+// MarshalJSON implements [jsonv1.Marshaler].
+func (v AutoUpdateConfigView) MarshalJSON() ([]byte, error) {
+	return jsonv1.Marshal(v.ж)
+}
+
+// MarshalJSONTo implements [jsonv2.MarshalerTo].
+func (v AutoUpdateConfigView) MarshalJSONTo(enc *jsontext.Encoder) error {
+	return jsonv2.MarshalEncode(enc, v.ж)
+}
+
+// UnmarshalJSON implements [jsonv1.Unmarshaler].
+func (v *AutoUpdateConfigView) UnmarshalJSON(b []byte) error {
+	if v.ж != nil {
+		return errors.New("already initialized")
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	var x AutoUpdateConfig
+	if err := jsonv1.Unmarshal(b, &x); err != nil {
+		return err
+	}
+	v.ж = &x
+	return nil
+}
+
+// UnmarshalJSONFrom implements [jsonv2.UnmarshalerFrom].
+func (v *AutoUpdateConfigView) UnmarshalJSONFrom(dec *jsontext.Decoder) error {
+	if v.ж != nil {
+		return errors.New("already initialized")
+	}
+	var x AutoUpdateConfig
+	if err := jsonv2.UnmarshalDecode(dec, &x); err != nil {
+		return err
+	}
+	v.ж = &x
+	return nil
+}
+
+// Mode is notify, which only reports new images, or apply, which also
+// updates the service and rolls it back if it does not come up.
+func (v AutoUpdateConfigView) Mode() string { return v.ж.Mode }
+
+// Window is when checks run in catch's local time: "Sun 03:00" weekly or
+// "03:00" daily. Empty means daily at 03:00.
+func (v AutoUpdateConfigView) Window() string { return v.ж.Window }
+
+// LastCheck is when the last scheduled check started, in RFC 3339.
+func (v AutoUpdateConfigView) LastCheck() string { return v.ж.LastCheck }
+
+// History holds the most recent check results, oldest first.
+func (v AutoUpdateConfigView) History() views.Slice[AutoUpdateResult] {
+	return views.SliceOf(v.ж.History)
+}
+
+// A compilation failure here means this code must be regenerated, with the command at the top of this file.
+var _AutoUpdateConfigViewNeedsRegeneration = AutoUpdateConfig(struct {
+	Mode      string
+	Window    string
+	LastCheck string
+	History   []AutoUpdateResult
+}{})
+
+// View returns a read-only view of AutoUpdateResult.
+func (p *AutoUpdateResult) View() AutoUpdateResultView {
+	return AutoUpdateResultView{ж: p}
+}
+
+// AutoUpdateResultView provides a read-only view over AutoUpdateResult.
+//
+// Its methods should only be called if `Valid()` returns true.
+type AutoUpdateResultView struct {
+	// ж is the underlying mutable value, named with a hard-to-type
+	// character that looks pointy like a pointer.
+	// It is named distinctively to make you think of how dangerous it is to escape
+	// to callers. You must not let callers be able to mutate it.
+	ж *AutoUpdateResult
+}
+
+// Valid reports whether v's underlying value is non-nil.
+func (v AutoUpdateResultView) Valid() bool { return v.ж != nil }
+
+// AsStruct returns a clone of the underlying value which aliases no memory with
+// the original.
+func (v AutoUpdateResultView) AsStruct() *AutoUpdateResult {
+	if v.ж == nil {
+		return nil
+	}
+	return v.ж.Clone()
+}
+
+// MarshalJSON implements [jsonv1.Marshaler].
+func (v AutoUpdateResultView) MarshalJSON() ([]byte, error) {
+	return jsonv1.Marshal(v.ж)
+}
+
+// MarshalJSONTo implements [jsonv2.MarshalerTo].
+func (v AutoUpdateResultView) MarshalJSONTo(enc *jsontext.Encoder) error {
+	return jsonv2.MarshalEncode(enc, v.ж)
+}
+
+// UnmarshalJSON implements [jsonv1.Unmarshaler].
+func (v *AutoUpdateResultView) UnmarshalJSON(b []byte) error {
+	if v.ж != nil {
+		return errors.New("already initialized")
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	var x AutoUpdateResult
+	if err := jsonv1.Unmarshal(b, &x); err != nil {
+		return err
+	}
+	v.ж = &x
+	return nil
+}
+
+// UnmarshalJSONFrom implements [jsonv2.UnmarshalerFrom].
+func (v *AutoUpdateResultView) UnmarshalJSONFrom(dec *jsontext.Decoder) error {
+	if v.ж != nil {
+		return errors.New("already initialized")
+	}
+	var x AutoUpdateResult
+	if err := jsonv2.UnmarshalDecode(dec, &x); err != nil {
+		return err
+	}
+	v.ж = &x
+	return nil
+}
+
+// Time is when the check finished, in RFC 3339.
+func (v AutoUpdateResultView) Time() string { return v.ж.Time }
+
+// Result is available, updated, rolled-back or failed.
+func (v AutoUpdateResultView) Result() string { return v.ж.Result }
+
+// Images names the images that had updates, comma-separated.
+func (v AutoUpdateResultView) Images() string { return v.ж.Images }
+
+// Error explains a failed check or the reason for a rollback.
+func (v AutoUpdateResultView) Error() string { return v.ж.Error }
+
+// A compilation failure here means this code must be regenerated, with the command at the top of this file.
+var _AutoUpdateResultViewNeedsRegeneration = AutoUpdateResult(struct {
+	Time   string
+	Result string
+	Images string
+	Error  string
+}{})
@@ -0,0 +1,232 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// Lister is implemented by storages that can enumerate their repositories and
+// tags for the catalog and tags/list endpoints. Storages that only implement
+// Collectable are listed from their tags.
+type Lister interface {
+	// Repositories lists every repository that has at least one tag.
+	Repositories(ctx context.Context) ([]string, error)
+	// Tags lists the tags in repo, or returns ErrRepositoryNotFound.
+	Tags(ctx context.Context, repo string) ([]string, error)
+}
+
+// collectableLister lists repositories and tags from Collectable.ListTags.
+type collectableLister struct {
+	c Collectable
+}
+
+func (l collectableLister) tags(ctx context.Context) (map[string][]string, error) {
+	tags, err := l.c.ListTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byRepo := map[string][]string{}
+	for _, tag := range tags {
+		byRepo[tag.Repo] = append(byRepo[tag.Repo], tag.Tag)
+	}
+	return byRepo, nil
+}
+
+func (l collectableLister) Repositories(ctx context.Context) ([]string, error) {
+	byRepo, err := l.tags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	repos := make([]string, 0, len(byRepo))
+	for repo := range byRepo {
+		repos = append(repos, repo)
+	}
+	return repos, nil
+}
+
+func (l collectableLister) Tags(ctx context.Context, repo string) ([]string, error) {
+	byRepo, err := l.tags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tags, ok := byRepo[repo]
+	if !ok {
+		return nil, ErrRepositoryNotFound
+	}
+	return tags, nil
+}
+
+func storageLister(s Storage) (Lister, bool) {
+	if l, ok := s.(Lister); ok {
+		return l, true
+	}
+	if c, ok := s.(Collectable); ok {
+		return collectableLister{c: c}, true
+	}
+	return nil, false
+}
+
+// pageRequest is the n and last query parameters of a paginated listing.
+type pageRequest struct {
+	// n is the page size, or -1 to return everything after last.
+	n    int
+	last string
+}
+
+func parsePageRequest(req *http.Request) (pageRequest, error) {
+	q := req.URL.Query()
+	page := pageRequest{n: -1, last: q.Get("last")}
+	if raw := q.Get("n"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return pageRequest{}, fmt.Errorf("invalid n %q", raw)
+		}
+		page.n = n
+	}
+	return page, nil
+}
+
+// paginate sorts items and returns the page after last, and whether more
+// items follow it.
+func paginate(items []string, page pageRequest) ([]string, bool) {
+	sort.Strings(items)
+	start := sort.SearchStrings(items, page.last)
+	if page.last != "" && start < len(items) && items[start] == page.last {
+		start++
+	}
+	out := append([]string{}, items[start:]...)
+	if page.n >= 0 && len(out) > page.n {
+		return out[:page.n], true
+	}
+	return out, false
+}
+
+// setNextLink sets the Link header the distribution spec uses to point at the
+// next page.
+func setNextLink(w http.ResponseWriter, path string, items []string, page pageRequest) {
+	if len(items) == 0 {
+		return
+	}
+	q := url.Values{}
+	q.Set("n", strconv.Itoa(page.n))
+	q.Set("last", items[len(items)-1])
+	w.Header().Set("Link", fmt.Sprintf("<%s?%s>; rel=\"next\"", path, q.Encode()))
+}
+
+func writeListing(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// handleCatalog handles the /v2/_catalog endpoint.
+func (r *Registry) handleCatalog(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		WriteError(w, http.StatusMethodNotAllowed, ErrCodeUnsupported, "method not allowed", nil)
+		return
+	}
+	lister, ok := storageLister(r.storage)
+	if !ok {
+		WriteError(w, http.StatusNotImplemented, ErrCodeUnsupported, "not implemented", nil)
+		return
+	}
+	page, err := parsePageRequest(req)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, ErrCodeUnsupported, err.Error(), nil)
+		return
+	}
+	repos, err := lister.Repositories(req.Context())
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, ErrCodeUnsupported, err.Error(), nil)
+		return
+	}
+	repos, more := paginate(repos, page)
+	if more {
+		setNextLink(w, req.URL.Path, repos, page)
+	}
+	writeListing(w, struct {
+		Repositories []string `json:"repositories"`
+	}{repos})
+}
+
+// handleTagsList handles the /v2/<repo>/tags/list endpoint.
+func (r *Registry) handleTagsList(w http.ResponseWriter, req *http.Request, repo string) {
+	if req.Method != http.MethodGet {
+		WriteError(w, http.StatusMethodNotAllowed, ErrCodeUnsupported, "method not allowed", nil)
+		return
+	}
+	lister, ok := storageLister(r.storage)
+	if !ok {
+		WriteError(w, http.StatusNotImplemented, ErrCodeUnsupported, "not implemented", nil)
+		return
+	}
+	page, err := parsePageRequest(req)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, ErrCodeUnsupported, err.Error(), nil)
+		return
+	}
+	tags, err := lister.Tags(req.Context(), repo)
+	if errors.Is(err, ErrRepositoryNotFound) {
+		WriteError(w, http.StatusNotFound, ErrCodeNameUnknown, "repository name not known to registry", map[string]string{"name": repo})
+		return
+	}
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, ErrCodeUnsupported, err.Error(), nil)
+		return
+	}
+	tags, more := paginate(tags, page)
+	if more {
+		setNextLink(w, req.URL.Path, tags, page)
+	}
+	writeListing(w, struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}{repo, tags})
+}
+
+// ImageSize returns the size of the manifest at reference plus the config and
+// layers it references. For an index it adds up every child manifest.
+func ImageSize(ctx context.Context, s Storage, repo, reference string) (int64, error) {
+	md, err := s.GetManifest(ctx, repo, reference)
+	if err != nil {
+		return 0, err
+	}
+	data, err := io.ReadAll(md.Data)
+	_ = md.Data.Close()
+	if err != nil {
+		return 0, err
+	}
+	var manifest manifestRefs
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return 0, fmt.Errorf("parse manifest %s@%s: %w", repo, md.Digest, err)
+	}
+	total := int64(len(data))
+	if manifest.Config != nil {
+		total += manifest.Config.Size
+	}
+	for _, layer := range manifest.Layers {
+		total += layer.Size
+	}
+	for _, child := range manifest.Manifests {
+		size, err := ImageSize(ctx, s, repo, child.Digest)
+		if errors.Is(err, ErrManifestNotFound) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	return total, nil
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestRegistryCatalogPaginatesFilesystemRepos(t *testing.T) {
+	storage := newTestFilesystemStorage(t)
+	for _, repo := range []string{"ns/web", "ns/api", "ns/worker"} {
+		putTestImage(t, storage, repo, "v1")
+	}
+	putTestImage(t, storage, "ns/api", "v2")
+
+	resp := performRegistryRequest(t, storage, http.MethodGet, "/v2/_catalog?n=2", nil, nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("status=%d, want 200; body=%q", resp.Code, resp.Body.String())
+	}
+	var catalog struct {
+		Repositories []string `json:"repositories"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &catalog); err != nil {
+		t.Fatalf("decode catalog: %v", err)
+	}
+	if want := []string{"ns/api", "ns/web"}; !reflect.DeepEqual(catalog.Repositories, want) {
+		t.Fatalf("repositories=%q, want %q", catalog.Repositories, want)
+	}
+	if got, want := resp.Header().Get("Link"), `</v2/_catalog?last=ns%2Fweb&n=2>; rel="next"`; got != want {
+		t.Fatalf("Link=%q, want %q", got, want)
+	}
+
+	resp = performRegistryRequest(t, storage, http.MethodGet, "/v2/_catalog?n=2&last=ns/web", nil, nil)
+	if err := json.Unmarshal(resp.Body.Bytes(), &catalog); err != nil {
+		t.Fatalf("decode catalog page 2: %v", err)
+	}
+	if want := []string{"ns/worker"}; !reflect.DeepEqual(catalog.Repositories, want) {
+		t.Fatalf("page 2 repositories=%q, want %q", catalog.Repositories, want)
+	}
+	if got := resp.Header().Get("Link"); got != "" {
+		t.Fatalf("last page Link=%q, want none", got)
+	}
+}
+
+func TestRegistryTagsList(t *testing.T) {
+	storage := newTestFilesystemStorage(t)
+	putTestImage(t, storage, "ns/api", "v2")
+	putTestImage(t, storage, "ns/api", "v1")
+
+	resp := performRegistryRequest(t, storage, http.MethodGet, "/v2/ns/api/tags/list", nil, nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("status=%d, want 200; body=%q", resp.Code, resp.Body.String())
+	}
+	var list struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decode tags: %v", err)
+	}
+	if list.Name != "ns/api" || !reflect.DeepEqual(list.Tags, []string{"v1", "v2"}) {
+		t.Fatalf("tags list=%+v, want ns/api [v1 v2]", list)
+	}
+
+	resp = performRegistryRequest(t, storage, http.MethodGet, "/v2/ns/api/tags/list?n=1&last=v1", nil, nil)
+	if err := json.Unmarshal(resp.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decode tags page: %v", err)
+	}
+	if !reflect.DeepEqual(list.Tags, []string{"v2"}) || resp.Header().Get("Link") != "" {
+		t.Fatalf("tags page=%+v link=%q, want [v2] without next", list, resp.Header().Get("Link"))
+	}
+
+	resp = performRegistryRequest(t, storage, http.MethodGet, "/v2/ns/missing/tags/list", nil, nil)
+	if resp.Code != http.StatusNotFound {
+		t.Fatalf("missing repo status=%d, want 404", resp.Code)
+	}
+	requireRegistryError(t, resp, ErrCodeNameUnknown)
+
+	resp = performRegistryRequest(t, storage, http.MethodGet, "/v2/ns/api/tags/list?n=-1", nil, nil)
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("invalid n status=%d, want 400", resp.Code)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	items := []string{"c", "a", "b"}
+	for _, tc := range []struct {
+		page pageRequest
+		want []string
+		more bool
+	}{
+		{pageRequest{n: -1}, []string{"a", "b", "c"}, false},
+		{pageRequest{n: 0}, []string{}, true},
+		{pageRequest{n: 2}, []string{"a", "b"}, true},
+		{pageRequest{n: 2, last: "a"}, []string{"b", "c"}, false},
+		{pageRequest{n: -1, last: "bb"}, []string{"c"}, false},
+		{pageRequest{n: 3, last: "c"}, []string{}, false},
+	} {
+		got, more := paginate(append([]string(nil), items...), tc.page)
+		if !reflect.DeepEqual(got, tc.want) || more != tc.more {
+			t.Errorf("paginate(%+v) = %q, %v; want %q, %v", tc.page, got, more, tc.want, tc.more)
+		}
+	}
+}
+
+func TestImageSizeAddsDescriptorsAndIndexChildren(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestFilesystemStorage(t)
+	child := []byte(`{"schemaVersion":2,"config":{"digest":"sha256:c","size":100},"layers":[{"digest":"sha256:l1","size":1000},{"digest":"sha256:l2","size":24}]}`)
+	childDigest, err := storage.PutManifest(ctx, "ns/api", "amd64", child, ocispec.MediaTypeImageManifest)
+	if err != nil {
+		t.Fatalf("PutManifest child: %v", err)
+	}
+	index := []byte(fmt.Sprintf(`{"schemaVersion":2,"manifests":[{"digest":%q},{"digest":"sha256:gone"}]}`, childDigest))
+	if _, err := storage.PutManifest(ctx, "ns/api", "v1", index, ocispec.MediaTypeImageIndex); err != nil {
+		t.Fatalf("PutManifest index: %v", err)
+	}
+
+	size, err := ImageSize(ctx, storage, "ns/api", "amd64")
+	if err != nil || size != int64(len(child))+1124 {
+		t.Fatalf("ImageSize(child) = %d, %v; want %d", size, err, len(child)+1124)
+	}
+	size, err = ImageSize(ctx, storage, "ns/api", "v1")
+	if err != nil || size != int64(len(index)+len(child))+1124 {
+		t.Fatalf("ImageSize(index) = %d, %v; want %d", size, err, len(index)+len(child)+1124)
+	}
+	if _, err := ImageSize(ctx, storage, "ns/api", "missing"); !errors.Is(err, ErrManifestNotFound) {
+		t.Fatalf("ImageSize(missing) err = %v, want ErrManifestNotFound", err)
+	}
+}
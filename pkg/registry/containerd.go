@@ -55,9 +55,11 @@ type containerdContentStore interface {
 	Update(ctx context.Context, info content.Info, fieldpaths ...string) (content.Info, error)
 	Delete(ctx context.Context, dg digest.Digest) error
 	Abort(ctx context.Context, ref string) error
+	Walk(ctx context.Context, fn content.WalkFunc, filters ...string) error
 }
 
 var _ Storage = (*ContainerdCacheStorage)(nil)
+var _ Collectable = (*ContainerdCacheStorage)(nil)
 
 // NewContainerdCacheStorage creates a new Docker cache-based storage.
 // containerdSocket is the path to containerd's socket (e.g., /run/containerd/containerd.sock).
@@ -291,8 +293,10 @@ func (s *ContainerdCacheStorage) finishManifestPut(ctx context.Context, manifest
 	if err := s.registerImage(ctx, img); err != nil {
 		return err
 	}
-	if err := s.unpackImage(ctx, img, manifestInfo.snapshotLabels()); err != nil {
-		return err
+	if !manifestInfo.artifact {
+		if err := s.unpackImage(ctx, img, manifestInfo.snapshotLabels()); err != nil {
+			return err
+		}
 	}
 	if err := s.updateManifestLabels(ctx, dg, manifestInfo.labels); err != nil {
 		return err
@@ -348,6 +352,9 @@ type containerdManifestInfo struct {
 	path      string
 	mediaType string
 	labels    map[string]string
+	// artifact is set for manifests without filesystem layers, such as
+	// cosign signatures, which are stored but not unpacked.
+	artifact bool
 }
 
 func buildContainerdManifestInfo(repo string, data []byte, mediaType string) (containerdManifestInfo, error) {
@@ -365,9 +372,11 @@ func buildContainerdManifestInfo(repo string, data []byte, mediaType string) (co
 
 	switch mediaType {
 	case ocispec.MediaTypeImageManifest, "application/vnd.docker.distribution.manifest.v2+json":
-		if err := addManifestLabels(info.labels, data); err != nil {
+		artifact, err := addManifestLabels(info.labels, data)
+		if err != nil {
 			return containerdManifestInfo{}, err
 		}
+		info.artifact = artifact
 	case ocispec.MediaTypeImageIndex, "application/vnd.docker.distribution.manifest.list.v2+json":
 		if err := addIndexLabels(info.labels, data); err != nil {
 			return containerdManifestInfo{}, err
@@ -378,16 +387,23 @@ func buildContainerdManifestInfo(repo string, data []byte, mediaType string) (co
 	return info, nil
 }
 
-func addManifestLabels(labels map[string]string, data []byte) error {
+// addManifestLabels labels an image manifest's references and reports
+// whether it is an artifact: every layer names a media type and none is a
+// filesystem tar.
+func addManifestLabels(labels map[string]string, data []byte) (artifact bool, _ error) {
 	var manifest ocispec.Manifest
 	if err := json.Unmarshal(data, &manifest); err != nil {
-		return fmt.Errorf("unmarshal manifest: %w", err)
+		return false, fmt.Errorf("unmarshal manifest: %w", err)
 	}
 	labels["containerd.io/gc.ref.content.config"] = manifest.Config.Digest.String()
+	artifact = len(manifest.Layers) > 0
 	for i, layer := range manifest.Layers {
 		labels[fmt.Sprintf("containerd.io/gc.ref.content.l.%d", i)] = layer.Digest.String()
+		if layer.MediaType == "" || strings.Contains(layer.MediaType, ".tar") {
+			artifact = false
+		}
 	}
-	return nil
+	return artifact, nil
 }
 
 func addIndexLabels(labels map[string]string, data []byte) error {
@@ -604,9 +620,9 @@ func (s *ContainerdCacheStorage) markContentRoot(dg digest.Digest) error {
 		ctx = context.Background()
 	}
 	labels := map[string]string{
-		"containerd.io/gc.root": time.Now().UTC().Format(time.RFC3339),
+		containerdGCRootLabel: time.Now().UTC().Format(time.RFC3339),
 	}
-	if _, err := cs.Update(ctx, content.Info{Digest: dg, Labels: labels}, "labels.containerd.io/gc.root"); err != nil {
+	if _, err := cs.Update(ctx, content.Info{Digest: dg, Labels: labels}, "labels."+containerdGCRootLabel); err != nil {
 		return fmt.Errorf("mark content root %s: %w", dg, err)
 	}
 	return nil
@@ -633,3 +649,80 @@ func (s *ContainerdCacheStorage) AbortUpload(ctx context.Context, uuid string) e
 	}
 	return nil
 }
+
+const (
+	containerdGCRootLabel       = "containerd.io/gc.root"
+	containerdGCRefContentLabel = "containerd.io/gc.ref.content."
+)
+
+// ListContent lists content pinned by CompleteUpload. Content that Docker's
+// own images still reference is left out, since the content store is shared
+// with the daemon and a layer may belong to both.
+func (s *ContainerdCacheStorage) ListContent(ctx context.Context) ([]ContentInfo, error) {
+	cs := s.getContentStore()
+	if cs == nil {
+		return nil, errors.New("content store unavailable")
+	}
+	var pinned []content.Info
+	shared := map[string]struct{}{}
+	err := cs.Walk(ctx, func(info content.Info) error {
+		if _, ok := info.Labels[containerdGCRootLabel]; ok {
+			pinned = append(pinned, info)
+			return nil
+		}
+		for key, value := range info.Labels {
+			if strings.HasPrefix(key, containerdGCRefContentLabel) {
+				shared[value] = struct{}{}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk containerd content: %w", err)
+	}
+	out := make([]ContentInfo, 0, len(pinned))
+	for _, info := range pinned {
+		if _, ok := shared[info.Digest.String()]; ok {
+			continue
+		}
+		out = append(out, ContentInfo{Digest: info.Digest.String(), Size: info.Size, UpdatedAt: info.UpdatedAt})
+	}
+	return out, nil
+}
+
+// ListTags lists every image in the namespace as a tag of its repository.
+func (s *ContainerdCacheStorage) ListTags(ctx context.Context) ([]TagInfo, error) {
+	imgs, err := s.containerdClient.ImageService().List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list containerd images: %w", err)
+	}
+	out := make([]TagInfo, 0, len(imgs))
+	for _, img := range imgs {
+		repo, tag := splitContainerdImageName(img.Name)
+		out = append(out, TagInfo{Repo: repo, Tag: tag, Digest: img.Target.Digest.String()})
+	}
+	return out, nil
+}
+
+// splitContainerdImageName splits "host:port/repo:tag" into repo and tag. A
+// colon before the last slash belongs to the host.
+func splitContainerdImageName(name string) (repo, tag string) {
+	name, _, _ = strings.Cut(name, "@")
+	slash := strings.LastIndex(name, "/")
+	if i := strings.LastIndex(name, ":"); i > slash {
+		return name[:i], name[i+1:]
+	}
+	return name, ""
+}
+
+// RemoveContent deletes content from the content store.
+func (s *ContainerdCacheStorage) RemoveContent(ctx context.Context, c ContentInfo) error {
+	cs := s.getContentStore()
+	if cs == nil {
+		return errors.New("content store unavailable")
+	}
+	if err := cs.Delete(ctx, digest.Digest(c.Digest)); err != nil && !errdefs.IsNotFound(err) {
+		return fmt.Errorf("delete content from containerd: %w", err)
+	}
+	return nil
+}
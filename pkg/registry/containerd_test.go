@@ -694,6 +694,28 @@ func TestBuildContainerdManifestInfoForImageManifest(t *testing.T) {
 	}
 }
 
+func TestBuildContainerdManifestInfoMarksArtifacts(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		layers   []ocispec.Descriptor
+		artifact bool
+	}{
+		{name: "image", layers: []ocispec.Descriptor{{MediaType: ocispec.MediaTypeImageLayerGzip}}},
+		{name: "docker image", layers: []ocispec.Descriptor{{MediaType: "application/vnd.docker.image.rootfs.diff.tar.gzip"}}},
+		{name: "cosign signature", layers: []ocispec.Descriptor{{MediaType: "application/vnd.dev.cosign.simplesigning.v1+json"}}, artifact: true},
+		{name: "no layers"},
+	} {
+		data := mustManifestJSON(t, ocispec.Manifest{Config: ocispec.Descriptor{Digest: digest.FromString("config")}, Layers: tc.layers})
+		got, err := buildContainerdManifestInfo("app", data, ocispec.MediaTypeImageManifest)
+		if err != nil {
+			t.Fatalf("%s: buildContainerdManifestInfo: %v", tc.name, err)
+		}
+		if got.artifact != tc.artifact {
+			t.Fatalf("%s: artifact = %v, want %v", tc.name, got.artifact, tc.artifact)
+		}
+	}
+}
+
 func TestBuildContainerdManifestInfoForImageIndex(t *testing.T) {
 	manifestDigest := digest.FromString("manifest")
 	data := mustManifestJSON(t, ocispec.Index{
@@ -1017,6 +1039,15 @@ func (f *fakeContentStore) Abort(_ context.Context, ref string) error {
 	return f.abortErr
 }
 
+func (f *fakeContentStore) Walk(_ context.Context, fn content.WalkFunc, _ ...string) error {
+	for _, info := range f.info {
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type fakeReaderAt struct {
 	data    []byte
 	closed  int
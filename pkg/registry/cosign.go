@@ -0,0 +1,199 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package registry
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	// cosignSimpleSigningMediaType is the layer media type of a cosign
+	// signature payload.
+	cosignSimpleSigningMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+	// cosignSignatureAnnotation holds the base64 signature over the payload.
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+	// cosignSignatureType is the critical.type of a container image signature.
+	cosignSignatureType = "cosign container image signature"
+
+	// maxSignaturePayloadSize bounds the payload blobs read to verify a
+	// signature; real payloads are a few hundred bytes.
+	maxSignaturePayloadSize = 1 << 20
+)
+
+// ErrNoSignature is returned by VerifyCosignSignature when the registry holds
+// no signature for the image.
+var ErrNoSignature = errors.New("no signature found")
+
+// SignatureTag returns the tag cosign stores the signatures of the image with
+// digest under, in the same repository: sha256-<hex>.sig.
+func SignatureTag(digest string) string {
+	return strings.Replace(digest, ":", "-", 1) + ".sig"
+}
+
+// SignedDigest returns the digest of the image a signature tag belongs to.
+func SignedDigest(tag string) (string, bool) {
+	hexDigest, ok := strings.CutPrefix(tag, "sha256-")
+	if !ok {
+		return "", false
+	}
+	hexDigest, ok = strings.CutSuffix(hexDigest, ".sig")
+	if !ok || len(hexDigest) != sha256.Size*2 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(hexDigest); err != nil {
+		return "", false
+	}
+	return "sha256:" + hexDigest, true
+}
+
+// ParsePublicKey parses a PEM encoded PKIX public key, as written by
+// cosign generate-key-pair. ECDSA, Ed25519 and RSA keys are supported.
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+	if block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("PEM block is %q, want PUBLIC KEY", block.Type)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, ed25519.PublicKey, *rsa.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+// KeyFingerprint identifies a public key as SHA256:<base64> over its PKIX
+// encoding.
+func KeyFingerprint(key crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "invalid key"
+	}
+	sum := sha256.Sum256(der)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+type simpleSigningPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+// VerifyCosignSignature checks that the image with digest in repo carries a
+// cosign signature made with one of keys. Signatures are read from store at
+// SignatureTag(digest), so verification needs nothing beyond the registry
+// that holds the image.
+func VerifyCosignSignature(ctx context.Context, store Storage, repo, digest string, keys []crypto.PublicKey) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("no trusted keys")
+	}
+	// A Mirror reads blobs from the repository in the context.
+	ctx = context.WithValue(ctx, mirrorRepoKey{}, repo)
+	md, err := store.GetManifest(ctx, repo, SignatureTag(digest))
+	if errors.Is(err, ErrManifestNotFound) {
+		return ErrNoSignature
+	}
+	if err != nil {
+		return fmt.Errorf("read signatures: %w", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(md.Data, maxMirrorManifestSize))
+	_ = md.Data.Close()
+	if err != nil {
+		return fmt.Errorf("read signatures: %w", err)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("unmarshal signature manifest: %w", err)
+	}
+	var reasons []string
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != cosignSimpleSigningMediaType {
+			continue
+		}
+		err := verifySignatureLayer(ctx, store, layer, digest, keys)
+		if err == nil {
+			return nil
+		}
+		reasons = append(reasons, err.Error())
+	}
+	if len(reasons) == 0 {
+		return ErrNoSignature
+	}
+	return fmt.Errorf("no signature verifies with a trusted key (%s)", strings.Join(reasons, "; "))
+}
+
+func verifySignatureLayer(ctx context.Context, store Storage, layer ocispec.Descriptor, digest string, keys []crypto.PublicKey) error {
+	sig, err := base64.StdEncoding.DecodeString(layer.Annotations[cosignSignatureAnnotation])
+	if err != nil || len(sig) == 0 {
+		return fmt.Errorf("signature annotation is missing or invalid")
+	}
+	rc, err := store.GetBlob(ctx, layer.Digest.String())
+	if err != nil {
+		return fmt.Errorf("read payload %s: %w", layer.Digest, err)
+	}
+	payload, err := io.ReadAll(io.LimitReader(rc, maxSignaturePayloadSize))
+	_ = rc.Close()
+	if err != nil {
+		return fmt.Errorf("read payload %s: %w", layer.Digest, err)
+	}
+	if err := layer.Digest.Validate(); err != nil || layer.Digest.Algorithm().FromBytes(payload) != layer.Digest {
+		return fmt.Errorf("payload does not match digest %s", layer.Digest)
+	}
+	var p simpleSigningPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("unmarshal payload: %w", err)
+	}
+	if p.Critical.Type != cosignSignatureType {
+		return fmt.Errorf("payload type is %q", p.Critical.Type)
+	}
+	if p.Critical.Image.DockerManifestDigest != digest {
+		return fmt.Errorf("payload signs %s", p.Critical.Image.DockerManifestDigest)
+	}
+	for _, key := range keys {
+		if verifySignature(key, payload, sig) {
+			return nil
+		}
+	}
+	return fmt.Errorf("signature does not match a trusted key")
+}
+
+func verifySignature(key crypto.PublicKey, payload, sig []byte) bool {
+	sum := sha256.Sum256(payload)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, sum[:], sig)
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, payload, sig)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig) == nil
+	default:
+		return false
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package registry
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// putTestSignature stores a cosign signature of digest, signed by sign, in
+// repo of storage.
+func putTestSignature(t *testing.T, storage *FilesystemStorage, repo, digest string, sign func([]byte) []byte) {
+	t.Helper()
+	payload := fmt.Appendf(nil, `{"critical":{"identity":{"docker-reference":%q},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, repo, digest)
+	_, payloadDigest := putTestBlob(t, storage, payload)
+	_, configDigest := putTestBlob(t, storage, []byte(`{"architecture":"","os":"","rootfs":{"type":"layers","diff_ids":[]}}`))
+	manifest := fmt.Appendf(nil, `{"schemaVersion":2,"mediaType":%q,"config":{"mediaType":%q,"digest":%q,"size":1},"layers":[{"mediaType":%q,"digest":%q,"size":%d,"annotations":{%q:%q}}]}`,
+		ocispec.MediaTypeImageManifest, ocispec.MediaTypeImageConfig, configDigest,
+		cosignSimpleSigningMediaType, payloadDigest, len(payload), cosignSignatureAnnotation, base64.StdEncoding.EncodeToString(sign(payload)))
+	if _, err := storage.PutManifest(context.Background(), repo, SignatureTag(digest), manifest, ocispec.MediaTypeImageManifest); err != nil {
+		t.Fatalf("PutManifest signature: %v", err)
+	}
+}
+
+func ecdsaSigner(t *testing.T, key *ecdsa.PrivateKey) func([]byte) []byte {
+	return func(payload []byte) []byte {
+		sum := sha256.Sum256(payload)
+		sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+		if err != nil {
+			t.Fatalf("SignASN1: %v", err)
+		}
+		return sig
+	}
+}
+
+func TestVerifyCosignSignature(t *testing.T) {
+	ctx := context.Background()
+	ciKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trusted := []crypto.PublicKey{edPub, &ciKey.PublicKey}
+	storage := newTestFilesystemStorage(t)
+	const (
+		signed   = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+		edSigned = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+		foreign  = "sha256:3333333333333333333333333333333333333333333333333333333333333333"
+		unsigned = "sha256:4444444444444444444444444444444444444444444444444444444444444444"
+	)
+	putTestSignature(t, storage, "web", signed, ecdsaSigner(t, ciKey))
+	putTestSignature(t, storage, "web", edSigned, func(p []byte) []byte { return ed25519.Sign(edPriv, p) })
+	putTestSignature(t, storage, "web", foreign, ecdsaSigner(t, otherKey))
+
+	for _, digest := range []string{signed, edSigned} {
+		if err := VerifyCosignSignature(ctx, storage, "web", digest, trusted); err != nil {
+			t.Fatalf("VerifyCosignSignature(%s) = %v", digest, err)
+		}
+	}
+	if err := VerifyCosignSignature(ctx, storage, "web", foreign, trusted); err == nil || !strings.Contains(err.Error(), "does not match a trusted key") {
+		t.Fatalf("foreign signature error = %v", err)
+	}
+	if err := VerifyCosignSignature(ctx, storage, "web", unsigned, trusted); !errors.Is(err, ErrNoSignature) {
+		t.Fatalf("unsigned error = %v, want ErrNoSignature", err)
+	}
+	if err := VerifyCosignSignature(ctx, storage, "other", signed, trusted); !errors.Is(err, ErrNoSignature) {
+		t.Fatalf("signature of another repo error = %v, want ErrNoSignature", err)
+	}
+
+	// A signature for one digest does not cover another.
+	md, err := storage.GetManifest(ctx, "web", SignatureTag(signed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := io.ReadAll(md.Data)
+	_ = md.Data.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.PutManifest(ctx, "web", SignatureTag(unsigned), raw, ocispec.MediaTypeImageManifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyCosignSignature(ctx, storage, "web", unsigned, trusted); err == nil || !strings.Contains(err.Error(), "payload signs "+signed) {
+		t.Fatalf("copied signature error = %v", err)
+	}
+}
+
+func TestVerifyCosignSignatureThroughMirror(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	up := newFakeUpstream(t)
+	const digest = "sha256:5555555555555555555555555555555555555555555555555555555555555555"
+	putTestSignature(t, up.storage, "library/nginx", digest, ecdsaSigner(t, key))
+
+	m := newTestMirror(t, up.URL)
+	if err := VerifyCosignSignature(context.Background(), m, "library/nginx", digest, []crypto.PublicKey{&key.PublicKey}); err != nil {
+		t.Fatalf("VerifyCosignSignature through mirror = %v", err)
+	}
+}
+
+func TestParsePublicKeyAndSignatureTags(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("ParsePublicKey: %v", err)
+	}
+	if got, want := KeyFingerprint(pub), KeyFingerprint(&key.PublicKey); got != want || !strings.HasPrefix(got, "SHA256:") {
+		t.Fatalf("KeyFingerprint = %q, want %q", got, want)
+	}
+	if _, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})); err == nil {
+		t.Fatal("ParsePublicKey accepted a private key block")
+	}
+	if _, err := ParsePublicKey([]byte("not pem")); err == nil {
+		t.Fatal("ParsePublicKey accepted non-PEM data")
+	}
+
+	const digest = "sha256:6666666666666666666666666666666666666666666666666666666666666666"
+	tag := SignatureTag(digest)
+	if tag != "sha256-6666666666666666666666666666666666666666666666666666666666666666.sig" {
+		t.Fatalf("SignatureTag = %q", tag)
+	}
+	if got, ok := SignedDigest(tag); !ok || got != digest {
+		t.Fatalf("SignedDigest(%q) = %q, %v", tag, got, ok)
+	}
+	for _, tag := range []string{"latest", "sha256-abc.sig", "sha256-" + strings.Repeat("z", 64) + ".sig", "sha256-" + strings.Repeat("6", 64) + ".att"} {
+		if _, ok := SignedDigest(tag); ok {
+			t.Fatalf("SignedDigest(%q) reported a signature tag", tag)
+		}
+	}
+}
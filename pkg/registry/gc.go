@@ -0,0 +1,224 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ContentInfo describes one blob or manifest held by a storage.
+type ContentInfo struct {
+	// Repo is set for manifests that the storage keeps per repository.
+	Repo   string
+	Digest string
+	Size   int64
+	// UpdatedAt is when the content was last written.
+	UpdatedAt time.Time
+}
+
+// TagInfo is a tag and the manifest digest it points at.
+type TagInfo struct {
+	Repo   string
+	Tag    string
+	Digest string
+}
+
+// Collectable is implemented by storages that can list their content for
+// garbage collection.
+type Collectable interface {
+	// ListContent lists blobs and manifests written through the registry.
+	ListContent(ctx context.Context) ([]ContentInfo, error)
+	// ListTags lists every tag the storage knows about.
+	ListTags(ctx context.Context) ([]TagInfo, error)
+	// RemoveContent deletes content garbage collection found unreachable.
+	RemoveContent(ctx context.Context, c ContentInfo) error
+}
+
+// GCRoot is a manifest that must survive garbage collection, named by tag or
+// digest.
+type GCRoot struct {
+	Repo      string
+	Reference string
+}
+
+// GCOptions configures CollectGarbage.
+type GCOptions struct {
+	Roots []GCRoot
+	// RepoPrefix limits collection to repositories under this prefix. Tags in
+	// other repositories are treated as roots.
+	RepoPrefix string
+	// Before protects content written at or after it, so blobs from a push
+	// that has not sent its manifest yet survive.
+	Before time.Time
+	// DryRun reports what would be removed without removing it.
+	DryRun bool
+}
+
+// GCResult reports what CollectGarbage removed, or would remove on a dry run.
+type GCResult struct {
+	Tags    []TagInfo
+	Content []ContentInfo
+	// Kept counts content that is reachable or too new to collect.
+	Kept int
+}
+
+// Bytes returns the total size of the collected content.
+func (r GCResult) Bytes() int64 {
+	var total int64
+	for _, c := range r.Content {
+		total += c.Size
+	}
+	return total
+}
+
+// CollectGarbage removes tags, manifests and blobs that no root reaches. It
+// fails before removing anything if a reachable manifest cannot be read, so a
+// broken mark phase never deletes live content.
+func CollectGarbage(ctx context.Context, s Storage, opts GCOptions) (GCResult, error) {
+	c, ok := s.(Collectable)
+	if !ok {
+		return GCResult{}, fmt.Errorf("registry storage %T does not support garbage collection", s)
+	}
+	tags, err := c.ListTags(ctx)
+	if err != nil {
+		return GCResult{}, fmt.Errorf("list registry tags: %w", err)
+	}
+	roots := append([]GCRoot(nil), opts.Roots...)
+	for _, tag := range tags {
+		if !inRepoPrefix(tag.Repo, opts.RepoPrefix) {
+			roots = append(roots, GCRoot{Repo: tag.Repo, Reference: tag.Digest})
+		}
+	}
+	m := &gcMarker{s: s, reachable: map[string]struct{}{}}
+	for _, root := range roots {
+		if err := m.markRoot(ctx, root); err != nil {
+			return GCResult{}, err
+		}
+	}
+	content, err := c.ListContent(ctx)
+	if err != nil {
+		return GCResult{}, fmt.Errorf("list registry content: %w", err)
+	}
+
+	var result GCResult
+	for _, tag := range tags {
+		if inRepoPrefix(tag.Repo, opts.RepoPrefix) && !m.isReachable(tag.Digest) {
+			result.Tags = append(result.Tags, tag)
+		}
+	}
+	for _, info := range content {
+		if m.isReachable(info.Digest) || !info.UpdatedAt.Before(opts.Before) {
+			result.Kept++
+			continue
+		}
+		result.Content = append(result.Content, info)
+	}
+	sortGCResult(&result)
+	if opts.DryRun {
+		return result, nil
+	}
+	var errs []error
+	for _, tag := range result.Tags {
+		if err := s.DeleteManifest(ctx, tag.Repo, tag.Tag); err != nil {
+			errs = append(errs, fmt.Errorf("delete tag %s:%s: %w", tag.Repo, tag.Tag, err))
+		}
+	}
+	for _, info := range result.Content {
+		if err := c.RemoveContent(ctx, info); err != nil {
+			errs = append(errs, fmt.Errorf("remove %s: %w", info.Digest, err))
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+func inRepoPrefix(repo, prefix string) bool {
+	return prefix == "" || strings.HasPrefix(repo, prefix+"/")
+}
+
+func sortGCResult(r *GCResult) {
+	sort.Slice(r.Tags, func(i, j int) bool {
+		if r.Tags[i].Repo != r.Tags[j].Repo {
+			return r.Tags[i].Repo < r.Tags[j].Repo
+		}
+		return r.Tags[i].Tag < r.Tags[j].Tag
+	})
+	sort.Slice(r.Content, func(i, j int) bool {
+		if r.Content[i].Repo != r.Content[j].Repo {
+			return r.Content[i].Repo < r.Content[j].Repo
+		}
+		return r.Content[i].Digest < r.Content[j].Digest
+	})
+}
+
+type gcMarker struct {
+	s         Storage
+	reachable map[string]struct{}
+}
+
+// manifestRefs holds the fields of image manifests and indexes that point at
+// other content.
+type manifestRefs struct {
+	Config    *descriptorRef  `json:"config"`
+	Layers    []descriptorRef `json:"layers"`
+	Manifests []descriptorRef `json:"manifests"`
+}
+
+type descriptorRef struct {
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+}
+
+func (m *gcMarker) isReachable(digest string) bool {
+	_, ok := m.reachable[digest]
+	return ok
+}
+
+// markRoot marks a root and everything it references. Roots whose manifest
+// is already gone are skipped.
+func (m *gcMarker) markRoot(ctx context.Context, root GCRoot) error {
+	md, err := m.s.GetManifest(ctx, root.Repo, root.Reference)
+	if errors.Is(err, ErrManifestNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read manifest %s:%s: %w", root.Repo, root.Reference, err)
+	}
+	return m.markManifest(ctx, root.Repo, md)
+}
+
+func (m *gcMarker) markManifest(ctx context.Context, repo string, md *ManifestMetadata) error {
+	data, err := io.ReadAll(md.Data)
+	_ = md.Data.Close()
+	if err != nil {
+		return fmt.Errorf("read manifest %s@%s: %w", repo, md.Digest, err)
+	}
+	if m.isReachable(md.Digest) {
+		return nil
+	}
+	m.reachable[md.Digest] = struct{}{}
+	var manifest manifestRefs
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("parse manifest %s@%s: %w", repo, md.Digest, err)
+	}
+	if manifest.Config != nil && manifest.Config.Digest != "" {
+		m.reachable[manifest.Config.Digest] = struct{}{}
+	}
+	for _, layer := range manifest.Layers {
+		m.reachable[layer.Digest] = struct{}{}
+	}
+	for _, child := range manifest.Manifests {
+		if err := m.markRoot(ctx, GCRoot{Repo: repo, Reference: child.Digest}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func putTestImage(t *testing.T, storage *FilesystemStorage, repo, tag string, layers ...string) string {
+	t.Helper()
+	_, config := putTestBlob(t, storage, []byte("config "+repo+":"+tag))
+	manifest := fmt.Sprintf(`{"schemaVersion":2,"config":{"digest":%q},"layers":[`, config)
+	for i, layer := range layers {
+		if i > 0 {
+			manifest += ","
+		}
+		manifest += fmt.Sprintf(`{"digest":%q}`, layer)
+	}
+	manifest += "]}"
+	dg, err := storage.PutManifest(context.Background(), repo, tag, []byte(manifest), ocispec.MediaTypeImageManifest)
+	if err != nil {
+		t.Fatalf("PutManifest: %v", err)
+	}
+	return dg
+}
+
+func ageTestStorage(t *testing.T, root string) {
+	t.Helper()
+	old := time.Now().Add(-2 * time.Hour)
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		return os.Chtimes(path, old, old)
+	})
+	if err != nil {
+		t.Fatalf("age storage: %v", err)
+	}
+}
+
+func TestCollectGarbageFilesystem(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestFilesystemStorage(t)
+	_, shared := putTestBlob(t, storage, []byte("shared layer"))
+	_, oldOnly := putTestBlob(t, storage, []byte("old layer"))
+	_, orphan := putTestBlob(t, storage, []byte("orphan layer"))
+	live := putTestImage(t, storage, "catchit.dev/api", "run", shared)
+	old := putTestImage(t, storage, "catchit.dev/api", "latest", shared, oldOnly)
+	other := putTestImage(t, storage, "mirror/nginx", "latest", orphan)
+	ageTestStorage(t, storage.rootDir)
+	_, fresh := putTestBlob(t, storage, []byte("pushed, manifest pending"))
+
+	opts := GCOptions{
+		Roots:      []GCRoot{{Repo: "catchit.dev/api", Reference: live}},
+		RepoPrefix: "catchit.dev",
+		Before:     time.Now().Add(-time.Hour),
+		DryRun:     true,
+	}
+	dry, err := CollectGarbage(ctx, storage, opts)
+	if err != nil {
+		t.Fatalf("CollectGarbage dry run: %v", err)
+	}
+	if len(dry.Tags) != 1 || dry.Tags[0].Tag != "latest" || dry.Tags[0].Digest != old {
+		t.Fatalf("dry run tags = %+v, want catchit.dev/api:latest", dry.Tags)
+	}
+	if !storage.ManifestExists(ctx, "catchit.dev/api", "latest") {
+		t.Fatal("dry run removed a tag")
+	}
+
+	opts.DryRun = false
+	result, err := CollectGarbage(ctx, storage, opts)
+	if err != nil {
+		t.Fatalf("CollectGarbage: %v", err)
+	}
+	removed := map[string]bool{}
+	for _, c := range result.Content {
+		removed[c.Digest] = true
+	}
+	if !removed[old] || !removed[oldOnly] || len(result.Content) != 3 {
+		t.Fatalf("removed = %+v, want old manifest, its config and its unique layer", result.Content)
+	}
+	for _, dg := range []string{shared, orphan, fresh} {
+		if !storage.BlobExists(ctx, dg) {
+			t.Fatalf("blob %s was collected", dg)
+		}
+	}
+	if storage.BlobExists(ctx, oldOnly) || storage.ManifestExists(ctx, "catchit.dev/api", old) || storage.ManifestExists(ctx, "catchit.dev/api", "latest") {
+		t.Fatal("unreachable image survived garbage collection")
+	}
+	if !storage.ManifestExists(ctx, "catchit.dev/api", live) || !storage.ManifestExists(ctx, "mirror/nginx", other) {
+		t.Fatal("reachable manifest was collected")
+	}
+	if result.Bytes() <= 0 {
+		t.Fatalf("Bytes() = %d, want freed space", result.Bytes())
+	}
+}
+
+func TestCollectGarbageFollowsIndexes(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestFilesystemStorage(t)
+	_, layer := putTestBlob(t, storage, []byte("amd64 layer"))
+	child := putTestImage(t, storage, "catchit.dev/api", "amd64", layer)
+	index := fmt.Sprintf(`{"schemaVersion":2,"manifests":[{"digest":%q},{"digest":"sha256:missing"}]}`, child)
+	root, err := storage.PutManifest(ctx, "catchit.dev/api", "run", []byte(index), ocispec.MediaTypeImageIndex)
+	if err != nil {
+		t.Fatalf("PutManifest index: %v", err)
+	}
+	if err := storage.DeleteManifest(ctx, "catchit.dev/api", "amd64"); err != nil {
+		t.Fatalf("DeleteManifest: %v", err)
+	}
+	ageTestStorage(t, storage.rootDir)
+
+	result, err := CollectGarbage(ctx, storage, GCOptions{
+		Roots:  []GCRoot{{Repo: "catchit.dev/api", Reference: root}},
+		Before: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("CollectGarbage: %v", err)
+	}
+	if len(result.Content) != 0 || len(result.Tags) != 0 {
+		t.Fatalf("collected %+v from a reachable index", result)
+	}
+}
+
+func TestCollectGarbageAbortsOnUnreadableManifest(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestFilesystemStorage(t)
+	root, err := storage.PutManifest(ctx, "catchit.dev/api", "run", []byte("not json"), ocispec.MediaTypeImageManifest)
+	if err != nil {
+		t.Fatalf("PutManifest: %v", err)
+	}
+	_, orphan := putTestBlob(t, storage, []byte("orphan"))
+	ageTestStorage(t, storage.rootDir)
+	if _, err := CollectGarbage(ctx, storage, GCOptions{Roots: []GCRoot{{Repo: "catchit.dev/api", Reference: root}}, Before: time.Now()}); err == nil {
+		t.Fatal("CollectGarbage succeeded with an unparseable root manifest")
+	}
+	if !storage.BlobExists(ctx, orphan) {
+		t.Fatal("blob removed after a failed mark phase")
+	}
+}
+
+func TestContainerdListContentSkipsSharedContent(t *testing.T) {
+	ours := digest.FromString("ours")
+	shared := digest.FromString("shared")
+	dockerManifest := digest.FromString("docker manifest")
+	now := time.Now()
+	storage := &ContainerdCacheStorage{contentStore: &fakeContentStore{info: map[digest.Digest]content.Info{
+		ours:           {Digest: ours, Size: 10, UpdatedAt: now, Labels: map[string]string{containerdGCRootLabel: "x"}},
+		shared:         {Digest: shared, Size: 20, UpdatedAt: now, Labels: map[string]string{containerdGCRootLabel: "x"}},
+		dockerManifest: {Digest: dockerManifest, Labels: map[string]string{containerdGCRefContentLabel + "l.0": shared.String()}},
+	}}}
+	got, err := storage.ListContent(context.Background())
+	if err != nil {
+		t.Fatalf("ListContent: %v", err)
+	}
+	if len(got) != 1 || got[0].Digest != ours.String() || got[0].Size != 10 {
+		t.Fatalf("ListContent = %+v, want only registry-owned content", got)
+	}
+}
+
+func TestSplitContainerdImageName(t *testing.T) {
+	for _, tc := range []struct{ name, repo, tag string }{
+		{"catchit.dev/api:run", "catchit.dev/api", "run"},
+		{"localhost:5000/app:v1", "localhost:5000/app", "v1"},
+		{"localhost:5000/app", "localhost:5000/app", ""},
+		{"docker.io/library/nginx:latest@sha256:abc", "docker.io/library/nginx", "latest"},
+	} {
+		repo, tag := splitContainerdImageName(tc.name)
+		if repo != tc.repo || tag != tc.tag {
+			t.Errorf("splitContainerdImageName(%q) = %q, %q; want %q, %q", tc.name, repo, tag, tc.repo, tc.tag)
+		}
+	}
+}
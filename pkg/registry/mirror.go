@@ -0,0 +1,515 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// ErrMirrorReadOnly is returned by the write methods of a Mirror.
+var ErrMirrorReadOnly = errors.New("registry mirror is read-only")
+
+// DefaultMirrorTTL is how long a manifest fetched by tag is served from the
+// cache before the mirror checks the upstream for a newer one.
+const DefaultMirrorTTL = 10 * time.Minute
+
+// DefaultMirrorCacheMaxAge is how long EvictMirrorCache keeps an image the
+// mirror fetched before dropping it from the cache.
+const DefaultMirrorCacheMaxAge = 30 * 24 * time.Hour
+
+// maxMirrorManifestSize bounds the manifests the mirror reads from upstream.
+const maxMirrorManifestSize = 4 << 20
+
+// mirrorManifestAccept lists the manifest media types the mirror asks the
+// upstream for, in the order a Docker daemon would.
+var mirrorManifestAccept = []string{
+	ocispec.MediaTypeImageIndex,
+	ocispec.MediaTypeImageManifest,
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// MirrorConfig configures a pull-through Mirror.
+type MirrorConfig struct {
+	// Upstream is the base URL of the upstream registry, for example
+	// https://registry-1.docker.io.
+	Upstream string
+	// Cache stores the manifests and blobs fetched from the upstream.
+	Cache Storage
+	// TTL is how long a tag is trusted before it is revalidated upstream.
+	// Zero uses DefaultMirrorTTL.
+	TTL time.Duration
+	// Client is the HTTP client for upstream requests. Nil uses
+	// http.DefaultClient.
+	Client *http.Client
+}
+
+// Mirror is a read-only Storage that pulls through to an upstream registry
+// and keeps what it fetches in a cache Storage. Digests and blobs are
+// immutable and served from the cache once fetched. Tags are revalidated with
+// a HEAD request after the TTL, and when the upstream cannot be reached the
+// cached manifest is served instead.
+type Mirror struct {
+	upstream *url.URL
+	cache    Storage
+	ttl      time.Duration
+	client   *http.Client
+	now      func() time.Time
+	handler  http.Handler
+
+	mu sync.Mutex
+	// fetched records when each repo:tag was last confirmed upstream.
+	fetched map[string]time.Time
+	// tokens caches bearer tokens by scope.
+	tokens map[string]mirrorToken
+}
+
+type mirrorToken struct {
+	token   string
+	expires time.Time
+}
+
+var _ Storage = (*Mirror)(nil)
+
+// NewMirror returns a Mirror for cfg.
+func NewMirror(cfg MirrorConfig) (*Mirror, error) {
+	upstream, err := url.Parse(strings.TrimSuffix(cfg.Upstream, "/"))
+	if err != nil || upstream.Scheme == "" || upstream.Host == "" {
+		return nil, fmt.Errorf("invalid upstream registry URL %q", cfg.Upstream)
+	}
+	if cfg.Cache == nil {
+		return nil, fmt.Errorf("mirror cache storage is required")
+	}
+	m := &Mirror{
+		upstream: upstream,
+		cache:    cfg.Cache,
+		ttl:      cfg.TTL,
+		client:   cfg.Client,
+		now:      time.Now,
+		fetched:  map[string]time.Time{},
+		tokens:   map[string]mirrorToken{},
+	}
+	if m.ttl <= 0 {
+		m.ttl = DefaultMirrorTTL
+	}
+	if m.client == nil {
+		m.client = http.DefaultClient
+	}
+	m.handler = New(m)
+	return m, nil
+}
+
+type mirrorRepoKey struct{}
+
+// ServeHTTP serves the pull side of the registry API. Blob requests do not
+// pass the repository to Storage, so it travels in the request context for
+// upstream fetches.
+func (m *Mirror) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		WriteError(w, http.StatusMethodNotAllowed, ErrCodeUnsupported, ErrMirrorReadOnly.Error(), nil)
+		return
+	}
+	if p, err := ParseRegistryPath(req.URL.Path); err == nil {
+		req = req.WithContext(context.WithValue(req.Context(), mirrorRepoKey{}, p.Repo))
+	}
+	m.handler.ServeHTTP(w, req)
+}
+
+func mirrorRepo(ctx context.Context) string {
+	repo, _ := ctx.Value(mirrorRepoKey{}).(string)
+	return repo
+}
+
+// GetManifest returns a cached manifest, fetching or revalidating it upstream
+// as needed.
+func (m *Mirror) GetManifest(ctx context.Context, repo, reference string) (*ManifestMetadata, error) {
+	if strings.HasPrefix(reference, "sha256:") {
+		if md, err := m.cache.GetManifest(ctx, repo, reference); err == nil {
+			return md, nil
+		}
+		return m.fetchManifest(ctx, repo, reference)
+	}
+	if m.fresh(repo, reference) {
+		if md, err := m.cache.GetManifest(ctx, repo, reference); err == nil {
+			return md, nil
+		}
+	}
+	md, err := m.revalidateManifest(ctx, repo, reference)
+	if err == nil || errors.Is(err, ErrManifestNotFound) {
+		return md, err
+	}
+	cached, cacheErr := m.cache.GetManifest(ctx, repo, reference)
+	if cacheErr != nil {
+		return nil, err
+	}
+	log.Printf("registry mirror: serving cached %s:%s; upstream failed: %v", repo, reference, err)
+	return cached, nil
+}
+
+func (m *Mirror) fresh(repo, tag string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	at, ok := m.fetched[repo+":"+tag]
+	return ok && m.now().Sub(at) < m.ttl
+}
+
+func (m *Mirror) markFetched(repo, tag string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fetched[repo+":"+tag] = m.now()
+}
+
+// revalidateManifest checks a tag upstream with HEAD, which does not count
+// against Docker Hub pull limits, and only downloads the manifest when the
+// digest moved.
+func (m *Mirror) revalidateManifest(ctx context.Context, repo, tag string) (*ManifestMetadata, error) {
+	cached, err := m.cache.GetManifest(ctx, repo, tag)
+	if err != nil {
+		return m.fetchManifest(ctx, repo, tag)
+	}
+	resp, err := m.upstreamRequest(ctx, http.MethodHead, repo, "/manifests/"+tag, mirrorManifestAccept)
+	if err != nil {
+		_ = cached.Data.Close()
+		return nil, err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode == http.StatusOK && resp.Header.Get("Docker-Content-Digest") == cached.Digest {
+		m.markFetched(repo, tag)
+		return cached, nil
+	}
+	_ = cached.Data.Close()
+	return m.fetchManifest(ctx, repo, tag)
+}
+
+func (m *Mirror) fetchManifest(ctx context.Context, repo, reference string) (*ManifestMetadata, error) {
+	resp, err := m.upstreamRequest(ctx, http.MethodGet, repo, "/manifests/"+reference, mirrorManifestAccept)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrManifestNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, upstreamStatusError(resp, repo, reference)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxMirrorManifestSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read upstream manifest %s:%s: %w", repo, reference, err)
+	}
+	if len(data) > maxMirrorManifestSize {
+		return nil, fmt.Errorf("upstream manifest %s:%s is larger than %d bytes", repo, reference, maxMirrorManifestSize)
+	}
+	digest, err := computeDigest(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if want := resp.Header.Get("Docker-Content-Digest"); want != "" && want != digest {
+		return nil, fmt.Errorf("upstream manifest %s:%s: %w", repo, reference, ErrDigestMismatch)
+	}
+	if strings.HasPrefix(reference, "sha256:") && reference != digest {
+		return nil, fmt.Errorf("upstream manifest %s@%s: %w", repo, reference, ErrDigestMismatch)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "" {
+		mediaType = ocispec.MediaTypeImageManifest
+	}
+	if _, err := m.cache.PutManifest(ctx, repo, reference, data, mediaType); err != nil {
+		return nil, fmt.Errorf("cache manifest %s:%s: %w", repo, reference, err)
+	}
+	if !strings.HasPrefix(reference, "sha256:") {
+		m.markFetched(repo, reference)
+	}
+	return &ManifestMetadata{
+		MediaType: mediaType,
+		Digest:    digest,
+		Size:      int64(len(data)),
+		Data:      io.NopCloser(bytes.NewReader(data)),
+	}, nil
+}
+
+// GetBlob returns a cached blob, downloading it into the cache first when
+// needed.
+func (m *Mirror) GetBlob(ctx context.Context, digest string) (io.ReadCloser, error) {
+	if rc, err := m.cache.GetBlob(ctx, digest); err == nil {
+		return rc, nil
+	}
+	if err := m.fetchBlob(ctx, digest); err != nil {
+		return nil, err
+	}
+	return m.cache.GetBlob(ctx, digest)
+}
+
+func (m *Mirror) fetchBlob(ctx context.Context, digest string) error {
+	repo := mirrorRepo(ctx)
+	if repo == "" {
+		return ErrBlobNotFound
+	}
+	resp, err := m.upstreamRequest(ctx, http.MethodGet, repo, "/blobs/"+digest, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrBlobNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return upstreamStatusError(resp, repo, digest)
+	}
+	session, err := m.cache.NewUpload(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := m.cache.CopyChunk(ctx, session.UUID, resp.Body); err != nil {
+		_ = m.cache.AbortUpload(ctx, session.UUID)
+		return fmt.Errorf("download blob %s: %w", digest, err)
+	}
+	if _, err := m.cache.CompleteUpload(ctx, session.UUID, digest); err != nil {
+		_ = m.cache.AbortUpload(ctx, session.UUID)
+		return fmt.Errorf("cache blob %s: %w", digest, err)
+	}
+	return nil
+}
+
+// BlobSize returns the size of a cached blob, or asks the upstream without
+// downloading it.
+func (m *Mirror) BlobSize(ctx context.Context, digest string) (int64, error) {
+	if size, err := m.cache.BlobSize(ctx, digest); err == nil {
+		return size, nil
+	}
+	repo := mirrorRepo(ctx)
+	if repo == "" {
+		return 0, ErrBlobNotFound
+	}
+	resp, err := m.upstreamRequest(ctx, http.MethodHead, repo, "/blobs/"+digest, nil)
+	if err != nil {
+		return 0, err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, ErrBlobNotFound
+	}
+	if resp.StatusCode != http.StatusOK || resp.ContentLength < 0 {
+		return 0, upstreamStatusError(resp, repo, digest)
+	}
+	return resp.ContentLength, nil
+}
+
+// BlobExists reports whether the blob is cached or available upstream.
+func (m *Mirror) BlobExists(ctx context.Context, digest string) bool {
+	_, err := m.BlobSize(ctx, digest)
+	return err == nil
+}
+
+// ManifestExists reports whether the manifest is cached or available upstream.
+func (m *Mirror) ManifestExists(ctx context.Context, repo, reference string) bool {
+	md, err := m.GetManifest(ctx, repo, reference)
+	if err != nil {
+		return false
+	}
+	_ = md.Data.Close()
+	return true
+}
+
+func (m *Mirror) DeleteBlob(context.Context, string) error { return ErrMirrorReadOnly }
+
+func (m *Mirror) PutManifest(context.Context, string, string, []byte, string) (string, error) {
+	return "", ErrMirrorReadOnly
+}
+
+func (m *Mirror) DeleteManifest(context.Context, string, string) error { return ErrMirrorReadOnly }
+
+func (m *Mirror) NewUpload(context.Context) (*UploadSession, error) { return nil, ErrMirrorReadOnly }
+
+func (m *Mirror) GetUpload(context.Context, string) (*UploadSession, error) {
+	return nil, ErrMirrorReadOnly
+}
+
+func (m *Mirror) CopyChunk(context.Context, string, io.Reader) (*UploadSession, error) {
+	return nil, ErrMirrorReadOnly
+}
+
+func (m *Mirror) CompleteUpload(context.Context, string, string) (string, error) {
+	return "", ErrMirrorReadOnly
+}
+
+func (m *Mirror) AbortUpload(context.Context, string) error { return ErrMirrorReadOnly }
+
+func upstreamStatusError(resp *http.Response, repo, reference string) error {
+	return fmt.Errorf("upstream %s %s@%s: %s", resp.Request.URL.Host, repo, reference, resp.Status)
+}
+
+// upstreamRequest sends a request for repo to the upstream, answering a
+// bearer token challenge once if the upstream asks for one.
+func (m *Mirror) upstreamRequest(ctx context.Context, method, repo, subpath string, accept []string) (*http.Response, error) {
+	scope := "repository:" + repo + ":pull"
+	do := func(token string) (*http.Response, error) {
+		u := *m.upstream
+		u.Path = u.Path + "/v2/" + repo + subpath
+		req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, mt := range accept {
+			req.Header.Add("Accept", mt)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := m.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("upstream %s: %w", m.upstream.Host, err)
+		}
+		return resp, nil
+	}
+	resp, err := do(m.cachedToken(scope))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge, ok := parseBearerChallenge(resp.Header.Get("WWW-Authenticate"))
+	_ = resp.Body.Close()
+	if !ok {
+		return nil, fmt.Errorf("upstream %s: unauthorized", m.upstream.Host)
+	}
+	token, err := m.fetchToken(ctx, challenge, scope)
+	if err != nil {
+		return nil, err
+	}
+	return do(token)
+}
+
+func (m *Mirror) cachedToken(scope string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tok, ok := m.tokens[scope]
+	if !ok || !m.now().Before(tok.expires) {
+		return ""
+	}
+	return tok.token
+}
+
+// fetchToken gets an anonymous pull token from the realm of a bearer
+// challenge.
+func (m *Mirror) fetchToken(ctx context.Context, challenge map[string]string, scope string) (string, error) {
+	realm, err := url.Parse(challenge["realm"])
+	if err != nil || realm.Scheme == "" {
+		return "", fmt.Errorf("upstream %s: invalid token realm %q", m.upstream.Host, challenge["realm"])
+	}
+	q := realm.Query()
+	if service := challenge["service"]; service != "" {
+		q.Set("service", service)
+	}
+	q.Set("scope", scope)
+	realm.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("upstream token %s: %w", realm.Host, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("upstream token %s: %s", realm.Host, resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode upstream token: %w", err)
+	}
+	token := body.Token
+	if token == "" {
+		token = body.AccessToken
+	}
+	if token == "" {
+		return "", fmt.Errorf("upstream token %s: empty token", realm.Host)
+	}
+	// The token spec defaults to 60 seconds; renew a little early.
+	lifetime := 60 * time.Second
+	if body.ExpiresIn > 0 {
+		lifetime = time.Duration(body.ExpiresIn) * time.Second
+	}
+	m.mu.Lock()
+	m.tokens[scope] = mirrorToken{token: token, expires: m.now().Add(lifetime - lifetime/10)}
+	m.mu.Unlock()
+	return token, nil
+}
+
+// parseBearerChallenge parses a WWW-Authenticate header like
+// `Bearer realm="https://auth.docker.io/token",service="registry.docker.io"`.
+func parseBearerChallenge(header string) (map[string]string, bool) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return nil, false
+	}
+	params := map[string]string{}
+	for rest = strings.TrimSpace(rest); rest != ""; {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				return nil, false
+			}
+			params[key] = value[1 : end+1]
+			rest = value[end+2:]
+		} else {
+			v, after, _ := strings.Cut(value, ",")
+			params[key] = strings.TrimSpace(v)
+			rest = "," + after
+		}
+		rest = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), ","))
+	}
+	_, ok := params["realm"]
+	return params, ok
+}
+
+// EvictMirrorCache removes images a mirror cache fetched before now minus
+// maxAge: their manifests, the tags pointing at them, and the blobs no newer
+// manifest shares. A tag revalidated upstream keeps the age of its last
+// download, so an image in steady use is fetched again once per maxAge.
+func EvictMirrorCache(ctx context.Context, cache Storage, maxAge time.Duration, now time.Time, dryRun bool) (GCResult, error) {
+	c, ok := cache.(Collectable)
+	if !ok {
+		return GCResult{}, fmt.Errorf("registry mirror cache %T does not support eviction", cache)
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultMirrorCacheMaxAge
+	}
+	content, err := c.ListContent(ctx)
+	if err != nil {
+		return GCResult{}, fmt.Errorf("list registry mirror cache: %w", err)
+	}
+	cutoff := now.Add(-maxAge)
+	var roots []GCRoot
+	for _, info := range content {
+		if info.Repo != "" && !info.UpdatedAt.Before(cutoff) {
+			roots = append(roots, GCRoot{Repo: info.Repo, Reference: info.Digest})
+		}
+	}
+	return CollectGarbage(ctx, cache, GCOptions{Roots: roots, Before: cutoff, DryRun: dryRun})
+}
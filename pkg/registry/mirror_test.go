@@ -0,0 +1,230 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// fakeUpstream is a token-protected registry like Docker Hub that counts the
+// registry requests it serves.
+type fakeUpstream struct {
+	*httptest.Server
+	storage *FilesystemStorage
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func newFakeUpstream(t *testing.T) *fakeUpstream {
+	t.Helper()
+	up := &fakeUpstream{storage: newTestFilesystemStorage(t)}
+	registry := NewHandler(up.storage)
+	up.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/token" {
+			if req.URL.Query().Get("scope") != "repository:library/nginx:pull" {
+				http.Error(w, "bad scope", http.StatusForbidden)
+				return
+			}
+			_, _ = io.WriteString(w, `{"token":"secret","expires_in":300}`)
+			return
+		}
+		if req.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake"`, up.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		up.mu.Lock()
+		up.requests = append(up.requests, req.Method+" "+req.URL.Path)
+		up.mu.Unlock()
+		registry.ServeHTTP(w, req)
+	}))
+	t.Cleanup(up.Close)
+	return up
+}
+
+func (up *fakeUpstream) takeRequests() []string {
+	up.mu.Lock()
+	defer up.mu.Unlock()
+	reqs := up.requests
+	up.requests = nil
+	return reqs
+}
+
+func newTestMirror(t *testing.T, upstream string) *Mirror {
+	t.Helper()
+	m, err := NewMirror(MirrorConfig{Upstream: upstream, Cache: newTestFilesystemStorage(t), TTL: time.Minute})
+	if err != nil {
+		t.Fatalf("NewMirror: %v", err)
+	}
+	return m
+}
+
+func mirrorGet(t *testing.T, m *Mirror, method, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	m.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+	return rr
+}
+
+func TestMirrorPullsThroughAndCaches(t *testing.T) {
+	up := newFakeUpstream(t)
+	_, layer := putTestBlob(t, up.storage, []byte("nginx layer"))
+	manifestDigest := putTestImage(t, up.storage, "library/nginx", "latest", layer)
+	m := newTestMirror(t, up.URL)
+
+	resp := mirrorGet(t, m, http.MethodGet, "/v2/library/nginx/manifests/latest")
+	if resp.Code != http.StatusOK || resp.Header().Get("Docker-Content-Digest") != manifestDigest {
+		t.Fatalf("manifest status=%d digest=%q, want 200 %s", resp.Code, resp.Header().Get("Docker-Content-Digest"), manifestDigest)
+	}
+	resp = mirrorGet(t, m, http.MethodGet, "/v2/library/nginx/blobs/"+layer)
+	if resp.Code != http.StatusOK || resp.Body.String() != "nginx layer" {
+		t.Fatalf("blob status=%d body=%q", resp.Code, resp.Body.String())
+	}
+	if got := up.takeRequests(); len(got) != 2 {
+		t.Fatalf("first pull upstream requests = %q, want manifest and blob", got)
+	}
+
+	for _, path := range []string{
+		"/v2/library/nginx/manifests/latest",
+		"/v2/library/nginx/manifests/" + manifestDigest,
+		"/v2/library/nginx/blobs/" + layer,
+	} {
+		if resp := mirrorGet(t, m, http.MethodGet, path); resp.Code != http.StatusOK {
+			t.Fatalf("cached GET %s status=%d", path, resp.Code)
+		}
+	}
+	if got := up.takeRequests(); len(got) != 0 {
+		t.Fatalf("cached pull upstream requests = %q, want none", got)
+	}
+
+	if resp := mirrorGet(t, m, http.MethodGet, "/v2/library/nginx/manifests/missing"); resp.Code != http.StatusNotFound {
+		t.Fatalf("missing tag status=%d, want 404", resp.Code)
+	}
+	if resp := mirrorGet(t, m, http.MethodPut, "/v2/library/nginx/manifests/latest"); resp.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("PUT status=%d, want 405", resp.Code)
+	}
+}
+
+func TestMirrorRevalidatesTagsAfterTTL(t *testing.T) {
+	up := newFakeUpstream(t)
+	first := putTestImage(t, up.storage, "library/nginx", "latest")
+	m := newTestMirror(t, up.URL)
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	mirrorGet(t, m, http.MethodGet, "/v2/library/nginx/manifests/latest")
+	up.takeRequests()
+
+	now = now.Add(2 * time.Minute)
+	resp := mirrorGet(t, m, http.MethodGet, "/v2/library/nginx/manifests/latest")
+	if resp.Header().Get("Docker-Content-Digest") != first {
+		t.Fatalf("digest=%q, want unchanged %s", resp.Header().Get("Docker-Content-Digest"), first)
+	}
+	if got, want := up.takeRequests(), []string{"HEAD /v2/library/nginx/manifests/latest"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unchanged revalidation requests = %q, want %q", got, want)
+	}
+
+	now = now.Add(2 * time.Minute)
+	next, err := up.storage.PutManifest(context.Background(), "library/nginx", "latest", []byte(`{"schemaVersion":2,"layers":[]}`), ocispec.MediaTypeImageManifest)
+	if err != nil {
+		t.Fatalf("PutManifest: %v", err)
+	}
+	resp = mirrorGet(t, m, http.MethodGet, "/v2/library/nginx/manifests/latest")
+	if resp.Header().Get("Docker-Content-Digest") != next {
+		t.Fatalf("digest=%q, want updated %s", resp.Header().Get("Docker-Content-Digest"), next)
+	}
+	if got := up.takeRequests(); len(got) != 2 || !strings.HasPrefix(got[1], "GET ") {
+		t.Fatalf("changed revalidation requests = %q, want HEAD then GET", got)
+	}
+}
+
+func TestMirrorServesCacheWhenUpstreamIsDown(t *testing.T) {
+	up := newFakeUpstream(t)
+	_, layer := putTestBlob(t, up.storage, []byte("nginx layer"))
+	_, uncached := putTestBlob(t, up.storage, []byte("never pulled"))
+	putTestImage(t, up.storage, "library/nginx", "latest", layer)
+	m := newTestMirror(t, up.URL)
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	mirrorGet(t, m, http.MethodGet, "/v2/library/nginx/manifests/latest")
+	mirrorGet(t, m, http.MethodGet, "/v2/library/nginx/blobs/"+layer)
+
+	up.Close()
+	now = now.Add(time.Hour)
+	if resp := mirrorGet(t, m, http.MethodGet, "/v2/library/nginx/manifests/latest"); resp.Code != http.StatusOK {
+		t.Fatalf("stale manifest status=%d, want 200", resp.Code)
+	}
+	if resp := mirrorGet(t, m, http.MethodHead, "/v2/library/nginx/blobs/"+layer); resp.Code != http.StatusOK {
+		t.Fatalf("cached blob HEAD status=%d, want 200", resp.Code)
+	}
+	if resp := mirrorGet(t, m, http.MethodGet, "/v2/library/nginx/blobs/"+uncached); resp.Code == http.StatusOK {
+		t.Fatal("uncached blob succeeded with the upstream down")
+	}
+	if resp := mirrorGet(t, m, http.MethodGet, "/v2/library/redis/manifests/latest"); resp.Code == http.StatusOK {
+		t.Fatal("uncached manifest succeeded with the upstream down")
+	}
+}
+
+func TestParseBearerChallenge(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		want   map[string]string
+		ok     bool
+	}{
+		{
+			header: `Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"`,
+			want:   map[string]string{"realm": "https://auth.docker.io/token", "service": "registry.docker.io", "scope": "repository:library/nginx:pull"},
+			ok:     true,
+		},
+		{header: `Bearer realm=https://ghcr.io/token, service=ghcr.io`, want: map[string]string{"realm": "https://ghcr.io/token", "service": "ghcr.io"}, ok: true},
+		{header: `Basic realm="registry"`},
+		{header: `Bearer service="x"`, want: map[string]string{"service": "x"}},
+	} {
+		got, ok := parseBearerChallenge(tc.header)
+		if ok != tc.ok || (tc.want != nil && !reflect.DeepEqual(got, tc.want)) {
+			t.Errorf("parseBearerChallenge(%q) = %v, %v; want %v, %v", tc.header, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestEvictMirrorCacheDropsOldImages(t *testing.T) {
+	ctx := context.Background()
+	cache := newTestFilesystemStorage(t)
+	_, shared := putTestBlob(t, cache, []byte("shared layer"))
+	_, oldOnly := putTestBlob(t, cache, []byte("old layer"))
+	old := putTestImage(t, cache, "library/nginx", "1.26", shared, oldOnly)
+	ageTestStorage(t, cache.rootDir)
+	recent := putTestImage(t, cache, "library/nginx", "1.27", shared)
+
+	dry, err := EvictMirrorCache(ctx, cache, time.Hour, time.Now(), true)
+	if err != nil {
+		t.Fatalf("EvictMirrorCache dry run: %v", err)
+	}
+	if len(dry.Tags) != 1 || dry.Tags[0].Tag != "1.26" || !cache.ManifestExists(ctx, "library/nginx", "1.26") {
+		t.Fatalf("dry run tags = %+v, want library/nginx:1.26 reported and kept", dry.Tags)
+	}
+
+	if _, err := EvictMirrorCache(ctx, cache, time.Hour, time.Now(), false); err != nil {
+		t.Fatalf("EvictMirrorCache: %v", err)
+	}
+	if cache.ManifestExists(ctx, "library/nginx", "1.26") || cache.ManifestExists(ctx, "library/nginx", old) || cache.BlobExists(ctx, oldOnly) {
+		t.Fatal("old mirrored image survived eviction")
+	}
+	if !cache.ManifestExists(ctx, "library/nginx", "1.27") || !cache.ManifestExists(ctx, "library/nginx", recent) || !cache.BlobExists(ctx, shared) {
+		t.Fatal("recent mirrored image was evicted")
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	mediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeDockerImageConfig  = "application/vnd.docker.container.image.v1+json"
+
+	// maxImageConfigSize bounds the config blob read to learn an image's
+	// platform; real configs are a few KiB.
+	maxImageConfigSize = 4 << 20
+)
+
+// PlatformString formats p as os/arch[/variant].
+func PlatformString(p ocispec.Platform) string {
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+// PlatformMatches reports whether an image built for p runs on host.
+// Variants only matter when both sides name one; arm64 is always v8.
+func PlatformMatches(p, host ocispec.Platform) bool {
+	if p.OS != host.OS || p.Architecture != host.Architecture {
+		return false
+	}
+	pv, hv := normalizeVariant(p), normalizeVariant(host)
+	return pv == "" || hv == "" || pv == hv
+}
+
+func normalizeVariant(p ocispec.Platform) string {
+	if p.Architecture == "arm64" && p.Variant == "v8" {
+		return ""
+	}
+	return p.Variant
+}
+
+// ManifestPlatforms returns the platforms an image manifest or index
+// provides. An image manifest names its platform in the config blob, read
+// from blobs. Index entries without a platform, or with unknown/unknown
+// (build attestations), are skipped. Manifests for other artifacts, such as
+// signatures, and configs without an OS report no platforms.
+func ManifestPlatforms(ctx context.Context, blobs Storage, data []byte, mediaType string) ([]ocispec.Platform, error) {
+	switch mediaType {
+	case ocispec.MediaTypeImageIndex, mediaTypeDockerManifestList:
+		var index ocispec.Index
+		if err := json.Unmarshal(data, &index); err != nil {
+			return nil, fmt.Errorf("unmarshal index: %w", err)
+		}
+		var out []ocispec.Platform
+		for _, m := range index.Manifests {
+			if m.Platform == nil || m.Platform.OS == "unknown" || m.Platform.OS == "" {
+				continue
+			}
+			out = append(out, *m.Platform)
+		}
+		return out, nil
+	case ocispec.MediaTypeImageManifest, mediaTypeDockerManifest:
+		var manifest ocispec.Manifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, fmt.Errorf("unmarshal manifest: %w", err)
+		}
+		switch manifest.Config.MediaType {
+		case ocispec.MediaTypeImageConfig, mediaTypeDockerImageConfig:
+		default:
+			return nil, nil
+		}
+		p, err := imageConfigPlatform(ctx, blobs, manifest.Config.Digest.String())
+		if err != nil || p.OS == "" {
+			return nil, err
+		}
+		return []ocispec.Platform{p}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func imageConfigPlatform(ctx context.Context, blobs Storage, digest string) (ocispec.Platform, error) {
+	rc, err := blobs.GetBlob(ctx, digest)
+	if err != nil {
+		return ocispec.Platform{}, fmt.Errorf("read image config %s: %w", digest, err)
+	}
+	defer func() { _ = rc.Close() }()
+	var config ocispec.Image
+	if err := json.NewDecoder(io.LimitReader(rc, maxImageConfigSize)).Decode(&config); err != nil {
+		return ocispec.Platform{}, fmt.Errorf("decode image config %s: %w", digest, err)
+	}
+	return config.Platform, nil
+}
+
+// CheckManifestPlatform refuses an image none of whose platforms run on
+// host. The error is a MANIFEST_INVALID descriptor, so a pushing client
+// sees the reason. Manifests that report no platform are accepted.
+func CheckManifestPlatform(ctx context.Context, blobs Storage, data []byte, mediaType string, host ocispec.Platform) error {
+	platforms, err := ManifestPlatforms(ctx, blobs, data, mediaType)
+	if err != nil || len(platforms) == 0 {
+		return err
+	}
+	names := make([]string, 0, len(platforms))
+	for _, p := range platforms {
+		if PlatformMatches(p, host) {
+			return nil
+		}
+		names = append(names, PlatformString(p))
+	}
+	want := PlatformString(host)
+	return NewError(ErrCodeManifestInvalid, fmt.Sprintf(
+		"image is built for %s, but this host runs %s; rebuild it with docker build --platform %s",
+		strings.Join(names, ", "), want, want))
+}
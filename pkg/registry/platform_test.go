@@ -0,0 +1,78 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestCheckManifestPlatform(t *testing.T) {
+	storage := newTestFilesystemStorage(t)
+	_, amd64Config := putTestBlob(t, storage, []byte(`{"os":"linux","architecture":"amd64"}`))
+	_, arm64Config := putTestBlob(t, storage, []byte(`{"os":"linux","architecture":"arm64","variant":"v8"}`))
+	_, emptyConfig := putTestBlob(t, storage, []byte(`{"os":"","architecture":""}`))
+	manifest := func(configMediaType, digest string) []byte {
+		return fmt.Appendf(nil, `{"schemaVersion":2,"mediaType":%q,"config":{"mediaType":%q,"digest":%q,"size":1},"layers":[]}`,
+			ocispec.MediaTypeImageManifest, configMediaType, digest)
+	}
+	index := `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[
+		{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":"sha256:aaa","size":1,"platform":{"os":"linux","architecture":"amd64"}},
+		{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":"sha256:bbb","size":1,"platform":{"os":"unknown","architecture":"unknown"}}%s]}`
+	host := ocispec.Platform{OS: "linux", Architecture: "arm64"}
+
+	tests := []struct {
+		name      string
+		data      []byte
+		mediaType string
+		wantErr   string
+	}{
+		{name: "matching image", data: manifest(ocispec.MediaTypeImageConfig, arm64Config), mediaType: ocispec.MediaTypeImageManifest},
+		{name: "docker config media type", data: manifest(mediaTypeDockerImageConfig, amd64Config), mediaType: mediaTypeDockerManifest, wantErr: "built for linux/amd64, but this host runs linux/arm64"},
+		{name: "signature artifact", data: manifest("application/vnd.dev.cosign.simplesigning.v1+json", amd64Config), mediaType: ocispec.MediaTypeImageManifest},
+		{name: "cosign signature config", data: manifest(ocispec.MediaTypeImageConfig, emptyConfig), mediaType: ocispec.MediaTypeImageManifest},
+		{name: "index with host platform", data: fmt.Appendf(nil, index, `,{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":"sha256:ccc","size":1,"platform":{"os":"linux","architecture":"arm64","variant":"v8"}}`), mediaType: ocispec.MediaTypeImageIndex},
+		{name: "index without host platform", data: fmt.Appendf(nil, index, ""), mediaType: ocispec.MediaTypeImageIndex, wantErr: "built for linux/amd64, but"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckManifestPlatform(context.Background(), storage, tt.data, tt.mediaType, host)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CheckManifestPlatform error = %v", err)
+				}
+				return
+			}
+			var desc ErrorDescriptor
+			if !errors.As(err, &desc) || desc.Code != ErrCodeManifestInvalid || !strings.Contains(desc.Message, tt.wantErr) {
+				t.Fatalf("CheckManifestPlatform error = %v, want MANIFEST_INVALID containing %q", err, tt.wantErr)
+			}
+			if !strings.Contains(desc.Message, "--platform linux/arm64") {
+				t.Fatalf("message = %q, want rebuild hint", desc.Message)
+			}
+		})
+	}
+}
+
+func TestPlatformMatchesVariants(t *testing.T) {
+	for _, tt := range []struct {
+		p, host ocispec.Platform
+		want    bool
+	}{
+		{ocispec.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}, ocispec.Platform{OS: "linux", Architecture: "arm64"}, true},
+		{ocispec.Platform{OS: "linux", Architecture: "arm", Variant: "v6"}, ocispec.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}, false},
+		{ocispec.Platform{OS: "linux", Architecture: "arm"}, ocispec.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}, true},
+		{ocispec.Platform{OS: "windows", Architecture: "amd64"}, ocispec.Platform{OS: "linux", Architecture: "amd64"}, false},
+	} {
+		if got := PlatformMatches(tt.p, tt.host); got != tt.want {
+			t.Errorf("PlatformMatches(%s, %s) = %v, want %v", PlatformString(tt.p), PlatformString(tt.host), got, tt.want)
+		}
+	}
+}
@@ -192,22 +192,13 @@ func (r *Registry) setupRoutes() {
 		case PathTypeBlobUpload:
 			r.handleBlobUpload(w, req, result.Repo, result.Reference)
 		case PathTypeTagsList:
-			http.NotFound(w, req)
+			r.handleTagsList(w, req, result.Repo)
 		default:
 			log.Println("unknown path type", result.Type)
 		}
 	})
 }
 
-// handleCatalog handles the /v2/_catalog endpoint.
-func (r *Registry) handleCatalog(w http.ResponseWriter, req *http.Request) {
-	if req.Method != http.MethodGet {
-		WriteError(w, http.StatusMethodNotAllowed, ErrCodeUnsupported, "method not allowed", nil)
-		return
-	}
-	WriteError(w, http.StatusNotImplemented, ErrCodeUnsupported, "not implemented", nil)
-}
-
 // ServeHTTP implements http.Handler for the registry.
 func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	r.mux.ServeHTTP(w, req)
@@ -382,6 +373,12 @@ func (r *Registry) handleManifestPut(w http.ResponseWriter, req *http.Request, r
 	// Store manifest
 	digest, err := r.storage.PutManifest(req.Context(), repo, reference, data, mediaType)
 	if err != nil {
+		// Storage refusals carry their own code and message for the client.
+		var desc ErrorDescriptor
+		if errors.As(err, &desc) {
+			WriteError(w, http.StatusBadRequest, desc.Code, desc.Message, desc.Detail)
+			return
+		}
 		WriteError(w, http.StatusInternalServerError, ErrCodeManifestInvalid, err.Error(), nil)
 		return
 	}
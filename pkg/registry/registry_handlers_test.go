@@ -32,7 +32,7 @@ func TestRegistryAPIVersionCatalogAndPathHelpers(t *testing.T) {
 		{name: "api version method", method: http.MethodPost, path: "/v2", status: http.StatusMethodNotAllowed, code: ErrCodeUnsupported},
 		{name: "catalog not implemented", method: http.MethodGet, path: "/v2/_catalog", status: http.StatusNotImplemented, code: ErrCodeUnsupported},
 		{name: "catalog method", method: http.MethodPost, path: "/v2/_catalog", status: http.StatusMethodNotAllowed, code: ErrCodeUnsupported},
-		{name: "tags list not implemented", method: http.MethodGet, path: "/v2/ns/app/tags/list", status: http.StatusNotFound},
+		{name: "tags list not implemented", method: http.MethodGet, path: "/v2/ns/app/tags/list", status: http.StatusNotImplemented, code: ErrCodeUnsupported},
 		{name: "bad path", method: http.MethodGet, path: "/bad", status: http.StatusNotFound},
 	}
 
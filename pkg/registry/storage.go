@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"hash"
 	"io"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
@@ -30,6 +31,8 @@ var (
 	ErrManifestNotFound = errors.New("manifest not found")
 	// ErrDigestMismatch indicates the digest does not match the content
 	ErrDigestMismatch = errors.New("digest mismatch")
+	// ErrRepositoryNotFound indicates the repository has no tags
+	ErrRepositoryNotFound = errors.New("repository not found")
 )
 
 type ManifestMetadata struct {
@@ -379,3 +382,97 @@ func (s *FilesystemStorage) AbortUpload(ctx context.Context, uuid string) error
 	}
 	return nil
 }
+
+var _ Collectable = (*FilesystemStorage)(nil)
+
+// ListContent lists stored blobs and the per-repository manifest copies kept
+// under their digest.
+func (s *FilesystemStorage) ListContent(ctx context.Context) ([]ContentInfo, error) {
+	var out []ContentInfo
+	blobsDir := filepath.Join(s.rootDir, "blobs")
+	err := filepath.WalkDir(blobsDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		st, err := d.Info()
+		if err != nil {
+			return err
+		}
+		out = append(out, ContentInfo{Digest: "sha256:" + d.Name(), Size: st.Size(), UpdatedAt: st.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk blobs: %w", err)
+	}
+	err = s.walkManifests(func(repo, reference, path string, st fs.FileInfo) error {
+		if strings.HasPrefix(reference, "sha256:") {
+			out = append(out, ContentInfo{Repo: repo, Digest: reference, Size: st.Size(), UpdatedAt: st.ModTime()})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListTags lists every manifest stored under a tag rather than a digest.
+func (s *FilesystemStorage) ListTags(ctx context.Context) ([]TagInfo, error) {
+	var out []TagInfo
+	err := s.walkManifests(func(repo, reference, path string, _ fs.FileInfo) error {
+		if strings.HasPrefix(reference, "sha256:") {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		digest, err := computeDigest(f)
+		_ = f.Close()
+		if err != nil {
+			return fmt.Errorf("compute digest of %s:%s: %w", repo, reference, err)
+		}
+		out = append(out, TagInfo{Repo: repo, Tag: reference, Digest: digest})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *FilesystemStorage) walkManifests(fn func(repo, reference, path string, st fs.FileInfo) error) error {
+	manifestsDir := filepath.Join(s.rootDir, "manifests")
+	err := filepath.WalkDir(manifestsDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasSuffix(d.Name(), ".mediatype") {
+			return err
+		}
+		rel, err := filepath.Rel(manifestsDir, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		st, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(rel), d.Name(), path, st)
+	})
+	if err != nil {
+		return fmt.Errorf("walk manifests: %w", err)
+	}
+	return nil
+}
+
+// RemoveContent deletes a blob, or a manifest from the repository it was
+// listed under.
+func (s *FilesystemStorage) RemoveContent(ctx context.Context, c ContentInfo) error {
+	if c.Repo == "" {
+		return s.DeleteBlob(ctx, c.Digest)
+	}
+	if err := s.DeleteManifest(ctx, c.Repo, c.Digest); err != nil {
+		return err
+	}
+	// Drop the repository directory once its last manifest is gone.
+	_ = os.Remove(filepath.Dir(s.manifestPath(c.Repo, c.Digest)))
+	return nil
+}
@@ -14,9 +14,13 @@ import (
 // ComposeResolveOptions identifies the exact Compose files and project context
 // used to resolve a canonical Docker Compose application model.
 type ComposeResolveOptions struct {
+	// Runtime is the engine whose compose resolves the model; zero is docker.
+	Runtime     ContainerRuntime
 	ProjectName string
 	ProjectDir  string
 	Files       []string
+	EnvFiles    []string
+	Profiles    []string
 	NewCmd      func(context.Context, string, ...string) *exec.Cmd
 }
 
@@ -34,9 +38,15 @@ func ResolveComposeJSON(ctx context.Context, opts ComposeResolveOptions) ([]byte
 		}
 		args = append(args, "--file", file)
 	}
+	for _, file := range opts.EnvFiles {
+		args = append(args, "--env-file", file)
+	}
+	for _, profile := range opts.Profiles {
+		args = append(args, "--profile", profile)
+	}
 	args = append(args, "config", "--format", "json")
 
-	docker, err := DockerCmd()
+	docker, err := opts.Runtime.LookPath()
 	if err != nil {
 		return nil, err
 	}
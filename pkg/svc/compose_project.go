@@ -0,0 +1,113 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package svc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/yeetrun/yeet/pkg/db"
+)
+
+// ComposeProject is what a generation layers on its base compose file:
+// override files applied in order, interpolation env files, and enabled
+// profiles. Each part is a generation artifact, so rolling back a service
+// restores the whole set.
+type ComposeProject struct {
+	Overrides []string
+	EnvFiles  []string
+	Profiles  []string
+}
+
+// IsZero reports whether the project adds nothing to the base compose file.
+func (p ComposeProject) IsZero() bool {
+	return len(p.Overrides) == 0 && len(p.EnvFiles) == 0 && len(p.Profiles) == 0
+}
+
+// ComposeProjectFromArtifacts loads the compose project recorded for gen.
+func ComposeProjectFromArtifacts(artifacts db.ArtifactStore, gen int) (ComposeProject, error) {
+	var p ComposeProject
+	for n := 0; ; n++ {
+		path, ok := artifacts.Gen(db.ArtifactDockerComposeOverride(n), gen)
+		if !ok {
+			break
+		}
+		p.Overrides = append(p.Overrides, path)
+	}
+	for n := 0; ; n++ {
+		path, ok := artifacts.Gen(db.ArtifactDockerComposeEnv(n), gen)
+		if !ok {
+			break
+		}
+		p.EnvFiles = append(p.EnvFiles, path)
+	}
+	if path, ok := artifacts.Gen(db.ArtifactDockerComposeProfiles, gen); ok {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return ComposeProject{}, fmt.Errorf("read compose profiles: %w", err)
+		}
+		p.Profiles = ParseComposeProfiles(raw)
+	}
+	return p, nil
+}
+
+// Hash reads the project files and returns ComposeProjectHash of them.
+func (p ComposeProject) Hash() (string, error) {
+	overrides, err := readComposeProjectFiles(p.Overrides)
+	if err != nil {
+		return "", err
+	}
+	envFiles, err := readComposeProjectFiles(p.EnvFiles)
+	if err != nil {
+		return "", err
+	}
+	return ComposeProjectHash(overrides, envFiles, p.Profiles), nil
+}
+
+func readComposeProjectFiles(paths []string) ([][]byte, error) {
+	out := make([][]byte, 0, len(paths))
+	for _, path := range paths {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, raw)
+	}
+	return out, nil
+}
+
+// ComposeProjectHash fingerprints a compose project by content and order so
+// yeet run can tell whether the files it would send match the deployed
+// generation. An empty project hashes to "".
+func ComposeProjectHash(overrides, envFiles [][]byte, profiles []string) string {
+	if len(overrides) == 0 && len(envFiles) == 0 && len(profiles) == 0 {
+		return ""
+	}
+	h := sha256.New()
+	for _, raw := range overrides {
+		fmt.Fprintf(h, "override %x\n", sha256.Sum256(raw))
+	}
+	for _, raw := range envFiles {
+		fmt.Fprintf(h, "env %x\n", sha256.Sum256(raw))
+	}
+	for _, profile := range profiles {
+		fmt.Fprintf(h, "profile %s\n", profile)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// FormatComposeProfiles renders profiles for the ArtifactDockerComposeProfiles
+// file.
+func FormatComposeProfiles(profiles []string) []byte {
+	return []byte(strings.Join(profiles, "\n") + "\n")
+}
+
+// ParseComposeProfiles parses an ArtifactDockerComposeProfiles file.
+func ParseComposeProfiles(raw []byte) []string {
+	return strings.Fields(string(raw))
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package svc
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/yeetrun/yeet/pkg/db"
+)
+
+// ContainerRuntime names the engine that runs docker compose services. Podman
+// is driven through `podman compose`, so the compose files, labels and JSON
+// output stay the ones docker compose produces. The zero value is docker.
+type ContainerRuntime string
+
+const (
+	ContainerRuntimeDocker ContainerRuntime = "docker"
+	ContainerRuntimePodman ContainerRuntime = "podman"
+)
+
+var ErrPodmanNotFound = fmt.Errorf("podman not found")
+
+// ParseContainerRuntime parses a runtime name; empty means docker.
+func ParseContainerRuntime(name string) (ContainerRuntime, error) {
+	switch rt := ContainerRuntime(strings.ToLower(strings.TrimSpace(name))); rt {
+	case "", ContainerRuntimeDocker:
+		return ContainerRuntimeDocker, nil
+	case ContainerRuntimePodman:
+		return rt, nil
+	default:
+		return "", fmt.Errorf("unknown container runtime %q; use docker or podman", name)
+	}
+}
+
+// ContainerRuntimeFromData returns the runtime stored in the host settings.
+func ContainerRuntimeFromData(dv db.DataView) (ContainerRuntime, error) {
+	if !dv.Valid() {
+		return ContainerRuntimeDocker, nil
+	}
+	return ParseContainerRuntime(dv.ContainerRuntime())
+}
+
+// IsPodman reports whether rt is podman.
+func (rt ContainerRuntime) IsPodman() bool {
+	return rt == ContainerRuntimePodman
+}
+
+// Command returns the name of the runtime's CLI.
+func (rt ContainerRuntime) Command() string {
+	if rt == "" {
+		return string(ContainerRuntimeDocker)
+	}
+	return string(rt)
+}
+
+// LookPath returns the path to the runtime's CLI.
+func (rt ContainerRuntime) LookPath() (string, error) {
+	p, err := exec.LookPath(rt.Command())
+	if err != nil {
+		if rt == ContainerRuntimePodman {
+			return "", ErrPodmanNotFound
+		}
+		return "", ErrDockerNotFound
+	}
+	return p, nil
+}
+
+// Limitations lists what compose services cannot do on the runtime. Podman
+// runs rootful through `podman compose`: there are no rootless or quadlet
+// units, and the pieces built on the yeet Docker network plugin or the Docker
+// daemon config are unavailable. Catch refuses each of them when a service or
+// setting asks for it.
+func (rt ContainerRuntime) Limitations() []string {
+	if rt != ContainerRuntimePodman {
+		return nil
+	}
+	return []string{
+		"rootless mode and quadlet units; compose projects run rootful through podman compose",
+		"--net=iso compose services (they attach through the yeet Docker network plugin)",
+		"compose ports: and --publish; services run inside the service network namespace",
+		"the pull-through registry mirror (host set --registry-mirror)",
+	}
+}
+
+// CheckCompose verifies the runtime can run compose projects. `podman
+// compose` needs a compose provider installed next to podman.
+func (rt ContainerRuntime) CheckCompose(ctx context.Context) error {
+	bin, err := rt.LookPath()
+	if err != nil {
+		return err
+	}
+	out, err := exec.CommandContext(ctx, bin, "compose", "version").CombinedOutput()
+	if err != nil {
+		detail := strings.TrimSpace(string(out))
+		if rt == ContainerRuntimePodman {
+			return fmt.Errorf("podman compose is not usable; install docker-compose (or podman-compose) and enable podman.socket: %w: %s", err, detail)
+		}
+		return fmt.Errorf("docker compose is not usable: %w: %s", err, detail)
+	}
+	return nil
+}
+
+// upstreamManifestCommand returns the command that prints the raw registry
+// manifest of image. Docker uses buildx; podman hosts use skopeo, which
+// ships alongside it.
+func upstreamManifestCommand(rt ContainerRuntime, image string) (string, []string, error) {
+	if !rt.IsPodman() {
+		docker, err := rt.LookPath()
+		if err != nil {
+			return "", nil, err
+		}
+		return docker, []string{"buildx", "imagetools", "inspect", image, "--raw"}, nil
+	}
+	skopeo, err := exec.LookPath("skopeo")
+	if err != nil {
+		return "", nil, fmt.Errorf("skopeo is required to check upstream images on podman hosts")
+	}
+	return skopeo, []string{"inspect", "--raw", "docker://" + image}, nil
+}
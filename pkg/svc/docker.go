@@ -38,7 +38,13 @@ type DockerComposeService struct {
 	DataDir       string
 	NewCmd        func(name string, arg ...string) *exec.Cmd
 	NewCmdContext func(ctx context.Context, name string, arg ...string) *exec.Cmd
-	sd            dockerSystemdService
+	// Runtime is the engine the project runs on, from the host settings.
+	Runtime ContainerRuntime
+	// RestartGate, when set, is a compose file applied after every other
+	// one. Catch uses it to turn off the restart policies of a project that
+	// must wait for its mounts, so a systemd unit can own its startup.
+	RestartGate string
+	sd          dockerSystemdService
 
 	netnsInspector netnsInspector
 	installEnvOnce lazy.SyncValue[error]
@@ -52,13 +58,10 @@ type dockerSystemdService interface {
 	hasArtifact(db.ArtifactName) bool
 }
 
-// DockerCmd returns the path to the docker binary.
-func DockerCmd() (string, error) {
-	p, err := exec.LookPath("docker")
-	if err != nil {
-		return "", ErrDockerNotFound
-	}
-	return p, nil
+// DockerCmd returns the path to the CLI of the service's container runtime:
+// docker, or podman on hosts set to it.
+func (s *DockerComposeService) DockerCmd() (string, error) {
+	return s.Runtime.LookPath()
 }
 
 func (s *DockerComposeService) command(args ...string) (*exec.Cmd, error) {
@@ -66,7 +69,7 @@ func (s *DockerComposeService) command(args ...string) (*exec.Cmd, error) {
 }
 
 func (s *DockerComposeService) commandContext(ctx context.Context, args ...string) (*exec.Cmd, error) {
-	dockerPath, err := DockerCmd()
+	dockerPath, err := s.DockerCmd()
 	if err != nil {
 		return nil, err
 	}
@@ -99,9 +102,33 @@ func (s *DockerComposeService) composeCommandArgs() ([]string, error) {
 	args = append(args,
 		"--file", cf,
 	)
+	project, err := ComposeProjectFromArtifacts(s.cfg.Artifacts, s.cfg.Generation)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range project.Overrides {
+		args = append(args, "--file", f)
+	}
+	// The network overlay goes last so overrides cannot undo yeet's networking.
 	if cf, ok := s.cfg.Artifacts.Gen(db.ArtifactDockerComposeNetwork, s.cfg.Generation); ok {
 		args = append(args, "--file", cf)
 	}
+	if s.RestartGate != "" {
+		args = append(args, "--file", s.RestartGate)
+	}
+	if len(project.EnvFiles) != 0 {
+		for _, f := range project.EnvFiles {
+			args = append(args, "--env-file", f)
+		}
+		// An explicit --env-file replaces compose's implicit .env lookup, so
+		// name the service env file again; it goes last so yeet env wins.
+		if _, ok := s.cfg.Artifacts.Gen(db.ArtifactEnvFile, s.cfg.Generation); ok {
+			args = append(args, "--env-file", filepath.Join(s.DataDir, ".env"))
+		}
+	}
+	for _, profile := range project.Profiles {
+		args = append(args, "--profile", profile)
+	}
 	return args, nil
 }
 
@@ -113,23 +140,30 @@ func (s *DockerComposeService) ResolveConfigJSON(ctx context.Context) ([]byte, e
 		return nil, err
 	}
 	return ResolveComposeJSON(ctx, ComposeResolveOptions{
+		Runtime:     s.Runtime,
 		ProjectName: s.composeProjectName(),
 		ProjectDir:  s.DataDir,
 		Files:       composeFilesFromArgs(args),
+		EnvFiles:    composeArgValues(args, "--env-file"),
+		Profiles:    composeArgValues(args, "--profile"),
 		NewCmd:      s.NewCmdContext,
 	})
 }
 
 func composeFilesFromArgs(args []string) []string {
-	var files []string
+	return composeArgValues(args, "--file")
+}
+
+func composeArgValues(args []string, flag string) []string {
+	var values []string
 	for idx := 0; idx+1 < len(args); idx++ {
-		if args[idx] != "--file" {
+		if args[idx] != flag {
 			continue
 		}
-		files = append(files, args[idx+1])
+		values = append(values, args[idx+1])
 		idx++
 	}
-	return files
+	return values
 }
 
 func (s *DockerComposeService) syncComposeEnvFile() error {
@@ -293,7 +327,7 @@ func (s *DockerComposeService) stopProjectContainersByLabel(ctx context.Context)
 	if err != nil || len(ids) == 0 {
 		return err
 	}
-	dockerPath, err := DockerCmd()
+	dockerPath, err := s.DockerCmd()
 	if err != nil {
 		return err
 	}
@@ -309,7 +343,7 @@ func (s *DockerComposeService) stopProjectContainersByLabel(ctx context.Context)
 }
 
 func (s *DockerComposeService) projectContainerIDs(ctx context.Context) ([]string, error) {
-	dockerPath, err := DockerCmd()
+	dockerPath, err := s.DockerCmd()
 	if err != nil {
 		return nil, err
 	}
@@ -355,7 +389,7 @@ func (s *DockerComposeService) VerifyProjectAbsent(ctx context.Context) error {
 }
 
 func (s *DockerComposeService) VerifyDefaultNetworkAbsent(ctx context.Context) error {
-	dockerPath, err := DockerCmd()
+	dockerPath, err := s.DockerCmd()
 	if err != nil {
 		return err
 	}
@@ -720,5 +754,5 @@ func (s *DockerComposeService) getNetNSInspector() netnsInspector {
 	if s.netnsInspector != nil {
 		return s.netnsInspector
 	}
-	return linuxNetNSInspector{}
+	return linuxNetNSInspector{runtime: s.Runtime}
 }
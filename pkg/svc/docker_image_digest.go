@@ -0,0 +1,64 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DeclaredImages returns the images the compose file declares, each once and
+// sorted.
+func (s *DockerComposeService) DeclaredImages(ctx context.Context) ([]string, error) {
+	declared, err := s.composeDeclaredImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	images := make([]string, 0, len(declared.byService))
+	for _, image := range declared.byService {
+		if !seen[image] {
+			seen[image] = true
+			images = append(images, image)
+		}
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+// LocalImageDigest returns the registry digest of the host's copy of image,
+// pulling it first when the host has none, so the digest is the one a
+// container started without pulling would run. A digest pinned in the
+// reference is returned as is. Images built on the host have no registry
+// digest and return an error.
+func (s *DockerComposeService) LocalImageDigest(ctx context.Context, image string) (string, error) {
+	if _, rawDigest, ok := strings.Cut(image, "@"); ok {
+		return parseDockerDigest(rawDigest)
+	}
+	out, err := s.dockerOutput(ctx, "image", "inspect", image)
+	if err != nil {
+		if _, pullErr := s.dockerOutput(ctx, "pull", image); pullErr != nil {
+			return "", fmt.Errorf("docker pull %s: %w", image, pullErr)
+		}
+		if out, err = s.dockerOutput(ctx, "image", "inspect", image); err != nil {
+			return "", fmt.Errorf("docker image inspect %s: %w", image, err)
+		}
+	}
+	var images []dockerImageInspectRow
+	if err := json.Unmarshal(out, &images); err != nil {
+		return "", fmt.Errorf("parse docker image inspect: %w", err)
+	}
+	if len(images) == 0 {
+		return "", fmt.Errorf("parse docker image inspect: empty result")
+	}
+	digest := selectRepoDigestForImage(images[0].RepoDigests, image)
+	if digest == "" {
+		return "", fmt.Errorf("%s has no registry digest; it was not pulled from a registry", image)
+	}
+	return digest, nil
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package svc
+
+import (
+	"context"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDockerComposeLocalImageDigest(t *testing.T) {
+	var pulled []string
+	present := false
+	service := newFakeRollbackService(t, func(args []string) *exec.Cmd {
+		switch {
+		case hasOrderedArgs(args, "compose", "config", "--format", "json"):
+			return fakeDockerOutputCmd(t, `{"services":{"app":{"image":"ghcr.io/acme/app:2"},"worker":{"image":"ghcr.io/acme/app:2"},"db":{"image":"postgres@sha256:abc"}}}`)
+		case hasOrderedArgs(args, "image", "inspect", "ghcr.io/acme/app:2"):
+			if !present {
+				return fakeDockerErrorCmd(t, "No such image", 1)
+			}
+			return fakeDockerOutputCmd(t, `[{"RepoDigests":["ghcr.io/acme/other@sha256:1111","ghcr.io/acme/app@sha256:2222"]}]`)
+		case hasOrderedArgs(args, "image", "inspect", "local/app:dev"):
+			return fakeDockerOutputCmd(t, `[{"RepoDigests":[]}]`)
+		case len(args) == 2 && args[0] == "pull":
+			pulled = append(pulled, args[1])
+			present = true
+			return fakeDockerOutputCmd(t, "")
+		default:
+			t.Fatalf("unexpected docker command: docker %v", args)
+			return fakeDockerOutputCmd(t, "")
+		}
+	})
+	ctx := context.Background()
+
+	images, err := service.DeclaredImages(ctx)
+	if err != nil {
+		t.Fatalf("DeclaredImages: %v", err)
+	}
+	if want := []string{"ghcr.io/acme/app:2", "postgres@sha256:abc"}; !reflect.DeepEqual(images, want) {
+		t.Fatalf("DeclaredImages = %q, want %q", images, want)
+	}
+	digest, err := service.LocalImageDigest(ctx, "ghcr.io/acme/app:2")
+	if err != nil || digest != "sha256:2222" {
+		t.Fatalf("LocalImageDigest = %q, %v; want sha256:2222", digest, err)
+	}
+	if !reflect.DeepEqual(pulled, []string{"ghcr.io/acme/app:2"}) {
+		t.Fatalf("pulled = %q, want the missing image", pulled)
+	}
+	if _, err := service.LocalImageDigest(ctx, "postgres@sha256:abc"); err == nil {
+		t.Fatal("LocalImageDigest accepted an invalid pinned digest")
+	}
+	if _, err := service.LocalImageDigest(ctx, "local/app:dev"); err == nil || !strings.Contains(err.Error(), "no registry digest") {
+		t.Fatalf("LocalImageDigest for a local build error = %v", err)
+	}
+}
@@ -21,7 +21,9 @@ type netnsInspector interface {
 	ProjectContainers(ctx context.Context, project string) ([]composeContainer, error)
 }
 
-type linuxNetNSInspector struct{}
+type linuxNetNSInspector struct {
+	runtime ContainerRuntime
+}
 
 func (linuxNetNSInspector) NamedNetNSLinkNames(ctx context.Context, path string) ([]string, error) {
 	if err := ctx.Err(); err != nil {
@@ -51,11 +53,11 @@ func (linuxNetNSInspector) NamedNetNSLinkNames(ctx context.Context, path string)
 	return links, nil
 }
 
-func (linuxNetNSInspector) ProjectContainers(ctx context.Context, project string) ([]composeContainer, error) {
+func (i linuxNetNSInspector) ProjectContainers(ctx context.Context, project string) ([]composeContainer, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	dockerPath, err := DockerCmd()
+	dockerPath, err := i.runtime.LookPath()
 	if err != nil {
 		return nil, err
 	}
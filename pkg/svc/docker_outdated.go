@@ -48,6 +48,7 @@ type dockerComposePSRow struct {
 	Service string `json:"Service"`
 	Image   string `json:"Image"`
 	State   string `json:"State"`
+	Health  string `json:"Health"`
 }
 
 type dockerImageInspectRow struct {
@@ -55,6 +56,18 @@ type dockerImageInspectRow struct {
 	RepoDigests  []string `json:"RepoDigests"`
 	Architecture string   `json:"Architecture"`
 	OS           string   `json:"Os"`
+	Config       struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+}
+
+// DockerImageState is the image behind one running compose container.
+type DockerImageState struct {
+	ContainerName string            `json:"containerName"`
+	Image         string            `json:"image"`
+	RunningDigest string            `json:"runningDigest,omitempty"`
+	LatestDigest  string            `json:"latestDigest,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
 }
 
 type dockerComposeDeclaredImages struct {
@@ -375,6 +388,48 @@ func (s *DockerComposeService) Outdated(ctx context.Context, opts DockerOutdated
 	return rows, nil
 }
 
+// ImageStates reports the image of every running container. The upstream
+// digest is resolved only for external images; internal registry images are
+// identified by their labels instead.
+func (s *DockerComposeService) ImageStates(ctx context.Context) ([]DockerImageState, error) {
+	declared, err := s.composeDeclaredImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	containers, err := s.composeContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	states := make([]DockerImageState, 0, len(containers))
+	for _, container := range containers {
+		if container.State != "" && container.State != "running" {
+			continue
+		}
+		row := dockerOutdatedRowForComposeContainer(s.Name, container)
+		if declaredImage, ok := declared.imageForContainer(container); ok {
+			row.Image = declaredImage
+		}
+		inspect, err := s.inspectContainerImage(ctx, container.ID, row.Image)
+		if err != nil {
+			return nil, err
+		}
+		state := DockerImageState{
+			ContainerName: row.ContainerName,
+			Image:         row.Image,
+			RunningDigest: inspect.runningDigest,
+			Labels:        inspect.labels,
+		}
+		if !isInternalRegistryImage(row.Image) {
+			state.LatestDigest, err = s.latestImageDigest(ctx, row.Image, inspect.os, inspect.architecture)
+			if err != nil {
+				return nil, err
+			}
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
 func appendFilteredDockerOutdatedRow(rows []DockerOutdatedRow, row DockerOutdatedRow, opts DockerOutdatedOptions) []DockerOutdatedRow {
 	filtered := filterDockerOutdatedRow(row, opts)
 	if filtered == nil {
@@ -408,7 +463,7 @@ func (s *DockerComposeService) readonlyComposeOutput(ctx context.Context, args .
 }
 
 func (s *DockerComposeService) dockerOutput(ctx context.Context, args ...string) ([]byte, error) {
-	dockerPath, err := DockerCmd()
+	dockerPath, err := s.DockerCmd()
 	if err != nil {
 		return nil, err
 	}
@@ -475,6 +530,7 @@ type runningImageInspect struct {
 	runningDigest string
 	os            string
 	architecture  string
+	labels        map[string]string
 }
 
 func filterDockerOutdatedRow(row DockerOutdatedRow, opts DockerOutdatedOptions) *DockerOutdatedRow {
@@ -496,20 +552,11 @@ func filterDockerOutdatedRow(row DockerOutdatedRow, opts DockerOutdatedOptions)
 }
 
 func (s *DockerComposeService) inspectContainerImage(ctx context.Context, containerID, image string) (runningImageInspect, error) {
-	out, err := s.dockerOutput(ctx, "inspect", containerID)
+	imageID, err := s.containerImageID(ctx, containerID)
 	if err != nil {
-		return runningImageInspect{}, fmt.Errorf("docker inspect container: %w", err)
+		return runningImageInspect{}, err
 	}
-	var containers []struct {
-		Image string `json:"Image"`
-	}
-	if err := json.Unmarshal(out, &containers); err != nil {
-		return runningImageInspect{}, fmt.Errorf("parse docker inspect container: %w", err)
-	}
-	if len(containers) == 0 {
-		return runningImageInspect{}, fmt.Errorf("parse docker inspect container: empty result")
-	}
-	imageOut, err := s.dockerOutput(ctx, "image", "inspect", containers[0].Image)
+	imageOut, err := s.dockerOutput(ctx, "image", "inspect", imageID)
 	if err != nil {
 		return runningImageInspect{}, fmt.Errorf("docker image inspect: %w", err)
 	}
@@ -524,6 +571,7 @@ func (s *DockerComposeService) inspectContainerImage(ctx context.Context, contai
 		runningDigest: selectRepoDigestForImage(images[0].RepoDigests, image),
 		os:            images[0].OS,
 		architecture:  images[0].Architecture,
+		labels:        images[0].Config.Labels,
 	}, nil
 }
 
@@ -535,7 +583,13 @@ func (s *DockerComposeService) latestImageDigest(ctx context.Context, image, osN
 		}
 		return parsed, nil
 	}
-	raw, err := s.dockerOutput(ctx, "buildx", "imagetools", "inspect", image, "--raw")
+	bin, args, err := upstreamManifestCommand(s.Runtime, image)
+	if err != nil {
+		return "", err
+	}
+	cmd := s.newDockerCommand(ctx, bin, args...)
+	cmd.Dir = s.DataDir
+	raw, err := captureCommandOutput(cmd)
 	if err != nil {
 		return "", fmt.Errorf("inspect upstream image: %w", err)
 	}
@@ -567,7 +621,7 @@ func parseDockerDigest(rawDigest string) (string, error) {
 }
 
 func (s *DockerComposeService) readonlyComposeCommandContext(ctx context.Context, args ...string) (*exec.Cmd, error) {
-	dockerPath, err := DockerCmd()
+	dockerPath, err := s.DockerCmd()
 	if err != nil {
 		return nil, err
 	}
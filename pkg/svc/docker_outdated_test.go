@@ -12,6 +12,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -722,3 +723,56 @@ func containsString(values []string, want string) bool {
 	}
 	return false
 }
+
+func TestDockerComposeImageStates(t *testing.T) {
+	tmp := t.TempDir()
+	compose := writeDockerOutdatedFile(t, tmp, "compose.yml", "services:\n  app:\n    image: ghcr.io/acme/app:2\n  built:\n    image: "+InternalRegistryHost+"/web/built:latest\n")
+	fakeBin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(fakeBin, "docker"), []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+		t.Fatalf("write fake docker binary: %v", err)
+	}
+	t.Setenv("PATH", fakeBin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	service := &DockerComposeService{
+		Name:    "web",
+		DataDir: tmp,
+		cfg: testDockerOutdatedServiceConfig{
+			composePath: compose,
+		}.service(),
+	}
+	service.NewCmdContext = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		switch {
+		case hasOrderedArgs(args, "compose", "config", "--format", "json"):
+			return fakeDockerOutputCmd(t, `{"services":{"app":{"image":"ghcr.io/acme/app:2"},"built":{"image":"`+InternalRegistryHost+`/web/built:latest"}}}`)
+		case hasOrderedArgs(args, "compose", "ps", "--format=json"):
+			return fakeDockerOutputCmd(t, `[{"ID":"appcid","Service":"app","Image":"ghcr.io/acme/app:2","State":"running"},{"ID":"builtcid","Service":"built","Image":"`+InternalRegistryHost+`/web/built:latest","State":"running"},{"ID":"gone","Service":"old","State":"exited"}]`)
+		case len(args) >= 2 && args[0] == "inspect" && args[1] == "appcid":
+			return fakeDockerOutputCmd(t, `[{"Image":"sha256:app"}]`)
+		case len(args) >= 2 && args[0] == "inspect" && args[1] == "builtcid":
+			return fakeDockerOutputCmd(t, `[{"Image":"sha256:built"}]`)
+		case len(args) >= 3 && args[0] == "image" && args[1] == "inspect" && args[2] == "sha256:app":
+			return fakeDockerOutputCmd(t, `[{"Id":"sha256:app","RepoDigests":["ghcr.io/acme/app@sha256:running"],"Architecture":"amd64","Os":"linux"}]`)
+		case len(args) >= 3 && args[0] == "image" && args[1] == "inspect" && args[2] == "sha256:built":
+			return fakeDockerOutputCmd(t, `[{"Id":"sha256:built","RepoDigests":[],"Architecture":"amd64","Os":"linux","Config":{"Labels":{"com.yeetrun.build-hash":"abc"}}}]`)
+		case len(args) >= 4 && args[0] == "buildx" && args[1] == "imagetools" && args[2] == "inspect":
+			if args[3] != "ghcr.io/acme/app:2" {
+				t.Fatalf("upstream lookup for %q, want only the external image", args[3])
+			}
+			return fakeDockerOutputCmd(t, `{"schemaVersion":2}`)
+		default:
+			t.Fatalf("unexpected docker command: docker %v", args)
+			return fakeDockerOutputCmd(t, "")
+		}
+	}
+
+	got, err := service.ImageStates(context.Background())
+	if err != nil {
+		t.Fatalf("ImageStates: %v", err)
+	}
+	want := []DockerImageState{
+		{ContainerName: "app", Image: "ghcr.io/acme/app:2", RunningDigest: "sha256:running", LatestDigest: digestFromManifestBytes([]byte(`{"schemaVersion":2}`))},
+		{ContainerName: "built", Image: InternalRegistryHost + "/web/built:latest", Labels: map[string]string{"com.yeetrun.build-hash": "abc"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ImageStates = %#v, want %#v", got, want)
+	}
+}
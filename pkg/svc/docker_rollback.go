@@ -0,0 +1,104 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ImageIDs maps each image the compose file declares to the local image ID
+// its running container uses. Passing the result to RestoreImages after an
+// update puts those images back.
+func (s *DockerComposeService) ImageIDs(ctx context.Context) (map[string]string, error) {
+	declared, err := s.composeDeclaredImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	containers, err := s.composeContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := map[string]string{}
+	for _, container := range containers {
+		image, ok := declared.imageForContainer(container)
+		if !ok || strings.Contains(image, "@") {
+			continue
+		}
+		id, err := s.containerImageID(ctx, container.ID)
+		if err != nil {
+			return nil, err
+		}
+		ids[image] = id
+	}
+	return ids, nil
+}
+
+// RestoreImages tags each image ID from ImageIDs with its declared reference
+// again and recreates the containers without pulling.
+func (s *DockerComposeService) RestoreImages(ctx context.Context, ids map[string]string) error {
+	images := make([]string, 0, len(ids))
+	for image := range ids {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	for _, image := range images {
+		if _, err := s.dockerOutput(ctx, "tag", ids[image], image); err != nil {
+			return fmt.Errorf("docker tag %s %s: %w", ids[image], image, err)
+		}
+	}
+	return s.runCommandContext(ctx, "up", "--pull", "never", "-d")
+}
+
+// Health reports whether every container of the project is running and none
+// is starting or unhealthy. When it is not, detail names the first container
+// that is not.
+func (s *DockerComposeService) Health(ctx context.Context) (ok bool, detail string, err error) {
+	out, err := s.readonlyComposeOutput(ctx, "ps", "-a", "--format=json")
+	if err != nil {
+		return false, "", fmt.Errorf("docker compose ps -a --format=json: %w", err)
+	}
+	containers, err := parseComposePSJSON(out)
+	if err != nil {
+		return false, "", err
+	}
+	if len(containers) == 0 {
+		return false, "no containers", nil
+	}
+	sort.Slice(containers, func(i, j int) bool { return containers[i].Service < containers[j].Service })
+	for _, container := range containers {
+		name := container.Service
+		if name == "" {
+			name = container.Name
+		}
+		if container.State != "running" {
+			return false, fmt.Sprintf("%s is %s", name, container.State), nil
+		}
+		if container.Health == "starting" || container.Health == "unhealthy" {
+			return false, fmt.Sprintf("%s is %s", name, container.Health), nil
+		}
+	}
+	return true, "", nil
+}
+
+func (s *DockerComposeService) containerImageID(ctx context.Context, containerID string) (string, error) {
+	out, err := s.dockerOutput(ctx, "inspect", containerID)
+	if err != nil {
+		return "", fmt.Errorf("docker inspect container: %w", err)
+	}
+	var containers []struct {
+		Image string `json:"Image"`
+	}
+	if err := json.Unmarshal(out, &containers); err != nil {
+		return "", fmt.Errorf("parse docker inspect container: %w", err)
+	}
+	if len(containers) == 0 || containers[0].Image == "" {
+		return "", fmt.Errorf("parse docker inspect container: empty result")
+	}
+	return containers[0].Image, nil
+}
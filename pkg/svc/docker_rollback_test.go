@@ -0,0 +1,95 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package svc
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newFakeRollbackService(t *testing.T, run func(args []string) *exec.Cmd) *DockerComposeService {
+	t.Helper()
+	tmp := t.TempDir()
+	compose := writeDockerOutdatedFile(t, tmp, "compose.yml", "services:\n  app:\n    image: ghcr.io/acme/app:2\n  db:\n    image: postgres@sha256:abc\n")
+	fakeBin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(fakeBin, "docker"), []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+		t.Fatalf("write fake docker binary: %v", err)
+	}
+	t.Setenv("PATH", fakeBin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	service := &DockerComposeService{
+		Name:    "web",
+		DataDir: tmp,
+		cfg:     testDockerOutdatedServiceConfig{composePath: compose}.service(),
+	}
+	service.NewCmdContext = func(_ context.Context, _ string, args ...string) *exec.Cmd { return run(args) }
+	return service
+}
+
+func TestDockerComposeImageIDsAndRestore(t *testing.T) {
+	var ran []string
+	service := newFakeRollbackService(t, func(args []string) *exec.Cmd {
+		switch {
+		case hasOrderedArgs(args, "compose", "config", "--format", "json"):
+			return fakeDockerOutputCmd(t, `{"services":{"app":{"image":"ghcr.io/acme/app:2"},"db":{"image":"postgres@sha256:abc"}}}`)
+		case hasOrderedArgs(args, "compose", "ps", "--format=json"):
+			return fakeDockerOutputCmd(t, `[{"ID":"appcid","Service":"app","State":"running"},{"ID":"dbcid","Service":"db","State":"running"}]`)
+		case len(args) >= 2 && args[0] == "inspect" && args[1] == "appcid":
+			return fakeDockerOutputCmd(t, `[{"Image":"sha256:old"}]`)
+		case len(args) >= 1 && args[0] == "tag", hasOrderedArgs(args, "compose", "up"):
+			ran = append(ran, strings.Join(args, " "))
+			return fakeDockerOutputCmd(t, "")
+		default:
+			t.Fatalf("unexpected docker command: docker %v", args)
+			return fakeDockerOutputCmd(t, "")
+		}
+	})
+
+	ids, err := service.ImageIDs(context.Background())
+	if err != nil {
+		t.Fatalf("ImageIDs: %v", err)
+	}
+	if want := map[string]string{"ghcr.io/acme/app:2": "sha256:old"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("ImageIDs = %v, want %v", ids, want)
+	}
+	if err := service.RestoreImages(context.Background(), ids); err != nil {
+		t.Fatalf("RestoreImages: %v", err)
+	}
+	if len(ran) != 2 || ran[0] != "tag sha256:old ghcr.io/acme/app:2" || !strings.HasSuffix(ran[1], "up --pull never -d") {
+		t.Fatalf("commands = %q, want tag then up without pull", ran)
+	}
+}
+
+func TestDockerComposeHealth(t *testing.T) {
+	tests := []struct {
+		name   string
+		ps     string
+		ok     bool
+		detail string
+	}{
+		{name: "running", ps: `[{"Service":"app","State":"running"},{"Service":"db","State":"running","Health":"healthy"}]`, ok: true},
+		{name: "exited", ps: `[{"Service":"app","State":"exited"}]`, detail: "app is exited"},
+		{name: "starting", ps: `[{"Service":"app","State":"running","Health":"starting"}]`, detail: "app is starting"},
+		{name: "empty", ps: ``, detail: "no containers"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newFakeRollbackService(t, func(args []string) *exec.Cmd {
+				if !hasOrderedArgs(args, "compose", "ps", "-a", "--format=json") {
+					t.Fatalf("unexpected docker command: docker %v", args)
+				}
+				return fakeDockerOutputCmd(t, tc.ps)
+			})
+			ok, detail, err := service.Health(context.Background())
+			if err != nil || ok != tc.ok || detail != tc.detail {
+				t.Fatalf("Health = %v, %q, %v; want %v, %q", ok, detail, err, tc.ok, tc.detail)
+			}
+		})
+	}
+}
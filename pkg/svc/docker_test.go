@@ -443,6 +443,21 @@ func TestDockerComposeCommandCopiesEnvAndIncludesNetworkComposeFile(t *testing.T
 	}
 }
 
+func TestDockerComposeRestartGateGoesLast(t *testing.T) {
+	service := newTestDockerComposeService(t, "services:\n  api:\n    image: nginx\n", recordCmd(t, &[]cmdCall{}))
+	overlay := filepath.Join(service.DataDir, "compose.network.yml")
+	service.cfg.Artifacts[db.ArtifactDockerComposeNetwork] = &db.Artifact{Refs: map[db.ArtifactRef]string{db.Gen(1): overlay}}
+	service.RestartGate = filepath.Join(service.DataDir, "compose.restart-gate.yml")
+	args, err := service.composeCommandArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := composeFilesFromArgs(args)
+	if len(files) != 3 || files[1] != overlay || files[2] != service.RestartGate {
+		t.Fatalf("compose files = %q, want the restart gate after the network overlay", files)
+	}
+}
+
 func TestDockerComposeResolveConfigJSONUsesExactGeneration(t *testing.T) {
 	service := newTestDockerComposeService(t, "services:\n  api:\n    image: nginx\n", recordCmd(t, &[]cmdCall{}))
 	basePath, ok := service.cfg.Artifacts.Gen(db.ArtifactDockerComposeFile, service.cfg.Generation)
@@ -484,6 +499,70 @@ func TestDockerComposeResolveConfigJSONUsesExactGeneration(t *testing.T) {
 	}
 }
 
+func TestDockerComposeCommandLayersComposeProject(t *testing.T) {
+	service := newTestDockerComposeService(t, "services:\n  api:\n    image: nginx\n", recordCmd(t, &[]cmdCall{}))
+	basePath, _ := service.cfg.Artifacts.Gen(db.ArtifactDockerComposeFile, service.cfg.Generation)
+	write := func(name, content string) string {
+		path := filepath.Join(service.DataDir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	override0 := write("override-0.yml", "services: {}\n")
+	override1 := write("override-1.yml", "services: {}\n")
+	network := write("network.yml", "networks: {}\n")
+	versions := write("versions.env", "TAG=1\n")
+	service.cfg.Artifacts[db.ArtifactDockerComposeOverride(0)] = artifactAt(1, override0)
+	service.cfg.Artifacts[db.ArtifactDockerComposeOverride(1)] = artifactAt(1, override1)
+	service.cfg.Artifacts[db.ArtifactDockerComposeNetwork] = artifactAt(1, network)
+	service.cfg.Artifacts[db.ArtifactDockerComposeEnv(0)] = artifactAt(1, versions)
+	service.cfg.Artifacts[db.ArtifactEnvFile] = artifactAt(1, write("service.env", "A=1\n"))
+	service.cfg.Artifacts[db.ArtifactDockerComposeProfiles] = artifactAt(1, write("profiles", "worker\ndebug\n"))
+
+	got, err := service.composeCommandArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"compose",
+		"--project-name", "catch-svc-a",
+		"--project-directory", service.DataDir,
+		"--file", basePath,
+		"--file", override0,
+		"--file", override1,
+		"--file", network,
+		"--env-file", versions,
+		"--env-file", filepath.Join(service.DataDir, ".env"),
+		"--profile", "worker",
+		"--profile", "debug",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("compose args mismatch (-want +got):\n%s", diff)
+	}
+
+	project, err := ComposeProjectFromArtifacts(service.cfg.Artifacts, 2)
+	if err != nil || !project.IsZero() {
+		t.Fatalf("generation 2 project = %#v, %v; want empty", project, err)
+	}
+}
+
+func TestComposeProjectHash(t *testing.T) {
+	if got := ComposeProjectHash(nil, nil, nil); got != "" {
+		t.Fatalf("empty hash = %q, want empty", got)
+	}
+	base := ComposeProjectHash([][]byte{[]byte("a"), []byte("b")}, nil, []string{"worker"})
+	for name, other := range map[string]string{
+		"reordered": ComposeProjectHash([][]byte{[]byte("b"), []byte("a")}, nil, []string{"worker"}),
+		"env":       ComposeProjectHash([][]byte{[]byte("a")}, [][]byte{[]byte("b")}, []string{"worker"}),
+		"profile":   ComposeProjectHash([][]byte{[]byte("a"), []byte("b")}, nil, nil),
+	} {
+		if other == base {
+			t.Fatalf("%s project hashed the same as base", name)
+		}
+	}
+}
+
 func TestComposeProjectNameMatchesDockerRuntimeIdentity(t *testing.T) {
 	if got := ComposeProjectName("svc-a"); got != "catch-svc-a" {
 		t.Fatalf("ComposeProjectName = %q, want catch-svc-a", got)
@@ -558,7 +558,9 @@ func sortedISOComponentNames(components map[string]netip.Addr) []string {
 
 func defaultISOInspectRunner(opts ISOInspectOptions) isoInspectRunner {
 	return func(ctx context.Context, operation string, ids ...string) ([]byte, error) {
-		dockerPath, err := DockerCmd()
+		// ISO networking rides on the yeet Docker network plugin, so ISO
+		// projects always run on docker.
+		dockerPath, err := ContainerRuntimeDocker.LookPath()
 		if err != nil {
 			return nil, err
 		}
@@ -60,8 +60,17 @@ func NewDockerComposeService(db *db.Store, cfg db.ServiceView, dataDir, runDir s
 	if err != nil {
 		return nil, err
 	}
+	dv, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+	rt, err := ContainerRuntimeFromData(dv)
+	if err != nil {
+		return nil, err
+	}
 	return &DockerComposeService{
 		Name:          cfg.Name(),
+		Runtime:       rt,
 		cfg:           cfg.AsStruct(),
 		DataDir:       dataDir,
 		NewCmd:        cmdutil.NewStdCmd,
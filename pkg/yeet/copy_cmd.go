@@ -39,6 +39,7 @@ type copyRequest struct {
 	Compress   bool
 	Verbose    bool
 	ForceProxy bool
+	Agent      bool
 	Sources    []copyEndpoint
 	Dst        copyEndpoint
 }
@@ -80,10 +81,10 @@ func runCopyCommand(args []string, cfg *ProjectConfig) error {
 		return err
 	}
 	if remoteCtx.Service.Info.ServiceType == serviceTypeVM {
-		return runVMRsyncCopyFunc(context.Background(), req, direction, remote, remoteCtx)
+		return runVMCopy(context.Background(), req, direction, remote, remoteCtx)
 	}
-	if req.ForceProxy {
-		return fmt.Errorf("copy --force-proxy only applies to VM services")
+	if err := validateNonVMCopyFlags(req); err != nil {
+		return err
 	}
 	req, err = normalizeServiceDataCopyRequest(req)
 	if err != nil {
@@ -98,6 +99,23 @@ func runCopyCommand(args []string, cfg *ProjectConfig) error {
 	return copyServiceDataToRemote(req)
 }
 
+func validateNonVMCopyFlags(req copyRequest) error {
+	if req.ForceProxy {
+		return fmt.Errorf("copy --force-proxy only applies to VM services")
+	}
+	if req.Agent {
+		return fmt.Errorf("copy --agent only applies to VM services")
+	}
+	return nil
+}
+
+func runVMCopy(ctx context.Context, req copyRequest, direction copyDirection, remote copyEndpoint, remoteCtx copyRemoteContext) error {
+	if req.Agent {
+		return runVMAgentCopyFunc(ctx, req, direction, remote, remoteCtx)
+	}
+	return runVMRsyncCopyFunc(ctx, req, direction, remote, remoteCtx)
+}
+
 func resolveCopyRemoteContext(ctx context.Context, remote copyEndpoint, cfg *ProjectConfig) (copyRemoteContext, error) {
 	if err := applyCopyHostOverrideForEndpoint(remote, cfg); err != nil {
 		return copyRemoteContext{}, err
@@ -345,14 +363,23 @@ func applyLongCopyFlag(req *copyRequest, arg string) error {
 		req.Verbose = true
 	case arg == "--force-proxy":
 		req.ForceProxy = true
-	case strings.HasPrefix(arg, "--force-proxy="):
-		return fmt.Errorf("copy --force-proxy does not take a value")
+	case arg == "--agent":
+		req.Agent = true
 	default:
-		return fmt.Errorf("unknown flag")
+		return copyFlagValueError(arg)
 	}
 	return nil
 }
 
+func copyFlagValueError(arg string) error {
+	for _, name := range []string{"--force-proxy", "--agent"} {
+		if strings.HasPrefix(arg, name+"=") {
+			return fmt.Errorf("copy %s does not take a value", name)
+		}
+	}
+	return fmt.Errorf("unknown flag")
+}
+
 func applyShortCopyFlag(req *copyRequest, flag rune) error {
 	switch flag {
 	case 'r', 'R':
@@ -1257,3 +1257,137 @@ func TestWaitRemoteCopyDrainsDone(t *testing.T) {
 	done <- nil
 	waitRemoteCopy(done)
 }
+
+func stubVMAgentCopyRemote(t *testing.T, fn func(target catchrpc.ExecTarget, service string, args []string, stdin io.Reader, stdout io.Writer) error) {
+	t.Helper()
+	oldExec := execRemoteShellFn
+	t.Cleanup(func() { execRemoteShellFn = oldExec })
+	execRemoteShellFn = func(ctx context.Context, host string, target catchrpc.ExecTarget, service string, args []string, stdin io.Reader, tty bool, stdout io.Writer) error {
+		if host != "yeet-lab" || tty {
+			t.Fatalf("exec host %q tty %v", host, tty)
+		}
+		return fn(target, service, args, stdin, stdout)
+	}
+}
+
+func TestRunVMAgentCopyPushesFileWithMode(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "motd")
+	if err := os.WriteFile(local, []byte("welcome\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	var gotArgs []string
+	var gotBody []byte
+	stubVMAgentCopyRemote(t, func(target catchrpc.ExecTarget, service string, args []string, stdin io.Reader, _ io.Writer) error {
+		if target != catchrpc.ExecTargetVMAgentPush || service != "devbox" {
+			t.Fatalf("target %q service %q", target, service)
+		}
+		gotArgs = args
+		gotBody, _ = io.ReadAll(stdin)
+		return nil
+	})
+
+	req := copyRequest{Agent: true, Sources: []copyEndpoint{{Path: local}}, Dst: copyEndpoint{Service: "devbox", Path: "/etc/", Remote: true, DirHint: true}}
+	if err := runVMAgentCopy(context.Background(), req, copyDirectionToRemote, req.Dst, copyRemoteContext{Host: "yeet-lab"}); err != nil {
+		t.Fatalf("runVMAgentCopy: %v", err)
+	}
+	if !reflect.DeepEqual(gotArgs, []string{"/etc/motd", "640"}) || string(gotBody) != "welcome\n" {
+		t.Fatalf("args %#v body %q", gotArgs, gotBody)
+	}
+}
+
+func TestRunVMAgentCopyPullsIntoDirectory(t *testing.T) {
+	dir := t.TempDir()
+	stubVMAgentCopyRemote(t, func(target catchrpc.ExecTarget, service string, args []string, _ io.Reader, stdout io.Writer) error {
+		if target != catchrpc.ExecTargetVMAgentPull || !reflect.DeepEqual(args, []string{"/var/log/app.log"}) {
+			t.Fatalf("target %q args %#v", target, args)
+		}
+		_, err := io.WriteString(stdout, "log line\n")
+		return err
+	})
+
+	src := copyEndpoint{Service: "devbox", Path: "/var/log/app.log", Remote: true}
+	req := copyRequest{Agent: true, Sources: []copyEndpoint{src}, Dst: copyEndpoint{Path: dir}}
+	if err := runVMAgentCopy(context.Background(), req, copyDirectionFromRemote, src, copyRemoteContext{Host: "yeet-lab"}); err != nil {
+		t.Fatalf("runVMAgentCopy: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	if err != nil || string(got) != "log line\n" {
+		t.Fatalf("pulled file = %q, %v", got, err)
+	}
+}
+
+func TestRunVMAgentCopyReportsGuestErrorAndLeavesNoFile(t *testing.T) {
+	dir := t.TempDir()
+	stubVMAgentCopyRemote(t, func(_ catchrpc.ExecTarget, _ string, _ []string, _ io.Reader, stdout io.Writer) error {
+		return remoteExitError{code: 1, output: "Error: VM agent error file_pull_failed: No such file or directory\n"}
+	})
+
+	src := copyEndpoint{Service: "devbox", Path: "/missing", Remote: true}
+	req := copyRequest{Agent: true, Sources: []copyEndpoint{src}, Dst: copyEndpoint{Path: filepath.Join(dir, "out")}}
+	err := runVMAgentCopy(context.Background(), req, copyDirectionFromRemote, src, copyRemoteContext{Host: "yeet-lab"})
+	if err == nil || err.Error() != "VM agent error file_pull_failed: No such file or directory" {
+		t.Fatalf("err = %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("left %d files behind", len(entries))
+	}
+}
+
+func TestVMAgentGuestPathRequiresAbsolutePath(t *testing.T) {
+	for _, ep := range []copyEndpoint{{Path: "etc/motd"}, {Path: ""}, {Path: "/", DirHint: true}} {
+		if _, err := vmAgentGuestPath(ep, ""); err == nil {
+			t.Fatalf("vmAgentGuestPath(%#v) succeeded", ep)
+		}
+	}
+}
+
+func TestRunCopyCommandRoutesAgentCopiesForVMs(t *testing.T) {
+	oldServerInfo := fetchSSHServerInfoFunc
+	oldServiceInfo := fetchSSHServiceInfoFunc
+	oldRsync := runVMRsyncCopyFunc
+	oldAgent := runVMAgentCopyFunc
+	oldHost := Host()
+	defer func() {
+		fetchSSHServerInfoFunc = oldServerInfo
+		fetchSSHServiceInfoFunc = oldServiceInfo
+		runVMRsyncCopyFunc = oldRsync
+		runVMAgentCopyFunc = oldAgent
+		SetHost(oldHost)
+		resetHostOverride()
+	}()
+	resetHostOverride()
+	SetHost("yeet-lab")
+
+	serviceType := serviceTypeVM
+	fetchSSHServerInfoFunc = func(context.Context, string) (serverInfo, error) {
+		return serverInfo{}, nil
+	}
+	fetchSSHServiceInfoFunc = func(context.Context, string, string) (catchrpc.ServiceInfoResponse, error) {
+		return catchrpc.ServiceInfoResponse{Found: true, Info: catchrpc.ServiceInfo{ServiceType: serviceType}}, nil
+	}
+	runVMRsyncCopyFunc = func(context.Context, copyRequest, copyDirection, copyEndpoint, copyRemoteContext) error {
+		t.Fatal("agent copy should not use rsync")
+		return nil
+	}
+	var agentCalled bool
+	runVMAgentCopyFunc = func(_ context.Context, req copyRequest, direction copyDirection, remote copyEndpoint, _ copyRemoteContext) error {
+		agentCalled = req.Agent && direction == copyDirectionFromRemote && remote.Path == "/var/log/syslog"
+		return nil
+	}
+
+	if err := runCopyCommand([]string{"--agent", "devbox:/var/log/syslog", "./"}, nil); err != nil {
+		t.Fatalf("runCopyCommand: %v", err)
+	}
+	if !agentCalled {
+		t.Fatal("agent copy was not routed")
+	}
+
+	serviceType = dockerServiceType
+	err := runCopyCommand([]string{"--agent", "./local.txt", "web:config.yml"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "copy --agent only applies to VM services") {
+		t.Fatalf("runCopyCommand error = %v, want agent regular service error", err)
+	}
+	if _, err := parseCopyArgs([]string{"--agent=yes", "a", "devbox:/b"}); err == nil || !strings.Contains(err.Error(), "does not take a value") {
+		t.Fatalf("parseCopyArgs error = %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yeet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+)
+
+var runVMAgentCopyFunc = runVMAgentCopy
+
+// runVMAgentCopy copies one regular file to or from a VM through catch and
+// the guest agent. Unlike the rsync path it needs neither ssh nor rsync, and
+// works before the guest has a network or sshd.
+func runVMAgentCopy(ctx context.Context, req copyRequest, direction copyDirection, remote copyEndpoint, remoteCtx copyRemoteContext) error {
+	if req.ForceProxy {
+		return fmt.Errorf("copy --agent does not take --force-proxy")
+	}
+	src, err := singleCopySource(req)
+	if err != nil {
+		return err
+	}
+	if direction == copyDirectionFromRemote {
+		return pullVMAgentCopy(ctx, req, remoteCtx.Host, src)
+	}
+	return pushVMAgentCopy(ctx, req, remoteCtx.Host, src.Path, remote)
+}
+
+func pushVMAgentCopy(ctx context.Context, req copyRequest, host, localPath string, dst copyEndpoint) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("copy --agent only copies regular files; %s is not one", localPath)
+	}
+	guestPath, err := vmAgentGuestPath(dst, filepath.Base(localPath))
+	if err != nil {
+		return err
+	}
+	report := newCopyReport(req.Verbose)
+	report.Start("sending")
+	counter := &countingReader{r: f}
+	args := []string{guestPath, fmt.Sprintf("%o", info.Mode().Perm())}
+	if err := execRemoteShellFn(ctx, host, catchrpc.ExecTargetVMAgentPush, dst.Service, args, counter, false, io.Discard); err != nil {
+		return vmAgentCopyError(err)
+	}
+	report.total = info.Size()
+	report.Finish(counter.N(), 0)
+	return nil
+}
+
+func pullVMAgentCopy(ctx context.Context, req copyRequest, host string, src copyEndpoint) (err error) {
+	guestPath, err := vmAgentGuestPath(src, "")
+	if err != nil {
+		return err
+	}
+	dest := req.Dst.Path
+	if existingLocalDirectory(dest) || strings.HasSuffix(dest, string(os.PathSeparator)) {
+		dest = filepath.Join(dest, path.Base(guestPath))
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".yeet-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	report := newCopyReport(req.Verbose)
+	report.Start("receiving")
+	counter := &countingWriter{w: tmp}
+	pullErr := execRemoteShellFn(ctx, host, catchrpc.ExecTargetVMAgentPull, src.Service, []string{guestPath}, nil, false, counter)
+	if err := errors.Join(vmAgentCopyError(pullErr), tmp.Chmod(0o644), tmp.Close()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return err
+	}
+	report.total = counter.n
+	report.Finish(0, counter.n)
+	return nil
+}
+
+// vmAgentGuestPath resolves the absolute guest path for an agent copy. A
+// directory destination keeps the local file name.
+func vmAgentGuestPath(ep copyEndpoint, base string) (string, error) {
+	p := strings.TrimSpace(ep.Path)
+	if !strings.HasPrefix(p, "/") {
+		return "", fmt.Errorf("copy --agent needs an absolute guest path, got %q", ep.Path)
+	}
+	if base != "" && ep.DirHint {
+		p = path.Join(p, base)
+	}
+	if p == "/" {
+		return "", fmt.Errorf("copy --agent needs a guest file path, got %q", ep.Path)
+	}
+	return path.Clean(p), nil
+}
+
+// vmAgentCopyError replaces the bare remote exit status with the error catch
+// printed, which is the useful part for a file transfer.
+func vmAgentCopyError(err error) error {
+	var exitErr remoteExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+	idx := strings.LastIndex(exitErr.output, "Error: ")
+	if idx < 0 {
+		return err
+	}
+	return errors.New(strings.TrimSpace(exitErr.output[idx+len("Error: "):]))
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
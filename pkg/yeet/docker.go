@@ -16,14 +16,16 @@ import (
 	"strings"
 
 	"github.com/shayne/yargs"
+	"github.com/yeetrun/yeet/pkg/catchrpc"
 	"github.com/yeetrun/yeet/pkg/cmdutil"
 	"github.com/yeetrun/yeet/pkg/svc"
 	"tailscale.com/client/local"
 )
 
 type pushFlagsParsed struct {
-	Run      bool `flag:"run"`
-	AllLocal bool `flag:"all-local"`
+	Run      bool   `flag:"run"`
+	AllLocal bool   `flag:"all-local"`
+	Platform string `flag:"platform"`
 }
 
 type pushRequest struct {
@@ -31,6 +33,7 @@ type pushRequest struct {
 	Image    string
 	Tag      string
 	AllLocal bool
+	Platform string
 }
 
 func HandlePush(ctx context.Context, args []string) error {
@@ -38,14 +41,14 @@ func HandlePush(ctx context.Context, args []string) error {
 	if err != nil {
 		return err
 	}
-	goos, goarch, err := remoteCatchOSAndArch()
-	if err != nil {
-		return err
-	}
 	if req.AllLocal {
+		goos, goarch, err := remoteCatchOSAndArch()
+		if err != nil {
+			return err
+		}
 		return pushAllLocalImages(ctx, req.Service, goos, goarch)
 	}
-	return pushImage(ctx, req.Service, req.Image, req.Tag)
+	return pushImageForPlatform(ctx, req.Image, req.Tag, req.Platform)
 }
 
 func parsePushRequest(args []string) (pushRequest, error) {
@@ -66,8 +69,11 @@ func parsePushRequest(args []string) (pushRequest, error) {
 	if len(pos) < 1 {
 		return pushRequest{}, errors.New("missing svc argument")
 	}
-	req := pushRequest{Service: pos[0], AllLocal: result.Flags.AllLocal}
+	req := pushRequest{Service: pos[0], AllLocal: result.Flags.AllLocal, Platform: strings.TrimSpace(result.Flags.Platform)}
 	if result.Flags.AllLocal {
+		if req.Platform != "" {
+			return pushRequest{}, errors.New("--platform cannot be used with --all-local")
+		}
 		return req, nil
 	}
 	if len(pos) < 2 {
@@ -123,7 +129,17 @@ func imageExists(ctx context.Context, imageName string) bool {
 }
 
 type dockerBuild struct {
-	Args []string
+	Args     []string
+	Platform string
+}
+
+// withLabel returns the build with a --label ahead of the context argument.
+func (b dockerBuild) withLabel(key, value string) dockerBuild {
+	n := len(b.Args)
+	args := make([]string, 0, n+2)
+	args = append(args, b.Args[:n-1]...)
+	args = append(args, "--label", key+"="+value, b.Args[n-1])
+	return dockerBuild{Args: args, Platform: b.Platform}
 }
 
 func buildDockerImageForRemote(ctx context.Context, dockerfilePath, imageName string) error {
@@ -142,7 +158,11 @@ func buildDockerImageForRemoteWithOutput(ctx context.Context, dockerfilePath, im
 	if err != nil {
 		return err
 	}
-	return runDockerBuildWithOutput(ctx, build, stderr)
+	hash, err := dockerBuildHash(dockerfilePath, build.Platform)
+	if err != nil {
+		return err
+	}
+	return runDockerBuildWithOutput(ctx, build.withLabel(catchrpc.ImageLabelBuildHash, hash), stderr)
 }
 
 func dockerBuildPlan(dockerfilePath, imageName, goos, goarch string) (dockerBuild, error) {
@@ -156,7 +176,7 @@ func dockerBuildPlan(dockerfilePath, imageName, goos, goarch string) (dockerBuil
 	}
 	targetPlatform := fmt.Sprintf("linux/%s", goarch)
 	dockerfileDir := filepath.Dir(dockerfilePath)
-	return dockerBuild{Args: []string{
+	return dockerBuild{Platform: targetPlatform, Args: []string{
 		"build",
 		"--platform", targetPlatform,
 		"-t", imageName,
@@ -185,17 +205,31 @@ func runDockerBuildWithOutput(ctx context.Context, build dockerBuild, stderr io.
 }
 
 func pushImage(ctx context.Context, _ string, image, tag string) error {
+	return pushImageForPlatform(ctx, image, tag, "")
+}
+
+// pushImageForPlatform pushes image for catch's platform. platform, when set,
+// is the one the user asked for and must be catch's.
+func pushImageForPlatform(ctx context.Context, image, tag, platform string) error {
 	return pushImageWithDeps(ctx, image, tag, pushImageDeps{
 		host:        getDockerHost,
 		imageExists: imageExists,
-		push:        runDockerPush,
+		platform: func(ctx context.Context, image string) (string, error) {
+			goos, goarch, err := remoteCatchOSAndArchFn()
+			if err != nil {
+				return "", err
+			}
+			return selectPushPlatform(ctx, image, platform, goos, goarch)
+		},
+		push: runDockerPush,
 	})
 }
 
 type pushImageDeps struct {
 	host        func(context.Context) (string, error)
 	imageExists func(context.Context, string) bool
-	push        func(context.Context, string, string) error
+	platform    func(context.Context, string) (string, error)
+	push        func(context.Context, string, string, string) error
 }
 
 func pushImageWithDeps(ctx context.Context, image, tag string, deps pushImageDeps) error {
@@ -206,11 +240,49 @@ func pushImageWithDeps(ctx context.Context, image, tag string, deps pushImageDep
 	if !deps.imageExists(ctx, image) {
 		return fmt.Errorf("image %s does not exist", image)
 	}
+	platform, err := deps.platform(ctx, image)
+	if err != nil {
+		return err
+	}
 	imgName, err := pushTargetImageName(host, image, tag)
 	if err != nil {
 		return err
 	}
-	return deps.push(ctx, image, imgName)
+	return deps.push(ctx, image, imgName, platform)
+}
+
+// selectPushPlatform returns the --platform to pass to docker push, or "" to
+// push the image as it is. An image built for another platform is pushed
+// only when it is a multi-platform image that also holds catch's; otherwise
+// it would fail at container start on catch.
+func selectPushPlatform(ctx context.Context, image, requested, goos, goarch string) (string, error) {
+	target := goos + "/" + goarch
+	if requested != "" {
+		reqOS, rest, _ := strings.Cut(requested, "/")
+		reqArch, _, _ := strings.Cut(rest, "/")
+		if reqOS != goos || reqArch != goarch {
+			return "", fmt.Errorf("--platform %s does not match %s, which runs %s", requested, Host(), target)
+		}
+	}
+	sys, arch, err := imageSystemAndArch(ctx, image)
+	if err != nil {
+		return "", err
+	}
+	if sys == goos && arch == goarch {
+		return requested, nil
+	}
+	if imageHasPlatform(ctx, image, target) {
+		return target, nil
+	}
+	return "", fmt.Errorf("image %s is built for %s/%s, but %s runs %s; rebuild it with `docker build --platform %s` or push a multi-platform image that includes %s", image, sys, arch, Host(), target, target, target)
+}
+
+// imageHasPlatform reports whether a multi-platform local image holds
+// platform. Docker engines without `image inspect --platform` report false.
+func imageHasPlatform(ctx context.Context, image, platform string) bool {
+	cmd := exec.CommandContext(ctx, "docker", "image", "inspect", "--platform", platform, "--format", "{{.Os}}/{{.Architecture}}", image)
+	output, err := cmd.Output()
+	return err == nil && strings.TrimSpace(string(output)) == platform
 }
 
 func pushTargetImageName(host, image, tag string) (string, error) {
@@ -238,10 +310,14 @@ func pushRepoName(image string) (string, error) {
 	return repo, nil
 }
 
-func runDockerPush(ctx context.Context, source, target string) error {
+func runDockerPush(ctx context.Context, source, target, platform string) error {
+	pushArgs := []string{"push", target}
+	if platform != "" {
+		pushArgs = []string{"push", "--platform", platform, target}
+	}
 	return do(
 		func() error { return exec.CommandContext(ctx, "docker", "tag", source, target).Run() },
-		func() error { return cmdutil.NewStdCmdContext(ctx, "docker", "push", target).Run() },
+		func() error { return cmdutil.NewStdCmdContext(ctx, "docker", pushArgs...).Run() },
 		func() error { return removeDockerImage(ctx, target) },
 	)
 }
@@ -0,0 +1,132 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yeet
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+)
+
+// dockerBuildHash identifies the inputs of a Dockerfile build: the target
+// platform, the Dockerfile and every file in the build context. Files matched
+// by .dockerignore are skipped; patterns the simple matcher does not
+// understand disable skipping, which at worst costs a redeploy.
+func dockerBuildHash(dockerfilePath, platform string) (string, error) {
+	manifest, _, err := dockerBuildContext(dockerfilePath)
+	if err != nil {
+		return "", err
+	}
+	return manifest.BuildHash(platform), nil
+}
+
+// dockerBuildContext lists the build context of dockerfilePath by content.
+// It also returns a local path for every digest in the manifest, which is
+// what a remote build uploads.
+func dockerBuildContext(dockerfilePath string) (catchrpc.BuildContextManifest, map[string]string, error) {
+	contextDir := filepath.Dir(dockerfilePath)
+	ignore, err := readDockerignore(contextDir)
+	if err != nil {
+		return catchrpc.BuildContextManifest{}, nil, err
+	}
+	dockerfile, err := hashFileSHA256(dockerfilePath)
+	if err != nil {
+		return catchrpc.BuildContextManifest{}, nil, err
+	}
+	manifest := catchrpc.BuildContextManifest{DockerfileSHA256: dockerfile}
+	blobs := map[string]string{dockerfile: dockerfilePath}
+	err = filepath.WalkDir(contextDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(contextDir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if dockerignored(rel, ignore) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch {
+		case d.IsDir():
+			return nil
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			manifest.Entries = append(manifest.Entries, catchrpc.BuildContextEntry{Path: rel, Link: target})
+		case d.Type().IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			sum, err := hashFileSHA256(path)
+			if err != nil {
+				return err
+			}
+			manifest.Entries = append(manifest.Entries, catchrpc.BuildContextEntry{Path: rel, Mode: uint32(info.Mode().Perm()), SHA256: sum})
+			if _, ok := blobs[sum]; !ok {
+				blobs[sum] = path
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return catchrpc.BuildContextManifest{}, nil, fmt.Errorf("hash build context %s: %w", contextDir, err)
+	}
+	return manifest, blobs, nil
+}
+
+// readDockerignore returns the exclusion patterns of the .dockerignore in
+// dir. It returns none when the file uses exceptions, so that nothing is
+// wrongly left out of the hash.
+func readDockerignore(dir string) ([]string, error) {
+	f, err := os.Open(filepath.Join(dir, ".dockerignore"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var patterns []string
+	scanner := bufio.NewScanner(io.LimitReader(f, 1<<20))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "!") || strings.Contains(line, "**") {
+			return nil, nil
+		}
+		line = filepath.ToSlash(filepath.Clean(strings.TrimPrefix(line, "/")))
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+func dockerignored(rel string, patterns []string) bool {
+	for _, pattern := range patterns {
+		for candidate := rel; candidate != "." && candidate != "/"; candidate = filepath.ToSlash(filepath.Dir(candidate)) {
+			if ok, _ := filepath.Match(pattern, candidate); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yeet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDockerBuildHash(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hash := func(platform string) string {
+		t.Helper()
+		got, err := dockerBuildHash(filepath.Join(dir, "Dockerfile"), platform)
+		if err != nil {
+			t.Fatalf("dockerBuildHash: %v", err)
+		}
+		return got
+	}
+	write("Dockerfile", "FROM scratch\nCOPY app /app\n")
+	write("app", "v1")
+	write(".dockerignore", "# build output\nlogs\n*.tmp\n")
+	base := hash("linux/amd64")
+
+	if again := hash("linux/amd64"); again != base {
+		t.Fatalf("hash not stable: %s != %s", again, base)
+	}
+	if hash("linux/arm64") == base {
+		t.Fatal("platform change kept the same hash")
+	}
+	write("logs/today.log", "noise")
+	write("scratch.tmp", "noise")
+	if got := hash("linux/amd64"); got != base {
+		t.Fatal("ignored files changed the hash")
+	}
+	write("app", "v2")
+	if hash("linux/amd64") == base {
+		t.Fatal("context change kept the same hash")
+	}
+}
+
+func TestReadDockerignoreDisablesExceptions(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".dockerignore"), []byte("*.md\n!README.md\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	patterns, err := readDockerignore(dir)
+	if err != nil || patterns != nil {
+		t.Fatalf("readDockerignore = %v, %v; want no patterns", patterns, err)
+	}
+	if !dockerignored("docs/a/b", []string{"docs"}) || dockerignored("src/docs", []string{"docs"}) {
+		t.Fatal("dockerignored should match from the context root")
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yeet
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+	"github.com/yeetrun/yeet/pkg/cli"
+)
+
+const runBuildFlag = "--build"
+
+// runArgsBuildMode returns the --build mode named by run args.
+func runArgsBuildMode(args []string) (string, error) {
+	flags, _, err := cli.ParseRun(args)
+	if err != nil {
+		return "", err
+	}
+	return flags.Build, nil
+}
+
+// removeRunBuildFlag drops --build, which only the client acts on for local
+// builds.
+func removeRunBuildFlag(args []string) []string {
+	flagArgs, payloadArgs := splitRunArgsForParsing(args)
+	flagArgs = removeRunFlags(flagArgs, map[string]bool{runBuildFlag: true})
+	if len(payloadArgs) == 0 {
+		return flagArgs
+	}
+	return append(append(flagArgs, "--"), payloadArgs...)
+}
+
+// runRemoteDockerBuild uploads the build context of dockerfilePath to catch,
+// which builds the image on the host and deploys it. Only the blobs catch
+// has not cached for the service are sent.
+func runRemoteDockerBuild(ctx context.Context, stdout io.Writer, dockerfilePath string, args []string) error {
+	manifest, blobs, err := dockerBuildContext(dockerfilePath)
+	if err != nil {
+		return err
+	}
+	svc := getService()
+	digests := make([]string, 0, len(blobs))
+	for digest := range blobs {
+		digests = append(digests, digest)
+	}
+	sort.Strings(digests)
+	missing, err := buildContextMissingFn(ctx, svc, digests)
+	if err != nil {
+		return err
+	}
+	args, err = encodeRunComposeProjectArgs(args)
+	if err != nil {
+		return err
+	}
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	go func() {
+		pw.CloseWithError(writeBuildContextUpload(pw, manifest, blobs, missing))
+	}()
+	if isStdoutWriter(stdout) {
+		return execRunFilePayload(ctx, svc, pr, args)
+	}
+	return execRunFilePayloadWithOutputFn(ctx, stdout, svc, pr, args)
+}
+
+func buildContextMissing(ctx context.Context, service string, digests []string) ([]string, error) {
+	resp, err := newRPCClient(Host()).BuildContextMissing(ctx, catchrpc.BuildContextMissingRequest{Service: service, Digests: digests})
+	if isRPCMethodNotFound(err) {
+		return nil, fmt.Errorf("catch on %s does not support --build=remote; update catch first", Host())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("check build context cache: %w", err)
+	}
+	return resp.Missing, nil
+}
+
+var buildContextMissingFn = buildContextMissing
+
+// writeBuildContextUpload writes the manifest followed by the missing blobs,
+// each once.
+func writeBuildContextUpload(w io.Writer, manifest catchrpc.BuildContextManifest, blobs map[string]string, missing []string) error {
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(w)
+	if err := tw.WriteHeader(&tar.Header{Name: catchrpc.BuildContextManifestName, Mode: 0o644, Size: int64(len(raw))}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(raw); err != nil {
+		return err
+	}
+	for _, digest := range missing {
+		file, ok := blobs[digest]
+		if !ok {
+			return fmt.Errorf("catch asked for unknown build context blob %s", digest)
+		}
+		if err := writeBuildContextBlob(tw, digest, file); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func writeBuildContextBlob(tw *tar.Writer, digest, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{Name: path.Join(catchrpc.BuildContextBlobDir, digest), Mode: 0o644, Size: info.Size()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("upload %s: %w", file, err)
+	}
+	return nil
+}
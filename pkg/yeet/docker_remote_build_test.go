@@ -0,0 +1,112 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yeet
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+)
+
+func TestRunRemoteDockerBuildUploadsMissingBlobs(t *testing.T) {
+	dir := t.TempDir()
+	dockerfile := filepath.Join(dir, "Dockerfile")
+	for name, content := range map[string]string{
+		"Dockerfile":    "FROM scratch\nCOPY . /\n",
+		"app.txt":       "hello\n",
+		"copy.txt":      "hello\n",
+		"secret.env":    "TOKEN=x\n",
+		".dockerignore": "secret.env\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldMissing := buildContextMissingFn
+	oldExec := execRunFilePayloadWithOutputFn
+	oldService := serviceOverride
+	t.Cleanup(func() {
+		buildContextMissingFn = oldMissing
+		execRunFilePayloadWithOutputFn = oldExec
+		serviceOverride = oldService
+	})
+	serviceOverride = "web"
+	appDigest, err := hashFileSHA256(filepath.Join(dir, "app.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buildContextMissingFn = func(_ context.Context, service string, digests []string) ([]string, error) {
+		if service != "web" || len(digests) != 3 {
+			t.Fatalf("missing query = %q, %q; want web and three unique blobs", service, digests)
+		}
+		return []string{appDigest}, nil
+	}
+	var gotArgs, gotNames []string
+	var gotManifest catchrpc.BuildContextManifest
+	execRunFilePayloadWithOutputFn = func(_ context.Context, _ io.Writer, _ string, payload io.Reader, args []string) error {
+		gotArgs = args
+		tr := tar.NewReader(payload)
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			gotNames = append(gotNames, hdr.Name)
+			if hdr.Name == catchrpc.BuildContextManifestName {
+				if err := json.NewDecoder(tr).Decode(&gotManifest); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	args := []string{"--build=remote", "--net=svc"}
+	if err := runRemoteDockerBuild(context.Background(), &bytes.Buffer{}, dockerfile, args); err != nil {
+		t.Fatalf("runRemoteDockerBuild: %v", err)
+	}
+	if !reflect.DeepEqual(gotArgs, args) {
+		t.Fatalf("remote args = %q, want %q", gotArgs, args)
+	}
+	if want := []string{catchrpc.BuildContextManifestName, "blobs/" + appDigest}; !reflect.DeepEqual(gotNames, want) {
+		t.Fatalf("upload entries = %q, want %q", gotNames, want)
+	}
+	var paths []string
+	for _, entry := range gotManifest.Entries {
+		paths = append(paths, entry.Path)
+	}
+	if want := []string{".dockerignore", "Dockerfile", "app.txt", "copy.txt"}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("manifest paths = %q, want %q", paths, want)
+	}
+	hash, err := dockerBuildHash(dockerfile, "linux/arm64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotManifest.BuildHash("linux/arm64") != hash {
+		t.Fatal("manifest build hash differs from the local build hash")
+	}
+}
+
+func TestRunBuildFlagHandling(t *testing.T) {
+	if got := removeRunBuildFlag([]string{"--build", "local", "--net=svc", "--", "--build=x"}); !reflect.DeepEqual(got, []string{"--net=svc", "--", "--build=x"}) {
+		t.Fatalf("removeRunBuildFlag = %q", got)
+	}
+	err := validateRunFileArgs(0, []string{"--build=remote"}, false)
+	if err == nil || !strings.Contains(err.Error(), "only applies to Dockerfile payloads") {
+		t.Fatalf("validateRunFileArgs error = %v, want Dockerfile-only rejection", err)
+	}
+}
@@ -42,6 +42,16 @@ func TestParsePushRequest(t *testing.T) {
 			args: []string{"svc-a", "--all-local"},
 			want: pushRequest{Service: "svc-a", AllLocal: true},
 		},
+		{
+			name: "platform",
+			args: []string{"svc-a", "app:v1", "--platform=linux/arm64"},
+			want: pushRequest{Service: "svc-a", Image: "app:v1", Tag: "latest", Platform: "linux/arm64"},
+		},
+		{
+			name:    "platform with all local",
+			args:    []string{"svc-a", "--all-local", "--platform=linux/arm64"},
+			wantErr: "--platform cannot be used with --all-local",
+		},
 		{
 			name:    "missing service",
 			args:    nil,
@@ -150,6 +160,10 @@ func TestBuildDockerImageForRemoteUsesPlannedDockerCommand(t *testing.T) {
 		return "linux", "amd64", nil
 	}
 
+	hash, err := dockerBuildHash(dockerfile, "linux/amd64")
+	if err != nil {
+		t.Fatalf("dockerBuildHash: %v", err)
+	}
 	if err := buildDockerImageForRemote(context.Background(), dockerfile, "svc:build-test"); err != nil {
 		t.Fatalf("buildDockerImageForRemote returned error: %v", err)
 	}
@@ -159,7 +173,7 @@ func TestBuildDockerImageForRemoteUsesPlannedDockerCommand(t *testing.T) {
 		t.Fatalf("read args: %v", err)
 	}
 	got := strings.Split(strings.TrimSpace(string(gotBytes)), "\n")
-	want := []string{"build", "--platform", "linux/amd64", "-t", "svc:build-test", "-f", dockerfile, tmp}
+	want := []string{"build", "--platform", "linux/amd64", "-t", "svc:build-test", "-f", dockerfile, "--label", "com.yeetrun.build-hash=" + hash, tmp}
 	if !reflect.DeepEqual(got, want) {
 		t.Fatalf("docker args = %#v, want %#v", got, want)
 	}
@@ -232,7 +246,10 @@ func TestPushImageWithDepsPlansTagPushAndCleanup(t *testing.T) {
 		imageExists: func(ctx context.Context, image string) bool {
 			return image == "registry.example.com/team/app:v1"
 		},
-		push: func(ctx context.Context, source, target string) error {
+		platform: func(context.Context, string) (string, error) {
+			return "", nil
+		},
+		push: func(ctx context.Context, source, target, platform string) error {
 			pushedSource = source
 			pushedTarget = target
 			return nil
@@ -259,7 +276,11 @@ func TestPushImageWithDepsErrors(t *testing.T) {
 			t.Fatal("imageExists should not be called after host error")
 			return false
 		},
-		push: func(context.Context, string, string) error {
+		platform: func(context.Context, string) (string, error) {
+			t.Fatal("platform should not be called after host error")
+			return "", nil
+		},
+		push: func(context.Context, string, string, string) error {
 			t.Fatal("push should not be called after host error")
 			return nil
 		},
@@ -275,7 +296,11 @@ func TestPushImageWithDepsErrors(t *testing.T) {
 		imageExists: func(ctx context.Context, image string) bool {
 			return image == "other:tag"
 		},
-		push: func(context.Context, string, string) error {
+		platform: func(context.Context, string) (string, error) {
+			t.Fatal("platform should not be called when image is missing")
+			return "", nil
+		},
+		push: func(context.Context, string, string, string) error {
 			t.Fatal("push should not be called when image is missing")
 			return nil
 		},
@@ -292,7 +317,10 @@ func TestPushImageWithDepsErrors(t *testing.T) {
 		imageExists: func(context.Context, string) bool {
 			return true
 		},
-		push: func(context.Context, string, string) error {
+		platform: func(context.Context, string) (string, error) {
+			return "", nil
+		},
+		push: func(context.Context, string, string, string) error {
 			return wantPushErr
 		},
 	})
@@ -450,7 +478,7 @@ printf '%%s\n' "$*" >> %q
 exit 0
 `, logFile))
 
-	if err := runDockerPush(context.Background(), "app:dev", "catch.example/app:latest"); err != nil {
+	if err := runDockerPush(context.Background(), "app:dev", "catch.example/app:latest", ""); err != nil {
 		t.Fatalf("runDockerPush error: %v", err)
 	}
 	b, err := os.ReadFile(logFile)
@@ -467,6 +495,64 @@ exit 0
 	}
 }
 
+func TestSelectPushPlatform(t *testing.T) {
+	fakeDockerInPath(t, `
+case "$*" in
+  "inspect --format {{.Os}},{{.Architecture}} native:dev") printf 'linux,arm64\n' ;;
+  "inspect --format {{.Os}},{{.Architecture}} "*) printf 'linux,amd64\n' ;;
+  "image inspect --platform linux/arm64 --format {{.Os}}/{{.Architecture}} multi:dev") printf 'linux/arm64\n' ;;
+  *) exit 1 ;;
+esac
+`)
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		image     string
+		requested string
+		want      string
+		wantErr   string
+	}{
+		{name: "native image pushes as is", image: "native:dev"},
+		{name: "native image keeps requested platform", image: "native:dev", requested: "linux/arm64", want: "linux/arm64"},
+		{name: "multi-platform image selects catch platform", image: "multi:dev", want: "linux/arm64"},
+		{name: "foreign image is refused", image: "amd64:dev", wantErr: "runs linux/arm64; rebuild it with `docker build --platform linux/arm64`"},
+		{name: "requested platform must be catch's", image: "native:dev", requested: "linux/amd64", wantErr: "--platform linux/amd64 does not match"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectPushPlatform(ctx, tt.image, tt.requested, "linux", "arm64")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("selectPushPlatform error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("selectPushPlatform = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunDockerPushSelectsPlatform(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "docker.log")
+	fakeDockerInPath(t, fmt.Sprintf(`
+printf '%%s\n' "$*" >> %q
+exit 0
+`, logFile))
+
+	if err := runDockerPush(context.Background(), "app:dev", "catch.example/app:latest", "linux/arm64"); err != nil {
+		t.Fatalf("runDockerPush error: %v", err)
+	}
+	b, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("ReadFile docker log: %v", err)
+	}
+	if !strings.Contains(string(b), "push --platform linux/arm64 catch.example/app:latest\n") {
+		t.Fatalf("docker calls = %q, want platform push", string(b))
+	}
+}
+
 func TestRunDockerPushReturnsDockerError(t *testing.T) {
 	fakeDockerInPath(t, `
 if [ "$1" = "tag" ]; then
@@ -475,7 +561,7 @@ fi
 exit 0
 `)
 
-	if err := runDockerPush(context.Background(), "app:dev", "catch.example/app:latest"); err == nil {
+	if err := runDockerPush(context.Background(), "app:dev", "catch.example/app:latest", ""); err == nil {
 		t.Fatal("runDockerPush error = nil, want docker error")
 	}
 }
@@ -485,7 +571,7 @@ func TestRunDockerPushHonorsContextCancellation(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
-	if err := runDockerPush(ctx, "app:dev", "catch.example/app:latest"); err == nil {
+	if err := runDockerPush(ctx, "app:dev", "catch.example/app:latest", ""); err == nil {
 		t.Fatal("runDockerPush error = nil, want context cancellation")
 	}
 }
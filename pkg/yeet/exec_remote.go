@@ -903,5 +903,49 @@ func writeEvent(w io.Writer, ev catchrpc.Event) error {
 }
 
 func HandleMountSys(ctx context.Context, rawArgs []string) error {
-	return execRemote(ctx, systemServiceName, rawArgs, nil, true)
+	args, credentials := mountCredentialsArg(rawArgs)
+	if credentials == "" {
+		return execRemoteFn(ctx, systemServiceName, args, nil, true)
+	}
+	var stdin io.Reader = os.Stdin
+	if credentials != "-" {
+		path, err := expandUserConfigPath(credentials)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("open credentials: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		stdin = f
+	}
+	return withRemoteExecTTYDisabled(func() error {
+		return execRemoteFn(ctx, systemServiceName, args, stdin, false)
+	})
+}
+
+// mountCredentialsArg pulls the local --credentials file out of a mount
+// command and rewrites the flag to read from stdin, since catch cannot read
+// files on the client.
+func mountCredentialsArg(rawArgs []string) ([]string, string) {
+	args := make([]string, 0, len(rawArgs))
+	var credentials string
+	for i := 0; i < len(rawArgs); i++ {
+		arg := rawArgs[i]
+		switch {
+		case arg == "--":
+			return append(args, rawArgs[i:]...), credentials
+		case strings.HasPrefix(arg, "--credentials="):
+			credentials = strings.TrimPrefix(arg, "--credentials=")
+		case arg == "--credentials" && i+1 < len(rawArgs):
+			i++
+			credentials = rawArgs[i]
+		default:
+			args = append(args, arg)
+			continue
+		}
+		args = append(args, "--credentials=-")
+	}
+	return args, credentials
 }
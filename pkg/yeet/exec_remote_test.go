@@ -1104,3 +1104,75 @@ func TestExecRemoteClosesInteractiveStdinBeforeNextLocalPrompt(t *testing.T) {
 		t.Fatal("timed out waiting for local confirmation to read input")
 	}
 }
+
+func TestMountCredentialsArg(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantArgs []string
+		wantFile string
+	}{
+		{
+			name:     "no credentials",
+			args:     []string{"mount", "host:/export", "data"},
+			wantArgs: []string{"mount", "host:/export", "data"},
+		},
+		{
+			name:     "equals form",
+			args:     []string{"mount", "//nas/media", "--type=cifs", "--credentials=./nas.cred"},
+			wantArgs: []string{"mount", "//nas/media", "--type=cifs", "--credentials=-"},
+			wantFile: "./nas.cred",
+		},
+		{
+			name:     "separate value",
+			args:     []string{"mount", "--credentials", "key", "user@host:/srv", "--type", "sshfs"},
+			wantArgs: []string{"mount", "--credentials=-", "user@host:/srv", "--type", "sshfs"},
+			wantFile: "key",
+		},
+		{
+			name:     "after double dash",
+			args:     []string{"mount", "host:/export", "--", "--credentials=x"},
+			wantArgs: []string{"mount", "host:/export", "--", "--credentials=x"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, file := mountCredentialsArg(tt.args)
+			if !reflect.DeepEqual(args, tt.wantArgs) || file != tt.wantFile {
+				t.Fatalf("mountCredentialsArg = %q, %q; want %q, %q", args, file, tt.wantArgs, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestHandleMountSysStreamsCredentialsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nas.cred")
+	if err := os.WriteFile(path, []byte("username=media\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	oldExec := execRemoteFn
+	t.Cleanup(func() { execRemoteFn = oldExec })
+	var gotArgs []string
+	var gotStdin string
+	var gotTTY bool
+	execRemoteFn = func(_ context.Context, _ string, args []string, stdin io.Reader, tty bool) error {
+		gotArgs = args
+		gotTTY = tty
+		raw, err := io.ReadAll(stdin)
+		gotStdin = string(raw)
+		return err
+	}
+
+	if err := HandleMountSys(context.Background(), []string{"mount", "//nas/media", "--type=cifs", "--credentials=" + path}); err != nil {
+		t.Fatalf("HandleMountSys: %v", err)
+	}
+	if want := []string{"mount", "//nas/media", "--type=cifs", "--credentials=-"}; !reflect.DeepEqual(gotArgs, want) {
+		t.Fatalf("remote args = %q, want %q", gotArgs, want)
+	}
+	if gotTTY {
+		t.Fatal("credentials must be streamed without a tty")
+	}
+	if gotStdin != "username=media\n" {
+		t.Fatalf("streamed credentials = %q", gotStdin)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/yeetrun/yeet/pkg/catchrpc"
 	"github.com/yeetrun/yeet/pkg/cli"
 	"github.com/yeetrun/yeet/pkg/cmdutil"
+	"github.com/yeetrun/yeet/pkg/svc"
 )
 
 var runHostSetFn = runHostSet
@@ -37,6 +38,18 @@ type isoPoolClient interface {
 	ISOPoolApply(context.Context, catchrpc.ISOPoolApplyRequest) (catchrpc.ISOPoolApplyResult, error)
 }
 
+type registryMirrorClient interface {
+	RegistryMirrorSet(context.Context, catchrpc.RegistryMirrorSetRequest) (catchrpc.RegistryMirrorSetResult, error)
+}
+
+type containerRuntimeClient interface {
+	ContainerRuntimeSet(context.Context, catchrpc.ContainerRuntimeSetRequest) (catchrpc.ContainerRuntimeSetResult, error)
+}
+
+type imagePolicyClient interface {
+	ImagePolicySet(context.Context, catchrpc.ImagePolicySetRequest) (catchrpc.ImagePolicySetResult, error)
+}
+
 var (
 	newHostStorageClientFn = func(host string) hostStorageClient {
 		return newRPCClient(host)
@@ -44,6 +57,15 @@ var (
 	newISOPoolClientFn = func(host string) isoPoolClient {
 		return newRPCClient(host)
 	}
+	newRegistryMirrorClientFn = func(host string) registryMirrorClient {
+		return newRPCClient(host)
+	}
+	newContainerRuntimeClientFn = func(host string) containerRuntimeClient {
+		return newRPCClient(host)
+	}
+	newImagePolicyClientFn = func(host string) imagePolicyClient {
+		return newRPCClient(host)
+	}
 	confirmHostSetFn                      = cmdutil.Confirm
 	hostSetStdin                io.Reader = os.Stdin
 	hostSetStdout               io.Writer = os.Stdout
@@ -86,6 +108,15 @@ func trimHostSetSubcommand(args []string) []string {
 }
 
 func runHostSet(ctx context.Context, flags cli.HostSetFlags) error {
+	if flags.ImagePolicy != "" || len(flags.TrustKeys) > 0 {
+		return runHostSetImagePolicy(ctx, flags)
+	}
+	if strings.TrimSpace(flags.ContainerRuntime) != "" {
+		return runHostSetContainerRuntime(ctx, flags)
+	}
+	if strings.TrimSpace(flags.RegistryMirror) != "" {
+		return runHostSetRegistryMirror(ctx, flags)
+	}
 	if strings.TrimSpace(flags.ISOPool) != "" {
 		return runHostSetISOPool(ctx, flags)
 	}
@@ -132,6 +163,114 @@ func runHostSetISOPool(ctx context.Context, flags cli.HostSetFlags) error {
 	return applyISOPoolPlan(ctx, client, host, plan, flags)
 }
 
+func runHostSetRegistryMirror(ctx context.Context, flags cli.HostSetFlags) error {
+	if hostSetHasStorageFlags(flags) || strings.TrimSpace(flags.ISOPool) != "" {
+		return fmt.Errorf("--registry-mirror cannot be combined with other host settings")
+	}
+	upstream := strings.TrimSpace(flags.RegistryMirror)
+	if upstream == "off" {
+		upstream = ""
+	}
+	host := Host()
+	result, err := newRegistryMirrorClientFn(host).RegistryMirrorSet(ctx, catchrpc.RegistryMirrorSetRequest{Upstream: upstream})
+	if err != nil {
+		return fmt.Errorf("set registry mirror on %s: %w", host, err)
+	}
+	return renderRegistryMirrorSetResult(hostSetStdout, host, result)
+}
+
+func renderRegistryMirrorSetResult(w io.Writer, host string, result catchrpc.RegistryMirrorSetResult) error {
+	var msg string
+	switch {
+	case result.Upstream == "" && !result.Changed:
+		msg = fmt.Sprintf("Registry mirror is already off on %s.", host)
+	case result.Upstream == "":
+		msg = fmt.Sprintf("Registry mirror disabled on %s.", host)
+	case !result.Changed:
+		msg = fmt.Sprintf("Registry mirror for %s is already enabled on %s at %s.", result.Upstream, host, result.URL)
+	default:
+		msg = fmt.Sprintf("Registry mirror for %s enabled on %s at %s.", result.Upstream, host, result.URL)
+	}
+	if _, err := fmt.Fprintln(w, msg); err != nil {
+		return err
+	}
+	if result.DockerReloaded {
+		_, err := fmt.Fprintln(w, "Reloaded the Docker daemon with the new registry-mirrors setting.")
+		return err
+	}
+	return nil
+}
+
+func runHostSetContainerRuntime(ctx context.Context, flags cli.HostSetFlags) error {
+	if hostSetHasStorageFlags(flags) || strings.TrimSpace(flags.ISOPool) != "" || strings.TrimSpace(flags.RegistryMirror) != "" {
+		return fmt.Errorf("--container-runtime cannot be combined with other host settings")
+	}
+	host := Host()
+	result, err := newContainerRuntimeClientFn(host).ContainerRuntimeSet(ctx, catchrpc.ContainerRuntimeSetRequest{Runtime: strings.TrimSpace(flags.ContainerRuntime)})
+	if err != nil {
+		return fmt.Errorf("set container runtime on %s: %w", host, err)
+	}
+	return renderContainerRuntimeSetResult(hostSetStdout, host, result)
+}
+
+func renderContainerRuntimeSetResult(w io.Writer, host string, result catchrpc.ContainerRuntimeSetResult) error {
+	if !result.Changed {
+		_, err := fmt.Fprintf(w, "Container runtime is already %s on %s.\n", result.Runtime, host)
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "Container runtime set to %s on %s.\n", result.Runtime, host); err != nil {
+		return err
+	}
+	if limits := svc.ContainerRuntime(result.Runtime).Limitations(); len(limits) > 0 {
+		if _, err := fmt.Fprintf(w, "Not available on %s:\n", result.Runtime); err != nil {
+			return err
+		}
+		for _, limit := range limits {
+			if _, err := fmt.Fprintf(w, "  - %s\n", limit); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintln(w, "Restart catch on the host (systemctl restart catch) to finish the switch.")
+	return err
+}
+
+func runHostSetImagePolicy(ctx context.Context, flags cli.HostSetFlags) error {
+	if hostSetHasStorageFlags(flags) || strings.TrimSpace(flags.ISOPool) != "" || strings.TrimSpace(flags.RegistryMirror) != "" || strings.TrimSpace(flags.ContainerRuntime) != "" {
+		return fmt.Errorf("--image-policy and --trust-key cannot be combined with other host settings")
+	}
+	req := catchrpc.ImagePolicySetRequest{Mode: flags.ImagePolicy}
+	for _, path := range flags.TrustKeys {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read trust key: %w", err)
+		}
+		req.TrustKeys = append(req.TrustKeys, string(data))
+	}
+	host := Host()
+	result, err := newImagePolicyClientFn(host).ImagePolicySet(ctx, req)
+	if err != nil {
+		return fmt.Errorf("set image policy on %s: %w", host, err)
+	}
+	return renderImagePolicySetResult(hostSetStdout, host, result)
+}
+
+func renderImagePolicySetResult(w io.Writer, host string, result catchrpc.ImagePolicySetResult) error {
+	verb := "set to"
+	if !result.Changed {
+		verb = "is already"
+	}
+	if _, err := fmt.Fprintf(w, "Image policy %s %s on %s.\n", verb, result.Mode, host); err != nil {
+		return err
+	}
+	for _, fp := range result.TrustKeys {
+		if _, err := fmt.Fprintf(w, "Trusted key: %s\n", fp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func applyISOPoolPlan(ctx context.Context, client isoPoolClient, host string, plan catchrpc.ISOPoolPlan, flags cli.HostSetFlags) error {
 	if err := blockedISOPoolPlanError(plan); err != nil {
 		return err
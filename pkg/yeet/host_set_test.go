@@ -54,7 +54,7 @@ func TestHandleHostSetCallsRunnerWithoutTargets(t *testing.T) {
 	if !called {
 		t.Fatal("runHostSetFn was not called")
 	}
-	if got != (cli.HostSetFlags{}) {
+	if !reflect.DeepEqual(got, cli.HostSetFlags{}) {
 		t.Fatalf("flags = %#v, want zero-value flags", got)
 	}
 }
@@ -347,6 +347,113 @@ func TestRunHostSetRejectsCombiningISOPoolAndStorage(t *testing.T) {
 	}
 }
 
+func TestRunHostSetRegistryMirror(t *testing.T) {
+	state := stubHostSetRuntime(t)
+	state.mirrorClient.result = catchrpc.RegistryMirrorSetResult{Upstream: "docker.io", URL: "http://127.0.0.1:41549", Changed: true, DockerReloaded: true}
+	if err := runHostSet(context.Background(), cli.HostSetFlags{RegistryMirror: "docker.io"}); err != nil {
+		t.Fatal(err)
+	}
+	state.mirrorClient.result = catchrpc.RegistryMirrorSetResult{}
+	if err := runHostSet(context.Background(), cli.HostSetFlags{RegistryMirror: "off"}); err != nil {
+		t.Fatal(err)
+	}
+	want := []catchrpc.RegistryMirrorSetRequest{{Upstream: "docker.io"}, {}}
+	if !reflect.DeepEqual(state.mirrorClient.requests, want) {
+		t.Fatalf("requests = %#v, want %#v", state.mirrorClient.requests, want)
+	}
+	for _, line := range []string{
+		"Registry mirror for docker.io enabled on catch-a at http://127.0.0.1:41549.",
+		"Reloaded the Docker daemon",
+		"Registry mirror is already off on catch-a.",
+	} {
+		if !strings.Contains(state.stdout.String(), line) {
+			t.Fatalf("stdout = %q, want %q", state.stdout.String(), line)
+		}
+	}
+	if len(state.prompts) != 0 {
+		t.Fatalf("prompts = %#v, want none", state.prompts)
+	}
+
+	err := runHostSet(context.Background(), cli.HostSetFlags{RegistryMirror: "docker.io", ISOPool: "10.42.0.0/16"})
+	if err == nil || !strings.Contains(err.Error(), "cannot be combined") {
+		t.Fatalf("combined error = %v", err)
+	}
+}
+
+func TestRunHostSetContainerRuntime(t *testing.T) {
+	state := stubHostSetRuntime(t)
+	state.rtClient.result = catchrpc.ContainerRuntimeSetResult{Runtime: "podman", Changed: true}
+	if err := runHostSet(context.Background(), cli.HostSetFlags{ContainerRuntime: "podman"}); err != nil {
+		t.Fatal(err)
+	}
+	state.rtClient.result = catchrpc.ContainerRuntimeSetResult{Runtime: "podman"}
+	if err := runHostSet(context.Background(), cli.HostSetFlags{ContainerRuntime: "podman"}); err != nil {
+		t.Fatal(err)
+	}
+	want := []catchrpc.ContainerRuntimeSetRequest{{Runtime: "podman"}, {Runtime: "podman"}}
+	if !reflect.DeepEqual(state.rtClient.requests, want) {
+		t.Fatalf("requests = %#v, want %#v", state.rtClient.requests, want)
+	}
+	for _, line := range []string{
+		"Container runtime set to podman on catch-a.",
+		"Not available on podman:\n  - rootless mode",
+		"--net=iso",
+		"systemctl restart catch",
+		"Container runtime is already podman on catch-a.",
+	} {
+		if !strings.Contains(state.stdout.String(), line) {
+			t.Fatalf("stdout = %q, want %q", state.stdout.String(), line)
+		}
+	}
+	if strings.Count(state.stdout.String(), "systemctl restart catch") != 1 {
+		t.Fatalf("stdout = %q, want one restart hint", state.stdout.String())
+	}
+
+	err := runHostSet(context.Background(), cli.HostSetFlags{ContainerRuntime: "podman", RegistryMirror: "docker.io"})
+	if err == nil || !strings.Contains(err.Error(), "cannot be combined") {
+		t.Fatalf("combined error = %v", err)
+	}
+}
+
+func TestRunHostSetImagePolicy(t *testing.T) {
+	state := stubHostSetRuntime(t)
+	keyPath := filepath.Join(t.TempDir(), "cosign.pub")
+	const key = "-----BEGIN PUBLIC KEY-----\nMFkw\n-----END PUBLIC KEY-----\n"
+	if err := os.WriteFile(keyPath, []byte(key), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	state.policyClient.result = catchrpc.ImagePolicySetResult{Mode: "require-signed", TrustKeys: []string{"SHA256:abc"}, Changed: true}
+	if err := runHostSet(context.Background(), cli.HostSetFlags{ImagePolicy: "require-signed", TrustKeys: []string{keyPath}}); err != nil {
+		t.Fatal(err)
+	}
+	state.policyClient.result = catchrpc.ImagePolicySetResult{Mode: "off", TrustKeys: []string{"SHA256:abc"}}
+	if err := runHostSet(context.Background(), cli.HostSetFlags{ImagePolicy: "off"}); err != nil {
+		t.Fatal(err)
+	}
+	want := []catchrpc.ImagePolicySetRequest{{Mode: "require-signed", TrustKeys: []string{key}}, {Mode: "off"}}
+	if !reflect.DeepEqual(state.policyClient.requests, want) {
+		t.Fatalf("requests = %#v, want %#v", state.policyClient.requests, want)
+	}
+	for _, line := range []string{
+		"Image policy set to require-signed on catch-a.",
+		"Trusted key: SHA256:abc",
+		"Image policy is already off on catch-a.",
+	} {
+		if !strings.Contains(state.stdout.String(), line) {
+			t.Fatalf("stdout = %q, want %q", state.stdout.String(), line)
+		}
+	}
+
+	err := runHostSet(context.Background(), cli.HostSetFlags{ImagePolicy: "off", ContainerRuntime: "podman"})
+	if err == nil || !strings.Contains(err.Error(), "cannot be combined") {
+		t.Fatalf("combined error = %v", err)
+	}
+	err = runHostSet(context.Background(), cli.HostSetFlags{TrustKeys: []string{filepath.Join(t.TempDir(), "missing.pub")}})
+	if err == nil || !strings.Contains(err.Error(), "read trust key") {
+		t.Fatalf("missing key error = %v", err)
+	}
+}
+
 func TestValidateExplicitISOPool(t *testing.T) {
 	for _, valid := range []string{"10.42.0.0/16", "172.30.0.0/16", "192.168.0.0/16"} {
 		if got, err := validateExplicitISOPool(valid); err != nil || got.String() != valid {
@@ -861,25 +968,34 @@ func TestApplyHostStorageConfigMovesUsesZFSChildDataset(t *testing.T) {
 }
 
 type hostSetTestState struct {
-	client     *fakeHostStorageClient
-	poolClient *fakeISOPoolClient
-	stdout     strings.Builder
-	prompts    []string
-	confirm    bool
-	host       string
+	client       *fakeHostStorageClient
+	poolClient   *fakeISOPoolClient
+	mirrorClient *fakeRegistryMirrorClient
+	rtClient     *fakeContainerRuntimeClient
+	policyClient *fakeImagePolicyClient
+	stdout       strings.Builder
+	prompts      []string
+	confirm      bool
+	host         string
 }
 
 func stubHostSetRuntime(t *testing.T) *hostSetTestState {
 	t.Helper()
 	t.Setenv("CATCH_HOST", "")
 	state := &hostSetTestState{
-		client:     &fakeHostStorageClient{},
-		poolClient: &fakeISOPoolClient{},
-		confirm:    true,
-		host:       "catch-a",
+		client:       &fakeHostStorageClient{},
+		poolClient:   &fakeISOPoolClient{},
+		mirrorClient: &fakeRegistryMirrorClient{},
+		rtClient:     &fakeContainerRuntimeClient{},
+		policyClient: &fakeImagePolicyClient{},
+		confirm:      true,
+		host:         "catch-a",
 	}
 	oldClient := newHostStorageClientFn
 	oldPoolClient := newISOPoolClientFn
+	oldMirrorClient := newRegistryMirrorClientFn
+	oldRuntimeClient := newContainerRuntimeClientFn
+	oldPolicyClient := newImagePolicyClientFn
 	oldConfirm := confirmHostSetFn
 	oldStdin := hostSetStdin
 	oldStdout := hostSetStdout
@@ -890,6 +1006,9 @@ func stubHostSetRuntime(t *testing.T) *hostSetTestState {
 	t.Cleanup(func() {
 		newHostStorageClientFn = oldClient
 		newISOPoolClientFn = oldPoolClient
+		newRegistryMirrorClientFn = oldMirrorClient
+		newContainerRuntimeClientFn = oldRuntimeClient
+		newImagePolicyClientFn = oldPolicyClient
 		confirmHostSetFn = oldConfirm
 		hostSetStdin = oldStdin
 		hostSetStdout = oldStdout
@@ -912,6 +1031,24 @@ func stubHostSetRuntime(t *testing.T) *hostSetTestState {
 		}
 		return state.poolClient
 	}
+	newRegistryMirrorClientFn = func(host string) registryMirrorClient {
+		if host != state.host {
+			t.Fatalf("host = %q, want %q", host, state.host)
+		}
+		return state.mirrorClient
+	}
+	newContainerRuntimeClientFn = func(host string) containerRuntimeClient {
+		if host != state.host {
+			t.Fatalf("host = %q, want %q", host, state.host)
+		}
+		return state.rtClient
+	}
+	newImagePolicyClientFn = func(host string) imagePolicyClient {
+		if host != state.host {
+			t.Fatalf("host = %q, want %q", host, state.host)
+		}
+		return state.policyClient
+	}
 	confirmHostSetFn = func(_ io.Reader, _ io.Writer, msg string) (bool, error) {
 		state.prompts = append(state.prompts, msg)
 		return state.confirm, nil
@@ -946,6 +1083,36 @@ type fakeISOPoolClient struct {
 	applyErr      error
 }
 
+type fakeRegistryMirrorClient struct {
+	requests []catchrpc.RegistryMirrorSetRequest
+	result   catchrpc.RegistryMirrorSetResult
+}
+
+func (c *fakeRegistryMirrorClient) RegistryMirrorSet(_ context.Context, req catchrpc.RegistryMirrorSetRequest) (catchrpc.RegistryMirrorSetResult, error) {
+	c.requests = append(c.requests, req)
+	return c.result, nil
+}
+
+type fakeContainerRuntimeClient struct {
+	requests []catchrpc.ContainerRuntimeSetRequest
+	result   catchrpc.ContainerRuntimeSetResult
+}
+
+func (c *fakeContainerRuntimeClient) ContainerRuntimeSet(_ context.Context, req catchrpc.ContainerRuntimeSetRequest) (catchrpc.ContainerRuntimeSetResult, error) {
+	c.requests = append(c.requests, req)
+	return c.result, nil
+}
+
+type fakeImagePolicyClient struct {
+	requests []catchrpc.ImagePolicySetRequest
+	result   catchrpc.ImagePolicySetResult
+}
+
+func (c *fakeImagePolicyClient) ImagePolicySet(_ context.Context, req catchrpc.ImagePolicySetRequest) (catchrpc.ImagePolicySetResult, error) {
+	c.requests = append(c.requests, req)
+	return c.result, nil
+}
+
 func (c *fakeISOPoolClient) ISOPoolPlan(_ context.Context, req catchrpc.ISOPoolPlanRequest) (catchrpc.ISOPoolPlan, error) {
 	c.planRequests = append(c.planRequests, req)
 	return c.plan, c.planErr
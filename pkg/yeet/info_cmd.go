@@ -364,6 +364,8 @@ func renderInfoPlain(w io.Writer, service, host string, hostInfoErr error, hostI
 		renderVMSection(server),
 		renderClientSection(client, server),
 		renderServerSectionForService(service, server),
+		renderStorageSection(server),
+		renderAutoUpdateSection(server),
 		renderNetworkSection(server),
 		renderRuntimeSection(service, server),
 		renderImagesSection(server),
@@ -610,7 +612,10 @@ func vmInfoRows(vm *catchrpc.ServiceVM) []infoRow {
 		{Label: "Console", Value: formatOptionalVMConsole(vm.Console)},
 		{Label: "SSH", Value: formatVMSSH(vm.SSH)},
 		{Label: "Provisioning", Value: vm.SetupState},
+		{Label: "User data", Value: vm.UserData},
+		{Label: "Power", Value: formatVMPower(vm.Power)},
 	}
+	candidates = append(candidates, vmMetricsInfoRows(vm.Metrics)...)
 	rows := make([]infoRow, 0, len(candidates))
 	for _, row := range candidates {
 		if row.Value != "" {
@@ -620,6 +625,54 @@ func vmInfoRows(vm *catchrpc.ServiceVM) []infoRow {
 	return rows
 }
 
+func vmMetricsInfoRows(metrics *catchrpc.ServiceVMMetrics) []infoRow {
+	if metrics == nil {
+		return nil
+	}
+	rows := []infoRow{
+		{Label: "Memory usage", Value: formatVMUsage(metrics.Memory)},
+		{Label: "Disk usage", Value: formatVMUsage(metrics.Disk)},
+	}
+	if metrics.CPU != nil {
+		rows = append([]infoRow{{Label: "CPU usage", Value: fmt.Sprintf("%.1f%% (%.0fs total)", metrics.CPU.Percent, metrics.CPU.UsageSeconds)}}, rows...)
+	}
+	if metrics.Network != nil {
+		rows = append(rows, infoRow{Label: "Network", Value: fmt.Sprintf("rx %s, tx %s", formatStorageBytes(metrics.Network.RxBytes), formatStorageBytes(metrics.Network.TxBytes))})
+	}
+	return rows
+}
+
+func formatVMPower(power *catchrpc.ServiceVMPower) string {
+	if power == nil {
+		return ""
+	}
+	parts := []string{"autostart off"}
+	if power.Autostart {
+		parts[0] = "autostart on"
+	}
+	if power.StartOrder != 0 {
+		parts = append(parts, fmt.Sprintf("start order %d", power.StartOrder))
+	}
+	switch power.ShutdownTimeout {
+	case "":
+	case "0s":
+		parts = append(parts, "no graceful shutdown")
+	default:
+		parts = append(parts, "shutdown timeout "+power.ShutdownTimeout)
+	}
+	if power.IdleStop != "" {
+		parts = append(parts, "idle stop "+power.IdleStop)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatVMUsage(usage *catchrpc.ServiceVMUsage) string {
+	if usage == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s used, %s available of %s (%s)", formatStorageBytes(usage.Used), formatStorageBytes(usage.Available), formatStorageBytes(usage.Total), usage.Source)
+}
+
 func formatVMMIsolation(value string) string {
 	switch strings.TrimSpace(value) {
 	case "jailer-pending-restart":
@@ -878,6 +931,66 @@ func renderServerSectionForService(service string, server catchrpc.ServiceInfoRe
 	return infoSection{Title: "Server (catch)", Rows: rows}
 }
 
+func renderStorageSection(server catchrpc.ServiceInfoResponse) infoSection {
+	storage := server.Info.Storage
+	if !server.Found || storage == nil {
+		return infoSection{}
+	}
+	quota := "none"
+	if storage.Quota > 0 {
+		quota = formatStorageBytes(storage.Quota)
+	}
+	rows := []infoRow{
+		{Label: "Backend", Value: storage.Backend},
+		{Label: "Used", Value: formatStorageBytes(storage.Used)},
+		{Label: "Available", Value: formatStorageBytes(storage.Available)},
+		{Label: "Quota", Value: quota},
+	}
+	if storage.Snapshots > 0 {
+		rows = append(rows, infoRow{Label: "Snapshots", Value: formatStorageBytes(storage.Snapshots)})
+	}
+	if storage.Volumes > 0 {
+		rows = append(rows, infoRow{Label: "Docker volumes", Value: formatStorageBytes(storage.Volumes)})
+	}
+	return infoSection{Title: "Storage", Rows: rows}
+}
+
+// infoAutoUpdateResults is how many recent auto-update results info shows.
+const infoAutoUpdateResults = 3
+
+func renderAutoUpdateSection(server catchrpc.ServiceInfoResponse) infoSection {
+	auto := server.Info.AutoUpdate
+	if !server.Found || auto == nil {
+		return infoSection{}
+	}
+	rows := []infoRow{
+		{Label: "Mode", Value: auto.Mode},
+		{Label: "Window", Value: auto.Window},
+	}
+	if auto.LastCheck != "" {
+		rows = append(rows, infoRow{Label: "Last check", Value: auto.LastCheck})
+	}
+	for i := len(auto.History) - 1; i >= 0 && i >= len(auto.History)-infoAutoUpdateResults; i-- {
+		result := auto.History[i]
+		value := result.Result
+		if result.Images != "" {
+			value += ": " + result.Images
+		}
+		if result.Error != "" {
+			value += " (" + result.Error + ")"
+		}
+		rows = append(rows, infoRow{Label: result.Time, Value: value})
+	}
+	return infoSection{Title: "Auto-update", Rows: rows}
+}
+
+func formatStorageBytes(bytes int64) string {
+	if bytes <= 0 {
+		return "0 B"
+	}
+	return formatBytesCompact(bytes)
+}
+
 func serviceSandboxInfoRows(service string, sandbox *catchrpc.ServiceSandbox) []infoRow {
 	if sandbox == nil || strings.TrimSpace(sandbox.State) == "" {
 		return nil
@@ -268,6 +268,45 @@ func TestFormatVMMIsolation(t *testing.T) {
 	}
 }
 
+func TestVMInfoRowsIncludeMetrics(t *testing.T) {
+	rows := vmInfoRows(&catchrpc.ServiceVM{
+		Runtime: "firecracker",
+		Metrics: &catchrpc.ServiceVMMetrics{
+			CPU:     &catchrpc.ServiceVMCPU{Percent: 37.5, UsageSeconds: 120},
+			Memory:  &catchrpc.ServiceVMUsage{Source: "agent", Total: 2 << 30, Used: 1 << 30, Available: 1 << 30},
+			Network: &catchrpc.ServiceVMNetTraffic{RxBytes: 2048, TxBytes: 1024},
+		},
+	})
+	got := map[string]string{}
+	for _, row := range rows {
+		got[row.Label] = row.Value
+	}
+	want := map[string]string{
+		"Runtime":      "firecracker",
+		"CPU usage":    "37.5% (120s total)",
+		"Memory usage": "1 GB used, 1 GB available of 2 GB (agent)",
+		"Network":      "rx 2 KB, tx 1 KB",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("rows = %#v, want %#v", got, want)
+	}
+}
+
+func TestFormatVMPower(t *testing.T) {
+	for _, tc := range []struct {
+		power *catchrpc.ServiceVMPower
+		want  string
+	}{
+		{power: &catchrpc.ServiceVMPower{Autostart: true, ShutdownTimeout: "30s"}, want: "autostart on, shutdown timeout 30s"},
+		{power: &catchrpc.ServiceVMPower{StartOrder: 10, ShutdownTimeout: "0s", IdleStop: "30m0s"}, want: "autostart off, start order 10, no graceful shutdown, idle stop 30m0s"},
+		{power: nil, want: ""},
+	} {
+		if got := formatVMPower(tc.power); got != tc.want {
+			t.Fatalf("formatVMPower(%#v) = %q, want %q", tc.power, got, tc.want)
+		}
+	}
+}
+
 func TestNormalizeInfoFormat(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -1308,6 +1347,77 @@ func TestInfoRenderServerSection(t *testing.T) {
 	})
 }
 
+func TestInfoRenderStorageSection(t *testing.T) {
+	if got := renderStorageSection(catchrpc.ServiceInfoResponse{Found: true}); len(got.Rows) != 0 {
+		t.Fatalf("rows without storage = %#v, want none", got.Rows)
+	}
+
+	got := renderStorageSection(catchrpc.ServiceInfoResponse{
+		Found: true,
+		Info: catchrpc.ServiceInfo{Storage: &catchrpc.ServiceStorage{
+			Backend:   "zfs",
+			Used:      3 << 30,
+			Available: 17 << 30,
+			Quota:     20 << 30,
+			Snapshots: 512 << 20,
+			Volumes:   1 << 30,
+		}},
+	})
+	if got.Title != "Storage" {
+		t.Fatalf("Title = %q, want Storage", got.Title)
+	}
+	assertInfoRows(t, got.Rows, []infoRow{
+		{Label: "Backend", Value: "zfs"},
+		{Label: "Used", Value: "3 GB"},
+		{Label: "Available", Value: "17 GB"},
+		{Label: "Quota", Value: "20 GB"},
+		{Label: "Snapshots", Value: "512 MB"},
+		{Label: "Docker volumes", Value: "1 GB"},
+	})
+
+	got = renderStorageSection(catchrpc.ServiceInfoResponse{
+		Found: true,
+		Info:  catchrpc.ServiceInfo{Storage: &catchrpc.ServiceStorage{Backend: "ext4", Available: 4 << 30}},
+	})
+	assertInfoRows(t, got.Rows, []infoRow{
+		{Label: "Backend", Value: "ext4"},
+		{Label: "Used", Value: "0 B"},
+		{Label: "Available", Value: "4 GB"},
+		{Label: "Quota", Value: "none"},
+	})
+}
+
+func TestInfoRenderAutoUpdateSection(t *testing.T) {
+	if got := renderAutoUpdateSection(catchrpc.ServiceInfoResponse{Found: true}); len(got.Rows) != 0 {
+		t.Fatalf("rows without policy = %#v, want none", got.Rows)
+	}
+	got := renderAutoUpdateSection(catchrpc.ServiceInfoResponse{
+		Found: true,
+		Info: catchrpc.ServiceInfo{AutoUpdate: &catchrpc.ServiceAutoUpdate{
+			Mode:      "apply",
+			Window:    "Sun 03:00",
+			LastCheck: "2026-10-18T03:00:00Z",
+			History: []catchrpc.AutoUpdateResult{
+				{Time: "2026-09-27T03:01:00Z", Result: "updated", Images: "web nginx:latest"},
+				{Time: "2026-10-04T03:01:00Z", Result: "updated", Images: "web nginx:latest"},
+				{Time: "2026-10-11T03:01:00Z", Result: "failed", Error: "inspect upstream image: timeout"},
+				{Time: "2026-10-18T03:02:00Z", Result: "rolled-back", Images: "web nginx:latest", Error: "web is exited"},
+			},
+		}},
+	})
+	if got.Title != "Auto-update" {
+		t.Fatalf("Title = %q, want Auto-update", got.Title)
+	}
+	assertInfoRows(t, got.Rows, []infoRow{
+		{Label: "Mode", Value: "apply"},
+		{Label: "Window", Value: "Sun 03:00"},
+		{Label: "Last check", Value: "2026-10-18T03:00:00Z"},
+		{Label: "2026-10-18T03:02:00Z", Value: "rolled-back: web nginx:latest (web is exited)"},
+		{Label: "2026-10-11T03:01:00Z", Value: "failed (inspect upstream image: timeout)"},
+		{Label: "2026-10-04T03:01:00Z", Value: "updated: web nginx:latest"},
+	})
+}
+
 func TestInfoRenderNetworkSection(t *testing.T) {
 	got := renderNetworkSection(catchrpc.ServiceInfoResponse{})
 	if got.Title != "Network" || got.Rows != nil {
@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/shayne/yargs"
+	"github.com/yeetrun/yeet/pkg/svc"
 	"github.com/yeetrun/yeet/pkg/tui"
 )
 
@@ -33,17 +34,18 @@ var archMap = map[string]string{
 }
 
 type initFlagsParsed struct {
-	FromGithub     bool   `flag:"from-github"`
-	Nightly        bool   `flag:"nightly"`
-	InstallDocker  bool   `flag:"install-docker"`
-	InstallVMTools bool   `flag:"install-vm-tools"`
-	TSAuthKey      string `flag:"ts-auth-key"`
-	TSClientSecret string `flag:"ts-client-secret"`
-	DataDir        string `flag:"data-dir"`
-	ServicesRoot   string `flag:"services-root"`
-	ZFS            bool   `flag:"zfs"`
-	Workspace      string `flag:"workspace"`
-	NoWorkspace    bool   `flag:"no-workspace"`
+	FromGithub       bool   `flag:"from-github"`
+	Nightly          bool   `flag:"nightly"`
+	InstallDocker    bool   `flag:"install-docker"`
+	InstallVMTools   bool   `flag:"install-vm-tools"`
+	TSAuthKey        string `flag:"ts-auth-key"`
+	TSClientSecret   string `flag:"ts-client-secret"`
+	DataDir          string `flag:"data-dir"`
+	ServicesRoot     string `flag:"services-root"`
+	ZFS              bool   `flag:"zfs"`
+	Workspace        string `flag:"workspace"`
+	NoWorkspace      bool   `flag:"no-workspace"`
+	ContainerRuntime string `flag:"container-runtime"`
 }
 
 type initOptions struct {
@@ -60,6 +62,9 @@ type initOptions struct {
 	tsClientSecret     string
 	releaseVersion     string
 	storage            initStorageOptions
+	// containerRuntime is "podman" when catch should run compose services
+	// on podman instead of docker; empty keeps the host's runtime.
+	containerRuntime string
 }
 
 var initCatchFn = initCatch
@@ -111,7 +116,11 @@ func initOptionsFromParsedFlags(flags initFlagsParsed) (initOptions, error) {
 	if err != nil {
 		return initOptions{}, err
 	}
-	return initOptions{
+	containerRuntime, err := initContainerRuntimeFromFlag(flags.ContainerRuntime)
+	if err != nil {
+		return initOptions{}, err
+	}
+	opts := initOptions{
 		fromGithub:     flags.FromGithub,
 		nightly:        flags.Nightly,
 		installDocker:  flags.InstallDocker,
@@ -121,13 +130,29 @@ func initOptionsFromParsedFlags(flags initFlagsParsed) (initOptions, error) {
 		tsAuthKey:      flags.TSAuthKey,
 		tsClientSecret: flags.TSClientSecret,
 		storage:        storage,
-	}, nil
+	}
+	opts.containerRuntime = containerRuntime
+	return opts, nil
+}
+
+func initContainerRuntimeFromFlag(value string) (string, error) {
+	if strings.TrimSpace(value) == "" {
+		return "", nil
+	}
+	rt, err := svc.ParseContainerRuntime(value)
+	if err != nil {
+		return "", fmt.Errorf("--container-runtime: %w", err)
+	}
+	return string(rt), nil
 }
 
 func validateInitArgs(pos []string, opts initOptions) error {
 	if opts.tsAuthKey != "" && opts.tsClientSecret != "" {
 		return fmt.Errorf("--ts-auth-key and --ts-client-secret cannot be used together")
 	}
+	if opts.installDocker && opts.containerRuntime == string(svc.ContainerRuntimePodman) {
+		return fmt.Errorf("--install-docker cannot be used with --container-runtime=podman")
+	}
 	if opts.tsClientSecret != "" && !strings.HasPrefix(opts.tsClientSecret, "tskey-client-") {
 		return fmt.Errorf("invalid --ts-client-secret (expected tskey-client-...)")
 	}
@@ -332,6 +357,7 @@ func initCatch(userAtRemote string, opts initOptions) (err error) {
 		return err
 	}
 	storage = withInitCatchRemoteBinary(storage, useSudo)
+	storage.containerRuntime = opts.containerRuntime
 	opts.storage = storage
 
 	if err := prepareInitCatchBinary(ui, userAtRemote, systemName, goarch, source, opts); err != nil {
@@ -456,6 +482,10 @@ func detectInitHost(ui *initUI, userAtRemote string) (string, string, error) {
 }
 
 func prepareInitDockerInstall(ui *initUI, userAtRemote string, opts initOptions) (bool, error) {
+	if opts.containerRuntime == string(svc.ContainerRuntimePodman) {
+		// catch install checks podman and its compose provider itself.
+		return false, nil
+	}
 	ui.StartStep("Check Docker")
 	if opts.installDocker {
 		ui.DoneStep("will install")
@@ -1265,6 +1295,9 @@ func remoteCatchInstallCommand(userAtRemote string, useSudo bool, installDocker
 func catchInstallEnvForOptions(userAtRemote string, installDocker bool, installVMTools bool, tsAuthKey string, tsClientSecret string, tsCatchTags []string, prepareVMLANBridge bool, skipVMLANBridge bool, storage initStorageOptions) []string {
 	installEnv := catchInstallEnv(userAtRemote)
 	installEnv = append(installEnv, catchStorageEnv(storage)...)
+	if storage.containerRuntime != "" {
+		installEnv = append(installEnv, "CATCH_CONTAINER_RUNTIME="+storage.containerRuntime)
+	}
 	if installDocker {
 		installEnv = append(installEnv, "CATCH_INSTALL_DOCKER=1")
 	}
@@ -35,6 +35,7 @@ type initStorageOptions struct {
 	remoteCatchBinary   string
 	existingCatch       bool
 	legacyCleanupSource string
+	containerRuntime    string
 }
 
 type initLegacyStorageCandidate struct {
@@ -105,6 +105,16 @@ func TestParseInitArgs(t *testing.T) {
 			args:    []string{"--ts-client-secret=not-a-client-secret", "root@example.com"},
 			wantErr: true,
 		},
+		{
+			name:    "rejects unknown container runtime",
+			args:    []string{"--container-runtime=containerd", "root@example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "rejects docker install with podman runtime",
+			args:    []string{"--install-docker", "--container-runtime=podman", "root@example.com"},
+			wantErr: true,
+		},
 		{
 			name:    "rejects too many args",
 			args:    []string{"one", "two"},
@@ -513,6 +523,32 @@ func TestPrepareInitDockerInstallRequiresFlagWhenMissingAndNonInteractive(t *tes
 	}
 }
 
+func TestInitContainerRuntimePodmanSkipsDockerAndReachesCatchInstall(t *testing.T) {
+	_, opts, err := parseInitArgs([]string{"--container-runtime=Podman", "root@example.com"})
+	if err != nil {
+		t.Fatalf("parseInitArgs failed: %v", err)
+	}
+	if opts.containerRuntime != "podman" {
+		t.Fatalf("containerRuntime = %q, want podman", opts.containerRuntime)
+	}
+
+	oldRemoteDocker := remoteDockerInstalledFn
+	t.Cleanup(func() { remoteDockerInstalledFn = oldRemoteDocker })
+	remoteDockerInstalledFn = func(string) (bool, error) {
+		t.Fatal("docker probed for a podman host")
+		return false, nil
+	}
+	ui := newInitUI(io.Discard, false, true, "catch", "root@example.com", catchServiceName)
+	if installDocker, err := prepareInitDockerInstall(ui, "root@example.com", opts); err != nil || installDocker {
+		t.Fatalf("prepareInitDockerInstall = %v, %v; want false, nil", installDocker, err)
+	}
+
+	env := catchInstallEnvForOptions("root@example.com", false, false, "", "", nil, false, false, initStorageOptions{containerRuntime: opts.containerRuntime})
+	if !slices.Contains(env, "CATCH_CONTAINER_RUNTIME=podman") {
+		t.Fatalf("install env = %#v, want CATCH_CONTAINER_RUNTIME=podman", env)
+	}
+}
+
 type scriptedInitPrompter struct {
 	confirmAnswers []bool
 	inputAnswers   []string
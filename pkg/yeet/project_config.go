@@ -10,6 +10,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
@@ -35,6 +36,10 @@ type ServiceEntry struct {
 	Payload          string   `toml:"payload,omitempty"`
 	PayloadKind      string   `toml:"payload_kind,omitempty"`
 	EnvFile          string   `toml:"env_file,omitempty"`
+	Compose          []string `toml:"compose,omitempty"`
+	Profiles         []string `toml:"profiles,omitempty"`
+	ComposeEnvFiles  []string `toml:"compose_env_files,omitempty"`
+	UserData         string   `toml:"user_data,omitempty"`
 	RunAs            string   `toml:"run_as,omitempty"`
 	ServiceRoot      string   `toml:"service_root,omitempty"`
 	ServiceRootZFS   bool     `toml:"service_root_zfs,omitempty"`
@@ -64,6 +69,10 @@ type serviceEntryTOML struct {
 	Payload          string   `toml:"payload,omitempty"`
 	PayloadKind      string   `toml:"payload_kind,omitempty"`
 	EnvFile          string   `toml:"env_file,omitempty"`
+	Compose          []string `toml:"compose,omitempty"`
+	Profiles         []string `toml:"profiles,omitempty"`
+	ComposeEnvFiles  []string `toml:"compose_env_files,omitempty"`
+	UserData         string   `toml:"user_data,omitempty"`
 	RunAs            string   `toml:"run_as,omitempty"`
 	ServiceRoot      string   `toml:"service_root,omitempty"`
 	ServiceRootZFS   bool     `toml:"service_root_zfs,omitempty"`
@@ -137,6 +146,32 @@ func cloneServiceEntrySandbox(entry *ServiceEntry) {
 	entry.SandboxRW = canonicalSandboxConfigValues(entry.SandboxRW)
 }
 
+// cloneServiceEntryCompose copies the compose project lists, keeping nil for
+// absent ones so they stay out of yeet.toml.
+func cloneServiceEntryCompose(entry *ServiceEntry) {
+	entry.Compose = slices.Clone(entry.Compose)
+	entry.Profiles = slices.Clone(entry.Profiles)
+	entry.ComposeEnvFiles = slices.Clone(entry.ComposeEnvFiles)
+}
+
+// normalizeServiceEntryCompose lets compose stand in for payload: its first
+// file is the payload and the rest are overrides.
+func normalizeServiceEntryCompose(entry *ServiceEntry) error {
+	if len(entry.Compose) == 0 {
+		return nil
+	}
+	base := strings.TrimSpace(entry.Compose[0])
+	payload := strings.TrimSpace(entry.Payload)
+	if payload == "" {
+		entry.Payload = base
+		return nil
+	}
+	if filepath.Clean(payload) != filepath.Clean(base) {
+		return fmt.Errorf("service %s: compose must start with the payload %q, not %q", entry.Name, payload, base)
+	}
+	return nil
+}
+
 var createProjectConfigFileFn = func(path string) (io.WriteCloser, error) {
 	return os.Create(path)
 }
@@ -480,6 +515,9 @@ func validateLoadedProjectConfig(cfg *ProjectConfig) error {
 	}
 	for i := range cfg.Services {
 		cloneServiceEntrySandbox(&cfg.Services[i])
+		if err := normalizeServiceEntryCompose(&cfg.Services[i]); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -568,6 +606,10 @@ func serviceEntryForTOML(entry ServiceEntry) serviceEntryTOML {
 		Payload:          entry.Payload,
 		PayloadKind:      entry.PayloadKind,
 		EnvFile:          entry.EnvFile,
+		Compose:          cloneStringSlice(entry.Compose),
+		Profiles:         cloneStringSlice(entry.Profiles),
+		ComposeEnvFiles:  cloneStringSlice(entry.ComposeEnvFiles),
+		UserData:         entry.UserData,
 		RunAs:            entry.RunAs,
 		ServiceRoot:      entry.ServiceRoot,
 		ServiceRootZFS:   entry.ServiceRootZFS,
@@ -790,6 +832,7 @@ func (c *ProjectConfig) ServiceEntry(service, host string) (ServiceEntry, bool)
 			entry.SnapshotEvents = cloneStringSlice(entry.SnapshotEvents)
 			entry.Ports = cloneStringSlice(entry.Ports)
 			cloneServiceEntrySandbox(&entry)
+			cloneServiceEntryCompose(&entry)
 			return entry, true
 		}
 	}
@@ -802,6 +845,7 @@ func (c *ProjectConfig) SetServiceEntry(entry ServiceEntry) {
 	entry.SnapshotEvents = cloneStringSlice(entry.SnapshotEvents)
 	entry.Ports = cloneStringSlice(entry.Ports)
 	cloneServiceEntrySandbox(&entry)
+	cloneServiceEntryCompose(&entry)
 	for i := range c.Services {
 		if c.Services[i].Name == entry.Name && c.Services[i].Host == entry.Host {
 			c.Services[i].Type = entry.Type
@@ -809,9 +853,17 @@ func (c *ProjectConfig) SetServiceEntry(entry ServiceEntry) {
 			c.Services[i].PayloadKind = entry.PayloadKind
 			c.Services[i].Schedule = entry.Schedule
 			c.Services[i].Args = cloneStringSlice(entry.Args)
+			if len(entry.Compose) != 0 || len(entry.Profiles) != 0 || len(entry.ComposeEnvFiles) != 0 {
+				c.Services[i].Compose = entry.Compose
+				c.Services[i].Profiles = entry.Profiles
+				c.Services[i].ComposeEnvFiles = entry.ComposeEnvFiles
+			}
 			if entry.EnvFile != "" {
 				c.Services[i].EnvFile = entry.EnvFile
 			}
+			if entry.UserData != "" {
+				c.Services[i].UserData = entry.UserData
+			}
 			if entry.RunAs != "" {
 				c.Services[i].RunAs = entry.RunAs
 			}
@@ -844,6 +896,7 @@ func (c *ProjectConfig) ReplaceServiceEntry(entry ServiceEntry) {
 	entry.SnapshotEvents = cloneStringSlice(entry.SnapshotEvents)
 	entry.Ports = cloneStringSlice(entry.Ports)
 	cloneServiceEntrySandbox(&entry)
+	cloneServiceEntryCompose(&entry)
 	for i := range c.Services {
 		if c.Services[i].Name == entry.Name && c.Services[i].Host == entry.Host {
 			c.Services[i] = entry
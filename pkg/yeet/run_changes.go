@@ -631,7 +631,7 @@ func runWithChangesToWithContextRunnerResult(ctx context.Context, stdout io.Writ
 		Required:  entry.SnapshotRequired,
 		Events:    entry.SnapshotEvents,
 	})
-	comparisonArgs := removeRunSandboxControlFlags(runArgs)
+	comparisonArgs := removeRunUserDataFlag(removeRunSandboxControlFlags(runArgs))
 	storedComparisonArgs := normalizeRunArgs(storedArgs)
 	summary, err := detectRunChangesWithOptions(ctx, payload, comparisonArgs, envFile, storedComparisonArgs, alwaysDeployPayload)
 	if err != nil {
@@ -1233,11 +1233,12 @@ func detectRunChanges(payload string, runArgs []string, envFile string, storedAr
 
 func detectRunChangesWithOptions(ctx context.Context, payload string, runArgs []string, envFile string, storedArgs []string, alwaysDeployPayload bool) (runChangeSummary, error) {
 	summary := runChangeSummary{
-		argsChanged: runArgsChanged(normalizeRunArgs(runArgs), storedArgs),
+		argsChanged: runArgsChanged(normalizeRunArgs(removeRunComposeProjectFlags(runArgs)), storedArgs),
 	}
 	needs := classifyRunChangeNeeds(payload, envFile)
 	if alwaysDeployPayload {
 		needs.payloadHash = false
+		needs.images = false
 		needs.alwaysDeployPayload = true
 	}
 	remoteHashes, supported, err := fetchHashesForRunChanges(ctx, needs)
@@ -1247,26 +1248,56 @@ func detectRunChangesWithOptions(ctx context.Context, payload string, runArgs []
 	if !supported {
 		return summaryForUnsupportedHashes(summary, payload, needs), nil
 	}
-	return detectHashBackedRunChanges(summary, payload, envFile, remoteHashes, needs)
+	summary, err = detectHashBackedRunChanges(summary, payload, envFile, remoteHashes, needs)
+	if err != nil || !needs.payloadHash || summary.payloadChanged {
+		return summary, err
+	}
+	return detectComposeProjectChange(summary, runArgs, remoteHashes)
+}
+
+// detectComposeProjectChange marks the payload changed when the compose
+// override files, env files, or profiles differ from the deployed generation
+// even though the base compose file is the same.
+func detectComposeProjectChange(summary runChangeSummary, runArgs []string, remoteHashes catchrpc.ArtifactHashesResponse) (runChangeSummary, error) {
+	localHash, err := localComposeProjectHash(runArgs)
+	if err != nil {
+		return summary, err
+	}
+	var remoteHash string
+	if remoteHashes.ComposeProject != nil {
+		remoteHash = remoteHashes.ComposeProject.SHA256
+	}
+	if localHash != remoteHash {
+		summary.payloadChanged = true
+		summary.payloadLabel = "compose project"
+	}
+	return summary, nil
 }
 
 type runChangeNeeds struct {
-	payloadHash         bool
-	envHash             bool
+	payloadHash bool
+	envHash     bool
+	// images compares an image ref or Dockerfile payload against the images
+	// of the running containers instead of a stored artifact.
+	images              bool
 	alwaysDeployPayload bool
 }
 
 func classifyRunChangeNeeds(payload string, envFile string) runChangeNeeds {
-	alwaysDeploy := shouldAlwaysDeployPayload(payload)
-	return runChangeNeeds{
-		payloadHash:         !alwaysDeploy,
-		envHash:             strings.TrimSpace(envFile) != "",
-		alwaysDeployPayload: alwaysDeploy,
+	needs := runChangeNeeds{envHash: strings.TrimSpace(envFile) != ""}
+	switch {
+	case shouldAlwaysDeployPayload(payload):
+		needs.alwaysDeployPayload = true
+	case imageChangePayload(payload):
+		needs.images = true
+	default:
+		needs.payloadHash = true
 	}
+	return needs
 }
 
 func (n runChangeNeeds) remoteHashes() bool {
-	return n.payloadHash || n.envHash
+	return n.payloadHash || n.envHash || n.images
 }
 
 func runArgsChanged(currentArgs []string, storedArgs []string) bool {
@@ -1283,11 +1314,14 @@ func fetchHashesForRunChanges(ctx context.Context, needs runChangeNeeds) (catchr
 	if !needs.remoteHashes() {
 		return catchrpc.ArtifactHashesResponse{}, true, nil
 	}
+	if needs.images {
+		return fetchRemoteArtifactImagesFn(ctx, getService())
+	}
 	return fetchRemoteArtifactHashesFn(ctx, getService())
 }
 
 func summaryForUnsupportedHashes(summary runChangeSummary, payload string, needs runChangeNeeds) runChangeSummary {
-	summary.payloadChanged = needs.payloadHash || needs.alwaysDeployPayload
+	summary.payloadChanged = needs.payloadHash || needs.images || needs.alwaysDeployPayload
 	summary.envChanged = needs.envHash
 	if needs.payloadHash {
 		summary.payloadLabel = payloadLabelFromLocal(payload, "")
@@ -1298,6 +1332,13 @@ func summaryForUnsupportedHashes(summary runChangeSummary, payload string, needs
 func detectHashBackedRunChanges(summary runChangeSummary, payload string, envFile string, remoteHashes catchrpc.ArtifactHashesResponse, needs runChangeNeeds) (runChangeSummary, error) {
 	if needs.alwaysDeployPayload {
 		summary.payloadChanged = true
+	} else if needs.images {
+		changed, label, err := detectImagePayloadChange(payload, remoteHashes)
+		if err != nil {
+			return summary, err
+		}
+		summary.payloadChanged = changed
+		summary.payloadLabel = label
 	} else if needs.payloadHash {
 		changed, label, err := detectPayloadHashChange(payload, remoteHashes)
 		if err != nil {
@@ -1325,6 +1366,56 @@ func detectPayloadHashChange(payload string, remoteHashes catchrpc.ArtifactHashe
 	return hashChanged(localHash, remoteHash), payloadLabelFromLocal(payload, remoteKind), nil
 }
 
+// detectImagePayloadChange reports whether deploying an image ref or a
+// Dockerfile would change the running service. An image ref is unchanged when
+// catch already runs the compose file yeet would send and every container is
+// on the digest the ref resolves to; a Dockerfile build is unchanged when the
+// running images were built from the same inputs.
+func detectImagePayloadChange(payload string, remoteHashes catchrpc.ArtifactHashesResponse) (bool, string, error) {
+	images := remoteHashes.Images
+	if filepath.Base(payload) == "Dockerfile" {
+		localHash, err := localDockerBuildHash(payload)
+		if err != nil {
+			return false, "", err
+		}
+		return !runningImagesMatch(images, func(image catchrpc.ArtifactImage) bool {
+			return image.BuildHash == localHash
+		}), "Dockerfile build", nil
+	}
+	sum := sha256.Sum256([]byte(imageComposeContent(getService(), payload)))
+	remoteHash, _ := remotePayloadHash(remoteHashes)
+	if hashChanged(hex.EncodeToString(sum[:]), remoteHash) {
+		return true, "image", nil
+	}
+	return !runningImagesMatch(images, func(image catchrpc.ArtifactImage) bool {
+		return image.RunningDigest != "" && image.RunningDigest == image.LatestDigest
+	}), "image", nil
+}
+
+func runningImagesMatch(images []catchrpc.ArtifactImage, match func(catchrpc.ArtifactImage) bool) bool {
+	if len(images) == 0 {
+		return false
+	}
+	for _, image := range images {
+		if !match(image) {
+			return false
+		}
+	}
+	return true
+}
+
+func localDockerBuildHash(dockerfilePath string) (string, error) {
+	goos, goarch, err := remoteCatchOSAndArchFn()
+	if err != nil {
+		return "", err
+	}
+	build, err := dockerBuildPlan(dockerfilePath, "", goos, goarch)
+	if err != nil {
+		return "", err
+	}
+	return dockerBuildHash(dockerfilePath, build.Platform)
+}
+
 func detectEnvHashChange(envFile string, remoteHashes catchrpc.ArtifactHashesResponse) (bool, error) {
 	localHash, err := hashFileSHA256(envFile)
 	if err != nil {
@@ -1355,18 +1446,21 @@ func shouldAlwaysDeployPayload(payload string) bool {
 	if isVMPayload(payload) {
 		return true
 	}
-	if looksLikeImageRef(payload) || looksLikeRunDraftLocalImageName(payload) {
-		if st, err := os.Stat(payload); err == nil && !st.IsDir() {
-			return false
-		}
-		// TODO: add change detection for image refs.
-		return true
+	if filepath.Base(payload) == "Dockerfile" || payloadNamesExistingFile(payload) {
+		return false
 	}
+	// Local images are pushed as they are, so there is nothing to compare
+	// them against.
+	return !looksLikeImageRef(payload) && looksLikeRunDraftLocalImageName(payload)
+}
+
+// imageChangePayload reports whether payload is an image ref or a Dockerfile,
+// whose changes are detected from the running containers.
+func imageChangePayload(payload string) bool {
 	if filepath.Base(payload) == "Dockerfile" {
-		// TODO: decide how to hash Dockerfile builds for change detection.
 		return true
 	}
-	return false
+	return looksLikeImageRef(payload) && !payloadNamesExistingFile(payload)
 }
 
 var payloadLabelsByFileType = map[ftdetect.FileType]string{
@@ -1449,8 +1543,16 @@ func hashReadCloserSHA256(r io.ReadCloser) (sum string, err error) {
 }
 
 func fetchRemoteArtifactHashes(ctx context.Context, service string) (catchrpc.ArtifactHashesResponse, bool, error) {
+	return callArtifactHashes(ctx, catchrpc.ArtifactHashesRequest{Service: service})
+}
+
+func fetchRemoteArtifactImages(ctx context.Context, service string) (catchrpc.ArtifactHashesResponse, bool, error) {
+	return callArtifactHashes(ctx, catchrpc.ArtifactHashesRequest{Service: service, Images: true})
+}
+
+func callArtifactHashes(ctx context.Context, req catchrpc.ArtifactHashesRequest) (catchrpc.ArtifactHashesResponse, bool, error) {
 	var resp catchrpc.ArtifactHashesResponse
-	if err := newRPCClient(Host()).Call(ctx, "catch.ArtifactHashes", catchrpc.ArtifactHashesRequest{Service: service}, &resp); err != nil {
+	if err := newRPCClient(Host()).Call(ctx, "catch.ArtifactHashes", req, &resp); err != nil {
 		if isRPCMethodNotFound(err) {
 			return resp, false, nil
 		}
@@ -1467,3 +1569,4 @@ func isRPCMethodNotFound(err error) bool {
 }
 
 var fetchRemoteArtifactHashesFn = fetchRemoteArtifactHashes
+var fetchRemoteArtifactImagesFn = fetchRemoteArtifactImages
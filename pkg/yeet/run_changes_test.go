@@ -7,6 +7,8 @@ package yeet
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"io"
 	"os"
@@ -122,6 +124,7 @@ func TestRunArgsWithServiceRootOptions(t *testing.T) {
 }
 
 func TestDetectRunChangesServiceRootOnly(t *testing.T) {
+	stubRemoteArtifactImages(t, catchrpc.ArtifactHashesResponse{})
 	oldHashes := fetchRemoteArtifactHashesFn
 	defer func() { fetchRemoteArtifactHashesFn = oldHashes }()
 
@@ -138,6 +141,7 @@ func TestDetectRunChangesServiceRootOnly(t *testing.T) {
 }
 
 func TestDetectRunChangesServiceRootZFSOnly(t *testing.T) {
+	stubRemoteArtifactImages(t, catchrpc.ArtifactHashesResponse{})
 	oldHashes := fetchRemoteArtifactHashesFn
 	defer func() { fetchRemoteArtifactHashesFn = oldHashes }()
 
@@ -829,6 +833,7 @@ func TestSuccessfulRunConfigSavePublishesExactIndependentCandidate(t *testing.T)
 }
 
 func TestExistingNativeRunTransitionRefreshesNonNativeSandboxBeforeConfigSave(t *testing.T) {
+	stubRemoteArtifactImages(t, catchrpc.ArtifactHashesResponse{})
 	oldInfo := fetchRunChangeServiceInfoFn
 	oldHashes := fetchRemoteArtifactHashesFn
 	oldService := serviceOverride
@@ -1009,6 +1014,7 @@ func TestExistingNativeRunKeepsSingleProtectedInfoFetch(t *testing.T) {
 }
 
 func TestRunSandboxResultStaysMatchedAcrossInterleavedSameServiceRuns(t *testing.T) {
+	stubRemoteArtifactImages(t, catchrpc.ArtifactHashesResponse{})
 	oldInfo, oldHashes, oldService := fetchRunChangeServiceInfoFn, fetchRemoteArtifactHashesFn, serviceOverride
 	t.Cleanup(func() {
 		fetchRunChangeServiceInfoFn, fetchRemoteArtifactHashesFn, serviceOverride = oldInfo, oldHashes, oldService
@@ -1074,6 +1080,7 @@ func TestRunSandboxResultStaysMatchedAcrossInterleavedSameServiceRuns(t *testing
 }
 
 func TestRunSandboxResultDoesNotSurviveFailedRunOrRetry(t *testing.T) {
+	stubRemoteArtifactImages(t, catchrpc.ArtifactHashesResponse{})
 	oldInfo, oldHashes, oldService := fetchRunChangeServiceInfoFn, fetchRemoteArtifactHashesFn, serviceOverride
 	t.Cleanup(func() {
 		fetchRunChangeServiceInfoFn, fetchRemoteArtifactHashesFn, serviceOverride = oldInfo, oldHashes, oldService
@@ -1289,6 +1296,7 @@ func stubExistingNativeSandboxInfo(t *testing.T) {
 }
 
 func TestFreshRunPostSuccessSandboxFetchUsesNativePayloadSemantics(t *testing.T) {
+	stubRemoteArtifactImages(t, catchrpc.ArtifactHashesResponse{})
 	oldInfo := fetchRunChangeServiceInfoFn
 	oldHashes := fetchRemoteArtifactHashesFn
 	t.Cleanup(func() {
@@ -1716,13 +1724,13 @@ func TestShouldAlwaysDeployPayload(t *testing.T) {
 		payload string
 		want    bool
 	}{
-		{payload: "ghcr.io/example/app:latest", want: true},
+		{payload: "ghcr.io/example/app:latest"},
 		{payload: "alpine", want: true},
 		{payload: "myapp", want: true},
 		{payload: "repo/myapp", want: true},
 		{payload: "registry.local/team/app", want: true},
 		{payload: "registry.local:5000/team/app", want: true},
-		{payload: "/tmp/Dockerfile", want: true},
+		{payload: "/tmp/Dockerfile"},
 		{payload: "/tmp/run.sh"},
 		{payload: "./compose.yml"},
 		{payload: extensionlessFile},
@@ -1737,6 +1745,111 @@ func TestShouldAlwaysDeployPayload(t *testing.T) {
 	}
 }
 
+func TestClassifyRunChangeNeedsImagePayloads(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    runChangeNeeds
+	}{
+		{payload: "ghcr.io/example/app:latest", want: runChangeNeeds{images: true}},
+		{payload: "nginx@sha256:abc", want: runChangeNeeds{images: true}},
+		{payload: "/tmp/app/Dockerfile", want: runChangeNeeds{images: true}},
+		{payload: "myapp", want: runChangeNeeds{alwaysDeployPayload: true}},
+		{payload: "./compose.yml", want: runChangeNeeds{payloadHash: true}},
+	}
+	for _, tt := range tests {
+		if got := classifyRunChangeNeeds(tt.payload, ""); got != tt.want {
+			t.Errorf("classifyRunChangeNeeds(%q) = %#v, want %#v", tt.payload, got, tt.want)
+		}
+	}
+}
+
+func TestDetectRunChangesImageRef(t *testing.T) {
+	oldService := serviceOverride
+	t.Cleanup(func() { serviceOverride = oldService })
+	serviceOverride = "web"
+	const image = "ghcr.io/example/app:1"
+	sum := sha256.Sum256([]byte(imageComposeContent("web", image)))
+	composeHash := &catchrpc.ArtifactHash{Kind: "docker compose", SHA256: hex.EncodeToString(sum[:])}
+
+	tests := []struct {
+		name string
+		resp catchrpc.ArtifactHashesResponse
+		want bool
+	}{
+		{
+			name: "current",
+			resp: catchrpc.ArtifactHashesResponse{Found: true, Payload: composeHash, Images: []catchrpc.ArtifactImage{{Image: image, RunningDigest: "sha256:a", LatestDigest: "sha256:a"}}},
+		},
+		{
+			name: "tag moved",
+			resp: catchrpc.ArtifactHashesResponse{Found: true, Payload: composeHash, Images: []catchrpc.ArtifactImage{{Image: image, RunningDigest: "sha256:a", LatestDigest: "sha256:b"}}},
+			want: true,
+		},
+		{
+			name: "unresolved",
+			resp: catchrpc.ArtifactHashesResponse{Found: true, Payload: composeHash, Images: []catchrpc.ArtifactImage{{Image: image}}},
+			want: true,
+		},
+		{
+			name: "not running",
+			resp: catchrpc.ArtifactHashesResponse{Found: true, Payload: composeHash},
+			want: true,
+		},
+		{
+			name: "other ref",
+			resp: catchrpc.ArtifactHashesResponse{Found: true, Payload: &catchrpc.ArtifactHash{SHA256: "other"}, Images: []catchrpc.ArtifactImage{{RunningDigest: "sha256:a", LatestDigest: "sha256:a"}}},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubRemoteArtifactImages(t, tt.resp)
+			summary, err := detectRunChanges(image, nil, "", nil)
+			if err != nil {
+				t.Fatalf("detectRunChanges: %v", err)
+			}
+			if summary.payloadChanged != tt.want || summary.payloadLabel != "image" {
+				t.Fatalf("summary = %#v, want payloadChanged=%v with image label", summary, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectRunChangesDockerfile(t *testing.T) {
+	oldArch := remoteCatchOSAndArchFn
+	t.Cleanup(func() { remoteCatchOSAndArchFn = oldArch })
+	remoteCatchOSAndArchFn = func() (string, string, error) { return "linux", "arm64", nil }
+	dockerfile := filepath.Join(t.TempDir(), "Dockerfile")
+	if err := os.WriteFile(dockerfile, []byte("FROM scratch\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := dockerBuildHash(dockerfile, "linux/arm64")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stubRemoteArtifactImages(t, catchrpc.ArtifactHashesResponse{Found: true, Images: []catchrpc.ArtifactImage{{BuildHash: hash}}})
+	summary, err := detectRunChanges(dockerfile, nil, "", nil)
+	if err != nil || summary.hasChanges() {
+		t.Fatalf("unchanged build summary = %#v, %v; want no changes", summary, err)
+	}
+
+	stubRemoteArtifactImages(t, catchrpc.ArtifactHashesResponse{Found: true, Images: []catchrpc.ArtifactImage{{BuildHash: "stale"}}})
+	summary, err = detectRunChanges(dockerfile, nil, "", nil)
+	if err != nil || !summary.payloadChanged || summary.payloadLabel != "Dockerfile build" {
+		t.Fatalf("changed build summary = %#v, %v; want Dockerfile build change", summary, err)
+	}
+}
+
+func stubRemoteArtifactImages(t *testing.T, resp catchrpc.ArtifactHashesResponse) {
+	t.Helper()
+	old := fetchRemoteArtifactImagesFn
+	t.Cleanup(func() { fetchRemoteArtifactImagesFn = old })
+	fetchRemoteArtifactImagesFn = func(context.Context, string) (catchrpc.ArtifactHashesResponse, bool, error) {
+		return resp, true, nil
+	}
+}
+
 func TestIsRPCMethodNotFound(t *testing.T) {
 	if isRPCMethodNotFound(nil) {
 		t.Fatal("isRPCMethodNotFound nil = true, want false")
@@ -0,0 +1,169 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yeet
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/yeetrun/yeet/pkg/cli"
+	"github.com/yeetrun/yeet/pkg/svc"
+)
+
+const (
+	runComposeFileFlag    = "--compose-file"
+	runComposeEnvFileFlag = "--compose-env-file"
+	runProfileFlag        = "--profile"
+	// runComposeProjectMaxBytes matches the per-file limit catch enforces.
+	runComposeProjectMaxBytes     = 256 << 10
+	runComposeProjectInlinePrefix = "base64:"
+)
+
+var runComposeProjectFlags = map[string]bool{
+	runComposeFileFlag:    true,
+	runComposeEnvFileFlag: true,
+	runProfileFlag:        true,
+}
+
+// composeOverrides returns the override files from a yeet.toml compose list;
+// the first entry is the payload itself.
+func (e ServiceEntry) composeOverrides() []string {
+	if len(e.Compose) < 2 {
+		return nil
+	}
+	return e.Compose[1:]
+}
+
+// runArgsWithConfiguredComposeProject adds the yeet.toml compose overrides,
+// profiles, and compose_env_files when the command line names none of them.
+// Any one of the flags on the command line replaces the whole configured set.
+func runArgsWithConfiguredComposeProject(args []string, entry ServiceEntry, hasEntry bool, cfgLoc *projectConfigLocation) []string {
+	if !hasEntry || cfgLoc == nil || runArgsHaveComposeProject(args) {
+		return args
+	}
+	for _, file := range entry.composeOverrides() {
+		args = appendRunControlFlagBeforeBoundary(args, runComposeFileFlag+"="+resolveEnvFilePath(cfgLoc.Dir, file))
+	}
+	for _, file := range entry.ComposeEnvFiles {
+		args = appendRunControlFlagBeforeBoundary(args, runComposeEnvFileFlag+"="+resolveEnvFilePath(cfgLoc.Dir, file))
+	}
+	for _, profile := range entry.Profiles {
+		args = appendRunControlFlagBeforeBoundary(args, runProfileFlag+"="+strings.TrimSpace(profile))
+	}
+	return args
+}
+
+func runArgsHaveComposeProject(args []string) bool {
+	for flag := range runComposeProjectFlags {
+		if runArgsHaveFlag(args, flag) {
+			return true
+		}
+	}
+	return false
+}
+
+// runConfigComposeProject splits the compose project flags out of run args so
+// yeet.toml keeps them as compose, compose_env_files, and profiles, with
+// paths relative to the config directory.
+func runConfigComposeProject(configDir, payloadRel string, args []string) (compose, envFiles, profiles []string, _ []string, _ error) {
+	flags, _, err := cli.ParseRun(args)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if !flags.HasComposeProject() {
+		return nil, nil, nil, args, nil
+	}
+	if len(flags.ComposeFiles) != 0 {
+		compose = append(compose, payloadRel)
+		for _, file := range flags.ComposeFiles {
+			compose = append(compose, relativeEnvFilePath(configDir, file))
+		}
+	}
+	for _, file := range flags.ComposeEnvFiles {
+		envFiles = append(envFiles, relativeEnvFilePath(configDir, file))
+	}
+	profiles = append(profiles, flags.Profiles...)
+	return compose, envFiles, profiles, removeRunComposeProjectFlags(args), nil
+}
+
+// encodeRunComposeProjectArgs replaces the --compose-file and
+// --compose-env-file paths with the file contents so catch receives them
+// inline.
+func encodeRunComposeProjectArgs(args []string) ([]string, error) {
+	flags, _, err := cli.ParseRun(args)
+	if err != nil || !flags.HasComposeProject() {
+		return args, err
+	}
+	out := removeRunComposeProjectFlags(args)
+	for _, spec := range []struct {
+		flag  string
+		files []string
+	}{
+		{runComposeFileFlag, flags.ComposeFiles},
+		{runComposeEnvFileFlag, flags.ComposeEnvFiles},
+	} {
+		for _, file := range spec.files {
+			raw, err := readRunComposeProjectFile(spec.flag, file)
+			if err != nil {
+				return nil, err
+			}
+			out = appendRunControlFlagBeforeBoundary(out, spec.flag+"="+runComposeProjectInlinePrefix+base64.StdEncoding.EncodeToString(raw))
+		}
+	}
+	for _, profile := range flags.Profiles {
+		out = appendRunControlFlagBeforeBoundary(out, runProfileFlag+"="+profile)
+	}
+	return out, nil
+}
+
+func readRunComposeProjectFile(flag, path string) ([]byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", flag, err)
+	}
+	if len(raw) > runComposeProjectMaxBytes {
+		return nil, fmt.Errorf("%s file %s is %d bytes; the limit is %d", flag, path, len(raw), runComposeProjectMaxBytes)
+	}
+	return raw, nil
+}
+
+// localComposeProjectHash hashes the compose project named by run args the
+// same way catch hashes a deployed generation.
+func localComposeProjectHash(args []string) (string, error) {
+	flags, _, err := cli.ParseRun(args)
+	if err != nil {
+		return "", err
+	}
+	overrides := make([][]byte, 0, len(flags.ComposeFiles))
+	for _, file := range flags.ComposeFiles {
+		raw, err := readRunComposeProjectFile(runComposeFileFlag, file)
+		if err != nil {
+			return "", err
+		}
+		overrides = append(overrides, raw)
+	}
+	envFiles := make([][]byte, 0, len(flags.ComposeEnvFiles))
+	for _, file := range flags.ComposeEnvFiles {
+		raw, err := readRunComposeProjectFile(runComposeEnvFileFlag, file)
+		if err != nil {
+			return "", err
+		}
+		envFiles = append(envFiles, raw)
+	}
+	return svc.ComposeProjectHash(overrides, envFiles, flags.Profiles), nil
+}
+
+// removeRunComposeProjectFlags drops --compose-file, --compose-env-file, and
+// --profile. Their contents are compared by hash instead of by path.
+func removeRunComposeProjectFlags(args []string) []string {
+	flagArgs, payloadArgs := splitRunArgsForParsing(args)
+	flagArgs = removeRunFlags(flagArgs, runComposeProjectFlags)
+	if len(payloadArgs) == 0 {
+		return flagArgs
+	}
+	return append(append(flagArgs, "--"), payloadArgs...)
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yeet
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+)
+
+func writeComposeProjectTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestRunComposeProjectPersistsAsConfigFields(t *testing.T) {
+	dir := t.TempDir()
+	args := []string{
+		"--net=svc",
+		"--compose-file=" + filepath.Join(dir, "prod.override.yml"),
+		"--compose-env-file=" + filepath.Join(dir, "versions.env"),
+		"--profile=worker",
+	}
+	compose, envFiles, profiles, rest, err := runConfigComposeProject(dir, "compose.yml", args)
+	if err != nil {
+		t.Fatalf("runConfigComposeProject: %v", err)
+	}
+	if want := []string{"compose.yml", "prod.override.yml"}; !reflect.DeepEqual(compose, want) {
+		t.Fatalf("compose = %#v, want %#v", compose, want)
+	}
+	if !reflect.DeepEqual(envFiles, []string{"versions.env"}) || !reflect.DeepEqual(profiles, []string{"worker"}) {
+		t.Fatalf("env files, profiles = %#v, %#v", envFiles, profiles)
+	}
+	if !reflect.DeepEqual(rest, []string{"--net=svc"}) {
+		t.Fatalf("remaining args = %#v, want --net=svc", rest)
+	}
+
+	loc := &projectConfigLocation{Dir: dir}
+	entry := ServiceEntry{Name: "web", Host: "catch", Payload: "compose.yml", Compose: compose, ComposeEnvFiles: envFiles, Profiles: profiles}
+	got := runArgsWithConfiguredComposeProject([]string{"--net=svc"}, entry, true, loc)
+	if want := append([]string{"--net=svc"}, args[1:]...); !reflect.DeepEqual(got, want) {
+		t.Fatalf("configured args = %#v, want %#v", got, want)
+	}
+	explicit := []string{"--profile=debug"}
+	if got := runArgsWithConfiguredComposeProject(explicit, entry, true, loc); !reflect.DeepEqual(got, explicit) {
+		t.Fatalf("explicit args = %#v, want command line project to win", got)
+	}
+}
+
+func TestEncodeRunComposeProjectArgsInlinesFiles(t *testing.T) {
+	dir := t.TempDir()
+	override := filepath.Join(dir, "prod.override.yml")
+	envFile := filepath.Join(dir, "versions.env")
+	writeComposeProjectTestFile(t, override, "services: {}\n")
+	writeComposeProjectTestFile(t, envFile, "TAG=1\n")
+
+	got, err := encodeRunComposeProjectArgs([]string{"--compose-env-file", envFile, "--profile=worker", "--compose-file=" + override, "--", "up"})
+	if err != nil {
+		t.Fatalf("encodeRunComposeProjectArgs: %v", err)
+	}
+	want := []string{
+		"--compose-file=base64:" + base64.StdEncoding.EncodeToString([]byte("services: {}\n")),
+		"--compose-env-file=base64:" + base64.StdEncoding.EncodeToString([]byte("TAG=1\n")),
+		"--profile=worker",
+		"--",
+		"up",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("encoded args = %#v, want %#v", got, want)
+	}
+
+	writeComposeProjectTestFile(t, override, strings.Repeat("x", runComposeProjectMaxBytes+1))
+	if _, err := encodeRunComposeProjectArgs([]string{"--compose-file=" + override}); err == nil || !strings.Contains(err.Error(), "the limit is") {
+		t.Fatalf("oversized override error = %v, want size limit", err)
+	}
+}
+
+func TestValidateRunFileArgsRejectsComposeProjectForOtherPayloads(t *testing.T) {
+	err := validateRunFileArgs(0, []string{"--profile=worker"}, false)
+	if err == nil || !strings.Contains(err.Error(), "only valid for docker compose payloads") {
+		t.Fatalf("validateRunFileArgs error = %v, want compose-only rejection", err)
+	}
+}
+
+func TestDetectComposeProjectChange(t *testing.T) {
+	dir := t.TempDir()
+	override := filepath.Join(dir, "prod.override.yml")
+	writeComposeProjectTestFile(t, override, "services: {}\n")
+	args := []string{"--compose-file=" + override, "--profile=worker"}
+	localHash, err := localComposeProjectHash(args)
+	if err != nil {
+		t.Fatalf("localComposeProjectHash: %v", err)
+	}
+
+	remote := catchrpc.ArtifactHashesResponse{Found: true, ComposeProject: &catchrpc.ArtifactHash{SHA256: localHash}}
+	summary, err := detectComposeProjectChange(runChangeSummary{}, args, remote)
+	if err != nil || summary.payloadChanged {
+		t.Fatalf("matching project = %+v, %v; want unchanged", summary, err)
+	}
+	summary, err = detectComposeProjectChange(runChangeSummary{}, []string{"--compose-file=" + override}, remote)
+	if err != nil || !summary.payloadChanged || summary.payloadLabel != "compose project" {
+		t.Fatalf("dropped profile = %+v, %v; want compose project change", summary, err)
+	}
+	summary, err = detectComposeProjectChange(runChangeSummary{}, nil, catchrpc.ArtifactHashesResponse{Found: true})
+	if err != nil || summary.payloadChanged {
+		t.Fatalf("no project = %+v, %v; want unchanged", summary, err)
+	}
+}
+
+func TestNormalizeServiceEntryCompose(t *testing.T) {
+	entry := ServiceEntry{Name: "web", Compose: []string{"compose.yml", "prod.override.yml"}}
+	if err := normalizeServiceEntryCompose(&entry); err != nil {
+		t.Fatalf("normalizeServiceEntryCompose: %v", err)
+	}
+	if entry.Payload != "compose.yml" {
+		t.Fatalf("payload = %q, want first compose file", entry.Payload)
+	}
+	if !reflect.DeepEqual(entry.composeOverrides(), []string{"prod.override.yml"}) {
+		t.Fatalf("overrides = %#v", entry.composeOverrides())
+	}
+	mismatch := ServiceEntry{Name: "web", Payload: "other.yml", Compose: []string{"compose.yml"}}
+	if err := normalizeServiceEntryCompose(&mismatch); err == nil {
+		t.Fatal("normalizeServiceEntryCompose accepted a payload that is not the first compose file")
+	}
+}
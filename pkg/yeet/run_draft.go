@@ -61,6 +61,7 @@ type RunDraftVM struct {
 	MemoryMin string `json:"memoryMin,omitempty"`
 	Balloon   string `json:"balloon,omitempty"`
 	Disk      string `json:"disk,omitempty"`
+	UserData  string `json:"userData,omitempty"`
 }
 
 type RunDraftStorage struct {
@@ -105,6 +106,8 @@ func runDraftFromCLI(cmdArgs []string, cfgLoc *projectConfigLocation, hostOverri
 	if err != nil {
 		return RunDraft{}, err
 	}
+	effectiveArgs = runArgsWithConfiguredUserData(effectiveArgs, entry, hasEntry, cfgLoc)
+	effectiveArgs = runArgsWithConfiguredComposeProject(effectiveArgs, entry, hasEntry, cfgLoc)
 	if err := ensureSvcRunEntryFlags(entry, hasEntry, effectiveArgs); err != nil {
 		return RunDraft{}, err
 	}
@@ -269,7 +272,8 @@ func appendRunDraftVMArgs(args []string, vm RunDraftVM) []string {
 	args = appendRunDraftStringFlag(args, "--memory", vm.Memory)
 	args = appendRunDraftStringFlag(args, "--memory-min", vm.MemoryMin)
 	args = appendRunDraftStringFlag(args, "--balloon", vm.Balloon)
-	return appendRunDraftStringFlag(args, "--disk", vm.Disk)
+	args = appendRunDraftStringFlag(args, "--disk", vm.Disk)
+	return appendRunDraftStringFlag(args, runUserDataFlag, vm.UserData)
 }
 
 func appendRunDraftRepeatedFlag(args []string, name string, values []string) []string {
@@ -296,6 +300,7 @@ func runDraftVMFromRunFlags(flags cli.RunFlags) RunDraftVM {
 		MemoryMin: strings.TrimSpace(flags.MemoryMin),
 		Balloon:   strings.TrimSpace(flags.Balloon),
 		Disk:      strings.TrimSpace(flags.Disk),
+		UserData:  flags.UserData,
 	}
 }
 
@@ -133,6 +133,7 @@ func TestRunDraftFromCLIPreservesScheduledVMKindForNativeOnlyValidation(t *testi
 }
 
 func TestRunDraftWithoutLocalEntryUsesRemoteNetworkAuthority(t *testing.T) {
+	stubRemoteArtifactImages(t, catchrpc.ArtifactHashesResponse{})
 	preserveRunDraftGlobals(t)
 	oldInfo := fetchRunChangeServiceInfoFn
 	oldHashes := fetchRemoteArtifactHashesFn
@@ -1027,6 +1028,7 @@ func TestRunDraftFromCLIPreservesAbsentLegacyRunAs(t *testing.T) {
 }
 
 func TestExecuteRunDraftSkipsServiceInfoOutsideNewOnlyMode(t *testing.T) {
+	stubRemoteArtifactImages(t, catchrpc.ArtifactHashesResponse{})
 	preserveRunDraftGlobals(t)
 	oldTryImage := tryRunRemoteImageFn
 	defer func() {
@@ -1256,6 +1258,7 @@ func TestRunFromProjectConfigUsesStoredLocalImagePayloadKind(t *testing.T) {
 }
 
 func TestRunFromProjectConfigPreservesStoredRemoteImageRef(t *testing.T) {
+	stubRemoteArtifactImages(t, catchrpc.ArtifactHashesResponse{})
 	preserveRunDraftGlobals(t)
 	oldTryImage := tryRunRemoteImageFn
 	defer func() {
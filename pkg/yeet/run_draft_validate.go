@@ -312,6 +312,9 @@ func validateRunDraftVM(draft *RunDraft, result *RunDraftValidationResult) {
 	if draft.VM.Disk != "" {
 		result.addError("vm.disk", "--disk is only valid for VM payloads")
 	}
+	if draft.VM.UserData != "" {
+		result.addError("vm.userData", "--user-data is only valid for VM payloads")
+	}
 }
 
 func validateRunDraftVMBalloon(balloon string, result *RunDraftValidationResult) {
@@ -0,0 +1,74 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yeet
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/yeetrun/yeet/pkg/cli"
+)
+
+const (
+	runUserDataFlag     = "--user-data"
+	runUserDataMaxBytes = 64 << 10
+	// runUserDataInlinePrefix matches the encoding catch expects; it never
+	// reads user-data paths from its own disk.
+	runUserDataInlinePrefix = "base64:"
+)
+
+// runArgsWithConfiguredUserData adds the yeet.toml user_data file when the
+// command line does not name one.
+func runArgsWithConfiguredUserData(args []string, entry ServiceEntry, hasEntry bool, cfgLoc *projectConfigLocation) []string {
+	userData := strings.TrimSpace(entry.UserData)
+	if !hasEntry || cfgLoc == nil || userData == "" || runArgsHaveFlag(args, runUserDataFlag) {
+		return args
+	}
+	return appendRunControlFlagBeforeBoundary(args, runUserDataFlag+"="+resolveEnvFilePath(cfgLoc.Dir, userData))
+}
+
+// runConfigUserData splits --user-data out of run args so yeet.toml keeps it
+// as user_data, relative to the config directory.
+func runConfigUserData(configDir string, args []string) (string, []string, error) {
+	flags, _, err := cli.ParseRun(args)
+	if err != nil {
+		return "", nil, err
+	}
+	if flags.UserData == "" {
+		return "", args, nil
+	}
+	return relativeEnvFilePath(configDir, flags.UserData), removeRunUserDataFlag(args), nil
+}
+
+// encodeRunUserDataArgs replaces the --user-data path with the file contents
+// so catch receives the data inline.
+func encodeRunUserDataArgs(args []string) ([]string, error) {
+	flags, _, err := cli.ParseRun(args)
+	if err != nil || flags.UserData == "" {
+		return args, err
+	}
+	raw, err := os.ReadFile(flags.UserData)
+	if err != nil {
+		return nil, fmt.Errorf("read --user-data: %w", err)
+	}
+	if len(raw) > runUserDataMaxBytes {
+		return nil, fmt.Errorf("--user-data file %s is %d bytes; the limit is %d", flags.UserData, len(raw), runUserDataMaxBytes)
+	}
+	value := runUserDataInlinePrefix + base64.StdEncoding.EncodeToString(raw)
+	return appendRunControlFlagBeforeBoundary(removeRunUserDataFlag(args), runUserDataFlag+"="+value), nil
+}
+
+// removeRunUserDataFlag drops --user-data. It only affects first boot, so a
+// changed path alone is not a reason to redeploy.
+func removeRunUserDataFlag(args []string) []string {
+	flagArgs, payloadArgs := splitRunArgsForParsing(args)
+	flagArgs = removeRunFlags(flagArgs, map[string]bool{runUserDataFlag: true})
+	if len(payloadArgs) == 0 {
+		return flagArgs
+	}
+	return append(append(flagArgs, "--"), payloadArgs...)
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yeet
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTryRunVMPayloadSendsUserDataInline(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "init.yaml")
+	if err := os.WriteFile(path, []byte("packages: [jq]\n"), 0o600); err != nil {
+		t.Fatalf("write user-data: %v", err)
+	}
+	oldExec := execRemoteToFn
+	oldService := serviceOverride
+	t.Cleanup(func() {
+		execRemoteToFn = oldExec
+		serviceOverride = oldService
+	})
+	serviceOverride = "devbox"
+	var gotArgs []string
+	execRemoteToFn = func(_ context.Context, _ string, args []string, _ io.Reader, _ bool, _ io.Writer) error {
+		gotArgs = args
+		return nil
+	}
+
+	ok, err := tryRunVMPayloadContextWithOutput(context.Background(), io.Discard, "vm://ubuntu/26.04", []string{"--user-data", path, "--disk=64g"})
+	if !ok || err != nil {
+		t.Fatalf("tryRunVMPayloadContextWithOutput = %v, %v", ok, err)
+	}
+	want := []string{"run", "--disk=64g", "--user-data=base64:" + base64.StdEncoding.EncodeToString([]byte("packages: [jq]\n")), "vm://ubuntu/26.04"}
+	if !reflect.DeepEqual(gotArgs, want) {
+		t.Fatalf("remote args = %#v, want %#v", gotArgs, want)
+	}
+}
+
+func TestEncodeRunUserDataArgsRejectsLargeFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "init.yaml")
+	if err := os.WriteFile(path, make([]byte, runUserDataMaxBytes+1), 0o600); err != nil {
+		t.Fatalf("write user-data: %v", err)
+	}
+	_, err := encodeRunUserDataArgs([]string{"--user-data=" + path})
+	if err == nil || !strings.Contains(err.Error(), "the limit is") {
+		t.Fatalf("encodeRunUserDataArgs error = %v, want size limit", err)
+	}
+}
+
+func TestRunUserDataPersistsAsConfigField(t *testing.T) {
+	dir := t.TempDir()
+	userData, args, err := runConfigUserData(dir, []string{"--disk=64g", "--user-data=" + filepath.Join(dir, "vm", "init.yaml")})
+	if err != nil {
+		t.Fatalf("runConfigUserData: %v", err)
+	}
+	if userData != filepath.Join("vm", "init.yaml") || !reflect.DeepEqual(args, []string{"--disk=64g"}) {
+		t.Fatalf("runConfigUserData = %q, %#v; want relative path and remaining args", userData, args)
+	}
+
+	loc := &projectConfigLocation{Dir: dir}
+	entry := ServiceEntry{Name: "devbox", Host: "catch", UserData: userData}
+	got := runArgsWithConfiguredUserData([]string{"--disk=64g"}, entry, true, loc)
+	if want := []string{"--disk=64g", "--user-data=" + filepath.Join(dir, "vm", "init.yaml")}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("configured args = %#v, want %#v", got, want)
+	}
+	explicit := []string{"--user-data=./other.yaml"}
+	if got := runArgsWithConfiguredUserData(explicit, entry, true, loc); !reflect.DeepEqual(got, explicit) {
+		t.Fatalf("explicit args = %#v, want command line user-data to win", got)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yeet
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+	"github.com/yeetrun/yeet/pkg/cli"
+)
+
+// serviceArchiveManifestLimit matches the catch-side bound on manifest size.
+const serviceArchiveManifestLimit = 1 << 20
+
+func handleServiceExport(ctx context.Context, req svcCommandRequest) error {
+	if isTerminalFn(int(os.Stdout.Fd())) {
+		return fmt.Errorf("refusing to write a service archive to a terminal; redirect stdout, e.g. yeet service export %s > %s.yeet.tar.zst", req.Service, req.Service)
+	}
+	return withRemoteExecTTYDisabled(func() error {
+		return execRemoteFn(ctx, req.Service, req.Command.RawArgs, nil, false)
+	})
+}
+
+func handleServiceImport(ctx context.Context, req svcCommandRequest) error {
+	flags, rest, err := cli.ParseServiceImport(req.Command.Args[1:])
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(rest[0])
+	if err != nil {
+		return fmt.Errorf("open service archive: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	manifest, err := readServiceArchiveManifest(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", rest[0], err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("rewind service archive: %w", err)
+	}
+	target := manifest.Service
+	if flags.As != "" {
+		target = flags.As
+	}
+	return withRemoteExecTTYDisabled(func() error {
+		return execRemoteFn(ctx, target, []string{"service", "import"}, f, false)
+	})
+}
+
+// readServiceArchiveManifest reads the leading manifest entry so the client
+// can pick the target service before streaming the archive to catch.
+func readServiceArchiveManifest(r io.Reader) (catchrpc.ServiceArchiveManifest, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return catchrpc.ServiceArchiveManifest{}, fmt.Errorf("open service archive: %w", err)
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+	hdr, err := tr.Next()
+	if errors.Is(err, io.EOF) {
+		return catchrpc.ServiceArchiveManifest{}, errors.New("service archive is empty")
+	}
+	if err != nil {
+		return catchrpc.ServiceArchiveManifest{}, fmt.Errorf("read service archive: %w", err)
+	}
+	if hdr.Name != catchrpc.ServiceArchiveManifestName {
+		return catchrpc.ServiceArchiveManifest{}, fmt.Errorf("not a yeet service archive: first entry is %q", hdr.Name)
+	}
+	var manifest catchrpc.ServiceArchiveManifest
+	if err := json.NewDecoder(io.LimitReader(tr, serviceArchiveManifestLimit)).Decode(&manifest); err != nil {
+		return catchrpc.ServiceArchiveManifest{}, fmt.Errorf("decode service archive manifest: %w", err)
+	}
+	if manifest.Format != catchrpc.ServiceArchiveFormat {
+		return catchrpc.ServiceArchiveManifest{}, fmt.Errorf("unsupported service archive format %d", manifest.Format)
+	}
+	if manifest.Service == "" {
+		return catchrpc.ServiceArchiveManifest{}, errors.New("service archive manifest has no service name")
+	}
+	return manifest, nil
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yeet
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+)
+
+func writeTestServiceArchive(t *testing.T, entries map[string][]byte, order []string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("zstd.NewWriter: %v", err)
+	}
+	tw := tar.NewWriter(zw)
+	for _, name := range order {
+		body := entries[name]
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(body))}); err != nil {
+			t.Fatalf("WriteHeader: %v", err)
+		}
+		if _, err := tw.Write(body); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tar Close: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zstd Close: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "svc-a.yeet.tar.zst")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func testServiceArchiveManifest(t *testing.T, manifest catchrpc.ServiceArchiveManifest) []byte {
+	t.Helper()
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	return raw
+}
+
+func TestHandleServiceExportRefusesTerminalStdout(t *testing.T) {
+	oldExec, oldIsTerminal := execRemoteFn, isTerminalFn
+	t.Cleanup(func() { execRemoteFn, isTerminalFn = oldExec, oldIsTerminal })
+	isTerminalFn = func(int) bool { return true }
+	execRemoteFn = func(context.Context, string, []string, io.Reader, bool) error {
+		t.Fatal("execRemoteFn should not be called")
+		return nil
+	}
+
+	err := handleServiceExport(context.Background(), svcCommandRequest{
+		Service: "svc-a",
+		Command: svcCommand{Args: []string{"export"}, RawArgs: []string{"service", "export"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "svc-a.yeet.tar.zst") {
+		t.Fatalf("handleServiceExport error = %v, want redirect hint", err)
+	}
+}
+
+func TestHandleServiceExportStreamsRemoteArchive(t *testing.T) {
+	oldExec, oldIsTerminal := execRemoteFn, isTerminalFn
+	t.Cleanup(func() { execRemoteFn, isTerminalFn = oldExec, oldIsTerminal })
+	isTerminalFn = func(int) bool { return false }
+	var gotService string
+	var gotArgs []string
+	var gotTTY *bool
+	execRemoteFn = func(_ context.Context, service string, args []string, _ io.Reader, tty bool) error {
+		gotService = service
+		gotArgs = append([]string{}, args...)
+		gotTTY = execUIOverrides.TTYOverride
+		return nil
+	}
+
+	err := handleServiceExport(context.Background(), svcCommandRequest{
+		Service: "svc-a",
+		Command: svcCommand{Args: []string{"export", "--data"}, RawArgs: []string{"service", "export", "--data"}},
+	})
+	if err != nil {
+		t.Fatalf("handleServiceExport: %v", err)
+	}
+	if gotService != "svc-a" || !reflect.DeepEqual(gotArgs, []string{"service", "export", "--data"}) {
+		t.Fatalf("exec = %q %v", gotService, gotArgs)
+	}
+	if gotTTY == nil || *gotTTY {
+		t.Fatalf("export should disable the remote TTY")
+	}
+}
+
+func TestHandleServiceImportStreamsArchiveToTarget(t *testing.T) {
+	manifest := testServiceArchiveManifest(t, catchrpc.ServiceArchiveManifest{
+		Format:  catchrpc.ServiceArchiveFormat,
+		Service: "svc-a",
+		Payload: "payload/binary",
+	})
+	path := writeTestServiceArchive(t, map[string][]byte{
+		catchrpc.ServiceArchiveManifestName: manifest,
+		"payload/binary":                    []byte("bin"),
+	}, []string{catchrpc.ServiceArchiveManifestName, "payload/binary"})
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+
+	oldExec := execRemoteFn
+	t.Cleanup(func() { execRemoteFn = oldExec })
+	tests := []struct {
+		name    string
+		args    []string
+		service string
+	}{
+		{name: "manifest name", args: []string{"import", path}, service: "svc-a"},
+		{name: "renamed", args: []string{"import", path, "--as=svc-b"}, service: "svc-b"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotService string
+			var gotArgs []string
+			var gotBody []byte
+			execRemoteFn = func(_ context.Context, service string, args []string, stdin io.Reader, _ bool) error {
+				gotService = service
+				gotArgs = append([]string{}, args...)
+				var err error
+				gotBody, err = io.ReadAll(stdin)
+				return err
+			}
+			if err := handleServiceImport(context.Background(), svcCommandRequest{Command: svcCommand{Args: tc.args}}); err != nil {
+				t.Fatalf("handleServiceImport: %v", err)
+			}
+			if gotService != tc.service || !reflect.DeepEqual(gotArgs, []string{"service", "import"}) {
+				t.Fatalf("exec = %q %v", gotService, gotArgs)
+			}
+			if !bytes.Equal(gotBody, want) {
+				t.Fatalf("streamed %d bytes, want full %d byte archive", len(gotBody), len(want))
+			}
+		})
+	}
+}
+
+func TestReadServiceArchiveManifestRejectsForeignArchives(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries map[string][]byte
+		order   []string
+		want    string
+	}{
+		{
+			name:    "empty",
+			want:    "empty",
+			entries: map[string][]byte{},
+		},
+		{
+			name:    "manifest not first",
+			entries: map[string][]byte{"payload/binary": []byte("bin")},
+			order:   []string{"payload/binary"},
+			want:    "first entry",
+		},
+		{
+			name: "format",
+			entries: map[string][]byte{catchrpc.ServiceArchiveManifestName: testServiceArchiveManifest(t, catchrpc.ServiceArchiveManifest{
+				Format:  99,
+				Service: "svc-a",
+			})},
+			order: []string{catchrpc.ServiceArchiveManifestName},
+			want:  "unsupported service archive format",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := os.Open(writeTestServiceArchive(t, tc.entries, tc.order))
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			defer func() { _ = f.Close() }()
+			_, err = readServiceArchiveManifest(f)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("readServiceArchiveManifest error = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
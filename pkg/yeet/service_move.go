@@ -0,0 +1,288 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yeet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+	"github.com/yeetrun/yeet/pkg/cli"
+)
+
+var (
+	fetchServiceInfoForMoveFn = func(ctx context.Context, host, service string) (catchrpc.ServiceInfoResponse, error) {
+		return newRPCClient(host).ServiceInfo(ctx, service)
+	}
+	serviceMoveVerifyAttempts = 30
+	serviceMoveVerifyInterval = time.Second
+)
+
+type serviceMove struct {
+	Service string
+	From    string
+	To      string
+	Running bool
+	// Network is the source's network, reported after the move for the
+	// addresses that do not follow the service.
+	Network catchrpc.ServiceNetwork
+}
+
+func handleServiceMove(ctx context.Context, req svcCommandRequest) error {
+	args := append(append([]string{}, req.Command.Args[1:]...), req.Service)
+	flags, _, err := cli.ParseServiceMove(args)
+	if err != nil {
+		return err
+	}
+	move := serviceMove{Service: req.Service, From: Host(), To: flags.To}
+	if move.From == move.To {
+		return fmt.Errorf("service %q is already on %s", move.Service, move.To)
+	}
+	if move.Network, err = checkServiceMoveHosts(ctx, move); err != nil {
+		return err
+	}
+	if move.Running, err = serviceRunningOnHost(ctx, move.From, move.Service); err != nil {
+		return err
+	}
+	if err := runServiceMove(ctx, move); err != nil {
+		return err
+	}
+	return finishServiceMove(ctx, req.Config, move)
+}
+
+// checkServiceMoveHosts returns the source's network once both hosts are
+// ready for the move.
+func checkServiceMoveHosts(ctx context.Context, move serviceMove) (catchrpc.ServiceNetwork, error) {
+	source, err := fetchServiceInfoForMoveFn(ctx, move.From, move.Service)
+	if err != nil {
+		return catchrpc.ServiceNetwork{}, fmt.Errorf("inspect %s on %s: %w", move.Service, move.From, err)
+	}
+	if !source.Found {
+		return catchrpc.ServiceNetwork{}, fmt.Errorf("service %q not found on %s", move.Service, move.From)
+	}
+	if source.Info.ServiceType == serviceTypeVM {
+		return catchrpc.ServiceNetwork{}, fmt.Errorf("service %q is a VM; service move does not support VMs; use yeet vm migrate", move.Service)
+	}
+	target, err := fetchServiceInfoForMoveFn(ctx, move.To, move.Service)
+	if err != nil {
+		return catchrpc.ServiceNetwork{}, fmt.Errorf("inspect %s on %s: %w", move.Service, move.To, err)
+	}
+	if target.Found {
+		return catchrpc.ServiceNetwork{}, fmt.Errorf("service %q already exists on %s", move.Service, move.To)
+	}
+	return source.Info.Network, nil
+}
+
+// runServiceMove stops the source before copying so the archive is consistent
+// and the service never runs on both hosts. Any failure before the target is
+// verified restores the source and removes the partial target.
+func runServiceMove(ctx context.Context, move serviceMove) error {
+	if move.Running {
+		printServiceMoveStep("stopping %s on %s", move.Service, move.From)
+		if _, err := execRemoteOutputFn(ctx, move.From, move.Service, []string{"stop"}, nil); err != nil {
+			return fmt.Errorf("stop %s on %s: %w", move.Service, move.From, err)
+		}
+	}
+	printServiceMoveStep("copying %s from %s to %s", move.Service, move.From, move.To)
+	err := transferServiceArchive(ctx, move)
+	if err == nil {
+		printServiceMoveStep("verifying %s on %s", move.Service, move.To)
+		err = verifyServiceMoveTarget(ctx, move)
+	}
+	if err == nil {
+		return nil
+	}
+	return errors.Join(err, rollbackServiceMove(ctx, move))
+}
+
+func transferServiceArchive(ctx context.Context, move serviceMove) error {
+	return pipeServiceMoveStream(ctx, move, []string{"service", "export", "--data"}, []string{"service", "import"})
+}
+
+// pipeServiceMoveStream feeds the output of a command on the source host into
+// a command on the target host.
+func pipeServiceMoveStream(ctx context.Context, move serviceMove, exportArgs, importArgs []string) error {
+	var archive io.ReadCloser
+	var done <-chan error
+	err := withTemporaryHost(move.From, func() error {
+		var err error
+		archive, done, err = execRemoteStreamFn(ctx, move.Service, exportArgs, nil)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("export %s from %s: %w", move.Service, move.From, err)
+	}
+	_, importErr := execRemoteOutputFn(ctx, move.To, move.Service, importArgs, archive)
+	_ = archive.Close()
+	exportErr := <-done
+	if exportErr != nil {
+		return fmt.Errorf("export %s from %s: %w", move.Service, move.From, exportErr)
+	}
+	if importErr != nil {
+		return fmt.Errorf("import %s on %s: %w", move.Service, move.To, importErr)
+	}
+	return nil
+}
+
+// verifyServiceMoveTarget waits for the imported service to come up. A
+// service that was stopped on the source only needs to exist on the target.
+func verifyServiceMoveTarget(ctx context.Context, move serviceMove) error {
+	var lastErr error
+	for attempt := 0; attempt < serviceMoveVerifyAttempts; attempt++ {
+		if attempt > 0 {
+			if err := sleepContext(ctx, serviceMoveVerifyInterval); err != nil {
+				return err
+			}
+		}
+		running, err := serviceRunningOnHost(ctx, move.To, move.Service)
+		if err == nil && (running || !move.Running) {
+			return nil
+		}
+		lastErr = err
+	}
+	if lastErr != nil {
+		return fmt.Errorf("verify %s on %s: %w", move.Service, move.To, lastErr)
+	}
+	return fmt.Errorf("service %q did not reach running on %s", move.Service, move.To)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func rollbackServiceMove(ctx context.Context, move serviceMove) error {
+	var errs []error
+	target, err := fetchServiceInfoForMoveFn(ctx, move.To, move.Service)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("inspect partial %s on %s: %w", move.Service, move.To, err))
+	} else if target.Found {
+		printServiceMoveStep("removing partial %s from %s", move.Service, move.To)
+		if _, err := execRemoteOutputFn(ctx, move.To, move.Service, []string{"remove", "--yes", "--clean-data"}, nil); err != nil {
+			errs = append(errs, fmt.Errorf("remove partial %s on %s: %w", move.Service, move.To, err))
+		}
+	}
+	if move.Running {
+		printServiceMoveStep("restarting %s on %s", move.Service, move.From)
+		if _, err := execRemoteOutputFn(ctx, move.From, move.Service, []string{"start"}, nil); err != nil {
+			errs = append(errs, fmt.Errorf("restart %s on %s: %w", move.Service, move.From, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// finishServiceMove points yeet.toml at the new host before removing the
+// source, so a failed cleanup never leaves the config on a stale host.
+func finishServiceMove(ctx context.Context, cfgLoc *projectConfigLocation, move serviceMove) error {
+	moved, err := moveServiceConfigHost(cfgLoc, move)
+	if err != nil {
+		return fmt.Errorf("%s is running on %s, but updating %s failed: %w", move.Service, move.To, projectConfigName, err)
+	}
+	printServiceMoveStep("removing %s from %s", move.Service, move.From)
+	if _, err := execRemoteOutputFn(ctx, move.From, move.Service, []string{"remove", "--yes", "--clean-data"}, nil); err != nil {
+		return fmt.Errorf("%s is running on %s, but removing it from %s failed: %w", move.Service, move.To, move.From, err)
+	}
+	if moved {
+		printServiceMoveStep("updated %s host to %s", projectConfigName, move.To)
+	}
+	printServiceMoveStep("moved %s to %s", move.Service, move.To)
+	for _, warning := range serviceMoveAddressWarnings(move) {
+		_, _ = fmt.Fprintf(os.Stdout, "Warning: %s\n", warning)
+	}
+	return nil
+}
+
+// serviceMoveAddressWarnings lists the addresses clients may still use for
+// the source host. The tailnet name and macvlan MAC follow the service, since
+// the tailscale state travels in the service root and the import keeps the
+// MAC. Published ports and the svc address belong to the host, and no DNS
+// outside catch is changed, so those are left to the user.
+func serviceMoveAddressWarnings(move serviceMove) []string {
+	var warnings []string
+	if ports := serviceMovePublishedPorts(move.Network.Ports); len(ports) != 0 {
+		warnings = append(warnings, fmt.Sprintf("published ports %s are now on %s; yeet did not re-point clients or DNS records that used %s, update them to %s", strings.Join(ports, ", "), move.To, move.From, move.To))
+	}
+	if move.Network.SvcIP != "" {
+		warnings = append(warnings, fmt.Sprintf("svc address %s stayed on %s and was not re-pointed; %s.yeet.internal now resolves only on %s, with a new address", move.Network.SvcIP, move.From, move.Service, move.To))
+	}
+	return warnings
+}
+
+func serviceMovePublishedPorts(ports []catchrpc.ServicePort) []string {
+	out := make([]string, 0, len(ports))
+	for _, port := range ports {
+		if port.HostPort == 0 {
+			continue
+		}
+		proto := port.Protocol
+		if proto == "" {
+			proto = "tcp"
+		}
+		out = append(out, fmt.Sprintf("%d/%s", port.HostPort, proto))
+	}
+	return out
+}
+
+func moveServiceConfigHost(cfgLoc *projectConfigLocation, move serviceMove) (bool, error) {
+	if cfgLoc == nil || cfgLoc.Config == nil {
+		return false, nil
+	}
+	entry, ok := cfgLoc.Config.ServiceEntry(move.Service, move.From)
+	if !ok {
+		return false, nil
+	}
+	cfgLoc.Config.RemoveServiceEntry(move.Service, move.From)
+	entry.Host = move.To
+	// The target recreates the service under its default root.
+	entry.ServiceRoot = ""
+	entry.ServiceRootZFS = false
+	cfgLoc.Config.ReplaceServiceEntry(entry)
+	return true, saveProjectConfig(cfgLoc)
+}
+
+func serviceRunningOnHost(ctx context.Context, host, service string) (bool, error) {
+	payload, err := execRemoteOutputFn(ctx, host, service, []string{"status", "--format=json"}, nil)
+	if err != nil {
+		return false, fmt.Errorf("status of %s on %s: %w", service, host, err)
+	}
+	var statuses []statusService
+	if err := json.Unmarshal(payload, &statuses); err != nil {
+		return false, fmt.Errorf("status of %s on %s returned invalid JSON: %w", service, host, err)
+	}
+	for _, status := range statuses {
+		if status.ServiceName == service {
+			return statusComponentsRunning(status.Components), nil
+		}
+	}
+	return false, nil
+}
+
+func statusComponentsRunning(components []statusComponent) bool {
+	if len(components) == 0 {
+		return false
+	}
+	for _, component := range components {
+		if !strings.EqualFold(component.Status, "running") {
+			return false
+		}
+	}
+	return true
+}
+
+func printServiceMoveStep(format string, args ...any) {
+	_, _ = fmt.Fprintf(os.Stdout, "==> "+format+"\n", args...)
+}
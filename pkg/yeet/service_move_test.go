@@ -0,0 +1,228 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yeet
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+)
+
+type fakeServiceMoveHosts struct {
+	found     map[string]bool
+	types     map[string]string
+	running   map[string]bool
+	importErr error
+	calls     []string
+	imported  string
+}
+
+func (f *fakeServiceMoveHosts) install(t *testing.T) {
+	t.Helper()
+	oldInfo, oldOutput, oldStream := fetchServiceInfoForMoveFn, execRemoteOutputFn, execRemoteStreamFn
+	oldAttempts, oldInterval := serviceMoveVerifyAttempts, serviceMoveVerifyInterval
+	t.Cleanup(func() {
+		fetchServiceInfoForMoveFn, execRemoteOutputFn, execRemoteStreamFn = oldInfo, oldOutput, oldStream
+		serviceMoveVerifyAttempts, serviceMoveVerifyInterval = oldAttempts, oldInterval
+	})
+	serviceMoveVerifyAttempts, serviceMoveVerifyInterval = 2, 0
+	fetchServiceInfoForMoveFn = func(_ context.Context, host, _ string) (catchrpc.ServiceInfoResponse, error) {
+		return catchrpc.ServiceInfoResponse{
+			Found: f.found[host],
+			Info:  catchrpc.ServiceInfo{ServiceType: f.types[host]},
+		}, nil
+	}
+	execRemoteStreamFn = func(_ context.Context, service string, args []string, _ io.Reader) (io.ReadCloser, <-chan error, error) {
+		f.calls = append(f.calls, Host()+" "+strings.Join(args, " "))
+		done := make(chan error, 1)
+		done <- nil
+		return io.NopCloser(strings.NewReader("archive-for-" + service)), done, nil
+	}
+	execRemoteOutputFn = f.exec
+}
+
+func (f *fakeServiceMoveHosts) exec(_ context.Context, host, service string, args []string, stdin io.Reader) ([]byte, error) {
+	call := host + " " + strings.Join(args, " ")
+	f.calls = append(f.calls, call)
+	switch strings.Join(args, " ") {
+	case "status --format=json":
+		status := "stopped"
+		if f.running[host] {
+			status = "running"
+		}
+		return []byte(`[{"serviceName":"` + service + `","components":[{"name":"` + service + `","status":"` + status + `"}]}]`), nil
+	case "stop":
+		f.running[host] = false
+	case "start":
+		f.running[host] = true
+	case "service import":
+		body, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, err
+		}
+		f.imported = string(body)
+		f.found[host] = true
+		if f.importErr != nil {
+			return nil, f.importErr
+		}
+		f.running[host] = true
+	case "remove --yes --clean-data":
+		f.found[host] = false
+		f.running[host] = false
+	}
+	return nil, nil
+}
+
+func newFakeServiceMoveHosts(t *testing.T) *fakeServiceMoveHosts {
+	f := &fakeServiceMoveHosts{
+		found:   map[string]bool{"host-a": true},
+		types:   map[string]string{"host-a": "service"},
+		running: map[string]bool{"host-a": true},
+	}
+	f.install(t)
+	return f
+}
+
+func serviceMoveRequest(cfgLoc *projectConfigLocation) svcCommandRequest {
+	return svcCommandRequest{
+		Service: "svc-a",
+		Config:  cfgLoc,
+		Command: svcCommand{Args: []string{"move", "--to=host-b"}},
+	}
+}
+
+func TestHandleServiceMoveMovesServiceAndConfig(t *testing.T) {
+	hosts := newFakeServiceMoveHosts(t)
+	tmp := t.TempDir()
+	cfg := &ProjectConfig{Version: projectConfigVersion}
+	cfg.SetServiceEntry(ServiceEntry{
+		Name:        "svc-a",
+		Host:        "host-a",
+		Type:        serviceTypeRun,
+		Payload:     "compose.yml",
+		ServiceRoot: "/srv/custom/svc-a",
+	})
+	loc := &projectConfigLocation{Path: filepath.Join(tmp, projectConfigName), Dir: tmp, Config: cfg}
+
+	err := withTemporaryHost("host-a", func() error {
+		return handleServiceMove(context.Background(), serviceMoveRequest(loc))
+	})
+	if err != nil {
+		t.Fatalf("handleServiceMove: %v", err)
+	}
+	want := []string{
+		"host-a status --format=json",
+		"host-a stop",
+		"host-a service export --data",
+		"host-b service import",
+		"host-b status --format=json",
+		"host-a remove --yes --clean-data",
+	}
+	if !reflect.DeepEqual(hosts.calls, want) {
+		t.Fatalf("calls = %q, want %q", hosts.calls, want)
+	}
+	if hosts.imported != "archive-for-svc-a" {
+		t.Fatalf("imported = %q", hosts.imported)
+	}
+	if _, ok := cfg.ServiceEntry("svc-a", "host-a"); ok {
+		t.Fatalf("source entry still in config: %+v", cfg.Services)
+	}
+	entry, ok := cfg.ServiceEntry("svc-a", "host-b")
+	if !ok || entry.Payload != "compose.yml" || entry.ServiceRoot != "" {
+		t.Fatalf("moved entry = %+v, ok=%v", entry, ok)
+	}
+	saved, err := loadProjectConfigFromDir(tmp)
+	if err != nil || saved == nil {
+		t.Fatalf("loadProjectConfigFromDir = %v, %v", saved, err)
+	}
+	if _, ok := saved.Config.ServiceEntry("svc-a", "host-b"); !ok {
+		t.Fatalf("saved config missing moved entry: %+v", saved.Config.Services)
+	}
+}
+
+func TestHandleServiceMoveRollsBackFailedImport(t *testing.T) {
+	hosts := newFakeServiceMoveHosts(t)
+	hosts.importErr = errors.New("import boom")
+
+	err := withTemporaryHost("host-a", func() error {
+		return handleServiceMove(context.Background(), serviceMoveRequest(nil))
+	})
+	if err == nil || !strings.Contains(err.Error(), "import boom") {
+		t.Fatalf("handleServiceMove error = %v, want import failure", err)
+	}
+	want := []string{
+		"host-a status --format=json",
+		"host-a stop",
+		"host-a service export --data",
+		"host-b service import",
+		"host-b remove --yes --clean-data",
+		"host-a start",
+	}
+	if !reflect.DeepEqual(hosts.calls, want) {
+		t.Fatalf("calls = %q, want %q", hosts.calls, want)
+	}
+	if !hosts.running["host-a"] || hosts.found["host-b"] {
+		t.Fatalf("rollback state: source running=%v target found=%v", hosts.running["host-a"], hosts.found["host-b"])
+	}
+}
+
+func TestHandleServiceMoveRejectsInvalidMoves(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(*fakeServiceMoveHosts)
+		to    string
+		want  string
+	}{
+		{name: "same host", to: "host-a", want: "already on host-a"},
+		{name: "missing source", setup: func(f *fakeServiceMoveHosts) { f.found["host-a"] = false }, want: "not found on host-a"},
+		{name: "vm", setup: func(f *fakeServiceMoveHosts) { f.types["host-a"] = serviceTypeVM }, want: "does not support VMs"},
+		{name: "target exists", setup: func(f *fakeServiceMoveHosts) { f.found["host-b"] = true }, want: "already exists on host-b"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			hosts := newFakeServiceMoveHosts(t)
+			if tc.setup != nil {
+				tc.setup(hosts)
+			}
+			req := serviceMoveRequest(nil)
+			if tc.to != "" {
+				req.Command.Args = []string{"move", "--to=" + tc.to}
+			}
+			err := withTemporaryHost("host-a", func() error {
+				return handleServiceMove(context.Background(), req)
+			})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("handleServiceMove error = %v, want %q", err, tc.want)
+			}
+			if len(hosts.calls) != 0 {
+				t.Fatalf("unexpected remote calls: %q", hosts.calls)
+			}
+		})
+	}
+}
+
+func TestServiceMoveAddressWarnings(t *testing.T) {
+	move := serviceMove{Service: "svc-a", From: "host-a", To: "host-b"}
+	if got := serviceMoveAddressWarnings(move); len(got) != 0 {
+		t.Fatalf("warnings without host addresses = %q", got)
+	}
+	move.Network = catchrpc.ServiceNetwork{
+		SvcIP: "192.168.100.7",
+		Ports: []catchrpc.ServicePort{{HostPort: 8080, ContainerPort: 80}, {HostPort: 53, ContainerPort: 53, Protocol: "udp"}, {ContainerPort: 9000}},
+	}
+	want := []string{
+		"published ports 8080/tcp, 53/udp are now on host-b; yeet did not re-point clients or DNS records that used host-a, update them to host-b",
+		"svc address 192.168.100.7 stayed on host-a and was not re-pointed; svc-a.yeet.internal now resolves only on host-b, with a new address",
+	}
+	if got := serviceMoveAddressWarnings(move); !reflect.DeepEqual(got, want) {
+		t.Fatalf("warnings = %q, want %q", got, want)
+	}
+}
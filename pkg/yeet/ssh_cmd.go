@@ -26,6 +26,16 @@ type sshInvocation struct {
 	Service    string
 	Command    []string
 	ForceProxy bool
+	// Agent runs the shell or command through the guest agent over vsock
+	// instead of SSH, so it works before guest networking or sshd is up.
+	Agent bool
+}
+
+// yeetSSHFlags are the flags yeet consumes before handing the rest of the
+// arguments to ssh.
+type yeetSSHFlags struct {
+	ForceProxy bool
+	Agent      bool
 }
 
 type rpcShellPlan struct {
@@ -118,7 +128,7 @@ func ensureSSHCLI() error {
 }
 
 func sshInvocationFromArgs(args []string) (sshInvocation, error) {
-	forceProxy, sshArgs, err := splitYeetSSHFlags(trimSSHCommandName(args))
+	flags, sshArgs, err := splitYeetSSHFlags(trimSSHCommandName(args))
 	if err != nil {
 		return sshInvocation{}, err
 	}
@@ -130,7 +140,8 @@ func sshInvocationFromArgs(args []string) (sshInvocation, error) {
 		Options:    options,
 		Service:    sshServiceOrOverride(service),
 		Command:    command,
-		ForceProxy: forceProxy,
+		ForceProxy: flags.ForceProxy,
+		Agent:      flags.Agent,
 	}, nil
 }
 
@@ -188,6 +199,9 @@ func runSSHPlan(ctx context.Context, plan sshExecutionPlan, stdin io.Reader, std
 }
 
 func rpcHostShellPlan(host string, inv sshInvocation) (sshExecutionPlan, error) {
+	if inv.Agent {
+		return sshExecutionPlan{}, fmt.Errorf("ssh --agent only applies to VM targets")
+	}
 	if len(inv.Options) > 0 || inv.ForceProxy {
 		return sshExecutionPlan{}, fmt.Errorf("SSH options only apply to VM targets")
 	}
@@ -210,17 +224,10 @@ func serviceSSHExecutionPlan(ctx context.Context, host string, inv sshInvocation
 		return sshExecutionPlan{}, serviceNotFoundShellError(service, resp.Message)
 	}
 	if resp.Info.ServiceType != serviceTypeVM {
-		if len(inv.Options) > 0 || inv.ForceProxy {
-			return sshExecutionPlan{}, fmt.Errorf("SSH options only apply to VM targets")
-		}
-		return sshExecutionPlan{
-			RPCShell: &rpcShellPlan{
-				Host:    host,
-				Target:  catchrpc.ExecTargetServiceShell,
-				Service: service,
-				Command: inv.Command,
-			},
-		}, nil
+		return nonVMServiceShellPlan(host, service, inv)
+	}
+	if inv.Agent {
+		return vmAgentShellPlan(host, service, inv)
 	}
 	if err := ensureSSHCLI(); err != nil {
 		return sshExecutionPlan{}, err
@@ -239,6 +246,39 @@ func serviceSSHExecutionPlan(ctx context.Context, host string, inv sshInvocation
 	return plan, nil
 }
 
+func nonVMServiceShellPlan(host, service string, inv sshInvocation) (sshExecutionPlan, error) {
+	if inv.Agent {
+		return sshExecutionPlan{}, fmt.Errorf("ssh --agent only applies to VM targets")
+	}
+	if len(inv.Options) > 0 || inv.ForceProxy {
+		return sshExecutionPlan{}, fmt.Errorf("SSH options only apply to VM targets")
+	}
+	return sshExecutionPlan{
+		RPCShell: &rpcShellPlan{
+			Host:    host,
+			Target:  catchrpc.ExecTargetServiceShell,
+			Service: service,
+			Command: inv.Command,
+		},
+	}, nil
+}
+
+// vmAgentShellPlan runs the shell through catch and the guest agent, which
+// needs neither the ssh CLI nor a reachable guest sshd.
+func vmAgentShellPlan(host, service string, inv sshInvocation) (sshExecutionPlan, error) {
+	if len(inv.Options) > 0 || inv.ForceProxy {
+		return sshExecutionPlan{}, fmt.Errorf("ssh --agent does not take SSH options or --force-proxy")
+	}
+	return sshExecutionPlan{
+		RPCShell: &rpcShellPlan{
+			Host:    host,
+			Target:  catchrpc.ExecTargetVMAgentExec,
+			Service: service,
+			Command: inv.Command,
+		},
+	}, nil
+}
+
 func replaySSHStderr(buf *bytes.Buffer, stderr io.Writer) {
 	if buf == nil || buf.Len() == 0 {
 		return
@@ -352,21 +392,21 @@ func trimVMSSHProxyCommandName(args []string) []string {
 	return args
 }
 
-func splitYeetSSHFlags(args []string) (bool, []string, error) {
+func splitYeetSSHFlags(args []string) (yeetSSHFlags, []string, error) {
 	out := make([]string, 0, len(args))
-	forceProxy := false
+	var flags yeetSSHFlags
 	for i := 0; i < len(args); i++ {
 		token := args[i]
 		if token == "--" || token == "-" || !strings.HasPrefix(token, "-") {
 			out = append(out, args[i:]...)
-			return forceProxy, out, nil
+			return flags, out, nil
 		}
-		if token == "--force-proxy" {
-			forceProxy = true
-			continue
+		consumed, err := parseYeetSSHFlag(token, &flags)
+		if err != nil {
+			return yeetSSHFlags{}, nil, err
 		}
-		if strings.HasPrefix(token, "--force-proxy=") {
-			return false, nil, fmt.Errorf("ssh --force-proxy does not take a value")
+		if consumed {
+			continue
 		}
 		out = append(out, token)
 		if sshOptionNeedsArg(token) && len(token) == 2 && i+1 < len(args) {
@@ -374,7 +414,24 @@ func splitYeetSSHFlags(args []string) (bool, []string, error) {
 			i++
 		}
 	}
-	return forceProxy, out, nil
+	return flags, out, nil
+}
+
+func parseYeetSSHFlag(token string, flags *yeetSSHFlags) (bool, error) {
+	switch token {
+	case "--force-proxy":
+		flags.ForceProxy = true
+		return true, nil
+	case "--agent":
+		flags.Agent = true
+		return true, nil
+	}
+	for _, name := range []string{"--force-proxy", "--agent"} {
+		if strings.HasPrefix(token, name+"=") {
+			return false, fmt.Errorf("ssh %s does not take a value", name)
+		}
+	}
+	return false, nil
 }
 
 func sshTarget(host string, info serverInfo) string {
@@ -211,6 +211,57 @@ func TestSSHNonVMServiceUsesRPCShell(t *testing.T) {
 	}
 }
 
+func TestSSHAgentUsesVMAgentExecForVMs(t *testing.T) {
+	useTempSvcCwd(t)
+	oldFetchSvc := fetchSSHServiceInfoFunc
+	t.Cleanup(func() { fetchSSHServiceInfoFunc = oldFetchSvc })
+	fetchSSHServiceInfoFunc = func(ctx context.Context, host, service string) (catchrpc.ServiceInfoResponse, error) {
+		return catchrpc.ServiceInfoResponse{Found: true, Info: catchrpc.ServiceInfo{ServiceType: serviceTypeVM}}, nil
+	}
+
+	plan, err := sshExecutionPlanForArgs(context.Background(), []string{"ssh", "--agent", "devbox@yeet-lab", "--", "journalctl", "-b"})
+	if err != nil {
+		t.Fatalf("sshExecutionPlanForArgs: %v", err)
+	}
+	rpc := plan.RPCShell
+	if rpc == nil || rpc.Target != catchrpc.ExecTargetVMAgentExec || rpc.Service != "devbox" || !reflect.DeepEqual(rpc.Command, []string{"journalctl", "-b"}) {
+		t.Fatalf("plan = %#v, want vm agent exec", rpc)
+	}
+
+	for _, args := range [][]string{
+		{"ssh", "--agent", "-i", "key.pem", "devbox@yeet-lab"},
+		{"ssh", "--agent", "--force-proxy", "devbox@yeet-lab"},
+	} {
+		if _, err := sshExecutionPlanForArgs(context.Background(), args); err == nil || !strings.Contains(err.Error(), "--agent") {
+			t.Fatalf("%v: error = %v, want --agent options error", args, err)
+		}
+	}
+}
+
+func TestSSHAgentRejectedForNonVMTargets(t *testing.T) {
+	useTempSvcCwd(t)
+	oldPrefs := loadedPrefs
+	oldFetchSvc := fetchSSHServiceInfoFunc
+	t.Cleanup(func() {
+		loadedPrefs = oldPrefs
+		fetchSSHServiceInfoFunc = oldFetchSvc
+	})
+	loadedPrefs.DefaultHost = "yeet-lab"
+	fetchSSHServiceInfoFunc = func(ctx context.Context, host, service string) (catchrpc.ServiceInfoResponse, error) {
+		return catchrpc.ServiceInfoResponse{Found: true, Info: catchrpc.ServiceInfo{ServiceType: "docker-compose"}}, nil
+	}
+
+	for _, args := range [][]string{{"ssh", "--agent", "api"}, {"ssh", "--agent", "--", "whoami"}} {
+		_, err := sshExecutionPlanForArgs(context.Background(), args)
+		if err == nil || !strings.Contains(err.Error(), "only applies to VM targets") {
+			t.Fatalf("%v: error = %v, want VM-only error", args, err)
+		}
+	}
+	if _, err := sshInvocationFromArgs([]string{"ssh", "--agent=1", "devbox"}); err == nil || !strings.Contains(err.Error(), "does not take a value") {
+		t.Fatalf("error = %v, want --agent value error", err)
+	}
+}
+
 func TestSSHOptionsRejectedForRPCShell(t *testing.T) {
 	oldPrefs := loadedPrefs
 	oldFetchInfo := fetchSSHServerInfoFunc
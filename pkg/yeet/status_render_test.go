@@ -8,6 +8,8 @@ import (
 	"bytes"
 	"strings"
 	"testing"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
 )
 
 func TestRenderStatusTablesSortedWithHostColumn(t *testing.T) {
@@ -198,3 +200,36 @@ func TestRenderStatusTablesStylesTTYHeadingsOnly(t *testing.T) {
 		valueCursor = valueColumn + len(values[i])
 	}
 }
+
+func TestRenderStatusTableWideShowsVMMetrics(t *testing.T) {
+	results := []hostStatusData{{
+		Host: "host-a",
+		Services: []statusService{
+			{ServiceName: "devbox", ServiceType: "vm", Components: []statusComponent{{Name: "devbox", Status: "running"}}, VMMetrics: &catchrpc.ServiceVMMetrics{
+				CPU:     &catchrpc.ServiceVMCPU{Percent: 12.4},
+				Memory:  &catchrpc.ServiceVMUsage{Total: 2 << 30, Used: 1 << 30},
+				Network: &catchrpc.ServiceVMNetTraffic{RxBytes: 1 << 20, TxBytes: 1 << 10},
+			}},
+			{ServiceName: "web", ServiceType: "binary", Components: []statusComponent{{Name: "web", Status: "running"}}},
+		},
+	}}
+
+	var buf bytes.Buffer
+	if err := renderStatusTable(&buf, results, false, true); err != nil {
+		t.Fatalf("renderStatusTable: %v", err)
+	}
+	text := stripHeadingANSI(buf.String())
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("lines = %q", lines)
+	}
+	if got := strings.Join(strings.Fields(lines[0]), " "); !strings.HasSuffix(got, "CPU MEM DISK NET RX/TX") {
+		t.Fatalf("header = %q, want metrics columns", got)
+	}
+	if got := strings.Join(strings.Fields(lines[1]), " "); !strings.HasSuffix(got, "12% 1 GB/2 GB - 1 MB/1 KB") {
+		t.Fatalf("vm row = %q", got)
+	}
+	if got := strings.Join(strings.Fields(lines[2]), " "); !strings.HasSuffix(got, "- - - -") {
+		t.Fatalf("service row = %q", got)
+	}
+}
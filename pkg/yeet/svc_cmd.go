@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/shayne/yargs"
+	"github.com/yeetrun/yeet/pkg/catchrpc"
 	"github.com/yeetrun/yeet/pkg/cli"
 	"github.com/yeetrun/yeet/pkg/cmdutil"
 	"github.com/yeetrun/yeet/pkg/copyutil"
@@ -337,6 +338,9 @@ func handleSvcVM(ctx context.Context, req svcCommandRequest) error {
 	if svcCommandMatches(args, "vm", "set") {
 		return handleVMSet(ctx, req)
 	}
+	if svcCommandMatches(args, "vm", "migrate") {
+		return handleVMMigrate(ctx, req)
+	}
 	return handleSvcRemote(ctx, req)
 }
 
@@ -525,6 +529,8 @@ func parseSvcRun(cmdArgs []string, cfgLoc *projectConfigLocation, hostOverride s
 	if err != nil {
 		return parsedSvcRun{}, err
 	}
+	effectiveArgs = runArgsWithConfiguredUserData(effectiveArgs, entry, hasEntry, cfgLoc)
+	effectiveArgs = runArgsWithConfiguredComposeProject(effectiveArgs, entry, hasEntry, cfgLoc)
 	if err := ensureSvcRunEntryFlags(entry, hasEntry, effectiveArgs); err != nil {
 		return parsedSvcRun{}, err
 	}
@@ -948,6 +954,12 @@ func handleSvcService(ctx context.Context, req svcCommandRequest) error {
 		return handleServiceSync(ctx, req)
 	case "set":
 		return handleServiceSet(ctx, req)
+	case "export":
+		return handleServiceExport(ctx, req)
+	case "import":
+		return handleServiceImport(ctx, req)
+	case "move":
+		return handleServiceMove(ctx, req)
 	default:
 		return handleSvcRemote(ctx, req)
 	}
@@ -1499,6 +1511,10 @@ func tryRunVMPayloadContextWithOutput(ctx context.Context, stdout io.Writer, pay
 	if len(payloadArgs) != 0 {
 		return true, fmt.Errorf("VM payloads do not accept payload args")
 	}
+	flagArgs, err := encodeRunUserDataArgs(flagArgs)
+	if err != nil {
+		return true, err
+	}
 	remoteArgs := append([]string{"run"}, flagArgs...)
 	remoteArgs = append(remoteArgs, payload)
 	if isStdoutWriter(stdout) {
@@ -1527,6 +1543,14 @@ func tryRunDockerfileContextWithOutput(ctx context.Context, stdout io.Writer, pa
 	} else if err != nil {
 		return false, err
 	}
+	build, err := runArgsBuildMode(args)
+	if err != nil {
+		return true, err
+	}
+	if build == cli.RunBuildRemote {
+		return true, runRemoteDockerBuild(ctx, stdout, path, args)
+	}
+	args = removeRunBuildFlag(args)
 	svc := getService()
 	tag := fmt.Sprintf("yeet-build-%d", time.Now().UnixNano())
 	imageName := fmt.Sprintf("%s:%s", svc, tag)
@@ -1538,7 +1562,6 @@ func tryRunDockerfileContextWithOutput(ctx context.Context, stdout io.Writer, pa
 		return true, err
 	}
 	var runOK bool
-	var err error
 	if isStdoutWriter(stdout) {
 		runOK, err = tryRunDockerFn(ctx, imageName, args)
 	} else {
@@ -1556,6 +1579,10 @@ const imageComposeTemplate = `services:
       - "./:/data"
 `
 
+func imageComposeContent(svc, image string) string {
+	return fmt.Sprintf(imageComposeTemplate, svc, image)
+}
+
 func tryRunRemoteImage(image string, args []string) (ok bool, _ error) {
 	return tryRunRemoteImageContext(context.Background(), image, args)
 }
@@ -1579,7 +1606,7 @@ func tryRunRemoteImageContextWithOutput(ctx context.Context, stdout io.Writer, i
 		}
 	}()
 	composePath := filepath.Join(tmpDir, "compose.yml")
-	content := fmt.Sprintf(imageComposeTemplate, svc, image)
+	content := imageComposeContent(svc, image)
 	if err := os.WriteFile(composePath, []byte(content), 0o644); err != nil {
 		return true, err
 	}
@@ -1634,6 +1661,8 @@ type runFileUpload struct {
 	ft      ftdetect.FileType
 	goos    string
 	goarch  string
+	// args are the run args to send, with compose project files inlined.
+	args []string
 }
 
 func runFilePayload(file string, args []string, pushLocalImages bool) (ok bool, _ error) {
@@ -1657,9 +1686,9 @@ func runFilePayloadContextWithOutput(ctx context.Context, stdout io.Writer, file
 	}
 	var runErr error
 	if isStdoutWriter(stdout) {
-		runErr = execRunFilePayload(ctx, svc, upload.payload, args)
+		runErr = execRunFilePayload(ctx, svc, upload.payload, upload.args)
 	} else {
-		runErr = execRunFilePayloadWithOutputFn(ctx, stdout, svc, upload.payload, args)
+		runErr = execRunFilePayloadWithOutputFn(ctx, stdout, svc, upload.payload, upload.args)
 	}
 	if runErr != nil {
 		return false, runErr
@@ -1680,17 +1709,29 @@ func prepareRunFileUpload(file string, args []string, pushLocalImages bool) (run
 		cleanup()
 		return runFileUpload{}, err
 	}
+	args, err = encodeRunComposeProjectArgs(args)
+	if err != nil {
+		cleanup()
+		return runFileUpload{}, err
+	}
 	return runFileUpload{
 		payload: payload,
 		cleanup: cleanup,
 		ft:      ft,
 		goos:    goos,
 		goarch:  goarch,
+		args:    args,
 	}, nil
 }
 
 func validateRunFileArgs(ft ftdetect.FileType, args []string, pushLocalImages bool) error {
+	if runArgsHaveFlag(args, runBuildFlag) {
+		return fmt.Errorf("--build only applies to Dockerfile payloads")
+	}
 	if ft != ftdetect.DockerCompose {
+		if runArgsHaveComposeProject(args) {
+			return fmt.Errorf("--compose-file, --compose-env-file, and --profile are only valid for docker compose payloads")
+		}
 		return nil
 	}
 	flags, _, err := cli.ParseRun(args)
@@ -2007,9 +2048,11 @@ type hostStatusData struct {
 }
 
 type statusService struct {
-	ServiceName string            `json:"serviceName"`
-	ServiceType string            `json:"serviceType"`
-	Components  []statusComponent `json:"components"`
+	ServiceName string                     `json:"serviceName"`
+	ServiceType string                     `json:"serviceType"`
+	Components  []statusComponent          `json:"components"`
+	Storage     *catchrpc.ServiceStorage   `json:"storage,omitempty"`
+	VMMetrics   *catchrpc.ServiceVMMetrics `json:"vmMetrics,omitempty"`
 }
 
 type statusComponent struct {
@@ -2029,7 +2072,7 @@ func handleStatusCommand(ctx context.Context, args []string, cfgLoc *projectConf
 		return statusMultiHost(ctx, statusHosts(cfgLoc, hostOverrideSet), flags)
 	}
 	if shouldRenderStatusTable(flags.Format) && serviceOverride != "" {
-		return renderStatusTableForService(ctx, Host(), serviceOverride)
+		return renderStatusTableForService(ctx, Host(), serviceOverride, flags)
 	}
 	svc := getService()
 	statusArgs := append([]string{"status"}, args...)
@@ -2215,11 +2258,19 @@ func renderStatusResults(w io.Writer, results []hostStatusData, flags cli.Status
 		}
 		return enc.Encode(results)
 	}
-	return renderStatusTables(w, results, aggregateContainers)
+	return renderStatusTable(w, results, aggregateContainers, flags.Wide)
 }
 
-func fetchStatusForHost(ctx context.Context, host string, _ cli.StatusFlags) ([]statusService, error) {
+func statusRemoteArgs(flags cli.StatusFlags) []string {
 	args := []string{"status", "--format=json"}
+	if flags.Wide {
+		args = append(args, "--wide")
+	}
+	return args
+}
+
+func fetchStatusForHost(ctx context.Context, host string, flags cli.StatusFlags) ([]statusService, error) {
+	args := statusRemoteArgs(flags)
 	payload, err := execRemoteOutputFn(ctx, host, systemServiceName, args, nil)
 	if err != nil {
 		return nil, fmt.Errorf("status on %s: %w", host, err)
@@ -2231,9 +2282,8 @@ func fetchStatusForHost(ctx context.Context, host string, _ cli.StatusFlags) ([]
 	return statuses, nil
 }
 
-func renderStatusTableForService(ctx context.Context, host, service string) error {
-	args := []string{"status", "--format=json"}
-	payload, err := execRemoteOutputFn(ctx, host, service, args, nil)
+func renderStatusTableForService(ctx context.Context, host, service string, flags cli.StatusFlags) error {
+	payload, err := execRemoteOutputFn(ctx, host, service, statusRemoteArgs(flags), nil)
 	if err != nil {
 		return err
 	}
@@ -2241,7 +2291,7 @@ func renderStatusTableForService(ctx context.Context, host, service string) erro
 	if err := json.Unmarshal(payload, &statuses); err != nil {
 		return fmt.Errorf("status on %s returned invalid JSON: %w", host, err)
 	}
-	return renderStatusTables(os.Stdout, []hostStatusData{{Host: host, Services: statuses}}, false)
+	return renderStatusTable(os.Stdout, []hostStatusData{{Host: host, Services: statuses}}, false, flags.Wide)
 }
 
 const statusContainersMaxWidth = 32
@@ -2252,6 +2302,12 @@ type statusRow struct {
 	Type       string
 	Containers string
 	Status     string
+	Used       string
+	Available  string
+	CPU        string
+	Memory     string
+	Disk       string
+	Network    string
 }
 
 func buildStatusRows(results []hostStatusData, aggregateContainers bool) []statusRow {
@@ -2278,22 +2334,22 @@ func buildStatusRows(results []hostStatusData, aggregateContainers bool) []statu
 
 func buildStatusRowsForService(host string, status statusService, aggregateContainers bool) []statusRow {
 	if aggregateContainers && status.ServiceType == dockerServiceType {
-		return []statusRow{{
+		return []statusRow{statusRow{
 			Host:       host,
 			Service:    status.ServiceName,
 			Type:       status.ServiceType,
 			Containers: truncateStatusContainers(formatStatusContainers(status.Components)),
 			Status:     dockerAggregateStatus(status.Components),
-		}}
+		}.withStorage(status.Storage).withVMMetrics(status.VMMetrics)}
 	}
 	if len(status.Components) == 0 {
-		return []statusRow{{
+		return []statusRow{statusRow{
 			Host:       host,
 			Service:    status.ServiceName,
 			Type:       status.ServiceType,
 			Containers: "-",
 			Status:     "unknown",
-		}}
+		}.withStorage(status.Storage).withVMMetrics(status.VMMetrics)}
 	}
 	rows := make([]statusRow, 0, len(status.Components))
 	for _, component := range status.Components {
@@ -2307,22 +2363,74 @@ func buildStatusRowsForService(host string, status statusService, aggregateConta
 			Type:       status.ServiceType,
 			Containers: container,
 			Status:     component.Status,
-		})
+		}.withStorage(status.Storage).withVMMetrics(status.VMMetrics))
 	}
 	return rows
 }
 
+// withStorage fills the disk columns. Catch omits storage for plain service
+// roots without a quota because measuring them means walking the tree.
+func (r statusRow) withStorage(storage *catchrpc.ServiceStorage) statusRow {
+	r.Used, r.Available = "-", "-"
+	if storage != nil {
+		r.Used = formatStorageBytes(storage.Used)
+		r.Available = formatStorageBytes(storage.Available)
+	}
+	return r
+}
+
+// withVMMetrics fills the --wide columns. Only running VMs have metrics.
+func (r statusRow) withVMMetrics(metrics *catchrpc.ServiceVMMetrics) statusRow {
+	if metrics == nil {
+		return r
+	}
+	r.CPU, r.Network = "-", "-"
+	if metrics.CPU != nil {
+		r.CPU = fmt.Sprintf("%.0f%%", metrics.CPU.Percent)
+	}
+	r.Memory = formatStatusVMUsage(metrics.Memory)
+	r.Disk = formatStatusVMUsage(metrics.Disk)
+	if metrics.Network != nil {
+		r.Network = formatStorageBytes(metrics.Network.RxBytes) + "/" + formatStorageBytes(metrics.Network.TxBytes)
+	}
+	return r
+}
+
+func formatStatusVMUsage(usage *catchrpc.ServiceVMUsage) string {
+	if usage == nil {
+		return "-"
+	}
+	return formatStorageBytes(usage.Used) + "/" + formatStorageBytes(usage.Total)
+}
+
 func renderStatusTables(w io.Writer, results []hostStatusData, aggregateContainers bool) error {
+	return renderStatusTable(w, results, aggregateContainers, false)
+}
+
+func renderStatusTable(w io.Writer, results []hostStatusData, aggregateContainers, wide bool) error {
 	rows := buildStatusRows(results, aggregateContainers)
 	header := "CONTAINER"
 	if aggregateContainers {
 		header = "CONTAINERS"
 	}
+	headers := []string{"SERVICE", "HOST", "TYPE", header, "STATUS", "USED", "AVAIL"}
+	if wide {
+		headers = append(headers, "CPU", "MEM", "DISK", "NET RX/TX")
+	}
 	tableRows := make([][]string, 0, len(rows))
 	for _, row := range rows {
-		tableRows = append(tableRows, []string{row.Service, row.Host, row.Type, row.Containers, row.Status})
+		cells := []string{row.Service, row.Host, row.Type, row.Containers, row.Status, row.Used, row.Available}
+		if wide {
+			for _, metric := range []string{row.CPU, row.Memory, row.Disk, row.Network} {
+				if metric == "" {
+					metric = "-"
+				}
+				cells = append(cells, metric)
+			}
+		}
+		tableRows = append(tableRows, cells)
 	}
-	return renderOutputTable(w, []string{"SERVICE", "HOST", "TYPE", header, "STATUS"}, tableRows)
+	return renderOutputTable(w, headers, tableRows)
 }
 
 func dockerAggregateStatus(components []statusComponent) string {
@@ -2570,14 +2678,23 @@ func prepareRunConfigEntry(loc *projectConfigLocation, host, payload, payloadKin
 	if err != nil {
 		return ServiceEntry{}, ServiceEntry{}, false, false, err
 	}
+	userData, filteredArgs, err := runConfigUserData(loc.Dir, filteredArgs)
+	if err != nil {
+		return ServiceEntry{}, ServiceEntry{}, false, false, err
+	}
 	entryType, payloadKind := runConfigEntryType(payload, payloadKind)
 	payloadRel := relativePayloadPathForKind(loc.Dir, payload, payloadKind)
+	compose, composeEnvFiles, profiles, filteredArgs, err := runConfigComposeProject(loc.Dir, payloadRel, filteredArgs)
+	if err != nil {
+		return ServiceEntry{}, ServiceEntry{}, false, false, err
+	}
 	entry := ServiceEntry{
 		Name:           serviceOverride,
 		Host:           host,
 		Type:           entryType,
 		Payload:        payloadRel,
 		PayloadKind:    runConfigPersistedPayloadKind(schedule, payloadKind),
+		UserData:       userData,
 		RunAs:          runFlags.RunAs,
 		ServiceRoot:    strings.TrimSpace(serviceRoot),
 		ServiceRootZFS: serviceRootZFS,
@@ -2585,6 +2702,7 @@ func prepareRunConfigEntry(loc *projectConfigLocation, host, payload, payloadKin
 		Schedule:       strings.TrimSpace(schedule),
 		Args:           normalizeArgs(filteredArgs),
 	}
+	entry.Compose, entry.ComposeEnvFiles, entry.Profiles = compose, composeEnvFiles, profiles
 	applyRunConfigSandboxFields(&entry, existing, hasExisting, sandbox, sandboxCaptured)
 	applyRunConfigSnapshotFields(&entry, existing, hasExisting, snapOpts, snapshotChange)
 	return entry, existing, hasExisting, sandboxCaptured, nil
@@ -2814,11 +2932,20 @@ func saveVMSetConfig(cfgLoc *projectConfigLocation, hostOverride string, flags c
 
 func applyVMSetConfigFlags(entry *ServiceEntry, flags cli.VMSetFlags) bool {
 	removals, updates := vmSetRunFlagChanges(flags)
-	if len(removals) == 0 || !serviceEntryIsVM(*entry) {
+	publishChanged := len(flags.Publish) != 0 || flags.PublishReset
+	if (len(removals) == 0 && !publishChanged) || !serviceEntryIsVM(*entry) {
 		return false
 	}
-	entry.Args = canonicalizeStoredVMRunArgs(entry.Args)
-	entry.Args = rewriteStoredRunArgs(entry.Args, removals, updates)
+	if len(removals) != 0 {
+		entry.Args = canonicalizeStoredVMRunArgs(entry.Args)
+		entry.Args = rewriteStoredRunArgs(entry.Args, removals, updates)
+	}
+	if publishChanged {
+		if _, rest, err := extractPublishOptions(entry.Args); err == nil {
+			entry.Args = rest
+		}
+		entry.Ports = normalizePublishPorts(flags.Publish)
+	}
 	return true
 }
 
@@ -496,6 +496,7 @@ func TestHandleSvcCmdRunWebInvalidBoolReturnsParseError(t *testing.T) {
 }
 
 func TestSvcRunWebFlagAfterTerminatorDoesNotTriggerGuard(t *testing.T) {
+	stubRemoteArtifactImages(t, catchrpc.ArtifactHashesResponse{})
 	preserveSvcCommandGlobals(t)
 	useTempSvcCwd(t)
 	serviceOverride = "svc-a"
@@ -621,6 +622,7 @@ func TestSvcRunZFSServiceRoot(t *testing.T) {
 }
 
 func TestSvcRunPreservesServiceRootPayloadArgsInSavedConfig(t *testing.T) {
+	stubRemoteArtifactImages(t, catchrpc.ArtifactHashesResponse{})
 	preserveSvcCommandGlobals(t)
 	tmp := useTempSvcCwd(t)
 	serviceOverride = "svc-a"
@@ -1507,6 +1509,24 @@ func TestVMSetConfigRewritesBalloonRunFlags(t *testing.T) {
 	}
 }
 
+func TestVMSetConfigStoresPublishedPorts(t *testing.T) {
+	entry := ServiceEntry{
+		Name:  "web",
+		Type:  serviceTypeVM,
+		Args:  []string{"--memory=2g", "-p", "80:80", "vm://ubuntu/26.04"},
+		Ports: []string{"80:80"},
+	}
+	if !applyVMSetConfigFlags(&entry, cli.VMSetFlags{Publish: []string{"8080:80"}, PublishReset: true}) {
+		t.Fatal("applyVMSetConfigFlags reported no change")
+	}
+	if want := []string{"--memory=2g", "vm://ubuntu/26.04"}; !reflect.DeepEqual(entry.Args, want) {
+		t.Fatalf("args = %#v, want %#v", entry.Args, want)
+	}
+	if want := []string{"8080:80"}; !reflect.DeepEqual(entry.Ports, want) {
+		t.Fatalf("ports = %#v, want %#v", entry.Ports, want)
+	}
+}
+
 func TestVMSetFlagsDoNotUpdateNonVMRunArgs(t *testing.T) {
 	preserveSvcCommandGlobals(t)
 	tmp := useTempSvcCwd(t)
@@ -1897,6 +1917,7 @@ func TestSvcRunSnapshotInheritWithFieldFlagDoesNotRunRemoteOrSaveConfig(t *testi
 }
 
 func TestSvcRunSnapshotFieldInheritRunsRemoteAndSavesConfig(t *testing.T) {
+	stubRemoteArtifactImages(t, catchrpc.ArtifactHashesResponse{})
 	preserveSvcCommandGlobals(t)
 	tmp := useTempSvcCwd(t)
 	serviceOverride = "svc-a"
@@ -3175,13 +3196,13 @@ func TestSvcHandleStatusRemoteAndParseErrors(t *testing.T) {
 	execRemoteOutputFn = func(ctx context.Context, host string, service string, args []string, stdin io.Reader) ([]byte, error) {
 		return nil, errors.New("status failed")
 	}
-	if err := renderStatusTableForService(context.Background(), "host-a", "svc-a"); err == nil || !strings.Contains(err.Error(), "status failed") {
+	if err := renderStatusTableForService(context.Background(), "host-a", "svc-a", cli.StatusFlags{}); err == nil || !strings.Contains(err.Error(), "status failed") {
 		t.Fatalf("renderStatusTableForService remote error = %v", err)
 	}
 	execRemoteOutputFn = func(ctx context.Context, host string, service string, args []string, stdin io.Reader) ([]byte, error) {
 		return []byte(`bad-json`), nil
 	}
-	if err := renderStatusTableForService(context.Background(), "host-a", "svc-a"); err == nil || !strings.Contains(err.Error(), "invalid JSON") {
+	if err := renderStatusTableForService(context.Background(), "host-a", "svc-a", cli.StatusFlags{}); err == nil || !strings.Contains(err.Error(), "invalid JSON") {
 		t.Fatalf("renderStatusTableForService JSON error = %v", err)
 	}
 }
@@ -7,6 +7,8 @@ package yeet
 import (
 	"reflect"
 	"testing"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
 )
 
 func TestSplitRunPayloadArgsPreservesFlagScanningBehavior(t *testing.T) {
@@ -98,15 +100,15 @@ func TestBuildStatusRowsHandlesAggregateAndEmptyServices(t *testing.T) {
 				{ServiceName: "svc-docker", ServiceType: dockerServiceType, Components: []statusComponent{
 					{Name: "web", Status: "running"},
 					{Name: "worker", Status: "stopped"},
-				}},
+				}, Storage: &catchrpc.ServiceStorage{Used: 1 << 30, Available: 19 << 30}},
 			},
 		},
 	}
 
 	got := buildStatusRows(results, true)
 	want := []statusRow{
-		{Host: "host-b", Service: "svc-docker", Type: dockerServiceType, Containers: "web,worker", Status: "partial (1/2)"},
-		{Host: "host-b", Service: "svc-empty", Type: "service", Containers: "-", Status: "unknown"},
+		{Host: "host-b", Service: "svc-docker", Type: dockerServiceType, Containers: "web,worker", Status: "partial (1/2)", Used: "1 GB", Available: "19 GB"},
+		{Host: "host-b", Service: "svc-empty", Type: "service", Containers: "-", Status: "unknown", Used: "-", Available: "-"},
 	}
 	if !reflect.DeepEqual(got, want) {
 		t.Fatalf("rows = %#v, want %#v", got, want)
@@ -0,0 +1,143 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yeet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+	"github.com/yeetrun/yeet/pkg/cli"
+)
+
+var fetchServiceRootDefaultsForMigrateFn = func(ctx context.Context, host, service string) (catchrpc.ServiceRootDefaultsResponse, error) {
+	return newRPCClient(host).ServiceRootDefaults(ctx, catchrpc.ServiceRootDefaultsRequest{Service: service})
+}
+
+type vmMigration struct {
+	serviceMove
+	// ZFS is set when both hosts keep VM disks on ZFS, so disks travel as
+	// zfs streams instead of sparse raw copies.
+	ZFS bool
+}
+
+func handleVMMigrate(ctx context.Context, req svcCommandRequest) error {
+	args := append(append([]string{}, req.Command.Args[1:]...), req.Service)
+	flags, _, err := cli.ParseVMMigrate(args)
+	if err != nil {
+		return err
+	}
+	migration := vmMigration{serviceMove: serviceMove{Service: req.Service, From: Host(), To: flags.To}}
+	if migration.From == migration.To {
+		return fmt.Errorf("VM %q is already on %s", migration.Service, migration.To)
+	}
+	if migration.ZFS, err = checkVMMigrateHosts(ctx, migration.serviceMove); err != nil {
+		return err
+	}
+	if migration.Running, err = serviceRunningOnHost(ctx, migration.From, migration.Service); err != nil {
+		return err
+	}
+	if err := runVMMigrate(ctx, migration); err != nil {
+		return err
+	}
+	return finishServiceMove(ctx, req.Config, migration.serviceMove)
+}
+
+// checkVMMigrateHosts checks that the VM exists only on the source and
+// reports whether its disks can be sent as zfs streams.
+func checkVMMigrateHosts(ctx context.Context, move serviceMove) (bool, error) {
+	source, err := fetchServiceInfoForMoveFn(ctx, move.From, move.Service)
+	if err != nil {
+		return false, fmt.Errorf("inspect %s on %s: %w", move.Service, move.From, err)
+	}
+	if !source.Found {
+		return false, fmt.Errorf("VM %q not found on %s", move.Service, move.From)
+	}
+	if source.Info.ServiceType != serviceTypeVM {
+		return false, fmt.Errorf("service %q is not a VM; use yeet service move", move.Service)
+	}
+	target, err := fetchServiceInfoForMoveFn(ctx, move.To, move.Service)
+	if err != nil {
+		return false, fmt.Errorf("inspect %s on %s: %w", move.Service, move.To, err)
+	}
+	if target.Found {
+		return false, fmt.Errorf("service %q already exists on %s", move.Service, move.To)
+	}
+	if source.Info.VM == nil || source.Info.VM.DiskBackend != "zvol" {
+		return false, nil
+	}
+	defaults, err := fetchServiceRootDefaultsForMigrateFn(ctx, move.To, move.Service)
+	if err != nil {
+		return false, fmt.Errorf("inspect storage on %s: %w", move.To, err)
+	}
+	return defaults.ZFS, nil
+}
+
+// runVMMigrate copies the disks, then recreates and starts the VM on the
+// target, which waits for guest_ready. Any failure removes the partial
+// target and restores the source.
+func runVMMigrate(ctx context.Context, migration vmMigration) error {
+	err := transferVMMigrateDisks(ctx, migration)
+	if err == nil {
+		printServiceMoveStep("starting %s on %s", migration.Service, migration.To)
+		if _, err = execRemoteOutputFn(ctx, migration.To, migration.Service, []string{"vm", "migrate", cli.VMMigrateStepFinish}, nil); err != nil {
+			err = fmt.Errorf("start %s on %s: %w", migration.Service, migration.To, err)
+		}
+	}
+	if err == nil {
+		return nil
+	}
+	return errors.Join(err, rollbackVMMigrate(ctx, migration))
+}
+
+// transferVMMigrateDisks keeps the downtime short on ZFS: a full pre-copy runs
+// while the VM is still up, so only the blocks written since then are sent
+// after it stops.
+func transferVMMigrateDisks(ctx context.Context, migration vmMigration) error {
+	receive := []string{"vm", "migrate", cli.VMMigrateStepReceive}
+	precopy := migration.Running && migration.ZFS
+	if precopy {
+		printServiceMoveStep("pre-copying %s disks from %s to %s", migration.Service, migration.From, migration.To)
+		if err := pipeServiceMoveStream(ctx, migration.serviceMove, vmMigrateSendArgs(migration.ZFS, true, false), receive); err != nil {
+			return err
+		}
+	}
+	if migration.Running {
+		printServiceMoveStep("stopping %s on %s", migration.Service, migration.From)
+		if _, err := execRemoteOutputFn(ctx, migration.From, migration.Service, []string{"stop"}, nil); err != nil {
+			return fmt.Errorf("stop %s on %s: %w", migration.Service, migration.From, err)
+		}
+	}
+	printServiceMoveStep("copying %s disks from %s to %s", migration.Service, migration.From, migration.To)
+	return pipeServiceMoveStream(ctx, migration.serviceMove, vmMigrateSendArgs(migration.ZFS, false, precopy), receive)
+}
+
+func vmMigrateSendArgs(zfs, precopy, incremental bool) []string {
+	args := []string{"vm", "migrate", cli.VMMigrateStepSend}
+	if zfs {
+		args = append(args, "--zfs")
+	}
+	if precopy {
+		args = append(args, "--precopy")
+	}
+	if incremental {
+		args = append(args, "--incremental")
+	}
+	return args
+}
+
+// rollbackVMMigrate removes the partial target VM and restarts the source,
+// then drops the staged disks and migration snapshots on both hosts.
+func rollbackVMMigrate(ctx context.Context, migration vmMigration) error {
+	errs := []error{rollbackServiceMove(ctx, migration.serviceMove)}
+	abort := []string{"vm", "migrate", cli.VMMigrateStepAbort}
+	for _, host := range []string{migration.To, migration.From} {
+		if _, err := execRemoteOutputFn(ctx, host, migration.Service, abort, nil); err != nil {
+			errs = append(errs, fmt.Errorf("clean up migration of %s on %s: %w", migration.Service, host, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yeet
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yeetrun/yeet/pkg/catchrpc"
+)
+
+// newFakeVMMigrateHosts extends the service move fake with a VM on host-a
+// and the catch-side `vm migrate finish` and `abort` steps.
+func newFakeVMMigrateHosts(t *testing.T, diskBackend string, targetZFS bool, finishErr error) *fakeServiceMoveHosts {
+	hosts := newFakeServiceMoveHosts(t)
+	hosts.types["host-a"] = serviceTypeVM
+	oldDefaults := fetchServiceRootDefaultsForMigrateFn
+	t.Cleanup(func() { fetchServiceRootDefaultsForMigrateFn = oldDefaults })
+	fetchServiceRootDefaultsForMigrateFn = func(context.Context, string, string) (catchrpc.ServiceRootDefaultsResponse, error) {
+		return catchrpc.ServiceRootDefaultsResponse{ZFS: targetZFS}, nil
+	}
+	fetchServiceInfoForMoveFn = func(_ context.Context, host, _ string) (catchrpc.ServiceInfoResponse, error) {
+		return catchrpc.ServiceInfoResponse{
+			Found: hosts.found[host],
+			Info:  catchrpc.ServiceInfo{ServiceType: hosts.types[host], VM: &catchrpc.ServiceVM{DiskBackend: diskBackend}},
+		}, nil
+	}
+	execRemoteOutputFn = func(ctx context.Context, host, service string, args []string, stdin io.Reader) ([]byte, error) {
+		if strings.Join(args, " ") != "vm migrate finish" {
+			return hosts.exec(ctx, host, service, args, stdin)
+		}
+		hosts.calls = append(hosts.calls, host+" vm migrate finish")
+		hosts.found[host] = true
+		if finishErr != nil {
+			return nil, finishErr
+		}
+		hosts.running[host] = true
+		return nil, nil
+	}
+	return hosts
+}
+
+func vmMigrateRequest() svcCommandRequest {
+	return svcCommandRequest{
+		Service: "svc-a",
+		Command: svcCommand{Name: "vm", Args: []string{"migrate", "--to=host-b"}, RawArgs: []string{"vm", "migrate", "--to=host-b"}},
+	}
+}
+
+func TestHandleVMMigratePrecopiesZFSDisksBeforeStopping(t *testing.T) {
+	hosts := newFakeVMMigrateHosts(t, "zvol", true, nil)
+
+	err := withTemporaryHost("host-a", func() error {
+		return handleVMMigrate(context.Background(), vmMigrateRequest())
+	})
+	if err != nil {
+		t.Fatalf("handleVMMigrate: %v", err)
+	}
+	want := []string{
+		"host-a status --format=json",
+		"host-a vm migrate send --zfs --precopy",
+		"host-b vm migrate receive",
+		"host-a stop",
+		"host-a vm migrate send --zfs --incremental",
+		"host-b vm migrate receive",
+		"host-b vm migrate finish",
+		"host-a remove --yes --clean-data",
+	}
+	if !reflect.DeepEqual(hosts.calls, want) {
+		t.Fatalf("calls = %q, want %q", hosts.calls, want)
+	}
+}
+
+func TestHandleVMMigrateSendsRawDisksOnce(t *testing.T) {
+	hosts := newFakeVMMigrateHosts(t, "raw", true, nil)
+	hosts.running["host-a"] = false
+
+	err := withTemporaryHost("host-a", func() error {
+		return handleVMMigrate(context.Background(), vmMigrateRequest())
+	})
+	if err != nil {
+		t.Fatalf("handleVMMigrate: %v", err)
+	}
+	want := []string{
+		"host-a status --format=json",
+		"host-a vm migrate send",
+		"host-b vm migrate receive",
+		"host-b vm migrate finish",
+		"host-a remove --yes --clean-data",
+	}
+	if !reflect.DeepEqual(hosts.calls, want) {
+		t.Fatalf("calls = %q, want %q", hosts.calls, want)
+	}
+}
+
+func TestHandleVMMigrateRollsBackFailedStart(t *testing.T) {
+	hosts := newFakeVMMigrateHosts(t, "raw", false, errors.New("guest never ready"))
+
+	err := withTemporaryHost("host-a", func() error {
+		return handleVMMigrate(context.Background(), vmMigrateRequest())
+	})
+	if err == nil || !strings.Contains(err.Error(), "guest never ready") {
+		t.Fatalf("handleVMMigrate error = %v, want start failure", err)
+	}
+	want := []string{
+		"host-a status --format=json",
+		"host-a stop",
+		"host-a vm migrate send",
+		"host-b vm migrate receive",
+		"host-b vm migrate finish",
+		"host-b remove --yes --clean-data",
+		"host-a start",
+		"host-b vm migrate abort",
+		"host-a vm migrate abort",
+	}
+	if !reflect.DeepEqual(hosts.calls, want) {
+		t.Fatalf("calls = %q, want %q", hosts.calls, want)
+	}
+	if !hosts.running["host-a"] || hosts.found["host-b"] {
+		t.Fatalf("rollback state: source running=%v target found=%v", hosts.running["host-a"], hosts.found["host-b"])
+	}
+}
+
+func TestHandleVMMigrateRejectsInvalidMoves(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(*fakeServiceMoveHosts)
+		to    string
+		want  string
+	}{
+		{name: "same host", to: "host-a", want: "already on host-a"},
+		{name: "missing source", setup: func(f *fakeServiceMoveHosts) { f.found["host-a"] = false }, want: "not found on host-a"},
+		{name: "not a vm", setup: func(f *fakeServiceMoveHosts) { f.types["host-a"] = serviceTypeRun }, want: "use yeet service move"},
+		{name: "target exists", setup: func(f *fakeServiceMoveHosts) { f.found["host-b"] = true }, want: "already exists on host-b"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			hosts := newFakeVMMigrateHosts(t, "raw", false, nil)
+			if tc.setup != nil {
+				tc.setup(hosts)
+			}
+			req := vmMigrateRequest()
+			if tc.to != "" {
+				req.Command.Args = []string{"migrate", "--to=" + tc.to}
+			}
+			err := withTemporaryHost("host-a", func() error {
+				return handleVMMigrate(context.Background(), req)
+			})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("handleVMMigrate error = %v, want %q", err, tc.want)
+			}
+			if len(hosts.calls) != 0 {
+				t.Fatalf("calls = %q, want none", hosts.calls)
+			}
+		})
+	}
+}
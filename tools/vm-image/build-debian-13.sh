@@ -0,0 +1,86 @@
+#!/usr/bin/env bash
+# Copyright (c) 2025 AUTHORS All rights reserved.
+# Use of this source code is governed by a BSD-style
+# license that can be found in the LICENSE file.
+
+set -euo pipefail
+
+distro="debian"
+distro_version="13"
+default_user="debian"
+version="${YEET_VM_IMAGE_VERSION:-debian-13-amd64-dev}"
+out_dir="${1:-dist/$version}"
+work_dir="${YEET_VM_IMAGE_WORK_DIR:-}"
+debian_suite="${DEBIAN_SUITE:-trixie}"
+debian_mirror="${DEBIAN_MIRROR:-https://deb.debian.org/debian}"
+
+# shellcheck source=bundle-lib.sh
+. "$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)/bundle-lib.sh"
+
+require debootstrap
+require_fast_inputs
+setup_work_dir
+
+create_rootfs "$out_dir/rootfs.ext4"
+
+echo "Bootstrapping Debian $debian_suite..."
+debootstrap --variant=minbase \
+	--include=systemd,systemd-sysv,systemd-resolved,systemd-timesyncd,dbus,openssh-server,sudo,iproute2,iptables,nftables,rsync,ca-certificates,curl,less,procps,bash-completion,locales \
+	"$debian_suite" "$rootfs_mount" "$debian_mirror"
+mount_rootfs_api_filesystems
+
+write_fast_rootfs_common_files "$rootfs_mount"
+install -d -m 0755 "$rootfs_mount/etc/apt/preferences.d"
+cat >"$rootfs_mount/etc/apt/preferences.d/99-yeet-managed-kernel" <<'EOF'
+Package: linux-image-* linux-headers-* grub-* shim-signed initramfs-tools initramfs-tools-* dracut dracut-*
+Pin: version *
+Pin-Priority: -1
+EOF
+cat >"$rootfs_mount/etc/apt/sources.list.d/debian.sources" <<EOF
+Types: deb
+URIs: $debian_mirror
+Suites: $debian_suite $debian_suite-updates
+Components: main
+Signed-By: /usr/share/keyrings/debian-archive-keyring.gpg
+
+Types: deb
+URIs: https://security.debian.org/debian-security
+Suites: $debian_suite-security
+Components: main
+Signed-By: /usr/share/keyrings/debian-archive-keyring.gpg
+EOF
+rm -f "$rootfs_mount/etc/apt/sources.list"
+cat >"$rootfs_mount/usr/sbin/policy-rc.d" <<'EOF'
+#!/bin/sh
+exit 101
+EOF
+chmod +x "$rootfs_mount/usr/sbin/policy-rc.d"
+
+chroot "$rootfs_mount" /bin/sh <<'EOF'
+set -e
+export DEBIAN_FRONTEND=noninteractive
+apt-get update
+apt-get upgrade -y
+apt-get clean
+rm -rf /var/lib/apt/lists/* /var/cache/apt/archives/*.deb
+printf 'en_US.UTF-8 UTF-8\n' >/etc/locale.gen
+locale-gen
+rm -f /etc/ssh/ssh_host_*
+: >/etc/machine-id
+EOF
+rm -f "$rootfs_mount/usr/sbin/policy-rc.d"
+
+enable_fast_rootfs_units "$rootfs_mount" \
+	apt-daily.timer \
+	apt-daily-upgrade.timer \
+	e2scrub_all.timer \
+	e2scrub_reap.service \
+	man-db.timer
+validate_fast_rootfs_paths "$rootfs_mount" /usr/bin/dpkg
+chroot "$rootfs_mount" /usr/bin/dpkg -S /usr/sbin/sshd >/dev/null
+finish_rootfs "$out_dir/rootfs.ext4"
+
+install_kernel_and_firecracker
+write_bundle_manifest \
+	"\"debian_suite\": \"$debian_suite\"" \
+	"\"debian_mirror\": \"$debian_mirror\""
@@ -0,0 +1,61 @@
+#!/usr/bin/env bash
+# Copyright (c) 2025 AUTHORS All rights reserved.
+# Use of this source code is governed by a BSD-style
+# license that can be found in the LICENSE file.
+
+set -euo pipefail
+
+distro="fedora"
+distro_version="44"
+default_user="fedora"
+version="${YEET_VM_IMAGE_VERSION:-fedora-44-amd64-dev}"
+out_dir="${1:-dist/$version}"
+work_dir="${YEET_VM_IMAGE_WORK_DIR:-}"
+
+# shellcheck source=bundle-lib.sh
+. "$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)/bundle-lib.sh"
+
+require dnf
+require_fast_inputs
+setup_work_dir
+
+create_rootfs "$out_dir/rootfs.ext4"
+mount_rootfs_api_filesystems
+
+echo "Installing Fedora $distro_version packages..."
+dnf -y --installroot="$rootfs_mount" --releasever="$distro_version" \
+	--setopt=install_weak_deps=False --setopt=tsflags=nodocs \
+	--exclude='kernel*' --exclude='grub2*' --exclude='shim*' --exclude='dracut*' \
+	install \
+	fedora-release systemd systemd-networkd systemd-resolved passwd \
+	openssh-server sudo iproute iptables-nft nftables rsync ca-certificates \
+	curl less procps-ng bash-completion util-linux glibc-langpack-en
+dnf -y --installroot="$rootfs_mount" clean all
+
+write_fast_rootfs_common_files "$rootfs_mount"
+cat >>"$rootfs_mount/etc/dnf/dnf.conf" <<'EOF'
+# The yeet VM image bundle supplies the kernel; see
+# /usr/share/doc/yeet-vm-image/kernel.md.
+excludepkgs=kernel* grub2* shim* dracut*
+EOF
+# The yeet-managed kernel has no SELinux policy loaded and catch writes
+# metadata into the rootfs from the host, so files would carry no labels.
+if [ -f "$rootfs_mount/etc/selinux/config" ]; then
+	sed -i 's/^SELINUX=.*/SELINUX=disabled/' "$rootfs_mount/etc/selinux/config"
+fi
+chroot "$rootfs_mount" /bin/sh <<'EOF'
+set -e
+rm -f /etc/ssh/ssh_host_*
+: >/etc/machine-id
+EOF
+
+enable_fast_rootfs_units "$rootfs_mount" \
+	dnf-makecache.timer \
+	raid-check.timer
+validate_fast_rootfs_paths "$rootfs_mount" /usr/bin/rpm /usr/bin/dnf
+chroot "$rootfs_mount" /usr/bin/rpm -qf /usr/sbin/sshd >/dev/null
+finish_rootfs "$out_dir/rootfs.ext4"
+
+install_kernel_and_firecracker
+write_bundle_manifest \
+	"\"fedora_releasever\": \"$distro_version\""
@@ -0,0 +1,76 @@
+// Copyright (c) 2025 AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vm_image_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDistroImageRecipesUseSharedFastBundleShape(t *testing.T) {
+	for _, tt := range []struct {
+		script string
+		want   []string
+	}{
+		{
+			script: "build-debian-13.sh",
+			want: []string{
+				`version="${YEET_VM_IMAGE_VERSION:-debian-13-amd64-dev}"`,
+				`default_user="debian"`,
+				"debootstrap --variant=minbase",
+				"systemd-resolved",
+				"99-yeet-managed-kernel",
+				"/usr/bin/dpkg -S /usr/sbin/sshd",
+			},
+		},
+		{
+			script: "build-fedora-44.sh",
+			want: []string{
+				`version="${YEET_VM_IMAGE_VERSION:-fedora-44-amd64-dev}"`,
+				`default_user="fedora"`,
+				`--installroot="$rootfs_mount"`,
+				"systemd-networkd",
+				"excludepkgs=kernel* grub2* shim* dracut*",
+				"SELINUX=disabled",
+				"/usr/bin/rpm -qf /usr/sbin/sshd",
+			},
+		},
+	} {
+		script := readVMImageScript(t, tt.script)
+		for _, want := range append(tt.want, `bundle-lib.sh"`, "require_fast_inputs", "enable_fast_rootfs_units", "write_bundle_manifest") {
+			if !strings.Contains(script, want) {
+				t.Fatalf("%s missing %q", tt.script, want)
+			}
+		}
+	}
+}
+
+func TestBundleLibWritesDistroMetadataDriver(t *testing.T) {
+	lib := readVMImageScript(t, "bundle-lib.sh")
+
+	for _, want := range []string{
+		`"metadata_driver": "$distro"`,
+		`"default_user": "$default_user"`,
+		`"distro_version": "$distro_version"`,
+		"NetworkManager.service",
+		"systemd-networkd.service",
+		"tune2fs -O ^orphan_file",
+		`checksum_files=(manifest.json vmlinux rootfs.ext4.zst firecracker jailer)`,
+	} {
+		if !strings.Contains(lib, want) {
+			t.Fatalf("bundle-lib.sh missing %q", want)
+		}
+	}
+}
+
+func readVMImageScript(t *testing.T, name string) string {
+	t.Helper()
+	raw, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return string(raw)
+}
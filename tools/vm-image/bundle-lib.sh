@@ -0,0 +1,328 @@
+# Copyright (c) 2025 AUTHORS All rights reserved.
+# Use of this source code is governed by a BSD-style
+# license that can be found in the LICENSE file.
+
+# Shared helpers for the fast-profile rootfs recipes that build a guest from
+# the distro's own package manager instead of a cloud image. Callers set
+# distro, distro_version, default_user, version, out_dir, and work_dir, then
+# source this file.
+
+kernel_path="${YEET_VM_KERNEL_PATH:-}"
+kernel_version_override="${YEET_VM_KERNEL_VERSION:-}"
+guest_init_path="${YEET_VM_INIT_PATH:-}"
+script_dir="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+repo_root="$(cd "$script_dir/../.." && pwd)"
+ghostty_terminfo_source="${YEET_VM_GHOSTTY_TERMINFO:-$repo_root/tools/vm-image/assets/xterm-ghostty.terminfo}"
+rootfs_size="${YEET_VM_ROOTFS_SIZE:-2G}"
+firecracker_version="${FIRECRACKER_VERSION:-v1.14.3}"
+firecracker_arch="${FIRECRACKER_ARCH:-x86_64}"
+firecracker_tgz="firecracker-${firecracker_version}-${firecracker_arch}.tgz"
+firecracker_url="${FIRECRACKER_URL:-https://github.com/firecracker-microvm/firecracker/releases/download/${firecracker_version}/${firecracker_tgz}}"
+zstd_level="${ZSTD_LEVEL:-10}"
+
+require() {
+	if ! command -v "$1" >/dev/null 2>&1; then
+		echo "missing required command: $1" >&2
+		exit 1
+	fi
+}
+
+require_fast_inputs() {
+	for cmd in awk chroot curl date dumpe2fs e2fsck file id infocmp install mkdir mkfs.ext4 mktemp mount mountpoint sha256sum stat tar tic truncate tune2fs umount zstd; do
+		require "$cmd"
+	done
+	if [ -z "$kernel_path" ] || [ ! -r "$kernel_path" ]; then
+		echo "YEET_VM_KERNEL_PATH must name a readable yeet-managed kernel" >&2
+		exit 1
+	fi
+	if [ -z "$guest_init_path" ] || [ ! -x "$guest_init_path" ]; then
+		echo "YEET_VM_INIT_PATH must name an executable yeet-init" >&2
+		exit 1
+	fi
+	if [ ! -r "$ghostty_terminfo_source" ]; then
+		echo "YEET_VM_GHOSTTY_TERMINFO is not readable: $ghostty_terminfo_source" >&2
+		exit 1
+	fi
+	if [ "$(id -u)" != 0 ]; then
+		echo "the fast profile must run as root so it can mount and customize the rootfs" >&2
+		exit 1
+	fi
+}
+
+rootfs_mount=""
+cleanup_rootfs_mount() {
+	if [ -z "$rootfs_mount" ]; then
+		return
+	fi
+	for rel in run sys proc dev; do
+		if mountpoint -q "$rootfs_mount/$rel" 2>/dev/null; then
+			umount "$rootfs_mount/$rel" || true
+		fi
+	done
+	if mountpoint -q "$rootfs_mount" 2>/dev/null; then
+		umount "$rootfs_mount" || true
+	fi
+}
+
+setup_work_dir() {
+	if [ -z "$work_dir" ]; then
+		work_dir="$(mktemp -d)"
+		cleanup_work=1
+	else
+		mkdir -p "$work_dir"
+		cleanup_work=0
+	fi
+	trap cleanup EXIT
+	mkdir -p "$out_dir"
+}
+
+cleanup() {
+	cleanup_rootfs_mount
+	if [ "${cleanup_work:-0}" = 1 ]; then
+		rm -rf "$work_dir"
+	fi
+}
+
+# create_rootfs formats an empty ext4 image and mounts it with the API
+# filesystems the package manager needs inside the chroot.
+create_rootfs() {
+	local rootfs="$1"
+	truncate -s "$rootfs_size" "$rootfs"
+	mkfs.ext4 -q -F -L rootfs "$rootfs"
+	normalize_fast_rootfs_ext4_features "$rootfs"
+	rootfs_mount="$work_dir/rootfs-mount"
+	mkdir -p "$rootfs_mount"
+	mount -o loop,rw "$rootfs" "$rootfs_mount"
+}
+
+mount_rootfs_api_filesystems() {
+	for rel in dev proc sys run; do
+		mkdir -p "$rootfs_mount/$rel"
+	done
+	mount --bind /dev "$rootfs_mount/dev"
+	mount -t proc proc "$rootfs_mount/proc"
+	mount -t sysfs sysfs "$rootfs_mount/sys"
+	mount --bind /run "$rootfs_mount/run"
+}
+
+finish_rootfs() {
+	local rootfs="$1"
+	cleanup_rootfs_mount
+	rootfs_mount=""
+	run_fast_rootfs_e2fsck "$rootfs"
+	normalize_fast_rootfs_ext4_features "$rootfs"
+}
+
+write_fast_rootfs_common_files() {
+	local root="$1"
+	install -d -m 0755 \
+		"$root/etc/sysctl.d" \
+		"$root/etc/tmpfiles.d" \
+		"$root/etc/systemd/network" \
+		"$root/usr/local/lib/yeet-vm" \
+		"$root/usr/share/doc/yeet-vm-image"
+	install -m 0755 "$guest_init_path" "$root/usr/local/lib/yeet-vm/yeet-init"
+	install -d -m 0755 "$root/etc/terminfo"
+	tic -x -o "$root/etc/terminfo" "$ghostty_terminfo_source"
+	TERMINFO="$root/etc/terminfo" infocmp -x xterm-ghostty >/dev/null
+	cat >"$root/usr/share/doc/yeet-vm-image/kernel.md" <<EOF
+# Yeet VM Kernel
+
+This image boots with Firecracker direct kernel boot. The kernel is supplied by
+the yeet VM image bundle manifest, not by packages installed inside the guest.
+
+Guest package upgrades intentionally do not install $distro kernel,
+bootloader, or initramfs packages. To update the boot kernel, publish a new
+yeet VM image bundle and create VMs from that image version.
+EOF
+	cat >"$root/etc/sysctl.d/99-yeet-vm-router.conf" <<'EOF'
+# Yeet VMs should be ready to run guest-managed routers and exit nodes.
+net.ipv4.ip_forward = 1
+net.ipv6.conf.all.forwarding = 1
+EOF
+	cat >"$root/etc/tmpfiles.d/yeet-vm-tun.conf" <<'EOF'
+d /dev/net 0755 root root -
+c /dev/net/tun 0666 root root 10:200
+EOF
+}
+
+# enable_fast_rootfs_units points the guest at systemd-networkd and masks
+# units that cannot work without modules or would slow the first boot.
+enable_fast_rootfs_units() {
+	local root="$1"
+	shift
+	local wants="$root/etc/systemd/system/multi-user.target.wants"
+	mkdir -p "$wants"
+	ln -sf /usr/lib/systemd/system/systemd-networkd.service "$wants/systemd-networkd.service"
+	ln -sf /usr/lib/systemd/system/systemd-resolved.service "$wants/systemd-resolved.service"
+	if [ -e "$root/usr/lib/systemd/system/systemd-timesyncd.service" ]; then
+		ln -sf /usr/lib/systemd/system/systemd-timesyncd.service "$wants/systemd-timesyncd.service"
+	fi
+	ln -sf /usr/lib/systemd/system/multi-user.target "$root/etc/systemd/system/default.target"
+	ln -sf ../run/systemd/resolve/stub-resolv.conf "$root/etc/resolv.conf"
+	for unit in \
+		NetworkManager.service \
+		NetworkManager-wait-online.service \
+		systemd-modules-load.service \
+		systemd-networkd-wait-online.service \
+		modprobe@.service \
+		proc-sys-fs-binfmt_misc.automount \
+		proc-sys-fs-binfmt_misc.mount \
+		fstrim.timer \
+		"$@"
+	do
+		ln -sf /dev/null "$root/etc/systemd/system/$unit"
+	done
+}
+
+validate_fast_rootfs_paths() {
+	local root="$1"
+	shift
+	for path in /usr/sbin/sshd /usr/sbin/agetty /usr/sbin/iptables /usr/bin/rsync /usr/bin/sudo /usr/lib/systemd/systemd-networkd "$@"; do
+		if [ ! -e "$root$path" ]; then
+			echo "missing $distro package-owned path $path" >&2
+			exit 1
+		fi
+	done
+	if ! chroot "$root" /usr/sbin/iptables --version | grep -q 'nf_tables'; then
+		echo "iptables must use the nf_tables backend" >&2
+		exit 1
+	fi
+}
+
+run_fast_rootfs_e2fsck() {
+	local rootfs="$1"
+	local status
+
+	set +e
+	e2fsck -fy "$rootfs"
+	status=$?
+	set -e
+
+	case "$status" in
+	0 | 1)
+		;;
+	*)
+		echo "e2fsck failed after rootfs feature normalization: exit $status" >&2
+		exit "$status"
+		;;
+	esac
+}
+
+normalize_fast_rootfs_ext4_features() {
+	local rootfs="$1"
+	local features
+
+	features="$(dumpe2fs -h "$rootfs" 2>/dev/null | awk -F: '/Filesystem features/ { print $2; exit }')"
+	if printf '%s\n' "$features" | grep -qw orphan_file; then
+		echo "Disabling ext4 orphan_file for LTS host e2fsprogs compatibility..."
+		tune2fs -O ^orphan_file "$rootfs" >/dev/null
+		run_fast_rootfs_e2fsck "$rootfs"
+	fi
+
+	features="$(dumpe2fs -h "$rootfs" 2>/dev/null | awk -F: '/Filesystem features/ { print $2; exit }')"
+	if printf '%s\n' "$features" | grep -Eq '(^|[[:space:]])orphan_file($|[[:space:]])|(^|[[:space:]])FEATURE_'; then
+		echo "rootfs ext4 features are not compatible with LTS host tooling: $features" >&2
+		exit 1
+	fi
+}
+
+install_kernel_and_firecracker() {
+	kernel_version="$kernel_version_override"
+	if [ -z "$kernel_version" ]; then
+		kernel_version="$(basename "$kernel_path")"
+	fi
+	echo "Installing yeet-managed kernel $kernel_version..."
+	install -m 0644 "$kernel_path" "$out_dir/vmlinux"
+	if ! file "$out_dir/vmlinux" | grep -q "ELF 64-bit"; then
+		echo "YEET_VM_KERNEL_PATH is not an x86_64 ELF kernel" >&2
+		exit 1
+	fi
+	if [ -r "$(dirname "$kernel_path")/kernel.config" ]; then
+		install -m 0644 "$(dirname "$kernel_path")/kernel.config" "$out_dir/kernel.config"
+	fi
+
+	echo "Downloading Firecracker $firecracker_version..."
+	curl -fL --retry 3 -o "$work_dir/$firecracker_tgz" "$firecracker_url"
+	tar xzf "$work_dir/$firecracker_tgz" -C "$work_dir"
+	local fc_dir="$work_dir/release-${firecracker_version}-${firecracker_arch}"
+	(
+		cd "$fc_dir"
+		sha256sum -c --ignore-missing SHA256SUMS
+	)
+	install -m 0755 "$fc_dir/firecracker-${firecracker_version}-${firecracker_arch}" "$out_dir/firecracker"
+	install -m 0755 "$fc_dir/jailer-${firecracker_version}-${firecracker_arch}" "$out_dir/jailer"
+}
+
+# write_bundle_manifest compresses the rootfs and writes manifest.json and
+# checksums.txt. Extra provenance lines are passed as preformatted JSON
+# members, one per argument.
+write_bundle_manifest() {
+	local provenance_lines=""
+	for line in "$@"; do
+		provenance_lines="$provenance_lines    $line,
+"
+	done
+
+	echo "Compressing rootfs..."
+	zstd -T0 "-$zstd_level" -f --no-progress -o "$out_dir/rootfs.ext4.zst" "$out_dir/rootfs.ext4"
+
+	local rootfs_bytes rootfs_sha kernel_sha firecracker_sha jailer_sha guest_init_sha build_time
+	rootfs_bytes="$(stat -c %s "$out_dir/rootfs.ext4")"
+	rootfs_sha="$(sha256sum "$out_dir/rootfs.ext4.zst" | awk '{ print $1 }')"
+	kernel_sha="$(sha256sum "$out_dir/vmlinux" | awk '{ print $1 }')"
+	firecracker_sha="$(sha256sum "$out_dir/firecracker" | awk '{ print $1 }')"
+	jailer_sha="$(sha256sum "$out_dir/jailer" | awk '{ print $1 }')"
+	guest_init_sha="$(sha256sum "$guest_init_path" | awk '{ print $1 }')"
+	build_time="$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+
+	cat >"$out_dir/manifest.json" <<JSON
+{
+  "name": "yeet-$distro-$distro_version",
+  "version": "$version",
+  "architecture": "x86_64",
+  "image_profile": "fast",
+  "distro": "$distro",
+  "distro_version": "$distro_version",
+  "default_user": "$default_user",
+  "metadata_driver": "$distro",
+  "kernel_policy": "yeet-managed",
+  "snap_support": false,
+  "guest_init": "/usr/local/lib/yeet-vm/yeet-init",
+  "guest_init_sha256": "$guest_init_sha",
+  "kernel": "vmlinux",
+  "rootfs": "rootfs.ext4.zst",
+  "firecracker": "firecracker",
+  "jailer": "jailer",
+  "rootfs_size": $rootfs_bytes,
+  "kernel_version": "$kernel_version",
+  "provenance": {
+$provenance_lines    "build_time": "$build_time",
+    "kernel_source": "yeet-managed",
+    "firecracker_version": "$firecracker_version",
+    "firecracker_url": "$firecracker_url"
+  },
+  "checksums": {
+    "vmlinux": "$kernel_sha",
+    "rootfs.ext4.zst": "$rootfs_sha",
+    "firecracker": "$firecracker_sha",
+    "jailer": "$jailer_sha"
+  }
+}
+JSON
+
+	(
+		cd "$out_dir"
+		checksum_files=(manifest.json vmlinux rootfs.ext4.zst firecracker jailer)
+		if [ -f kernel.config ]; then
+			checksum_files+=(kernel.config)
+		fi
+		sha256sum "${checksum_files[@]}" >checksums.txt
+	)
+
+	rm -f "$out_dir/rootfs.ext4"
+
+	echo "Wrote VM image bundle to $out_dir"
+	echo "Version: $version"
+	echo "Kernel: $kernel_version"
+}